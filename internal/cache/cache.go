@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// Version of the cache format, caches written with another version are discarded
+	Version int = 1
+	// FileName is the name of the results file in the cache directory or archive
+	FileName string = "results.json"
+)
+
+// Files read by the tools besides the analyzed file; a change invalidates the provider results
+var toolFiles = []string{"composer.lock", ".php-cs-fixer.php", ".php-cs-fixer.dist.php"}
+
+// ResultCache holds the diagnostics of file local providers keyed by file content, so unchanged files
+// are not analyzed again. A nil cache is valid and never hits.
+type ResultCache struct {
+	location     string
+	configHashes map[string]string
+	data         cacheData
+	hits         int
+	misses       int
+}
+
+type cacheData struct {
+	Version   int                        `json:"version"`
+	Providers map[string]*providerResult `json:"providers"`
+}
+
+type providerResult struct {
+	// Hash of the provider configuration the results were computed with
+	ConfigHash string                `json:"configHash"`
+	Files      map[string]fileResult `json:"files"`
+}
+
+type fileResult struct {
+	ContentHash string                `json:"contentHash"`
+	Diagnostics []protocol.Diagnostic `json:"diagnostics"`
+}
+
+// Load reads the cache from a directory, or from a gzipped tarball when the location ends with .tar.gz
+// or .tgz. A missing or outdated cache starts empty. Only the providers in configHashes are cached,
+// and their results are dropped when the configuration hash changed since the cache was written.
+func Load(location string, configHashes map[string]string) (*ResultCache, error) {
+	c := &ResultCache{
+		location:     location,
+		configHashes: configHashes,
+		data:         cacheData{Version: Version, Providers: make(map[string]*providerResult)},
+	}
+
+	content, err := c.read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache %s: %w", location, err)
+	}
+
+	var data cacheData
+	if err := json.Unmarshal(content, &data); err != nil || data.Version != Version {
+		// Corrupted or written by another version, start over
+		return c, nil
+	}
+
+	for id, hash := range configHashes {
+		if result, exists := data.Providers[id]; exists && result.ConfigHash == hash {
+			c.data.Providers[id] = result
+		}
+	}
+
+	return c, nil
+}
+
+// Get returns the cached diagnostics of the provider for the file content
+func (c *ResultCache) Get(providerId string, path string, content string) ([]protocol.Diagnostic, bool) {
+	if c == nil {
+		return nil, false
+	}
+	if _, cacheable := c.configHashes[providerId]; !cacheable {
+		return nil, false
+	}
+
+	if result, exists := c.data.Providers[providerId]; exists {
+		if file, exists := result.Files[path]; exists && file.ContentHash == hashContent(content) {
+			c.hits++
+			return file.Diagnostics, true
+		}
+	}
+
+	c.misses++
+	return nil, false
+}
+
+// Put stores the diagnostics of the provider for the file content, replacing the previous version of the file
+func (c *ResultCache) Put(providerId string, path string, content string, diagnostics []protocol.Diagnostic) {
+	if c == nil {
+		return
+	}
+	configHash, cacheable := c.configHashes[providerId]
+	if !cacheable {
+		return
+	}
+
+	result, exists := c.data.Providers[providerId]
+	if !exists {
+		result = &providerResult{ConfigHash: configHash, Files: make(map[string]fileResult)}
+		c.data.Providers[providerId] = result
+	}
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	result.Files[path] = fileResult{ContentHash: hashContent(content), Diagnostics: diagnostics}
+}
+
+// Stats returns the number of hits and misses since the cache was loaded
+func (c *ResultCache) Stats() (hits int, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	return c.hits, c.misses
+}
+
+// Save writes the cache back to its location
+func (c *ResultCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(c.data)
+	if err != nil {
+		return err
+	}
+
+	if IsArchive(c.location) {
+		return writeArchive(c.location, content)
+	}
+
+	if err := os.MkdirAll(c.location, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(c.location, FileName), content)
+}
+
+func (c *ResultCache) read() ([]byte, error) {
+	if IsArchive(c.location) {
+		return readArchive(c.location)
+	}
+	return os.ReadFile(filepath.Join(c.location, FileName))
+}
+
+// IsArchive reports whether the cache location is a gzipped tarball rather than a directory
+func IsArchive(location string) bool {
+	return strings.HasSuffix(location, ".tar.gz") || strings.HasSuffix(location, ".tgz")
+}
+
+// HashProviderConfig hashes everything besides the file content the provider results depend on: the
+// provider configuration, its tool configuration file and the installed tool versions (composer.lock)
+func HashProviderConfig(projectRoot string, providerConfig config.DiagnosticsProvider) string {
+	hash := sha256.New()
+
+	encodedConfig, _ := json.Marshal(providerConfig)
+	hash.Write(encodedConfig)
+
+	files := toolFiles
+	if providerConfig.ConfigFile != "" {
+		files = append([]string{providerConfig.ConfigFile}, files...)
+	}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(projectRoot, file)
+		}
+		if content, err := os.ReadFile(file); err == nil {
+			fmt.Fprintf(hash, "\x00%s\x00", filepath.Base(file))
+			hash.Write(content)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func readArchive(location string) ([]byte, error) {
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive: %w", FileName, fs.ErrNotExist)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == FileName {
+			return io.ReadAll(tarReader)
+		}
+	}
+}
+
+func writeArchive(location string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(location), filepath.Base(location)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	gzipWriter := gzip.NewWriter(tmpFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	header := &tar.Header{Name: FileName, Mode: 0o644, Size: int64(len(content)), ModTime: time.Now()}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if _, err := tarWriter.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), location)
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
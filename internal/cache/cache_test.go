@@ -0,0 +1,133 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/cache"
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+var testDiagnostics = []protocol.Diagnostic{{
+	Range:    protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 100}},
+	Severity: protocol.DiagnosticSeverityWarning,
+	Source:   "php-cs-fixer",
+	Message:  "Unused import",
+	Code:     "no_unused_imports",
+}}
+
+func TestResultCache_RoundTrip(t *testing.T) {
+	for _, location := range []string{"cache", "cache.tar.gz"} {
+		t.Run(location, func(t *testing.T) {
+			location := filepath.Join(t.TempDir(), location)
+			hashes := map[string]string{"phpcsfixer": "config-v1"}
+
+			resultCache, err := cache.Load(location, hashes)
+			if err != nil {
+				t.Fatalf("Failed to load missing cache: %v", err)
+			}
+			if _, cached := resultCache.Get("phpcsfixer", "src/Foo.php", "<?php\n"); cached {
+				t.Fatal("Expected empty cache")
+			}
+			resultCache.Put("phpcsfixer", "src/Foo.php", "<?php\n", testDiagnostics)
+			resultCache.Put("phpcsfixer", "src/Clean.php", "<?php\n", nil)
+			if err := resultCache.Save(); err != nil {
+				t.Fatalf("Failed to save cache: %v", err)
+			}
+
+			restored, err := cache.Load(location, hashes)
+			if err != nil {
+				t.Fatalf("Failed to load cache: %v", err)
+			}
+			diagnostics, cached := restored.Get("phpcsfixer", "src/Foo.php", "<?php\n")
+			if !cached || len(diagnostics) != 1 || diagnostics[0].Message != "Unused import" || diagnostics[0].Code != "no_unused_imports" {
+				t.Errorf("Expected the stored diagnostics, got %v (cached %v)", diagnostics, cached)
+			}
+			if diagnostics, cached := restored.Get("phpcsfixer", "src/Clean.php", "<?php\n"); !cached || len(diagnostics) != 0 {
+				t.Errorf("Expected a cached clean file, got %v (cached %v)", diagnostics, cached)
+			}
+			if _, cached := restored.Get("phpcsfixer", "src/Foo.php", "<?php\n// changed\n"); cached {
+				t.Error("Expected a miss for changed content")
+			}
+			if hits, misses := restored.Stats(); hits != 2 || misses != 1 {
+				t.Errorf("Expected 2 hits and 1 miss, got %d and %d", hits, misses)
+			}
+		})
+	}
+}
+
+func TestResultCache_ConfigChange(t *testing.T) {
+	location := t.TempDir()
+
+	resultCache, _ := cache.Load(location, map[string]string{"phpcsfixer": "config-v1", "phplint": "lint-v1"})
+	resultCache.Put("phpcsfixer", "src/Foo.php", "<?php\n", testDiagnostics)
+	resultCache.Put("phplint", "src/Foo.php", "<?php\n", nil)
+	if err := resultCache.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	restored, _ := cache.Load(location, map[string]string{"phpcsfixer": "config-v2", "phplint": "lint-v1"})
+	if _, cached := restored.Get("phpcsfixer", "src/Foo.php", "<?php\n"); cached {
+		t.Error("Expected results of a changed configuration to be dropped")
+	}
+	if _, cached := restored.Get("phplint", "src/Foo.php", "<?php\n"); !cached {
+		t.Error("Expected results of an unchanged configuration to be kept")
+	}
+}
+
+func TestResultCache_NotCacheable(t *testing.T) {
+	resultCache, _ := cache.Load(t.TempDir(), map[string]string{"phplint": "lint-v1"})
+	resultCache.Put("phpstan", "src/Foo.php", "<?php\n", testDiagnostics)
+	if _, cached := resultCache.Get("phpstan", "src/Foo.php", "<?php\n"); cached {
+		t.Error("Expected providers without a configuration hash not to be cached")
+	}
+
+	var nilCache *cache.ResultCache
+	nilCache.Put("phplint", "src/Foo.php", "<?php\n", nil)
+	if _, cached := nilCache.Get("phplint", "src/Foo.php", "<?php\n"); cached {
+		t.Error("Expected a nil cache to never hit")
+	}
+	if err := nilCache.Save(); err != nil {
+		t.Errorf("Expected saving a nil cache to succeed, got %v", err)
+	}
+}
+
+func TestResultCache_CorruptedCache(t *testing.T) {
+	location := t.TempDir()
+	if err := os.WriteFile(filepath.Join(location, cache.FileName), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resultCache, err := cache.Load(location, map[string]string{"phplint": "lint-v1"})
+	if err != nil {
+		t.Fatalf("Expected a corrupted cache to be discarded, got %v", err)
+	}
+	if _, cached := resultCache.Get("phplint", "src/Foo.php", "<?php\n"); cached {
+		t.Error("Expected an empty cache")
+	}
+}
+
+func TestHashProviderConfig(t *testing.T) {
+	projectRoot := t.TempDir()
+	providerConfig := config.DiagnosticsProvider{Enabled: true, Container: "php", Path: "php-cs-fixer"}
+
+	initial := cache.HashProviderConfig(projectRoot, providerConfig)
+	if initial != cache.HashProviderConfig(projectRoot, providerConfig) {
+		t.Error("Expected a stable hash")
+	}
+
+	if err := os.WriteFile(filepath.Join(projectRoot, ".php-cs-fixer.dist.php"), []byte("<?php return [];"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withRules := cache.HashProviderConfig(projectRoot, providerConfig)
+	if withRules == initial {
+		t.Error("Expected the tool configuration file to change the hash")
+	}
+
+	providerConfig.Path = "vendor/bin/php-cs-fixer"
+	if cache.HashProviderConfig(projectRoot, providerConfig) == withRules {
+		t.Error("Expected the provider configuration to change the hash")
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+// LastKnownFileName is the name of the last known diagnostics file in the workspace cache directory
+const LastKnownFileName string = "last-diagnostics.json"
+
+// LastKnown holds the diagnostics last published for the files of a workspace, so they can be shown again
+// right after a restart. A nil value is valid and holds nothing.
+type LastKnown struct {
+	mu   sync.Mutex
+	path string
+	data lastKnownData
+}
+
+type lastKnownData struct {
+	Version int                              `json:"version"`
+	Files   map[string][]protocol.Diagnostic `json:"files"`
+}
+
+// WorkspaceDir returns the directory of the user cache holding the state of the workspace
+func WorkspaceDir(workspaceRoot string) (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(workspaceRoot))
+	return filepath.Join(userCacheDir, config.Name, hex.EncodeToString(sum[:8])), nil
+}
+
+// LoadLastKnown reads the last known diagnostics of the workspace directory, starting empty when the
+// file is missing, corrupted or written by another version
+func LoadLastKnown(dir string) *LastKnown {
+	l := &LastKnown{
+		path: filepath.Join(dir, LastKnownFileName),
+		data: lastKnownData{Version: Version, Files: make(map[string][]protocol.Diagnostic)},
+	}
+
+	content, err := os.ReadFile(l.path)
+	if err != nil {
+		return l
+	}
+
+	var data lastKnownData
+	if err := json.Unmarshal(content, &data); err != nil || data.Version != Version || data.Files == nil {
+		return l
+	}
+	l.data = data
+
+	return l
+}
+
+// Get returns the diagnostics last published for the file
+func (l *LastKnown) Get(filePath string) ([]protocol.Diagnostic, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	diagnostics, exists := l.data.Files[filePath]
+	return diagnostics, exists
+}
+
+// Set records the diagnostics published for the file, files without diagnostics are not kept
+func (l *LastKnown) Set(filePath string, diagnostics []protocol.Diagnostic) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(diagnostics) == 0 {
+		delete(l.data.Files, filePath)
+		return
+	}
+	l.data.Files[filePath] = diagnostics
+}
+
+// Save writes the last known diagnostics to the workspace directory
+func (l *LastKnown) Save() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	content, err := json.Marshal(l.data)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(l.path, content)
+}
@@ -0,0 +1,62 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/cache"
+)
+
+func TestLastKnown_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "workspace")
+
+	lastKnown := cache.LoadLastKnown(dir)
+	if _, exists := lastKnown.Get("/app/src/Foo.php"); exists {
+		t.Fatal("Expected empty last known diagnostics")
+	}
+
+	lastKnown.Set("/app/src/Foo.php", testDiagnostics)
+	lastKnown.Set("/app/src/Clean.php", testDiagnostics)
+	lastKnown.Set("/app/src/Clean.php", nil)
+	if err := lastKnown.Save(); err != nil {
+		t.Fatalf("Failed to save last known diagnostics: %v", err)
+	}
+
+	restored := cache.LoadLastKnown(dir)
+	diagnostics, exists := restored.Get("/app/src/Foo.php")
+	if !exists || len(diagnostics) != 1 || diagnostics[0].Message != "Unused import" {
+		t.Errorf("Expected the stored diagnostics, got %v (exists %v)", diagnostics, exists)
+	}
+	if _, exists := restored.Get("/app/src/Clean.php"); exists {
+		t.Error("Expected files without diagnostics not to be kept")
+	}
+}
+
+func TestLastKnown_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, cache.LastKnownFileName), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write corrupted file: %v", err)
+	}
+
+	lastKnown := cache.LoadLastKnown(dir)
+	if _, exists := lastKnown.Get("/app/src/Foo.php"); exists {
+		t.Error("Expected a corrupted file to start empty")
+	}
+	lastKnown.Set("/app/src/Foo.php", testDiagnostics)
+	if _, exists := lastKnown.Get("/app/src/Foo.php"); !exists {
+		t.Error("Expected the corrupted file to be replaced")
+	}
+}
+
+func TestLastKnown_Nil(t *testing.T) {
+	var lastKnown *cache.LastKnown
+
+	lastKnown.Set("/app/src/Foo.php", testDiagnostics)
+	if _, exists := lastKnown.Get("/app/src/Foo.php"); exists {
+		t.Error("Expected a nil value to hold nothing")
+	}
+	if err := lastKnown.Save(); err != nil {
+		t.Errorf("Expected saving a nil value to succeed, got %v", err)
+	}
+}
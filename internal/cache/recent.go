@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// RecentFilesFileName is the name of the recently used files list in the workspace cache directory
+	RecentFilesFileName string = "recent-files.json"
+
+	// Number of files kept in the recently used files list
+	maxRecentFiles = 50
+)
+
+// RecentFiles is the list of the files last opened in a workspace, most recent first. A nil value is
+// valid and holds nothing.
+type RecentFiles struct {
+	mu   sync.Mutex
+	path string
+	data recentFilesData
+}
+
+type recentFilesData struct {
+	Version int      `json:"version"`
+	Files   []string `json:"files"`
+}
+
+// LoadRecentFiles reads the recently used files of the workspace directory, starting empty when the
+// file is missing, corrupted or written by another version
+func LoadRecentFiles(dir string) *RecentFiles {
+	r := &RecentFiles{
+		path: filepath.Join(dir, RecentFilesFileName),
+		data: recentFilesData{Version: Version},
+	}
+
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return r
+	}
+
+	var data recentFilesData
+	if err := json.Unmarshal(content, &data); err != nil || data.Version != Version {
+		return r
+	}
+	r.data = data
+
+	return r
+}
+
+// Touch moves the file to the top of the list
+func (r *RecentFiles) Touch(filePath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := []string{filePath}
+	for _, file := range r.data.Files {
+		if file != filePath && len(files) < maxRecentFiles {
+			files = append(files, file)
+		}
+	}
+	r.data.Files = files
+}
+
+// List returns up to limit recently used files, most recent first
+func (r *RecentFiles) List(limit int) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.data.Files) < limit {
+		limit = len(r.data.Files)
+	}
+	return append([]string(nil), r.data.Files[:limit]...)
+}
+
+// Save writes the list to the workspace directory
+func (r *RecentFiles) Save() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	content, err := json.Marshal(r.data)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, content)
+}
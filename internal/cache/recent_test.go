@@ -0,0 +1,41 @@
+package cache_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/cache"
+)
+
+func TestRecentFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "workspace")
+
+	recentFiles := cache.LoadRecentFiles(dir)
+	recentFiles.Touch("/app/src/A.php")
+	recentFiles.Touch("/app/src/B.php")
+	recentFiles.Touch("/app/src/A.php")
+	if err := recentFiles.Save(); err != nil {
+		t.Fatalf("Failed to save recent files: %v", err)
+	}
+
+	files := cache.LoadRecentFiles(dir).List(10)
+	if len(files) != 2 || files[0] != "/app/src/A.php" || files[1] != "/app/src/B.php" {
+		t.Errorf("Expected A.php then B.php, got %v", files)
+	}
+	if files := recentFiles.List(1); len(files) != 1 || files[0] != "/app/src/A.php" {
+		t.Errorf("Expected the most recent file only, got %v", files)
+	}
+}
+
+func TestRecentFiles_Capped(t *testing.T) {
+	recentFiles := cache.LoadRecentFiles(t.TempDir())
+	for i := 0; i < 60; i++ {
+		recentFiles.Touch(fmt.Sprintf("/app/src/File%d.php", i))
+	}
+
+	files := recentFiles.List(100)
+	if len(files) != 50 || files[0] != "/app/src/File59.php" {
+		t.Errorf("Expected the 50 most recent files, got %d starting with %v", len(files), files[0])
+	}
+}
@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cristianradulescu/php-diagls/internal/cache"
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// fileIssues are the diagnostics reported for one file, path relative to the project root
+type fileIssues struct {
+	Path        string
+	Diagnostics []protocol.Diagnostic
+}
+
+func runCheck(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	staged := flags.Bool("staged", false, "Analyze the content staged in git and only report issues not present in HEAD")
+	format := flags.String("format", OutputFormatText, "Output format: text, json (versioned, see schema/php-diagls-output.schema.json) or rdjson (reviewdog)")
+	failOn := flags.String("fail-on", "", "Lowest severity making the check fail: error, warning or none (default: failOn from the config, or warning)")
+	cacheDir := flags.String("cache-dir", "", "Directory (or .tar.gz archive) keeping the results of unchanged files between runs")
+	verbose := flags.Bool("v", false, "Log provider commands to stderr")
+	configArg := configFlag(flags, configFile)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s check [--config file] [--staged] [--format text|json|rdjson] [--fail-on error|warning|none] [--cache-dir dir] [-v] [files...]\n", config.Name)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	if *format != OutputFormatText && *format != OutputFormatJSON && *format != OutputFormatRDJSON {
+		fmt.Fprintf(stderr, "unknown output format: %s\n", *format)
+		return ExitError
+	}
+	if *failOn != "" && !config.IsValidFailOn(*failOn) {
+		fmt.Fprintf(stderr, "invalid --fail-on: %s\n", *failOn)
+		return ExitError
+	}
+	setupLogging(*verbose, stderr)
+
+	ctx := context.Background()
+	projectRoot, err := resolveProjectRoot(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	serverConfig, providers, err := loadProviders(projectRoot, *configArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	var resultCache *cache.ResultCache
+	if *cacheDir != "" {
+		resultCache, err = loadResultCache(*cacheDir, projectRoot, serverConfig, providers)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitError
+		}
+	}
+
+	var results []fileIssues
+	if *staged {
+		results, err = checkStaged(ctx, projectRoot, serverConfig, providers, resultCache, stderr)
+	} else {
+		results, err = checkFiles(ctx, projectRoot, serverConfig, providers, resultCache, flags.Args())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	if resultCache != nil {
+		hits, misses := resultCache.Stats()
+		log.Printf("Result cache: %d hits, %d misses", hits, misses)
+		if err := resultCache.Save(); err != nil {
+			// The results are valid, the next run is only slower
+			fmt.Fprintf(stderr, "failed to save the result cache: %v\n", err)
+		}
+	}
+
+	if _, err := writeResults(stdout, *format, results); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	policy := serverConfig.FailOn
+	if *failOn != "" {
+		policy = *failOn
+	}
+	if failsPolicy(results, policy) {
+		return ExitIssues
+	}
+	return ExitOK
+}
+
+// failsPolicy reports whether a diagnostic reaches the severity the policy fails on
+func failsPolicy(results []fileIssues, failOn string) bool {
+	threshold := protocol.DiagnosticSeverityWarning
+	switch failOn {
+	case config.FailOnNone:
+		return false
+	case config.FailOnError:
+		threshold = protocol.DiagnosticSeverityError
+	}
+
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			// Lower values are more severe
+			if diagnostic.Severity != 0 && diagnostic.Severity <= threshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadConfig loads the configuration of the project from the configuration file given with --config, the
+// default one when empty
+func loadConfig(projectRoot string, configFile string) (*config.Config, error) {
+	files, err := config.ParseFiles([]string{configFile})
+	if err != nil {
+		return nil, err
+	}
+	return (&config.Config{}).LoadConfig(projectRoot, files)
+}
+
+// resolveProjectRoot uses the git top level directory, or the working directory outside of git
+func resolveProjectRoot(ctx context.Context) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if root, err := gitRoot(ctx, cwd); err == nil {
+		return root, nil
+	}
+	return cwd, nil
+}
+
+// loadProviders loads the project config and initializes the enabled providers, like the editor does
+func loadProviders(projectRoot string, configFile string) (*config.Config, []diagnostics.DiagnosticsProvider, error) {
+	serverConfig, err := loadConfig(projectRoot, configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	diagnostics.AutoConfigure(context.Background(), serverConfig, projectRoot)
+	diagnostics.SplitPhpDocErrors(serverConfig)
+
+	ids := make([]string, 0, len(serverConfig.DiagnosticsProviders))
+	for id := range serverConfig.DiagnosticsProviders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var providers []diagnostics.DiagnosticsProvider
+	for _, id := range ids {
+		providerConfig := serverConfig.DiagnosticsProviders[id]
+		if !providerConfig.Enabled {
+			continue
+		}
+
+		provider, err := diagnostics.NewDiagnosticsProvider(id, providerConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return serverConfig, providers, nil
+}
+
+// loadResultCache loads the result cache for the enabled providers having file local results
+func loadResultCache(location string, projectRoot string, serverConfig *config.Config, providers []diagnostics.DiagnosticsProvider) (*cache.ResultCache, error) {
+	configHashes := make(map[string]string)
+	for _, provider := range providers {
+		if diagnostics.HasFileLocalResults(provider.Id()) {
+			configHashes[provider.Id()] = cache.HashProviderConfig(projectRoot, serverConfig.DiagnosticsProviders[provider.Id()])
+		}
+	}
+
+	return cache.Load(location, configHashes)
+}
+
+// checkStaged analyzes the staged version of every staged file and keeps the issues which are not
+// reported for the version in HEAD, so a hook only fails on issues introduced by the commit
+func checkStaged(ctx context.Context, projectRoot string, serverConfig *config.Config, providers []diagnostics.DiagnosticsProvider, resultCache *cache.ResultCache, stderr io.Writer) ([]fileIssues, error) {
+	files, err := stagedFiles(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []fileIssues
+	for _, file := range files {
+		filePath := filepath.Join(projectRoot, file)
+		if !serverConfig.SupportsFile(filePath) {
+			continue
+		}
+
+		stagedContent, _, err := utils.GitShow(ctx, projectRoot, ":"+file)
+		if err != nil {
+			return nil, err
+		}
+		stagedDiagnostics := analyzeContent(ctx, serverConfig, providers, resultCache, file, filePath, stagedContent, stderr)
+
+		headContent, existsInHead, err := utils.GitShow(ctx, projectRoot, "HEAD:"+file)
+		if err != nil {
+			return nil, err
+		}
+		if existsInHead && len(stagedDiagnostics) > 0 {
+			stagedDiagnostics = newIssues(stagedDiagnostics, analyzeContent(ctx, serverConfig, providers, resultCache, file, filePath, headContent, stderr))
+		}
+
+		results = append(results, fileIssues{Path: file, Diagnostics: stagedDiagnostics})
+	}
+
+	return results, nil
+}
+
+// checkFiles analyzes the given files and directories (relative to the working directory) as they are on disk
+func checkFiles(ctx context.Context, projectRoot string, serverConfig *config.Config, providers []diagnostics.DiagnosticsProvider, resultCache *cache.ResultCache, args []string) ([]fileIssues, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no files given, pass files to check or use --staged")
+	}
+
+	files, err := collectFiles(ctx, serverConfig, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []fileIssues
+	for _, filePath := range files {
+		if !serverConfig.SupportsFile(filePath) {
+			continue
+		}
+
+		relativeFilePath := relativePath(projectRoot, filePath)
+		content := ""
+		if resultCache != nil {
+			fileContent, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, err
+			}
+			content = string(fileContent)
+		}
+
+		var fileDiagnostics []protocol.Diagnostic
+		fileCtx := diagnostics.WithSharedRuns(ctx)
+		for _, provider := range providers {
+			if !diagnostics.AnalyzesFile(provider.Id(), serverConfig.DiagnosticsProviders, filePath) {
+				continue
+			}
+			providerDiagnostics, cached := resultCache.Get(provider.Id(), relativeFilePath, content)
+			if !cached {
+				providerCtx, failed := diagnostics.TrackFailures(fileCtx)
+				providerDiagnostics, err = provider.Analyze(providerCtx, filePath)
+				if err != nil {
+					return nil, fmt.Errorf("%s failed on %s: %w", provider.Name(), filePath, err)
+				}
+				if failed() == nil {
+					resultCache.Put(provider.Id(), relativeFilePath, content, providerDiagnostics)
+				}
+			}
+			fileDiagnostics = append(fileDiagnostics, providerDiagnostics...)
+		}
+
+		results = append(results, fileIssues{Path: relativeFilePath, Diagnostics: fileDiagnostics})
+	}
+
+	return results, nil
+}
+
+// analyzeContent runs the providers able to analyze content which is not on disk
+func analyzeContent(ctx context.Context, serverConfig *config.Config, providers []diagnostics.DiagnosticsProvider, resultCache *cache.ResultCache, relativeFilePath string, filePath string, content string, stderr io.Writer) []protocol.Diagnostic {
+	var result []protocol.Diagnostic
+	ctx = diagnostics.WithSharedRuns(ctx)
+	for _, provider := range providers {
+		if !diagnostics.AnalyzesFile(provider.Id(), serverConfig.DiagnosticsProviders, filePath) {
+			continue
+		}
+		if cachedDiagnostics, cached := resultCache.Get(provider.Id(), relativeFilePath, content); cached {
+			result = append(result, cachedDiagnostics...)
+			continue
+		}
+
+		analyzer, ok := provider.(diagnostics.ContentAnalyzer)
+		if !ok {
+			fmt.Fprintf(stderr, "%s can't analyze staged content, skipped\n", provider.Name())
+			continue
+		}
+
+		providerCtx, failed := diagnostics.TrackFailures(ctx)
+		providerDiagnostics, err := analyzer.AnalyzeContent(providerCtx, filePath, content)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s failed on %s: %v\n", provider.Name(), filePath, err)
+			continue
+		}
+		if failed() == nil {
+			resultCache.Put(provider.Id(), relativeFilePath, content, providerDiagnostics)
+		}
+		result = append(result, providerDiagnostics...)
+	}
+
+	return result
+}
+
+// newIssues returns the diagnostics not reported for the base version. Lines move between versions,
+// so diagnostics are matched on provider, rule and message, each base diagnostic matching once.
+func newIssues(current []protocol.Diagnostic, base []protocol.Diagnostic) []protocol.Diagnostic {
+	known := make(map[string]int)
+	for _, diagnostic := range base {
+		known[issueKey(diagnostic)]++
+	}
+
+	var added []protocol.Diagnostic
+	for _, diagnostic := range current {
+		key := issueKey(diagnostic)
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+		added = append(added, diagnostic)
+	}
+
+	return added
+}
+
+func issueKey(diagnostic protocol.Diagnostic) string {
+	return fmt.Sprintf("%s\x00%v\x00%s", diagnostic.Source, diagnostic.Code, diagnostic.Message)
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+// Exit codes of the CLI commands
+const (
+	ExitOK     = 0
+	ExitIssues = 1
+	ExitError  = 2
+)
+
+type command struct {
+	description string
+	run         func(args []string, configFile string, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"check":   {"Analyze files (or the staged content with --staged) and exit non-zero on issues", runCheck},
+	"config":  {"Print the configuration, or the effective one with the source of every value with --resolved", runConfig},
+	"explain": {"Print the full description and examples of a rule reported in diagnostics", runExplain},
+	"fmt":     {"Format files in place, or print the changes as a unified diff with --diff", runFmt},
+	"init":    {"Generate the configuration file from the tool configuration files of the project", runInit},
+}
+
+// IsCommand reports whether the argument names a CLI command rather than a server flag
+func IsCommand(name string) bool {
+	_, exists := commands[name]
+	return exists || name == "help"
+}
+
+// Run executes the CLI command named by the first argument and returns the process exit code. The
+// configuration file is the one given with --config before the command, the command's --config wins.
+func Run(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		usage(stdout)
+		return ExitOK
+	}
+
+	cmd, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+		usage(stderr)
+		return ExitError
+	}
+
+	return cmd.run(args[1:], configFile, stdout, stderr)
+}
+
+// configFlag registers --config on the command, defaulting to the configuration file given before the command
+func configFlag(flags *flag.FlagSet, configFile string) *string {
+	return flags.String("config", configFile, "Configuration file, relative to the project root or absolute (default "+config.ConfigFileName+")")
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: %s [--config file] <command> [options]\n\nCommands:\n", config.Name)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(w, "\nRun without a command to start the language server.\n")
+}
+
+// setupLogging keeps the provider and runner logs out of the command output unless verbose
+func setupLogging(verbose bool, stderr io.Writer) {
+	if verbose {
+		log.SetOutput(stderr)
+		return
+	}
+	log.SetOutput(io.Discard)
+}
+
+// relativePath shortens paths inside the project root for display
+func relativePath(projectRoot string, filePath string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(filePath, projectRoot), "/")
+}
@@ -0,0 +1,325 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/cli"
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+func TestRun_Help(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := cli.Run([]string{"help"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitOK, code)
+	}
+	if !strings.Contains(stdout.String(), "check") {
+		t.Errorf("Expected usage to list the check command, got %q", stdout.String())
+	}
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := cli.Run([]string{"frobnicate"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "unknown command: frobnicate") {
+		t.Errorf("Expected unknown command error, got %q", stderr.String())
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	if !cli.IsCommand("check") {
+		t.Error("Expected check to be a command")
+	}
+	if cli.IsCommand("-stdin") {
+		t.Error("Expected server flags not to be commands")
+	}
+}
+
+func TestCheck_StagedWithoutProviders(t *testing.T) {
+	root := newTestRepo(t, `{"diagnosticsProviders": {"phpstan": {"enabled": false}}}`)
+	writeFile(t, filepath.Join(root, "src", "Foo.php"), "<?php\n")
+	runGit(t, root, "add", ".")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"check", "--staged"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Errorf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected no issues, got %q", stdout.String())
+	}
+}
+
+func TestCheck_MissingConfig(t *testing.T) {
+	newTestRepo(t, "")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"check", "--staged"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "config file not found") {
+		t.Errorf("Expected config error, got %q", stderr.String())
+	}
+}
+
+// newTestRepo creates a git repository with the given config (none when empty) and makes it the working directory
+func newTestRepo(t *testing.T, configContent string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	if configContent != "" {
+		writeFile(t, filepath.Join(root, ".php-diagls.json"), configContent)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	return root
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, output)
+	}
+}
+
+func TestFmt_NoFormattingProvider(t *testing.T) {
+	root := newTestRepo(t, `{"diagnosticsProviders": {"phpcsfixer": {"enabled": true, "format": {"enabled": false}}}}`)
+	writeFile(t, filepath.Join(root, "src", "Foo.php"), "<?php\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"fmt", "--diff", "src"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "no provider has formatting enabled") {
+		t.Errorf("Expected missing formatter error, got %q", stderr.String())
+	}
+}
+
+func TestCheck_JSONOutput(t *testing.T) {
+	root := newTestRepo(t, `{"diagnosticsProviders": {"phpstan": {"enabled": false}}}`)
+	writeFile(t, filepath.Join(root, "src", "Foo.php"), "<?php\n")
+	runGit(t, root, "add", ".")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"check", "--staged", "--format", "json"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+
+	var report cli.JSONReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", stdout.String(), err)
+	}
+	if report.Version != cli.JSONOutputVersion {
+		t.Errorf("Expected version %d, got %d", cli.JSONOutputVersion, report.Version)
+	}
+	if report.Diagnostics == nil || len(report.Diagnostics) != 0 {
+		t.Errorf("Expected an empty diagnostics array, got %v", report.Diagnostics)
+	}
+}
+
+func TestCheck_RDJSONOutput(t *testing.T) {
+	root := newTestRepo(t, `{"diagnosticsProviders": {"phpstan": {"enabled": false}}}`)
+	writeFile(t, filepath.Join(root, "src", "Foo.php"), "<?php\n")
+	runGit(t, root, "add", ".")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"check", "--staged", "--format", "rdjson"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+
+	var result cli.RDJSONResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Expected rdjson output, got %q: %v", stdout.String(), err)
+	}
+	if result.Source.Name != "php-diagls" {
+		t.Errorf("Expected source php-diagls, got %q", result.Source.Name)
+	}
+	if result.Diagnostics == nil || len(result.Diagnostics) != 0 {
+		t.Errorf("Expected an empty diagnostics array, got %v", result.Diagnostics)
+	}
+}
+
+func TestCheck_UnknownFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := cli.Run([]string{"check", "--format", "xml", "src"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+}
+
+func TestCheck_InvalidFailOn(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := cli.Run([]string{"check", "--fail-on", "notice", "src"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "invalid --fail-on") {
+		t.Errorf("Expected invalid --fail-on error, got %q", stderr.String())
+	}
+}
+
+func TestConfig_Resolved(t *testing.T) {
+	newTestRepo(t, `{"failOn": "none", "diagnosticsProviders": {"phpstan": {"enabled": false}}}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"config", "--resolved"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+
+	for _, expected := range []string{`failOn`, `"none"`, `.php-diagls.json`, `fileExtensions`, `# default`} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, stdout.String())
+		}
+	}
+}
+
+func TestConfig_ConfigFile(t *testing.T) {
+	root := newTestRepo(t, "")
+	writeFile(t, filepath.Join(root, "tools", "php-diagls.json"), `{"failOn": "error", "diagnosticsProviders": {}}`)
+
+	for name, run := range map[string]func(stdout *bytes.Buffer, stderr *bytes.Buffer) int{
+		"before the command": func(stdout *bytes.Buffer, stderr *bytes.Buffer) int {
+			return cli.Run([]string{"config"}, "tools/php-diagls.json", stdout, stderr)
+		},
+		"after the command": func(stdout *bytes.Buffer, stderr *bytes.Buffer) int {
+			return cli.Run([]string{"config", "--config", "tools/php-diagls.json"}, "", stdout, stderr)
+		},
+		"absolute": func(stdout *bytes.Buffer, stderr *bytes.Buffer) int {
+			return cli.Run([]string{"config", "--config", filepath.Join(root, "tools", "php-diagls.json")}, "", stdout, stderr)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(&stdout, &stderr); code != cli.ExitOK {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), `"failOn": "error"`) {
+				t.Errorf("Expected the tools/ configuration, got:\n%s", stdout.String())
+			}
+		})
+	}
+}
+
+func TestExplain_NoExplainingProvider(t *testing.T) {
+	newTestRepo(t, `{"diagnosticsProviders": {"phpstan": {"enabled": false}}}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"explain", "no_unused_imports"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "no enabled provider can explain rules") {
+		t.Errorf("Expected missing provider error, got %q", stderr.String())
+	}
+}
+
+func TestExplain_MissingRule(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"explain"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := cli.Run([]string{"completion", shell}, "", &stdout, &stderr); code != cli.ExitOK {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+			}
+
+			for _, expected := range []string{"check", "completion", "config", "explain", "fmt", "fail-on", "json", "phpstan"} {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected completion script to contain %q", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCompletion_UnsupportedShell(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"completion", "tcsh"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d, got %d", cli.ExitError, code)
+	}
+	if !strings.Contains(stderr.String(), "unsupported shell: tcsh") {
+		t.Errorf("Expected unsupported shell error, got %q", stderr.String())
+	}
+}
+
+func TestInit(t *testing.T) {
+	root := newTestRepo(t, "")
+	writeFile(t, filepath.Join(root, "phpstan.neon.dist"), "parameters:\n    level: 5\n")
+	writeFile(t, filepath.Join(root, "pint.json"), "{}")
+	writeFile(t, filepath.Join(root, "psalm.xml"), "<psalm/>")
+	writeFile(t, filepath.Join(root, "compose.yaml"), "services:\n  app:\n    image: php:8.3-fpm\n    container_name: shop-app\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli.Run([]string{"init"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+
+	cfg, err := (&config.Config{}).LoadConfig(root, config.DefaultFiles)
+	if err != nil {
+		t.Fatalf("Generated config failed to load: %v", err)
+	}
+	phpstan := cfg.DiagnosticsProviders["phpstan"]
+	if !phpstan.Enabled || phpstan.Container != "shop-app" || phpstan.ConfigFile != "phpstan.neon.dist" {
+		t.Errorf("Unexpected phpstan provider: %+v", phpstan)
+	}
+	if _, exists := cfg.DiagnosticsProviders["phplint"]; !exists {
+		t.Error("Expected the phplint provider")
+	}
+	if pint := cfg.DiagnosticsProviders["pint"]; !pint.Enabled || pint.ConfigFile != "pint.json" {
+		t.Errorf("Unexpected pint provider: %+v", pint)
+	}
+	if !strings.Contains(stderr.String(), "no provider for psalm") {
+		t.Errorf("Expected a note about psalm, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := cli.Run([]string{"init"}, "", &stdout, &stderr); code != cli.ExitError {
+		t.Errorf("Expected exit code %d with an existing config, got %d", cli.ExitError, code)
+	}
+	if code := cli.Run([]string{"init", "--stdout", "--container", "php-8"}, "", &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", cli.ExitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"container": "php-8"`) {
+		t.Errorf("Expected the given container in the printed config, got:\n%s", stdout.String())
+	}
+}
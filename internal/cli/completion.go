@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+// Shells supported by the completion command
+var completionShells = []string{"bash", "zsh", "fish"}
+
+type completionFlag struct {
+	name        string
+	description string
+	// Values completed for the flag argument, nil for boolean flags
+	values []string
+	// The flag argument is a path
+	path bool
+}
+
+// completionSpec describes the arguments of a command for the shell completions
+type completionSpec struct {
+	flags []completionFlag
+	// Values completed for the positional arguments, nil to complete files
+	args []string
+}
+
+func init() {
+	// Registered here, the completion scripts are generated from the commands map itself
+	commands["completion"] = command{"Print the shell completion script for bash, zsh or fish", runCompletion}
+}
+
+func completionSpecs() map[string]completionSpec {
+	formats := []string{OutputFormatText, OutputFormatJSON}
+	verbose := completionFlag{"v", "Log provider commands to stderr", nil, false}
+	configFile := completionFlag{"config", "Configuration file", nil, true}
+
+	return map[string]completionSpec{
+		"check": {flags: []completionFlag{
+			{"staged", "Analyze the content staged in git", nil, false},
+			{"format", "Output format", append(formats, OutputFormatRDJSON), false},
+			{"fail-on", "Lowest severity making the check fail", []string{config.FailOnError, config.FailOnWarning, config.FailOnNone}, false},
+			{"cache-dir", "Directory or .tar.gz archive keeping the results between runs", nil, true},
+			verbose,
+			configFile,
+		}},
+		"completion": {args: completionShells},
+		"config": {flags: []completionFlag{
+			{"resolved", "Print the effective configuration", nil, false},
+			{"format", "Output format", formats, false},
+			configFile,
+		}, args: []string{}},
+		"explain": {flags: []completionFlag{
+			{"provider", "Only ask this provider", diagnostics.ProviderIds(), false},
+			verbose,
+			configFile,
+		}, args: []string{}},
+		"fmt": {flags: []completionFlag{
+			{"diff", "Print a unified diff instead of rewriting the files", nil, false},
+			verbose,
+			configFile,
+		}},
+		"init": {flags: []completionFlag{
+			{"container", "Container of the providers", nil, false},
+			{"force", "Overwrite an existing configuration file", nil, false},
+			{"stdout", "Print the configuration instead of writing it", nil, false},
+			configFile,
+		}, args: []string{}},
+	}
+}
+
+func runCompletion(args []string, _ string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(stderr, "Usage: %s completion %s\n", config.Name, strings.Join(completionShells, "|"))
+		return ExitError
+	}
+
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(stdout)
+	case "zsh":
+		writeZshCompletion(stdout)
+	case "fish":
+		writeFishCompletion(stdout)
+	default:
+		fmt.Fprintf(stderr, "unsupported shell: %s\n", args[0])
+		return ExitError
+	}
+
+	return ExitOK
+}
+
+// commandNames lists the commands in a stable order, help included
+func commandNames() []string {
+	names := []string{"help"}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flagName returns the flag as typed: single dash for one letter flags, double dash otherwise
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+func writeBashCompletion(w io.Writer) {
+	specs := completionSpecs()
+	funcName := "_" + strings.ReplaceAll(config.Name, "-", "_")
+
+	fmt.Fprintf(w, "# bash completion for %s\n", config.Name)
+	fmt.Fprintf(w, "%s() {\n", funcName)
+	fmt.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "        return\n    fi\n\n")
+	fmt.Fprintf(w, "    local opts=\"\" args=\"\" files=1\n")
+	fmt.Fprintf(w, "    case \"${COMP_WORDS[1]}\" in\n")
+	for _, name := range commandNames() {
+		spec, exists := specs[name]
+		if !exists {
+			continue
+		}
+		fmt.Fprintf(w, "        %s)\n", name)
+		for _, flag := range spec.flags {
+			switch {
+			case flag.path:
+				fmt.Fprintf(w, "            [ \"$prev\" = %q ] && COMPREPLY=($(compgen -f -- \"$cur\")) && return\n", flagName(flag.name))
+			case flag.values != nil:
+				fmt.Fprintf(w, "            [ \"$prev\" = %q ] && COMPREPLY=($(compgen -W %q -- \"$cur\")) && return\n", flagName(flag.name), strings.Join(flag.values, " "))
+			}
+		}
+		var opts []string
+		for _, flag := range spec.flags {
+			opts = append(opts, flagName(flag.name))
+		}
+		fmt.Fprintf(w, "            opts=%q\n", strings.Join(opts, " "))
+		if spec.args != nil {
+			fmt.Fprintf(w, "            args=%q files=0\n", strings.Join(spec.args, " "))
+		}
+		fmt.Fprintf(w, "            ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n\n")
+	fmt.Fprintf(w, "    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    elif [ \"$files\" -eq 1 ]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    else\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$args\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o filenames -F %s %s\n", funcName, config.Name)
+}
+
+func writeZshCompletion(w io.Writer) {
+	specs := completionSpecs()
+	funcName := "_" + strings.ReplaceAll(config.Name, "-", "_")
+
+	fmt.Fprintf(w, "#compdef %s\n\n", config.Name)
+	fmt.Fprintf(w, "%s() {\n", funcName)
+	fmt.Fprintf(w, "    local -a commands\n    commands=(\n")
+	for _, name := range commandNames() {
+		description := "Show the usage"
+		if cmd, exists := commands[name]; exists {
+			description = cmd.description
+		}
+		fmt.Fprintf(w, "        %s\n", singleQuote(name+":"+description))
+	}
+	fmt.Fprintf(w, "    )\n\n")
+	fmt.Fprintf(w, "    if (( CURRENT == 2 )); then\n        _describe command commands\n        return\n    fi\n\n")
+	fmt.Fprintf(w, "    local subcommand=$words[2]\n    shift words\n    (( CURRENT-- ))\n\n")
+	fmt.Fprintf(w, "    case $subcommand in\n")
+	for _, name := range commandNames() {
+		spec, exists := specs[name]
+		if !exists {
+			continue
+		}
+		fmt.Fprintf(w, "        %s)\n            _arguments", name)
+		for _, flag := range spec.flags {
+			arg := fmt.Sprintf("%s[%s]", flagName(flag.name), flag.description)
+			switch {
+			case flag.path:
+				arg += fmt.Sprintf(":%s:_files", flag.name)
+			case flag.values != nil:
+				arg += fmt.Sprintf(":%s:(%s)", flag.name, strings.Join(flag.values, " "))
+			}
+			fmt.Fprintf(w, " \\\n                %s", singleQuote(arg))
+		}
+		switch {
+		case spec.args == nil:
+			fmt.Fprintf(w, " \\\n                '*:file:_files'")
+		case len(spec.args) > 0:
+			fmt.Fprintf(w, " \\\n                %s", singleQuote(fmt.Sprintf("1:argument:(%s)", strings.Join(spec.args, " "))))
+		}
+		fmt.Fprintf(w, "\n            ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n}\n\n")
+	fmt.Fprintf(w, "%s \"$@\"\n", funcName)
+}
+
+func writeFishCompletion(w io.Writer) {
+	specs := completionSpecs()
+
+	fmt.Fprintf(w, "# fish completion for %s\n", config.Name)
+	fmt.Fprintf(w, "complete -c %s -f\n", config.Name)
+	for _, name := range commandNames() {
+		description := "Show the usage"
+		if cmd, exists := commands[name]; exists {
+			description = cmd.description
+		}
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", config.Name, name, singleQuote(description))
+	}
+
+	for _, name := range commandNames() {
+		spec, exists := specs[name]
+		if !exists {
+			continue
+		}
+		condition := singleQuote("__fish_seen_subcommand_from " + name)
+		for _, flag := range spec.flags {
+			option := "-l " + flag.name
+			if len(flag.name) == 1 {
+				option = "-s " + flag.name
+			}
+			switch {
+			case flag.path:
+				option += " -r -F"
+			case flag.values != nil:
+				option += " -x -a " + singleQuote(strings.Join(flag.values, " "))
+			}
+			fmt.Fprintf(w, "complete -c %s -n %s %s -d %s\n", config.Name, condition, option, singleQuote(flag.description))
+		}
+		switch {
+		case spec.args == nil:
+			fmt.Fprintf(w, "complete -c %s -n %s -F\n", config.Name, condition)
+		case len(spec.args) > 0:
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s\n", config.Name, condition, singleQuote(strings.Join(spec.args, " ")))
+		}
+	}
+}
+
+// singleQuote quotes the value for the zsh and fish scripts
+func singleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func runConfig(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	resolved := flags.Bool("resolved", false, "Print the effective configuration with the source of every value")
+	format := flags.String("format", OutputFormatText, "Output format of --resolved: text or json")
+	configArg := configFlag(flags, configFile)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s config [--config file] [--resolved] [--format text|json]\n", config.Name)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+
+	projectRoot, err := resolveProjectRoot(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	serverConfig, err := loadConfig(projectRoot, *configArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	if !*resolved {
+		fmt.Fprintf(stdout, "%s\n", serverConfig.RawData)
+		return ExitOK
+	}
+
+	diagnostics.AutoConfigure(context.Background(), serverConfig, projectRoot)
+	values, err := serverConfig.Resolve()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	switch *format {
+	case OutputFormatJSON:
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(values); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitError
+		}
+	case OutputFormatText:
+		writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		for _, value := range values {
+			encoded, _ := json.Marshal(value.Value)
+			fmt.Fprintf(writer, "%s\t%s\t# %s\n", value.Key, encoded, value.Source)
+		}
+		writer.Flush()
+	default:
+		fmt.Fprintf(stderr, "unknown output format: %s\n", *format)
+		return ExitError
+	}
+
+	return ExitOK
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func runExplain(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	providerId := flags.String("provider", "", "Only ask the provider with this id, e.g. phpcsfixer or phpstan")
+	verbose := flags.Bool("v", false, "Log provider commands to stderr")
+	configArg := configFlag(flags, configFile)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s explain [--config file] [--provider id] [-v] <rule>\n", config.Name)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return ExitError
+	}
+	rule := flags.Arg(0)
+	setupLogging(*verbose, stderr)
+
+	ctx := context.Background()
+	projectRoot, err := resolveProjectRoot(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	_, providers, err := loadProviders(projectRoot, *configArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	// The rule id format tells the providers apart, the first one knowing the rule wins
+	var reasons []string
+	for _, provider := range providers {
+		if *providerId != "" && provider.Id() != *providerId {
+			continue
+		}
+		explainer, ok := provider.(diagnostics.RuleExplainer)
+		if !ok {
+			continue
+		}
+
+		explanation, err := explainer.ExplainRule(ctx, rule)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+
+		fmt.Fprintln(stdout, explanation)
+		return ExitOK
+	}
+
+	if len(reasons) == 0 {
+		fmt.Fprintf(stderr, "no enabled provider can explain rules\n")
+	} else {
+		fmt.Fprintf(stderr, "unknown rule %s: %s\n", rule, strings.Join(reasons, "; "))
+	}
+	return ExitError
+}
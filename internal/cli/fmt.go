@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/formatting"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+func runFmt(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	diff := flags.Bool("diff", false, "Print a unified diff applicable with `git apply` instead of rewriting the files; exit non-zero when files need formatting")
+	verbose := flags.Bool("v", false, "Log provider commands to stderr")
+	configArg := configFlag(flags, configFile)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s fmt [--config file] [--diff] [-v] <files or directories...>\n", config.Name)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	setupLogging(*verbose, stderr)
+
+	ctx := context.Background()
+	projectRoot, err := resolveProjectRoot(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	serverConfig, err := loadConfig(projectRoot, *configArg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	providers := formatting.LoadFormattingProviders(serverConfig.DiagnosticsProviders)
+	if len(providers) == 0 {
+		fmt.Fprintf(stderr, "no provider has formatting enabled\n")
+		return ExitError
+	}
+
+	files, err := collectFiles(ctx, serverConfig, flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+
+	changed := 0
+	for _, filePath := range files {
+		original, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitError
+		}
+
+		formatted := string(original)
+		for _, provider := range providers {
+			formatted, err = provider.Format(ctx, filePath, formatted)
+			if err != nil {
+				fmt.Fprintf(stderr, "%s failed on %s: %v\n", provider.Name(), filePath, err)
+				return ExitError
+			}
+		}
+
+		if formatted == string(original) {
+			continue
+		}
+		changed++
+
+		path := relativePath(projectRoot, filePath)
+		if *diff {
+			fmt.Fprint(stdout, utils.UnifiedDiff(path, string(original), formatted))
+			continue
+		}
+
+		if err := os.WriteFile(filePath, []byte(formatted), 0644); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitError
+		}
+		fmt.Fprintln(stdout, path)
+	}
+
+	if *diff && changed > 0 {
+		return ExitIssues
+	}
+	return ExitOK
+}
+
+// collectFiles expands the arguments into the supported files, walking directories
+func collectFiles(ctx context.Context, serverConfig *config.Config, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no files given")
+	}
+
+	var files []string
+	for _, arg := range args {
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found, err := utils.FindFiles(ctx, path, serverConfig.SupportsFile)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+// gitRoot returns the top level directory of the repository containing dir
+func gitRoot(ctx context.Context, dir string) (string, error) {
+	output, err := utils.Git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// stagedFiles returns the added, copied, modified and renamed files of the index, relative to the root
+func stagedFiles(ctx context.Context, root string) ([]string, error) {
+	output, err := utils.Git(ctx, root, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(output, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
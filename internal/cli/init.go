@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+// Container suggested when none is given nor found in a compose file
+const initDefaultContainer = "php"
+
+// generatedProvider holds the settings of a generated provider, leaving the other ones to their defaults
+type generatedProvider struct {
+	Enabled    bool   `json:"enabled"`
+	Container  string `json:"container"`
+	Path       string `json:"path"`
+	ConfigFile string `json:"configFile,omitempty"`
+}
+
+type generatedConfig struct {
+	DiagnosticsProviders map[string]generatedProvider `json:"diagnosticsProviders"`
+}
+
+func runInit(args []string, configFile string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	containerName := flags.String("container", "", "Container of the providers, found in the compose file by default")
+	force := flags.Bool("force", false, "Overwrite an existing configuration file")
+	printOnly := flags.Bool("stdout", false, "Print the configuration instead of writing it")
+	configArg := configFlag(flags, configFile)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s init [--config file] [--container name] [--force] [--stdout]\n", config.Name)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+
+	files, err := config.ParseFiles([]string{*configArg})
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+	projectRoot, err := resolveProjectRoot(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+	configPath := files.Path(projectRoot)
+	if _, err := os.Stat(configPath); err == nil && !*force && !*printOnly {
+		fmt.Fprintf(stderr, "%s already exists, use --force to overwrite it\n", configPath)
+		return ExitError
+	}
+
+	if *containerName == "" {
+		detection, err := config.DetectComposeContainer(projectRoot)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitError
+		}
+		if detection != nil {
+			*containerName = detection.Container
+			fmt.Fprintf(stderr, "Container %s found for service %s of %s\n", detection.Container, detection.Service, relativePath(projectRoot, detection.File))
+		} else {
+			*containerName = initDefaultContainer
+			fmt.Fprintf(stderr, "No PHP service found in a compose file, using container %q; set --container to change it\n", initDefaultContainer)
+		}
+	}
+
+	providers, notes := diagnostics.GenerateProviders(projectRoot, *containerName)
+	for _, note := range notes {
+		fmt.Fprintf(stderr, "%s\n", note)
+	}
+
+	generated := generatedConfig{DiagnosticsProviders: make(map[string]generatedProvider, len(providers))}
+	for id, provider := range providers {
+		generated.DiagnosticsProviders[id] = generatedProvider{
+			Enabled:    provider.Enabled,
+			Container:  provider.Container,
+			Path:       provider.Path,
+			ConfigFile: provider.ConfigFile,
+		}
+	}
+	data, err := json.MarshalIndent(generated, "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitError
+	}
+	data = append(data, '\n')
+
+	if *printOnly {
+		stdout.Write(data)
+		return ExitOK
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		fmt.Fprintf(stderr, "failed to create config directory: %v\n", err)
+		return ExitError
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		fmt.Fprintf(stderr, "failed to write config file: %v\n", err)
+		return ExitError
+	}
+	fmt.Fprintf(stdout, "Created %s\n", configPath)
+	return ExitOK
+}
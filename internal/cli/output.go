@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+// JSONOutputVersion is bumped on incompatible changes of the JSON output. Fields may be added
+// within a version, never removed, renamed or changed in meaning.
+const JSONOutputVersion = 1
+
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+	// Reviewdog Diagnostic Format, see https://github.com/reviewdog/reviewdog/tree/master/proto/rdf
+	OutputFormatRDJSON = "rdjson"
+)
+
+// JSONReport is the document printed by `check --format json`, see schema/php-diagls-output.schema.json
+type JSONReport struct {
+	Version     int              `json:"version"`
+	Diagnostics []JSONDiagnostic `json:"diagnostics"`
+}
+
+type JSONDiagnostic struct {
+	// File path relative to the project root, with forward slashes
+	File     string    `json:"file"`
+	Range    JSONRange `json:"range"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	// Rule identifier reported by the tool, empty when the tool has none
+	Rule     string `json:"rule"`
+	Provider string `json:"provider"`
+	// Documentation of the rule, empty when unknown
+	DocsUrl string `json:"docsUrl"`
+}
+
+// JSONRange uses 1-based lines and columns, like the text output
+type JSONRange struct {
+	Start JSONPosition `json:"start"`
+	End   JSONPosition `json:"end"`
+}
+
+type JSONPosition struct {
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+}
+
+// RDJSONResult is the DiagnosticResult message of the Reviewdog Diagnostic Format, in its JSON encoding
+type RDJSONResult struct {
+	Source      RDJSONSource       `json:"source"`
+	Diagnostics []RDJSONDiagnostic `json:"diagnostics"`
+}
+
+type RDJSONSource struct {
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+type RDJSONDiagnostic struct {
+	Message  string         `json:"message"`
+	Location RDJSONLocation `json:"location"`
+	// ERROR, WARNING or INFO
+	Severity string       `json:"severity"`
+	Source   RDJSONSource `json:"source"`
+	Code     *RDJSONCode  `json:"code,omitempty"`
+}
+
+type RDJSONLocation struct {
+	Path  string      `json:"path"`
+	Range RDJSONRange `json:"range"`
+}
+
+// RDJSONRange uses 1-based lines and columns
+type RDJSONRange struct {
+	Start RDJSONPosition `json:"start"`
+	End   RDJSONPosition `json:"end"`
+}
+
+type RDJSONPosition struct {
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+}
+
+type RDJSONCode struct {
+	Value string `json:"value"`
+	Url   string `json:"url,omitempty"`
+}
+
+// writeResults prints the results in the requested format and returns the number of diagnostics
+func writeResults(w io.Writer, format string, results []fileIssues) (int, error) {
+	switch format {
+	case OutputFormatText:
+		return writeText(w, results), nil
+	case OutputFormatJSON:
+		return writeJSON(w, results)
+	case OutputFormatRDJSON:
+		return writeRDJSON(w, results)
+	default:
+		return 0, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+func writeJSON(w io.Writer, results []fileIssues) (int, error) {
+	report := JSONReport{Version: JSONOutputVersion, Diagnostics: []JSONDiagnostic{}}
+
+	for _, result := range results {
+		for _, diagnostic := range sortedDiagnostics(result.Diagnostics) {
+			report.Diagnostics = append(report.Diagnostics, JSONDiagnostic{
+				File: result.Path,
+				Range: JSONRange{
+					Start: JSONPosition{Line: diagnostic.Range.Start.Line + 1, Column: diagnostic.Range.Start.Character + 1},
+					End:   JSONPosition{Line: diagnostic.Range.End.Line + 1, Column: diagnostic.Range.End.Character + 1},
+				},
+				Severity: severityName(diagnostic.Severity),
+				Message:  diagnostic.Message,
+				Rule:     diagnosticRule(diagnostic),
+				Provider: diagnostic.Source,
+				DocsUrl:  diagnosticDocsUrl(diagnostic),
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return len(report.Diagnostics), encoder.Encode(report)
+}
+
+// writeRDJSON prints a single rdjson DiagnosticResult, for `reviewdog -f=rdjson`
+func writeRDJSON(w io.Writer, results []fileIssues) (int, error) {
+	report := RDJSONResult{
+		Source:      RDJSONSource{Name: config.Name, Url: config.RepositoryUrl},
+		Diagnostics: []RDJSONDiagnostic{},
+	}
+
+	for _, result := range results {
+		for _, diagnostic := range sortedDiagnostics(result.Diagnostics) {
+			rdDiagnostic := RDJSONDiagnostic{
+				Message: diagnostic.Message,
+				Location: RDJSONLocation{
+					Path: result.Path,
+					Range: RDJSONRange{
+						Start: RDJSONPosition{Line: diagnostic.Range.Start.Line + 1, Column: diagnostic.Range.Start.Character + 1},
+						End:   RDJSONPosition{Line: diagnostic.Range.End.Line + 1, Column: diagnostic.Range.End.Character + 1},
+					},
+				},
+				Severity: rdjsonSeverity(diagnostic.Severity),
+				Source:   RDJSONSource{Name: diagnostic.Source},
+			}
+			if rule := diagnosticRule(diagnostic); rule != "" {
+				rdDiagnostic.Code = &RDJSONCode{Value: rule, Url: diagnosticDocsUrl(diagnostic)}
+			}
+			report.Diagnostics = append(report.Diagnostics, rdDiagnostic)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return len(report.Diagnostics), encoder.Encode(report)
+}
+
+// writeText prints one line per diagnostic (path:line:column: severity: message [rule] (provider))
+// and returns the number of diagnostics
+func writeText(w io.Writer, results []fileIssues) int {
+	count := 0
+	for _, result := range results {
+		for _, diagnostic := range sortedDiagnostics(result.Diagnostics) {
+			rule := ""
+			if r := diagnosticRule(diagnostic); r != "" {
+				rule = fmt.Sprintf(" [%s]", r)
+			}
+			fmt.Fprintf(w, "%s:%d:%d: %s: %s%s (%s)\n",
+				result.Path,
+				diagnostic.Range.Start.Line+1,
+				diagnostic.Range.Start.Character+1,
+				severityName(diagnostic.Severity),
+				diagnostic.Message,
+				rule,
+				diagnostic.Source,
+			)
+			count++
+		}
+	}
+
+	return count
+}
+
+func sortedDiagnostics(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	sorted := append([]protocol.Diagnostic(nil), diagnostics...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Line < sorted[j].Range.Start.Line
+	})
+	return sorted
+}
+
+func diagnosticRule(diagnostic protocol.Diagnostic) string {
+	if diagnostic.Code == nil {
+		return ""
+	}
+	return fmt.Sprint(diagnostic.Code)
+}
+
+func diagnosticDocsUrl(diagnostic protocol.Diagnostic) string {
+	if diagnostic.CodeDescription == nil {
+		return ""
+	}
+	return string(diagnostic.CodeDescription.Href)
+}
+
+// rdjsonSeverity maps the severity to the rdjson enum, hints are reported as INFO
+func rdjsonSeverity(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.DiagnosticSeverityError:
+		return "ERROR"
+	case protocol.DiagnosticSeverityWarning:
+		return "WARNING"
+	default:
+		return "INFO"
+	}
+}
+
+func severityName(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.DiagnosticSeverityError:
+		return "error"
+	case protocol.DiagnosticSeverityWarning:
+		return "warning"
+	case protocol.DiagnosticSeverityInformation:
+		return "info"
+	default:
+		return "hint"
+	}
+}
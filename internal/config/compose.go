@@ -0,0 +1,244 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Label marking the compose service the providers run in
+	ComposeLabel string = Name
+
+	// Why a compose service was picked as the PHP service
+	ComposeReasonLabel string = "label"
+	ComposeReasonImage string = "image"
+	ComposeReasonName  string = "name"
+)
+
+// Compose files in the lookup order of docker compose, each may have an override file
+var ComposeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+var composeProjectNameInvalidChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// ComposeDetection is the container of the PHP service found in the compose file of a project
+type ComposeDetection struct {
+	File      string `json:"file"`
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	Reason    string `json:"reason"`
+}
+
+type composeService struct {
+	name          string
+	image         string
+	containerName string
+	labels        map[string]string
+}
+
+// DetectComposeContainer finds the PHP service of the project compose file: the service labeled php-diagls,
+// else the first one running a PHP image, else the first one named after PHP. Its container is the
+// container_name of the service or the name given by docker compose. No detection is returned without a
+// compose file or a PHP service.
+func DetectComposeContainer(projectRoot string) (*ComposeDetection, error) {
+	for _, fileName := range ComposeFileNames {
+		composePath := filepath.Join(projectRoot, fileName)
+		data, err := os.ReadFile(composePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compose file: %w", err)
+		}
+
+		projectName, services := parseComposeFile(data)
+		extension := filepath.Ext(fileName)
+		if override, err := os.ReadFile(filepath.Join(projectRoot, strings.TrimSuffix(fileName, extension)+".override"+extension)); err == nil {
+			overrideName, overrideServices := parseComposeFile(override)
+			if overrideName != "" {
+				projectName = overrideName
+			}
+			services = mergeComposeServices(services, overrideServices)
+		}
+		if projectName == "" {
+			projectName = filepath.Base(projectRoot)
+		}
+
+		service, reason := phpComposeService(services)
+		if service == nil {
+			return nil, nil
+		}
+
+		containerName := service.containerName
+		if containerName == "" {
+			projectName = composeProjectNameInvalidChars.ReplaceAllString(strings.ToLower(projectName), "")
+			containerName = fmt.Sprintf("%s-%s-1", projectName, service.name)
+		}
+		return &ComposeDetection{File: composePath, Service: service.name, Container: containerName, Reason: reason}, nil
+	}
+
+	return nil, nil
+}
+
+func phpComposeService(services []composeService) (*composeService, string) {
+	for i, service := range services {
+		if value, exists := service.labels[ComposeLabel]; exists && value != "false" {
+			return &services[i], ComposeReasonLabel
+		}
+	}
+	for i, service := range services {
+		image := service.image
+		if index := strings.LastIndex(image, "/"); index >= 0 {
+			image = image[index+1:]
+		}
+		if index := strings.Index(image, ":"); index >= 0 {
+			image = image[:index]
+		}
+		if isPhpName(image) {
+			return &services[i], ComposeReasonImage
+		}
+	}
+	for i, service := range services {
+		if isPhpName(service.name) {
+			return &services[i], ComposeReasonName
+		}
+	}
+	return nil, ""
+}
+
+// isPhpName matches php, php-fpm or app-php but not phpmyadmin
+func isPhpName(name string) bool {
+	for _, part := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+		if part == "php" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeComposeServices applies the override services on top of the base ones
+func mergeComposeServices(base []composeService, overrides []composeService) []composeService {
+	for _, override := range overrides {
+		merged := false
+		for i := range base {
+			if base[i].name != override.name {
+				continue
+			}
+			if override.image != "" {
+				base[i].image = override.image
+			}
+			if override.containerName != "" {
+				base[i].containerName = override.containerName
+			}
+			for label, value := range override.labels {
+				base[i].labels[label] = value
+			}
+			merged = true
+		}
+		if !merged {
+			base = append(base, override)
+		}
+	}
+	return base
+}
+
+// parseComposeFile reads the project name and the services of a compose file. Only the block style YAML
+// written in compose files is understood: the image, container_name and labels of every service.
+func parseComposeFile(data []byte) (string, []composeService) {
+	var projectName string
+	var services []composeService
+	var current *composeService
+	inServices, inLabels := false, false
+	serviceIndent, keyIndent := -1, -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := stripYamlComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if indent == 0 {
+			key, value := yamlKeyValue(trimmed)
+			inServices = key == "services"
+			current, inLabels = nil, false
+			if key == "name" {
+				projectName = value
+			}
+			continue
+		}
+		if !inServices {
+			continue
+		}
+
+		if serviceIndent < 0 {
+			serviceIndent = indent
+		}
+		if indent <= serviceIndent {
+			key, _ := yamlKeyValue(trimmed)
+			services = append(services, composeService{name: key, labels: map[string]string{}})
+			current, inLabels, keyIndent = &services[len(services)-1], false, -1
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+		if indent <= keyIndent && !strings.HasPrefix(trimmed, "- ") {
+			key, value := yamlKeyValue(trimmed)
+			inLabels = key == "labels"
+			switch key {
+			case "image":
+				current.image = value
+			case "container_name":
+				current.containerName = value
+			}
+			continue
+		}
+
+		if inLabels {
+			if item, isItem := strings.CutPrefix(trimmed, "- "); isItem {
+				label, value, _ := strings.Cut(unquoteYaml(strings.TrimSpace(item)), "=")
+				current.labels[label] = value
+			} else {
+				label, value := yamlKeyValue(trimmed)
+				current.labels[label] = value
+			}
+		}
+	}
+
+	return projectName, services
+}
+
+// yamlKeyValue splits a "key: value" line, unquoting both
+func yamlKeyValue(line string) (string, string) {
+	key, value, _ := strings.Cut(line, ":")
+	return unquoteYaml(strings.TrimSpace(key)), unquoteYaml(strings.TrimSpace(value))
+}
+
+func unquoteYaml(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// stripYamlComment removes a trailing comment, a # starting the line or preceded by a space
+func stripYamlComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if index := strings.Index(line, " #"); index >= 0 {
+		return line[:index]
+	}
+	return line
+}
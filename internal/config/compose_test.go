@@ -0,0 +1,164 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+func writeComposeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	projectRoot := filepath.Join(t.TempDir(), "My_Shop")
+	if err := os.Mkdir(projectRoot, 0755); err != nil {
+		t.Fatalf("Failed to create project root: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectRoot, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return projectRoot
+}
+
+func TestDetectComposeContainer(t *testing.T) {
+	tests := []struct {
+		name              string
+		files             map[string]string
+		expectedService   string
+		expectedContainer string
+		expectedReason    string
+	}{
+		{
+			name: "php image",
+			files: map[string]string{"docker-compose.yml": `
+services:
+  db:
+    image: mysql:8
+  admin:
+    image: phpmyadmin/phpmyadmin
+  app:
+    image: "bitnami/php-fpm:8.3" # runtime
+`},
+			expectedService:   "app",
+			expectedContainer: "my_shop-app-1",
+			expectedReason:    config.ComposeReasonImage,
+		},
+		{
+			name: "label wins over image",
+			files: map[string]string{"compose.yaml": `
+name: shop
+services:
+  php:
+    image: php:8.3-cli
+  tools:
+    build: ./docker/tools
+    container_name: shop-tools
+    labels:
+      - "php-diagls=true"
+      - traefik.enable=false
+`},
+			expectedService:   "tools",
+			expectedContainer: "shop-tools",
+			expectedReason:    config.ComposeReasonLabel,
+		},
+		{
+			name: "label map",
+			files: map[string]string{"compose.yml": `
+services:
+  web:
+    image: nginx
+  worker:
+    build: .
+    labels:
+      php-diagls: "true"
+`},
+			expectedService:   "worker",
+			expectedContainer: "my_shop-worker-1",
+			expectedReason:    config.ComposeReasonLabel,
+		},
+		{
+			name: "service name",
+			files: map[string]string{"docker-compose.yaml": `
+version: "3.8"
+services:
+  php-fpm:
+    build:
+      context: .
+      dockerfile: docker/php/Dockerfile
+    environment:
+      APP_ENV: dev
+`},
+			expectedService:   "php-fpm",
+			expectedContainer: "my_shop-php-fpm-1",
+			expectedReason:    config.ComposeReasonName,
+		},
+		{
+			name: "override file",
+			files: map[string]string{
+				"docker-compose.yml":          "services:\n  app:\n    image: php:8.2\n",
+				"docker-compose.override.yml": "services:\n  app:\n    container_name: shop-app\n",
+			},
+			expectedService:   "app",
+			expectedContainer: "shop-app",
+			expectedReason:    config.ComposeReasonImage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection, err := config.DetectComposeContainer(writeComposeFiles(t, tt.files))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if detection == nil {
+				t.Fatal("Expected a detection")
+			}
+			if detection.Service != tt.expectedService || detection.Container != tt.expectedContainer || detection.Reason != tt.expectedReason {
+				t.Errorf("Expected %s/%s/%s, got %s/%s/%s", tt.expectedService, tt.expectedContainer, tt.expectedReason,
+					detection.Service, detection.Container, detection.Reason)
+			}
+		})
+	}
+
+	t.Run("no php service", func(t *testing.T) {
+		detection, err := config.DetectComposeContainer(writeComposeFiles(t, map[string]string{"compose.yaml": "services:\n  db:\n    image: postgres\n"}))
+		if err != nil || detection != nil {
+			t.Errorf("Expected no detection, got %v, %v", detection, err)
+		}
+	})
+
+	t.Run("no compose file", func(t *testing.T) {
+		detection, err := config.DetectComposeContainer(t.TempDir())
+		if err != nil || detection != nil {
+			t.Errorf("Expected no detection, got %v, %v", detection, err)
+		}
+	})
+}
+
+func TestConfig_ComposeContainer(t *testing.T) {
+	projectRoot := writeComposeFiles(t, map[string]string{
+		config.ConfigFileName: `{"fileExtensions": [".php"]}`,
+		"compose.yaml":        "services:\n  php:\n    image: php:8.3\n",
+	})
+
+	cfg, err := (&config.Config{}).LoadConfig(projectRoot, config.DefaultFiles)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.AutoConfigure || cfg.Container != "my_shop-php-1" || cfg.ComposeDetection == nil {
+		t.Errorf("Expected auto-configuration in container my_shop-php-1, got %v %q", cfg.AutoConfigure, cfg.Container)
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	for _, value := range resolved {
+		if value.Key == config.ConfigItemContainer && value.Source != config.SourceDetected {
+			t.Errorf("Expected source %s, got %s", config.SourceDetected, value.Source)
+		}
+	}
+}
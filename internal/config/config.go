@@ -4,44 +4,333 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	Name           string = "php-diagls"
 	Version        string = "0.2.0"
 	ConfigFileName string = ".php-diagls.json"
+	RepositoryUrl  string = "https://github.com/cristianradulescu/php-diagls"
 
 	ConfigItemDiagnosticsProviders string = "diagnosticsProviders"
+	ConfigItemFileExtensions       string = "fileExtensions"
+	ConfigItemLanguageIds          string = "languageIds"
+	ConfigItemGeneratedMarkers     string = "generatedMarkers"
+	ConfigItemDocker               string = "docker"
+	ConfigItemMaxOutputBytes       string = "maxOutputBytes"
+	ConfigItemFailOn               string = "failOn"
+	ConfigItemMessages             string = "messages"
+	ConfigItemFormatChain          string = "formatChain"
+	ConfigItemFormatOnSave         string = "formatOnSave"
+	ConfigItemFormatChangedLines   string = "formatChangedLines"
+	// Container of the providers detected in the project, when no diagnosticsProviders are configured
+	ConfigItemContainer string = "container"
+
+	// Lowest severity making the CLI check fail
+	FailOnError   string = "error"
+	FailOnWarning string = "warning"
+	FailOnNone    string = "none"
+
+	// Severities a provider can report its cosmetic rules with
+	SeverityError       string = "error"
+	SeverityWarning     string = "warning"
+	SeverityInformation string = "information"
+	SeverityHint        string = "hint"
+
+	// When a provider runs: on every open, change and save, only on save, or only when requested
+	RunOnAuto   string = "auto"
+	RunOnSave   string = "save"
+	RunOnManual string = "manual"
+
+	// Report formats the custom providers can read
+	OutputFormatCheckstyle string = "checkstyle"
+	OutputFormatSarif      string = "sarif"
+
+	// Categories of rules: layout only (whitespace, casing, ...) or changing the code
+	RuleCategoryCosmetic   string = "cosmetic"
+	RuleCategoryStructural string = "structural"
+)
+
+var (
+	DefaultFileExtensions = []string{".php"}
+	DefaultLanguageIds    = []string{"php"}
+	// Markers found at the top of generated files; heavy providers skip such files
+	DefaultGeneratedMarkers = []string{"@generated", "Autogenerated by"}
+	// Cap of the captured output of every tool command
+	DefaultMaxOutputBytes int64 = 4 * 1024 * 1024
+	// PHP versions checked by PHPCompatibility: a version, a minimum (7.4-) or a range (7.4-8.3)
+	testVersionRegex = regexp.MustCompile(`^\d+\.\d+(-(\d+\.\d+)?)?$`)
+	// Seconds a provider stays suspended by its circuit breaker before being retried
+	DefaultCircuitBreakerCooldownSeconds = 60
 )
 
 type Config struct {
 	RawData              json.RawMessage
 	DiagnosticsProviders map[string]DiagnosticsProvider
-	initialized          bool
+	FileExtensions       []string
+	LanguageIds          []string
+	GeneratedMarkers     []string
+	Docker               DockerConfig
+	MaxOutputBytes       int64
+	FailOn               string
+	Messages             map[string]string
+	FormatChain          []FormatStep
+	Container            string
+	// Documents are formatted before being saved, by clients sending willSaveWaitUntil
+	FormatOnSave bool
+	// Git ref whose merge base with HEAD is the version formatting is compared to, only the lines changed since
+	// being formatted
+	FormatChangedLines string
+	// Providers are detected from the tools installed in the project, see diagnostics.AutoConfigure
+	AutoConfigure bool
+	// Compose service the container was found in, when the configuration names none
+	ComposeDetection *ComposeDetection
+	// Root of the project the configuration was loaded for
+	ProjectRoot string
+	path        string
+	initialized bool
 }
 
 type FormatConfig struct {
 	Enabled        bool `json:"enabled"`
 	TimeoutSeconds int  `json:"timeoutSeconds,omitempty"`
+	// Formatters with a higher priority are selected first when several are enabled
+	Priority int `json:"priority,omitempty"`
+}
+
+// FormatStep is a step of the formatting chain, fed the output of the previous one: the formatting of a
+// provider, or a command reading the content on stdin and printing the formatted content
+type FormatStep struct {
+	Provider string `json:"provider,omitempty"`
+	Command  string `json:"command,omitempty"`
+	// Name of the command step in logs and errors
+	Name string `json:"name,omitempty"`
+	// Container of the command step, the project container by default
+	Container      string `json:"container,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"`
+}
+
+// DockerConfig selects the Docker daemon running the analysis containers, which may live on a remote machine
+type DockerConfig struct {
+	Host      string `json:"host,omitempty"`
+	TlsVerify bool   `json:"tlsVerify,omitempty"`
+	CertPath  string `json:"certPath,omitempty"`
+	// Docker compatible API socket used instead of the docker CLI, e.g. rootless podman
+	Socket string `json:"socket,omitempty"`
+	// Host directory -> container directory, used to run commands in the container copy of the project
+	PathMappings map[string]string `json:"pathMappings,omitempty"`
+}
+
+// LimitsConfig constrains the CPU usage of the provider's tool commands
+type LimitsConfig struct {
+	Nice     int `json:"nice,omitempty"`
+	CpuLimit int `json:"cpuLimit,omitempty"`
+}
+
+// CircuitBreakerConfig suspends a provider after consecutive failures, so a broken tool stops slowing down every analysis
+type CircuitBreakerConfig struct {
+	// Consecutive failures suspending the provider, disabled when 0
+	Failures        int `json:"failures,omitempty"`
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
+}
+
+// Cooldown returns the time the provider stays suspended before being retried
+func (breaker CircuitBreakerConfig) Cooldown() time.Duration {
+	if breaker.CooldownSeconds > 0 {
+		return time.Duration(breaker.CooldownSeconds) * time.Second
+	}
+	return time.Duration(DefaultCircuitBreakerCooldownSeconds) * time.Second
+}
+
+// WatchConfig keeps the tool running in watch mode instead of starting it for every analysis
+type WatchConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Command streaming one report per run, required when enabled
+	Command string `json:"command,omitempty"`
 }
 
 type DiagnosticsProvider struct {
-	Enabled    bool         `json:"enabled"`
-	Container  string       `json:"container"`
-	Path       string       `json:"path"`
-	ConfigFile string       `json:"configFile"`
-	Format     FormatConfig `json:"format"`
+	Enabled            bool         `json:"enabled"`
+	Container          string       `json:"container"`
+	Containers         []string     `json:"containers,omitempty"`
+	ComposeService     string       `json:"composeService,omitempty"`
+	ComposeFile        string       `json:"composeFile,omitempty"`
+	LocalPath          string       `json:"localPath,omitempty"`
+	Fallback           []string     `json:"fallback,omitempty"`
+	Path               string       `json:"path"`
+	ConfigFile         string       `json:"configFile"`
+	Format             FormatConfig `json:"format"`
+	SummarizeThreshold int          `json:"summarizeThreshold,omitempty"`
+	Limits             LimitsConfig `json:"limits,omitempty"`
+	// Report each rule once per file, at its first occurrence, with the number of occurrences
+	FirstOccurrenceOnly bool `json:"firstOccurrenceOnly,omitempty"`
+	// Severity of the cosmetic (whitespace, casing) rules, hint when empty
+	CosmeticSeverity string `json:"cosmeticSeverity,omitempty"`
+	// Rule categories reported once per file, at the first occurrence of each rule, with the number of occurrences
+	GroupRules []string `json:"groupRules,omitempty"`
+	// Identifiers (glob patterns such as missingType.*) whose errors are not reported
+	IgnoreIdentifiers []string `json:"ignoreIdentifiers,omitempty"`
+	// Seconds an analysis of one file may run, no limit when 0
+	TimeoutSeconds int                  `json:"timeoutSeconds,omitempty"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuitBreaker,omitempty"`
+	Watch          WatchConfig          `json:"watch,omitempty"`
+	// Manual providers only run from the analyzeFile and analyzeWorkspace commands, save providers on save too
+	RunOn string `json:"runOn,omitempty"`
+	// Seconds a save provider waits after the last save of the file before running, no wait when 0
+	DebounceSeconds int `json:"debounceSeconds,omitempty"`
+	// Refuse commands which could modify the working tree (fixers without --dry-run)
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Container directory where unsaved buffers are synced, empty to analyze the files on disk only
+	ShadowWorkspace string `json:"shadowWorkspace,omitempty"`
+	// Directories scanned by the project wide tools (phpcpd), relative to the project root, the root when empty
+	Paths []string `json:"paths,omitempty"`
+	// PHP executables checking the syntax (php-parallel-lint), one run per version, the PATH php when empty
+	PhpBinaries []string `json:"phpBinaries,omitempty"`
+	// File suffixes of the documents analyzed by the document providers (YAML, Twig, Blade), replacing their default ones
+	FileExtensions []string `json:"fileExtensions,omitempty"`
+	// PHP versions the code must run on (PHPCompatibility testVersion), e.g. 7.4-8.3
+	TestVersion string `json:"testVersion,omitempty"`
+	// Cyclomatic complexity and lines of the functions above which PhpMetrics reports them, defaults when 0
+	MaxComplexity int `json:"maxComplexity,omitempty"`
+	MaxLines      int `json:"maxLines,omitempty"`
+	// Show the cyclomatic complexity of the functions as inlay hints (PhpMetrics)
+	InlayHints bool `json:"inlayHints,omitempty"`
+	// Numbers which are not magic (phpmnd), 0 and 1 when empty
+	IgnoreNumbers []string `json:"ignoreNumbers,omitempty"`
+	// Severity of the diagnostics of the tools reporting none (phpmnd), hint when empty
+	Severity string `json:"severity,omitempty"`
+	// Report format of a custom provider, a tool php-diagls has no provider for
+	OutputFormat string `json:"outputFormat,omitempty"`
+	// Arguments of a custom provider, followed by the file unless they hold a {file} placeholder
+	Arguments string `json:"arguments,omitempty"`
+
+	// Informed whenever the provider switches to another backend of its fallback chain, set by the server
+	BackendNotifier func(message string) `json:"-"`
+	// Root of the project the configuration was loaded for, set by LoadConfig
+	ProjectRoot string `json:"-"`
+}
+
+// ContainerNames returns the main container followed by the additional replicas
+func (provider DiagnosticsProvider) ContainerNames() []string {
+	names := []string{}
+	if provider.Container != "" {
+		names = append(names, provider.Container)
+	}
+	for _, name := range provider.Containers {
+		if name != provider.Container {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetProjectRoot records the project root in the configuration and in its providers, the root providers run
+// their commands in
+func (config *Config) SetProjectRoot(projectRoot string) {
+	config.ProjectRoot = projectRoot
+	for id, provider := range config.DiagnosticsProviders {
+		provider.ProjectRoot = projectRoot
+		config.DiagnosticsProviders[id] = provider
+	}
 }
 
 func (config *Config) IsInitialized() bool {
 	return config.initialized
 }
 
-func (config *Config) LoadConfig(projectRoot string) (*Config, error) {
-	configPath := filepath.Join(projectRoot, ConfigFileName)
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return config, fmt.Errorf("config file not found: %s", configPath)
+// SupportsFile reports whether the file extension is one of the configured PHP file extensions
+func (config *Config) SupportsFile(filePath string) bool {
+	extensions := config.FileExtensions
+	if len(extensions) == 0 {
+		extensions = DefaultFileExtensions
+	}
+
+	for _, extension := range extensions {
+		if strings.HasSuffix(filePath, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsLanguageId reports whether the document language id (as sent on didOpen) is handled
+func (config *Config) SupportsLanguageId(languageId string) bool {
+	languageIds := config.LanguageIds
+	if len(languageIds) == 0 {
+		languageIds = DefaultLanguageIds
+	}
+
+	for _, id := range languageIds {
+		if id == languageId {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupsCategory reports whether the rules of the category are grouped per file
+func (provider DiagnosticsProvider) GroupsCategory(category string) bool {
+	for _, grouped := range provider.GroupRules {
+		if grouped == category {
+			return true
+		}
+	}
+	return false
+}
+
+// IsManual reports whether the provider only runs when requested
+func (provider DiagnosticsProvider) IsManual() bool {
+	return provider.RunOn == RunOnManual
+}
+
+func IsValidFailOn(failOn string) bool {
+	return failOn == FailOnError || failOn == FailOnWarning || failOn == FailOnNone
+}
+
+func IsValidSeverity(severity string) bool {
+	return severity == SeverityError || severity == SeverityWarning || severity == SeverityInformation || severity == SeverityHint
+}
+
+// LoadConfig loads the first of the configuration files found for the project root
+// validateShadowWorkspace rejects the shadow directories whose removal, done before every copy of the project,
+// could delete the project: the container root, relative paths and the directories of the project or above it,
+// on the host or mapped into the container
+func validateShadowWorkspace(dir string, projectRoot string, pathMappings map[string]string) error {
+	if strings.TrimSpace(dir) == "" {
+		return fmt.Errorf("empty path")
+	}
+	if !path.IsAbs(dir) {
+		return fmt.Errorf("%s is not an absolute path", dir)
+	}
+	dir = path.Clean(dir)
+	if dir == "/" {
+		return fmt.Errorf("%s is the root directory", dir)
+	}
+
+	projectDirs := []string{filepath.ToSlash(projectRoot)}
+	for hostPath, containerPath := range pathMappings {
+		if relativePath, err := filepath.Rel(hostPath, projectRoot); err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			projectDirs = append(projectDirs, path.Join(containerPath, filepath.ToSlash(relativePath)))
+		}
+	}
+	for _, projectDir := range projectDirs {
+		if projectDir == dir || strings.HasPrefix(projectDir, dir+"/") {
+			return fmt.Errorf("%s holds the project directory %s", dir, projectDir)
+		}
+	}
+	return nil
+}
+
+func (config *Config) LoadConfig(projectRoot string, files Files) (*Config, error) {
+	configPath, exists := files.Find(projectRoot)
+	if !exists {
+		return config, fmt.Errorf("config file not found: %s", files.Path(projectRoot))
 	}
 
 	rawData, err := os.ReadFile(configPath)
@@ -54,17 +343,204 @@ func (config *Config) LoadConfig(projectRoot string) (*Config, error) {
 		return config, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	var containerName string
+	if rawContainer, exists := rawMap[ConfigItemContainer]; exists {
+		if err := json.Unmarshal(rawContainer, &containerName); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemContainer, err)
+		}
+	}
+
 	diagnosticsProvidersData := make(map[string]DiagnosticsProvider)
+	autoConfigure := false
+	var composeDetection *ComposeDetection
+	// Shadow directories as set, an empty one is rejected rather than disabling the shadow workspace
+	shadowWorkspaces := make(map[string]struct {
+		ShadowWorkspace *string `json:"shadowWorkspace"`
+	})
 	if rawProviders, exists := rawMap[ConfigItemDiagnosticsProviders]; exists {
 		if err := json.Unmarshal(rawProviders, &diagnosticsProvidersData); err != nil {
 			return config, fmt.Errorf("failed to parse diagnostics providers: %w", err)
 		}
+		if err := json.Unmarshal(rawProviders, &shadowWorkspaces); err != nil {
+			return config, fmt.Errorf("failed to parse diagnostics providers: %w", err)
+		}
+	} else if containerName != "" {
+		autoConfigure = true
 	} else {
-		return config, fmt.Errorf("no diagnostics providers configured (missing key %s)", ConfigItemDiagnosticsProviders)
+		if composeDetection, err = DetectComposeContainer(projectRoot); err != nil {
+			return config, err
+		}
+		if composeDetection == nil {
+			return config, fmt.Errorf("no diagnostics providers configured (missing key %s or %s, no PHP service found in a compose file)", ConfigItemDiagnosticsProviders, ConfigItemContainer)
+		}
+		containerName = composeDetection.Container
+		autoConfigure = true
+	}
+	for name, provider := range diagnosticsProvidersData {
+		if provider.Limits.Nice < 0 || provider.Limits.Nice > 19 {
+			return config, fmt.Errorf("invalid limits.nice for %s: %d (expected 0-19)", name, provider.Limits.Nice)
+		}
+		if provider.Limits.CpuLimit < 0 {
+			return config, fmt.Errorf("invalid limits.cpuLimit for %s: %d", name, provider.Limits.CpuLimit)
+		}
+		for _, pattern := range provider.IgnoreIdentifiers {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return config, fmt.Errorf("invalid ignoreIdentifiers pattern for %s: %s", name, pattern)
+			}
+		}
+		if provider.CircuitBreaker.Failures < 0 || provider.CircuitBreaker.CooldownSeconds < 0 {
+			return config, fmt.Errorf("invalid circuitBreaker for %s: failures and cooldownSeconds must be positive", name)
+		}
+		if provider.Watch.Enabled && provider.Watch.Command == "" {
+			return config, fmt.Errorf("invalid watch for %s: command is required when enabled", name)
+		}
+		if provider.TimeoutSeconds < 0 {
+			return config, fmt.Errorf("invalid timeoutSeconds for %s: %d", name, provider.TimeoutSeconds)
+		}
+		if provider.DebounceSeconds < 0 {
+			return config, fmt.Errorf("invalid debounceSeconds for %s: %d", name, provider.DebounceSeconds)
+		}
+		if provider.OutputFormat != "" && provider.OutputFormat != OutputFormatCheckstyle && provider.OutputFormat != OutputFormatSarif {
+			return config, fmt.Errorf("invalid outputFormat for %s: %s (expected %s or %s)", name, provider.OutputFormat, OutputFormatCheckstyle, OutputFormatSarif)
+		}
+		if provider.RunOn != "" && provider.RunOn != RunOnAuto && provider.RunOn != RunOnSave && provider.RunOn != RunOnManual {
+			return config, fmt.Errorf("invalid runOn for %s: %s (expected %s, %s or %s)", name, provider.RunOn, RunOnAuto, RunOnSave, RunOnManual)
+		}
+		for _, category := range provider.GroupRules {
+			if category != RuleCategoryCosmetic && category != RuleCategoryStructural {
+				return config, fmt.Errorf("invalid groupRules category for %s: %s (expected %s or %s)", name, category, RuleCategoryCosmetic, RuleCategoryStructural)
+			}
+		}
+		if provider.MaxComplexity < 0 || provider.MaxLines < 0 {
+			return config, fmt.Errorf("invalid thresholds for %s: maxComplexity and maxLines must be positive", name)
+		}
+		if provider.TestVersion != "" && !testVersionRegex.MatchString(provider.TestVersion) {
+			return config, fmt.Errorf("invalid testVersion for %s: %s (expected e.g. 8.1, 7.4- or 7.4-8.3)", name, provider.TestVersion)
+		}
+		if provider.Severity != "" && !IsValidSeverity(provider.Severity) {
+			return config, fmt.Errorf("invalid severity for %s: %s (expected %s, %s, %s or %s)", name, provider.Severity, SeverityError, SeverityWarning, SeverityInformation, SeverityHint)
+		}
+		for _, number := range provider.IgnoreNumbers {
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return config, fmt.Errorf("invalid ignoreNumbers for %s: %s is not a number", name, number)
+			}
+		}
+		if provider.CosmeticSeverity != "" && !IsValidSeverity(provider.CosmeticSeverity) {
+			return config, fmt.Errorf("invalid cosmeticSeverity for %s: %s (expected %s, %s, %s or %s)", name, provider.CosmeticSeverity, SeverityError, SeverityWarning, SeverityInformation, SeverityHint)
+		}
+	}
+
+	var fileExtensions []string
+	if rawExtensions, exists := rawMap[ConfigItemFileExtensions]; exists {
+		if err := json.Unmarshal(rawExtensions, &fileExtensions); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemFileExtensions, err)
+		}
+	}
+
+	var languageIds []string
+	if rawLanguageIds, exists := rawMap[ConfigItemLanguageIds]; exists {
+		if err := json.Unmarshal(rawLanguageIds, &languageIds); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemLanguageIds, err)
+		}
+	}
+
+	generatedMarkers := append([]string(nil), DefaultGeneratedMarkers...)
+	if rawMarkers, exists := rawMap[ConfigItemGeneratedMarkers]; exists {
+		generatedMarkers = nil
+		if err := json.Unmarshal(rawMarkers, &generatedMarkers); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemGeneratedMarkers, err)
+		}
+	}
+
+	var docker DockerConfig
+	if rawDocker, exists := rawMap[ConfigItemDocker]; exists {
+		if err := json.Unmarshal(rawDocker, &docker); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemDocker, err)
+		}
+	}
+
+	for name, provider := range shadowWorkspaces {
+		if provider.ShadowWorkspace == nil {
+			continue
+		}
+		if err := validateShadowWorkspace(*provider.ShadowWorkspace, projectRoot, docker.PathMappings); err != nil {
+			return config, fmt.Errorf("invalid shadowWorkspace for %s: %w", name, err)
+		}
+	}
+
+	maxOutputBytes := DefaultMaxOutputBytes
+	if rawMaxOutput, exists := rawMap[ConfigItemMaxOutputBytes]; exists {
+		if err := json.Unmarshal(rawMaxOutput, &maxOutputBytes); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemMaxOutputBytes, err)
+		}
+	}
+
+	failOn := FailOnWarning
+	if rawFailOn, exists := rawMap[ConfigItemFailOn]; exists {
+		if err := json.Unmarshal(rawFailOn, &failOn); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemFailOn, err)
+		}
+		if !IsValidFailOn(failOn) {
+			return config, fmt.Errorf("invalid %s: %s (expected %s, %s or %s)", ConfigItemFailOn, failOn, FailOnError, FailOnWarning, FailOnNone)
+		}
+	}
+
+	var messages map[string]string
+	if rawMessages, exists := rawMap[ConfigItemMessages]; exists {
+		if err := json.Unmarshal(rawMessages, &messages); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemMessages, err)
+		}
+	}
+
+	var formatChain []FormatStep
+	if rawChain, exists := rawMap[ConfigItemFormatChain]; exists {
+		if err := json.Unmarshal(rawChain, &formatChain); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemFormatChain, err)
+		}
+	}
+	for i, step := range formatChain {
+		if (step.Provider == "") == (step.Command == "") {
+			return config, fmt.Errorf("invalid %s step %d: expected either a provider or a command", ConfigItemFormatChain, i+1)
+		}
+		if step.Command != "" && step.Container == "" && containerName == "" {
+			return config, fmt.Errorf("invalid %s step %d: missing container", ConfigItemFormatChain, i+1)
+		}
+		if step.TimeoutSeconds < 0 {
+			return config, fmt.Errorf("invalid %s step %d: negative timeoutSeconds", ConfigItemFormatChain, i+1)
+		}
+	}
+
+	formatOnSave := false
+	if rawFormatOnSave, exists := rawMap[ConfigItemFormatOnSave]; exists {
+		if err := json.Unmarshal(rawFormatOnSave, &formatOnSave); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemFormatOnSave, err)
+		}
+	}
+
+	formatChangedLines := ""
+	if rawChangedLines, exists := rawMap[ConfigItemFormatChangedLines]; exists {
+		if err := json.Unmarshal(rawChangedLines, &formatChangedLines); err != nil {
+			return config, fmt.Errorf("failed to parse %s: %w", ConfigItemFormatChangedLines, err)
+		}
 	}
 
+	config.path = configPath
 	config.RawData = rawData
 	config.DiagnosticsProviders = diagnosticsProvidersData
+	config.FileExtensions = fileExtensions
+	config.LanguageIds = languageIds
+	config.GeneratedMarkers = generatedMarkers
+	config.Docker = docker
+	config.MaxOutputBytes = maxOutputBytes
+	config.FailOn = failOn
+	config.Messages = messages
+	config.FormatChain = formatChain
+	config.FormatOnSave = formatOnSave
+	config.FormatChangedLines = formatChangedLines
+	config.Container = containerName
+	config.AutoConfigure = autoConfigure
+	config.ComposeDetection = composeDetection
+	config.SetProjectRoot(projectRoot)
 	config.initialized = true
 
 	return config, nil
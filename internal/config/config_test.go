@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 )
@@ -120,7 +121,7 @@ func TestConfig_LoadConfig(t *testing.T) {
 
 			// Test loading config
 			cfg := &config.Config{}
-			result, err := cfg.LoadConfig(tempDir)
+			result, err := cfg.LoadConfig(tempDir, config.DefaultFiles)
 
 			if tt.expectedError {
 				if err == nil {
@@ -182,7 +183,7 @@ func TestConfig_LoadConfig(t *testing.T) {
 
 func TestConfig_LoadConfig_FileNotFound(t *testing.T) {
 	cfg := &config.Config{}
-	_, err := cfg.LoadConfig("/non/existent/path")
+	_, err := cfg.LoadConfig("/non/existent/path", config.DefaultFiles)
 
 	if err == nil {
 		t.Error("Expected error for non-existent config file")
@@ -217,7 +218,7 @@ func TestConfig_IsInitialized(t *testing.T) {
 		t.Fatalf("Failed to create test config file: %v", err)
 	}
 
-	_, err := cfg.LoadConfig(tempDir)
+	_, err := cfg.LoadConfig(tempDir, config.DefaultFiles)
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
@@ -227,6 +228,627 @@ func TestConfig_IsInitialized(t *testing.T) {
 	}
 }
 
+func TestConfig_FileExtensionsAndLanguageIds(t *testing.T) {
+	t.Run("defaults when not configured", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{
+			"diagnosticsProviders": {
+				"phplint": {"enabled": true, "container": "php", "path": "/usr/local/bin/php"}
+			}
+		}`)
+
+		if !cfg.SupportsFile("/app/src/index.php") {
+			t.Error("Expected .php files to be supported by default")
+		}
+		if cfg.SupportsFile("/app/templates/page.phtml") {
+			t.Error("Expected .phtml files not to be supported by default")
+		}
+		if !cfg.SupportsLanguageId("php") {
+			t.Error("Expected php language id to be supported by default")
+		}
+	})
+
+	t.Run("configured values", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{
+			"fileExtensions": [".php", ".module", ".inc"],
+			"languageIds": ["php", "drupal"],
+			"diagnosticsProviders": {
+				"phplint": {"enabled": true, "container": "php", "path": "/usr/local/bin/php"}
+			}
+		}`)
+
+		for _, file := range []string{"/app/index.php", "/app/custom.module", "/app/legacy.inc"} {
+			if !cfg.SupportsFile(file) {
+				t.Errorf("Expected %s to be supported", file)
+			}
+		}
+		if cfg.SupportsFile("/app/README.md") {
+			t.Error("Expected .md files not to be supported")
+		}
+		if !cfg.SupportsLanguageId("drupal") {
+			t.Error("Expected drupal language id to be supported")
+		}
+		if cfg.SupportsLanguageId("javascript") {
+			t.Error("Expected javascript language id not to be supported")
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		tempDir := t.TempDir()
+		configPath := filepath.Join(tempDir, config.ConfigFileName)
+		content := `{"fileExtensions": ".php", "diagnosticsProviders": {}}`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "failed to parse fileExtensions") {
+			t.Errorf("Expected fileExtensions parse error, got: %v", err)
+		}
+	})
+}
+
+func TestConfig_GeneratedMarkers(t *testing.T) {
+	t.Run("defaults when not configured", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {}}`)
+
+		if len(cfg.GeneratedMarkers) != len(config.DefaultGeneratedMarkers) {
+			t.Errorf("Expected default markers %v, got %v", config.DefaultGeneratedMarkers, cfg.GeneratedMarkers)
+		}
+	})
+
+	t.Run("empty list disables detection", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"generatedMarkers": [], "diagnosticsProviders": {}}`)
+
+		if len(cfg.GeneratedMarkers) != 0 {
+			t.Errorf("Expected no markers, got %v", cfg.GeneratedMarkers)
+		}
+	})
+
+	t.Run("custom markers", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"generatedMarkers": ["DO NOT EDIT"], "diagnosticsProviders": {}}`)
+
+		if len(cfg.GeneratedMarkers) != 1 || cfg.GeneratedMarkers[0] != "DO NOT EDIT" {
+			t.Errorf("Expected [DO NOT EDIT], got %v", cfg.GeneratedMarkers)
+		}
+	})
+}
+
+func TestConfig_Docker(t *testing.T) {
+	cfg := loadTestConfig(t, `{
+		"docker": {
+			"host": "tcp://build-box:2376",
+			"tlsVerify": true,
+			"certPath": "/home/dev/.docker/remote",
+			"pathMappings": {"/home/dev/app": "/var/www/html"}
+		},
+		"diagnosticsProviders": {}
+	}`)
+
+	if cfg.Docker.Host != "tcp://build-box:2376" || !cfg.Docker.TlsVerify || cfg.Docker.CertPath != "/home/dev/.docker/remote" {
+		t.Errorf("Unexpected docker config: %+v", cfg.Docker)
+	}
+	if cfg.Docker.PathMappings["/home/dev/app"] != "/var/www/html" {
+		t.Errorf("Expected path mapping to /var/www/html, got %v", cfg.Docker.PathMappings)
+	}
+}
+
+func TestConfig_FailOn(t *testing.T) {
+	t.Run("defaults to warning", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {}}`)
+
+		if cfg.FailOn != config.FailOnWarning {
+			t.Errorf("Expected %s, got %s", config.FailOnWarning, cfg.FailOn)
+		}
+	})
+
+	t.Run("configured policy", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"failOn": "error", "diagnosticsProviders": {}}`)
+
+		if cfg.FailOn != config.FailOnError {
+			t.Errorf("Expected %s, got %s", config.FailOnError, cfg.FailOn)
+		}
+	})
+
+	t.Run("rejects unknown policy", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"failOn": "notice", "diagnosticsProviders": {}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		if _, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles); err == nil {
+			t.Error("Expected an error for an unknown failOn value")
+		}
+	})
+}
+
+func TestConfig_Resolve(t *testing.T) {
+	cfg := loadTestConfig(t, `{
+		"failOn": "error",
+		"diagnosticsProviders": {"phpstan": {"enabled": true, "container": "php", "limits": {"nice": 5}}}
+	}`)
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Failed to resolve config: %v", err)
+	}
+
+	values := make(map[string]config.ResolvedValue)
+	for _, value := range resolved {
+		values[value.Key] = value
+	}
+
+	tests := []struct {
+		key         string
+		value       interface{}
+		fromDefault bool
+	}{
+		{"failOn", "error", false},
+		{"diagnosticsProviders.phpstan.container", "php", false},
+		{"diagnosticsProviders.phpstan.limits.nice", 5, false},
+		{"diagnosticsProviders.phpstan.limits.cpuLimit", 0, true},
+		{"diagnosticsProviders.phpstan.readOnly", false, true},
+		{"maxOutputBytes", config.DefaultMaxOutputBytes, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, exists := values[tt.key]
+			if !exists {
+				t.Fatalf("Expected %s to be resolved", tt.key)
+			}
+			if value.Value != tt.value {
+				t.Errorf("Expected value %v, got %v", tt.value, value.Value)
+			}
+			if (value.Source == config.SourceDefault) != tt.fromDefault {
+				t.Errorf("Expected default source %v, got %s", tt.fromDefault, value.Source)
+			}
+			if !tt.fromDefault && !containsString(value.Source, config.ConfigFileName) {
+				t.Errorf("Expected the config file as source, got %s", value.Source)
+			}
+		})
+	}
+}
+
+func TestConfig_Limits(t *testing.T) {
+	t.Run("parses limits", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"limits": {"nice": 10, "cpuLimit": 50}}}}`)
+
+		limits := cfg.DiagnosticsProviders["phpstan"].Limits
+		if limits.Nice != 10 || limits.CpuLimit != 50 {
+			t.Errorf("Expected nice 10 and cpuLimit 50, got %+v", limits)
+		}
+	})
+
+	t.Run("rejects out of range niceness", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"limits": {"nice": 25}}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "limits.nice") {
+			t.Errorf("Expected limits.nice error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_ShadowWorkspace(t *testing.T) {
+	t.Run("parses the directory", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"shadowWorkspace": "/tmp/php-diagls-shadow"}}}`)
+
+		if dir := cfg.DiagnosticsProviders["phpstan"].ShadowWorkspace; dir != "/tmp/php-diagls-shadow" {
+			t.Errorf("Expected /tmp/php-diagls-shadow, got %s", dir)
+		}
+	})
+
+	// The directory is removed before every copy of the project
+	projectRoot := filepath.Join(t.TempDir(), "project")
+	tests := []struct {
+		name   string
+		dir    string
+		docker string
+	}{
+		{"empty", "", ""},
+		{"root", "/", ""},
+		{"relative", "tmp/shadow", ""},
+		{"project directory", projectRoot, ""},
+		{"above the project directory", filepath.Dir(projectRoot), ""},
+		{"mapped project directory", "/app", `"docker": {"pathMappings": {"` + projectRoot + `": "/app"}},`},
+		{"above the mapped project directory", "/srv/", `"docker": {"pathMappings": {"` + filepath.Dir(projectRoot) + `": "/srv/www"}},`},
+	}
+
+	for _, tt := range tests {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			if err := os.MkdirAll(projectRoot, 0755); err != nil {
+				t.Fatal(err)
+			}
+			content := `{` + tt.docker + `"diagnosticsProviders": {"phpstan": {"shadowWorkspace": "` + tt.dir + `"}}}`
+			if err := os.WriteFile(filepath.Join(projectRoot, config.ConfigFileName), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test config file: %v", err)
+			}
+
+			_, err := (&config.Config{}).LoadConfig(projectRoot, config.DefaultFiles)
+			if err == nil || !containsString(err.Error(), "invalid shadowWorkspace for phpstan") {
+				t.Errorf("Expected shadowWorkspace %q rejected, got %v", tt.dir, err)
+			}
+		})
+	}
+}
+
+func TestConfig_CosmeticSeverity(t *testing.T) {
+	t.Run("parses severity", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpcsfixer": {"cosmeticSeverity": "warning"}}}`)
+
+		if severity := cfg.DiagnosticsProviders["phpcsfixer"].CosmeticSeverity; severity != config.SeverityWarning {
+			t.Errorf("Expected %s, got %s", config.SeverityWarning, severity)
+		}
+	})
+
+	t.Run("rejects unknown severity", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpcsfixer": {"cosmeticSeverity": "notice"}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "cosmeticSeverity") {
+			t.Errorf("Expected cosmeticSeverity error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_IgnoreNumbers(t *testing.T) {
+	t.Run("parses numbers and severity", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpmnd": {"ignoreNumbers": ["0", "1", "-1", "0.5"], "severity": "warning"}}}`)
+
+		provider := cfg.DiagnosticsProviders["phpmnd"]
+		if len(provider.IgnoreNumbers) != 4 || provider.Severity != config.SeverityWarning {
+			t.Errorf("Expected 4 numbers and warning, got %v and %s", provider.IgnoreNumbers, provider.Severity)
+		}
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		for content, expected := range map[string]string{
+			`{"diagnosticsProviders": {"phpmnd": {"ignoreNumbers": ["one"]}}}`: "ignoreNumbers",
+			`{"diagnosticsProviders": {"phpmnd": {"severity": "notice"}}}`:     "severity",
+		} {
+			tempDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test config file: %v", err)
+			}
+
+			_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+			if err == nil || !containsString(err.Error(), expected) {
+				t.Errorf("Expected %s error, got %v", expected, err)
+			}
+		}
+	})
+}
+
+func TestConfig_OutputFormat(t *testing.T) {
+	t.Run("parses format and arguments", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpcs": {"outputFormat": "checkstyle", "arguments": "--report=checkstyle"}}}`)
+
+		provider := cfg.DiagnosticsProviders["phpcs"]
+		if provider.OutputFormat != config.OutputFormatCheckstyle || provider.Arguments != "--report=checkstyle" {
+			t.Errorf("Expected checkstyle and its arguments, got %s and %s", provider.OutputFormat, provider.Arguments)
+		}
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpcs": {"outputFormat": "junit"}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "outputFormat") {
+			t.Errorf("Expected outputFormat error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_GroupRules(t *testing.T) {
+	t.Run("parses categories", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpcsfixer": {"groupRules": ["cosmetic"]}}}`)
+
+		provider := cfg.DiagnosticsProviders["phpcsfixer"]
+		if !provider.GroupsCategory(config.RuleCategoryCosmetic) || provider.GroupsCategory(config.RuleCategoryStructural) {
+			t.Errorf("Expected only cosmetic rules grouped, got %v", provider.GroupRules)
+		}
+	})
+
+	t.Run("rejects unknown category", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpcsfixer": {"groupRules": ["style"]}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "groupRules") {
+			t.Errorf("Expected groupRules error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_IgnoreIdentifiers(t *testing.T) {
+	t.Run("parses patterns", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"ignoreIdentifiers": ["missingType.*", "argument.type"]}}}`)
+
+		if patterns := cfg.DiagnosticsProviders["phpstan"].IgnoreIdentifiers; len(patterns) != 2 || patterns[0] != "missingType.*" {
+			t.Errorf("Expected the configured patterns, got %v", patterns)
+		}
+	})
+
+	t.Run("rejects malformed pattern", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"ignoreIdentifiers": ["missingType.[a"]}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "ignoreIdentifiers") {
+			t.Errorf("Expected ignoreIdentifiers error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_RunOn(t *testing.T) {
+	t.Run("parses manual mode", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"runOn": "manual"}, "phplint": {}}}`)
+
+		if !cfg.DiagnosticsProviders["phpstan"].IsManual() {
+			t.Error("Expected phpstan to be manual")
+		}
+		if cfg.DiagnosticsProviders["phplint"].IsManual() {
+			t.Error("Expected phplint to run automatically by default")
+		}
+	})
+
+	t.Run("parses save mode", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"runOn": "save"}}}`)
+
+		if cfg.DiagnosticsProviders["phpstan"].RunOn != config.RunOnSave || cfg.DiagnosticsProviders["phpstan"].IsManual() {
+			t.Errorf("Expected phpstan to run on save, got %+v", cfg.DiagnosticsProviders["phpstan"])
+		}
+	})
+
+	t.Run("rejects unknown mode", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"runOn": "commit"}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "runOn") {
+			t.Errorf("Expected runOn error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_TestVersion(t *testing.T) {
+	cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpcompatibility": {"testVersion": "7.4-8.3"}}}`)
+	if cfg.DiagnosticsProviders["phpcompatibility"].TestVersion != "7.4-8.3" {
+		t.Errorf("Expected testVersion 7.4-8.3, got %+v", cfg.DiagnosticsProviders["phpcompatibility"])
+	}
+
+	tempDir := t.TempDir()
+	content := `{"diagnosticsProviders": {"phpcompatibility": {"testVersion": "php8"}}}`
+	if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+	if err == nil || !containsString(err.Error(), "testVersion") {
+		t.Errorf("Expected testVersion error, got %v", err)
+	}
+}
+
+func TestConfig_Container(t *testing.T) {
+	t.Run("container only enables auto-configuration", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"container": "php"}`)
+
+		if !cfg.AutoConfigure || cfg.Container != "php" {
+			t.Errorf("Expected auto-configuration in container php, got %v %q", cfg.AutoConfigure, cfg.Container)
+		}
+		if len(cfg.DiagnosticsProviders) != 0 {
+			t.Errorf("Expected no configured providers, got %v", cfg.DiagnosticsProviders)
+		}
+	})
+
+	t.Run("configured providers win", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"container": "php", "diagnosticsProviders": {"phplint": {"enabled": true}}}`)
+
+		if cfg.AutoConfigure {
+			t.Error("Expected no auto-configuration with configured providers")
+		}
+	})
+
+	t.Run("detected providers source", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"container": "php"}`)
+		cfg.DiagnosticsProviders["phplint"] = config.DiagnosticsProvider{Enabled: true, Container: "php", Path: "php"}
+
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		for _, value := range resolved {
+			if value.Key == "diagnosticsProviders.phplint.path" && value.Source != config.SourceDetected {
+				t.Errorf("Expected source %s, got %s", config.SourceDetected, value.Source)
+			}
+		}
+	})
+}
+
+func TestConfig_TimeoutSeconds(t *testing.T) {
+	t.Run("parses timeout", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"timeoutSeconds": 30}}}`)
+
+		if timeout := cfg.DiagnosticsProviders["phpstan"].TimeoutSeconds; timeout != 30 {
+			t.Errorf("Expected 30, got %d", timeout)
+		}
+	})
+
+	t.Run("rejects negative timeout", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"timeoutSeconds": -1}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "timeoutSeconds") {
+			t.Errorf("Expected timeoutSeconds error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_MetricsThresholds(t *testing.T) {
+	t.Run("parses thresholds", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpmetrics": {"maxComplexity": 15, "maxLines": 80}}}`)
+
+		if provider := cfg.DiagnosticsProviders["phpmetrics"]; provider.MaxComplexity != 15 || provider.MaxLines != 80 {
+			t.Errorf("Expected 15 and 80, got %d and %d", provider.MaxComplexity, provider.MaxLines)
+		}
+	})
+
+	t.Run("rejects negative threshold", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpmetrics": {"maxLines": -1}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "maxLines") {
+			t.Errorf("Expected maxLines error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_DebounceSeconds(t *testing.T) {
+	t.Run("parses debounce", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"infection": {"runOn": "save", "debounceSeconds": 120}}}`)
+
+		if debounce := cfg.DiagnosticsProviders["infection"].DebounceSeconds; debounce != 120 {
+			t.Errorf("Expected 120, got %d", debounce)
+		}
+	})
+
+	t.Run("rejects negative debounce", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"infection": {"debounceSeconds": -1}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "debounceSeconds") {
+			t.Errorf("Expected debounceSeconds error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_Watch(t *testing.T) {
+	t.Run("parses the watch command", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"watch": {"enabled": true, "command": "bin/phpstan-watch"}}}}`)
+
+		if watch := cfg.DiagnosticsProviders["phpstan"].Watch; !watch.Enabled || watch.Command != "bin/phpstan-watch" {
+			t.Errorf("Expected the watch command enabled, got %+v", watch)
+		}
+	})
+
+	t.Run("rejects an enabled watch without command", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"watch": {"enabled": true}}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "watch") {
+			t.Errorf("Expected watch error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_CircuitBreaker(t *testing.T) {
+	t.Run("parses settings", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {"phpstan": {"circuitBreaker": {"failures": 3, "cooldownSeconds": 120}}, "phplint": {"circuitBreaker": {"failures": 3}}}}`)
+
+		breaker := cfg.DiagnosticsProviders["phpstan"].CircuitBreaker
+		if breaker.Failures != 3 || breaker.Cooldown() != 2*time.Minute {
+			t.Errorf("Expected 3 failures and a 2m cooldown, got %d and %v", breaker.Failures, breaker.Cooldown())
+		}
+		if cooldown := cfg.DiagnosticsProviders["phplint"].CircuitBreaker.Cooldown(); cooldown != time.Minute {
+			t.Errorf("Expected the default 1m cooldown, got %v", cooldown)
+		}
+	})
+
+	t.Run("rejects negative settings", func(t *testing.T) {
+		tempDir := t.TempDir()
+		content := `{"diagnosticsProviders": {"phpstan": {"circuitBreaker": {"failures": -1}}}}`
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+		if err == nil || !containsString(err.Error(), "circuitBreaker") {
+			t.Errorf("Expected circuitBreaker error, got %v", err)
+		}
+	})
+}
+
+func TestDiagnosticsProvider_ContainerNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider config.DiagnosticsProvider
+		expected []string
+	}{
+		{
+			name:     "single container",
+			provider: config.DiagnosticsProvider{Container: "php"},
+			expected: []string{"php"},
+		},
+		{
+			name:     "container with replicas",
+			provider: config.DiagnosticsProvider{Container: "php-1", Containers: []string{"php-2", "php-3"}},
+			expected: []string{"php-1", "php-2", "php-3"},
+		},
+		{
+			name:     "main container repeated in replicas",
+			provider: config.DiagnosticsProvider{Container: "php-1", Containers: []string{"php-1", "php-2"}},
+			expected: []string{"php-1", "php-2"},
+		},
+		{
+			name:     "replicas only",
+			provider: config.DiagnosticsProvider{Containers: []string{"php-1", "php-2"}},
+			expected: []string{"php-1", "php-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.provider.ContainerNames()
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, result)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
 func TestConstants(t *testing.T) {
 	if config.Name == "" {
 		t.Error("Name constant should not be empty")
@@ -257,7 +879,68 @@ func TestConstants(t *testing.T) {
 	}
 }
 
+// Helper function to load a config from the given content
+func loadTestConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, config.ConfigFileName)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	cfg, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	return cfg
+}
+
 // Helper function to check if a string contains a substring
 func containsString(s, substr string) bool {
 	return len(substr) == 0 || len(s) >= len(substr) && (s == substr || containsString(s[1:], substr) || (len(s) > 0 && s[:len(substr)] == substr))
 }
+
+func TestConfig_FormatOnSave(t *testing.T) {
+	if cfg := loadTestConfig(t, `{"diagnosticsProviders": {}}`); cfg.FormatOnSave {
+		t.Error("Expected formatOnSave disabled by default")
+	}
+	if cfg := loadTestConfig(t, `{"diagnosticsProviders": {}, "formatOnSave": true}`); !cfg.FormatOnSave {
+		t.Error("Expected formatOnSave enabled")
+	}
+}
+
+func TestConfig_FormatChangedLines(t *testing.T) {
+	if cfg := loadTestConfig(t, `{"diagnosticsProviders": {}}`); cfg.FormatChangedLines != "" {
+		t.Errorf("Expected no formatChangedLines by default, got %q", cfg.FormatChangedLines)
+	}
+	if cfg := loadTestConfig(t, `{"diagnosticsProviders": {}, "formatChangedLines": "origin/main"}`); cfg.FormatChangedLines != "origin/main" {
+		t.Errorf("Expected formatChangedLines origin/main, got %q", cfg.FormatChangedLines)
+	}
+}
+
+func TestConfig_FormatChain(t *testing.T) {
+	t.Run("parses steps", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"diagnosticsProviders": {}, "formatChain": [{"name": "rector", "command": "rector-stdin", "container": "php"}, {"provider": "phpcsfixer", "timeoutSeconds": 20}]}`)
+
+		if len(cfg.FormatChain) != 2 || cfg.FormatChain[0].Name != "rector" || cfg.FormatChain[1].TimeoutSeconds != 20 {
+			t.Errorf("Unexpected chain: %+v", cfg.FormatChain)
+		}
+	})
+
+	for _, content := range []string{
+		`{"diagnosticsProviders": {}, "formatChain": [{"provider": "phpcsfixer", "command": "cat"}]}`,
+		`{"diagnosticsProviders": {}, "formatChain": [{"command": "cat"}]}`,
+		`{"diagnosticsProviders": {}, "formatChain": [{"provider": "phpcsfixer", "timeoutSeconds": -1}]}`,
+	} {
+		tempDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tempDir, config.ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		if _, err := (&config.Config{}).LoadConfig(tempDir, config.DefaultFiles); err == nil || !containsString(err.Error(), "formatChain") {
+			t.Errorf("Expected a formatChain error for %s, got %v", content, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files are the configuration files of the projects: paths relative to the project root in lookup order, or
+// a single absolute path configuring the projects wherever it is
+type Files []string
+
+// DefaultFiles searches .php-diagls.json in the project root
+var DefaultFiles = Files{ConfigFileName}
+
+// ParseFiles cleans the configuration file paths, e.g. to keep the configuration under tools/. Relative paths
+// can't leave the project root and an absolute path must be the only one. Without path, DefaultFiles is used.
+func ParseFiles(paths []string) (Files, error) {
+	files := Files{}
+	for _, name := range paths {
+		if name == "" {
+			continue
+		}
+		name = filepath.Clean(name)
+		if name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("invalid config file %s: expected a path inside the project root or an absolute path", name)
+		}
+		files = append(files, name)
+	}
+
+	switch {
+	case len(files) == 0:
+		return DefaultFiles, nil
+	case len(files) > 1:
+		for _, name := range files {
+			if filepath.IsAbs(name) {
+				return nil, fmt.Errorf("invalid config file %s: an absolute path can't be searched with other paths", name)
+			}
+		}
+	}
+	return files, nil
+}
+
+// names returns the paths to search, DefaultFiles for the zero value
+func (files Files) names() []string {
+	if len(files) == 0 {
+		return DefaultFiles
+	}
+	return files
+}
+
+// Absolute returns the configuration file used by every project, false when the paths are relative to the
+// project roots
+func (files Files) Absolute() (string, bool) {
+	names := files.names()
+	if filepath.IsAbs(names[0]) {
+		return names[0], true
+	}
+	return "", false
+}
+
+// Path returns the path of the configuration file of the project, the first one searched
+func (files Files) Path(projectRoot string) string {
+	if absolute, ok := files.Absolute(); ok {
+		return absolute
+	}
+	return filepath.Join(projectRoot, files.names()[0])
+}
+
+// Find returns the first configuration file existing in the directory
+func (files Files) Find(dir string) (string, bool) {
+	if absolute, ok := files.Absolute(); ok {
+		if info, err := os.Stat(absolute); err == nil && !info.IsDir() {
+			return absolute, true
+		}
+		return "", false
+	}
+
+	for _, name := range files.names() {
+		configPath := filepath.Join(dir, name)
+		if info, err := os.Stat(configPath); err == nil && !info.IsDir() {
+			return configPath, true
+		}
+	}
+	return "", false
+}
+
+// Root returns the project root of a configuration file path, false when the path is not a configuration
+// file or the configuration file is absolute. The longest matching name wins, tools/php-diagls.json over
+// php-diagls.json.
+func (files Files) Root(filePath string) (string, bool) {
+	if _, ok := files.Absolute(); ok {
+		return "", false
+	}
+
+	root, found := "", false
+	for _, name := range files.names() {
+		suffix := string(filepath.Separator) + name
+		if strings.HasSuffix(filePath, suffix) && (!found || len(filePath)-len(suffix) < len(root)) {
+			root, found = strings.TrimSuffix(filePath, suffix), true
+		}
+	}
+	return root, found
+}
+
+// IsConfigFile reports whether the path is one of the configuration files
+func (files Files) IsConfigFile(filePath string) bool {
+	if absolute, ok := files.Absolute(); ok {
+		return filepath.Clean(filePath) == absolute
+	}
+	_, found := files.Root(filePath)
+	return found
+}
+
+// Names returns the configuration file paths searched, for messages
+func (files Files) Names() []string {
+	return append([]string(nil), files.names()...)
+}
@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+func TestParseFiles(t *testing.T) {
+	for _, paths := range [][]string{{"../php-diagls.json"}, {"/etc/php-diagls.json", "php-diagls.json"}} {
+		if _, err := config.ParseFiles(paths); err == nil {
+			t.Errorf("Expected %v to be rejected", paths)
+		}
+	}
+
+	files, err := config.ParseFiles([]string{"tools/./php-diagls.json", ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if names := files.Names(); len(names) != 1 || names[0] != filepath.Join("tools", "php-diagls.json") {
+		t.Errorf("Expected the cleaned name, got %v", names)
+	}
+
+	files, err = config.ParseFiles([]string{""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if names := files.Names(); len(names) != 1 || names[0] != config.ConfigFileName {
+		t.Errorf("Expected the default name, got %v", names)
+	}
+}
+
+func TestConfig_LoadConfig_Files(t *testing.T) {
+	projectRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectRoot, "tools"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(projectRoot, "tools", "php-diagls.json")
+	if err := os.WriteFile(configPath, []byte(`{"diagnosticsProviders": {"phplint": {"enabled": true}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := config.ParseFiles([]string{"php-diagls.json", "tools/php-diagls.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := (&config.Config{}).LoadConfig(projectRoot, files)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.DiagnosticsProviders["phplint"].Enabled {
+		t.Errorf("Expected the tools/ configuration, got %v", cfg.DiagnosticsProviders)
+	}
+	// The config directory itself is not the project root
+	if root := cfg.DiagnosticsProviders["phplint"].ProjectRoot; root != projectRoot {
+		t.Errorf("Expected the providers to run in %s, got %s", projectRoot, root)
+	}
+
+	if root, ok := files.Root(configPath); !ok || root != projectRoot {
+		t.Errorf("Expected root %s, got %s (%v)", projectRoot, root, ok)
+	}
+	if files.IsConfigFile(filepath.Join(projectRoot, config.ConfigFileName)) {
+		t.Error("Expected the default name not to be a config file anymore")
+	}
+	if _, err := (&config.Config{}).LoadConfig(projectRoot, config.DefaultFiles); err == nil {
+		t.Error("Expected the default files not to find the tools/ configuration")
+	}
+}
+
+func TestConfig_LoadConfig_AbsoluteFile(t *testing.T) {
+	projectRoot := t.TempDir()
+	configPath := filepath.Join(t.TempDir(), "php-diagls.json")
+	if err := os.WriteFile(configPath, []byte(`{"diagnosticsProviders": {"phplint": {"enabled": true}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := config.ParseFiles([]string{configPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg, err := (&config.Config{}).LoadConfig(projectRoot, files)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if root := cfg.DiagnosticsProviders["phplint"].ProjectRoot; root != projectRoot {
+		t.Errorf("Expected the providers to run in %s, got %s", projectRoot, root)
+	}
+	if !files.IsConfigFile(configPath) {
+		t.Errorf("Expected %s to be the config file", configPath)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Catalog used when none matches the client locale
+const DefaultMessageLocale string = "default"
+
+// MessageCatalog maps rule ids to the message reported instead of the tool message, which the
+// {message} placeholder inserts
+type MessageCatalog map[string]string
+
+// LoadMessageCatalog reads the catalog configured for the locale, else for its language (fr for fr-CA),
+// else the default one. Catalog paths are relative to the project root. No catalog is returned when
+// none is configured for the locale.
+func (config *Config) LoadMessageCatalog(projectRoot string, locale string) (MessageCatalog, error) {
+	catalogPath, exists := messageCatalogPath(config.Messages, locale)
+	if !exists {
+		return nil, nil
+	}
+	if !filepath.IsAbs(catalogPath) {
+		catalogPath = filepath.Join(projectRoot, catalogPath)
+	}
+
+	data, err := os.ReadFile(catalogPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message catalog: %w", err)
+	}
+	var catalog MessageCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse message catalog %s: %w", catalogPath, err)
+	}
+	return catalog, nil
+}
+
+func messageCatalogPath(catalogs map[string]string, locale string) (string, bool) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language, _, _ := strings.Cut(locale, "-")
+	for _, candidate := range []string{locale, language, DefaultMessageLocale} {
+		for catalogLocale, catalogPath := range catalogs {
+			if candidate != "" && strings.ToLower(strings.ReplaceAll(catalogLocale, "_", "-")) == candidate {
+				return catalogPath, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,65 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+func TestConfig_LoadMessageCatalog(t *testing.T) {
+	projectRoot := t.TempDir()
+	files := map[string]string{
+		config.ConfigFileName: `{"diagnosticsProviders": {}, "messages": {"default": "messages/en.json", "fr": "messages/fr.json"}}`,
+		"messages/en.json":    `{"array_syntax": "Use [] instead of array()"}`,
+		"messages/fr.json":    `{"array_syntax": "Utilisez [] au lieu de array()"}`,
+	}
+	for name, content := range files {
+		filePath := filepath.Join(projectRoot, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg, err := (&config.Config{}).LoadConfig(projectRoot, config.DefaultFiles)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"fr", "Utilisez [] au lieu de array()"},
+		{"fr-CA", "Utilisez [] au lieu de array()"},
+		{"de", "Use [] instead of array()"},
+		{"", "Use [] instead of array()"},
+	}
+	for _, tt := range tests {
+		catalog, err := cfg.LoadMessageCatalog(projectRoot, tt.locale)
+		if err != nil {
+			t.Fatalf("LoadMessageCatalog(%q) failed: %v", tt.locale, err)
+		}
+		if catalog["array_syntax"] != tt.expected {
+			t.Errorf("Locale %q: expected %q, got %q", tt.locale, tt.expected, catalog["array_syntax"])
+		}
+	}
+
+	t.Run("no catalog", func(t *testing.T) {
+		catalog, err := (&config.Config{}).LoadMessageCatalog(projectRoot, "fr")
+		if err != nil || catalog != nil {
+			t.Errorf("Expected no catalog, got %v, %v", catalog, err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		cfg := &config.Config{Messages: map[string]string{"default": "missing.json"}}
+		if _, err := cfg.LoadMessageCatalog(projectRoot, "en"); err == nil {
+			t.Error("Expected an error for a missing catalog")
+		}
+	})
+}
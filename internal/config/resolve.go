@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// SourceDefault marks values which are not set in any configuration file
+	SourceDefault = "default"
+	// SourceDetected marks the providers detected in the project, see Config.AutoConfigure
+	SourceDetected = "detected"
+)
+
+// ResolvedValue is one leaf of the effective configuration with the layer it comes from
+type ResolvedValue struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// Resolve returns the effective configuration, one entry per leaf value sorted by key. Values set in
+// the configuration file are annotated with its path, the others with SourceDefault.
+func (config *Config) Resolve() ([]ResolvedValue, error) {
+	effective := map[string]interface{}{
+		ConfigItemDiagnosticsProviders: config.DiagnosticsProviders,
+		ConfigItemFileExtensions:       orDefault(config.FileExtensions, DefaultFileExtensions),
+		ConfigItemLanguageIds:          orDefault(config.LanguageIds, DefaultLanguageIds),
+		ConfigItemGeneratedMarkers:     config.GeneratedMarkers,
+		ConfigItemDocker:               config.Docker,
+		ConfigItemMaxOutputBytes:       config.MaxOutputBytes,
+		ConfigItemFailOn:               config.FailOn,
+		ConfigItemMessages:             config.Messages,
+		ConfigItemFormatChain:          config.FormatChain,
+		ConfigItemFormatOnSave:         config.FormatOnSave,
+		ConfigItemFormatChangedLines:   config.FormatChangedLines,
+		ConfigItemContainer:            config.Container,
+	}
+
+	values := make(map[string]interface{})
+	flatten("", reflect.ValueOf(effective), values)
+
+	setInFile := make(map[string]bool)
+	if len(config.RawData) > 0 {
+		var raw interface{}
+		if err := json.Unmarshal(config.RawData, &raw); err != nil {
+			return nil, err
+		}
+		flatten("", reflect.ValueOf(raw), setInFile)
+	}
+
+	resolved := make([]ResolvedValue, 0, len(values))
+	for key, value := range values {
+		source := SourceDefault
+		if _, exists := setInFile[key]; exists {
+			source = config.path
+		} else if config.AutoConfigure && strings.HasPrefix(key, ConfigItemDiagnosticsProviders+".") ||
+			config.ComposeDetection != nil && key == ConfigItemContainer {
+			source = SourceDetected
+		}
+		resolved = append(resolved, ResolvedValue{Key: key, Value: value, Source: source})
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].Key < resolved[j].Key })
+
+	return resolved, nil
+}
+
+func orDefault(values []string, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
+
+// flatten stores the leaves of value under their dotted key path. Structs use their JSON names and
+// include zero values; slices are leaves.
+func flatten(prefix string, value reflect.Value, out interface{}) {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			store(out, prefix, nil)
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		keys := value.MapKeys()
+		if len(keys) == 0 {
+			store(out, prefix, map[string]interface{}{})
+			return
+		}
+		for _, key := range keys {
+			flatten(joinKey(prefix, key.String()), value.MapIndex(key), out)
+		}
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			flatten(joinKey(prefix, name), value.Field(i), out)
+		}
+	default:
+		store(out, prefix, value.Interface())
+	}
+}
+
+func store(out interface{}, key string, value interface{}) {
+	switch target := out.(type) {
+	case map[string]interface{}:
+		target[key] = value
+	case map[string]bool:
+		target[key] = true
+	}
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	BackendContainer      = "container"
+	BackendComposeService = "composeService"
+	BackendLocal          = "local"
+)
+
+// Backend is one step of an executor's fallback chain
+type Backend struct {
+	Kind string
+	// Compose service and optional compose file used by the composeService backend
+	ComposeService string
+	ComposeFile    string
+	// Tool path used by the local backend instead of the container path
+	LocalToolPath string
+}
+
+func (b Backend) String() string {
+	switch b.Kind {
+	case BackendComposeService:
+		return fmt.Sprintf("compose service %s", b.ComposeService)
+	case BackendLocal:
+		return "local execution"
+	default:
+		return b.Kind
+	}
+}
+
+// rewriteToolPath replaces the container tool path with the one configured for the local backend
+func (b Backend) rewriteToolPath(toolPath string, containerCmd string) string {
+	if b.Kind == BackendLocal && b.LocalToolPath != "" && toolPath != "" && strings.HasPrefix(containerCmd, toolPath) {
+		return b.LocalToolPath + strings.TrimPrefix(containerCmd, toolPath)
+	}
+	return containerCmd
+}
+
+// command builds the process running containerCmd on this backend. The container backend is handled
+// by RunCommandInContainer.
+func (b Backend) command(ctx context.Context, projectRoot string, containerCmd string) *exec.Cmd {
+	var cmd *exec.Cmd
+
+	switch b.Kind {
+	case BackendComposeService:
+		args := []string{"compose"}
+		if b.ComposeFile != "" {
+			args = append(args, "-f", b.ComposeFile)
+		}
+		// -T disables the pseudo-TTY, stdin is still forwarded
+		args = append(args, "exec", "-T", b.ComposeService, "sh", "-c", containerCmd)
+		cmd = dockerCommand(ctx, args...)
+	case BackendLocal:
+		cmd = exec.CommandContext(ctx, "sh", "-c", containerCmd)
+	}
+
+	if cmd != nil {
+		cmd.Dir = projectRoot
+	}
+	return cmd
+}
+
+// backendUnavailable reports whether the failure is caused by the backend rather than by the analyzed code
+func backendUnavailable(result *CommandResult) bool {
+	switch Failure(result.Failure()) {
+	case FailureDockerMissing, FailureDaemonUnreachable, FailureContainerMissing:
+		return true
+	}
+	return false
+}
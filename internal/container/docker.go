@@ -0,0 +1,103 @@
+package container
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DockerSettings select the Docker daemon commands are sent to. Empty fields keep the values
+// inherited from the environment (DOCKER_HOST, DOCKER_TLS_VERIFY, DOCKER_CERT_PATH), so a remote
+// daemon can be configured either way.
+type DockerSettings struct {
+	Host      string
+	TLSVerify bool
+	CertPath  string
+	// Socket of a Docker compatible API used instead of the docker CLI (e.g. rootless podman)
+	Socket string
+	// PathMappings translate host directories (keys) to their location in the containers (values)
+	PathMappings map[string]string
+}
+
+var (
+	dockerMu       sync.RWMutex
+	dockerSettings DockerSettings
+)
+
+// SetDockerSettings configures the Docker daemon used by all subsequent commands
+func SetDockerSettings(settings DockerSettings) {
+	dockerMu.Lock()
+	defer dockerMu.Unlock()
+	dockerSettings = settings
+}
+
+func currentDockerSettings() DockerSettings {
+	dockerMu.RLock()
+	defer dockerMu.RUnlock()
+	return dockerSettings
+}
+
+// dockerCommand builds a docker CLI invocation targeting the configured daemon
+func dockerCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+
+	if env := currentDockerSettings().environment(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd
+}
+
+// environment returns the variables overriding the inherited Docker environment
+func (d DockerSettings) environment() []string {
+	var env []string
+	if d.Host != "" {
+		env = append(env, "DOCKER_HOST="+d.Host)
+	}
+	if d.TLSVerify {
+		env = append(env, "DOCKER_TLS_VERIFY=1")
+	}
+	if d.CertPath != "" {
+		env = append(env, "DOCKER_CERT_PATH="+d.CertPath)
+	}
+	return env
+}
+
+// MapPath translates a host path to its location in the containers using the longest matching
+// mapping. The path is returned unchanged when no mapping applies.
+func (d DockerSettings) MapPath(hostPath string) string {
+	prefixes := make([]string, 0, len(d.PathMappings))
+	for prefix := range d.PathMappings {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	cleanPath := filepath.Clean(hostPath)
+	for _, prefix := range prefixes {
+		cleanPrefix := filepath.Clean(prefix)
+		if cleanPath != cleanPrefix && !strings.HasPrefix(cleanPath, cleanPrefix+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(filepath.Join(d.PathMappings[prefix], strings.TrimPrefix(cleanPath, cleanPrefix)))
+	}
+
+	return hostPath
+}
+
+// containerWorkdir returns the container directory matching the project root, or "" to keep the
+// container's default working directory
+func containerWorkdir(projectRoot string) string {
+	if projectRoot == "" {
+		return ""
+	}
+
+	mapped := currentDockerSettings().MapPath(projectRoot)
+	if mapped == projectRoot {
+		return ""
+	}
+	return mapped
+}
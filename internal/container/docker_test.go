@@ -0,0 +1,36 @@
+package container_test
+
+import (
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestDockerSettings_MapPath(t *testing.T) {
+	settings := container.DockerSettings{
+		PathMappings: map[string]string{
+			"/home/dev/projects":     "/srv",
+			"/home/dev/projects/app": "/var/www/html",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		hostPath string
+		expected string
+	}{
+		{"longest prefix wins", "/home/dev/projects/app/src/Foo.php", "/var/www/html/src/Foo.php"},
+		{"exact directory", "/home/dev/projects/app", "/var/www/html"},
+		{"shorter prefix", "/home/dev/projects/lib", "/srv/lib"},
+		{"partial segment does not match", "/home/dev/projects-old/app", "/home/dev/projects-old/app"},
+		{"unmapped path", "/tmp/app", "/tmp/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := settings.MapPath(tt.hostPath); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// FailureKind tells why a command failed, for callers to react without parsing its output
+type FailureKind string
+
+const (
+	FailureDockerMissing      FailureKind = "docker-missing"
+	FailureLocalBinaryMissing FailureKind = "local-binary-missing"
+	FailureDaemonUnreachable  FailureKind = "daemon-unreachable"
+	FailureContainerMissing   FailureKind = "container-missing"
+	FailureBinaryMissing      FailureKind = "binary-missing"
+	FailurePermission         FailureKind = "permission"
+	FailureReadOnly           FailureKind = "read-only"
+	FailureToolError          FailureKind = "tool-error"
+	FailureTimeout            FailureKind = "timeout"
+	FailureCancelled          FailureKind = "cancelled"
+)
+
+// CommandError is a command failure of a known kind
+type CommandError struct {
+	Kind FailureKind
+	Err  error
+}
+
+func (e *CommandError) Error() string { return e.Err.Error() }
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// Failure returns the kind of the command failure, "" when err isn't a CommandError
+func Failure(err error) FailureKind {
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Kind
+	}
+	return ""
+}
+
+// IsBackendFailure reports whether the tool couldn't run at all, as opposed to the tool failing
+func (kind FailureKind) IsBackendFailure() bool {
+	switch kind {
+	case FailureDockerMissing, FailureLocalBinaryMissing, FailureDaemonUnreachable, FailureContainerMissing, FailureBinaryMissing:
+		return true
+	}
+	return false
+}
+
+// cancellationError is the failure of a command interrupted by its context
+func cancellationError(ctx context.Context) error {
+	kind := FailureCancelled
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		kind = FailureTimeout
+	}
+	return &CommandError{Kind: kind, Err: fmt.Errorf("command cancelled: %w", ctx.Err())}
+}
+
+// startFailure is the failure of a command which didn't start: its program isn't installed, docker or the
+// shell of the local backend, or the docker CLI couldn't run
+func startFailure(cmd *exec.Cmd, err error) *CommandError {
+	kind := FailureDaemonUnreachable
+	if errors.Is(err, exec.ErrNotFound) {
+		kind = FailureLocalBinaryMissing
+		if filepath.Base(cmd.Args[0]) == "docker" {
+			kind = FailureDockerMissing
+		}
+	}
+	return &CommandError{Kind: kind, Err: fmt.Errorf("failed to start command: %w", err)}
+}
+
+// Markers of the failures in the command output or in untyped errors, checked in order: docker and the
+// shell report a missing binary with "not found" too
+var failureMarkers = []struct {
+	kind    FailureKind
+	markers []string
+}{
+	{FailureDaemonUnreachable, []string{"Cannot connect to the Docker daemon", "error during connect"}},
+	{FailureContainerMissing, []string{"No such container", "is not running", "no such service", "service is not running"}},
+	{FailureBinaryMissing, []string{"not found in container", "executable file not found", "command not found"}},
+	{FailurePermission, []string{"permission denied", "Permission denied"}},
+	{FailureReadOnly, []string{"read-only mode"}},
+}
+
+// markedFailure returns the kind of the failure mentioned in the message, "" without marker
+func markedFailure(message string) FailureKind {
+	for _, failure := range failureMarkers {
+		for _, marker := range failure.markers {
+			if strings.Contains(message, marker) {
+				return failure.kind
+			}
+		}
+	}
+	return ""
+}
+
+// Failure returns the failure of the command as a *CommandError, nil when the command ran. Tools report
+// issues with a non-zero exit code, which is only a failure without output: the backend failed, the
+// shell didn't find the binary (exit code 126 or 127) or the tool crashed.
+func (result *CommandResult) Failure() error {
+	if result.Err != nil {
+		if Failure(result.Err) != "" {
+			return result.Err
+		}
+		kind := markedFailure(result.Err.Error())
+		if kind == "" {
+			kind = FailureToolError
+		}
+		return &CommandError{Kind: kind, Err: result.Err}
+	}
+	if result.ExitCode == 0 || len(bytes.TrimSpace(result.Stdout)) > 0 {
+		return nil
+	}
+
+	stderr := strings.TrimSpace(string(result.Stderr))
+	kind := markedFailure(stderr)
+	if !kind.IsBackendFailure() && (result.ExitCode == 126 || result.ExitCode == 127) {
+		kind = FailureBinaryMissing
+	}
+	if kind == "" {
+		kind = FailureToolError
+	}
+	return &CommandError{Kind: kind, Err: fmt.Errorf("exit code %d: %s", result.ExitCode, stderr)}
+}
+
+// Classes of command failures, a broken setup keeps failing with the same class
+const (
+	ErrorClassUnavailable = "unavailable"
+	ErrorClassToolMissing = "tool-missing"
+	ErrorClassPermission  = "permission"
+	ErrorClassReadOnly    = "read-only"
+	ErrorClassCancelled   = "cancelled"
+	ErrorClassOther       = "other"
+)
+
+// ErrorClass groups the error with the errors having the same cause, whatever the file or container it
+// mentions. Untyped errors are classified by their message.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	kind := Failure(err)
+	switch {
+	case kind != "":
+	case errors.Is(err, context.Canceled):
+		kind = FailureCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = FailureTimeout
+	default:
+		kind = markedFailure(err.Error())
+	}
+
+	switch kind {
+	case FailureDockerMissing, FailureLocalBinaryMissing, FailureBinaryMissing:
+		return ErrorClassToolMissing
+	case FailureDaemonUnreachable, FailureContainerMissing:
+		return ErrorClassUnavailable
+	case FailurePermission:
+		return ErrorClassPermission
+	case FailureReadOnly:
+		return ErrorClassReadOnly
+	case FailureTimeout, FailureCancelled:
+		return ErrorClassCancelled
+	}
+	return ErrorClassOther
+}
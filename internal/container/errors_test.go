@@ -0,0 +1,91 @@
+package container_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"no error", nil, ""},
+		{"missing container", errors.New("Error response from daemon: No such container: php"), container.ErrorClassUnavailable},
+		{"stopped container", errors.New("container php is not running"), container.ErrorClassUnavailable},
+		{"docker missing", errors.New(`container php not found: exec: "docker": executable file not found in $PATH`), container.ErrorClassToolMissing},
+		{"missing binary", errors.New("binary vendor/bin/phpstan not found in container php; docker output: "), container.ErrorClassToolMissing},
+		{"permission", errors.New("open /app/var/cache: permission denied"), container.ErrorClassPermission},
+		{"read-only", errors.New(`refusing to run "php-cs-fixer fix a.php" in read-only mode`), container.ErrorClassReadOnly},
+		{"cancelled", fmt.Errorf("command cancelled: %w", context.DeadlineExceeded), container.ErrorClassCancelled},
+		{"other", errors.New("failed to write /tmp/x.php: exit status 1"), container.ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if class := container.ErrorClass(tt.err); class != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, class)
+			}
+		})
+	}
+
+	t.Run("same class whatever the container", func(t *testing.T) {
+		first := container.ErrorClass(errors.New("container php-1 is not running"))
+		second := container.ErrorClass(errors.New("container php-2 is not running"))
+		if first != second {
+			t.Errorf("Expected the same class, got %q and %q", first, second)
+		}
+	})
+}
+
+func TestCommandResult_Failure(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   container.CommandResult
+		expected container.FailureKind
+	}{
+		{"success", container.CommandResult{Stdout: []byte("{}")}, ""},
+		{"issues reported", container.CommandResult{Stdout: []byte(`{"totals": {"errors": 2}}`), ExitCode: 1}, ""},
+		{"daemon unreachable", container.CommandResult{Stderr: []byte("Cannot connect to the Docker daemon at unix:///var/run/docker.sock."), ExitCode: 1}, container.FailureDaemonUnreachable},
+		{"container missing", container.CommandResult{Stderr: []byte("Error response from daemon: No such container: php"), ExitCode: 1}, container.FailureContainerMissing},
+		{"binary missing", container.CommandResult{Stderr: []byte("sh: vendor/bin/phpstan: not found"), ExitCode: 127}, container.FailureBinaryMissing},
+		{"tool crash", container.CommandResult{Stderr: []byte("PHP Fatal error: Allowed memory size exhausted"), ExitCode: 255}, container.FailureToolError},
+		{"typed error", container.CommandResult{ExitCode: -1, Err: &container.CommandError{Kind: container.FailureTimeout, Err: context.DeadlineExceeded}}, container.FailureTimeout},
+		{"untyped error", container.CommandResult{ExitCode: -1, Err: errors.New("failed to write /tmp/x.php: exit status 1")}, container.FailureToolError},
+		{"untyped read-only error", container.CommandResult{ExitCode: -1, Err: errors.New(`refusing to run "php-cs-fixer fix a.php" in read-only mode`)}, container.FailureReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := tt.result.Failure()
+			if kind := container.Failure(failure); kind != tt.expected {
+				t.Errorf("Expected %q, got %q (%v)", tt.expected, kind, failure)
+			}
+		})
+	}
+
+	t.Run("error class", func(t *testing.T) {
+		err := &container.CommandError{Kind: container.FailureContainerMissing, Err: errors.New("exit code 1")}
+		if class := container.ErrorClass(err); class != container.ErrorClassUnavailable {
+			t.Errorf("Expected %q, got %q", container.ErrorClassUnavailable, class)
+		}
+	})
+
+	t.Run("missing local binary", func(t *testing.T) {
+		executor := container.NewExecutor("php-diagls-missing-container").WithFallbacks("phpstan", "", container.Backend{Kind: container.BackendLocal})
+		t.Setenv("PATH", t.TempDir())
+
+		failure := executor.Run(context.Background(), t.TempDir(), "phpstan analyze").Failure()
+		if kind := container.Failure(failure); kind != container.FailureLocalBinaryMissing {
+			t.Errorf("Expected %q, got %q (%v)", container.FailureLocalBinaryMissing, kind, failure)
+		}
+		if class := container.ErrorClass(failure); class != container.ErrorClassToolMissing {
+			t.Errorf("Expected %q, got %q", container.ErrorClassToolMissing, class)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Time after which an executor running on a fallback backend tries the preferred backends again
+const fallbackRetryInterval = time.Minute
+
+// Executor runs provider commands, distributing them round-robin across the configured containers
+// and falling back to the next backend of its chain when the containers are unavailable
+type Executor struct {
+	containers []string
+	next       uint32
+
+	label     string
+	toolPath  string
+	fallbacks []Backend
+	limits    Limits
+	readOnly  bool
+	notify    func(message string)
+
+	mu         sync.Mutex
+	active     int
+	switchedAt time.Time
+}
+
+func NewExecutor(containers ...string) *Executor {
+	unique := make([]string, 0, len(containers))
+	seen := make(map[string]bool)
+	for _, name := range containers {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	return &Executor{containers: unique}
+}
+
+// WithFallbacks configures the backends tried, in order, when the containers are unavailable.
+// The label identifies the executor in logs and notifications, toolPath is the tool path used in
+// commands so the local backend can substitute its own.
+func (e *Executor) WithFallbacks(label string, toolPath string, fallbacks ...Backend) *Executor {
+	e.label = label
+	e.toolPath = toolPath
+	e.fallbacks = fallbacks
+	return e
+}
+
+// WithLimits configures the CPU constraints applied to every command run by the executor
+func (e *Executor) WithLimits(limits Limits) *Executor {
+	e.limits = limits
+	return e
+}
+
+// WithReadOnly refuses commands which could modify the working tree, see checkReadOnly
+func (e *Executor) WithReadOnly(readOnly bool) *Executor {
+	e.readOnly = readOnly
+	return e
+}
+
+// WithNotifier registers a callback informed whenever the executor switches to another backend of its
+// fallback chain, nil for none
+func (e *Executor) WithNotifier(notify func(message string)) *Executor {
+	e.notify = notify
+	return e
+}
+
+// Containers returns the containers commands are distributed across
+func (e *Executor) Containers() []string {
+	return e.containers
+}
+
+// NextContainer returns the container the next command should run in
+func (e *Executor) NextContainer() string {
+	if len(e.containers) == 0 {
+		return ""
+	}
+
+	index := atomic.AddUint32(&e.next, 1) - 1
+	return e.containers[int(index)%len(e.containers)]
+}
+
+// Backend returns the backend commands currently run on
+func (e *Executor) Backend() Backend {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.backend(e.active)
+}
+
+func (e *Executor) backend(index int) Backend {
+	if index == 0 {
+		return Backend{Kind: BackendContainer}
+	}
+	return e.fallbacks[index-1]
+}
+
+// Run executes the command on the active backend. The project root is the working directory of
+// backends running on the host (compose, local).
+func (e *Executor) Run(ctx context.Context, projectRoot string, containerCmd string, stdin ...string) *CommandResult {
+	if e.readOnly {
+		if err := checkReadOnly(containerCmd); err != nil {
+			log.Printf("%s: %v", e.label, err)
+			return &CommandResult{ExitCode: -1, Err: err}
+		}
+	}
+
+	start := e.startIndex()
+
+	var result *CommandResult
+	for index := start; index <= len(e.fallbacks); index++ {
+		result = e.runOn(ctx, index, projectRoot, containerCmd, stdin...)
+
+		if !backendUnavailable(result) || ctx.Err() != nil || index == len(e.fallbacks) {
+			e.use(index)
+			return result
+		}
+
+		log.Printf("Backend %s unavailable for %s: %s", e.backend(index), e.label, result.Stderr)
+	}
+
+	return result
+}
+
+// startIndex returns the backend to try first, going back to the preferred ones periodically
+func (e *Executor) startIndex() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.active > 0 && time.Since(e.switchedAt) > fallbackRetryInterval {
+		return 0
+	}
+	return e.active
+}
+
+func (e *Executor) use(index int) {
+	e.mu.Lock()
+	previous := e.active
+	e.active = index
+	if index != previous {
+		e.switchedAt = time.Now()
+	}
+	e.mu.Unlock()
+
+	if index == previous {
+		return
+	}
+
+	message := fmt.Sprintf("%s: using %s", e.label, e.backend(index))
+	if index > 0 {
+		message = fmt.Sprintf("%s: containers unavailable, using %s", e.label, e.backend(index))
+	}
+	log.Print(message)
+	if e.notify != nil {
+		e.notify(message)
+	}
+}
+
+func (e *Executor) runOn(ctx context.Context, index int, projectRoot string, containerCmd string, stdin ...string) *CommandResult {
+	if index == 0 {
+		containerName := e.NextContainer()
+		workdir := containerWorkdir(projectRoot)
+		result := runCommandInContainer(ctx, containerName, workdir, e.limits.Wrap(containerCmd), stdin...)
+
+		// The container may have been restarted or recreated since validation, retry once when it's back
+		if backendUnavailable(result) && ctx.Err() == nil {
+			if err := revalidateContainer(ctx, containerName); err != nil {
+				log.Printf("Revalidation failed for %s: %v", e.label, err)
+				return result
+			}
+			log.Printf("Container %s is running again, retrying command for %s", containerName, e.label)
+			result = runCommandInContainer(ctx, containerName, workdir, e.limits.Wrap(containerCmd), stdin...)
+		}
+
+		return result
+	}
+
+	stdinInput := ""
+	if len(stdin) > 0 {
+		stdinInput = stdin[0]
+	}
+
+	backend := e.backend(index)
+	log.Printf("Running cmd on %s: %s", backend, containerCmd)
+	cmd := backend.command(ctx, projectRoot, e.limits.Wrap(backend.rewriteToolPath(e.toolPath, containerCmd)))
+
+	return runCommand(ctx, cmd, containerCmd, stdinInput)
+}
@@ -0,0 +1,170 @@
+package container_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestNewExecutor_Containers(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []string
+		expected   []string
+	}{
+		{
+			name:       "single container",
+			containers: []string{"php"},
+			expected:   []string{"php"},
+		},
+		{
+			name:       "duplicates and empty names are dropped",
+			containers: []string{"php-1", "", "php-2", "php-1"},
+			expected:   []string{"php-1", "php-2"},
+		},
+		{
+			name:       "no containers",
+			containers: nil,
+			expected:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := container.NewExecutor(tt.containers...)
+			result := executor.Containers()
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, result)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutor_NextContainer_RoundRobin(t *testing.T) {
+	executor := container.NewExecutor("php-1", "php-2", "php-3")
+
+	expected := []string{"php-1", "php-2", "php-3", "php-1", "php-2"}
+	for i, name := range expected {
+		if next := executor.NextContainer(); next != name {
+			t.Errorf("Call %d: expected %s, got %s", i, name, next)
+		}
+	}
+}
+
+func TestExecutor_NextContainer_Empty(t *testing.T) {
+	executor := container.NewExecutor()
+
+	if next := executor.NextContainer(); next != "" {
+		t.Errorf("Expected empty container name, got %s", next)
+	}
+}
+
+func TestExecutor_FallsBackToLocal(t *testing.T) {
+	var messages []string
+	projectRoot := t.TempDir()
+	executor := container.NewExecutor("definitely-does-not-exist-12345").
+		WithFallbacks("test-provider", "/usr/local/bin/tool", container.Backend{Kind: container.BackendLocal, LocalToolPath: "echo"}).
+		WithNotifier(func(message string) {
+			messages = append(messages, message)
+		})
+
+	result := executor.Run(context.Background(), projectRoot, "/usr/local/bin/tool hello")
+
+	if result.Err != nil {
+		t.Fatalf("Expected local fallback to succeed, got error: %v", result.Err)
+	}
+	if strings.TrimSpace(string(result.Stdout)) != "hello" {
+		t.Errorf("Expected local tool path substitution output 'hello', got %q", result.Stdout)
+	}
+	if executor.Backend().Kind != container.BackendLocal {
+		t.Errorf("Expected active backend to be local, got %s", executor.Backend().Kind)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "using local execution") {
+		t.Errorf("Expected one backend change notification, got %v", messages)
+	}
+
+	// Further commands stay on the fallback without notifying again
+	result = executor.Run(context.Background(), projectRoot, "pwd")
+	if strings.TrimSpace(string(result.Stdout)) != projectRoot {
+		t.Errorf("Expected local command to run in project root %s, got %q", projectRoot, result.Stdout)
+	}
+	if len(messages) != 1 {
+		t.Errorf("Expected no further notifications, got %v", messages)
+	}
+}
+
+func TestExecutor_NoFallbackKeepsContainerResult(t *testing.T) {
+	executor := container.NewExecutor("definitely-does-not-exist-12345")
+
+	result := executor.Run(context.Background(), t.TempDir(), "echo hello")
+
+	if result.ExitCode == 0 && result.Err == nil {
+		t.Error("Expected command in missing container to fail")
+	}
+	if executor.Backend().Kind != container.BackendContainer {
+		t.Errorf("Expected active backend to stay container, got %s", executor.Backend().Kind)
+	}
+}
+
+func TestContainerID_NotValidated(t *testing.T) {
+	if id := container.ContainerID("never-validated-container"); id != "" {
+		t.Errorf("Expected no recorded ID, got %s", id)
+	}
+}
+
+func TestExecutor_Stream(t *testing.T) {
+	projectRoot := t.TempDir()
+	executor := container.NewExecutor("definitely-does-not-exist-12345").
+		WithFallbacks("test-provider", "/usr/local/bin/tool", container.Backend{Kind: container.BackendLocal, LocalToolPath: "printf"})
+
+	// Switch to the local backend, streams run on the active one
+	executor.Run(context.Background(), projectRoot, "true")
+
+	var output strings.Builder
+	err := executor.Stream(context.Background(), projectRoot, `/usr/local/bin/tool 'first\nsecond\n'`, &output)
+
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("Expected the stream to report the exit, got %v", err)
+	}
+	if output.String() != "first\nsecond\n" {
+		t.Errorf("Expected the streamed output, got %q", output.String())
+	}
+}
+
+func TestExecutor_StreamFallback(t *testing.T) {
+	executor := container.NewExecutor("definitely-does-not-exist-12345").
+		WithFallbacks("test-provider", "/usr/local/bin/tool", container.Backend{Kind: container.BackendLocal, LocalToolPath: "printf"})
+
+	// No command ran before, the stream finds out the container is missing
+	var output strings.Builder
+	executor.Stream(context.Background(), t.TempDir(), `/usr/local/bin/tool 'first\n'`, &output)
+
+	if output.String() != "first\n" {
+		t.Errorf("Expected the output streamed locally, got %q", output.String())
+	}
+	if executor.Backend().Kind != container.BackendLocal {
+		t.Errorf("Expected the active backend to switch to local, got %s", executor.Backend().Kind)
+	}
+}
+
+func TestExecutor_StreamCancelled(t *testing.T) {
+	executor := container.NewExecutor().
+		WithFallbacks("test-provider", "", container.Backend{Kind: container.BackendLocal})
+	executor.Run(context.Background(), t.TempDir(), "true")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output strings.Builder
+	if err := executor.Stream(ctx, t.TempDir(), "sleep 5", &output); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected a cancelled stream, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits constrain the CPU usage of tool commands so that editor triggered analysis doesn't starve
+// the application running in the same container
+type Limits struct {
+	// Niceness the command runs with (1-19), 0 leaves the scheduling priority untouched
+	Nice int
+	// Maximum CPU usage in percent enforced with cpulimit, 0 disables the limit
+	CpuLimit int
+}
+
+func (l Limits) IsZero() bool {
+	return l.Nice <= 0 && l.CpuLimit <= 0
+}
+
+// Wrap runs the command under nice/cpulimit. The command goes through its own shell so every part of
+// "cmd1 && cmd2" or "a | b", redirections included, gets the limits.
+func (l Limits) Wrap(containerCmd string) string {
+	if l.IsZero() || strings.TrimSpace(containerCmd) == "" {
+		return containerCmd
+	}
+
+	prefix := ""
+	if l.CpuLimit > 0 {
+		// -f keeps cpulimit in the foreground so the exit code of the tool is preserved
+		prefix += fmt.Sprintf("cpulimit -l %d -f -- ", l.CpuLimit)
+	}
+	if l.Nice > 0 {
+		prefix += fmt.Sprintf("nice -n %d ", l.Nice)
+	}
+
+	return prefix + "sh -c " + ShellQuote(containerCmd)
+}
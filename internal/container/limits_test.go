@@ -0,0 +1,67 @@
+package container_test
+
+import (
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestLimits_Wrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   container.Limits
+		cmd      string
+		expected string
+	}{
+		{
+			name:     "no limits",
+			limits:   container.Limits{},
+			cmd:      "phpstan analyse src/Foo.php 2>/dev/null",
+			expected: "phpstan analyse src/Foo.php 2>/dev/null",
+		},
+		{
+			name:     "nice only",
+			limits:   container.Limits{Nice: 10},
+			cmd:      "phpstan analyse src/Foo.php",
+			expected: "nice -n 10 sh -c 'phpstan analyse src/Foo.php'",
+		},
+		{
+			name:     "cpulimit only",
+			limits:   container.Limits{CpuLimit: 50},
+			cmd:      "phpstan analyse src/Foo.php",
+			expected: "cpulimit -l 50 -f -- sh -c 'phpstan analyse src/Foo.php'",
+		},
+		{
+			name:     "nice and cpulimit",
+			limits:   container.Limits{Nice: 5, CpuLimit: 25},
+			cmd:      "php -l src/Foo.php",
+			expected: "cpulimit -l 25 -f -- nice -n 5 sh -c 'php -l src/Foo.php'",
+		},
+		{
+			name:     "command list and pipeline",
+			limits:   container.Limits{Nice: 10},
+			cmd:      "cd /app && vendor/bin/phpstan analyse --error-format=json | cat",
+			expected: "nice -n 10 sh -c 'cd /app && vendor/bin/phpstan analyse --error-format=json | cat'",
+		},
+		{
+			name:     "quotes in the command",
+			limits:   container.Limits{Nice: 10},
+			cmd:      "php -r 'echo 1;' 2>/dev/null",
+			expected: `nice -n 10 sh -c 'php -r '\''echo 1;'\'' 2>/dev/null'`,
+		},
+		{
+			name:     "empty command",
+			limits:   container.Limits{Nice: 5},
+			cmd:      "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.limits.Wrap(tt.cmd); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
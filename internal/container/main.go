@@ -1,7 +1,6 @@
 package container
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log"
@@ -15,9 +14,16 @@ type CommandResult struct {
 	Stderr   []byte
 	ExitCode int
 	Err      error
+	// Stdout was capped, it only holds the beginning of the tool output
+	Truncated bool
 }
 
 func RunCommandInContainer(ctx context.Context, containerName string, containerCmd string, stdin ...string) *CommandResult {
+	return runCommandInContainer(ctx, containerName, "", containerCmd, stdin...)
+}
+
+// runCommandInContainer runs the command in the container, in workdir when not empty
+func runCommandInContainer(ctx context.Context, containerName string, workdir string, containerCmd string, stdin ...string) *CommandResult {
 	log.Printf("Running cmd: %s", containerCmd)
 
 	stdinInput := ""
@@ -25,19 +31,34 @@ func RunCommandInContainer(ctx context.Context, containerName string, containerC
 		stdinInput = stdin[0]
 	}
 
-	var cmd *exec.Cmd
+	if client := socketAPIClient(); client != nil {
+		return client.exec(ctx, containerName, workdir, containerCmd, stdinInput)
+	}
+
+	args := []string{"exec"}
 	if stdinInput != "" {
 		log.Printf("Using stdin input")
-		cmd = exec.CommandContext(ctx, "docker", "exec", "-i", containerName, "sh", "-c", containerCmd)
+		args = append(args, "-i")
+	}
+	if workdir != "" {
+		args = append(args, "-w", workdir)
+	}
+	args = append(args, containerName, "sh", "-c", containerCmd)
+
+	return runCommand(ctx, dockerCommand(ctx, args...), containerCmd, stdinInput)
+}
+
+// runCommand starts the prepared command, feeds it the stdin input and waits for it to finish or for
+// the context to be cancelled
+func runCommand(ctx context.Context, cmd *exec.Cmd, label string, stdinInput string) *CommandResult {
+	if stdinInput != "" {
 		cmd.Stdin = strings.NewReader(stdinInput)
-	} else {
-		cmd = exec.CommandContext(ctx, "docker", "exec", containerName, "sh", "-c", containerCmd)
 	}
 
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout := newCappedBuffer()
+	stderr := newCappedBuffer()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	err := cmd.Start()
 	if err != nil {
@@ -45,7 +66,7 @@ func RunCommandInContainer(ctx context.Context, containerName string, containerC
 			Stdout:   nil,
 			Stderr:   nil,
 			ExitCode: -1,
-			Err:      fmt.Errorf("failed to start command: %w", err),
+			Err:      startFailure(cmd, err),
 		}
 	}
 
@@ -61,40 +82,61 @@ func RunCommandInContainer(ctx context.Context, containerName string, containerC
 			if exitErr, ok := err.(*exec.ExitError); ok {
 				exitCode = exitErr.ExitCode()
 			} else {
-				return &CommandResult{
-					Stdout:   stdout.Bytes(),
-					Stderr:   stderr.Bytes(),
-					ExitCode: -1,
-					Err:      err,
-				}
+				return newCommandResult(label, stdout, stderr, -1, &CommandError{Kind: FailureToolError, Err: err})
 			}
 		}
-		return &CommandResult{
-			Stdout:   stdout.Bytes(),
-			Stderr:   stderr.Bytes(),
-			ExitCode: exitCode,
-			Err:      nil,
-		}
+		return newCommandResult(label, stdout, stderr, exitCode, nil)
 	case <-ctx.Done():
-		log.Printf("Command cancelled, killing process: %s", containerCmd)
+		log.Printf("Command cancelled, killing process: %s", label)
 		if cmd.Process != nil {
 			cmd.Process.Kill()
 		}
 		<-done
-		return &CommandResult{
-			Stdout:   stdout.Bytes(),
-			Stderr:   stderr.Bytes(),
-			ExitCode: -1,
-			Err:      fmt.Errorf("command cancelled: %w", ctx.Err()),
+		return newCommandResult(label, stdout, stderr, -1, cancellationError(ctx))
+	}
+}
+
+// newCommandResult collects the captured output, adding a truncation marker to stderr for every capped stream
+func newCommandResult(label string, stdout *cappedBuffer, stderr *cappedBuffer, exitCode int, err error) *CommandResult {
+	result := &CommandResult{
+		Stdout:    stdout.Bytes(),
+		Stderr:    stderr.Bytes(),
+		ExitCode:  exitCode,
+		Err:       err,
+		Truncated: stdout.Truncated(),
+	}
+
+	for _, stream := range []struct {
+		name string
+		buf  *cappedBuffer
+	}{{"stdout", stdout}, {"stderr", stderr}} {
+		if stream.buf.Truncated() {
+			marker := stream.buf.truncationMarker(stream.name)
+			log.Printf("Output capped for %s: %s", label, strings.TrimSpace(marker))
+			result.Stderr = append(result.Stderr, marker...)
 		}
 	}
+
+	return result
 }
 
 func ValidateContainer(containerName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "docker", "ps", "--filter", fmt.Sprintf("name=^%s$", containerName), "--format", "{{.Names}}")
+	if client := socketAPIClient(); client != nil {
+		containerID, running, err := client.inspect(ctx, containerName)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return fmt.Errorf("container %s is not running", containerName)
+		}
+		recordContainerID(containerName, containerID)
+		return nil
+	}
+
+	cmd := dockerCommand(ctx, "ps", "--filter", fmt.Sprintf("name=^%s$", containerName), "--format", "{{.Names}} {{.ID}}")
 	cmdOutput, err := cmd.Output()
 	if err != nil {
 		if ctx.Err() != nil {
@@ -103,9 +145,13 @@ func ValidateContainer(containerName string) error {
 		return err
 	}
 
-	if strings.TrimSpace(string(cmdOutput)) != containerName {
+	fields := strings.Fields(string(cmdOutput))
+	if len(fields) == 0 || fields[0] != containerName {
 		return fmt.Errorf("container %s is not running; docker output: %s", containerName, cmdOutput)
 	}
+	if len(fields) > 1 {
+		recordContainerID(containerName, fields[1])
+	}
 
 	return nil
 }
@@ -0,0 +1,60 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultMaxOutputBytes caps the captured stdout and stderr of every command
+const DefaultMaxOutputBytes = 4 * 1024 * 1024
+
+var maxOutputBytes int64 = DefaultMaxOutputBytes
+
+// SetMaxOutputBytes changes the cap of the captured output, values <= 0 restore the default
+func SetMaxOutputBytes(limit int64) {
+	if limit <= 0 {
+		limit = DefaultMaxOutputBytes
+	}
+	atomic.StoreInt64(&maxOutputBytes, limit)
+}
+
+// cappedBuffer keeps the first limit bytes written and counts the dropped ones, so a runaway tool
+// can't balloon the server memory. Writes never fail, the tool keeps running normally.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int64
+}
+
+func newCappedBuffer() *cappedBuffer {
+	return &cappedBuffer{limit: int(atomic.LoadInt64(&maxOutputBytes))}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining >= len(p) {
+		return b.buf.Write(p)
+	}
+
+	if remaining > 0 {
+		b.buf.Write(p[:remaining])
+	}
+	b.dropped += int64(len(p) - max(remaining, 0))
+
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *cappedBuffer) Truncated() bool {
+	return b.dropped > 0
+}
+
+// truncationMarker is appended to stderr for every truncated stream. Stdout itself is kept as an
+// intact prefix so structured output can still be parsed.
+func (b *cappedBuffer) truncationMarker(stream string) string {
+	return fmt.Sprintf("\n[%s truncated: %d bytes dropped after %d bytes]\n", stream, b.dropped, b.limit)
+}
@@ -0,0 +1,32 @@
+package container_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestExecutor_OutputCap(t *testing.T) {
+	container.SetMaxOutputBytes(10)
+	defer container.SetMaxOutputBytes(0)
+
+	executor := container.NewExecutor("definitely-does-not-exist-12345").
+		WithFallbacks("test-provider", "", container.Backend{Kind: container.BackendLocal})
+
+	result := executor.Run(context.Background(), t.TempDir(), "printf '0123456789abcdef'")
+
+	if result.Err != nil {
+		t.Fatalf("Expected command to succeed, got error: %v", result.Err)
+	}
+	if string(result.Stdout) != "0123456789" {
+		t.Errorf("Expected stdout capped to its first 10 bytes, got %q", result.Stdout)
+	}
+	if !result.Truncated {
+		t.Error("Expected result to be marked as truncated")
+	}
+	if !strings.Contains(string(result.Stderr), "stdout truncated: 6 bytes dropped") {
+		t.Errorf("Expected truncation marker in stderr, got %q", result.Stderr)
+	}
+}
@@ -0,0 +1,265 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Docker API version requested from the socket, supported by podman's compatibility API
+const socketAPIVersion = "v1.41"
+
+// apiClient runs commands through the Docker compatible API of a unix socket, used for rootless
+// podman setups where no docker CLI is installed
+type apiClient struct {
+	socket string
+	client *http.Client
+}
+
+// Clients of the sockets, sharing the idle connections of their transport across the commands
+var apiClients sync.Map
+
+// newAPIClient returns the client of the socket, created on first use
+func newAPIClient(socket string) *apiClient {
+	if client, ok := apiClients.Load(socket); ok {
+		return client.(*apiClient)
+	}
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+
+	client, _ := apiClients.LoadOrStore(socket, &apiClient{
+		socket: socket,
+		client: &http.Client{Transport: &http.Transport{DialContext: dial}},
+	})
+	return client.(*apiClient)
+}
+
+// socketAPIClient returns the client to use instead of the docker CLI, or nil. The explicitly
+// configured socket always wins; otherwise the rootless podman socket is only picked up when the
+// docker CLI is not installed and no daemon is configured, so existing setups are left untouched.
+func socketAPIClient() *apiClient {
+	settings := currentDockerSettings()
+	if settings.Socket != "" {
+		return newAPIClient(settings.Socket)
+	}
+
+	if settings.Host != "" || os.Getenv("DOCKER_HOST") != "" {
+		return nil
+	}
+	if _, err := exec.LookPath("docker"); err == nil {
+		return nil
+	}
+
+	if socket := detectPodmanSocket(); socket != "" {
+		return newAPIClient(socket)
+	}
+	return nil
+}
+
+// detectPodmanSocket returns the rootless podman socket of the current user, if it exists
+func detectPodmanSocket() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return ""
+	}
+
+	socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+	if info, err := os.Stat(socket); err != nil || info.Mode()&os.ModeSocket == 0 {
+		return ""
+	}
+	return socket
+}
+
+func (c *apiClient) url(path string) string {
+	return "http://d/" + socketAPIVersion + path
+}
+
+// call sends a JSON request and decodes the JSON response into out (when not nil)
+func (c *apiClient) call(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// apiError formats the daemon error like the docker CLI does, so failures are classified the same way
+func apiError(resp *http.Response) error {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(raw, &payload) != nil || payload.Message == "" {
+		payload.Message = strings.TrimSpace(string(raw))
+	}
+
+	if resp.StatusCode == http.StatusNotFound && !strings.Contains(payload.Message, "No such container") {
+		payload.Message = "No such container: " + payload.Message
+	}
+	return fmt.Errorf("Error response from daemon: %s", payload.Message)
+}
+
+// inspect returns the container ID and whether it is running
+func (c *apiClient) inspect(ctx context.Context, containerName string) (string, bool, error) {
+	var container struct {
+		Id    string `json:"Id"`
+		State struct {
+			Running bool `json:"Running"`
+		} `json:"State"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/containers/"+containerName+"/json", nil, &container); err != nil {
+		return "", false, err
+	}
+
+	return container.Id, container.State.Running, nil
+}
+
+// exec runs the command in the container, mirroring `docker exec [-i] [-w workdir] container sh -c cmd`
+func (c *apiClient) exec(ctx context.Context, containerName string, workdir string, containerCmd string, stdinInput string) *CommandResult {
+	stdout := newCappedBuffer()
+	stderr := newCappedBuffer()
+
+	var created struct {
+		Id string `json:"Id"`
+	}
+	err := c.call(ctx, http.MethodPost, "/containers/"+containerName+"/exec", map[string]interface{}{
+		"AttachStdin":  stdinInput != "",
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"WorkingDir":   workdir,
+		"Cmd":          []string{"sh", "-c", containerCmd},
+	}, &created)
+	if err != nil {
+		if ctx.Err() != nil {
+			return newCommandResult(containerCmd, stdout, stderr, -1, cancellationError(ctx))
+		}
+		if _, isNetErr := err.(net.Error); isNetErr || strings.Contains(err.Error(), "connect:") {
+			return &CommandResult{ExitCode: -1, Err: &CommandError{Kind: FailureDaemonUnreachable, Err: fmt.Errorf("failed to start command: %w", err)}}
+		}
+		stderr.Write([]byte(err.Error()))
+		return newCommandResult(containerCmd, stdout, stderr, 1, nil)
+	}
+
+	if err := c.start(ctx, created.Id, stdinInput, stdout, stderr); err != nil {
+		if ctx.Err() != nil {
+			log.Printf("Command cancelled: %s", containerCmd)
+			return newCommandResult(containerCmd, stdout, stderr, -1, cancellationError(ctx))
+		}
+		return newCommandResult(containerCmd, stdout, stderr, -1, &CommandError{Kind: FailureDaemonUnreachable, Err: err})
+	}
+
+	var inspected struct {
+		ExitCode int `json:"ExitCode"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/exec/"+created.Id+"/json", nil, &inspected); err != nil {
+		return newCommandResult(containerCmd, stdout, stderr, -1, &CommandError{Kind: FailureDaemonUnreachable, Err: err})
+	}
+
+	return newCommandResult(containerCmd, stdout, stderr, inspected.ExitCode, nil)
+}
+
+// start attaches to the exec instance over a hijacked connection, feeds stdin and demultiplexes the
+// output stream until the command exits
+func (c *apiClient) start(ctx context.Context, execId string, stdinInput string, stdout io.Writer, stderr io.Writer) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the reads below when the analysis is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	req, err := http.NewRequest(http.MethodPost, c.url("/exec/"+execId+"/start"), strings.NewReader(`{"Detach":false,"Tty":false}`))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusSwitchingProtocols {
+		return apiError(resp)
+	}
+
+	if stdinInput != "" {
+		if _, err := io.WriteString(conn, stdinInput); err != nil {
+			return err
+		}
+	}
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		unixConn.CloseWrite()
+	}
+
+	return demultiplex(reader, stdout, stderr)
+}
+
+// demultiplex splits the attached stream: every frame starts with a header holding the stream type
+// (1 stdout, 2 stderr) and the big endian payload size
+func demultiplex(reader io.Reader, stdout io.Writer, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		target := stdout
+		if header[0] == 2 {
+			target = stderr
+		}
+		if _, err := io.CopyN(target, reader, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package container_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+// fakeSocketAPI serves the subset of the Docker compatible API used by the runner: echoes stdin to
+// stdout, writes the command to stderr and exits with code 3
+func fakeSocketAPI(t *testing.T) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socket, err)
+	}
+
+	var lastCmd []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/php/json", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"Id":"abc123","State":{"Running":true}}`)
+	})
+	mux.HandleFunc("/v1.41/containers/missing/exec", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"no container with name or ID \"missing\" found: no such container"}`)
+	})
+	mux.HandleFunc("/v1.41/containers/php/exec", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		lastCmd = body.Cmd
+		fmt.Fprint(w, `{"Id":"exec1"}`)
+	})
+	mux.HandleFunc("/v1.41/exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+
+		stdin, _ := io.ReadAll(buf)
+		writeFrame(conn, 1, stdin)
+		writeFrame(conn, 2, []byte(strings.Join(lastCmd, " ")))
+	})
+	mux.HandleFunc("/v1.41/exec/exec1/json", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"ExitCode":3}`)
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return socket
+}
+
+func writeFrame(w io.Writer, stream byte, payload []byte) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	w.Write(header)
+	w.Write(payload)
+}
+
+func TestRunCommandInContainer_SocketAPI(t *testing.T) {
+	container.SetDockerSettings(container.DockerSettings{Socket: fakeSocketAPI(t)})
+	defer container.SetDockerSettings(container.DockerSettings{})
+
+	result := container.RunCommandInContainer(context.Background(), "php", "php -l", "<?php echo 1;")
+
+	if result.Err != nil {
+		t.Fatalf("Expected no error, got %v", result.Err)
+	}
+	if string(result.Stdout) != "<?php echo 1;" {
+		t.Errorf("Expected stdin echoed to stdout, got %q", result.Stdout)
+	}
+	if string(result.Stderr) != "sh -c php -l" {
+		t.Errorf("Expected command on stderr, got %q", result.Stderr)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+	}
+
+	if err := container.ValidateContainer("php"); err != nil {
+		t.Errorf("Expected running container to validate, got %v", err)
+	}
+	if container.ContainerID("php") != "abc123" {
+		t.Errorf("Expected container ID abc123 to be recorded, got %q", container.ContainerID("php"))
+	}
+}
+
+func TestRunCommandInContainer_SocketAPIMissingContainer(t *testing.T) {
+	container.SetDockerSettings(container.DockerSettings{Socket: fakeSocketAPI(t)})
+	defer container.SetDockerSettings(container.DockerSettings{})
+
+	result := container.RunCommandInContainer(context.Background(), "missing", "php -l")
+
+	if result.ExitCode == 0 || !strings.Contains(string(result.Stderr), "No such container") {
+		t.Errorf("Expected a docker-like missing container error, got exit code %d and stderr %q", result.ExitCode, result.Stderr)
+	}
+}
+
+func TestRunCommandInContainer_SocketAPIUnreachable(t *testing.T) {
+	container.SetDockerSettings(container.DockerSettings{Socket: filepath.Join(os.TempDir(), "does-not-exist.sock")})
+	defer container.SetDockerSettings(container.DockerSettings{})
+
+	result := container.RunCommandInContainer(context.Background(), "php", "php -l")
+
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "failed to start command") {
+		t.Errorf("Expected start failure, got %v", result.Err)
+	}
+}
+
+func TestValidateContainer_SocketAPIReusesConnections(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socket, err)
+	}
+	var connections atomic.Int32
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `{"Id":"abc123","State":{"Running":true}}`)
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		},
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	container.SetDockerSettings(container.DockerSettings{Socket: socket})
+	defer container.SetDockerSettings(container.DockerSettings{})
+
+	for i := 0; i < 3; i++ {
+		if err := container.ValidateContainer("php"); err != nil {
+			t.Fatalf("Expected running container to validate, got %v", err)
+		}
+	}
+	if count := connections.Load(); count != 1 {
+		t.Errorf("Expected the client of the socket to reuse its connection, got %d connections", count)
+	}
+}
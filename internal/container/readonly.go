@@ -0,0 +1,60 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tools (and subcommands) which modify files unless they run in dry-run mode
+var mutatingCommands = []struct {
+	tool       string
+	subcommand string
+	// Argument of the dry run, --dry-run when empty
+	dryRun string
+}{
+	{"php-cs-fixer", "fix", ""},
+	{"phpcbf", "", ""},
+	{"rector", "process", ""},
+	{"rector", "", ""},
+	{"psalter", "", ""},
+	{"pint", "", "--test"},
+}
+
+// checkReadOnly refuses commands which could mutate the working tree: fixers running without
+// --dry-run and writing to files (fixing stdin with "-" only writes to stdout)
+func checkReadOnly(containerCmd string) error {
+	fields := strings.Fields(containerCmd)
+	for i, field := range fields {
+		tool := field[strings.LastIndex(field, "/")+1:]
+		for _, mutating := range mutatingCommands {
+			if tool != mutating.tool {
+				continue
+			}
+
+			args := fields[i+1:]
+			if mutating.subcommand != "" && (len(args) == 0 || args[0] != mutating.subcommand) {
+				continue
+			}
+			dryRun := mutating.dryRun
+			if dryRun == "" {
+				dryRun = "--dry-run"
+			}
+			if hasArg(args, dryRun) || (mutating.subcommand == "fix" && len(args) > 1 && args[1] == "-") {
+				continue
+			}
+
+			return &CommandError{Kind: FailureReadOnly, Err: fmt.Errorf("refusing to run %q in read-only mode: %s may modify files without %s", containerCmd, tool, dryRun)}
+		}
+	}
+
+	return nil
+}
+
+func hasArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package container_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+func TestExecutor_ReadOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		refused bool
+	}{
+		{"fixer without dry-run", "/usr/local/bin/php-cs-fixer fix src/Foo.php --format json", true},
+		{"fixer with dry-run", "/usr/local/bin/php-cs-fixer fix src/Foo.php --dry-run --diff", false},
+		{"fixer on stdin", "/usr/local/bin/php-cs-fixer fix - --diff", false},
+		{"fixer describe", "/usr/local/bin/php-cs-fixer describe array_syntax", false},
+		{"limited fixer", "nice -n 10 php-cs-fixer fix src/Foo.php", true},
+		{"phpcbf", "vendor/bin/phpcbf src/Foo.php", true},
+		{"rector with dry-run", "vendor/bin/rector process src --dry-run", false},
+		{"rector", "vendor/bin/rector process src", true},
+		{"pint with test", "vendor/bin/pint --test --format=json src/Foo.php", false},
+		{"pint", "vendor/bin/pint src/Foo.php", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := container.NewExecutor("definitely-does-not-exist-12345").WithReadOnly(true)
+			result := executor.Run(context.Background(), "", tt.cmd)
+
+			refused := result.Err != nil && strings.Contains(result.Err.Error(), "read-only mode")
+			if refused != tt.refused {
+				t.Errorf("Expected refused=%v for %q, got error %v", tt.refused, tt.cmd, result.Err)
+			}
+		})
+	}
+}
+
+func TestShadowWorkspace_ReadOnly(t *testing.T) {
+	shadow := container.NewExecutor("definitely-does-not-exist-12345").WithReadOnly(true).Shadow("/tmp/shadow")
+
+	result := shadow.Run(context.Background(), "vendor/bin/phpcbf src/Foo.php")
+	if container.Failure(result.Err) != container.FailureReadOnly {
+		t.Errorf("Expected the shadow copy to refuse the command in read-only mode, got %v", result.Err)
+	}
+}
@@ -0,0 +1,81 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const revalidateTimeout = 10 * time.Second
+
+// IDs of the validated containers, used to detect restarts and compose recreations
+var containerIDs sync.Map
+
+func recordContainerID(containerName string, containerID string) {
+	if containerID != "" {
+		containerIDs.Store(containerName, containerID)
+	}
+}
+
+// ContainerID returns the container ID recorded at validation time
+func ContainerID(containerName string) string {
+	if id, ok := containerIDs.Load(containerName); ok {
+		return id.(string)
+	}
+	return ""
+}
+
+// revalidateContainer resolves the container again after a failed command. It returns nil when the
+// container is running, so the command can be retried, and logs when its ID changed since validation.
+func revalidateContainer(ctx context.Context, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, revalidateTimeout)
+	defer cancel()
+
+	containerID, running, err := inspectContainer(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	previousID := ContainerID(containerName)
+	if previousID != "" && !strings.HasPrefix(containerID, previousID) && !strings.HasPrefix(previousID, containerID) {
+		log.Printf("Container %s was recreated (id %s -> %s)", containerName, shortID(previousID), shortID(containerID))
+	}
+	recordContainerID(containerName, containerID)
+
+	if !running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	return nil
+}
+
+func inspectContainer(ctx context.Context, containerName string) (string, bool, error) {
+	if client := socketAPIClient(); client != nil {
+		containerID, running, err := client.inspect(ctx, containerName)
+		if err != nil {
+			return "", false, fmt.Errorf("container %s not found: %w", containerName, err)
+		}
+		return containerID, running, nil
+	}
+
+	output, err := dockerCommand(ctx, "inspect", "--format", "{{.Id}} {{.State.Running}}", containerName).Output()
+	if err != nil {
+		return "", false, fmt.Errorf("container %s not found: %w", containerName, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return "", false, fmt.Errorf("unexpected docker inspect output for %s: %s", containerName, output)
+	}
+	return fields[0], fields[1] == "true", nil
+}
+
+func shortID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
@@ -0,0 +1,244 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ShadowWorkspace is a copy of the project inside the container where unsaved buffers are synced,
+// so multi-file aware tools see the edits of every dirty file and not only the analyzed one.
+// The copy is created on the first synced buffer, the files changed on disk are copied again before the next
+// analysis; vendor is linked instead of copied.
+type ShadowWorkspace struct {
+	executor *Executor
+	dir      string
+
+	mu        sync.Mutex
+	sourceDir string
+	ready     bool
+	// Content of the synced buffers, written again over a new copy
+	dirty map[string]string
+	// Files changed on disk since they were copied
+	stale map[string]bool
+}
+
+// Changed files refreshed one by one, more of them (a checkout) copy the whole project again
+const shadowRefreshLimit = 50
+
+// Shadow returns a shadow workspace kept in dir, inside the executor's main container
+func (e *Executor) Shadow(dir string) *ShadowWorkspace {
+	return &ShadowWorkspace{
+		executor: e,
+		dir:      strings.TrimRight(dir, "/"),
+		dirty:    make(map[string]string),
+		stale:    make(map[string]bool),
+	}
+}
+
+// Dir is the container directory holding the shadow copy
+func (w *ShadowWorkspace) Dir() string {
+	return w.dir
+}
+
+// HasDirtyFiles reports whether at least one unsaved buffer is synced, i.e. whether analyses should
+// run in the shadow copy
+func (w *ShadowWorkspace) HasDirtyFiles() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.ready && len(w.dirty) > 0
+}
+
+// Sync writes the buffer content of the file (relative to the project root) into the shadow copy
+func (w *ShadowWorkspace) Sync(ctx context.Context, projectRoot string, relativePath string, content string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.dirty[relativePath] = content
+	if !w.ready {
+		// The new copy gets the buffer with the other ones
+		return w.init(ctx, projectRoot)
+	}
+	return w.write(ctx, relativePath, content)
+}
+
+// Refresh marks a file (relative to the project root) changed on disk, e.g. by a checkout or a generator,
+// to be copied again before the next analysis. The synced buffer of the file keeps winning over it.
+func (w *ShadowWorkspace) Refresh(relativePath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.ready || !shadowedPath(relativePath) {
+		return
+	}
+	w.stale[relativePath] = true
+	if len(w.stale) > shadowRefreshLimit {
+		// Analyses run on the project until the next sync copies it again
+		w.ready = false
+	}
+}
+
+// Restore replaces the shadow copy of the file with the version on disk, once it was saved or closed
+func (w *ShadowWorkspace) Restore(ctx context.Context, relativePath string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, dirty := w.dirty[relativePath]; !dirty {
+		return nil
+	}
+	if !w.ready {
+		delete(w.dirty, relativePath)
+		return nil
+	}
+
+	if err := commandError(w.run(ctx, w.copyCommand(relativePath))); err != nil {
+		return fmt.Errorf("failed to restore %s in shadow workspace: %w", relativePath, err)
+	}
+
+	delete(w.dirty, relativePath)
+	delete(w.stale, relativePath)
+	return nil
+}
+
+// Run executes the command in the shadow copy, in the container the buffers are synced to, once the files
+// changed on disk are copied again
+func (w *ShadowWorkspace) Run(ctx context.Context, containerCmd string) *CommandResult {
+	if err := w.refreshStale(ctx); err != nil {
+		return &CommandResult{ExitCode: -1, Err: err}
+	}
+	return w.runIn(ctx, w.dir, w.executor.limits.Wrap(containerCmd))
+}
+
+// refreshStale copies the files changed on disk into the shadow copy, in one command. The files with a
+// synced buffer are skipped.
+func (w *ShadowWorkspace) refreshStale(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var commands []string
+	for relativePath := range w.stale {
+		if _, dirty := w.dirty[relativePath]; !dirty {
+			commands = append(commands, w.copyCommand(relativePath))
+		}
+	}
+	if len(commands) == 0 {
+		w.stale = make(map[string]bool)
+		return nil
+	}
+
+	if err := commandError(w.run(ctx, strings.Join(commands, " && "))); err != nil {
+		// The next sync copies the whole project
+		w.ready = false
+		return fmt.Errorf("failed to refresh shadow workspace: %w", err)
+	}
+	w.stale = make(map[string]bool)
+	return nil
+}
+
+// copyCommand copies the version on disk of the file or directory into the shadow copy, removing the copy
+// of a deleted one
+func (w *ShadowWorkspace) copyCommand(relativePath string) string {
+	source := ShellQuote(path.Join(w.sourceDir, relativePath))
+	target := ShellQuote(path.Join(w.dir, relativePath))
+	return fmt.Sprintf(
+		"if [ -d %s ]; then mkdir -p %s; elif [ -e %s ]; then mkdir -p %s && cp %s %s; else rm -rf %s; fi",
+		source, target, source, ShellQuote(path.Dir(path.Join(w.dir, relativePath))), source, target, target,
+	)
+}
+
+// shadowedPath reports whether the path (relative to the project root) is copied into the shadow directory:
+// vendor is linked to the project one and .git is left out
+func shadowedPath(relativePath string) bool {
+	relativePath = path.Clean(relativePath)
+	for _, excluded := range []string{".", "..", "vendor", ".git"} {
+		if relativePath == excluded || strings.HasPrefix(relativePath, excluded+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// init copies the project into the shadow directory and writes the synced buffers over it. Must be called
+// with mu held.
+func (w *ShadowWorkspace) init(ctx context.Context, projectRoot string) error {
+	sourceDir := containerWorkdir(projectRoot)
+	if sourceDir == "" {
+		result := runCommandInContainer(ctx, w.container(), "", "pwd")
+		if err := commandError(result); err != nil {
+			return fmt.Errorf("failed to resolve the project directory in the container: %w", err)
+		}
+		sourceDir = strings.TrimSpace(string(result.Stdout))
+	}
+
+	log.Printf("Creating shadow workspace %s from %s in %s", w.dir, sourceDir, w.container())
+	source, dir := ShellQuote(sourceDir), ShellQuote(w.dir)
+	copyCmd := fmt.Sprintf(
+		"rm -rf %s && mkdir -p %s && tar -C %s --exclude=./vendor --exclude=./.git -cf - . | tar -C %s -xf - && if [ -d %s/vendor ]; then ln -s %s/vendor %s/vendor; fi",
+		dir, dir, source, dir, source, source, dir,
+	)
+	if err := commandError(w.run(ctx, copyCmd)); err != nil {
+		return fmt.Errorf("failed to create shadow workspace: %w", err)
+	}
+
+	w.sourceDir = sourceDir
+	w.stale = make(map[string]bool)
+	for relativePath, content := range w.dirty {
+		if err := w.write(ctx, relativePath, content); err != nil {
+			return err
+		}
+	}
+
+	w.ready = true
+	return nil
+}
+
+// write writes the buffer content into the shadow copy of the file. Must be called with mu held.
+func (w *ShadowWorkspace) write(ctx context.Context, relativePath string, content string) error {
+	target := path.Join(w.dir, relativePath)
+	result := w.run(ctx, fmt.Sprintf("mkdir -p %s && cat > %s", ShellQuote(path.Dir(target)), ShellQuote(target)), content)
+	if err := commandError(result); err != nil {
+		return fmt.Errorf("failed to sync %s to shadow workspace: %w", relativePath, err)
+	}
+	return nil
+}
+
+func (w *ShadowWorkspace) run(ctx context.Context, containerCmd string, stdin ...string) *CommandResult {
+	return w.runIn(ctx, "", containerCmd, stdin...)
+}
+
+// runIn runs the command in the container, refused like the executor's commands in read-only mode
+func (w *ShadowWorkspace) runIn(ctx context.Context, workdir string, containerCmd string, stdin ...string) *CommandResult {
+	if w.executor.readOnly {
+		if err := checkReadOnly(containerCmd); err != nil {
+			log.Printf("%s: %v", w.executor.label, err)
+			return &CommandResult{ExitCode: -1, Err: err}
+		}
+	}
+	return runCommandInContainer(ctx, w.container(), workdir, containerCmd, stdin...)
+}
+
+func (w *ShadowWorkspace) container() string {
+	if len(w.executor.containers) == 0 {
+		return ""
+	}
+	return w.executor.containers[0]
+}
+
+func commandError(result *CommandResult) error {
+	if result.Err != nil {
+		return result.Err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(string(result.Stderr)))
+	}
+	return nil
+}
+
+// ShellQuote quotes the value for sh -c
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,174 @@
+package container_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+// fakeShellSocket serves the exec API of the Docker compatible socket by running the commands with the local
+// shell, in workdir when they don't set one: a container sharing the filesystem of the test. Returns the
+// socket and the commands run so far.
+func fakeShellSocket(t *testing.T, workdir string) (string, func() []string) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socket, err)
+	}
+
+	type execution struct {
+		cmd      []string
+		workdir  string
+		exitCode int
+	}
+	var mu sync.Mutex
+	executions := map[string]*execution{}
+	var commands []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/php/exec", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Cmd        []string
+			WorkingDir string
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		id := fmt.Sprintf("exec%d", len(executions))
+		executions[id] = &execution{cmd: body.Cmd, workdir: body.WorkingDir}
+		commands = append(commands, body.Cmd[len(body.Cmd)-1])
+		mu.Unlock()
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+	})
+	mux.HandleFunc("/v1.41/exec/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1.41/exec/"), "/")
+		mu.Lock()
+		run := executions[id]
+		mu.Unlock()
+
+		if action == "json" {
+			fmt.Fprintf(w, `{"ExitCode":%d}`, run.exitCode)
+			return
+		}
+
+		io.ReadAll(r.Body)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(run.cmd[0], run.cmd[1:]...)
+		cmd.Dir = workdir
+		if run.workdir != "" {
+			cmd.Dir = run.workdir
+		}
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = buf, &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			run.exitCode = cmd.ProcessState.ExitCode()
+		}
+		writeFrame(conn, 1, stdout.Bytes())
+		writeFrame(conn, 2, stderr.Bytes())
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return socket, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func TestShadowWorkspace_Refresh(t *testing.T) {
+	projectRoot := t.TempDir()
+	shadowDir := filepath.Join(t.TempDir(), "shadow")
+	write := func(dir string, name string, content string) {
+		t.Helper()
+		filePath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, _ := os.ReadFile(filepath.Join(shadowDir, name))
+		return string(content)
+	}
+	write(projectRoot, "src/Foo.php", "<?php // foo")
+	write(projectRoot, "src/Bar.php", "<?php // bar")
+	write(projectRoot, "src/Old.php", "<?php // old")
+
+	socket, commands := fakeShellSocket(t, projectRoot)
+	container.SetDockerSettings(container.DockerSettings{Socket: socket})
+	defer container.SetDockerSettings(container.DockerSettings{})
+
+	ctx := context.Background()
+	shadow := container.NewExecutor("php").Shadow(shadowDir)
+	if err := shadow.Sync(ctx, projectRoot, "src/Foo.php", "<?php // dirty foo"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	copied := len(commands())
+
+	// Changed, created and deleted on disk, the dirty buffer keeps winning
+	write(projectRoot, "src/Bar.php", "<?php // new bar")
+	write(projectRoot, "src/New/Baz.php", "<?php // baz")
+	write(projectRoot, "src/Foo.php", "<?php // saved elsewhere")
+	if err := os.Remove(filepath.Join(projectRoot, "src/Old.php")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"src/Bar.php", "src/New/Baz.php", "src/Foo.php", "src/Old.php", "vendor/autoload.php"} {
+		shadow.Refresh(name)
+	}
+	if result := shadow.Run(ctx, "true"); result.Err != nil || result.ExitCode != 0 {
+		t.Fatalf("Run failed: %v %s", result.Err, result.Stderr)
+	}
+
+	for name, expected := range map[string]string{
+		"src/Foo.php":     "<?php // dirty foo",
+		"src/Bar.php":     "<?php // new bar",
+		"src/New/Baz.php": "<?php // baz",
+		"src/Old.php":     "",
+	} {
+		if content := read(name); content != expected {
+			t.Errorf("Expected %q in the shadow copy of %s, got %q", expected, name, content)
+		}
+	}
+
+	// One command refreshes the files, the project isn't copied again
+	refreshed := commands()[copied:]
+	if len(refreshed) != 2 || strings.Contains(refreshed[0], "rm -rf "+container.ShellQuote(shadowDir)+" ") || strings.Contains(refreshed[0], "vendor") {
+		t.Errorf("Expected the changed files refreshed in one command before the analysis, got %q", refreshed)
+	}
+
+	t.Run("checkout", func(t *testing.T) {
+		for i := 0; i <= 50; i++ {
+			shadow.Refresh(fmt.Sprintf("src/Gen%d.php", i))
+		}
+		if shadow.HasDirtyFiles() {
+			t.Error("Expected the project analyzed until the next sync copies it again")
+		}
+	})
+}
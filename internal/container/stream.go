@@ -0,0 +1,86 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+)
+
+// Stream runs a long running command, writing its output to stdout as it comes, until the command exits or
+// the context is cancelled. Like Run, the next backend of the chain is used while the active one can't run
+// the command; a stream failing once started is restarted by the caller.
+func (e *Executor) Stream(ctx context.Context, projectRoot string, containerCmd string, stdout io.Writer) error {
+	if e.readOnly {
+		if err := checkReadOnly(containerCmd); err != nil {
+			return err
+		}
+	}
+
+	start := e.startIndex()
+
+	var result *CommandResult
+	for index := start; index <= len(e.fallbacks); index++ {
+		result = e.streamOn(ctx, index, projectRoot, containerCmd, stdout)
+
+		if !backendUnavailable(result) || ctx.Err() != nil || index == len(e.fallbacks) {
+			e.use(index)
+			break
+		}
+
+		log.Printf("Backend %s unavailable for %s: %v", e.backend(index), e.label, result.Failure())
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("command cancelled: %w", ctx.Err())
+	}
+	if err := result.Failure(); err != nil {
+		return err
+	}
+	return fmt.Errorf("command exited")
+}
+
+// streamOn runs the command on the backend, the result holding its exit code and stderr only
+func (e *Executor) streamOn(ctx context.Context, index int, projectRoot string, containerCmd string, stdout io.Writer) *CommandResult {
+	backend := e.backend(index)
+
+	var cmd *exec.Cmd
+	if index == 0 {
+		if socketAPIClient() != nil {
+			return &CommandResult{ExitCode: -1, Err: fmt.Errorf("streaming commands is not supported through the Docker API socket")}
+		}
+
+		args := []string{"exec"}
+		if workdir := containerWorkdir(projectRoot); workdir != "" {
+			args = append(args, "-w", workdir)
+		}
+		args = append(args, e.NextContainer(), "sh", "-c", e.limits.Wrap(containerCmd))
+		cmd = dockerCommand(ctx, args...)
+	} else {
+		cmd = backend.command(ctx, projectRoot, e.limits.Wrap(backend.rewriteToolPath(e.toolPath, containerCmd)))
+	}
+
+	log.Printf("%s%s Streaming cmd on %s: %s", logging.LogTagLSP, logging.LogTagServer, backend, containerCmd)
+
+	stderr := newCappedBuffer()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	result := &CommandResult{Stderr: stderr.Bytes()}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+		result.Err = startFailure(cmd, err)
+	}
+	return result
+}
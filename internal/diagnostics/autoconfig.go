@@ -0,0 +1,126 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+)
+
+// detectableTool is a tool installed with composer, found by its package or its vendor/bin binary
+type detectableTool struct {
+	providerId string
+	binary     string
+	packages   []string
+}
+
+var detectableTools = []detectableTool{
+	{PhpStanProviderId, "phpstan", []string{"phpstan/phpstan"}},
+	{PhpCsFixerProviderId, "php-cs-fixer", []string{"friendsofphp/php-cs-fixer", "php-cs-fixer/shim"}},
+	{PintProviderId, "pint", []string{"laravel/pint"}},
+	{EcsProviderId, "ecs", []string{"symplify/easy-coding-standard"}},
+	// No provider yet, only reported
+	{"", "phpcs", []string{"squizlabs/php_codesniffer"}},
+}
+
+// AutoConfigure enables the providers of the tools installed in the project when the configuration only
+// names the container. Tools are required in composer.json or found in vendor/bin, listed in the container
+// or locally when the container is unavailable. phplint only needs the php binary of the container.
+// Providers are detected once per configuration.
+func AutoConfigure(ctx context.Context, serverConfig *config.Config, projectRoot string) {
+	if !serverConfig.AutoConfigure || len(serverConfig.DiagnosticsProviders) > 0 {
+		return
+	}
+	if detection := serverConfig.ComposeDetection; detection != nil {
+		log.Printf("Container %s detected from the %s of service %s in %s", detection.Container, detection.Reason, detection.Service, detection.File)
+	}
+
+	installed := composerPackages(projectRoot)
+	for _, binary := range vendorBinaries(ctx, serverConfig.Container, projectRoot) {
+		installed[binary] = true
+	}
+
+	providers := map[string]config.DiagnosticsProvider{
+		PhpLintProviderId: {Enabled: true, Container: serverConfig.Container, Path: "php"},
+	}
+	for _, tool := range detectableTools {
+		detected := installed[tool.binary]
+		for _, composerPackage := range tool.packages {
+			detected = detected || installed[composerPackage]
+		}
+		if !detected {
+			continue
+		}
+
+		if tool.providerId == "" {
+			log.Printf("%s detected in %s, but php-diagls has no provider for it", tool.binary, projectRoot)
+			continue
+		}
+		providers[tool.providerId] = config.DiagnosticsProvider{
+			Enabled:   true,
+			Container: serverConfig.Container,
+			Path:      "vendor/bin/" + tool.binary,
+		}
+	}
+
+	ids := make([]string, 0, len(providers))
+	for id := range providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	log.Printf("Auto-configured providers in %s for container %s: %s", projectRoot, serverConfig.Container, strings.Join(ids, ", "))
+
+	serverConfig.DiagnosticsProviders = providers
+	serverConfig.SetProjectRoot(projectRoot)
+}
+
+// composerPackages returns the packages required by the project, dev dependencies included
+func composerPackages(projectRoot string) map[string]bool {
+	packages := make(map[string]bool)
+
+	data, err := os.ReadFile(filepath.Join(projectRoot, "composer.json"))
+	if err != nil {
+		return packages
+	}
+
+	var composer struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.Unmarshal(data, &composer); err != nil {
+		log.Printf("Failed to parse composer.json of %s: %v", projectRoot, err)
+		return packages
+	}
+
+	for name := range composer.Require {
+		packages[name] = true
+	}
+	for name := range composer.RequireDev {
+		packages[name] = true
+	}
+	return packages
+}
+
+// vendorBinaries lists vendor/bin in the container, or in the project when the container is unavailable
+func vendorBinaries(ctx context.Context, containerName string, projectRoot string) []string {
+	result := container.NewExecutor(containerName).Run(ctx, projectRoot, "ls vendor/bin")
+	if result.Err == nil && result.ExitCode == 0 {
+		return strings.Fields(string(result.Stdout))
+	}
+
+	entries, err := os.ReadDir(filepath.Join(projectRoot, "vendor", "bin"))
+	if err != nil {
+		return nil
+	}
+	binaries := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		binaries = append(binaries, entry.Name())
+	}
+	return binaries
+}
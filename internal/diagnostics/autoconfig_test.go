@@ -0,0 +1,57 @@
+package diagnostics_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func TestAutoConfigure(t *testing.T) {
+	projectRoot := t.TempDir()
+	composer := `{"require": {"php": ">=8.1"}, "require-dev": {"phpstan/phpstan": "^1.10", "squizlabs/php_codesniffer": "^3.7"}}`
+	if err := os.WriteFile(filepath.Join(projectRoot, "composer.json"), []byte(composer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Installed without being required, e.g. by a dependency
+	if err := os.MkdirAll(filepath.Join(projectRoot, "vendor", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectRoot, "vendor", "bin", "php-cs-fixer"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The container doesn't exist, vendor/bin is listed locally
+	serverConfig := &config.Config{Container: "php-diagls-missing-container", AutoConfigure: true}
+	diagnostics.AutoConfigure(context.Background(), serverConfig, projectRoot)
+
+	expected := map[string]string{
+		diagnostics.PhpLintProviderId:    "php",
+		diagnostics.PhpStanProviderId:    "vendor/bin/phpstan",
+		diagnostics.PhpCsFixerProviderId: "vendor/bin/php-cs-fixer",
+	}
+	if len(serverConfig.DiagnosticsProviders) != len(expected) {
+		t.Fatalf("Expected providers %v, got %v", expected, serverConfig.DiagnosticsProviders)
+	}
+	for id, path := range expected {
+		provider := serverConfig.DiagnosticsProviders[id]
+		if !provider.Enabled || provider.Path != path || provider.Container != "php-diagls-missing-container" {
+			t.Errorf("Expected %s enabled with path %s in the container, got %+v", id, path, provider)
+		}
+	}
+}
+
+func TestAutoConfigure_ConfiguredProviders(t *testing.T) {
+	serverConfig := &config.Config{
+		Container:            "php",
+		DiagnosticsProviders: map[string]config.DiagnosticsProvider{"phplint": {Enabled: false}},
+	}
+	diagnostics.AutoConfigure(context.Background(), serverConfig, t.TempDir())
+
+	if len(serverConfig.DiagnosticsProviders) != 1 || serverConfig.DiagnosticsProviders["phplint"].Enabled {
+		t.Errorf("Expected the configured providers untouched, got %v", serverConfig.DiagnosticsProviders)
+	}
+}
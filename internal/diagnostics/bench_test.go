@@ -0,0 +1,133 @@
+package diagnostics_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+// Enables the regression thresholds of the benchmarks, see `make bench-check`
+const benchThresholdsEnv = "PHP_DIAGLS_BENCH_THRESHOLDS"
+
+// phpCsFixerDiffFixture returns a php-cs-fixer diff of a file with the given number of lines, changing
+// every step-th line
+func phpCsFixerDiffFixture(lines int, step int) string {
+	var diff strings.Builder
+	diff.WriteString("--- Original\n+++ New\n")
+	for i := step / 2; i < lines-1; i += step {
+		fmt.Fprintf(&diff, "@@ -%d,3 +%d,3 @@\n     $value%d = array(%d);\n-    $value%d = array(%d, \"item\");\n+    $value%d = [%d, 'item'];\n     $value%d = array(%d);\n",
+			i, i, i-1, i-1, i, i, i, i, i+1, i+1)
+	}
+	return diff.String()
+}
+
+// phpStanOutputFixture returns a phpstan JSON report with the given number of messages spread over files
+func phpStanOutputFixture(files int, messagesPerFile int) []byte {
+	identifier := "argument.type"
+	report := struct {
+		Totals struct {
+			Errors     int `json:"errors"`
+			FileErrors int `json:"file_errors"`
+		} `json:"totals"`
+		Files  map[string]map[string]interface{} `json:"files"`
+		Errors []string                          `json:"errors"`
+	}{Files: make(map[string]map[string]interface{}), Errors: []string{}}
+
+	for f := 0; f < files; f++ {
+		messages := make([]diagnostics.PhpstanMessage, messagesPerFile)
+		for m := range messages {
+			messages[m] = diagnostics.PhpstanMessage{
+				Message:    fmt.Sprintf("Parameter #1 $value of method App\\Service%d::handle%d() expects int, string given.", f, m),
+				Line:       m*7 + 1,
+				Ignorable:  m%3 == 0,
+				Identifier: &identifier,
+			}
+		}
+		report.Files[fmt.Sprintf("/app/src/Service%d.php", f)] = map[string]interface{}{"errors": messagesPerFile, "messages": messages}
+		report.Totals.FileErrors += messagesPerFile
+	}
+
+	output, err := json.Marshal(report)
+	if err != nil {
+		panic(err)
+	}
+	return output
+}
+
+func BenchmarkPhpCsFixer_ParseDiff(b *testing.B) {
+	provider := diagnostics.NewPhpCsFixer(config.DiagnosticsProvider{})
+	diff := phpCsFixerDiffFixture(5000, 10)
+	b.SetBytes(int64(len(diff)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		provider.ParseDiffForDiagnostics(diff)
+	}
+}
+
+func BenchmarkPhpStan_ParseOutput(b *testing.B) {
+	provider := diagnostics.NewPhpStan(config.DiagnosticsProvider{})
+	output := phpStanOutputFixture(50, 40)
+	b.SetBytes(int64(len(output)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := provider.ParseOutput(context.Background(), &container.CommandResult{Stdout: output, ExitCode: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParsers_LargeFixtures(t *testing.T) {
+	ranges := diagnostics.NewPhpCsFixer(config.DiagnosticsProvider{}).ParseDiffForDiagnostics(phpCsFixerDiffFixture(5000, 10))
+	if len(ranges) != 500 {
+		t.Errorf("Expected 500 ranges from the diff fixture, got %d", len(ranges))
+	}
+
+	result := &container.CommandResult{Stdout: phpStanOutputFixture(50, 40), ExitCode: 1}
+	diags, err := diagnostics.NewPhpStan(config.DiagnosticsProvider{}).ParseOutput(context.Background(), result)
+	if err != nil {
+		t.Fatalf("ParseOutput failed: %v", err)
+	}
+	if len(diags) != 2000 {
+		t.Errorf("Expected 2000 diagnostics from the report fixture, got %d", len(diags))
+	}
+}
+
+// TestBenchmarkThresholds guards the parsers against performance regressions. Allocations are stable
+// across machines, durations get a wide margin.
+func TestBenchmarkThresholds(t *testing.T) {
+	if os.Getenv(benchThresholdsEnv) == "" {
+		t.Skipf("Set %s=1 to check the benchmark thresholds", benchThresholdsEnv)
+	}
+
+	thresholds := []struct {
+		name         string
+		benchmark    func(b *testing.B)
+		maxNsPerOp   int64
+		maxAllocsPer int64
+	}{
+		{"PhpCsFixer_ParseDiff", BenchmarkPhpCsFixer_ParseDiff, 10_000_000, 1_500},
+		{"PhpStan_ParseOutput", BenchmarkPhpStan_ParseOutput, 60_000_000, 12_000},
+	}
+
+	for _, threshold := range thresholds {
+		result := testing.Benchmark(threshold.benchmark)
+		t.Logf("%s: %d ns/op, %d allocs/op", threshold.name, result.NsPerOp(), result.AllocsPerOp())
+		if result.NsPerOp() > threshold.maxNsPerOp {
+			t.Errorf("%s: %d ns/op exceeds %d", threshold.name, result.NsPerOp(), threshold.maxNsPerOp)
+		}
+		if result.AllocsPerOp() > threshold.maxAllocsPer {
+			t.Errorf("%s: %d allocs/op exceeds %d", threshold.name, result.AllocsPerOp(), threshold.maxAllocsPer)
+		}
+	}
+}
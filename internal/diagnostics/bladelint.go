@@ -0,0 +1,123 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	BladeLintProviderId   string = "bladelint"
+	BladeLintProviderName string = "blade-lint"
+
+	BladeTemplatePattern string = "*.blade.php"
+)
+
+// A syntax error of the compiled template, e.g. "PHP Parse error:  syntax error, unexpected end of file in
+// resources/views/welcome.blade.php on line 12"
+var bladeLintErrorRegex = regexp.MustCompile(`^(?:PHP )?(?:[A-Za-z]+ )?error:\s+(.+) in (.+) on line (\d+)$`)
+
+// BladeLintError is a syntax error of a template, the line being 1-based
+type BladeLintError struct {
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+// BladeLintOutputResult is the report of blade:lint, which has no JSON format, and the errors read from it
+type BladeLintOutputResult struct {
+	Output string           `json:"output"`
+	Errors []BladeLintError `json:"errors"`
+}
+
+type BladeLint struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *BladeLint) Id() string {
+	return BladeLintProviderId
+}
+
+func (dp *BladeLint) Name() string {
+	return BladeLintProviderName
+}
+
+// Analyze compiles the Blade template with the blade:lint command of artisan and checks the syntax of the
+// compiled PHP code
+func (dp *BladeLint) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	// The verbose output confirms the templates without errors
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s blade:lint -v --no-ansi --no-interaction %s 2>&1", dp.config.Path, relativeFilePath),
+	)
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+func (dp *BladeLint) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running blade:lint: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	fullAnalysisResult := BladeLintOutputResult{Output: string(result.Stdout), Errors: []BladeLintError{}}
+	for _, line := range strings.Split(fullAnalysisResult.Output, "\n") {
+		matches := bladeLintErrorRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			continue
+		}
+		errorLine, _ := strconv.Atoi(matches[3])
+		fullAnalysisResult.Errors = append(fullAnalysisResult.Errors, BladeLintError{Message: strings.TrimSpace(matches[1]), File: matches[2], Line: errorLine})
+	}
+	if len(fullAnalysisResult.Errors) == 0 && !strings.Contains(fullAnalysisResult.Output, "No syntax errors detected") {
+		// Neither a clean template nor a syntax error, e.g. the linter isn't installed
+		markFailed(ctx, outputFailure(result, fmt.Errorf("unexpected output: %s", strings.TrimSpace(fullAnalysisResult.Output))))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, bladeError := range fullAnalysisResult.Errors {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, bladeError.File); !found || resolvedPath != filePath {
+			continue
+		}
+
+		// The line of the compiled code, the same as the template's as Blade keeps the line breaks
+		line := 0
+		if bladeError.Line > 0 {
+			line = bladeError.Line - 1
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: uint32(line), Character: 0}, End: protocol.Position{Line: uint32(line), Character: 100}},
+			Severity: protocol.DiagnosticSeverityError,
+			Source:   dp.Name(),
+			Message:  bladeError.Message,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+func NewBladeLint(providerConfig config.DiagnosticsProvider) *BladeLint {
+	return &BladeLint{
+		config:   providerConfig,
+		executor: newExecutor(BladeLintProviderName, providerConfig),
+	}
+}
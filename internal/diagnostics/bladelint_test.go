@@ -0,0 +1,55 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestBladeLint_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "artisan", outputScript(`PHP Parse error:  syntax error, unexpected end of file, expecting "elseif" or "else" or "endif" in resources/views/welcome.blade.php on line 12
+PHP Parse error:  syntax error, unexpected token "}" in resources/views/layout.blade.php on line 3`, 1))
+	filePath := tool.writeFile(t, "resources/views/welcome.blade.php", "{}")
+
+	provider := diagnostics.NewBladeLint(tool.providerConfig("artisan"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected the error of the template only, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 11 || result[0].Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Expected an error on line 12, got %+v", result[0])
+	}
+	if result[0].Message != `syntax error, unexpected end of file, expecting "elseif" or "else" or "endif"` {
+		t.Errorf("Unexpected message: %q", result[0].Message)
+	}
+}
+
+func TestBladeLint_AnalyzeUnexpectedOutput(t *testing.T) {
+	for output, expectFailure := range map[string]bool{
+		"No syntax errors detected in resources/views/welcome.blade.php": false,
+		`Command "blade:lint" is not defined.`:                           true,
+	} {
+		tool := newFakeTool(t, "artisan", outputScript(output, 1))
+		filePath := tool.writeFile(t, "resources/views/welcome.blade.php", "{}")
+		provider := diagnostics.NewBladeLint(tool.providerConfig("artisan"))
+
+		ctx, failed := diagnostics.TrackFailures(context.Background())
+		result, err := provider.Analyze(ctx, filePath)
+		if err != nil || len(result) != 0 {
+			t.Errorf("Expected no diagnostics for %q, got %+v, %v", output, result, err)
+		}
+		if (failed() != nil) != expectFailure {
+			t.Errorf("Expected failure %v for %q, got %v", expectFailure, output, failed())
+		}
+	}
+}
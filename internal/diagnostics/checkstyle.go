@@ -0,0 +1,85 @@
+package diagnostics
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// CheckstyleOutputResult is a Checkstyle XML report, the format many PHP tools can write (twig-cs-fixer,
+// phpcs, psalm, ...)
+type CheckstyleOutputResult struct {
+	XMLName xml.Name         `xml:"checkstyle" json:"-"`
+	Files   []CheckstyleFile `xml:"file" json:"files"`
+}
+
+// CheckstyleFile holds the violations of a file
+type CheckstyleFile struct {
+	Name   string            `xml:"name,attr" json:"name"`
+	Errors []CheckstyleError `xml:"error" json:"errors"`
+}
+
+// CheckstyleError is a rule violation, line and column being 1-based
+type CheckstyleError struct {
+	Line     int    `xml:"line,attr" json:"line"`
+	Column   int    `xml:"column,attr" json:"column"`
+	Severity string `xml:"severity,attr" json:"severity"`
+	Message  string `xml:"message,attr" json:"message"`
+	Source   string `xml:"source,attr" json:"source"`
+}
+
+// checkstyleDiagnostics converts the violations reported for the file, the reported paths being resolved
+// from the project root
+func checkstyleDiagnostics(report CheckstyleOutputResult, projectRoot string, filePath string, source string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	filePath = filepath.Clean(filePath)
+	for _, file := range report.Files {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, file.Name); !found || resolvedPath != filePath {
+			continue
+		}
+
+		for _, violation := range file.Errors {
+			diagnostics = append(diagnostics, checkstyleDiagnostic(violation, source))
+		}
+	}
+
+	return diagnostics
+}
+
+func checkstyleDiagnostic(violation CheckstyleError, source string) protocol.Diagnostic {
+	line := uint32(0)
+	if violation.Line > 0 {
+		line = uint32(violation.Line - 1)
+	}
+	column := uint32(0)
+	if violation.Column > 0 {
+		column = uint32(violation.Column - 1)
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: column}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: checkstyleSeverity(violation.Severity),
+		Source:   source,
+		Message:  strings.TrimSpace(violation.Message),
+	}
+	if violation.Source != "" {
+		diagnostic.Code = violation.Source
+	}
+	return diagnostic
+}
+
+// checkstyleSeverity maps the checkstyle severity, fatal being a file which can't be parsed
+func checkstyleSeverity(severity string) protocol.DiagnosticSeverity {
+	switch strings.ToLower(severity) {
+	case "error", "fatal":
+		return protocol.DiagnosticSeverityError
+	case "notice", "info":
+		return protocol.DiagnosticSeverityInformation
+	default:
+		return protocol.DiagnosticSeverityWarning
+	}
+}
@@ -0,0 +1,213 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ComposerAuditProviderId   string = "composeraudit"
+	ComposerAuditProviderName string = "composer-audit"
+
+	ComposerJsonFile string = "composer.json"
+	ComposerLockFile string = "composer.lock"
+)
+
+// ComposerAdvisory is a security advisory affecting an installed package
+type ComposerAdvisory struct {
+	AdvisoryId       string `json:"advisoryId"`
+	PackageName      string `json:"packageName"`
+	AffectedVersions string `json:"affectedVersions"`
+	Title            string `json:"title"`
+	Cve              string `json:"cve"`
+	Link             string `json:"link"`
+	Severity         string `json:"severity"`
+}
+
+// ComposerAdvisories are the advisories of a package. composer reports them as a list, or as an object
+// keyed by index once some advisories are ignored.
+type ComposerAdvisories []ComposerAdvisory
+
+func (advisories *ComposerAdvisories) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var indexed map[string]ComposerAdvisory
+		if err := json.Unmarshal(data, &indexed); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(indexed))
+		for key := range indexed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			*advisories = append(*advisories, indexed[key])
+		}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]ComposerAdvisory)(advisories))
+}
+
+// ComposerAuditOutputResult is the report of composer audit --format=json
+type ComposerAuditOutputResult struct {
+	Advisories map[string]ComposerAdvisories `json:"advisories"`
+}
+
+func (result *ComposerAuditOutputResult) UnmarshalJSON(data []byte) error {
+	var report struct {
+		Advisories json.RawMessage `json:"advisories"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	// Without advisories, composer encodes an empty list instead of an object
+	result.Advisories = map[string]ComposerAdvisories{}
+	if len(report.Advisories) == 0 || bytes.HasPrefix(bytes.TrimSpace(report.Advisories), []byte("[")) {
+		return nil
+	}
+	return json.Unmarshal(report.Advisories, &result.Advisories)
+}
+
+type ComposerAudit struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *ComposerAudit) Id() string {
+	return ComposerAuditProviderId
+}
+
+func (dp *ComposerAudit) Name() string {
+	return ComposerAuditProviderName
+}
+
+// Analyze audits the packages locked next to the composer.json file (or composer.lock), the advisories
+// are reported on the requirements of the package, or at the top of the file for the indirect dependencies
+func (dp *ComposerAudit) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	filePath = filepath.Join(filepath.Dir(filePath), ComposerJsonFile)
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeDir, _ := filepath.Rel(projectRoot, filepath.Dir(filePath))
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s audit --format=json --locked --no-interaction --working-dir=%s 2>/dev/null", dp.config.Path, relativeDir),
+	)
+
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	return dp.parseOutput(ctx, string(content), result)
+}
+
+func (dp *ComposerAudit) parseOutput(ctx context.Context, composerJson string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running composer audit: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult ComposerAuditOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	packages := make([]string, 0, len(fullAnalysisResult.Advisories))
+	for packageName := range fullAnalysisResult.Advisories {
+		packages = append(packages, packageName)
+	}
+	sort.Strings(packages)
+
+	lines := strings.Split(composerJson, "\n")
+	for _, packageName := range packages {
+		packageRange := requirementRange(lines, packageName)
+		for _, advisory := range fullAnalysisResult.Advisories[packageName] {
+			diagnostics = append(diagnostics, dp.advisoryDiagnostic(packageName, advisory, packageRange))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+func (dp *ComposerAudit) advisoryDiagnostic(packageName string, advisory ComposerAdvisory, packageRange protocol.Range) protocol.Diagnostic {
+	id := advisory.Cve
+	if id == "" {
+		id = advisory.AdvisoryId
+	}
+
+	message := fmt.Sprintf("%s: %s", packageName, advisory.Title)
+	if advisory.Cve != "" && !strings.Contains(advisory.Title, advisory.Cve) {
+		message = fmt.Sprintf("%s: %s %s", packageName, advisory.Cve, advisory.Title)
+	}
+	if advisory.Severity != "" {
+		message += fmt.Sprintf(" (%s severity)", advisory.Severity)
+	}
+	if advisory.AffectedVersions != "" {
+		message += fmt.Sprintf("\nAffected versions: %s", advisory.AffectedVersions)
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    packageRange,
+		Severity: advisorySeverity(advisory.Severity),
+		Source:   dp.Name(),
+		Message:  message,
+		Code:     id,
+	}
+	if advisory.Link != "" {
+		diagnostic.CodeDescription = &protocol.CodeDescription{Href: protocol.URI(advisory.Link)}
+	}
+	return diagnostic
+}
+
+// advisorySeverity maps the advisory severity, unknown ones being warnings
+func advisorySeverity(severity string) protocol.DiagnosticSeverity {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return protocol.DiagnosticSeverityError
+	case "low":
+		return protocol.DiagnosticSeverityInformation
+	default:
+		return protocol.DiagnosticSeverityWarning
+	}
+}
+
+// requirementRange locates the quoted package name in composer.json, the first line when the package is
+// not required directly
+func requirementRange(lines []string, packageName string) protocol.Range {
+	quoted := `"` + packageName + `"`
+	for i, line := range lines {
+		if column := strings.Index(line, quoted); column >= 0 {
+			return protocol.Range{
+				Start: protocol.Position{Line: uint32(i), Character: uint32(column)},
+				End:   protocol.Position{Line: uint32(i), Character: uint32(column + len(quoted))},
+			}
+		}
+	}
+
+	return protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 100}}
+}
+
+func NewComposerAudit(providerConfig config.DiagnosticsProvider) *ComposerAudit {
+	return &ComposerAudit{
+		config:   providerConfig,
+		executor: newExecutor(ComposerAuditProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,121 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+// Manifest of the projects of the composer tools, requiring one package
+const kernelComposerJson = "{\n  \"require\": {\n    \"symfony/http-kernel\": \"^5.4\"\n  }\n}\n"
+
+func TestComposerAudit_Analyze(t *testing.T) {
+	// Advisories of a package reported as an object once some are ignored
+	report := `{
+  "advisories": {
+    "symfony/http-kernel": {"1": {"advisoryId": "PKSA-1", "packageName": "symfony/http-kernel", "affectedVersions": ">=5.4.0,<5.4.20", "title": "CVE-2022-24894: Prevent storing cookie headers in HttpCache", "cve": "CVE-2022-24894", "link": "https://symfony.com/cve-2022-24894", "severity": "high"}},
+    "guzzlehttp/psr7": [{"advisoryId": "PKSA-2", "packageName": "guzzlehttp/psr7", "affectedVersions": "<1.9.1", "title": "Improper header validation", "cve": "CVE-2023-29197", "link": "", "severity": "medium"}]
+  },
+  "abandoned": []
+}`
+	tool := newFakeTool(t, "composer", outputScript(report, 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := diagnostics.NewComposerAudit(tool.providerConfig("composer")).Analyze(ctx, tool.path("composer.lock"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", result)
+	}
+
+	// Sorted by package, the indirect dependency is reported at the top of the file
+	indirect, direct := result[0], result[1]
+	if indirect.Range.Start.Line != 0 || indirect.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Expected a warning at the top of the file, got %+v", indirect)
+	}
+	if !strings.HasPrefix(indirect.Message, "guzzlehttp/psr7: CVE-2023-29197 Improper header validation (medium severity)") {
+		t.Errorf("Unexpected message: %q", indirect.Message)
+	}
+	if direct.Range.Start.Line != 2 || direct.Range.Start.Character != 4 || direct.Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Expected an error on the requirement, got %+v", direct)
+	}
+	if direct.Code != "CVE-2022-24894" || direct.CodeDescription == nil || direct.CodeDescription.Href != "https://symfony.com/cve-2022-24894" {
+		t.Errorf("Expected the CVE and its link, got %+v", direct)
+	}
+}
+
+func TestComposerAudit_AnalyzeWithoutAdvisories(t *testing.T) {
+	tool := newFakeTool(t, "composer", outputScript(`{"advisories": [], "abandoned": []}`, 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := diagnostics.NewComposerAudit(tool.providerConfig("composer")).Analyze(ctx, tool.path("composer.json"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", result)
+	}
+}
+
+func TestAnalyzesFile(t *testing.T) {
+	tests := []struct {
+		providerId string
+		filePath   string
+		expected   bool
+	}{
+		{diagnostics.ComposerAuditProviderId, "/project/composer.json", true},
+		{diagnostics.ComposerAuditProviderId, "/project/composer.lock", true},
+		{diagnostics.ComposerAuditProviderId, "/project/src/Foo.php", false},
+		{diagnostics.ComposerValidateProviderId, "/project/composer.json", true},
+		{diagnostics.ComposerUnusedProviderId, "/project/composer.lock", true},
+		{diagnostics.PhpStanProviderId, "/project/src/Foo.php", true},
+		{diagnostics.PhpStanProviderId, "/project/composer.json", false},
+		{diagnostics.TwigCsFixerProviderId, "/project/templates/base.html.twig", true},
+		{diagnostics.TwigCsFixerProviderId, "/project/src/Foo.php", false},
+		{diagnostics.PhpStanProviderId, "/project/templates/base.html.twig", false},
+		{diagnostics.SymfonyYamlProviderId, "/project/config/services.yaml", true},
+		{diagnostics.SymfonyYamlProviderId, "/project/config/routes.yml", true},
+		{diagnostics.BladeLintProviderId, "/project/resources/views/welcome.blade.php", true},
+		{diagnostics.BladeLintProviderId, "/project/app/User.php", false},
+		{diagnostics.PhpStanProviderId, "/project/resources/views/welcome.blade.php", false},
+	}
+
+	for _, tt := range tests {
+		if got := diagnostics.AnalyzesFile(tt.providerId, nil, tt.filePath); got != tt.expected {
+			t.Errorf("AnalyzesFile(%s, %s) = %v, expected %v", tt.providerId, tt.filePath, got, tt.expected)
+		}
+	}
+
+	// The fileExtensions of a document provider replace its default patterns
+	providerConfigs := map[string]config.DiagnosticsProvider{
+		diagnostics.SymfonyYamlProviderId: {FileExtensions: []string{".yaml.dist"}},
+	}
+	if !diagnostics.AnalyzesFile(diagnostics.SymfonyYamlProviderId, providerConfigs, "/project/config/app.yaml.dist") {
+		t.Error("Expected the configured extension to be analyzed")
+	}
+	if diagnostics.AnalyzesFile(diagnostics.SymfonyYamlProviderId, providerConfigs, "/project/config/services.yaml") {
+		t.Error("Expected the default patterns to be replaced")
+	}
+
+	if published := diagnostics.PublishedFile("/project/composer.lock"); published != "/project/composer.json" {
+		t.Errorf("Expected composer.lock results on composer.json, got %s", published)
+	}
+	if published := diagnostics.PublishedFile("/project/src/Foo.php"); published != "/project/src/Foo.php" {
+		t.Errorf("Expected PHP results on the file itself, got %s", published)
+	}
+}
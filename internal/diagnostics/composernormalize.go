@@ -0,0 +1,144 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ComposerNormalizeProviderId   string = "composernormalize"
+	ComposerNormalizeProviderName string = "composer-normalize"
+
+	ComposerNormalizeMessage string = "composer.json is not normalized"
+)
+
+// ComposerNormalize runs ergebnis/composer-normalize (composer normalize) on composer.json, reporting the
+// lines it would change and formatting the manifest
+type ComposerNormalize struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *ComposerNormalize) Id() string {
+	return ComposerNormalizeProviderId
+}
+
+func (dp *ComposerNormalize) Name() string {
+	return ComposerNormalizeProviderName
+}
+
+func (dp *ComposerNormalize) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	return dp.AnalyzeContent(ctx, filePath, string(content))
+}
+
+// AnalyzeContent reports the lines of the manifest composer normalize would change
+func (dp *ComposerNormalize) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	normalizedContent, result, err := dp.normalize(ctx, filePath, content)
+	if err != nil {
+		log.Printf("Error running composer normalize: %v", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+
+	for _, lineRange := range diffRanges(utils.UnifiedDiff(ComposerJsonFile, content, normalizedContent)) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Message:  ComposerNormalizeMessage,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// normalizeCommand normalizes a copy of stdin, a composer.json in a temporary directory of the container
+// printed once normalized. The copy has no lock file to check or update.
+func (dp *ComposerNormalize) normalizeCommand() string {
+	stdinDir := fmt.Sprintf("/tmp/php-diagls-%s-$$", ComposerNormalizeProviderId)
+	stdinFile := stdinDir + "/" + ComposerJsonFile
+
+	return fmt.Sprintf(
+		"%s normalize $(mkdir -p %s; cat > %s; echo %s) --no-check-lock --no-update-lock --no-interaction >/dev/null 2>&1 && cat %s; status=$?; rm -rf %s; exit $status",
+		dp.config.Path, stdinDir, stdinFile, stdinFile, stdinFile, stdinDir,
+	)
+}
+
+// normalize returns the normalized content, or an error when composer normalize failed
+func (dp *ComposerNormalize) normalize(ctx context.Context, filePath string, content string) (string, *container.CommandResult, error) {
+	result := dp.executor.Run(ctx, providerRoot(dp.config, filePath), dp.normalizeCommand(), content)
+	if result.Err != nil {
+		return content, result, result.Err
+	}
+	if result.ExitCode != 0 {
+		return content, result, fmt.Errorf("composer normalize failed with exit code %d", result.ExitCode)
+	}
+	if result.Truncated {
+		// A partial manifest would be invalid JSON
+		return content, result, fmt.Errorf("composer normalize output exceeded the output cap")
+	}
+
+	return string(result.Stdout), result, nil
+}
+
+// CanFormat returns true if formatting is enabled for this provider
+func (dp *ComposerNormalize) CanFormat() bool {
+	return dp.config.Format.Enabled
+}
+
+// Format returns the normalized manifest
+func (dp *ComposerNormalize) Format(ctx context.Context, filePath string, content string) (string, error) {
+	if !dp.CanFormat() {
+		return content, fmt.Errorf("formatting is not enabled for %s", dp.Name())
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		timeout := 30 * time.Second
+		if dp.config.Format.TimeoutSeconds > 0 {
+			timeout = time.Duration(dp.config.Format.TimeoutSeconds) * time.Second
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		log.Printf("%s%s Added %v timeout for composer normalize formatting", logging.LogTagLSP, logging.LogTagServer, timeout)
+	}
+
+	startTime := time.Now()
+	normalizedContent, _, err := dp.normalize(ctx, filePath, content)
+	duration := time.Since(startTime)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("%s%s composer normalize execution cancelled: %v", logging.LogTagLSP, logging.LogTagServer, ctx.Err())
+			return content, fmt.Errorf("formatting cancelled: %w", ctx.Err())
+		}
+
+		log.Printf("%s%s composer normalize failed after %v: %v", logging.LogTagLSP, logging.LogTagServer, duration, err)
+		return content, fmt.Errorf("composer normalize command failed: %w", err)
+	}
+	log.Printf("%s%s composer normalize completed in %v", logging.LogTagLSP, logging.LogTagServer, duration)
+
+	return normalizedContent, nil
+}
+
+func NewComposerNormalize(providerConfig config.DiagnosticsProvider) *ComposerNormalize {
+	return &ComposerNormalize{
+		config:   providerConfig,
+		executor: newExecutor(ComposerNormalizeProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,77 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestComposerNormalize(t *testing.T) {
+	composerJson := "{\n    \"require\": {\n        \"symfony/yaml\": \"^7.0\",\n        \"php\": \"^8.2\"\n    }\n}\n"
+	// Sorts the requirements of the copy, php first, like composer normalize
+	tool := newFakeTool(t, "composer", `[ "$1" = "normalize" ] || exit 1
+yaml=$(grep symfony/yaml "$2" | sed 's/,$//')
+php=$(grep '"php"' "$2")
+sed -i -e '/symfony\/yaml/d' -e '/"php"/d' "$2"
+sed -i "s#\"require\": {#\"require\": {\n$php,\n$yaml#" "$2"
+`)
+	filePath := tool.writeFile(t, "composer.json", composerJson)
+	normalized := "{\n    \"require\": {\n        \"php\": \"^8.2\",\n        \"symfony/yaml\": \"^7.0\"\n    }\n}\n"
+
+	providerConfig := tool.providerConfig("composer")
+	providerConfig.Format = config.FormatConfig{Enabled: true}
+
+	t.Run("reports the lines to normalize", func(t *testing.T) {
+		provider, err := diagnostics.NewDiagnosticsProvider(diagnostics.ComposerNormalizeProviderId, providerConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, failed := diagnostics.TrackFailures(context.Background())
+		result, err := provider.Analyze(ctx, filePath)
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		if failure := failed(); failure != nil {
+			t.Errorf("Expected no failure, got %v", failure)
+		}
+
+		if len(result) == 0 {
+			t.Fatal("Expected the unsorted requirements reported")
+		}
+		if result[0].Range.Start.Line != 2 || result[0].Severity != protocol.DiagnosticSeverityWarning || result[0].Message != diagnostics.ComposerNormalizeMessage {
+			t.Errorf("Unexpected diagnostic: %+v", result[0])
+		}
+	})
+
+	t.Run("formats the manifest", func(t *testing.T) {
+		provider := diagnostics.NewComposerNormalize(providerConfig)
+
+		formatted, err := provider.Format(context.Background(), filePath, composerJson)
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+		if formatted != normalized {
+			t.Errorf("Expected the normalized manifest, got %q", formatted)
+		}
+
+		if content := tool.readFile(t, "composer.json"); content != composerJson {
+			t.Errorf("Expected the manifest on disk untouched, got %q", content)
+		}
+	})
+
+	t.Run("keeps the content when normalize fails", func(t *testing.T) {
+		failing := providerConfig
+		failing.Path = "composer-missing"
+		failing.LocalPath = tool.path("composer-missing")
+		provider := diagnostics.NewComposerNormalize(failing)
+
+		formatted, err := provider.Format(context.Background(), filePath, composerJson)
+		if err == nil || formatted != composerJson {
+			t.Errorf("Expected an error and the content untouched, got %v and %q", err, formatted)
+		}
+	})
+}
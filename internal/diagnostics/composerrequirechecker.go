@@ -0,0 +1,189 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ComposerRequireCheckerProviderId   string = "composerrequirechecker"
+	ComposerRequireCheckerProviderName string = "composer-require-checker"
+)
+
+// ComposerRequireCheckerOutputResult is the JSON report of composer-require-checker: the symbols used by the
+// project which no required package or extension provides, with the packages guessed to provide them
+type ComposerRequireCheckerOutputResult struct {
+	UnknownSymbols map[string][]string `json:"unknown-symbols"`
+}
+
+func (result *ComposerRequireCheckerOutputResult) UnmarshalJSON(data []byte) error {
+	var report struct {
+		UnknownSymbols json.RawMessage `json:"unknown-symbols"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	// Without unknown symbols, an empty list is encoded instead of an object
+	result.UnknownSymbols = map[string][]string{}
+	if len(report.UnknownSymbols) == 0 || bytes.HasPrefix(bytes.TrimSpace(report.UnknownSymbols), []byte("[")) {
+		return nil
+	}
+	return json.Unmarshal(report.UnknownSymbols, &result.UnknownSymbols)
+}
+
+// composerRequireCheckerRun is the last check of a project, reused until the file or composer.json is saved
+// after it
+type composerRequireCheckerRun struct {
+	at     time.Time
+	result ComposerRequireCheckerOutputResult
+}
+
+type ComposerRequireChecker struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+
+	mu       sync.Mutex
+	lastRuns map[string]composerRequireCheckerRun
+}
+
+func (dp *ComposerRequireChecker) Id() string {
+	return ComposerRequireCheckerProviderId
+}
+
+func (dp *ComposerRequireChecker) Name() string {
+	return ComposerRequireCheckerProviderName
+}
+
+// Analyze reports the unknown symbols the file uses. The project is checked at once, the check being reused
+// for the other files until a file is saved.
+func (dp *ComposerRequireChecker) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	fullAnalysisResult, ok := dp.check(ctx, projectRoot, filePath)
+	if !ok {
+		return []protocol.Diagnostic{}, nil
+	}
+
+	return dp.fileDiagnostics(string(content), fullAnalysisResult), nil
+}
+
+// check returns the last check of the project, or runs a new one when the file or composer.json was saved since
+func (dp *ComposerRequireChecker) check(ctx context.Context, projectRoot string, filePath string) (ComposerRequireCheckerOutputResult, bool) {
+	dp.mu.Lock()
+	lastRun, exists := dp.lastRuns[projectRoot]
+	dp.mu.Unlock()
+
+	if exists && modifiedBefore(lastRun.at, filePath, filepath.Join(projectRoot, ComposerJsonFile)) {
+		recordRawResult(ctx, lastRun.result)
+		return lastRun.result, true
+	}
+
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--config-file=%s", dp.config.ConfigFile)
+	}
+
+	startedAt := time.Now()
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s check --output=json --no-interaction %s %s 2>/dev/null", dp.config.Path, configArg, ComposerJsonFile),
+	)
+
+	if result.Err != nil {
+		log.Printf("Error running composer-require-checker: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return ComposerRequireCheckerOutputResult{}, false
+	}
+
+	var fullAnalysisResult ComposerRequireCheckerOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return fullAnalysisResult, false
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	dp.mu.Lock()
+	dp.lastRuns[projectRoot] = composerRequireCheckerRun{at: startedAt, result: fullAnalysisResult}
+	dp.mu.Unlock()
+	return fullAnalysisResult, true
+}
+
+// fileDiagnostics reports every unknown symbol the file uses once, on its import or its first use
+func (dp *ComposerRequireChecker) fileDiagnostics(content string, fullAnalysisResult ComposerRequireCheckerOutputResult) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	symbols := make([]string, 0, len(fullAnalysisResult.UnknownSymbols))
+	for symbol := range fullAnalysisResult.UnknownSymbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	lines := strings.Split(content, "\n")
+	for _, symbol := range symbols {
+		symbolRange, found := symbolRange(lines, symbol)
+		if !found {
+			continue
+		}
+
+		message := fmt.Sprintf("%s is not provided by a required package or extension", symbol)
+		if packages := fullAnalysisResult.UnknownSymbols[symbol]; len(packages) > 0 {
+			message += fmt.Sprintf(", require %s", strings.Join(packages, " or "))
+		}
+
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    symbolRange,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Message:  message,
+		})
+	}
+
+	return diagnostics
+}
+
+// symbolRange locates the import of the symbol, or of one of its namespaces, else the first fully qualified
+// use of the symbol. Global symbols (functions, constants and classes of the extensions) are located by
+// their first use.
+func symbolRange(lines []string, symbol string) (protocol.Range, bool) {
+	segments := strings.Split(symbol, `\`)
+	if len(segments) == 1 {
+		return declarationRange(lines, regexp.MustCompile(`(^|[^\w\\$>:])(?P<at>\\?`+regexp.QuoteMeta(symbol)+`)\b`))
+	}
+
+	for i := len(segments); i > 1; i-- {
+		imported := regexp.QuoteMeta(strings.Join(segments[:i], `\`))
+		if importRange, found := declarationRange(lines, regexp.MustCompile(`^\s*(?P<at>use\s+(function\s+|const\s+)?\\?`+imported+`)\s*(;|\s+as\s)`)); found {
+			return importRange, true
+		}
+	}
+
+	return declarationRange(lines, regexp.MustCompile(`(^|[^\w\\])(?P<at>\\?`+regexp.QuoteMeta(symbol)+`)\b`))
+}
+
+func NewComposerRequireChecker(providerConfig config.DiagnosticsProvider) *ComposerRequireChecker {
+	return &ComposerRequireChecker{
+		config:   providerConfig,
+		executor: newExecutor(ComposerRequireCheckerProviderName, providerConfig),
+		lastRuns: make(map[string]composerRequireCheckerRun),
+	}
+}
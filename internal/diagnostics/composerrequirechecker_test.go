@@ -0,0 +1,68 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+const requireCheckerSource = `<?php
+
+namespace App\Service;
+
+use Psr\Log\LoggerInterface;
+use Symfony\Component\Yaml\Yaml;
+
+class Loader
+{
+    public function load(string $file): array
+    {
+        $handle = curl_init($file);
+        $this->curl_init();
+
+        return Yaml::parse(\GuzzleHttp\Psr7\Utils::copyToString($handle));
+    }
+}
+`
+
+func TestComposerRequireChecker_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "composer-require-checker", outputScript(`{"_meta": {"composer-require-checker": {"version": "4.7.1"}}, "unknown-symbols": {
+  "Symfony\\Component\\Yaml\\Yaml": ["symfony/yaml"],
+  "curl_init": ["ext-curl"],
+  "GuzzleHttp\\Psr7\\Utils": [],
+  "Doctrine\\ORM\\EntityManager": ["doctrine/orm"]
+}}`, 1))
+	tool.writeFile(t, "composer.json", "{}")
+	filePath := tool.writeFile(t, "src/Loader.php", requireCheckerSource)
+
+	provider := diagnostics.NewComposerRequireChecker(tool.providerConfig("vendor/bin/composer-require-checker"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	// Sorted by symbol, EntityManager is not used by the file
+	expected := []struct {
+		start   protocol.Position
+		message string
+	}{
+		{protocol.Position{Line: 14, Character: 27}, `GuzzleHttp\Psr7\Utils is not provided by a required package or extension`},
+		{protocol.Position{Line: 5, Character: 0}, `Symfony\Component\Yaml\Yaml is not provided by a required package or extension, require symfony/yaml`},
+		{protocol.Position{Line: 11, Character: 18}, `curl_init is not provided by a required package or extension, require ext-curl`},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %+v", len(expected), result)
+	}
+	for i, diagnostic := range result {
+		if diagnostic.Range.Start != expected[i].start || diagnostic.Message != expected[i].message {
+			t.Errorf("Expected %s at %+v, got %+v", expected[i].message, expected[i].start, diagnostic)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ComposerUnusedProviderId   string = "composerunused"
+	ComposerUnusedProviderName string = "composer-unused"
+)
+
+// ComposerUnusedOutputResult is the JSON report of composer-unused
+type ComposerUnusedOutputResult struct {
+	UsedPackages   []string `json:"used-packages"`
+	UnusedPackages []string `json:"unused-packages"`
+}
+
+type ComposerUnused struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *ComposerUnused) Id() string {
+	return ComposerUnusedProviderId
+}
+
+func (dp *ComposerUnused) Name() string {
+	return ComposerUnusedProviderName
+}
+
+// Analyze reports the required packages of the composer.json file (the one next to composer.lock) which the
+// code never uses, on their requirement
+func (dp *ComposerUnused) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	filePath = filepath.Join(filepath.Dir(filePath), ComposerJsonFile)
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--configuration=%s", dp.config.ConfigFile)
+	}
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s %s --output-format=json --no-progress --no-interaction %s 2>/dev/null", dp.config.Path, relativeFilePath, configArg),
+	)
+
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	return dp.parseOutput(ctx, string(content), result)
+}
+
+func (dp *ComposerUnused) parseOutput(ctx context.Context, composerJson string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running composer-unused: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult ComposerUnusedOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	lines := strings.Split(composerJson, "\n")
+	for _, packageName := range fullAnalysisResult.UnusedPackages {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    requirementRange(lines, packageName),
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Message:  fmt.Sprintf("%s is required but never used", packageName),
+			Tags:     []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary},
+		})
+	}
+
+	return diagnostics, nil
+}
+
+func NewComposerUnused(providerConfig config.DiagnosticsProvider) *ComposerUnused {
+	return &ComposerUnused{
+		config:   providerConfig,
+		executor: newExecutor(ComposerUnusedProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,37 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestComposerUnused_Analyze(t *testing.T) {
+	report := `{"used-packages": ["php"], "unused-packages": ["symfony/http-kernel"], "ignored-packages": []}`
+	tool := newFakeTool(t, "composer", outputScript(report, 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+	provider := diagnostics.NewComposerUnused(tool.providerConfig("vendor/bin/composer-unused"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, tool.path("composer.lock"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+
+	unused := result[0]
+	if unused.Range.Start != (protocol.Position{Line: 2, Character: 4}) || unused.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Expected a warning on the requirement, got %+v", unused)
+	}
+	if unused.Message != "symfony/http-kernel is required but never used" || len(unused.Tags) != 1 || unused.Tags[0] != protocol.DiagnosticTagUnnecessary {
+		t.Errorf("Expected an unnecessary requirement, got %+v", unused)
+	}
+}
@@ -0,0 +1,153 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ComposerValidateProviderId   string = "composervalidate"
+	ComposerValidateProviderName string = "composer-validate"
+)
+
+var composerValidateJsonErrorRegex = regexp.MustCompile(`Parse error on line (\d+)`)
+
+// ComposerValidateRawResult is the report of composer validate, which has no JSON format
+type ComposerValidateRawResult struct {
+	Output string `json:"output"`
+}
+
+type ComposerValidate struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *ComposerValidate) Id() string {
+	return ComposerValidateProviderId
+}
+
+func (dp *ComposerValidate) Name() string {
+	return ComposerValidateProviderName
+}
+
+// Analyze validates the composer.json file (the one next to composer.lock), its schema, its requirements
+// and whether the lock file is up to date
+func (dp *ComposerValidate) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	filePath = filepath.Join(filepath.Dir(filePath), ComposerJsonFile)
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s validate --no-check-publish --no-interaction %s 2>&1", dp.config.Path, relativeFilePath),
+	)
+
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	return dp.parseOutput(ctx, string(content), result)
+}
+
+// parseOutput reads the issues listed under the "# ... errors" and "# ... warnings" headings of the report
+func (dp *ComposerValidate) parseOutput(ctx context.Context, composerJson string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running composer validate: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	output := string(result.Stdout)
+	lines := strings.Split(composerJson, "\n")
+
+	if matches := composerValidateJsonErrorRegex.FindStringSubmatch(output); len(matches) == 2 {
+		line, _ := strconv.Atoi(matches[1])
+		if line > 0 {
+			line--
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: uint32(line), Character: 0}, End: protocol.Position{Line: uint32(line), Character: 100}},
+			Severity: protocol.DiagnosticSeverityError,
+			Source:   dp.Name(),
+			Message:  "Invalid JSON: " + lastLine(output),
+		})
+		recordRawResult(ctx, ComposerValidateRawResult{Output: output})
+		return diagnostics, nil
+	}
+
+	if !strings.Contains(output, " is valid") && !strings.Contains(output, " is invalid") {
+		// Neither a report nor a syntax error, the command itself failed
+		markFailed(ctx, outputFailure(result, fmt.Errorf("unexpected output: %s", strings.TrimSpace(output))))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, ComposerValidateRawResult{Output: output})
+
+	var severity protocol.DiagnosticSeverity
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "# "):
+			heading := strings.ToLower(line)
+			severity = 0
+			if strings.HasSuffix(heading, "errors") {
+				severity = protocol.DiagnosticSeverityError
+			} else if strings.HasSuffix(heading, "warnings") {
+				severity = protocol.DiagnosticSeverityWarning
+			}
+		case strings.HasPrefix(line, "- ") && severity != 0:
+			message := strings.TrimPrefix(line, "- ")
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    composerKeyRange(lines, message),
+				Severity: severity,
+				Source:   dp.Name(),
+				Message:  message,
+			})
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// composerKeyRange locates the key of the issues starting with its path (e.g. "require.php : invalid
+// version constraint"), the first line for the other issues
+func composerKeyRange(lines []string, message string) protocol.Range {
+	if keyPath, _, found := strings.Cut(message, " : "); found {
+		key := keyPath[strings.LastIndex(keyPath, ".")+1:]
+		keyRegex := regexp.MustCompile(`"` + regexp.QuoteMeta(key) + `"\s*:`)
+		for i, line := range lines {
+			if location := keyRegex.FindStringIndex(line); location != nil {
+				return protocol.Range{
+					Start: protocol.Position{Line: uint32(i), Character: uint32(location[0])},
+					End:   protocol.Position{Line: uint32(i), Character: uint32(location[0] + len(key) + 2)},
+				}
+			}
+		}
+	}
+
+	return protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 100}}
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func NewComposerValidate(providerConfig config.DiagnosticsProvider) *ComposerValidate {
+	return &ComposerValidate{
+		config:   providerConfig,
+		executor: newExecutor(ComposerValidateProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,86 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestComposerValidate_Analyze(t *testing.T) {
+	report := `./composer.json is invalid, the following errors/warnings were found:
+See https://getcomposer.org/doc/04-schema.md for details on the schema
+# General errors
+- require.symfony/http-kernel : invalid version constraint (Could not parse version constraint ^5.4.x)
+# Lock file errors
+- The lock file is not up to date with the latest changes in composer.json.
+# General warnings
+- No license specified, it is recommended to do so.`
+	tool := newFakeTool(t, "composer", outputScript(report, 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := diagnostics.NewComposerValidate(tool.providerConfig("composer")).Analyze(ctx, tool.path("composer.json"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %+v", result)
+	}
+
+	expected := []struct {
+		line     uint32
+		severity protocol.DiagnosticSeverity
+	}{
+		{2, protocol.DiagnosticSeverityError},
+		{0, protocol.DiagnosticSeverityError},
+		{0, protocol.DiagnosticSeverityWarning},
+	}
+	for i, diagnostic := range result {
+		if diagnostic.Range.Start.Line != expected[i].line || diagnostic.Severity != expected[i].severity {
+			t.Errorf("Diagnostic %d: expected line %d with severity %v, got %+v", i, expected[i].line, expected[i].severity, diagnostic)
+		}
+	}
+	if result[0].Range.Start.Character != 4 {
+		t.Errorf("Expected the requirement key to be located, got %+v", result[0].Range)
+	}
+}
+
+func TestComposerValidate_AnalyzeInvalidJson(t *testing.T) {
+	report := `./composer.json does not contain valid JSON
+Parse error on line 3:
+...y/http-kernel": "^5.4",  }}
+----------------------^
+Expected: 'STRING' - It appears you have an extra trailing comma`
+	tool := newFakeTool(t, "composer", outputScript(report, 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+
+	result, err := diagnostics.NewComposerValidate(tool.providerConfig("composer")).Analyze(context.Background(), tool.path("composer.lock"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(result) != 1 || result[0].Range.Start.Line != 2 || result[0].Severity != protocol.DiagnosticSeverityError {
+		t.Fatalf("Expected 1 error on line 2, got %+v", result)
+	}
+}
+
+func TestComposerValidate_AnalyzeUnexpectedOutput(t *testing.T) {
+	tool := newFakeTool(t, "composer", outputScript("sh: composer: Permission denied", 1))
+	tool.writeFile(t, "composer.json", kernelComposerJson)
+	tool.writeFile(t, "composer.lock", "{}")
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, _ := diagnostics.NewComposerValidate(tool.providerConfig("composer")).Analyze(ctx, tool.path("composer.json"))
+	if len(result) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", result)
+	}
+	if failed() == nil {
+		t.Error("Expected the unexpected output to be a failure")
+	}
+}
@@ -0,0 +1,96 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+// Placeholder of the analyzed file in the arguments of a custom provider
+const customFilePlaceholder string = "{file}"
+
+// Custom runs a tool php-diagls has no provider for, reading its report in the configured outputFormat.
+// The id of its configuration names it.
+type Custom struct {
+	id       string
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *Custom) Id() string {
+	return dp.id
+}
+
+func (dp *Custom) Name() string {
+	return dp.id
+}
+
+func (dp *Custom) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+// analyzeCommand runs the tool with the configured arguments, followed by the file unless they place it
+func (dp *Custom) analyzeCommand(relativeFilePath string) string {
+	arguments := dp.config.Arguments
+	if strings.Contains(arguments, customFilePlaceholder) {
+		arguments = strings.ReplaceAll(arguments, customFilePlaceholder, relativeFilePath)
+	} else {
+		arguments = strings.TrimSpace(arguments + " " + relativeFilePath)
+	}
+
+	return fmt.Sprintf("%s %s 2>/dev/null", dp.config.Path, arguments)
+}
+
+func (dp *Custom) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	if result.Err != nil {
+		log.Printf("Error running %s: %v", dp.id, result.Err)
+		markFailed(ctx, result.Failure())
+		return []protocol.Diagnostic{}, nil
+	}
+
+	switch dp.config.OutputFormat {
+	case config.OutputFormatCheckstyle:
+		var fullAnalysisResult CheckstyleOutputResult
+		if err := xml.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+			log.Printf("Unmarshall err: %s", err)
+			markFailed(ctx, outputFailure(result, err))
+			return []protocol.Diagnostic{}, nil
+		}
+		recordRawResult(ctx, fullAnalysisResult)
+
+		return checkstyleDiagnostics(fullAnalysisResult, projectRoot, filePath, dp.Name()), nil
+	case config.OutputFormatSarif:
+		var fullAnalysisResult SarifOutputResult
+		if err := json.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+			log.Printf("Unmarshall err: %s", err)
+			markFailed(ctx, outputFailure(result, err))
+			return []protocol.Diagnostic{}, nil
+		}
+		recordRawResult(ctx, fullAnalysisResult)
+
+		return sarifDiagnostics(fullAnalysisResult, projectRoot, filePath, dp.Name()), nil
+	default:
+		return []protocol.Diagnostic{}, fmt.Errorf("unsupported output format %q of %s", dp.config.OutputFormat, dp.id)
+	}
+}
+
+func NewCustom(providerId string, providerConfig config.DiagnosticsProvider) *Custom {
+	return &Custom{
+		id:       providerId,
+		config:   providerConfig,
+		executor: newExecutor(providerId, providerConfig),
+	}
+}
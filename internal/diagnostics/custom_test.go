@@ -0,0 +1,127 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestCustom_AnalyzeCheckstyle(t *testing.T) {
+	tool := newFakeTool(t, "phpcs", "echo \"$@\" > arguments\n"+outputScript(`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="3.9.0">
+<file name="/app/src/User.php">
+ <error line="3" column="1" severity="error" message="Missing doc comment for class User" source="Squiz.Commenting.ClassComment.Missing"/>
+ <error line="8" column="5" severity="warning" message="Line exceeds 120 characters" source="Generic.Files.LineLength.TooLong"/>
+</file>
+<file name="/app/src/Order.php">
+ <error line="1" column="1" severity="error" message="Missing file doc comment" source="Squiz.Commenting.FileComment.Missing"/>
+</file>
+</checkstyle>`, 2))
+	filePath := tool.writeFile(t, "src/User.php", "{}")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpcs")
+	providerConfig.OutputFormat = config.OutputFormatCheckstyle
+	providerConfig.Arguments = "--report=checkstyle -q {file} --standard=PSR12"
+	provider, err := diagnostics.NewDiagnosticsProvider("phpcs", providerConfig)
+	if err != nil {
+		t.Fatalf("Expected a custom provider, got %v", err)
+	}
+	if provider.Id() != "phpcs" || provider.Name() != "phpcs" {
+		t.Errorf("Expected the provider named after its id, got %s and %s", provider.Id(), provider.Name())
+	}
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	if content := tool.readFile(t, "arguments"); strings.TrimSpace(content) != "--report=checkstyle -q src/User.php --standard=PSR12" {
+		t.Errorf("Expected the file in place of the placeholder, got %q", content)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 violations of the file, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 2 || result[0].Severity != protocol.DiagnosticSeverityError || result[0].Code != "Squiz.Commenting.ClassComment.Missing" {
+		t.Errorf("Unexpected error: %+v", result[0])
+	}
+	if result[1].Range.Start != (protocol.Position{Line: 7, Character: 4}) || result[1].Severity != protocol.DiagnosticSeverityWarning || result[1].Source != "phpcs" {
+		t.Errorf("Unexpected warning: %+v", result[1])
+	}
+}
+
+func TestNewDiagnosticsProvider_UnknownWithoutOutputFormat(t *testing.T) {
+	_, err := diagnostics.NewDiagnosticsProvider("phpcs", config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "php-diagls-missing-container",
+		Path:      "vendor/bin/phpcs",
+		Fallback:  []string{"local"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown diagnostics provider") {
+		t.Errorf("Expected an unknown provider error, got %v", err)
+	}
+}
+
+func TestCustom_AnalyzeSarif(t *testing.T) {
+	tool := newFakeTool(t, "psalm", outputScript(`{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "Psalm", "rules": [
+      {"id": "InvalidReturnType", "helpUri": "https://psalm.dev/011", "defaultConfiguration": {"level": "error"}},
+      {"id": "PossiblyNullReference", "helpUri": "https://psalm.dev/083"}
+    ]}},
+    "results": [
+      {"ruleId": "InvalidReturnType", "ruleIndex": 0, "message": {"text": "The declared return type 'int' is incorrect"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/User.php"}, "region": {"startLine": 12, "startColumn": 5, "endLine": 12, "endColumn": 18}}}]},
+      {"ruleId": "PossiblyNullReference", "level": "note", "message": {"text": "Cannot call method on possibly null value"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///app/src/User.php"}, "region": {"startLine": 20}}}]},
+      {"ruleId": "InvalidReturnType", "ruleIndex": 0, "message": {"text": "Not this file"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/Order.php"}, "region": {"startLine": 1}}}]}
+    ]
+  }]
+}`, 2))
+	filePath := tool.writeFile(t, "src/User.php", "{}")
+	tool.writeFile(t, "src/Order.php", "{}")
+
+	providerConfig := tool.providerConfig("vendor/bin/psalm")
+	providerConfig.OutputFormat = config.OutputFormatSarif
+	providerConfig.Arguments = "--output-format=sarif --no-progress"
+	provider, err := diagnostics.NewDiagnosticsProvider("psalm", providerConfig)
+	if err != nil {
+		t.Fatalf("Expected a custom provider, got %v", err)
+	}
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 results of the file, got %+v", result)
+	}
+	expectedRange := protocol.Range{Start: protocol.Position{Line: 11, Character: 4}, End: protocol.Position{Line: 11, Character: 17}}
+	if result[0].Range != expectedRange || result[0].Severity != protocol.DiagnosticSeverityError || result[0].Code != "InvalidReturnType" {
+		t.Errorf("Unexpected error: %+v", result[0])
+	}
+	if result[0].CodeDescription == nil || result[0].CodeDescription.Href != "https://psalm.dev/011" {
+		t.Errorf("Expected the rule help link, got %+v", result[0].CodeDescription)
+	}
+	if result[1].Range.Start.Line != 19 || result[1].Severity != protocol.DiagnosticSeverityInformation || result[1].Source != "psalm" {
+		t.Errorf("Unexpected note: %+v", result[1])
+	}
+	if result[1].CodeDescription == nil || result[1].CodeDescription.Href != "https://psalm.dev/083" {
+		t.Errorf("Expected the rule found by id, got %+v", result[1].CodeDescription)
+	}
+}
@@ -0,0 +1,158 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	DeptracProviderId   string = "deptrac"
+	DeptracProviderName string = "deptrac"
+)
+
+// The violated rule ends the violation messages, e.g. "(Controller on Repository)"
+var deptracRuleRegex = regexp.MustCompile(`\(([^()]+ on [^()]+)\)\s*$`)
+
+// The dependency of the violation or of the uncovered dependency message
+var deptracDependencyRegex = regexp.MustCompile(`(?:must not depend on|has uncovered dependency on) ([\w\\]+)`)
+
+// DeptracMessage is a layer violation, or an uncovered or skipped dependency when reported
+type DeptracMessage struct {
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Type    string `json:"type"`
+}
+
+// DeptracOutputResult is the JSON report of deptrac analyse
+type DeptracOutputResult struct {
+	Files map[string]struct {
+		Violations int              `json:"violations"`
+		Messages   []DeptracMessage `json:"messages"`
+	} `json:"files"`
+}
+
+type Deptrac struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *Deptrac) Id() string {
+	return DeptracProviderId
+}
+
+func (dp *Deptrac) Name() string {
+	return DeptracProviderName
+}
+
+// Analyze reports the layer violations of the file. deptrac can't analyze a single file: the project is
+// analyzed, its cache keeping the runs short, and the violations of the other files are dropped.
+func (dp *Deptrac) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand())
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+func (dp *Deptrac) analyzeCommand() string {
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--config-file=%s", dp.config.ConfigFile)
+	}
+
+	return fmt.Sprintf("%s analyse --formatter=json --no-progress --no-interaction %s 2>/dev/null", dp.config.Path, configArg)
+}
+
+func (dp *Deptrac) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running deptrac: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult DeptracOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for reportedPath, file := range fullAnalysisResult.Files {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, reportedPath); !found || resolvedPath != filePath {
+			continue
+		}
+
+		for _, message := range file.Messages {
+			diagnostics = append(diagnostics, dp.messageDiagnostic(projectRoot, message))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+func (dp *Deptrac) messageDiagnostic(projectRoot string, message DeptracMessage) protocol.Diagnostic {
+	line := uint32(0)
+	if message.Line > 0 {
+		line = uint32(message.Line - 1)
+	}
+
+	severity := protocol.DiagnosticSeverityError
+	if strings.ToLower(message.Type) != "error" {
+		severity = protocol.DiagnosticSeverityWarning
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: severity,
+		Source:   dp.Name(),
+		Message:  message.Message,
+	}
+	if matches := deptracRuleRegex.FindStringSubmatch(message.Message); len(matches) == 2 {
+		diagnostic.Code = matches[1]
+	}
+	if matches := deptracDependencyRegex.FindStringSubmatch(message.Message); len(matches) == 2 {
+		diagnostic.RelatedInformation = deptracDependencyLocation(projectRoot, matches[1])
+	}
+	return diagnostic
+}
+
+// deptracDependencyLocation links the declaration of the dependency class, when the composer autoloading
+// resolves it to a project file
+func deptracDependencyLocation(projectRoot string, className string) []protocol.DiagnosticRelatedInformation {
+	classFile, found := composerClassFile(projectRoot, className)
+	if !found {
+		return nil
+	}
+	content, err := os.ReadFile(classFile)
+	if err != nil {
+		return nil
+	}
+
+	shortName := className[strings.LastIndex(className, `\`)+1:]
+	classRange, _ := declarationRange(strings.Split(string(content), "\n"), regexp.MustCompile(`\b(class|interface|trait|enum)\s+(?P<at>`+regexp.QuoteMeta(shortName)+`)\b`))
+	return []protocol.DiagnosticRelatedInformation{{
+		Location: protocol.Location{URI: utils.PathToURI(classFile), Range: classRange},
+		Message:  fmt.Sprintf("%s is declared here", className),
+	}}
+}
+
+func NewDeptrac(providerConfig config.DiagnosticsProvider) *Deptrac {
+	return &Deptrac{
+		config:   providerConfig,
+		executor: newExecutor(DeptracProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,76 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestDeptrac_Analyze(t *testing.T) {
+	// Reports container paths, for the whole project
+	tool := newFakeTool(t, "deptrac", `case "$*" in
+  *"--config-file=deptrac.yaml"*) ;;
+  *) exit 2;;
+esac
+cat <<'JSON'
+{
+  "Report": {"Violations": 2, "Skipped violations": 0, "Uncovered": 1, "Allowed": 4, "Warnings": 0, "Errors": 0},
+  "files": {
+    "/app/src/Controller/Foo.php": {"violations": 2, "messages": [
+      {"message": "App\\Controller\\Foo must not depend on App\\Repository\\UserRepository (Controller on Repository)", "line": 12, "type": "error"},
+      {"message": "App\\Controller\\Foo has uncovered dependency on Vendor\\Client", "line": 20, "type": "warning"}
+    ]},
+    "/app/src/Controller/Bar.php": {"violations": 1, "messages": [
+      {"message": "App\\Controller\\Bar must not depend on App\\Repository\\OrderRepository (Controller on Repository)", "line": 7, "type": "error"}
+    ]}
+  }
+}
+JSON
+exit 1
+`)
+	tool.writeFile(t, "deptrac.yaml", "{}")
+	filePath := tool.writeFile(t, "src/Controller/Foo.php", "{}")
+	tool.writeFile(t, "src/Controller/Bar.php", "{}")
+
+	// The dependency of the violation is resolved with the composer autoloading
+	tool.writeFile(t, "composer.json", `{"autoload": {"psr-4": {"App\\": "src/"}}}`)
+	repositoryPath := tool.writeFile(t, "src/Repository/UserRepository.php", "<?php\n\nnamespace App\\Repository;\n\nfinal class UserRepository\n{\n}\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/deptrac")
+	providerConfig.ConfigFile = "deptrac.yaml"
+	provider := diagnostics.NewDeptrac(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 messages of Foo.php, got %+v", result)
+	}
+
+	violation, uncovered := result[0], result[1]
+	if violation.Range.Start.Line != 11 || violation.Severity != protocol.DiagnosticSeverityError || violation.Code != "Controller on Repository" {
+		t.Errorf("Unexpected violation: %+v", violation)
+	}
+	if uncovered.Range.Start.Line != 19 || uncovered.Severity != protocol.DiagnosticSeverityWarning || uncovered.Code != nil {
+		t.Errorf("Unexpected uncovered dependency: %+v", uncovered)
+	}
+
+	if len(violation.RelatedInformation) != 1 {
+		t.Fatalf("Expected the dependency declaration as related information, got %+v", violation.RelatedInformation)
+	}
+	related := violation.RelatedInformation[0]
+	if related.Location.URI.Filename() != repositoryPath || related.Location.Range.Start != (protocol.Position{Line: 4, Character: 12}) {
+		t.Errorf("Unexpected dependency location: %+v", related.Location)
+	}
+	// Vendor classes aren't autoloaded from the project
+	if len(uncovered.RelatedInformation) != 0 {
+		t.Errorf("Expected no related information for the vendor dependency, got %+v", uncovered.RelatedInformation)
+	}
+}
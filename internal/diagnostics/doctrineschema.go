@@ -0,0 +1,276 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	DoctrineSchemaProviderId   string = "doctrineschema"
+	DoctrineSchemaProviderName string = "doctrine-schema"
+)
+
+var (
+	// Mapped classes, by attribute or annotation
+	doctrineEntityRegex = regexp.MustCompile(`(#\[|@)(ORM\\)?(Entity|MappedSuperclass|Embeddable)\b`)
+	// Heading of the errors of a class, e.g. "[FAIL] The entity-class App\Entity\User mapping is invalid:"
+	doctrineFailRegex = regexp.MustCompile(`^\[FAIL\] The entity-class (\S+) mapping is invalid:`)
+)
+
+// DoctrineSchemaOutputResult is the report of doctrine:schema:validate, which has no JSON format, and the
+// mapping errors read from it by class name
+type DoctrineSchemaOutputResult struct {
+	Output string              `json:"output"`
+	Errors map[string][]string `json:"errors"`
+}
+
+// doctrineSchemaRun is the last validation of a project, reused until an entity file is saved after it
+type doctrineSchemaRun struct {
+	at     time.Time
+	result DoctrineSchemaOutputResult
+}
+
+type DoctrineSchema struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+
+	mu       sync.Mutex
+	lastRuns map[string]doctrineSchemaRun
+}
+
+func (dp *DoctrineSchema) Id() string {
+	return DoctrineSchemaProviderId
+}
+
+func (dp *DoctrineSchema) Name() string {
+	return DoctrineSchemaProviderName
+}
+
+// Analyze reports the mapping errors of the entity class of the file. The whole mapping is validated at
+// once: the last validation is reused for every entity file until one of them is saved.
+func (dp *DoctrineSchema) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+	if !doctrineEntityRegex.Match(content) {
+		return []protocol.Diagnostic{}, nil
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	fullAnalysisResult, ok := dp.validate(ctx, projectRoot, filePath)
+	if !ok {
+		return []protocol.Diagnostic{}, nil
+	}
+
+	return dp.fileDiagnostics(projectRoot, filePath, string(content), fullAnalysisResult), nil
+}
+
+// validate returns the last validation of the project, or runs a new one when the file was saved since
+func (dp *DoctrineSchema) validate(ctx context.Context, projectRoot string, filePath string) (DoctrineSchemaOutputResult, bool) {
+	dp.mu.Lock()
+	lastRun, exists := dp.lastRuns[projectRoot]
+	dp.mu.Unlock()
+
+	if exists && modifiedBefore(lastRun.at, filePath) {
+		recordRawResult(ctx, lastRun.result)
+		return lastRun.result, true
+	}
+
+	startedAt := time.Now()
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s doctrine:schema:validate --skip-sync --no-interaction --no-ansi 2>&1", dp.config.Path),
+	)
+
+	fullAnalysisResult, ok := dp.parseOutput(ctx, result)
+	if ok {
+		dp.mu.Lock()
+		dp.lastRuns[projectRoot] = doctrineSchemaRun{at: startedAt, result: fullAnalysisResult}
+		dp.mu.Unlock()
+	}
+	return fullAnalysisResult, ok
+}
+
+// parseOutput reads the errors listed under the "[FAIL]" line of every invalid class, the errors wrapped
+// by the console being joined
+func (dp *DoctrineSchema) parseOutput(ctx context.Context, result *container.CommandResult) (DoctrineSchemaOutputResult, bool) {
+	fullAnalysisResult := DoctrineSchemaOutputResult{Output: string(result.Stdout), Errors: map[string][]string{}}
+
+	if result.Err != nil {
+		log.Printf("Error running doctrine:schema:validate: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return fullAnalysisResult, false
+	}
+
+	if !strings.Contains(fullAnalysisResult.Output, "[OK]") && !strings.Contains(fullAnalysisResult.Output, "[FAIL]") {
+		// Neither a report nor mapping errors, e.g. DoctrineBundle is not installed
+		markFailed(ctx, outputFailure(result, fmt.Errorf("unexpected output: %s", strings.TrimSpace(fullAnalysisResult.Output))))
+		return fullAnalysisResult, false
+	}
+
+	className := ""
+	for _, line := range strings.Split(fullAnalysisResult.Output, "\n") {
+		line = strings.TrimSpace(line)
+		errors := fullAnalysisResult.Errors[className]
+		switch {
+		case doctrineFailRegex.MatchString(line):
+			className = strings.TrimPrefix(doctrineFailRegex.FindStringSubmatch(line)[1], `\`)
+		case className == "":
+			continue
+		case strings.HasPrefix(line, "* "):
+			fullAnalysisResult.Errors[className] = append(errors, strings.TrimPrefix(line, "* "))
+		case line == "" && len(errors) > 0:
+			// The list of the errors of a class ends with a blank line
+			className = ""
+		case line != "" && len(errors) > 0:
+			errors[len(errors)-1] += " " + line
+		}
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	return fullAnalysisResult, true
+}
+
+// fileDiagnostics reports the errors of the classes declared in the file, on the property they are about
+// (App\Entity\User#posts) when found, on the class declaration otherwise
+func (dp *DoctrineSchema) fileDiagnostics(projectRoot string, filePath string, content string, fullAnalysisResult DoctrineSchemaOutputResult) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	classNames := make([]string, 0, len(fullAnalysisResult.Errors))
+	for className := range fullAnalysisResult.Errors {
+		classNames = append(classNames, className)
+	}
+	sort.Strings(classNames)
+
+	lines := strings.Split(content, "\n")
+	filePath = filepath.Clean(filePath)
+	for _, className := range classNames {
+		if classFile, found := composerClassFile(projectRoot, className); !found || classFile != filePath {
+			continue
+		}
+
+		shortName := className[strings.LastIndex(className, `\`)+1:]
+		classRange, _ := declarationRange(lines, regexp.MustCompile(`\b(class|trait)\s+`+regexp.QuoteMeta(shortName)+`\b`))
+		fieldRegex := regexp.MustCompile(regexp.QuoteMeta(className) + `#(\w+)`)
+
+		for _, message := range fullAnalysisResult.Errors[className] {
+			errorRange := classRange
+			if matches := fieldRegex.FindStringSubmatch(message); len(matches) == 2 {
+				if fieldRange, found := declarationRange(lines, regexp.MustCompile(`\b(public|protected|private|var)\s[^$;]*\$`+matches[1]+`\b`)); found {
+					errorRange = fieldRange
+				}
+			}
+
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    errorRange,
+				Severity: protocol.DiagnosticSeverityError,
+				Source:   dp.Name(),
+				Message:  message,
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// declarationRange locates the first match of the declaration, limited to its "at" group when it has one,
+// the first line when not found
+func declarationRange(lines []string, declaration *regexp.Regexp) (protocol.Range, bool) {
+	group := declaration.SubexpIndex("at")
+	for i, line := range lines {
+		location := declaration.FindStringSubmatchIndex(line)
+		if location == nil {
+			continue
+		}
+
+		start, end := location[0], location[1]
+		if group > 0 && location[2*group] >= 0 {
+			start, end = location[2*group], location[2*group+1]
+		}
+		return protocol.Range{
+			Start: protocol.Position{Line: uint32(i), Character: uint32(start)},
+			End:   protocol.Position{Line: uint32(i), Character: uint32(end)},
+		}, true
+	}
+
+	return protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 100}}, false
+}
+
+// composerClassFile resolves the class to its file with the PSR-4 autoload prefixes of the project
+// composer.json, the longest matching prefix first
+func composerClassFile(projectRoot string, className string) (string, bool) {
+	content, err := os.ReadFile(filepath.Join(projectRoot, ComposerJsonFile))
+	if err != nil {
+		return "", false
+	}
+
+	var composerJson struct {
+		Autoload struct {
+			Psr4 map[string]json.RawMessage `json:"psr-4"`
+		} `json:"autoload"`
+		AutoloadDev struct {
+			Psr4 map[string]json.RawMessage `json:"psr-4"`
+		} `json:"autoload-dev"`
+	}
+	if err := json.Unmarshal(content, &composerJson); err != nil {
+		return "", false
+	}
+
+	type prefixDirs struct {
+		prefix string
+		dirs   []string
+	}
+	var prefixes []prefixDirs
+	for _, psr4 := range []map[string]json.RawMessage{composerJson.Autoload.Psr4, composerJson.AutoloadDev.Psr4} {
+		for prefix, rawDirs := range psr4 {
+			// A directory, or a list of directories
+			var dirs []string
+			if err := json.Unmarshal(rawDirs, &dirs); err != nil {
+				var dir string
+				if json.Unmarshal(rawDirs, &dir) != nil {
+					continue
+				}
+				dirs = []string{dir}
+			}
+			prefixes = append(prefixes, prefixDirs{prefix: prefix, dirs: dirs})
+		}
+	}
+	sort.SliceStable(prefixes, func(i, j int) bool { return len(prefixes[i].prefix) > len(prefixes[j].prefix) })
+
+	for _, candidate := range prefixes {
+		if !strings.HasPrefix(className, candidate.prefix) {
+			continue
+		}
+		relativePath := strings.ReplaceAll(strings.TrimPrefix(className, candidate.prefix), `\`, "/") + ".php"
+		for _, dir := range candidate.dirs {
+			classFile := filepath.Join(projectRoot, dir, relativePath)
+			if _, err := os.Stat(classFile); err == nil {
+				return classFile, true
+			}
+		}
+	}
+	return "", false
+}
+
+func NewDoctrineSchema(providerConfig config.DiagnosticsProvider) *DoctrineSchema {
+	return &DoctrineSchema{
+		config:   providerConfig,
+		executor: newExecutor(DoctrineSchemaProviderName, providerConfig),
+		lastRuns: make(map[string]doctrineSchemaRun),
+	}
+}
@@ -0,0 +1,135 @@
+package diagnostics_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+const doctrineEntity = `<?php
+
+namespace App\Entity;
+
+use Doctrine\ORM\Mapping as ORM;
+
+#[ORM\Entity]
+class User
+{
+    #[ORM\Id]
+    private ?int $id = null;
+
+    #[ORM\OneToMany(mappedBy: 'author', targetEntity: Post::class)]
+    private Collection $posts;
+}
+`
+
+func TestDoctrineSchema_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "console", "echo run >> runs\n"+outputScript(`
+Mapping
+-------
+
+ [FAIL] The entity-class App\Entity\User mapping is invalid:
+ * The association App\Entity\User#posts refers to the owning side field App\Entity\Post#author which does not exist.
+ * The identifier id is missing for a query
+   of App\Entity\User
+
+ [FAIL] The entity-class App\Entity\Post mapping is invalid:
+ * The field App\Entity\Post#title is missing.
+
+Database
+--------
+
+ [SKIPPED] The database was not checked for synchronicity.
+`, 2))
+	files := map[string]string{
+		"composer.json":             `{"autoload": {"psr-4": {"App\\": "src/"}}, "autoload-dev": {"psr-4": {"App\\Tests\\": ["tests/"]}}}`,
+		"src/Entity/User.php":       doctrineEntity,
+		"src/Entity/Post.php":       "<?php\n\nnamespace App\\Entity;\n\n#[ORM\\Entity]\nclass Post\n{\n}\n",
+		"src/Service/Mailer.php":    "<?php\n\nnamespace App\\Service;\n\nclass Mailer\n{\n}\n",
+		"tests/Entity/UserTest.php": "<?php\n",
+	}
+	for name, content := range files {
+		tool.writeFile(t, name, content)
+	}
+
+	provider := diagnostics.NewDoctrineSchema(tool.providerConfig("bin/console"))
+
+	// Files are older than the first validation
+	past := time.Now().Add(-time.Minute)
+	for name := range files {
+		if err := os.Chtimes(tool.path(name), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, tool.path("src/Entity/User.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 errors of User, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 13 || !strings.Contains(result[0].Message, "User#posts") {
+		t.Errorf("Expected the association error on the posts property, got %+v", result[0])
+	}
+	if result[1].Range.Start.Line != 7 || result[1].Message != `The identifier id is missing for a query of App\Entity\User` {
+		t.Errorf("Expected the other error on the class declaration, got %+v", result[1])
+	}
+
+	// Another entity reuses the validation, files which are not entities don't run it
+	result, _ = provider.Analyze(ctx, tool.path("src/Entity/Post.php"))
+	if len(result) != 1 || result[0].Range.Start.Line != 5 {
+		t.Errorf("Expected the Post error on its class, got %+v", result)
+	}
+	if result, _ = provider.Analyze(ctx, tool.path("src/Service/Mailer.php")); len(result) != 0 {
+		t.Errorf("Expected no errors outside the entities, got %+v", result)
+	}
+	if count := countRuns(t, tool); count != 1 {
+		t.Errorf("Expected 1 validation, got %d", count)
+	}
+
+	// Saving an entity validates the mapping again
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(tool.path("src/Entity/User.php"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Analyze(ctx, tool.path("src/Entity/User.php")); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if count := countRuns(t, tool); count != 2 {
+		t.Errorf("Expected a new validation after the save, got %d", count)
+	}
+}
+
+func TestDoctrineSchema_UnexpectedOutput(t *testing.T) {
+	tool := newFakeTool(t, "console", "echo 'There are no commands defined in the \"doctrine:schema\" namespace.'\nexit 1\n")
+	tool.writeFile(t, "src/Entity/User.php", doctrineEntity)
+	provider := diagnostics.NewDoctrineSchema(tool.providerConfig("bin/console"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, tool.path("src/Entity/User.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", result)
+	}
+	if failed() == nil {
+		t.Error("Expected the unexpected output to be a tool failure")
+	}
+}
+
+// countRuns returns the number of runs recorded by the script of the tool
+func countRuns(t *testing.T, tool *fakeTool) int {
+	t.Helper()
+
+	return strings.Count(tool.readFile(t, "runs"), "run")
+}
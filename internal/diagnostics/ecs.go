@@ -0,0 +1,220 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	EcsProviderId   string = "ecs"
+	EcsProviderName string = "ecs"
+)
+
+// EcsDiff is a change ECS would make, with the checkers (fixers and sniffs) making it
+type EcsDiff struct {
+	Diff            string   `json:"diff"`
+	AppliedCheckers []string `json:"applied_checkers"`
+}
+
+// EcsError is a violation ECS can't fix, the line being 1-based
+type EcsError struct {
+	Line        int    `json:"line"`
+	FilePath    string `json:"file_path"`
+	Message     string `json:"message"`
+	SourceClass string `json:"source_class"`
+}
+
+// EcsOutputResult is the JSON report of ECS, by file path
+type EcsOutputResult struct {
+	Files map[string]struct {
+		Diffs  []EcsDiff  `json:"diffs"`
+		Errors []EcsError `json:"errors"`
+	} `json:"files"`
+}
+
+// EcsRawResult is the output of the check, with the diff of every applied checker
+type EcsRawResult struct {
+	EcsOutputResult
+	CheckerDiffs map[string]string `json:"checkerDiffs"`
+}
+
+type Ecs struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *Ecs) Id() string {
+	return EcsProviderId
+}
+
+func (dp *Ecs) Name() string {
+	return EcsProviderName
+}
+
+func (dp *Ecs) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	return dp.analyze(ctx, projectRoot, func(only string) string {
+		return dp.checkCommand(relativeFilePath, only)
+	})
+}
+
+// AnalyzeContent checks the content piped through stdin, copied to a temporary file as ECS can't read it
+func (dp *Ecs) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	return dp.analyze(ctx, providerRoot(dp.config, filePath), func(only string) string {
+		return stdinCopyCommand(EcsProviderId, func(target string) string {
+			return dp.checkCommand(target, only)
+		})
+	}, content)
+}
+
+// checkCommand reports the changes ECS would make to the target and the violations it can't fix, limited
+// to a checker unless only is empty
+func (dp *Ecs) checkCommand(target string, only string) string {
+	args := ""
+	if dp.config.ConfigFile != "" {
+		args = fmt.Sprintf(" --config=%s", dp.config.ConfigFile)
+	}
+	if only != "" {
+		args += fmt.Sprintf(" --only='%s'", only)
+	}
+
+	return fmt.Sprintf("%s check %s --output-format=json --no-progress-bar%s 2>/dev/null", dp.config.Path, target, args)
+}
+
+// analyze runs the check, then once per applied checker to locate the lines it changes, like the
+// php-cs-fixer provider does with the rules
+func (dp *Ecs) analyze(ctx context.Context, projectRoot string, command func(only string) string, stdin ...string) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	result := dp.executor.Run(ctx, projectRoot, command(""), stdin...)
+	if result.Err != nil {
+		log.Printf("Error running ecs: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult EcsOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+
+	rawResult := EcsRawResult{EcsOutputResult: fullAnalysisResult, CheckerDiffs: map[string]string{}}
+
+	// Only the target was checked, its path being the temporary file for the content
+	for _, file := range fullAnalysisResult.Files {
+		for _, ecsError := range file.Errors {
+			line := 0
+			if ecsError.Line > 0 {
+				line = ecsError.Line - 1
+			}
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    protocol.Range{Start: protocol.Position{Line: uint32(line), Character: 0}, End: protocol.Position{Line: uint32(line), Character: 100}},
+				Severity: protocol.DiagnosticSeverityError,
+				Source:   dp.Name(),
+				Code:     ecsError.SourceClass,
+				Message:  ecsError.Message,
+			})
+		}
+
+		for _, diff := range file.Diffs {
+			for _, checker := range diff.AppliedCheckers {
+				if _, done := rawResult.CheckerDiffs[checker]; done {
+					continue
+				}
+				checkerDiff, ok := dp.checkerDiff(ctx, projectRoot, command(checker), stdin...)
+				if !ok {
+					return []protocol.Diagnostic{}, nil
+				}
+				rawResult.CheckerDiffs[checker] = checkerDiff
+
+				for _, lineRange := range diffRanges(checkerDiff) {
+					diagnostics = append(diagnostics, protocol.Diagnostic{
+						Range:    lineRange,
+						Severity: protocol.DiagnosticSeverityWarning,
+						Source:   dp.Name(),
+						Code:     checker,
+						Message:  fmt.Sprintf("%s (%s)", PhpCsFixerStyleIssueMessage, checker[strings.LastIndex(checker, `\`)+1:]),
+					})
+				}
+			}
+		}
+	}
+
+	recordRawResult(ctx, rawResult)
+
+	return diagnostics, nil
+}
+
+// checkerDiff returns the diff of a run limited to a checker, false when the run failed
+func (dp *Ecs) checkerDiff(ctx context.Context, projectRoot string, command string, stdin ...string) (string, bool) {
+	result := dp.executor.Run(ctx, projectRoot, command, stdin...)
+	if result.Err != nil {
+		log.Printf("Error running ecs: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return "", false
+	}
+
+	var checkerResult EcsOutputResult
+	if err := unmarshalToolOutput(result, &checkerResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return "", false
+	}
+
+	diff := ""
+	for _, file := range checkerResult.Files {
+		for _, fileDiff := range file.Diffs {
+			diff += fileDiff.Diff
+		}
+	}
+	return diff, true
+}
+
+// CanFormat returns true if formatting is enabled for this provider
+func (dp *Ecs) CanFormat() bool {
+	return dp.config.Format.Enabled
+}
+
+// Format applies the diff of the changes ECS would make to the content
+func (dp *Ecs) Format(ctx context.Context, filePath string, content string) (string, error) {
+	command := stdinCopyCommand(EcsProviderId, func(target string) string {
+		return dp.checkCommand(target, "")
+	})
+
+	return stdinFormat(ctx, dp.Name(), dp.config, dp.executor, filePath, content, command, func(result *container.CommandResult, duration time.Duration) ([]string, error) {
+		var formatResult EcsOutputResult
+		if err := unmarshalToolOutput(result, &formatResult); err != nil {
+			log.Printf("%s%s ecs returned exit code %d after %v: %s", logging.LogTagLSP, logging.LogTagServer, result.ExitCode, duration, string(result.Stderr))
+			return nil, fmt.Errorf("ecs report unusable: %w", err)
+		}
+		log.Printf("%s%s ecs completed in %v, %d files to fix", logging.LogTagLSP, logging.LogTagServer, duration, len(formatResult.Files))
+
+		var diffs []string
+		for _, file := range formatResult.Files {
+			for _, diff := range file.Diffs {
+				diffs = append(diffs, diff.Diff)
+			}
+		}
+		return diffs, nil
+	})
+}
+
+func NewEcs(providerConfig config.DiagnosticsProvider) *Ecs {
+	return &Ecs{
+		config:   providerConfig,
+		executor: newExecutor(EcsProviderName, providerConfig),
+	}
+}
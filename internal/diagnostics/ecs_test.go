@@ -0,0 +1,96 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+// ecsScript reports a diff per checker and their sum for the full check, recording the arguments of every run
+const ecsScript = `echo "$@" >> arguments
+case "$*" in
+*--only=*ArraySyntaxFixer*)
+	cat <<'JSON'
+{"totals": {"errors": 0, "diffs": 1}, "files": {"src/User.php": {"diffs": [{"diff": "--- Original\n+++ New\n@@ -1,4 +1,4 @@\n <?php\n \n-$a = array();\n+$a = [];\n echo \"hello\";\n", "applied_checkers": ["PhpCsFixer\\Fixer\\ArrayNotation\\ArraySyntaxFixer"]}]}}}
+JSON
+	;;
+*--only=*SingleQuoteFixer*)
+	cat <<'JSON'
+{"totals": {"errors": 0, "diffs": 1}, "files": {"src/User.php": {"diffs": [{"diff": "--- Original\n+++ New\n@@ -1,4 +1,4 @@\n <?php\n \n $a = array();\n-echo \"hello\";\n+echo 'hello';\n", "applied_checkers": ["PhpCsFixer\\Fixer\\StringNotation\\SingleQuoteFixer"]}]}}}
+JSON
+	;;
+*)
+	cat <<'JSON'
+{"totals": {"errors": 1, "diffs": 1}, "files": {"src/User.php": {
+  "errors": [{"line": 1, "file_path": "src/User.php", "message": "Missing file doc comment", "source_class": "PHP_CodeSniffer\\Standards\\Squiz\\Sniffs\\Commenting\\FileCommentSniff"}],
+  "diffs": [{"diff": "--- Original\n+++ New\n@@ -1,4 +1,4 @@\n <?php\n \n-$a = array();\n-echo \"hello\";\n+$a = [];\n+echo 'hello';\n", "applied_checkers": ["PhpCsFixer\\Fixer\\ArrayNotation\\ArraySyntaxFixer", "PhpCsFixer\\Fixer\\StringNotation\\SingleQuoteFixer"]}]
+}}}
+JSON
+	;;
+esac
+exit 1
+`
+
+func TestEcs_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "ecs", ecsScript)
+	providerConfig := tool.providerConfig("vendor/bin/ecs")
+	providerConfig.ConfigFile = "ecs.php"
+	provider := diagnostics.NewEcs(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, tool.path("src/User.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	runs := strings.Split(strings.TrimSpace(tool.readFile(t, "arguments")), "\n")
+	if len(runs) != 3 || !strings.HasPrefix(runs[0], "check src/User.php --output-format=json --no-progress-bar --config=ecs.php") {
+		t.Fatalf("Expected the check then a run per checker, got %q", runs)
+	}
+	if !strings.HasSuffix(runs[1], `--only=PhpCsFixer\Fixer\ArrayNotation\ArraySyntaxFixer`) {
+		t.Errorf("Expected a run limited to the checker, got %q", runs[1])
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %+v", result)
+	}
+
+	sniffError := result[0]
+	if sniffError.Range.Start.Line != 0 || sniffError.Severity != protocol.DiagnosticSeverityError || sniffError.Message != "Missing file doc comment" {
+		t.Errorf("Expected the sniff error on the first line, got %+v", sniffError)
+	}
+
+	arraySyntax := result[1]
+	if arraySyntax.Range.Start.Line != 2 || arraySyntax.Severity != protocol.DiagnosticSeverityWarning || arraySyntax.Code != `PhpCsFixer\Fixer\ArrayNotation\ArraySyntaxFixer` {
+		t.Errorf("Expected the array syntax on its line, got %+v", arraySyntax)
+	}
+	if arraySyntax.Message != "Style issue (ArraySyntaxFixer)" {
+		t.Errorf("Unexpected message: %q", arraySyntax.Message)
+	}
+
+	if singleQuote := result[2]; singleQuote.Range.Start.Line != 3 || singleQuote.Code != `PhpCsFixer\Fixer\StringNotation\SingleQuoteFixer` {
+		t.Errorf("Expected the quotes on their line, got %+v", singleQuote)
+	}
+}
+
+func TestEcs_Format(t *testing.T) {
+	tool := newFakeTool(t, "ecs", ecsScript)
+	providerConfig := tool.providerConfig("vendor/bin/ecs")
+	providerConfig.Format = config.FormatConfig{Enabled: true}
+	provider := diagnostics.NewEcs(providerConfig)
+
+	formatted, err := provider.Format(context.Background(), tool.path("src/User.php"), "<?php\n\n$a = array();\necho \"hello\";\n")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if formatted != "<?php\n\n$a = [];\necho 'hello';\n" {
+		t.Errorf("Expected the diff applied, got %q", formatted)
+	}
+}
@@ -0,0 +1,18 @@
+package diagnostics
+
+import (
+	"context"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+// Exposes the output parsers to the benchmarks
+
+func (dp *PhpCsFixer) ParseDiffForDiagnostics(diff string) []protocol.Range {
+	return dp.parseDiffForDiagnostics(diff)
+}
+
+func (dp *PhpStan) ParseOutput(ctx context.Context, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	return dp.parseOutput(ctx, "", result)
+}
@@ -0,0 +1,78 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+)
+
+// toolConfigFiles are the configuration files of the tools, in the lookup order of each tool
+var toolConfigFiles = []struct {
+	providerId string
+	binary     string
+	files      []string
+}{
+	{PhpStanProviderId, "phpstan", []string{"phpstan.neon", "phpstan.neon.dist", "phpstan.dist.neon"}},
+	{PhpCsFixerProviderId, "php-cs-fixer", []string{".php-cs-fixer.php", ".php-cs-fixer.dist.php"}},
+	{DeptracProviderId, "deptrac", []string{"deptrac.yaml", "deptrac.yml"}},
+	{PhpInsightsProviderId, "phpinsights", []string{"phpinsights.php"}},
+	{TwigCsFixerProviderId, "twig-cs-fixer", []string{".twig-cs-fixer.php", ".twig-cs-fixer.dist.php"}},
+	{PintProviderId, "pint", []string{"pint.json"}},
+	{EcsProviderId, "ecs", []string{"ecs.php"}},
+	// No provider yet, only reported
+	{"", "phpcs", []string{"phpcs.xml", ".phpcs.xml", "phpcs.xml.dist", ".phpcs.xml.dist"}},
+	{"", "psalm", []string{"psalm.xml", "psalm.xml.dist"}},
+	{"", "rector", []string{"rector.php"}},
+}
+
+// GenerateProviders returns the providers matching the tool configuration files found in the project,
+// running in the container, and notes about the tools found without a provider. phplint is always
+// enabled. Tools required in composer.json or found in the local vendor/bin run from vendor/bin.
+func GenerateProviders(projectRoot string, containerName string) (map[string]config.DiagnosticsProvider, []string) {
+	providers := map[string]config.DiagnosticsProvider{
+		PhpLintProviderId: {Enabled: true, Container: containerName, Path: "php"},
+	}
+	var notes []string
+
+	installed := composerPackages(projectRoot)
+	for _, tool := range detectableTools {
+		for _, composerPackage := range tool.packages {
+			if installed[composerPackage] {
+				installed[tool.binary] = true
+			}
+		}
+	}
+
+	for _, tool := range toolConfigFiles {
+		var configFile string
+		for _, file := range tool.files {
+			if _, err := os.Stat(filepath.Join(projectRoot, file)); err == nil {
+				configFile = file
+				break
+			}
+		}
+		if configFile == "" {
+			continue
+		}
+
+		if tool.providerId == "" {
+			notes = append(notes, fmt.Sprintf("%s found, but php-diagls has no provider for %s", configFile, tool.binary))
+			continue
+		}
+
+		toolPath := tool.binary
+		if _, err := os.Stat(filepath.Join(projectRoot, "vendor", "bin", tool.binary)); err == nil || installed[tool.binary] {
+			toolPath = "vendor/bin/" + tool.binary
+		}
+		providers[tool.providerId] = config.DiagnosticsProvider{
+			Enabled:    true,
+			Container:  containerName,
+			Path:       toolPath,
+			ConfigFile: configFile,
+		}
+	}
+
+	return providers, notes
+}
@@ -0,0 +1,47 @@
+package diagnostics_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func TestGenerateProviders(t *testing.T) {
+	projectRoot := t.TempDir()
+	files := map[string]string{
+		"composer.json":          `{"require-dev": {"friendsofphp/php-cs-fixer": "^3.0"}}`,
+		".php-cs-fixer.dist.php": "<?php\n",
+		"phpstan.neon":           "parameters:\n",
+		"phpcs.xml.dist":         "<ruleset/>\n",
+		"deptrac.yaml":           "deptrac:\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectRoot, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	providers, notes := diagnostics.GenerateProviders(projectRoot, "app")
+
+	expected := map[string][2]string{
+		diagnostics.PhpLintProviderId:    {"php", ""},
+		diagnostics.PhpStanProviderId:    {"phpstan", "phpstan.neon"},
+		diagnostics.PhpCsFixerProviderId: {"vendor/bin/php-cs-fixer", ".php-cs-fixer.dist.php"},
+		diagnostics.DeptracProviderId:    {"deptrac", "deptrac.yaml"},
+	}
+	if len(providers) != len(expected) {
+		t.Fatalf("Expected providers %v, got %v", expected, providers)
+	}
+	for id, settings := range expected {
+		provider := providers[id]
+		if !provider.Enabled || provider.Container != "app" || provider.Path != settings[0] || provider.ConfigFile != settings[1] {
+			t.Errorf("Unexpected %s provider: %+v", id, provider)
+		}
+	}
+
+	if len(notes) != 1 {
+		t.Errorf("Expected a note about phpcs, got %v", notes)
+	}
+}
@@ -0,0 +1,134 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	InfectionProviderId   string = "infection"
+	InfectionProviderName string = "infection"
+
+	// A mutation run takes minutes, the file is mutated once saving settles
+	infectionSaveDebounce = 30 * time.Second
+)
+
+// InfectionEscapedMutant is an escaped mutant in the GitLab code quality report of Infection, check_name
+// being the mutator, content the diff of the mutation and the line 1-based
+type InfectionEscapedMutant struct {
+	CheckName   string `json:"check_name"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+	Location    struct {
+		Path  string `json:"path"`
+		Lines struct {
+			Begin int `json:"begin"`
+		} `json:"lines"`
+	} `json:"location"`
+}
+
+// InfectionOutputResult is the GitLab code quality report of Infection, listing the escaped mutants
+type InfectionOutputResult []InfectionEscapedMutant
+
+type Infection struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *Infection) Id() string {
+	return InfectionProviderId
+}
+
+func (dp *Infection) Name() string {
+	return InfectionProviderName
+}
+
+// Analyze mutates the file and runs the tests covering it, reporting the mutants the tests didn't kill
+func (dp *Infection) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+// analyzeCommand writes the GitLab report to stdout, through file descriptor 3 as Infection prints its
+// progress and summary there
+func (dp *Infection) analyzeCommand(relativeFilePath string) string {
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--configuration=%s", dp.config.ConfigFile)
+	}
+
+	return fmt.Sprintf("%s --filter=%s %s --logger-gitlab=php://fd/3 --no-progress --no-interaction 3>&1 >/dev/null 2>&1", dp.config.Path, relativeFilePath, configArg)
+}
+
+func (dp *Infection) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running infection: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult InfectionOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, mutant := range fullAnalysisResult {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, mutant.Location.Path); !found || resolvedPath != filePath {
+			continue
+		}
+
+		diagnostics = append(diagnostics, dp.mutantDiagnostic(mutant))
+	}
+
+	return diagnostics, nil
+}
+
+// mutantDiagnostic reports the escaped mutant as a hint on the mutated line, with the diff of the mutation
+func (dp *Infection) mutantDiagnostic(mutant InfectionEscapedMutant) protocol.Diagnostic {
+	line := uint32(0)
+	if mutant.Location.Lines.Begin > 0 {
+		line = uint32(mutant.Location.Lines.Begin - 1)
+	}
+
+	message := fmt.Sprintf("Escaped mutant: %s", mutant.CheckName)
+	if diff := strings.TrimSpace(mutant.Content); diff != "" {
+		message += "\n" + diff
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: protocol.DiagnosticSeverityHint,
+		Source:   dp.Name(),
+		Message:  message,
+	}
+	if mutant.CheckName != "" {
+		diagnostic.Code = mutant.CheckName
+	}
+	return diagnostic
+}
+
+func NewInfection(providerConfig config.DiagnosticsProvider) *Infection {
+	return &Infection{
+		config:   providerConfig,
+		executor: newExecutor(InfectionProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,66 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestInfection_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "infection", `echo "$@" > arguments
+echo "Running initial test suite..."
+cat >&3 <<'JSON'
+[
+  {"type": "issue", "fingerprint": "a1", "check_name": "GreaterThan", "description": "Escaped Mutant for Mutator GreaterThan", "content": "--- Original\n+++ New\n@@ @@\n-        return $amount > 0;\n+        return $amount >= 0;", "categories": ["Escaped Mutant"], "location": {"path": "src/Price.php", "lines": {"begin": 12}}, "severity": "major"},
+  {"type": "issue", "fingerprint": "b2", "check_name": "PublicVisibility", "description": "Escaped Mutant for Mutator PublicVisibility", "content": "", "categories": ["Escaped Mutant"], "location": {"path": "src/Other.php", "lines": {"begin": 3}}, "severity": "major"}
+]
+JSON
+`)
+	filePath := tool.writeFile(t, "src/Price.php", "{}")
+
+	providerConfig := tool.providerConfig("vendor/bin/infection")
+	providerConfig.ConfigFile = "infection.json5"
+	provider := diagnostics.NewInfection(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if !strings.Contains(content, "--filter=src/Price.php") || !strings.Contains(content, "--configuration=infection.json5") {
+		t.Errorf("Expected a run restricted to the file, got %q", content)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+
+	mutant := result[0]
+	if mutant.Range.Start.Line != 11 || mutant.Severity != protocol.DiagnosticSeverityHint || mutant.Code != "GreaterThan" {
+		t.Errorf("Expected a hint on the mutated line, got %+v", mutant)
+	}
+	if !strings.HasPrefix(mutant.Message, "Escaped mutant: GreaterThan\n") || !strings.Contains(mutant.Message, "+        return $amount >= 0;") {
+		t.Errorf("Expected the mutator and the diff, got %q", mutant.Message)
+	}
+}
+
+func TestSaveDebounce(t *testing.T) {
+	if debounce := diagnostics.SaveDebounce(diagnostics.InfectionProviderId, config.DiagnosticsProvider{}); debounce <= 0 {
+		t.Errorf("Expected Infection to wait for the saves to settle, got %v", debounce)
+	}
+	if debounce := diagnostics.SaveDebounce(diagnostics.PhpUnitProviderId, config.DiagnosticsProvider{}); debounce != 0 {
+		t.Errorf("Expected phpunit to run on every save, got %v", debounce)
+	}
+	if debounce := diagnostics.SaveDebounce(diagnostics.PhpUnitProviderId, config.DiagnosticsProvider{DebounceSeconds: 5}); debounce.Seconds() != 5 {
+		t.Errorf("Expected the configured debounce, got %v", debounce)
+	}
+}
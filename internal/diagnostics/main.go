@@ -1,17 +1,374 @@
 package diagnostics
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
 	"go.lsp.dev/protocol"
 )
 
 type DiagnosticsProvider interface {
 	Id() string
 	Name() string
-	Analyze(filePath string) ([]protocol.Diagnostic, error)
+	Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error)
+}
+
+// ContentAnalyzer is implemented by providers able to analyze content which is not on disk, e.g. the
+// version of a file staged in git. The file path is only used to resolve the project and the rules.
+type ContentAnalyzer interface {
+	AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error)
+}
+
+// BatchAnalyzer is implemented by providers analyzing many files in one run, e.g. for the workspace scan
+type BatchAnalyzer interface {
+	// AnalyzeFiles analyzes the files of the project, returning the diagnostics by file path. Files missing
+	// from the results are clean.
+	AnalyzeFiles(ctx context.Context, projectRoot string, filePaths []string) (map[string][]protocol.Diagnostic, error)
+}
+
+// BufferSyncer is implemented by providers analyzing unsaved buffers in a shadow copy of the project
+type BufferSyncer interface {
+	// SyncBuffer makes the unsaved content of the file visible to the next analyses
+	SyncBuffer(ctx context.Context, filePath string, content string) error
+	// RestoreBuffer goes back to the file on disk once the buffer was saved or closed
+	RestoreBuffer(ctx context.Context, filePath string) error
+	// RefreshFile copies the file changed on disk again before the next analysis
+	RefreshFile(filePath string)
+}
+
+// RuleExplainer is implemented by providers able to document the rules reported in their diagnostics
+type RuleExplainer interface {
+	// ExplainRule returns the full description of the rule, with examples when the tool provides them
+	ExplainRule(ctx context.Context, rule string) (string, error)
+}
+
+// Watcher is implemented by providers able to keep their tool running and report results as files change
+type Watcher interface {
+	// Watch runs the tool in watch mode in the project until it exits or the context is cancelled. Every
+	// report covers the whole project and is passed to onReport keyed by file path, files missing from
+	// it are clean.
+	Watch(ctx context.Context, projectRoot string, onReport func(results map[string][]protocol.Diagnostic)) error
+}
+
+type failureTrackerKey struct{}
+
+// TrackFailures returns a context recording whether a provider failed to run its tool. Providers log
+// such failures and return no diagnostics, the returned function tells them apart from a clean file by
+// returning the first failure, a *container.CommandError.
+func TrackFailures(ctx context.Context) (context.Context, func() error) {
+	tracker := &failureTracker{}
+	return context.WithValue(ctx, failureTrackerKey{}, tracker), tracker.failure
+}
+
+type failureTracker struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (tracker *failureTracker) failure() error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.err
+}
+
+// markFailed records a tool failure in the context tracking failures, if any
+func markFailed(ctx context.Context, err error) {
+	if tracker, ok := ctx.Value(failureTrackerKey{}).(*failureTracker); ok {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		if tracker.err == nil {
+			tracker.err = err
+		}
+	}
+}
+
+type rawResultKey struct{}
+
+type rawResultRecorder struct {
+	mu     sync.Mutex
+	result interface{}
+}
+
+// TrackRawResult returns a context recording the parsed tool output of a provider, for clients building
+// their own views of the results. The returned function returns it, nil when no output was parsed.
+func TrackRawResult(ctx context.Context) (context.Context, func() interface{}) {
+	recorder := &rawResultRecorder{}
+	return context.WithValue(ctx, rawResultKey{}, recorder), func() interface{} {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		return recorder.result
+	}
+}
+
+// recordRawResult stores the parsed tool output in the context tracking it, if any
+func recordRawResult(ctx context.Context, result interface{}) {
+	if recorder, ok := ctx.Value(rawResultKey{}).(*rawResultRecorder); ok {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.result = result
+	}
+}
+
+type documentReaderKey struct{}
+
+// WithDocumentReader returns a context making the providers read the analyzed documents with the reader
+// instead of from disk, e.g. from the synchronized buffers when the server doesn't share the filesystem
+func WithDocumentReader(ctx context.Context, read func(filePath string) (string, error)) context.Context {
+	return context.WithValue(ctx, documentReaderKey{}, read)
+}
+
+// readDocument returns the content of the analyzed document, with the reader of the context if any
+func readDocument(ctx context.Context, filePath string) ([]byte, error) {
+	if read, ok := ctx.Value(documentReaderKey{}).(func(string) (string, error)); ok {
+		content, err := read(filePath)
+		return []byte(content), err
+	}
+	return os.ReadFile(filePath)
+}
+
+type sharedRunsKey struct{}
+
+type sharedRuns struct {
+	mu   sync.Mutex
+	runs map[string]*sharedRun
+}
+
+type sharedRun struct {
+	done   chan struct{}
+	result *container.CommandResult
+}
+
+// WithSharedRuns returns a context where the providers of an analysis run identical commands once, e.g.
+// phpdoc reading its errors from the phpstan analysis of the file. Later runs get the output of the first.
+func WithSharedRuns(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sharedRunsKey{}, &sharedRuns{runs: make(map[string]*sharedRun)})
+}
+
+// runShared runs the command with the executor, or waits for the same command run on the same stdin by
+// another provider of the analysis sharing the runs of the context
+func runShared(ctx context.Context, executor *container.Executor, projectRoot string, containerCmd string, stdin ...string) *container.CommandResult {
+	shared, ok := ctx.Value(sharedRunsKey{}).(*sharedRuns)
+	if !ok {
+		return executor.Run(ctx, projectRoot, containerCmd, stdin...)
+	}
+
+	key := strings.Join(executor.Containers(), ",") + "\x00" + projectRoot + "\x00" + containerCmd + "\x00" + strings.Join(stdin, "\x00")
+	shared.mu.Lock()
+	run, running := shared.runs[key]
+	if !running {
+		run = &sharedRun{done: make(chan struct{})}
+		shared.runs[key] = run
+	}
+	shared.mu.Unlock()
+
+	if !running {
+		run.result = executor.Run(ctx, projectRoot, containerCmd, stdin...)
+		close(run.done)
+		return run.result
+	}
+
+	select {
+	case <-run.done:
+		return run.result
+	case <-ctx.Done():
+		return &container.CommandResult{Err: ctx.Err()}
+	}
+}
+
+// outputFailure is the failure of a command whose output can't be used: the command failure when the
+// tool didn't run, else a tool error
+func outputFailure(result *container.CommandResult, err error) error {
+	if failure := result.Failure(); failure != nil {
+		return failure
+	}
+	return &container.CommandError{Kind: container.FailureToolError, Err: err}
+}
+
+// Patterns of the files other than the PHP ones analyzed by the document providers, matched against the
+// file name. Document providers only analyze these files, or the ones with their fileExtensions.
+var providerDocuments = map[string][]string{
+	BladeLintProviderId:         {BladeTemplatePattern},
+	ComposerAuditProviderId:     {ComposerJsonFile, ComposerLockFile},
+	ComposerNormalizeProviderId: {ComposerJsonFile},
+	ComposerUnusedProviderId:    {ComposerJsonFile, ComposerLockFile},
+	ComposerValidateProviderId:  {ComposerJsonFile, ComposerLockFile},
+	SymfonyYamlProviderId:       {"*.yaml", "*.yml"},
+	TwigCsFixerProviderId:       {TwigTemplatePattern},
+}
+
+// Documents whose analysis results are published on another document of their directory
+var publishedDocuments = map[string]string{
+	ComposerLockFile: ComposerJsonFile,
+}
+
+// documentPatterns returns the patterns of the documents of the provider, its fileExtensions replacing
+// the default ones
+func documentPatterns(providerId string, providerConfig config.DiagnosticsProvider) []string {
+	defaults, isDocumentProvider := providerDocuments[providerId]
+	if !isDocumentProvider || len(providerConfig.FileExtensions) == 0 {
+		return defaults
+	}
+
+	patterns := make([]string, 0, len(providerConfig.FileExtensions))
+	for _, extension := range providerConfig.FileExtensions {
+		patterns = append(patterns, "*"+extension)
+	}
+	return patterns
+}
+
+// HandlesDocument reports whether the provider analyzes the file as a document other than a PHP file
+func HandlesDocument(providerId string, providerConfig config.DiagnosticsProvider, filePath string) bool {
+	name := filepath.Base(filePath)
+	for _, pattern := range documentPatterns(providerId, providerConfig) {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDocumentProvider reports whether the provider only analyzes documents other than PHP files
+func IsDocumentProvider(providerId string) bool {
+	_, isDocumentProvider := providerDocuments[providerId]
+	return isDocumentProvider
+}
+
+// AnalyzesFile reports whether the provider analyzes the file: document providers analyze their
+// documents only, the other providers anything but these documents
+func AnalyzesFile(providerId string, providerConfigs map[string]config.DiagnosticsProvider, filePath string) bool {
+	if _, isDocumentProvider := providerDocuments[providerId]; isDocumentProvider {
+		return HandlesDocument(providerId, providerConfigs[providerId], filePath)
+	}
+
+	for documentProviderId := range providerDocuments {
+		if HandlesDocument(documentProviderId, providerConfigs[documentProviderId], filePath) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublishedFile returns the file the diagnostics of an analysis of filePath are published on, e.g.
+// composer.json for composer.lock changes
+func PublishedFile(filePath string) string {
+	if published, exists := publishedDocuments[filepath.Base(filePath)]; exists {
+		return filepath.Join(filepath.Dir(filePath), published)
+	}
+	return filePath
+}
+
+// IsLightweightProvider reports whether the provider is cheap enough to run on every file,
+// including generated ones
+func IsLightweightProvider(providerId string) bool {
+	return providerId == PhpLintProviderId || providerId == ParallelLintProviderId
+}
+
+// HasFileLocalResults reports whether the provider results only depend on the file content and the
+// provider configuration, so they can be cached by content. phpstan results depend on other files.
+func HasFileLocalResults(providerId string) bool {
+	return providerId == PhpLintProviderId || providerId == PhpCsFixerProviderId || providerId == ParallelLintProviderId
+}
+
+// stdinCopyCommand runs the command of a tool unable to read stdin on a copy of it, a temporary file of the
+// container removed after the run. The project configuration still applies, the tool running in the project
+// root, and the shell pid keeps concurrent runs apart.
+func stdinCopyCommand(toolId string, command func(target string) string) string {
+	stdinFile := fmt.Sprintf("/tmp/php-diagls-%s-$$.php", toolId)
+	return fmt.Sprintf("%s; rm -f %s", command(fmt.Sprintf("$(cat > %s; echo %s)", stdinFile, stdinFile)), stdinFile)
+}
+
+// stdinFormat formats the content piped through stdin to the format command of a provider, within its
+// format timeout, 30 seconds by default. diffs returns the unified diffs of the changes found in the tool
+// output, applied in order to the content.
+func stdinFormat(ctx context.Context, name string, providerConfig config.DiagnosticsProvider, executor *container.Executor, filePath string, content string, command string, diffs func(result *container.CommandResult, duration time.Duration) ([]string, error)) (string, error) {
+	if !providerConfig.Format.Enabled {
+		return content, fmt.Errorf("formatting is not enabled for %s", name)
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		timeout := 30 * time.Second
+		if providerConfig.Format.TimeoutSeconds > 0 {
+			timeout = time.Duration(providerConfig.Format.TimeoutSeconds) * time.Second
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		log.Printf("%s%s Added %v timeout for %s formatting", logging.LogTagLSP, logging.LogTagServer, timeout, name)
+	}
+
+	startTime := time.Now()
+	result := executor.Run(ctx, providerRoot(providerConfig, filePath), command, content)
+	duration := time.Since(startTime)
+
+	if result.Err != nil {
+		if ctx.Err() != nil {
+			log.Printf("%s%s %s execution cancelled: %v", logging.LogTagLSP, logging.LogTagServer, name, ctx.Err())
+			return content, fmt.Errorf("formatting cancelled: %w", ctx.Err())
+		}
+
+		log.Printf("%s%s %s failed after %v: %v", logging.LogTagLSP, logging.LogTagServer, name, duration, result.Err)
+		return content, fmt.Errorf("%s command failed: %w", name, result.Err)
+	}
+
+	if result.Truncated {
+		// Applying a partial diff would corrupt the document
+		return content, fmt.Errorf("%s output exceeded the output cap, formatting skipped", name)
+	}
+
+	changes, err := diffs(result, duration)
+	if err != nil {
+		return content, err
+	}
+
+	formattedContent := content
+	for _, diff := range changes {
+		if strings.TrimSpace(diff) == "" {
+			continue
+		}
+
+		if formattedContent, err = utils.ApplyUnifiedDiff(formattedContent, diff); err != nil {
+			return content, fmt.Errorf("failed to apply diff: %w", err)
+		}
+	}
+
+	return formattedContent, nil
+}
+
+// RunsOnSave reports whether the provider only runs when the file is saved, or when requested: the providers
+// configured with runOn save, and the test runners unless configured otherwise
+func RunsOnSave(providerId string, providerConfig config.DiagnosticsProvider) bool {
+	if providerConfig.RunOn == "" {
+		return providerId == PhpUnitProviderId || providerId == InfectionProviderId
+	}
+	return providerConfig.RunOn == config.RunOnSave
+}
+
+// SaveDebounce returns the time a save provider waits for the file to be saved again before running, Infection
+// waiting 30 seconds unless configured otherwise
+func SaveDebounce(providerId string, providerConfig config.DiagnosticsProvider) time.Duration {
+	if providerConfig.DebounceSeconds > 0 {
+		return time.Duration(providerConfig.DebounceSeconds) * time.Second
+	}
+	if providerId == InfectionProviderId {
+		return infectionSaveDebounce
+	}
+	return 0
+}
+
+// ProviderIds lists the ids of the supported diagnostics providers
+func ProviderIds() []string {
+	return []string{BladeLintProviderId, ComposerAuditProviderId, ComposerNormalizeProviderId, ComposerRequireCheckerProviderId, ComposerUnusedProviderId, ComposerValidateProviderId, DeptracProviderId, DoctrineSchemaProviderId, EcsProviderId, InfectionProviderId, ParallelLintProviderId, PhpCbfProviderId, PhpCompatibilityProviderId, PhpCpdProviderId, PhpCsFixerProviderId, PhpDocProviderId, PhpInsightsProviderId, PhpLintProviderId, PhpMetricsProviderId, PhpMndProviderId, PhpStanProviderId, PhpUnitProviderId, PintProviderId, SymfonyYamlProviderId, TwigCsFixerProviderId, VarDumpCheckProviderId}
 }
 
 func NewDiagnosticsProvider(providerId string, providerConfig config.DiagnosticsProvider) (DiagnosticsProvider, error) {
@@ -25,23 +382,162 @@ func NewDiagnosticsProvider(providerId string, providerConfig config.Diagnostics
 		return NewPhpCsFixer(providerConfig), nil
 	case PhpStanProviderId:
 		return NewPhpStan(providerConfig), nil
+	case PintProviderId:
+		return NewPint(providerConfig), nil
+	case EcsProviderId:
+		return NewEcs(providerConfig), nil
+	case PhpCbfProviderId:
+		return NewPhpCbf(providerConfig), nil
+	case PhpDocProviderId:
+		return NewPhpDoc(providerConfig), nil
+	case BladeLintProviderId:
+		return NewBladeLint(providerConfig), nil
 	case PhpLintProviderId:
 		return NewPhpLint(providerConfig), nil
+	case ParallelLintProviderId:
+		return NewParallelLint(providerConfig), nil
+	case DeptracProviderId:
+		return NewDeptrac(providerConfig), nil
+	case ComposerRequireCheckerProviderId:
+		return NewComposerRequireChecker(providerConfig), nil
+	case ComposerUnusedProviderId:
+		return NewComposerUnused(providerConfig), nil
+	case DoctrineSchemaProviderId:
+		return NewDoctrineSchema(providerConfig), nil
+	case InfectionProviderId:
+		return NewInfection(providerConfig), nil
+	case PhpCompatibilityProviderId:
+		return NewPhpCompatibility(providerConfig), nil
+	case PhpInsightsProviderId:
+		return NewPhpInsights(providerConfig), nil
+	case PhpUnitProviderId:
+		return NewPhpUnit(providerConfig), nil
+	case PhpMetricsProviderId:
+		return NewPhpMetrics(providerConfig), nil
+	case PhpMndProviderId:
+		return NewPhpMnd(providerConfig), nil
+	case SymfonyYamlProviderId:
+		return NewSymfonyYaml(providerConfig), nil
+	case TwigCsFixerProviderId:
+		return NewTwigCsFixer(providerConfig), nil
+	case VarDumpCheckProviderId:
+		return NewVarDumpCheck(providerConfig), nil
+	case PhpCpdProviderId:
+		return NewPhpCpd(providerConfig), nil
+	case ComposerAuditProviderId:
+		return NewComposerAudit(providerConfig), nil
+	case ComposerValidateProviderId:
+		return NewComposerValidate(providerConfig), nil
+	case ComposerNormalizeProviderId:
+		return NewComposerNormalize(providerConfig), nil
 	default:
+		// Tools without a provider, reporting in a supported format
+		if providerConfig.OutputFormat != "" {
+			return NewCustom(providerId, providerConfig), nil
+		}
 		return nil, fmt.Errorf("unknown diagnostics provider: %s", providerId)
 	}
 }
 
-func validateProviderConfig(providerConfig config.DiagnosticsProvider) error {
-	err := container.ValidateContainer(providerConfig.Container)
-	if err != nil {
+// providerRoot returns the root of the project the provider configuration was loaded for, the commands run
+// in. Configurations created without one look for the closest directory holding the default config file.
+func providerRoot(providerConfig config.DiagnosticsProvider, filePath string) string {
+	if providerConfig.ProjectRoot != "" {
+		return providerConfig.ProjectRoot
+	}
+	return utils.FindProjectRoot(filePath)
+}
+
+// newExecutor creates the executor running the provider commands, including the configured fallback chain
+func newExecutor(label string, providerConfig config.DiagnosticsProvider) *container.Executor {
+	var fallbacks []container.Backend
+	for _, kind := range providerConfig.Fallback {
+		switch kind {
+		case container.BackendComposeService:
+			if providerConfig.ComposeService == "" {
+				log.Printf("%s: fallback %s ignored, composeService is not configured", label, kind)
+				continue
+			}
+			fallbacks = append(fallbacks, container.Backend{
+				Kind:           kind,
+				ComposeService: providerConfig.ComposeService,
+				ComposeFile:    providerConfig.ComposeFile,
+			})
+		case container.BackendLocal:
+			fallbacks = append(fallbacks, container.Backend{
+				Kind:          kind,
+				LocalToolPath: providerConfig.LocalPath,
+			})
+		case container.BackendContainer:
+			// Containers are always tried first
+		default:
+			log.Printf("%s: unknown fallback %s ignored", label, kind)
+		}
+	}
+
+	return container.NewExecutor(providerConfig.ContainerNames()...).
+		WithFallbacks(label, providerConfig.Path, fallbacks...).
+		WithLimits(container.Limits{Nice: providerConfig.Limits.Nice, CpuLimit: providerConfig.Limits.CpuLimit}).
+		WithReadOnly(providerConfig.ReadOnly).
+		WithNotifier(providerConfig.BackendNotifier)
+}
+
+// unmarshalToolOutput decodes the JSON output of a tool. When the output was capped, the intact
+// prefix is decoded so the issues reported so far are not lost.
+func unmarshalToolOutput(result *container.CommandResult, v interface{}) error {
+	err := json.Unmarshal(result.Stdout, v)
+	if err == nil || !result.Truncated {
 		return err
 	}
 
-	err = container.ValidateBinaryInContainer(providerConfig.Container, providerConfig.Path)
-	if err != nil {
+	repaired := utils.RepairTruncatedJSON(result.Stdout)
+	if repaired == nil {
 		return err
 	}
 
+	log.Printf("Output truncated at %d bytes, decoding its intact prefix", len(result.Stdout))
+	return json.Unmarshal(repaired, v)
+}
+
+func validateProviderConfig(providerConfig config.DiagnosticsProvider) error {
+	err := validateContainers(providerConfig)
+	if err != nil && len(providerConfig.Fallback) > 0 {
+		// The executor switches to the fallback chain on the first command
+		log.Printf("Container validation failed, relying on fallback %v: %v", providerConfig.Fallback, err)
+		return nil
+	}
+
+	return err
+}
+
+func validateContainers(providerConfig config.DiagnosticsProvider) error {
+	containerNames := providerConfig.ContainerNames()
+	if len(containerNames) == 0 {
+		containerNames = []string{providerConfig.Container}
+	}
+
+	for _, containerName := range containerNames {
+		err := container.ValidateContainer(containerName)
+		if err != nil {
+			return err
+		}
+
+		err = container.ValidateBinaryInContainer(containerName, providerConfig.Path)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// modifiedBefore reports whether the files were last modified before the time
+func modifiedBefore(at time.Time, filePaths ...string) bool {
+	for _, filePath := range filePaths {
+		info, err := os.Stat(filePath)
+		if err != nil || !info.ModTime().Before(at) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package diagnostics_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+// fakeTool is a project whose tool is a shell script, run through the local fallback as the container of the
+// provider doesn't exist
+type fakeTool struct {
+	projectRoot string
+	binary      string
+}
+
+// newFakeTool creates a project holding a php-diagls configuration and the script of the tool. The script
+// runs in the project root, where it can record its arguments and input for the test.
+func newFakeTool(t *testing.T, name string, script string) *fakeTool {
+	t.Helper()
+
+	tool := &fakeTool{projectRoot: t.TempDir()}
+	tool.writeFile(t, config.ConfigFileName, "{}")
+	tool.binary = tool.writeFile(t, name, "#!/bin/sh\n"+script)
+	if err := os.Chmod(tool.binary, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return tool
+}
+
+// outputScript is a script printing the output and exiting with the code
+func outputScript(output string, exitCode int) string {
+	return fmt.Sprintf("cat <<'OUTPUT'\n%s\nOUTPUT\nexit %d\n", output, exitCode)
+}
+
+// providerConfig configures a provider running the fake tool in place of the tool at path
+func (tool *fakeTool) providerConfig(path string) config.DiagnosticsProvider {
+	return config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "php-diagls-missing-container",
+		Path:      path,
+		Fallback:  []string{"local"},
+		LocalPath: tool.binary,
+	}
+}
+
+// path returns the absolute path of a project file
+func (tool *fakeTool) path(name string) string {
+	return filepath.Join(tool.projectRoot, filepath.FromSlash(name))
+}
+
+// writeFile writes a project file, creating its directory, and returns its path
+func (tool *fakeTool) writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	filePath := tool.path(name)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+// readFile returns the content of a project file, e.g. the arguments recorded by the script
+func (tool *fakeTool) readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(tool.path(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestWithDocumentReader(t *testing.T) {
+	projectRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectRoot, config.ConfigFileName), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The analyzed documents only exist in the buffers, reading them from disk fails
+	composerJson := filepath.Join(projectRoot, diagnostics.ComposerJsonFile)
+	phpFile := filepath.Join(projectRoot, "src", "Cart.php")
+	buffers := map[string]string{
+		composerJson: "{\n    \"name\": \"acme/cart\"\n}\n",
+		phpFile:      "<?php\n\nclass Cart\n{\n}\n",
+	}
+
+	providerConfig := config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "php-diagls-missing-container",
+		Path:      "/bin/false",
+	}
+
+	tests := []struct {
+		name     string
+		provider diagnostics.DiagnosticsProvider
+		filePath string
+	}{
+		{"composer-audit", diagnostics.NewComposerAudit(providerConfig), composerJson},
+		{"composer-normalize", diagnostics.NewComposerNormalize(providerConfig), composerJson},
+		{"composer-require-checker", diagnostics.NewComposerRequireChecker(providerConfig), composerJson},
+		{"composer-unused", diagnostics.NewComposerUnused(providerConfig), composerJson},
+		{"composer-validate", diagnostics.NewComposerValidate(providerConfig), composerJson},
+		{"doctrine-schema", diagnostics.NewDoctrineSchema(providerConfig), phpFile},
+		{"phpcbf", diagnostics.NewPhpCbf(providerConfig), phpFile},
+		{"phpdoc", diagnostics.NewPhpDoc(providerConfig), phpFile},
+		{"phpmetrics", diagnostics.NewPhpMetrics(providerConfig), phpFile},
+		{"phpmnd", diagnostics.NewPhpMnd(providerConfig), phpFile},
+		{"var-dump-check", diagnostics.NewVarDumpCheck(providerConfig), phpFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var read []string
+			ctx := diagnostics.WithDocumentReader(context.Background(), func(filePath string) (string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				read = append(read, filePath)
+				return buffers[filePath], nil
+			})
+
+			_, _ = tt.provider.Analyze(ctx, tt.filePath)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(read) == 0 || read[0] != tt.filePath {
+				t.Errorf("Expected %s to be read through the document reader, got %v", tt.filePath, read)
+			}
+		})
+	}
+}
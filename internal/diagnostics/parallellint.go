@@ -0,0 +1,160 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	ParallelLintProviderId   string = "parallellint"
+	ParallelLintProviderName string = "php-parallel-lint"
+
+	// Files per command of a batch, keeping the command line short
+	parallelLintBatchSize = 500
+)
+
+// ParallelLintOutputResult is the JSON report of php-parallel-lint
+type ParallelLintOutputResult struct {
+	Results struct {
+		CheckedFiles         int                 `json:"checkedFiles"`
+		FilesWithSyntaxError int                 `json:"filesWithSyntaxError"`
+		Errors               []ParallelLintError `json:"errors"`
+	} `json:"results"`
+}
+
+// ParallelLintError is a syntax error, or a file which couldn't be checked
+type ParallelLintError struct {
+	Type             string `json:"type"`
+	File             string `json:"file"`
+	Line             int    `json:"line"`
+	Message          string `json:"message"`
+	NormalizeMessage string `json:"normalizeMessage"`
+}
+
+type ParallelLint struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *ParallelLint) Id() string {
+	return ParallelLintProviderId
+}
+
+func (dp *ParallelLint) Name() string {
+	return ParallelLintProviderName
+}
+
+func (dp *ParallelLint) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	results, err := dp.AnalyzeFiles(ctx, providerRoot(dp.config, filePath), []string{filePath})
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+	if diagnostics, found := results[filepath.Clean(filePath)]; found {
+		return diagnostics, nil
+	}
+	return []protocol.Diagnostic{}, nil
+}
+
+// AnalyzeFiles checks the files in batches, with every configured PHP version. Errors of a version are
+// tagged with it when several versions are checked.
+func (dp *ParallelLint) AnalyzeFiles(ctx context.Context, projectRoot string, filePaths []string) (map[string][]protocol.Diagnostic, error) {
+	results := make(map[string][]protocol.Diagnostic)
+
+	phpBinaries := dp.config.PhpBinaries
+	if len(phpBinaries) == 0 {
+		phpBinaries = []string{""}
+	}
+
+	for start := 0; start < len(filePaths); start += parallelLintBatchSize {
+		end := min(start+parallelLintBatchSize, len(filePaths))
+		relativeFilePaths := make([]string, 0, end-start)
+		for _, filePath := range filePaths[start:end] {
+			relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+			relativeFilePaths = append(relativeFilePaths, relativeFilePath)
+		}
+
+		for _, phpBinary := range phpBinaries {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+
+			result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(phpBinary, relativeFilePaths))
+			version := ""
+			if len(phpBinaries) > 1 {
+				version = filepath.Base(phpBinary)
+			}
+			dp.parseOutput(ctx, projectRoot, version, result, results)
+		}
+	}
+
+	return results, nil
+}
+
+func (dp *ParallelLint) analyzeCommand(phpBinary string, relativeFilePaths []string) string {
+	phpArg := ""
+	if phpBinary != "" {
+		phpArg = fmt.Sprintf("-p %s", phpBinary)
+	}
+
+	return fmt.Sprintf("%s --json --no-colors %s %s 2>/dev/null", dp.config.Path, phpArg, strings.Join(relativeFilePaths, " "))
+}
+
+// parseOutput adds the errors of the report to the results, by file path
+func (dp *ParallelLint) parseOutput(ctx context.Context, projectRoot string, version string, result *container.CommandResult, results map[string][]protocol.Diagnostic) {
+	if result.Err != nil {
+		log.Printf("Error running php-parallel-lint: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return
+	}
+
+	var fullAnalysisResult ParallelLintOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	for _, lintError := range fullAnalysisResult.Results.Errors {
+		filePath, found := utils.ResolveToolPath(projectRoot, lintError.File)
+		if !found {
+			log.Printf("Skipping php-parallel-lint error of %s, not found in %s", lintError.File, projectRoot)
+			continue
+		}
+
+		line := uint32(0)
+		if lintError.Line > 0 {
+			line = uint32(lintError.Line - 1)
+		}
+
+		message := lintError.NormalizeMessage
+		if message == "" {
+			message = lintError.Message
+		}
+		if version != "" {
+			message = fmt.Sprintf("%s (%s)", message, version)
+		}
+
+		results[filePath] = append(results[filePath], protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+			Severity: protocol.DiagnosticSeverityError,
+			Source:   dp.Name(),
+			Message:  strings.TrimSpace(message),
+		})
+	}
+}
+
+func NewParallelLint(providerConfig config.DiagnosticsProvider) *ParallelLint {
+	return &ParallelLint{
+		config:   providerConfig,
+		executor: newExecutor(ParallelLintProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,73 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+// parallelLintScript reports a syntax error in b.php, only with the php7.4 binary
+const parallelLintScript = `case "$*" in
+  *"-p php7.4"*b.php*) echo '{"results":{"checkedFiles":2,"filesWithSyntaxError":1,"skippedFiles":[],"errors":[{"type":"syntaxError","file":"b.php","line":2,"message":"Parse error: syntax error, unexpected identifier \"A\" in b.php on line 2","normalizeMessage":"Syntax error, unexpected identifier \"A\"","blame":null}]}}'; exit 1;;
+  *) echo '{"results":{"checkedFiles":2,"filesWithSyntaxError":0,"skippedFiles":[],"errors":[]}}';;
+esac
+`
+
+func TestParallelLint_AnalyzeFiles(t *testing.T) {
+	tool := newFakeTool(t, "parallel-lint", parallelLintScript)
+	tool.writeFile(t, "a.php", "<?php\n")
+	tool.writeFile(t, "b.php", "<?php\nenum A {}\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/parallel-lint")
+	providerConfig.PhpBinaries = []string{"php7.4", "php8.3"}
+	provider := diagnostics.NewParallelLint(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	files := []string{tool.path("a.php"), tool.path("b.php")}
+	results, err := provider.AnalyzeFiles(ctx, tool.projectRoot, files)
+	if err != nil {
+		t.Fatalf("AnalyzeFiles failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected results for b.php only, got %+v", results)
+	}
+
+	diagnosticsB := results[files[1]]
+	if len(diagnosticsB) != 1 {
+		t.Fatalf("Expected 1 diagnostic in b.php, got %+v", diagnosticsB)
+	}
+	if diagnosticsB[0].Range.Start.Line != 1 || diagnosticsB[0].Message != `Syntax error, unexpected identifier "A" (php7.4)` {
+		t.Errorf("Unexpected diagnostic: %+v", diagnosticsB[0])
+	}
+}
+
+func TestParallelLint_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "parallel-lint", parallelLintScript)
+	tool.writeFile(t, "a.php", "<?php\n")
+	tool.writeFile(t, "b.php", "<?php\nenum A {}\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/parallel-lint")
+	providerConfig.PhpBinaries = []string{"php7.4"}
+	provider := diagnostics.NewParallelLint(providerConfig)
+
+	result, err := provider.Analyze(context.Background(), tool.path("b.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	// A single version isn't tagged
+	if len(result) != 1 || result[0].Message != `Syntax error, unexpected identifier "A"` {
+		t.Errorf("Expected the syntax error of b.php, got %+v", result)
+	}
+
+	result, err = provider.Analyze(context.Background(), tool.path("a.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected a.php to be clean, got %+v", result)
+	}
+}
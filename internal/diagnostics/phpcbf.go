@@ -0,0 +1,145 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpCbfProviderId   string = "phpcbf"
+	PhpCbfProviderName string = "phpcbf"
+)
+
+// PhpCbf runs the PHP_CodeSniffer fixer on the content piped through stdin, the fixed content being written to
+// stdout. The changes it would make are reported as style issues.
+type PhpCbf struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpCbf) Id() string {
+	return PhpCbfProviderId
+}
+
+func (dp *PhpCbf) Name() string {
+	return PhpCbfProviderName
+}
+
+func (dp *PhpCbf) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	return dp.AnalyzeContent(ctx, filePath, string(content))
+}
+
+// AnalyzeContent reports the lines phpcbf would change in the content
+func (dp *PhpCbf) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	fixedContent, result, err := dp.fix(ctx, filePath, content)
+	if err != nil {
+		log.Printf("Error running phpcbf: %v", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+
+	for _, lineRange := range diffRanges(utils.UnifiedDiff(filepath.Base(filePath), content, fixedContent)) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Message:  PhpCsFixerStyleIssueMessage,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// fixCommand fixes stdin with the configured standard, the file path selecting the rules of the standard
+func (dp *PhpCbf) fixCommand(relativeFilePath string) string {
+	standardArg := ""
+	if dp.config.ConfigFile != "" {
+		standardArg = fmt.Sprintf("--standard=%s", dp.config.ConfigFile)
+	}
+
+	return fmt.Sprintf("%s -q --stdin-path=%s %s - 2>/dev/null", dp.config.Path, relativeFilePath, standardArg)
+}
+
+// fix returns the content fixed by phpcbf, or an error when its output can't be trusted
+func (dp *PhpCbf) fix(ctx context.Context, filePath string, content string) (string, *container.CommandResult, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.fixCommand(relativeFilePath), content)
+	if result.Err != nil {
+		return content, result, result.Err
+	}
+	if result.Truncated {
+		// A partial document would drop the end of the file
+		return content, result, fmt.Errorf("phpcbf output exceeded the output cap")
+	}
+	// phpcbf writes the content even when there is nothing to fix, no output is a failed run
+	if len(result.Stdout) == 0 && content != "" {
+		return content, result, fmt.Errorf("phpcbf returned no content (exit code %d)", result.ExitCode)
+	}
+
+	return string(result.Stdout), result, nil
+}
+
+// CanFormat returns true if formatting is enabled for this provider
+func (dp *PhpCbf) CanFormat() bool {
+	return dp.config.Format.Enabled
+}
+
+// Format returns the content fixed by phpcbf
+func (dp *PhpCbf) Format(ctx context.Context, filePath string, content string) (string, error) {
+	if !dp.CanFormat() {
+		return content, fmt.Errorf("formatting is not enabled for %s", dp.Name())
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		timeout := 30 * time.Second
+		if dp.config.Format.TimeoutSeconds > 0 {
+			timeout = time.Duration(dp.config.Format.TimeoutSeconds) * time.Second
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		log.Printf("%s%s Added %v timeout for phpcbf formatting", logging.LogTagLSP, logging.LogTagServer, timeout)
+	}
+
+	startTime := time.Now()
+	formattedContent, _, err := dp.fix(ctx, filePath, content)
+	duration := time.Since(startTime)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("%s%s phpcbf execution cancelled: %v", logging.LogTagLSP, logging.LogTagServer, ctx.Err())
+			return content, fmt.Errorf("formatting cancelled: %w", ctx.Err())
+		}
+
+		log.Printf("%s%s phpcbf failed after %v: %v", logging.LogTagLSP, logging.LogTagServer, duration, err)
+		return content, fmt.Errorf("phpcbf command failed: %w", err)
+	}
+	log.Printf("%s%s phpcbf completed in %v", logging.LogTagLSP, logging.LogTagServer, duration)
+
+	return formattedContent, nil
+}
+
+func NewPhpCbf(providerConfig config.DiagnosticsProvider) *PhpCbf {
+	return &PhpCbf{
+		config:   providerConfig,
+		executor: newExecutor(PhpCbfProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,76 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+// phpCbfScript replaces the double quotes of stdin, recording its arguments
+const phpCbfScript = `echo "$@" > arguments
+sed 's/"/'"'"'/g'
+exit 1
+`
+
+func TestPhpCbf_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "phpcbf", phpCbfScript)
+	filePath := tool.writeFile(t, "src/User.php", "<?php\n\necho \"hello\";\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpcbf")
+	providerConfig.ConfigFile = "phpcs.xml.dist"
+	provider, err := diagnostics.NewDiagnosticsProvider(diagnostics.PhpCbfProviderId, providerConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if strings.TrimSpace(content) != "-q --stdin-path=src/User.php --standard=phpcs.xml.dist -" {
+		t.Errorf("Expected a stdin run of the file, got %q", content)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 2 || result[0].Severity != protocol.DiagnosticSeverityWarning || result[0].Source != "phpcbf" {
+		t.Errorf("Unexpected diagnostic: %+v", result[0])
+	}
+}
+
+func TestPhpCbf_Format(t *testing.T) {
+	tool := newFakeTool(t, "phpcbf", phpCbfScript)
+	providerConfig := tool.providerConfig("vendor/bin/phpcbf")
+
+	t.Run("returns the fixed content", func(t *testing.T) {
+		providerConfig.Format.Enabled = true
+		provider := diagnostics.NewPhpCbf(providerConfig)
+
+		formatted, err := provider.Format(context.Background(), tool.path("src/User.php"), "<?php\n\necho \"unsaved\";\n")
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+		if formatted != "<?php\n\necho 'unsaved';\n" {
+			t.Errorf("Unexpected formatted content: %q", formatted)
+		}
+	})
+
+	t.Run("requires formatting enabled", func(t *testing.T) {
+		providerConfig.Format.Enabled = false
+		provider := diagnostics.NewPhpCbf(providerConfig)
+
+		if _, err := provider.Format(context.Background(), tool.path("src/User.php"), "<?php\n"); err == nil {
+			t.Error("Expected an error with formatting disabled")
+		}
+	})
+}
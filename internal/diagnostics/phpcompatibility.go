@@ -0,0 +1,145 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpCompatibilityProviderId   string = "phpcompatibility"
+	PhpCompatibilityProviderName string = "phpcompatibility"
+
+	phpCompatibilityStandard string = "PHPCompatibility"
+)
+
+// PhpCsOutputResult is the JSON report of phpcs
+type PhpCsOutputResult struct {
+	Totals struct {
+		Errors   int `json:"errors"`
+		Warnings int `json:"warnings"`
+	} `json:"totals"`
+	Files map[string]struct {
+		Messages []PhpCsMessage `json:"messages"`
+	} `json:"files"`
+}
+
+// PhpCsMessage is a sniff violation, line and column being 1-based
+type PhpCsMessage struct {
+	Message  string `json:"message"`
+	Source   string `json:"source"`
+	Severity int    `json:"severity"`
+	Type     string `json:"type"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type PhpCompatibility struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpCompatibility) Id() string {
+	return PhpCompatibilityProviderId
+}
+
+func (dp *PhpCompatibility) Name() string {
+	return PhpCompatibilityProviderName
+}
+
+func (dp *PhpCompatibility) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+// analyzeCommand runs phpcs with the PHPCompatibility standard, or the configured ruleset including it, for
+// the configured PHP versions
+func (dp *PhpCompatibility) analyzeCommand(relativeFilePath string) string {
+	standard := phpCompatibilityStandard
+	if dp.config.ConfigFile != "" {
+		standard = dp.config.ConfigFile
+	}
+
+	testVersionArg := ""
+	if dp.config.TestVersion != "" {
+		testVersionArg = fmt.Sprintf("--runtime-set testVersion %s", dp.config.TestVersion)
+	}
+
+	return fmt.Sprintf("%s --standard=%s %s --report=json -q --no-colors %s 2>/dev/null", dp.config.Path, standard, testVersionArg, relativeFilePath)
+}
+
+func (dp *PhpCompatibility) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpcs: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpCsOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for reportedPath, file := range fullAnalysisResult.Files {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, reportedPath); !found || resolvedPath != filePath {
+			continue
+		}
+
+		for _, message := range file.Messages {
+			diagnostics = append(diagnostics, dp.messageDiagnostic(message))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+func (dp *PhpCompatibility) messageDiagnostic(message PhpCsMessage) protocol.Diagnostic {
+	line := uint32(0)
+	if message.Line > 0 {
+		line = uint32(message.Line - 1)
+	}
+	column := uint32(0)
+	if message.Column > 0 {
+		column = uint32(message.Column - 1)
+	}
+
+	severity := protocol.DiagnosticSeverityWarning
+	if strings.ToUpper(message.Type) == "ERROR" {
+		severity = protocol.DiagnosticSeverityError
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: column}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: severity,
+		Source:   dp.Name(),
+		Message:  message.Message,
+	}
+	if message.Source != "" {
+		diagnostic.Code = message.Source
+	}
+	return diagnostic
+}
+
+func NewPhpCompatibility(providerConfig config.DiagnosticsProvider) *PhpCompatibility {
+	return &PhpCompatibility{
+		config:   providerConfig,
+		executor: newExecutor(PhpCompatibilityProviderName, providerConfig),
+	}
+}
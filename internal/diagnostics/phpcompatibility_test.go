@@ -0,0 +1,55 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpCompatibility_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "phpcs", `echo "$@" > arguments
+cat <<'JSON'
+{"totals": {"errors": 1, "warnings": 1, "fixable": 0}, "files": {"/app/src/Legacy.php": {"errors": 1, "warnings": 1, "messages": [
+  {"message": "Function each() is deprecated since PHP 7.2 and removed since PHP 8.0; Use a foreach loop instead", "source": "PHPCompatibility.FunctionUse.RemovedFunctions.eachRemoved", "severity": 5, "fixable": false, "type": "ERROR", "line": 4, "column": 9},
+  {"message": "The constant \"FILTER_FLAG_SCHEME_REQUIRED\" is deprecated since PHP 7.3", "source": "PHPCompatibility.Constants.RemovedConstants.filter_flag_scheme_requiredDeprecated", "severity": 5, "fixable": false, "type": "WARNING", "line": 7, "column": 1}
+]}}}
+JSON
+exit 2
+`)
+	filePath := tool.writeFile(t, "src/Legacy.php", "{}")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpcs")
+	providerConfig.TestVersion = "7.4-8.3"
+	provider := diagnostics.NewPhpCompatibility(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if !strings.Contains(content, "--standard=PHPCompatibility --runtime-set testVersion 7.4-8.3") {
+		t.Errorf("Expected the standard and the test version in the arguments, got %s", content)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", result)
+	}
+	removed, deprecated := result[0], result[1]
+	if removed.Range.Start != (protocol.Position{Line: 3, Character: 8}) || removed.Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Unexpected removed function diagnostic: %+v", removed)
+	}
+	if removed.Code != "PHPCompatibility.FunctionUse.RemovedFunctions.eachRemoved" {
+		t.Errorf("Expected the sniff as code, got %v", removed.Code)
+	}
+	if deprecated.Range.Start.Line != 6 || deprecated.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Unexpected deprecated constant diagnostic: %+v", deprecated)
+	}
+}
@@ -0,0 +1,158 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpCpdProviderId   string = "phpcpd"
+	PhpCpdProviderName string = "phpcpd"
+)
+
+// PhpCpdOutputResult is the PMD-CPD XML report of phpcpd
+type PhpCpdOutputResult struct {
+	XMLName      xml.Name            `xml:"pmd-cpd" json:"-"`
+	Duplications []PhpCpdDuplication `xml:"duplication" json:"duplications"`
+}
+
+// PhpCpdDuplication is a code block found in several places
+type PhpCpdDuplication struct {
+	Lines        int              `xml:"lines,attr" json:"lines"`
+	Tokens       int              `xml:"tokens,attr" json:"tokens"`
+	Files        []PhpCpdFileCopy `xml:"file" json:"files"`
+	CodeFragment string           `xml:"codefragment" json:"codeFragment"`
+}
+
+// PhpCpdFileCopy is one of the copies of a duplicated block, starting at line (1-based)
+type PhpCpdFileCopy struct {
+	Path string `xml:"path,attr" json:"path"`
+	Line int    `xml:"line,attr" json:"line"`
+}
+
+type PhpCpd struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpCpd) Id() string {
+	return PhpCpdProviderId
+}
+
+func (dp *PhpCpd) Name() string {
+	return PhpCpdProviderName
+}
+
+// Analyze looks for the copies of the file blocks in the configured paths, the other copies can be in any
+// of them. Copies are only found in the files on disk.
+func (dp *PhpCpd) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand())
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+// analyzeCommand writes the XML report to stdout, through file descriptor 3 as phpcpd prints its text
+// report there too
+func (dp *PhpCpd) analyzeCommand() string {
+	paths := dp.config.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	return fmt.Sprintf("%s --log-pmd /dev/fd/3 --exclude vendor %s 3>&1 >/dev/null 2>&1", dp.config.Path, strings.Join(paths, " "))
+}
+
+func (dp *PhpCpd) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpcpd: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpCpdOutputResult
+	if err := xml.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, duplication := range fullAnalysisResult.Duplications {
+		// Resolved once, a block may be copied several times in the file
+		copies := make([]string, len(duplication.Files))
+		for i, file := range duplication.Files {
+			copies[i], _ = utils.ResolveToolPath(projectRoot, file.Path)
+		}
+
+		for i := range duplication.Files {
+			if copies[i] == filePath {
+				diagnostics = append(diagnostics, dp.duplicationDiagnostic(projectRoot, duplication, copies, i))
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// duplicationDiagnostic spans the copy at index current, the other copies are its related information
+func (dp *PhpCpd) duplicationDiagnostic(projectRoot string, duplication PhpCpdDuplication, copies []string, current int) protocol.Diagnostic {
+	var related []protocol.DiagnosticRelatedInformation
+	var others []string
+	for i, file := range duplication.Files {
+		if i == current {
+			continue
+		}
+
+		location := file.Path
+		if copies[i] != "" {
+			location, _ = filepath.Rel(projectRoot, copies[i])
+			related = append(related, protocol.DiagnosticRelatedInformation{
+				Location: protocol.Location{URI: utils.PathToURI(copies[i]), Range: duplicationRange(file.Line, duplication.Lines)},
+				Message:  "Other copy",
+			})
+		}
+		others = append(others, fmt.Sprintf("%s:%d", location, file.Line))
+	}
+
+	return protocol.Diagnostic{
+		Range:              duplicationRange(duplication.Files[current].Line, duplication.Lines),
+		Severity:           protocol.DiagnosticSeverityWarning,
+		Source:             dp.Name(),
+		Message:            fmt.Sprintf("Duplicated code: %d lines (%d tokens) also in %s", duplication.Lines, duplication.Tokens, strings.Join(others, ", ")),
+		RelatedInformation: related,
+	}
+}
+
+func duplicationRange(line int, lines int) protocol.Range {
+	start := uint32(0)
+	if line > 0 {
+		start = uint32(line - 1)
+	}
+	end := start
+	if lines > 1 {
+		end += uint32(lines - 1)
+	}
+
+	return protocol.Range{Start: protocol.Position{Line: start, Character: 0}, End: protocol.Position{Line: end, Character: 100}}
+}
+
+func NewPhpCpd(providerConfig config.DiagnosticsProvider) *PhpCpd {
+	return &PhpCpd{
+		config:   providerConfig,
+		executor: newExecutor(PhpCpdProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,73 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpCpd_IdAndName(t *testing.T) {
+	provider := diagnostics.NewPhpCpd(config.DiagnosticsProvider{Enabled: true, Container: "test-container", Path: "vendor/bin/phpcpd"})
+
+	if provider.Id() != diagnostics.PhpCpdProviderId {
+		t.Errorf("Expected ID '%s', got '%s'", diagnostics.PhpCpdProviderId, provider.Id())
+	}
+	if provider.Name() != diagnostics.PhpCpdProviderName {
+		t.Errorf("Expected name '%s', got '%s'", diagnostics.PhpCpdProviderName, provider.Name())
+	}
+}
+
+func TestPhpCpd_Analyze(t *testing.T) {
+	// Reports container paths, the XML report going to file descriptor 3 and the text report to stdout
+	tool := newFakeTool(t, "phpcpd", `echo 'Found 1 code clones with 12 duplicated lines'
+cat >&3 <<'XML'
+<?xml version="1.0" encoding="UTF-8"?>
+<pmd-cpd>
+  <duplication lines="12" tokens="80">
+    <file path="/app/src/A.php" line="10"/>
+    <file path="/app/src/B.php" line="40"/>
+    <codefragment>$a = 1;</codefragment>
+  </duplication>
+</pmd-cpd>
+XML
+exit 1
+`)
+	filePath := tool.writeFile(t, "src/A.php", "<?php\n")
+	tool.writeFile(t, "src/B.php", "<?php\n")
+
+	provider := diagnostics.NewPhpCpd(tool.providerConfig("vendor/bin/phpcpd"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+
+	diagnostic := result[0]
+	if diagnostic.Range.Start.Line != 9 || diagnostic.Range.End.Line != 20 {
+		t.Errorf("Expected the range to span lines 9-20, got %+v", diagnostic.Range)
+	}
+	if diagnostic.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Expected a warning, got %v", diagnostic.Severity)
+	}
+	if !strings.Contains(diagnostic.Message, "src/B.php:40") {
+		t.Errorf("Expected the message to point to the other copy, got %q", diagnostic.Message)
+	}
+	if len(diagnostic.RelatedInformation) != 1 {
+		t.Fatalf("Expected 1 related information, got %+v", diagnostic.RelatedInformation)
+	}
+	related := diagnostic.RelatedInformation[0]
+	if related.Location.URI != protocol.DocumentURI("file://"+tool.path("src/B.php")) || related.Location.Range.Start.Line != 39 {
+		t.Errorf("Expected the related information to locate the other copy, got %+v", related.Location)
+	}
+}
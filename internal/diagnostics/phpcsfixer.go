@@ -2,7 +2,6 @@ package diagnostics
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"path/filepath"
@@ -15,13 +14,15 @@ import (
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/container"
 	"github.com/cristianradulescu/php-diagls/internal/logging"
-	"github.com/cristianradulescu/php-diagls/internal/utils"
 	"go.lsp.dev/protocol"
 )
 
 const (
 	PhpCsFixerProviderId   string = "phpcsfixer"
 	PhpCsFixerProviderName string = "php-cs-fixer"
+
+	// Message of the changes located from the plain diff, which doesn't tell the rules of each change
+	PhpCsFixerStyleIssueMessage string = "Style issue"
 )
 
 type PhpCsFixerOutputResult struct {
@@ -32,11 +33,24 @@ type PhpCsFixerOutputResult struct {
 	} `json:"files"`
 }
 
+// PhpCsFixerRawResult is the output of the dry run, with the diff of every applied rule
+type PhpCsFixerRawResult struct {
+	PhpCsFixerOutputResult
+	RuleDiffs map[string]string `json:"ruleDiffs"`
+}
+
 type PhpCsFixer struct {
 	config           config.DiagnosticsProvider
+	executor         *container.Executor
 	ruleDescriptions sync.Map
+	ruleDocs         sync.Map
 }
 
+var phpCsFixerRuleRegex = regexp.MustCompile(`^@?[A-Za-z0-9_]+(/[A-Za-z0-9_]+)?(:risky)?$`)
+
+// File line of the plain verbose output, "   1) src/Foo.php (array_syntax, single_quote)"
+var phpCsFixerPlainFileRegex = regexp.MustCompile(`^\s*\d+\)\s+\S+\s+\(([^)]+)\)`)
+
 func (dp *PhpCsFixer) Id() string {
 	return PhpCsFixerProviderId
 }
@@ -45,62 +59,89 @@ func (dp *PhpCsFixer) Name() string {
 	return PhpCsFixerProviderName
 }
 
-func (dp *PhpCsFixer) Analyze(filePath string) ([]protocol.Diagnostic, error) {
+func (dp *PhpCsFixer) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	return dp.analyze(ctx, projectRoot, relativeFilePath)
+}
+
+// AnalyzeContent checks the content piped through stdin
+func (dp *PhpCsFixer) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	return dp.analyze(ctx, providerRoot(dp.config, filePath), "-", content)
+}
+
+// analyze runs the fixer in dry-run mode on the target (a path relative to the project root, or "-"
+// for stdin), then once per applied rule to locate the lines it changes
+func (dp *PhpCsFixer) analyze(ctx context.Context, projectRoot string, target string, stdin ...string) ([]protocol.Diagnostic, error) {
 	var diagnostics []protocol.Diagnostic
 	var linesRange []protocol.Range
 
-	projectRoot := utils.FindProjectRoot(filePath)
-	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
-
 	configArg := ""
 	if dp.config.ConfigFile != "" {
 		configArg = fmt.Sprintf("--config %s", dp.config.ConfigFile)
 	}
-	result := container.RunCommandInContainer(
-		context.Background(),
-		dp.config.Container,
-		fmt.Sprintf("%s fix %s --dry-run --diff --verbose --format json %s 2>/dev/null", dp.config.Path, relativeFilePath, configArg),
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s fix %s --dry-run --diff --verbose --format json %s 2>/dev/null", dp.config.Path, target, configArg),
+		stdin...,
 	)
 
 	if result.Err != nil {
 		log.Printf("Error running php-cs-fixer: %v", result.Err)
+		markFailed(ctx, result.Failure())
 		return []protocol.Diagnostic{}, nil
 	}
 
 	var fullAnalysisResult PhpCsFixerOutputResult
-	if err := json.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
 		log.Printf("Unmarshall err: %s", err)
+		failure := outputFailure(result, err)
+		// The tool ran but its JSON report is unusable (older versions, plugins writing to stdout)
+		if !container.Failure(failure).IsBackendFailure() {
+			if plainDiagnostics, ok := dp.analyzePlainDiff(ctx, projectRoot, target, configArg, stdin...); ok {
+				return plainDiagnostics, nil
+			}
+		}
+		markFailed(ctx, failure)
 		return []protocol.Diagnostic{}, nil
 	}
 
+	rawResult := PhpCsFixerRawResult{PhpCsFixerOutputResult: fullAnalysisResult, RuleDiffs: map[string]string{}}
+
 	for _, file := range fullAnalysisResult.Files {
 		for _, rule := range file.Rules {
-			ruleResult := container.RunCommandInContainer(
-				context.Background(),
-				dp.config.Container,
-				fmt.Sprintf("%s fix %s --dry-run --diff --verbose --format json --rules %s 2>/dev/null", dp.config.Path, relativeFilePath, rule),
+			ruleResult := dp.executor.Run(
+				ctx,
+				projectRoot,
+				fmt.Sprintf("%s fix %s --dry-run --diff --verbose --format json --rules %s 2>/dev/null", dp.config.Path, target, rule),
+				stdin...,
 			)
 
 			if ruleResult.Err != nil {
 				log.Printf("Error running php-cs-fixer for rule %s: %v", rule, ruleResult.Err)
+				markFailed(ctx, ruleResult.Failure())
 				continue
 			}
 
 			var ruleAnalysisResult PhpCsFixerOutputResult
-			if err := json.Unmarshal(ruleResult.Stdout, &ruleAnalysisResult); err != nil {
+			if err := unmarshalToolOutput(ruleResult, &ruleAnalysisResult); err != nil {
 				log.Printf("Unmarshall err: %s", err)
+				markFailed(ctx, outputFailure(ruleResult, err))
 				return []protocol.Diagnostic{}, nil
 			}
 
 			for _, file := range ruleAnalysisResult.Files {
 				if file.Diff != "" {
+					rawResult.RuleDiffs[rule] += file.Diff
 					linesRange = dp.parseDiffForDiagnostics(file.Diff)
 					for _, lineRange := range linesRange {
 						diagnostics = append(diagnostics, protocol.Diagnostic{
 							Range:    lineRange,
-							Severity: protocol.DiagnosticSeverityWarning,
+							Severity: dp.ruleSeverity(rule),
 							Source:   dp.Name(),
-							Message:  dp.explainRule(rule),
+							Message:  dp.explainRule(ctx, rule),
 							Code:     rule,
 						})
 					}
@@ -111,16 +152,68 @@ func (dp *PhpCsFixer) Analyze(filePath string) ([]protocol.Diagnostic, error) {
 		}
 	}
 
+	recordRawResult(ctx, rawResult)
+
 	return diagnostics, nil
 }
 
+// analyzePlainDiff locates the changes from the plain diff output, reporting them as generic style issues
+// listing the rules applied to the file. False is returned when the output has no usable diff either.
+func (dp *PhpCsFixer) analyzePlainDiff(ctx context.Context, projectRoot string, target string, configArg string, stdin ...string) ([]protocol.Diagnostic, bool) {
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s fix %s --dry-run --diff --verbose %s 2>/dev/null", dp.config.Path, target, configArg),
+		stdin...,
+	)
+	// Exit code 8 means the file needs fixing
+	if result.Err != nil || (result.ExitCode != 0 && result.ExitCode != 8) {
+		return nil, false
+	}
+
+	output := string(result.Stdout)
+	linesRange := dp.parseDiffForDiagnostics(output)
+	if len(linesRange) == 0 && result.ExitCode != 0 {
+		return nil, false
+	}
+
+	var rules []string
+	for _, line := range strings.Split(output, "\n") {
+		if matches := phpCsFixerPlainFileRegex.FindStringSubmatch(line); matches != nil {
+			rules = append(rules, matches[1])
+		}
+	}
+	message := PhpCsFixerStyleIssueMessage
+	if len(rules) > 0 {
+		message = fmt.Sprintf("%s (%s)", message, strings.Join(rules, ", "))
+	}
+	log.Printf("php-cs-fixer JSON report unusable, %d changes located from the plain diff", len(linesRange))
+
+	diagnostics := make([]protocol.Diagnostic, 0, len(linesRange))
+	for _, lineRange := range linesRange {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Message:  message,
+		})
+	}
+	return diagnostics, true
+}
+
 func NewPhpCsFixer(providerConfig config.DiagnosticsProvider) *PhpCsFixer {
 	return &PhpCsFixer{
-		config: providerConfig,
+		config:   providerConfig,
+		executor: newExecutor(PhpCsFixerProviderName, providerConfig),
 	}
 }
 
 func (dp *PhpCsFixer) parseDiffForDiagnostics(diff string) []protocol.Range {
+	return diffRanges(diff)
+}
+
+// diffRanges locates the changes of a unified diff of a fixer on the original lines
+func diffRanges(diff string) []protocol.Range {
 	var linesRange []protocol.Range
 
 	lines := strings.Split(diff, "\n")
@@ -178,18 +271,12 @@ func (dp *PhpCsFixer) parseDiffForDiagnostics(diff string) []protocol.Range {
 	return linesRange
 }
 
-func (dp *PhpCsFixer) explainRule(rule string) string {
+func (dp *PhpCsFixer) explainRule(ctx context.Context, rule string) string {
 	if cachedDescription, ok := dp.ruleDescriptions.Load(rule); ok {
 		return cachedDescription.(string)
 	}
 
-	result := container.RunCommandInContainer(
-		context.Background(),
-		dp.config.Container,
-		fmt.Sprintf("%s describe %s 2>/dev/null", dp.config.Path, rule),
-	)
-
-	fullRuleDescription := strings.TrimSpace(string(result.Stdout))
+	fullRuleDescription, _ := dp.describeRule(ctx, rule)
 
 	re1 := regexp.MustCompile(`Description of .* rule.`)
 	ruleDescription := re1.ReplaceAllString(fullRuleDescription, "")
@@ -203,67 +290,81 @@ func (dp *PhpCsFixer) explainRule(rule string) string {
 	return ruleDescription
 }
 
-// CanFormat returns true if formatting is enabled for this provider
-func (dp *PhpCsFixer) CanFormat() bool {
-	return dp.config.Format.Enabled
+// ExplainRule returns the full `describe` output of the rule or rule set, including the fixing examples
+func (dp *PhpCsFixer) ExplainRule(ctx context.Context, rule string) (string, error) {
+	if !phpCsFixerRuleRegex.MatchString(rule) {
+		return "", fmt.Errorf("%s is not a %s rule", rule, dp.Name())
+	}
+
+	return dp.describeRule(ctx, rule)
 }
 
-func (dp *PhpCsFixer) Format(ctx context.Context, filePath string, content string) (string, error) {
-	if !dp.CanFormat() {
-		return content, fmt.Errorf("formatting is not enabled for %s", dp.Name())
+// describeRule runs `describe` for the rule, successful descriptions are cached
+func (dp *PhpCsFixer) describeRule(ctx context.Context, rule string) (string, error) {
+	if cachedDocs, ok := dp.ruleDocs.Load(rule); ok {
+		return cachedDocs.(string), nil
 	}
 
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
-		var cancel context.CancelFunc
-		timeout := 30 * time.Second
-		if dp.config.Format.TimeoutSeconds > 0 {
-			timeout = time.Duration(dp.config.Format.TimeoutSeconds) * time.Second
-		}
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-		defer cancel()
-		log.Printf("%s%s Added %v timeout for php-cs-fixer formatting", logging.LogTagLSP, logging.LogTagServer, timeout)
+	// Rule descriptions don't depend on the project, no working directory needed
+	result := dp.executor.Run(
+		ctx,
+		"",
+		fmt.Sprintf("%s describe %s 2>/dev/null", dp.config.Path, rule),
+	)
+
+	docs := strings.TrimSpace(string(result.Stdout))
+	if result.Err != nil {
+		return "", result.Err
 	}
+	if result.ExitCode != 0 || docs == "" {
+		return "", fmt.Errorf("%s has no rule or rule set %s", dp.Name(), rule)
+	}
+
+	dp.ruleDocs.Store(rule, docs)
+
+	return docs, nil
+}
 
+// CanFormat returns true if formatting is enabled for this provider
+func (dp *PhpCsFixer) CanFormat() bool {
+	return dp.config.Format.Enabled
+}
+
+func (dp *PhpCsFixer) Format(ctx context.Context, filePath string, content string) (string, error) {
 	configArg := ""
 	if dp.config.ConfigFile != "" {
 		configArg = fmt.Sprintf("--config %s", dp.config.ConfigFile)
 	}
 
-	cmd := fmt.Sprintf("%s fix - --diff %s", dp.config.Path, configArg)
-
-	startTime := time.Now()
-	result := container.RunCommandInContainer(ctx, dp.config.Container, cmd, content)
-	duration := time.Since(startTime)
+	return dp.format(ctx, filePath, content, configArg)
+}
 
-	if result.Err != nil {
-		if ctx.Err() != nil {
-			log.Printf("%s%s php-cs-fixer execution cancelled: %v", logging.LogTagLSP, logging.LogTagServer, ctx.Err())
-			return content, fmt.Errorf("formatting cancelled: %w", ctx.Err())
+// FormatRules only applies the given rules, like the analysis locating the changes of each rule
+func (dp *PhpCsFixer) FormatRules(ctx context.Context, filePath string, content string, rules []string) (string, error) {
+	for _, rule := range rules {
+		if !phpCsFixerRuleRegex.MatchString(rule) {
+			return content, fmt.Errorf("%s is not a %s rule", rule, dp.Name())
 		}
-
-		log.Printf("%s%s php-cs-fixer failed after %v: %v", logging.LogTagLSP, logging.LogTagServer, duration, result.Err)
-		return content, fmt.Errorf("php-cs-fixer command failed: %w", result.Err)
-	}
-
-	if result.ExitCode == 8 {
-		log.Printf("%s%s php-cs-fixer found formatting changes (exit code 8) in %v", logging.LogTagLSP, logging.LogTagServer, duration)
-	} else if result.ExitCode != 0 {
-		log.Printf("%s%s php-cs-fixer returned non-zero exit code %d after %v", logging.LogTagLSP, logging.LogTagServer, result.ExitCode, duration)
-		log.Printf("%s%s php-cs-fixer stderr: %s", logging.LogTagLSP, logging.LogTagServer, string(result.Stderr))
-		return content, fmt.Errorf("php-cs-fixer failed with exit code %d", result.ExitCode)
-	} else {
-		log.Printf("%s%s php-cs-fixer completed successfully in %v, output length: %d bytes", logging.LogTagLSP, logging.LogTagServer, duration, len(result.Stdout))
 	}
 
-	diffStr := strings.TrimSpace(string(result.Stdout))
-	if diffStr == "" {
-		return content, nil
-	}
+	return dp.format(ctx, filePath, content, fmt.Sprintf("--rules %s", container.ShellQuote(strings.Join(rules, ","))))
+}
 
-	formattedContent, err := utils.ApplyUnifiedDiff(content, diffStr)
-	if err != nil {
-		return content, fmt.Errorf("failed to apply diff: %w", err)
-	}
+// format fixes the content piped through stdin with the rules selected by the arguments
+func (dp *PhpCsFixer) format(ctx context.Context, filePath string, content string, rulesArgs string) (string, error) {
+	cmd := fmt.Sprintf("%s fix - --diff %s", dp.config.Path, rulesArgs)
+
+	return stdinFormat(ctx, dp.Name(), dp.config, dp.executor, filePath, content, cmd, func(result *container.CommandResult, duration time.Duration) ([]string, error) {
+		if result.ExitCode == 8 {
+			log.Printf("%s%s php-cs-fixer found formatting changes (exit code 8) in %v", logging.LogTagLSP, logging.LogTagServer, duration)
+		} else if result.ExitCode != 0 {
+			log.Printf("%s%s php-cs-fixer returned non-zero exit code %d after %v", logging.LogTagLSP, logging.LogTagServer, result.ExitCode, duration)
+			log.Printf("%s%s php-cs-fixer stderr: %s", logging.LogTagLSP, logging.LogTagServer, string(result.Stderr))
+			return nil, fmt.Errorf("php-cs-fixer failed with exit code %d", result.ExitCode)
+		} else {
+			log.Printf("%s%s php-cs-fixer completed successfully in %v, output length: %d bytes", logging.LogTagLSP, logging.LogTagServer, duration, len(result.Stdout))
+		}
 
-	return formattedContent, nil
+		return []string{strings.TrimSpace(string(result.Stdout))}, nil
+	})
 }
@@ -0,0 +1,127 @@
+package diagnostics
+
+import (
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+// phpCsFixerCosmeticRules are the fixers only changing whitespace, casing, quoting or ordering. They
+// never change the behavior of the code, so they are reported with a lower severity than structural
+// fixers (unused imports, strict types, visibility, ...).
+var phpCsFixerCosmeticRules = map[string]bool{
+	// Whitespace and indentation
+	"align_multiline_comment":                       true,
+	"array_indentation":                             true,
+	"binary_operator_spaces":                        true,
+	"blank_line_after_namespace":                    true,
+	"blank_line_after_opening_tag":                  true,
+	"blank_line_before_statement":                   true,
+	"blank_line_between_import_groups":              true,
+	"blank_lines_before_namespace":                  true,
+	"braces":                                        true,
+	"braces_position":                               true,
+	"cast_spaces":                                   true,
+	"class_attributes_separation":                   true,
+	"compact_nullable_type_declaration":             true,
+	"concat_space":                                  true,
+	"control_structure_braces":                      true,
+	"control_structure_continuation_position":       true,
+	"declare_equal_normalize":                       true,
+	"declare_parentheses":                           true,
+	"function_declaration":                          true,
+	"function_typehint_space":                       true,
+	"heredoc_indentation":                           true,
+	"indentation_type":                              true,
+	"line_ending":                                   true,
+	"linebreak_after_opening_tag":                   true,
+	"method_argument_space":                         true,
+	"method_chaining_indentation":                   true,
+	"multiline_whitespace_before_semicolons":        true,
+	"no_blank_lines_after_class_opening":            true,
+	"no_blank_lines_after_phpdoc":                   true,
+	"no_extra_blank_lines":                          true,
+	"no_multiline_whitespace_around_double_arrow":   true,
+	"no_multiple_statements_per_line":               true,
+	"no_singleline_whitespace_before_semicolons":    true,
+	"no_space_around_double_colon":                  true,
+	"no_spaces_after_function_name":                 true,
+	"no_spaces_around_offset":                       true,
+	"no_spaces_inside_parenthesis":                  true,
+	"no_trailing_whitespace":                        true,
+	"no_trailing_whitespace_in_comment":             true,
+	"no_whitespace_before_comma_in_array":           true,
+	"no_whitespace_in_blank_line":                   true,
+	"not_operator_with_space":                       true,
+	"not_operator_with_successor_space":             true,
+	"object_operator_without_whitespace":            true,
+	"operator_linebreak":                            true,
+	"phpdoc_indent":                                 true,
+	"phpdoc_trim":                                   true,
+	"phpdoc_trim_consecutive_blank_line_separation": true,
+	"return_type_declaration":                       true,
+	"single_blank_line_at_eof":                      true,
+	"single_line_after_imports":                     true,
+	"single_line_empty_body":                        true,
+	"single_space_around_construct":                 true,
+	"space_after_semicolon":                         true,
+	"spaces_inside_parentheses":                     true,
+	"statement_indentation":                         true,
+	"switch_case_space":                             true,
+	"ternary_operator_spaces":                       true,
+	"trim_array_spaces":                             true,
+	"type_declaration_spaces":                       true,
+	"types_spaces":                                  true,
+	"unary_operator_spaces":                         true,
+	"whitespace_after_comma_in_array":               true,
+	// Casing
+	"constant_case":                  true,
+	"integer_literal_case":           true,
+	"lowercase_cast":                 true,
+	"lowercase_keywords":             true,
+	"lowercase_static_reference":     true,
+	"magic_constant_casing":          true,
+	"magic_method_casing":            true,
+	"native_function_casing":         true,
+	"native_type_declaration_casing": true,
+	// Quoting and ordering
+	"ordered_imports": true,
+	"single_quote":    true,
+}
+
+// ruleSeverity returns the severity of a php-cs-fixer rule, cosmetic rules use the configured
+// cosmeticSeverity (hint by default)
+func (dp *PhpCsFixer) ruleSeverity(rule string) protocol.DiagnosticSeverity {
+	if !phpCsFixerCosmeticRules[rule] {
+		return protocol.DiagnosticSeverityWarning
+	}
+
+	return configuredSeverity(dp.config.CosmeticSeverity)
+}
+
+// configuredSeverity converts a severity of the configuration, hint when empty
+func configuredSeverity(severity string) protocol.DiagnosticSeverity {
+	switch severity {
+	case config.SeverityError:
+		return protocol.DiagnosticSeverityError
+	case config.SeverityWarning:
+		return protocol.DiagnosticSeverityWarning
+	case config.SeverityInformation:
+		return protocol.DiagnosticSeverityInformation
+	default:
+		return protocol.DiagnosticSeverityHint
+	}
+}
+
+// IsCosmeticRule reports whether the php-cs-fixer rule only changes the layout of the code
+func IsCosmeticRule(rule string) bool {
+	return phpCsFixerCosmeticRules[rule]
+}
+
+// RuleCategory returns the category of the rule of a diagnostic, the rules of the other providers than
+// php-cs-fixer are structural
+func RuleCategory(diagnostic protocol.Diagnostic) string {
+	if rule, ok := diagnostic.Code.(string); ok && diagnostic.Source == PhpCsFixerProviderName && IsCosmeticRule(rule) {
+		return config.RuleCategoryCosmetic
+	}
+	return config.RuleCategoryStructural
+}
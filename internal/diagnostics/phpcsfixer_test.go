@@ -2,6 +2,7 @@ package diagnostics_test
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
@@ -171,7 +172,7 @@ echo "Hello World";
 
 	// This test documents the expected behavior when Docker is not available
 	// The provider should return an empty slice, not an error
-	diagnostics, err := provider.Analyze(tmpFile)
+	diagnostics, err := provider.Analyze(context.Background(), tmpFile)
 
 	if err != nil {
 		t.Errorf("Analyze should not return error for missing container, got: %v", err)
@@ -657,3 +658,96 @@ func TestPhpCsFixer_DiagnosticCharacterRange(t *testing.T) {
 	t.Log("  Range: Start(line: N, char: 0), End(line: N, char: 0)")
 	t.Log("  This creates a zero-width range at the insertion point")
 }
+
+func TestPhpCsFixer_IsCosmeticRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		cosmetic bool
+	}{
+		{"no_trailing_whitespace", true},
+		{"binary_operator_spaces", true},
+		{"lowercase_keywords", true},
+		{"single_quote", true},
+		{"no_unused_imports", false},
+		{"declare_strict_types", false},
+		{"visibility_required", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			if got := diagnostics.IsCosmeticRule(tt.rule); got != tt.cosmetic {
+				t.Errorf("Expected cosmetic %v, got %v", tt.cosmetic, got)
+			}
+		})
+	}
+}
+
+func TestPhpCsFixer_FormatRules(t *testing.T) {
+	// Runs through the local fallback, only fixing the quotes when asked for single_quote alone
+	tool := newFakeTool(t, "php-cs-fixer", `cat >/dev/null
+case "$*" in
+  *"--rules single_quote"*) printf '%s\n' '--- a' '+++ b' '@@ -1,2 +1,2 @@' ' <?php' '-echo "a" ;' '+echo '"'"'a'"'"' ;'; exit 8;;
+  *) printf '%s\n' '--- a' '+++ b' '@@ -1,2 +1,2 @@' ' <?php' '-echo "a" ;' '+echo '"'"'a'"'"';'; exit 8;;
+esac
+`)
+
+	providerConfig := tool.providerConfig("/usr/local/bin/php-cs-fixer")
+	providerConfig.ConfigFile = ".php-cs-fixer.dist.php"
+	providerConfig.Format = config.FormatConfig{Enabled: true}
+	provider := diagnostics.NewPhpCsFixer(providerConfig)
+
+	result, err := provider.FormatRules(context.Background(), tool.path("a.php"), "<?php\necho \"a\" ;\n", []string{"single_quote"})
+	if err != nil {
+		t.Fatalf("FormatRules failed: %v", err)
+	}
+	if result != "<?php\necho 'a' ;\n" {
+		t.Errorf("Expected only the quotes fixed, got %q", result)
+	}
+}
+
+func TestPhpCsFixer_FormatRulesRejectsInvalidRule(t *testing.T) {
+	// The fake fixer leaves a marker when it runs at all
+	tool := newFakeTool(t, "php-cs-fixer", "touch ran\n")
+	providerConfig := tool.providerConfig("/usr/local/bin/php-cs-fixer")
+	providerConfig.Format = config.FormatConfig{Enabled: true}
+	provider := diagnostics.NewPhpCsFixer(providerConfig)
+
+	content := "<?php\necho \"a\" ;\n"
+	result, err := provider.FormatRules(context.Background(), tool.path("a.php"), content, []string{"single_quote;touch /tmp/pwned"})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid rule")
+	}
+	if result != content {
+		t.Errorf("Expected the content unchanged, got %q", result)
+	}
+	if _, statErr := os.Stat(tool.path("ran")); statErr == nil {
+		t.Error("Expected php-cs-fixer not to run for an invalid rule")
+	}
+}
+
+func TestPhpCsFixer_AnalyzePlainDiffFallback(t *testing.T) {
+	// A plugin breaks the JSON report, the plain output still holds the diff
+	tool := newFakeTool(t, "php-cs-fixer", `cat >/dev/null
+case "$*" in
+  *"--format json"*) printf '%s\n' 'Plugin loaded' '{"files": ['; exit 8;;
+  *) printf '%s\n' 'Loaded config default.' '   1) - (single_quote, no_whitespace_before_semicolon)' '      ---------- begin diff ----------' '--- Original' '+++ New' '@@ -1,3 +1,3 @@' ' <?php' '-echo "a" ;' '+echo '"'"'a'"'"';' ' echo 1;' '      ----------- end diff -----------'; exit 8;;
+esac
+`)
+
+	provider := diagnostics.NewPhpCsFixer(tool.providerConfig("/usr/local/bin/php-cs-fixer"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.AnalyzeContent(ctx, tool.path("a.php"), "<?php\necho \"a\" ;\necho 1;\n")
+	if err != nil {
+		t.Fatalf("AnalyzeContent failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 1 || result[0].Message != "Style issue (single_quote, no_whitespace_before_semicolon)" {
+		t.Errorf("Unexpected diagnostic: %+v", result[0])
+	}
+}
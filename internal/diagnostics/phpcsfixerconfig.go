@@ -0,0 +1,81 @@
+package diagnostics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PhpCsFixerConfigFiles are the configuration files php-cs-fixer loads from the project without --config, in
+// order of precedence
+var PhpCsFixerConfigFiles = []string{".php-cs-fixer.php", ".php-cs-fixer.dist.php"}
+
+var phpCsFixerSetRulesRegex = regexp.MustCompile(`->setRules\(\s*(\[|array\()`)
+
+// DisablePhpCsFixerRule returns the php-cs-fixer configuration with the rule set to false in the rules of
+// setRules, replacing its current value or adding it first. Configurations building their rules elsewhere
+// (a variable, a method) are reported as not supported.
+func DisablePhpCsFixerRule(configContent string, rule string) (string, error) {
+	location := phpCsFixerSetRulesRegex.FindStringIndex(configContent)
+	if location == nil {
+		return configContent, fmt.Errorf("no ->setRules([...]) rules array found")
+	}
+	rulesStart := location[1]
+	rulesEnd, ok := phpArrayEnd(configContent, rulesStart, false)
+	if !ok {
+		return configContent, fmt.Errorf("unterminated ->setRules([...]) rules array")
+	}
+
+	// Only the keys of the rules array, not the ones of the rule options
+	keyRegex := regexp.MustCompile(`^\s*['"]` + regexp.QuoteMeta(rule) + `['"]\s*=>\s*`)
+	for elementStart := rulesStart; elementStart < rulesEnd; {
+		elementEnd, ok := phpArrayEnd(configContent, elementStart, true)
+		if !ok {
+			break
+		}
+		if keyLocation := keyRegex.FindStringIndex(configContent[elementStart:elementEnd]); keyLocation != nil {
+			return configContent[:elementStart+keyLocation[1]] + "false" + configContent[elementEnd:], nil
+		}
+		elementStart = elementEnd + 1
+	}
+
+	rules := configContent[rulesStart:rulesEnd]
+
+	// Added first, with the indentation of the first rule
+	indentation := "    "
+	if newline := strings.Index(rules, "\n"); newline >= 0 {
+		nextLine := rules[newline+1:]
+		if lineIndentation := nextLine[:len(nextLine)-len(strings.TrimLeft(nextLine, " \t"))]; lineIndentation != "" {
+			indentation = lineIndentation
+		}
+	}
+	return configContent[:rulesStart] + fmt.Sprintf("\n%s'%s' => false,", indentation, rule) + configContent[rulesStart:], nil
+}
+
+// phpArrayEnd returns the end of the PHP array elements starting at start, before the closing bracket of the
+// array, or at the comma ending the first element with untilComma. Nested arrays and strings are skipped.
+func phpArrayEnd(content string, start int, untilComma bool) (int, bool) {
+	depth := 0
+	for i := start; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '\'', '"':
+			end := strings.IndexByte(content[i+1:], c)
+			if end < 0 {
+				return 0, false
+			}
+			i += end + 1
+		case '[', '(':
+			depth++
+		case ']', ')':
+			if depth == 0 {
+				return len(strings.TrimRight(content[:i], " \t\r\n")), true
+			}
+			depth--
+		case ',':
+			if depth == 0 && untilComma {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package diagnostics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func TestDisablePhpCsFixerRule(t *testing.T) {
+	config := `<?php
+
+return (new PhpCsFixer\Config())
+    ->setRules([
+        '@PSR12' => true,
+        'array_syntax' => ['syntax' => 'short'],
+        'single_quote' => true
+    ])
+    ->setFinder($finder);
+`
+
+	tests := []struct {
+		name     string
+		rule     string
+		expected string
+	}{
+		{
+			name:     "rule with an array value",
+			rule:     "array_syntax",
+			expected: "        'array_syntax' => false,\n        'single_quote' => true\n",
+		},
+		{
+			name:     "last rule",
+			rule:     "single_quote",
+			expected: "        'single_quote' => false\n    ])",
+		},
+		{
+			name:     "rule not configured",
+			rule:     "no_unused_imports",
+			expected: "->setRules([\n        'no_unused_imports' => false,\n        '@PSR12' => true,",
+		},
+		{
+			name:     "option key of another rule",
+			rule:     "syntax",
+			expected: "->setRules([\n        'syntax' => false,\n        '@PSR12' => true,\n        'array_syntax' => ['syntax' => 'short'],",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := diagnostics.DisablePhpCsFixerRule(config, tt.rule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Expected %q in:\n%s", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := diagnostics.DisablePhpCsFixerRule("<?php\n\nreturn $config->setRules($rules);\n", "single_quote"); err == nil {
+		t.Error("Expected an error without a rules array")
+	}
+}
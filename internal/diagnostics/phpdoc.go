@@ -0,0 +1,175 @@
+package diagnostics
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpDocProviderId   string = "phpdoc"
+	PhpDocProviderName string = "phpdoc"
+)
+
+// Patterns of the identifiers of the phpstan errors about PHPDoc blocks: missing types, types not matching
+// the native ones, unparsable blocks and misplaced @var tags
+var phpDocIdentifierPatterns = []string{"phpDoc.*", "missingType.*", "varTag.*", "*.phpDocType"}
+
+// First variable named in a message, e.g. "PHPDoc tag @param for parameter $name with type ..."
+var phpDocVariableRegex = regexp.MustCompile(`\$(\w+)`)
+
+// PhpDoc validates the PHPDoc blocks with the phpdoc rules of phpstan, reporting on the docblock the errors
+// phpstan reports on the declaration
+type PhpDoc struct {
+	config  config.DiagnosticsProvider
+	phpstan *PhpStan
+}
+
+func (dp *PhpDoc) Id() string {
+	return PhpDocProviderId
+}
+
+func (dp *PhpDoc) Name() string {
+	return PhpDocProviderName
+}
+
+func (dp *PhpDoc) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	phpstanDiagnostics, err := dp.phpstan.Analyze(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	return dp.docblockDiagnostics(string(content), phpstanDiagnostics), nil
+}
+
+// AnalyzeContent checks the content with phpstan's editor mode
+func (dp *PhpDoc) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	phpstanDiagnostics, err := dp.phpstan.AnalyzeContent(ctx, filePath, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return dp.docblockDiagnostics(content, phpstanDiagnostics), nil
+}
+
+// docblockDiagnostics keeps the PHPDoc errors, moved to the tag they are about or to the start of the
+// docblock. Declarations without a docblock keep the reported line.
+func (dp *PhpDoc) docblockDiagnostics(content string, phpstanDiagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	lines := strings.Split(content, "\n")
+	for _, diagnostic := range phpstanDiagnostics {
+		identifier, _ := diagnostic.Code.(string)
+		if !isPhpDocIdentifier(identifier) {
+			continue
+		}
+
+		diagnostic.Source = dp.Name()
+		diagnostic.Severity = protocol.DiagnosticSeverityWarning
+		if start, end, found := docblockAbove(lines, int(diagnostic.Range.Start.Line)); found {
+			diagnostic.Range = docblockTagRange(lines, start, end, identifier, diagnostic.Message)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// isPhpDocIdentifier reports whether the phpstan error identifier is about a PHPDoc block
+func isPhpDocIdentifier(identifier string) bool {
+	for _, pattern := range phpDocIdentifierPatterns {
+		if matched, _ := path.Match(pattern, identifier); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitPhpDocErrors leaves the PHPDoc errors to phpdoc when both phpdoc and phpstan are enabled, so they
+// are reported once, on the docblock. phpstan ignores their identifiers, phpdoc reads them from the same
+// analysis when the runs are shared.
+func SplitPhpDocErrors(serverConfig *config.Config) {
+	phpstanConfig, phpstanExists := serverConfig.DiagnosticsProviders[PhpStanProviderId]
+	phpdocConfig, phpdocExists := serverConfig.DiagnosticsProviders[PhpDocProviderId]
+	if !phpstanExists || !phpdocExists || !phpstanConfig.Enabled || !phpdocConfig.Enabled {
+		return
+	}
+
+	ignored := append([]string(nil), phpstanConfig.IgnoreIdentifiers...)
+	configured := make(map[string]bool, len(ignored))
+	for _, pattern := range ignored {
+		configured[pattern] = true
+	}
+	for _, pattern := range phpDocIdentifierPatterns {
+		if !configured[pattern] {
+			ignored = append(ignored, pattern)
+		}
+	}
+	phpstanConfig.IgnoreIdentifiers = ignored
+	serverConfig.DiagnosticsProviders[PhpStanProviderId] = phpstanConfig
+}
+
+// docblockAbove returns the first and last lines of the docblock of the declaration on the line, skipping
+// its attributes. An inline @var docblock is found above the statement the same way.
+func docblockAbove(lines []string, line int) (int, int, bool) {
+	end := line - 1
+	for end >= 0 && end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "#[") {
+		end--
+	}
+	if end < 0 || end >= len(lines) || !strings.HasSuffix(strings.TrimSpace(lines[end]), "*/") {
+		return 0, 0, false
+	}
+
+	for start := end; start >= 0; start-- {
+		if strings.Contains(lines[start], "/**") {
+			return start, end, true
+		}
+		if start < end && strings.Contains(lines[start], "*/") {
+			break
+		}
+	}
+	return 0, 0, false
+}
+
+// docblockTagRange spans the tag the error is about (the @param of the named parameter, @return or @var)
+// to the end of its line, else the first line of the docblock
+func docblockTagRange(lines []string, start int, end int, identifier string, message string) protocol.Range {
+	tag := ""
+	switch {
+	case strings.Contains(identifier, "parameter") || strings.Contains(message, "@param") || strings.Contains(message, "parameter $"):
+		tag = `@param\b[^$]*`
+		if matches := phpDocVariableRegex.FindStringSubmatch(message); matches != nil {
+			tag += `\$` + matches[1] + `\b`
+		}
+	case strings.Contains(identifier, "return") || strings.Contains(message, "@return") || strings.Contains(message, "return type"):
+		tag = `@return\b`
+	case strings.HasPrefix(identifier, "varTag.") || strings.Contains(identifier, "property") || strings.Contains(message, "@var") || strings.HasPrefix(message, "Property "):
+		tag = `@var\b`
+	}
+
+	if tag != "" {
+		if tagRange, found := declarationRange(lines[start:end+1], regexp.MustCompile(tag)); found {
+			line := tagRange.Start.Line + uint32(start)
+			return protocol.Range{Start: protocol.Position{Line: line, Character: tagRange.Start.Character}, End: protocol.Position{Line: line, Character: uint32(len(lines[line]))}}
+		}
+	}
+
+	indent := len(lines[start]) - len(strings.TrimLeft(lines[start], " \t"))
+	return protocol.Range{Start: protocol.Position{Line: uint32(start), Character: uint32(indent)}, End: protocol.Position{Line: uint32(start), Character: uint32(len(lines[start]))}}
+}
+
+func NewPhpDoc(providerConfig config.DiagnosticsProvider) *PhpDoc {
+	return &PhpDoc{
+		config:  providerConfig,
+		phpstan: NewPhpStan(providerConfig),
+	}
+}
@@ -0,0 +1,125 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpDoc_Analyze(t *testing.T) {
+	source := `<?php
+
+class User
+{
+    /**
+     * @param string $name
+     * @param int $age
+     */
+    #[Deprecated]
+    public function rename($name, string $age): void
+    {
+    }
+
+    public function items()
+    {
+        /** @var int $count */
+        $count = count([]);
+    }
+}
+`
+	script := `cat <<'JSON'
+{"totals": {"errors": 0, "file_errors": 4}, "files": {"/app/User.php": {"errors": 4, "messages": [
+  {"message": "PHPDoc tag @param for parameter $age with type int is incompatible with native type string.", "line": 10, "ignorable": true, "identifier": "parameter.phpDocType"},
+  {"message": "Call to an undefined method User::save().", "line": 10, "ignorable": true, "identifier": "method.notFound"},
+  {"message": "Method User::items() has no return type specified.", "line": 14, "ignorable": true, "identifier": "missingType.return"},
+  {"message": "PHPDoc tag @var with type int is not subtype of native type int<0, max>.", "line": 17, "ignorable": true, "identifier": "varTag.nativeType"}
+]}}, "errors": []}
+JSON
+exit 1
+`
+	tool := newFakeTool(t, "phpstan", script)
+	filePath := tool.writeFile(t, "User.php", source)
+
+	provider := diagnostics.NewPhpDoc(tool.providerConfig("vendor/bin/phpstan"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("Expected the 3 PHPDoc errors, got %+v", result)
+	}
+
+	expected := []struct {
+		line      uint32
+		character uint32
+		code      string
+	}{
+		{6, 7, "parameter.phpDocType"},
+		{13, 0, "missingType.return"},
+		{15, 12, "varTag.nativeType"},
+	}
+	for i, e := range expected {
+		diagnostic := result[i]
+		if diagnostic.Range.Start.Line != e.line || diagnostic.Range.Start.Character != e.character || diagnostic.Code != e.code {
+			t.Errorf("Expected %s at %d:%d, got %+v", e.code, e.line, e.character, diagnostic)
+		}
+		if diagnostic.Source != "phpdoc" || diagnostic.Severity != protocol.DiagnosticSeverityWarning {
+			t.Errorf("Expected a phpdoc warning, got %+v", diagnostic)
+		}
+	}
+}
+
+func TestPhpDoc_SharesPhpStanRun(t *testing.T) {
+	// Every run is counted, the second provider must reuse the output of the first
+	script := `echo run >> runs
+cat <<'JSON'
+{"totals": {"errors": 0, "file_errors": 2}, "files": {"/app/User.php": {"errors": 2, "messages": [
+  {"message": "Call to an undefined method User::save().", "line": 5, "ignorable": true, "identifier": "method.notFound"},
+  {"message": "Method User::items() has no return type specified.", "line": 5, "ignorable": true, "identifier": "missingType.return"}
+]}}, "errors": []}
+JSON
+exit 1
+`
+	tool := newFakeTool(t, "phpstan", script)
+	filePath := tool.writeFile(t, "User.php", "<?php\n\nclass User\n{\n    public function items()\n    {\n    }\n}\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpstan")
+	serverConfig := &config.Config{DiagnosticsProviders: map[string]config.DiagnosticsProvider{
+		diagnostics.PhpStanProviderId: providerConfig,
+		diagnostics.PhpDocProviderId:  providerConfig,
+	}}
+	diagnostics.SplitPhpDocErrors(serverConfig)
+
+	phpstan := diagnostics.NewPhpStan(serverConfig.DiagnosticsProviders[diagnostics.PhpStanProviderId])
+	phpdoc := diagnostics.NewPhpDoc(serverConfig.DiagnosticsProviders[diagnostics.PhpDocProviderId])
+
+	ctx := diagnostics.WithSharedRuns(context.Background())
+	phpstanDiagnostics, err := phpstan.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("phpstan failed: %v", err)
+	}
+	phpdocDiagnostics, err := phpdoc.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("phpdoc failed: %v", err)
+	}
+
+	if len(phpstanDiagnostics) != 1 || phpstanDiagnostics[0].Code != "method.notFound" {
+		t.Errorf("Expected phpstan to leave the PHPDoc error to phpdoc, got %+v", phpstanDiagnostics)
+	}
+	if len(phpdocDiagnostics) != 1 || phpdocDiagnostics[0].Code != "missingType.return" {
+		t.Errorf("Expected phpdoc to report the PHPDoc error, got %+v", phpdocDiagnostics)
+	}
+
+	if count := countRuns(t, tool); count != 1 {
+		t.Errorf("Expected phpstan to run once, ran %d times", count)
+	}
+}
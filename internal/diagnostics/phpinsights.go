@@ -0,0 +1,163 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpInsightsProviderId   string = "phpinsights"
+	PhpInsightsProviderName string = "phpinsights"
+)
+
+// Categories of the PHPInsights report, in report order
+var phpInsightsCategories = []string{"Code", "Complexity", "Architecture", "Style", "Security"}
+
+// PhpInsightsIssue is an issue of the PHPInsights report, file and line are missing for the project wide ones
+type PhpInsightsIssue struct {
+	Title        string `json:"title"`
+	InsightClass string `json:"insightClass"`
+	File         string `json:"file,omitempty"`
+	Line         int    `json:"line,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// PhpInsightsOutputResult is the JSON report of phpinsights analyse: the category scores and the issues
+// of every category
+type PhpInsightsOutputResult struct {
+	Summary map[string]interface{}        `json:"summary"`
+	Issues  map[string][]PhpInsightsIssue `json:"issues"`
+}
+
+func (result *PhpInsightsOutputResult) UnmarshalJSON(data []byte) error {
+	var report map[string]json.RawMessage
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	result.Issues = make(map[string][]PhpInsightsIssue)
+	if summary, exists := report["summary"]; exists {
+		if err := json.Unmarshal(summary, &result.Summary); err != nil {
+			return err
+		}
+	}
+	for _, category := range phpInsightsCategories {
+		issues, exists := report[category]
+		if !exists {
+			continue
+		}
+		var categoryIssues []PhpInsightsIssue
+		if err := json.Unmarshal(issues, &categoryIssues); err != nil {
+			return fmt.Errorf("invalid %s issues: %w", category, err)
+		}
+		result.Issues[category] = categoryIssues
+	}
+	return nil
+}
+
+type PhpInsights struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpInsights) Id() string {
+	return PhpInsightsProviderId
+}
+
+func (dp *PhpInsights) Name() string {
+	return PhpInsightsProviderName
+}
+
+func (dp *PhpInsights) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--config-path=%s", dp.config.ConfigFile)
+	}
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s analyse %s --format=json --no-interaction %s 2>/dev/null", dp.config.Path, relativeFilePath, configArg),
+	)
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+func (dp *PhpInsights) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpinsights: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpInsightsOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, category := range phpInsightsCategories {
+		for _, issue := range fullAnalysisResult.Issues[category] {
+			// Issues of other files, or of the project (e.g. vulnerable packages)
+			if resolvedPath, found := utils.ResolveToolPath(projectRoot, issue.File); issue.File == "" || !found || resolvedPath != filePath {
+				continue
+			}
+			diagnostics = append(diagnostics, dp.issueDiagnostic(category, issue))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// issueDiagnostic reports the issue with its category in the source, style issues being information
+func (dp *PhpInsights) issueDiagnostic(category string, issue PhpInsightsIssue) protocol.Diagnostic {
+	line := uint32(0)
+	if issue.Line > 0 {
+		line = uint32(issue.Line - 1)
+	}
+
+	severity := protocol.DiagnosticSeverityWarning
+	if category == "Style" {
+		severity = protocol.DiagnosticSeverityInformation
+	}
+
+	message := issue.Title
+	if issue.Message != "" && issue.Message != issue.Title {
+		message = fmt.Sprintf("%s: %s", issue.Title, issue.Message)
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: severity,
+		Source:   fmt.Sprintf("%s/%s", dp.Name(), strings.ToLower(category)),
+		Message:  strings.TrimSpace(message),
+	}
+	if issue.InsightClass != "" {
+		diagnostic.Code = issue.InsightClass
+	}
+	return diagnostic
+}
+
+func NewPhpInsights(providerConfig config.DiagnosticsProvider) *PhpInsights {
+	return &PhpInsights{
+		config:   providerConfig,
+		executor: newExecutor(PhpInsightsProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,50 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpInsights_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "phpinsights", `cat <<'JSON'
+{
+  "summary": {"code": 85.5, "complexity": 90, "architecture": 75, "style": 88.2, "security issues": 1, "fixed issues": 0},
+  "Code": [{"title": "Unused parameter", "insightClass": "SlevomatCodingStandard\\Sniffs\\Functions\\UnusedParameterSniff", "file": "/app/src/Foo.php", "line": 12, "message": "Unused parameter $id."}],
+  "Complexity": [{"title": "Having classes with more than 5 cyclomatic complexity is prohibited", "insightClass": "NunoMaduro\\PhpInsights\\Domain\\Insights\\CyclomaticComplexityIsHigh", "file": "/app/src/Foo.php", "message": "9 cyclomatic complexity"}],
+  "Architecture": [],
+  "Style": [{"title": "Line length", "insightClass": "PHP_CodeSniffer\\Standards\\Generic\\Sniffs\\Files\\LineLengthSniff", "file": "/app/src/Bar.php", "line": 3, "message": "Line exceeds 120 characters"}],
+  "Security": [{"title": "Vulnerable package", "insightClass": "NunoMaduro\\PhpInsights\\Domain\\Insights\\ForbiddenSecurityIssues", "message": "symfony/http-kernel@5.4.0"}]
+}
+JSON
+exit 1
+`)
+	filePath := tool.writeFile(t, "src/Foo.php", "{}")
+
+	provider := diagnostics.NewPhpInsights(tool.providerConfig("vendor/bin/phpinsights"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 issues of Foo.php, got %+v", result)
+	}
+
+	code, complexity := result[0], result[1]
+	if code.Range.Start.Line != 11 || code.Source != "phpinsights/code" || code.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Unexpected code issue: %+v", code)
+	}
+	if code.Code != `SlevomatCodingStandard\Sniffs\Functions\UnusedParameterSniff` || code.Message != "Unused parameter: Unused parameter $id." {
+		t.Errorf("Expected the insight class as code, got %+v", code)
+	}
+	if complexity.Range.Start.Line != 0 || complexity.Source != "phpinsights/complexity" {
+		t.Errorf("Expected the complexity issue at the top of the file, got %+v", complexity)
+	}
+}
@@ -11,7 +11,6 @@ import (
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/container"
-	"github.com/cristianradulescu/php-diagls/internal/utils"
 	"go.lsp.dev/protocol"
 )
 
@@ -21,7 +20,8 @@ const (
 )
 
 type PhpLint struct {
-	config config.DiagnosticsProvider
+	config   config.DiagnosticsProvider
+	executor *container.Executor
 }
 
 func (dp *PhpLint) Id() string {
@@ -32,20 +32,42 @@ func (dp *PhpLint) Name() string {
 	return PhpLintProviderName
 }
 
-func (dp *PhpLint) Analyze(filePath string) ([]protocol.Diagnostic, error) {
-	var diagnostics []protocol.Diagnostic
-
-	projectRoot := utils.FindProjectRoot(filePath)
+func (dp *PhpLint) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
 	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
 
-	result := container.RunCommandInContainer(
-		context.Background(),
-		dp.config.Container,
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
 		fmt.Sprintf("%s -l %s 2>&1", dp.config.Path, relativeFilePath),
 	)
 
+	return dp.parseOutput(ctx, result)
+}
+
+// AnalyzeContent lints the content piped through stdin
+func (dp *PhpLint) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	result := dp.executor.Run(
+		ctx,
+		providerRoot(dp.config, filePath),
+		fmt.Sprintf("%s -l 2>&1", dp.config.Path),
+		content,
+	)
+
+	return dp.parseOutput(ctx, result)
+}
+
+// PhpLintRawResult is the output of php -l
+type PhpLintRawResult struct {
+	Output string `json:"output"`
+}
+
+func (dp *PhpLint) parseOutput(ctx context.Context, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	var diagnostics []protocol.Diagnostic
+
 	output := string(result.Stdout)
 	if strings.HasPrefix(output, "No syntax errors detected") {
+		recordRawResult(ctx, PhpLintRawResult{Output: output})
 		return diagnostics, nil
 	}
 
@@ -67,18 +89,22 @@ func (dp *PhpLint) Analyze(filePath string) ([]protocol.Diagnostic, error) {
 			Source:   dp.Name(),
 			Message:  strings.TrimSpace(matches[1]),
 		})
+		recordRawResult(ctx, PhpLintRawResult{Output: output})
 		return diagnostics, nil
 	}
 
 	if result.Err != nil {
 		log.Printf("Error running phplint command: %v. Output: %s", result.Err, output)
 	}
+	// Neither a clean file nor a syntax error, the command itself failed
+	markFailed(ctx, outputFailure(result, fmt.Errorf("unexpected output: %s", strings.TrimSpace(output))))
 
 	return diagnostics, nil
 }
 
 func NewPhpLint(providerConfig config.DiagnosticsProvider) *PhpLint {
 	return &PhpLint{
-		config: providerConfig,
+		config:   providerConfig,
+		executor: newExecutor(PhpLintProviderName, providerConfig),
 	}
 }
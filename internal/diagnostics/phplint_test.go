@@ -1,6 +1,7 @@
 package diagnostics_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
@@ -52,7 +53,7 @@ func TestPhpLint_Analyze(t *testing.T) {
 	testFile := tmpDir + "/test.php"
 
 	// Test with non-existent container - should handle gracefully
-	diagnostics, err := linter.Analyze(testFile)
+	diagnostics, err := linter.Analyze(context.Background(), testFile)
 
 	// Should not return error even if container doesn't exist
 	if err != nil {
@@ -200,3 +201,23 @@ func TestPhpLint_NewPhpLint(t *testing.T) {
 		})
 	}
 }
+
+func TestPhpLint_TrackFailures(t *testing.T) {
+	linter := diagnostics.NewPhpLint(config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "php-diagls-missing-container",
+		Path:      "/usr/bin/php",
+	})
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := linter.AnalyzeContent(ctx, "/tmp/test.php", "<?php\n")
+	if err != nil {
+		t.Fatalf("Expected the failure to be logged, got error %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", result)
+	}
+	if failed() == nil {
+		t.Error("Expected the failed lint command to be tracked")
+	}
+}
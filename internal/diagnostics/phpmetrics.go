@@ -0,0 +1,248 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpMetricsProviderId   string = "phpmetrics"
+	PhpMetricsProviderName string = "phpmetrics"
+
+	phpMetricsDefaultMaxComplexity int = 10
+	phpMetricsDefaultMaxLines      int = 50
+
+	phpMetricsClassType    string = `Hal\Metric\ClassMetric`
+	phpMetricsFunctionType string = `Hal\Metric\FunctionMetric`
+)
+
+// PhpMetricsMethod is a method of a class in the PhpMetrics report
+type PhpMetricsMethod struct {
+	Name string `json:"name"`
+	Ccn  int    `json:"ccn"`
+}
+
+// PhpMetricsElement is a class or a function in the PhpMetrics report, the other elements (packages) having
+// none of these metrics
+type PhpMetricsElement struct {
+	Type    string             `json:"_type"`
+	Name    string             `json:"name"`
+	Ccn     int                `json:"ccn"`
+	Methods []PhpMetricsMethod `json:"methods"`
+}
+
+// PhpMetricsOutputResult is the JSON report of phpmetrics, by element name
+type PhpMetricsOutputResult map[string]PhpMetricsElement
+
+type PhpMetrics struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpMetrics) Id() string {
+	return PhpMetricsProviderId
+}
+
+func (dp *PhpMetrics) Name() string {
+	return PhpMetricsProviderName
+}
+
+// Analyze reports the functions and methods of the file whose cyclomatic complexity or length exceed the
+// thresholds
+func (dp *PhpMetrics) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, string(content), result)
+}
+
+// analyzeCommand writes the JSON report to stdout, through file descriptor 3 as phpmetrics prints its
+// summary there
+func (dp *PhpMetrics) analyzeCommand(relativeFilePath string) string {
+	return fmt.Sprintf("%s --report-json=/dev/fd/3 %s 3>&1 >/dev/null 2>&1", dp.config.Path, relativeFilePath)
+}
+
+func (dp *PhpMetrics) parseOutput(ctx context.Context, content string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpmetrics: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpMetricsOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	// Only the file was analyzed, the report has no paths
+	lines := strings.Split(content, "\n")
+	for _, function := range PhpMetricsFunctions(content, fullAnalysisResult) {
+		diagnostics = append(diagnostics, dp.functionDiagnostics(lines, function)...)
+	}
+
+	return diagnostics, nil
+}
+
+// PhpMetricsFunction is a function or a method of the PhpMetrics report, located in the analyzed file
+type PhpMetricsFunction struct {
+	// Label names the function in the messages, e.g. Foo::bar()
+	Label string
+	// Range is the name of the function in its declaration
+	Range protocol.Range
+	// Ccn is the cyclomatic complexity
+	Ccn int
+}
+
+// PhpMetricsFunctions locates the functions and methods of the report in the content of the analyzed file,
+// sorted by element name. Elements not declared in the content are skipped.
+func PhpMetricsFunctions(content string, report PhpMetricsOutputResult) []PhpMetricsFunction {
+	functions := []PhpMetricsFunction{}
+
+	names := make([]string, 0, len(report))
+	for name := range report {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := strings.Split(content, "\n")
+	for _, name := range names {
+		element := report[name]
+		switch element.Type {
+		case phpMetricsClassType:
+			shortName := element.Name[strings.LastIndex(element.Name, `\`)+1:]
+			classRange, found := declarationRange(lines, regexp.MustCompile(`\b(class|trait|enum)\s+`+regexp.QuoteMeta(shortName)+`\b`))
+			if !found {
+				continue
+			}
+			for _, method := range element.Methods {
+				if functionRange, found := functionDeclarationRange(lines, int(classRange.Start.Line), method.Name); found {
+					functions = append(functions, PhpMetricsFunction{Label: shortName + "::" + method.Name + "()", Range: functionRange, Ccn: method.Ccn})
+				}
+			}
+		case phpMetricsFunctionType:
+			shortName := element.Name[strings.LastIndex(element.Name, `\`)+1:]
+			if functionRange, found := functionDeclarationRange(lines, 0, shortName); found {
+				functions = append(functions, PhpMetricsFunction{Label: shortName + "()", Range: functionRange, Ccn: element.Ccn})
+			}
+		}
+	}
+
+	return functions
+}
+
+// functionDeclarationRange locates the name of the function declared after the line from
+func functionDeclarationRange(lines []string, from int, name string) (protocol.Range, bool) {
+	functionRange, found := declarationRange(lines[from:], regexp.MustCompile(`\bfunction\s+&?(?P<at>`+regexp.QuoteMeta(name)+`)\s*\(`))
+	if !found {
+		return functionRange, false
+	}
+	functionRange.Start.Line += uint32(from)
+	functionRange.End.Line += uint32(from)
+	return functionRange, true
+}
+
+// functionDiagnostics reports the function when it exceeds a threshold, on its declaration
+func (dp *PhpMetrics) functionDiagnostics(lines []string, function PhpMetricsFunction) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	maxComplexity := dp.config.MaxComplexity
+	if maxComplexity == 0 {
+		maxComplexity = phpMetricsDefaultMaxComplexity
+	}
+	if function.Ccn > maxComplexity {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    function.Range,
+			Severity: protocol.DiagnosticSeverityInformation,
+			Source:   dp.Name(),
+			Code:     "complexity",
+			Message:  fmt.Sprintf("%s has a cyclomatic complexity of %d (max %d)", function.Label, function.Ccn, maxComplexity),
+		})
+	}
+
+	maxLines := dp.config.MaxLines
+	if maxLines == 0 {
+		maxLines = phpMetricsDefaultMaxLines
+	}
+	if length := functionLength(lines, int(function.Range.Start.Line)); length > maxLines {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    function.Range,
+			Severity: protocol.DiagnosticSeverityInformation,
+			Source:   dp.Name(),
+			Code:     "length",
+			Message:  fmt.Sprintf("%s has %d lines (max %d)", function.Label, length, maxLines),
+		})
+	}
+
+	return diagnostics
+}
+
+// functionLength counts the lines from the declaration to the closing brace of the body, skipping the braces
+// of the strings and comments, 0 for an abstract function
+func functionLength(lines []string, declaration int) int {
+	depth := 0
+	var quote byte
+	blockComment := false
+	for i := declaration; i < len(lines); i++ {
+		line := lines[i]
+		for j := 0; j < len(line); j++ {
+			switch {
+			case blockComment:
+				if strings.HasPrefix(line[j:], "*/") {
+					blockComment = false
+					j++
+				}
+			case quote != 0:
+				if line[j] == '\\' {
+					j++
+				} else if line[j] == quote {
+					quote = 0
+				}
+			case line[j] == '\'' || line[j] == '"':
+				quote = line[j]
+			case strings.HasPrefix(line[j:], "/*"):
+				blockComment = true
+				j++
+			case strings.HasPrefix(line[j:], "//") || line[j] == '#' && !strings.HasPrefix(line[j:], "#["):
+				j = len(line)
+			case line[j] == ';' && depth == 0:
+				return 0
+			case line[j] == '{':
+				depth++
+			case line[j] == '}':
+				depth--
+				if depth == 0 {
+					return i - declaration + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func NewPhpMetrics(providerConfig config.DiagnosticsProvider) *PhpMetrics {
+	return &PhpMetrics{
+		config:   providerConfig,
+		executor: newExecutor(PhpMetricsProviderName, providerConfig),
+	}
+}
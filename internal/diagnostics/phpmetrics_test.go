@@ -0,0 +1,94 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpMetrics_Analyze(t *testing.T) {
+	body := strings.Repeat("        $total += 1;\n", 6)
+	source := `<?php
+
+namespace App;
+
+function helper(): void
+{
+}
+
+abstract class Invoice
+{
+    abstract public function total(): int;
+
+    public function price(array $items): int
+    {
+        $total = 0; // {
+` + body + `        return "}" === $total ? 0 : $total;
+    }
+}
+`
+	tool := newFakeTool(t, "phpmetrics", `echo "PhpMetrics by Jean-François Lépine"
+cat >&3 <<'JSON'
+{
+  "App\\Invoice": {"name": "App\\Invoice", "interface": false, "abstract": true, "ccn": 14, "ccnMethodMax": 12, "loc": 20,
+    "methods": [{"name": "total", "ccn": 1, "_type": "Hal\\Metric\\FunctionMetric"}, {"name": "price", "ccn": 12, "_type": "Hal\\Metric\\FunctionMetric"}],
+    "_type": "Hal\\Metric\\ClassMetric"},
+  "App\\helper": {"name": "App\\helper", "ccn": 1, "loc": 3, "_type": "Hal\\Metric\\FunctionMetric"},
+  "App": {"name": "App", "classes": ["App\\Invoice"], "_type": "Hal\\Metric\\PackageMetric"}
+}
+JSON
+`)
+	filePath := tool.writeFile(t, "src/Invoice.php", source)
+
+	providerConfig := tool.providerConfig("vendor/bin/phpmetrics")
+	providerConfig.MaxLines = 8
+	provider := diagnostics.NewPhpMetrics(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", result)
+	}
+
+	expectedRange := protocol.Range{Start: protocol.Position{Line: 12, Character: 20}, End: protocol.Position{Line: 12, Character: 25}}
+	complexity, length := result[0], result[1]
+	if complexity.Range != expectedRange || complexity.Severity != protocol.DiagnosticSeverityInformation || complexity.Code != "complexity" {
+		t.Errorf("Expected the complexity on the method name, got %+v", complexity)
+	}
+	if complexity.Message != "Invoice::price() has a cyclomatic complexity of 12 (max 10)" {
+		t.Errorf("Unexpected complexity message: %q", complexity.Message)
+	}
+	if length.Range != expectedRange || length.Message != "Invoice::price() has 11 lines (max 8)" {
+		t.Errorf("Expected the length of the method, got %+v", length)
+	}
+}
+
+func TestPhpMetricsFunctions(t *testing.T) {
+	content := "<?php\n\nnamespace App;\n\nfinal class Cart\n{\n    public function add(): void\n    {\n    }\n}\n\nfunction &helper(): array\n{\n}\n"
+	report := diagnostics.PhpMetricsOutputResult{
+		`App\Cart`:    {Type: `Hal\Metric\ClassMetric`, Name: `App\Cart`, Methods: []diagnostics.PhpMetricsMethod{{Name: "add", Ccn: 3}, {Name: "inherited", Ccn: 1}}},
+		`App\helper`:  {Type: `Hal\Metric\FunctionMetric`, Name: `App\helper`, Ccn: 2},
+		`App\Missing`: {Type: `Hal\Metric\ClassMetric`, Name: `App\Missing`, Methods: []diagnostics.PhpMetricsMethod{{Name: "add", Ccn: 5}}},
+		`App`:         {Type: `Hal\Metric\PackageMetric`, Name: `App`},
+	}
+
+	functions := diagnostics.PhpMetricsFunctions(content, report)
+	if len(functions) != 2 {
+		t.Fatalf("Expected the 2 functions declared in the content, got %+v", functions)
+	}
+	if add := functions[0]; add.Label != "Cart::add()" || add.Ccn != 3 || add.Range.Start != (protocol.Position{Line: 6, Character: 20}) {
+		t.Errorf("Unexpected method: %+v", add)
+	}
+	if helper := functions[1]; helper.Label != "helper()" || helper.Ccn != 2 || helper.Range.Start != (protocol.Position{Line: 11, Character: 10}) {
+		t.Errorf("Unexpected function: %+v", helper)
+	}
+}
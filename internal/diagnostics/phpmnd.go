@@ -0,0 +1,146 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpMndProviderId   string = "phpmnd"
+	PhpMndProviderName string = "phpmnd"
+)
+
+// Not magic unless configured otherwise
+var phpMndDefaultIgnoreNumbers = []string{"0", "1"}
+
+// PhpMndOutputResult is the XML report of phpmnd
+type PhpMndOutputResult struct {
+	XMLName xml.Name     `xml:"phpmnd" json:"-"`
+	Files   []PhpMndFile `xml:"files>file" json:"files"`
+}
+
+type PhpMndFile struct {
+	Path    string        `xml:"path,attr" json:"path"`
+	Entries []PhpMndEntry `xml:"entry" json:"entries"`
+}
+
+// PhpMndEntry is a magic number, the line being 1-based and the start and end offsets of the literal in
+// the line 0-based
+type PhpMndEntry struct {
+	Line        int      `xml:"line,attr" json:"line"`
+	Start       int      `xml:"start,attr" json:"start"`
+	End         int      `xml:"end,attr" json:"end"`
+	Suggestions []string `xml:"suggestions>suggestion" json:"suggestions,omitempty"`
+}
+
+type PhpMnd struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpMnd) Id() string {
+	return PhpMndProviderId
+}
+
+func (dp *PhpMnd) Name() string {
+	return PhpMndProviderName
+}
+
+func (dp *PhpMnd) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, projectRoot, filePath, string(content), result)
+}
+
+// analyzeCommand writes the XML report to stdout, through file descriptor 3 as phpmnd prints its text
+// report there too. The constants holding the same value are suggested.
+func (dp *PhpMnd) analyzeCommand(relativeFilePath string) string {
+	ignoreNumbers := dp.config.IgnoreNumbers
+	if len(ignoreNumbers) == 0 {
+		ignoreNumbers = phpMndDefaultIgnoreNumbers
+	}
+
+	return fmt.Sprintf("%s %s --ignore-numbers=%s --hint --xml-output=/dev/fd/3 3>&1 >/dev/null 2>&1", dp.config.Path, relativeFilePath, strings.Join(ignoreNumbers, ","))
+}
+
+func (dp *PhpMnd) parseOutput(ctx context.Context, projectRoot string, filePath string, content string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpmnd: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpMndOutputResult
+	if err := xml.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	lines := strings.Split(content, "\n")
+	filePath = filepath.Clean(filePath)
+	for _, file := range fullAnalysisResult.Files {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, file.Path); !found || resolvedPath != filePath {
+			continue
+		}
+
+		for _, entry := range file.Entries {
+			diagnostics = append(diagnostics, dp.entryDiagnostic(lines, entry))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// entryDiagnostic spans the literal, named in the message with the suggested constants
+func (dp *PhpMnd) entryDiagnostic(lines []string, entry PhpMndEntry) protocol.Diagnostic {
+	line := 0
+	if entry.Line > 0 {
+		line = entry.Line - 1
+	}
+
+	entryRange := protocol.Range{Start: protocol.Position{Line: uint32(line), Character: 0}, End: protocol.Position{Line: uint32(line), Character: 100}}
+	message := "Magic number"
+	if line < len(lines) && entry.Start >= 0 && entry.Start < entry.End && entry.End <= len(lines[line]) {
+		entryRange.Start.Character = uint32(entry.Start)
+		entryRange.End.Character = uint32(entry.End)
+		message = fmt.Sprintf("Magic number %s", lines[line][entry.Start:entry.End])
+	}
+	if len(entry.Suggestions) > 0 {
+		message += fmt.Sprintf(", use %s", strings.Join(entry.Suggestions, " or "))
+	}
+
+	return protocol.Diagnostic{
+		Range:    entryRange,
+		Severity: configuredSeverity(dp.config.Severity),
+		Source:   dp.Name(),
+		Message:  message,
+	}
+}
+
+func NewPhpMnd(providerConfig config.DiagnosticsProvider) *PhpMnd {
+	return &PhpMnd{
+		config:   providerConfig,
+		executor: newExecutor(PhpMndProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,64 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpMnd_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "phpmnd", `echo "$@" > arguments
+echo "phpmnd 3.2.0 by Povilas Susinskas"
+cat >&3 <<'XML'
+<?xml version="1.0"?>
+<phpmnd version="3.2.0" fileCount="1" errorCount="1">
+  <files>
+    <file path="src/Retry.php" errors="1">
+      <entry line="5" start="20" end="24">
+        <snippet><![CDATA[    return $tries * 3600;]]></snippet>
+        <suggestions><suggestion>App\Clock::HOUR</suggestion></suggestions>
+      </entry>
+    </file>
+  </files>
+</phpmnd>
+XML
+exit 1
+`)
+	filePath := tool.writeFile(t, "src/Retry.php", "<?php\n\nfunction delay(int $tries): int\n{\n    return $tries * 3600;\n}\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpmnd")
+	providerConfig.IgnoreNumbers = []string{"0", "1", "2"}
+	providerConfig.Severity = config.SeverityWarning
+	provider := diagnostics.NewPhpMnd(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if !strings.Contains(content, "--ignore-numbers=0,1,2") {
+		t.Errorf("Expected the configured numbers ignored, got %q", content)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+
+	number := result[0]
+	expectedRange := protocol.Range{Start: protocol.Position{Line: 4, Character: 20}, End: protocol.Position{Line: 4, Character: 24}}
+	if number.Range != expectedRange || number.Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Expected a warning on the literal, got %+v", number)
+	}
+	if number.Message != `Magic number 3600, use App\Clock::HOUR` {
+		t.Errorf("Unexpected message: %q", number.Message)
+	}
+}
@@ -2,10 +2,16 @@ package diagnostics
 
 import (
 	"context"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/container"
@@ -16,22 +22,34 @@ import (
 const (
 	PhpStanProviderId   string = "phpstan"
 	PhpStanProviderName string = "phpstan"
+	// PHPStan has no describe command, every identifier is documented on its website
+	PhpStanIdentifierDocsUrl string = "https://phpstan.org/error-identifiers/%s"
 )
 
+var phpStanIdentifierRegex = regexp.MustCompile(`^[a-z][A-Za-z0-9]*(\.[A-Za-z0-9]+)+$`)
+
+// Locations mentioned by the messages, e.g. "defined at /app/src/Foo.php:12" or "in src/Foo.php on line 12"
+var phpStanLocationRegex = regexp.MustCompile(`([\w./-]+\.php)(?::| on line )(\d+)`)
+
+type PhpstanMessage struct {
+	Message    string  `json:"message"`
+	Line       int     `json:"line"`
+	Ignorable  bool    `json:"ignorable"`
+	Identifier *string `json:"identifier,omitempty"`
+	Tip        *string `json:"tip,omitempty"`
+}
+
 type PhpstanOutputResult struct {
 	Files map[string]struct {
-		Messages []struct {
-			Message    string  `json:"message"`
-			Line       int     `json:"line"`
-			Ignorable  bool    `json:"ignorable"`
-			Identifier *string `json:"identifier,omitempty"`
-		} `json:"messages"`
+		Messages []PhpstanMessage `json:"messages"`
 	} `json:"files"`
 	Errors []string `json:"errors"`
 }
 
 type PhpStan struct {
-	config config.DiagnosticsProvider
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+	shadow   *container.ShadowWorkspace
 }
 
 func (dp *PhpStan) Id() string {
@@ -42,63 +60,253 @@ func (dp *PhpStan) Name() string {
 	return PhpStanProviderName
 }
 
-func (dp *PhpStan) Analyze(filePath string) ([]protocol.Diagnostic, error) {
-	var diagnostics []protocol.Diagnostic
+func (dp *PhpStan) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	cmd := dp.analyzeCommand(relativeFilePath, "")
+
+	var result *container.CommandResult
+	if dp.useShadow() {
+		// Other dirty files are only visible in the shadow copy
+		result = dp.shadow.Run(ctx, cmd)
+	} else {
+		result = runShared(ctx, dp.executor, projectRoot, cmd)
+	}
+
+	return dp.parseOutput(ctx, projectRoot, result)
+}
 
-	projectRoot := utils.FindProjectRoot(filePath)
+// AnalyzeContent writes the content to a temporary file and analyzes it in place of the file,
+// using phpstan's --tmp-file/--instead-of editor mode. One command writes, analyzes and removes the
+// file, the runs of an executor with several containers each going to the next container.
+func (dp *PhpStan) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
 	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+	tmpFile := fmt.Sprintf("/tmp/%s-%x.php", config.Name, sha1.Sum([]byte(filePath)))
+
+	// The command starts with the tool, like stdinCopyCommand, so the local fallback runs the local one.
+	// phpstan's stderr is discarded, the shell only reports the temporary file errors there.
+	analyzeCmd := dp.analyzeCommand(relativeFilePath, fmt.Sprintf("$(cat > %[1]s && echo %[1]s)", tmpFile))
+	cmd := fmt.Sprintf("%[1]s; rc=$?; rm -f %[2]s || echo \"failed to remove %[2]s\" >&2; exit $rc", analyzeCmd, tmpFile)
+	result := runShared(ctx, dp.executor, projectRoot, cmd, content)
+	if result.Err == nil && len(result.Stderr) > 0 {
+		log.Printf("Temporary file of phpstan in %s: %s", projectRoot, strings.TrimSpace(string(result.Stderr)))
+	}
+
+	return dp.parseOutput(ctx, projectRoot, result)
+}
 
+// analyzeCommand builds the analysis command, analyzing tmpFile in place of the file when not empty
+func (dp *PhpStan) analyzeCommand(relativeFilePath string, tmpFile string) string {
 	configArg := ""
 	if dp.config.ConfigFile != "" {
 		configArg = fmt.Sprintf("--configuration=%s", dp.config.ConfigFile)
 	}
-	result := container.RunCommandInContainer(
-		context.Background(),
-		dp.config.Container,
-		fmt.Sprintf("%s analyze %s --memory-limit=-1 --no-progress --error-format=json %s 2>/dev/null", dp.config.Path, relativeFilePath, configArg),
-	)
+	if tmpFile != "" {
+		configArg += fmt.Sprintf(" --tmp-file=%s --instead-of=%s", tmpFile, relativeFilePath)
+	}
+
+	return fmt.Sprintf("%s analyze %s --memory-limit=-1 --no-progress --error-format=json %s 2>/dev/null", dp.config.Path, relativeFilePath, configArg)
+}
+
+// Watch keeps the configured watch command of phpstan running, decoding one JSON report per analysis run
+// from its output. Reported paths are resolved to the project files.
+func (dp *PhpStan) Watch(ctx context.Context, projectRoot string, onReport func(results map[string][]protocol.Diagnostic)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	streamErr := make(chan error, 1)
+	go func() {
+		err := dp.executor.Stream(ctx, projectRoot, dp.config.Watch.Command, writer)
+		writer.CloseWithError(err)
+		streamErr <- err
+	}()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var report PhpstanOutputResult
+		if err := decoder.Decode(&report); err != nil {
+			// Stop the tool, it would block writing to the closed pipe
+			cancel()
+			reader.Close()
+			// The pipe returns the stream error once the tool is gone
+			if streamResult := <-streamErr; err != streamResult {
+				return fmt.Errorf("invalid %s watch output: %w", dp.Name(), err)
+			}
+			return err
+		}
+
+		for _, reportError := range report.Errors {
+			log.Printf("Error reported by phpstan: %s", reportError)
+		}
+
+		results := make(map[string][]protocol.Diagnostic, len(report.Files))
+		for reportedPath, file := range report.Files {
+			filePath, found := utils.ResolveToolPath(projectRoot, reportedPath)
+			if !found {
+				log.Printf("Skipping phpstan results of %s, not found in %s", reportedPath, projectRoot)
+				continue
+			}
+			results[filePath] = dp.messageDiagnostics(projectRoot, file.Messages)
+		}
+		onReport(results)
+	}
+}
+
+// ExplainRule points to the documentation of the error identifier, phpstan can't describe it offline
+func (dp *PhpStan) ExplainRule(ctx context.Context, rule string) (string, error) {
+	if !phpStanIdentifierRegex.MatchString(rule) {
+		return "", fmt.Errorf("%s is not a %s error identifier", rule, dp.Name())
+	}
+
+	return fmt.Sprintf("PHPStan error identifier %s\n\nDescription and examples: %s", rule, fmt.Sprintf(PhpStanIdentifierDocsUrl, rule)), nil
+}
+
+func (dp *PhpStan) parseOutput(ctx context.Context, projectRoot string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	var diagnostics []protocol.Diagnostic
 
 	if result.Err != nil {
 		log.Printf("Error running phpstan: %v", result.Err)
+		markFailed(ctx, result.Failure())
 		return []protocol.Diagnostic{}, nil
 	}
 
 	var fullAnalysisResult PhpstanOutputResult
-	if err := json.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
 		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
 		return []protocol.Diagnostic{}, nil
 	}
+	recordRawResult(ctx, fullAnalysisResult)
 
 	for _, file := range fullAnalysisResult.Files {
-		for _, message := range file.Messages {
-			line := uint32(0)
-			if message.Line > 0 {
-				line = uint32(message.Line - 1)
-			}
+		diagnostics = append(diagnostics, dp.messageDiagnostics(projectRoot, file.Messages)...)
+	}
 
-			severity := protocol.DiagnosticSeverityError
-			if message.Ignorable {
-				severity = protocol.DiagnosticSeverityWarning
-			}
+	return diagnostics, nil
+}
 
-			diagnostic := protocol.Diagnostic{
-				Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
-				Severity: severity,
-				Source:   dp.Name(),
-				Message:  message.Message,
-			}
-			if message.Identifier != nil {
-				diagnostic.Code = *message.Identifier
+func (dp *PhpStan) messageDiagnostics(projectRoot string, messages []PhpstanMessage) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	for _, message := range messages {
+		line := uint32(0)
+		if message.Line > 0 {
+			line = uint32(message.Line - 1)
+		}
+
+		severity := protocol.DiagnosticSeverityError
+		if message.Ignorable {
+			severity = protocol.DiagnosticSeverityWarning
+		}
+
+		if message.Identifier != nil && dp.isIgnoredIdentifier(*message.Identifier) {
+			continue
+		}
+
+		diagnostic := protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+			Severity: severity,
+			Source:   dp.Name(),
+			Message:  message.Message,
+		}
+		if message.Identifier != nil {
+			diagnostic.Code = *message.Identifier
+		}
+		diagnostic.RelatedInformation = phpStanRelatedInformation(projectRoot, message)
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// phpStanRelatedInformation links the project locations mentioned by the message and its tip, such as the
+// definition the error is about
+func phpStanRelatedInformation(projectRoot string, message PhpstanMessage) []protocol.DiagnosticRelatedInformation {
+	texts := []string{message.Message}
+	if message.Tip != nil {
+		texts = append(texts, *message.Tip)
+	}
+
+	var related []protocol.DiagnosticRelatedInformation
+	for _, text := range texts {
+		// Most messages mention no file, skip the regex
+		if !strings.Contains(text, ".php") {
+			continue
+		}
+		for _, matches := range phpStanLocationRegex.FindAllStringSubmatch(text, -1) {
+			filePath, found := utils.ResolveToolPath(projectRoot, matches[1])
+			line, err := strconv.Atoi(matches[2])
+			if !found || err != nil || line < 1 {
+				continue
 			}
-			diagnostics = append(diagnostics, diagnostic)
+			related = append(related, protocol.DiagnosticRelatedInformation{
+				Location: protocol.Location{
+					URI:   utils.PathToURI(filePath),
+					Range: protocol.Range{Start: protocol.Position{Line: uint32(line - 1), Character: 0}, End: protocol.Position{Line: uint32(line - 1), Character: 100}},
+				},
+				Message: "Mentioned by the error",
+			})
 		}
 	}
 
-	return diagnostics, nil
+	return related
+}
+
+// isIgnoredIdentifier reports whether the identifier matches one of the ignoreIdentifiers patterns
+func (dp *PhpStan) isIgnoredIdentifier(identifier string) bool {
+	for _, pattern := range dp.config.IgnoreIdentifiers {
+		if matched, _ := path.Match(pattern, identifier); matched {
+			return true
+		}
+	}
+	return false
 }
 
 func NewPhpStan(providerConfig config.DiagnosticsProvider) *PhpStan {
-	return &PhpStan{
-		config: providerConfig,
+	dp := &PhpStan{
+		config:   providerConfig,
+		executor: newExecutor(PhpStanProviderName, providerConfig),
+	}
+	if providerConfig.ShadowWorkspace != "" {
+		dp.shadow = dp.executor.Shadow(providerConfig.ShadowWorkspace)
+	}
+
+	return dp
+}
+
+// useShadow reports whether the analysis must run in the shadow copy. The shadow copy lives in the
+// container, so it is skipped while the executor runs on a fallback backend.
+func (dp *PhpStan) useShadow() bool {
+	return dp.shadow != nil && dp.executor.Backend().Kind == container.BackendContainer && dp.shadow.HasDirtyFiles()
+}
+
+func (dp *PhpStan) SyncBuffer(ctx context.Context, filePath string, content string) error {
+	if dp.shadow == nil {
+		return nil
 	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+	return dp.shadow.Sync(ctx, projectRoot, filepath.ToSlash(relativeFilePath), content)
+}
+
+func (dp *PhpStan) RestoreBuffer(ctx context.Context, filePath string) error {
+	if dp.shadow == nil {
+		return nil
+	}
+
+	relativeFilePath, _ := filepath.Rel(providerRoot(dp.config, filePath), filePath)
+	return dp.shadow.Restore(ctx, filepath.ToSlash(relativeFilePath))
+}
+
+func (dp *PhpStan) RefreshFile(filePath string) {
+	if dp.shadow == nil {
+		return
+	}
+
+	relativeFilePath, _ := filepath.Rel(providerRoot(dp.config, filePath), filePath)
+	dp.shadow.Refresh(filepath.ToSlash(relativeFilePath))
 }
@@ -1,10 +1,15 @@
 package diagnostics_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
 )
 
 func TestPhpStan_Id(t *testing.T) {
@@ -102,7 +107,7 @@ func TestPhpStan_Analyze(t *testing.T) {
 	testFile := tmpDir + "/test.php"
 
 	// Test with non-existent container - should handle gracefully
-	diagnostics, err := analyzer.Analyze(testFile)
+	diagnostics, err := analyzer.Analyze(context.Background(), testFile)
 
 	// Should not return error even if container doesn't exist
 	if err != nil {
@@ -331,3 +336,218 @@ func TestPhpStan_ConfigFileHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestPhpStan_BufferSyncWithoutShadowWorkspace(t *testing.T) {
+	providerConfig := config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "test-container",
+		Path:      "/usr/local/bin/phpstan",
+	}
+
+	var provider diagnostics.DiagnosticsProvider = diagnostics.NewPhpStan(providerConfig)
+	syncer, ok := provider.(diagnostics.BufferSyncer)
+	if !ok {
+		t.Fatal("Expected phpstan to implement BufferSyncer")
+	}
+
+	if err := syncer.SyncBuffer(context.Background(), "/tmp/project/src/Foo.php", "<?php\n"); err != nil {
+		t.Errorf("Expected no error without shadow workspace, got %v", err)
+	}
+	if err := syncer.RestoreBuffer(context.Background(), "/tmp/project/src/Foo.php"); err != nil {
+		t.Errorf("Expected no error without shadow workspace, got %v", err)
+	}
+	syncer.RefreshFile("/tmp/project/src/Foo.php")
+}
+
+func TestPhpStan_ExplainRule(t *testing.T) {
+	analyzer := diagnostics.NewPhpStan(config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "test-container",
+		Path:      "/usr/local/bin/phpstan",
+	})
+
+	tests := []struct {
+		rule    string
+		wantErr bool
+	}{
+		{"argument.type", false},
+		{"missingType.iterableValue", false},
+		{"no_unused_imports", true},
+		{"Generic.Files.LineLength", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			explanation, err := analyzer.ExplainRule(context.Background(), tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !strings.Contains(explanation, "https://phpstan.org/error-identifiers/"+tt.rule) {
+				t.Errorf("Expected the docs URL in the explanation, got %q", explanation)
+			}
+		})
+	}
+}
+
+func TestPhpStan_IgnoreIdentifiers(t *testing.T) {
+	// Runs through the local fallback, the container doesn't exist
+	output := `{"files": {"src/Foo.php": {"messages": [
+		{"message": "No value type specified in iterable type array.", "line": 3, "identifier": "missingType.iterableValue"},
+		{"message": "Method has no return type specified.", "line": 4, "identifier": "missingType.return"},
+		{"message": "Undefined variable: $foo", "line": 5, "identifier": "variable.undefined"}
+	]}}, "errors": []}`
+	tool := newFakeTool(t, "phpstan", outputScript(output, 0))
+
+	providerConfig := tool.providerConfig("/usr/local/bin/phpstan")
+	providerConfig.IgnoreIdentifiers = []string{"missingType.*"}
+	analyzer := diagnostics.NewPhpStan(providerConfig)
+
+	result, err := analyzer.Analyze(context.Background(), tool.path("src/Foo.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(result) != 1 || result[0].Code != "variable.undefined" {
+		t.Errorf("Expected only the variable.undefined error, got %v", result)
+	}
+}
+
+func TestPhpStan_RelatedInformation(t *testing.T) {
+	// Container paths in the message and the tip, the vendor file isn't in the project
+	output := `{"files": {"/app/src/Foo.php": {"messages": [
+		{"message": "Method App\\Foo::run() overrides method App\\Base::run() defined at /app/src/Base.php:12 but misses parameter $force.", "line": 8, "identifier": "method.childParameterType"},
+		{"message": "Call to deprecated method send().", "line": 9, "identifier": "method.deprecated", "tip": "Declared in /app/vendor/acme/Client.php:40, replaced by App\\Base::run() in src/Base.php on line 20"},
+		{"message": "Undefined variable: $foo", "line": 10, "identifier": "variable.undefined"}
+	]}}, "errors": []}`
+	tool := newFakeTool(t, "phpstan", outputScript(output, 0))
+	parentPath := tool.writeFile(t, "src/Base.php", "<?php\n")
+
+	analyzer := diagnostics.NewPhpStan(tool.providerConfig("/usr/local/bin/phpstan"))
+
+	result, err := analyzer.Analyze(context.Background(), tool.path("src/Foo.php"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %v", result)
+	}
+
+	for i, expectedLine := range []uint32{11, 19} {
+		related := result[i].RelatedInformation
+		if len(related) != 1 || related[0].Location.URI.Filename() != parentPath || related[0].Location.Range.Start.Line != expectedLine {
+			t.Errorf("Expected %s line %d as related information of %q, got %+v", parentPath, expectedLine+1, result[i].Message, related)
+		}
+	}
+	if result[2].RelatedInformation != nil {
+		t.Errorf("Expected no related information, got %+v", result[2].RelatedInformation)
+	}
+}
+
+func TestPhpStan_AnalyzeContent(t *testing.T) {
+	// Records the temporary file and its content, then reports an error on the analyzed file
+	script := `for arg in "$@"; do
+  case "$arg" in --tmp-file=*) tmp="${arg#--tmp-file=}";; esac
+done
+echo "$tmp" > tmp-path
+cp "$tmp" tmp-content
+` + outputScript(`{"files": {"src/Foo.php": {"messages": [{"message": "Undefined variable: $foo", "line": 2}]}}, "errors": []}`, 1)
+	tool := newFakeTool(t, "phpstan", script)
+
+	analyzer := diagnostics.NewPhpStan(tool.providerConfig("/usr/local/bin/phpstan"))
+
+	content := "<?php\necho $foo;\n"
+	result, err := analyzer.AnalyzeContent(context.Background(), tool.path("src/Foo.php"), content)
+	if err != nil {
+		t.Fatalf("AnalyzeContent failed: %v", err)
+	}
+	if len(result) != 1 || result[0].Range.Start.Line != 1 {
+		t.Errorf("Expected the error of the content, got %v", result)
+	}
+	if analyzed := tool.readFile(t, "tmp-content"); analyzed != content {
+		t.Errorf("Expected phpstan to analyze the content, got %q", analyzed)
+	}
+	tmpFile := strings.TrimSpace(tool.readFile(t, "tmp-path"))
+	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Errorf("Expected %s removed after the analysis, got %v", tmpFile, err)
+	}
+}
+
+func TestPhpStan_Watch(t *testing.T) {
+	// Two runs reported with the container paths, the second one after the error was fixed
+	script := "if [ \"$2\" != --watch ]; then echo '{\"files\": {}, \"errors\": []}'; exit 0; fi\n" +
+		"echo '{\"files\": {\"/app/src/Foo.php\": {\"messages\": [{\"message\": \"Undefined variable: $foo\", \"line\": 5, \"identifier\": \"variable.undefined\"}]}}, \"errors\": []}'\n" +
+		"echo '{\"files\": {\"/app/src/Foo.php\": {\"messages\": []}}, \"errors\": []}'\n"
+	tool := newFakeTool(t, "phpstan", script)
+	fooPath := tool.writeFile(t, "src/Foo.php", "<?php\n")
+
+	providerConfig := tool.providerConfig("/usr/local/bin/phpstan")
+	providerConfig.Watch = config.WatchConfig{Enabled: true, Command: "/usr/local/bin/phpstan analyze --watch"}
+	analyzer := diagnostics.NewPhpStan(providerConfig)
+
+	// Streams run on the active backend, the analysis switches to the fallback
+	if _, err := analyzer.Analyze(context.Background(), fooPath); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var reports []map[string]int
+	err := analyzer.Watch(context.Background(), tool.projectRoot, func(results map[string][]protocol.Diagnostic) {
+		counts := make(map[string]int, len(results))
+		for filePath, diags := range results {
+			counts[filePath] = len(diags)
+		}
+		reports = append(reports, counts)
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("Expected the watch to end with the tool, got %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %v", reports)
+	}
+	if reports[0][fooPath] != 1 {
+		t.Errorf("Expected 1 diagnostic for %s in the first report, got %v", fooPath, reports[0])
+	}
+	if count, exists := reports[1][fooPath]; !exists || count != 0 {
+		t.Errorf("Expected %s reported clean in the second report, got %v", fooPath, reports[1])
+	}
+}
+
+func TestPhpStan_WatchWrapper(t *testing.T) {
+	// The wrapper of the README: phpstan runs again whenever inotifywait reports a change, an error reported
+	// by the first run and fixed before the second one
+	script := `if [ -f fixed ]; then echo '{"files": {"src/Foo.php": {"messages": []}}, "errors": []}'; exit 0; fi
+echo '{"files": {"src/Foo.php": {"messages": [{"message": "Undefined variable: $foo", "line": 5}]}}, "errors": []}'
+exit 1
+`
+	tool := newFakeTool(t, "phpstan", script)
+	fooPath := tool.writeFile(t, "src/Foo.php", "<?php\n")
+
+	// The first wait sees the fix, the second one fails and ends the wrapper
+	binDir := t.TempDir()
+	inotifywait := filepath.Join(binDir, "inotifywait")
+	if err := os.WriteFile(inotifywait, []byte("#!/bin/sh\nif [ -f fixed ]; then exit 1; fi\ntouch fixed\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	providerConfig := tool.providerConfig("/usr/local/bin/phpstan")
+	providerConfig.Watch = config.WatchConfig{
+		Enabled: true,
+		Command: "while true; do " + tool.binary + " analyze --memory-limit=-1 --no-progress --error-format=json; inotifywait -qq -r -e close_write,moved_to,delete src || exit 1; done",
+	}
+	analyzer := diagnostics.NewPhpStan(providerConfig)
+	if _, err := analyzer.Analyze(context.Background(), fooPath); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var counts []int
+	err := analyzer.Watch(context.Background(), tool.projectRoot, func(results map[string][]protocol.Diagnostic) {
+		counts = append(counts, len(results[fooPath]))
+	})
+
+	if err == nil {
+		t.Error("Expected the watch to end with the wrapper")
+	}
+	if len(counts) != 2 || counts[0] != 1 || counts[1] != 0 {
+		t.Errorf("Expected the error reported then fixed, got %v", counts)
+	}
+}
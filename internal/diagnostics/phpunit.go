@@ -0,0 +1,185 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PhpUnitProviderId   string = "phpunit"
+	PhpUnitProviderName string = "phpunit"
+
+	// Only the test case files are run
+	phpUnitTestFileSuffix string = "Test.php"
+)
+
+// Frame of the trace of a failure, e.g. "/app/tests/UserTest.php:42"
+var phpUnitFrameRegex = regexp.MustCompile(`^(\S.*):(\d+)$`)
+
+// PhpUnitOutputResult is the JUnit report of phpunit
+type PhpUnitOutputResult struct {
+	XMLName    xml.Name           `xml:"testsuites" json:"-"`
+	TestSuites []PhpUnitTestSuite `xml:"testsuite" json:"testSuites"`
+}
+
+// PhpUnitTestSuite is a test class, or the data sets of a test, nesting its suites
+type PhpUnitTestSuite struct {
+	Name       string             `xml:"name,attr" json:"name"`
+	TestSuites []PhpUnitTestSuite `xml:"testsuite" json:"testSuites,omitempty"`
+	TestCases  []PhpUnitTestCase  `xml:"testcase" json:"testCases,omitempty"`
+}
+
+// PhpUnitTestCase is a test run, the line of the test method being 1-based
+type PhpUnitTestCase struct {
+	Name     string           `xml:"name,attr" json:"name"`
+	File     string           `xml:"file,attr" json:"file"`
+	Line     int              `xml:"line,attr" json:"line"`
+	Failures []PhpUnitProblem `xml:"failure" json:"failures,omitempty"`
+	Errors   []PhpUnitProblem `xml:"error" json:"errors,omitempty"`
+}
+
+// PhpUnitProblem is a failed assertion or an error of a test: the test name, the message and the trace
+type PhpUnitProblem struct {
+	Type string `xml:"type,attr" json:"type"`
+	Text string `xml:",chardata" json:"text"`
+}
+
+type PhpUnit struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *PhpUnit) Id() string {
+	return PhpUnitProviderId
+}
+
+func (dp *PhpUnit) Name() string {
+	return PhpUnitProviderName
+}
+
+// Analyze runs the tests of the test case file, reporting the failures and errors on the failing lines
+func (dp *PhpUnit) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	if !strings.HasSuffix(filePath, phpUnitTestFileSuffix) {
+		return []protocol.Diagnostic{}, nil
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.analyzeCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+// analyzeCommand writes the JUnit report to stdout, through file descriptor 3 as phpunit prints its
+// progress there
+func (dp *PhpUnit) analyzeCommand(relativeFilePath string) string {
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--configuration=%s", dp.config.ConfigFile)
+	}
+
+	return fmt.Sprintf("%s %s --log-junit /dev/fd/3 %s 3>&1 >/dev/null 2>&1", dp.config.Path, configArg, relativeFilePath)
+}
+
+func (dp *PhpUnit) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running phpunit: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult PhpUnitOutputResult
+	if err := xml.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, testCase := range phpUnitTestCases(fullAnalysisResult.TestSuites) {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, testCase.File); !found || resolvedPath != filePath {
+			continue
+		}
+
+		for _, problem := range append(append([]PhpUnitProblem{}, testCase.Failures...), testCase.Errors...) {
+			diagnostics = append(diagnostics, dp.problemDiagnostic(projectRoot, filePath, testCase, problem))
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// problemDiagnostic reports the problem on the first frame of its trace in the file, the assertion or the
+// call which failed, else on the test method
+func (dp *PhpUnit) problemDiagnostic(projectRoot string, filePath string, testCase PhpUnitTestCase, problem PhpUnitProblem) protocol.Diagnostic {
+	lines := strings.Split(strings.TrimSpace(problem.Text), "\n")
+	if len(lines) > 0 && strings.Contains(lines[0], "::") {
+		// The first line names the test, e.g. "App\Tests\UserTest::testName"
+		lines = lines[1:]
+	}
+
+	line := testCase.Line
+	frameLine := 0
+	for len(lines) > 0 {
+		matches := phpUnitFrameRegex.FindStringSubmatch(strings.TrimSpace(lines[len(lines)-1]))
+		if matches == nil {
+			break
+		}
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, matches[1]); found && resolvedPath == filePath {
+			frameLine, _ = strconv.Atoi(matches[2])
+		}
+		lines = lines[:len(lines)-1]
+	}
+	if frameLine > 0 {
+		line = frameLine
+	}
+
+	message := strings.TrimSpace(strings.Join(lines, "\n"))
+	if message == "" {
+		message = problem.Type
+	}
+
+	start := uint32(0)
+	if line > 0 {
+		start = uint32(line - 1)
+	}
+
+	return protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: start, Character: 0}, End: protocol.Position{Line: start, Character: 100}},
+		Severity: protocol.DiagnosticSeverityError,
+		Source:   dp.Name(),
+		Message:  fmt.Sprintf("%s: %s", testCase.Name, message),
+	}
+}
+
+// phpUnitTestCases flattens the nested suites
+func phpUnitTestCases(testSuites []PhpUnitTestSuite) []PhpUnitTestCase {
+	var testCases []PhpUnitTestCase
+	for _, testSuite := range testSuites {
+		testCases = append(testCases, testSuite.TestCases...)
+		testCases = append(testCases, phpUnitTestCases(testSuite.TestSuites)...)
+	}
+	return testCases
+}
+
+func NewPhpUnit(providerConfig config.DiagnosticsProvider) *PhpUnit {
+	return &PhpUnit{
+		config:   providerConfig,
+		executor: newExecutor(PhpUnitProviderName, providerConfig),
+	}
+}
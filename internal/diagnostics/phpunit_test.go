@@ -0,0 +1,122 @@
+package diagnostics_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestPhpUnit_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "phpunit", `echo "$@" > arguments
+echo "PHPUnit 10.5.0 by Sebastian Bergmann and contributors."
+cat >&3 <<'XML'
+<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="App\Tests\UserTest" file="/app/tests/UserTest.php" tests="4" failures="1" errors="1">
+    <testcase name="testName" file="/app/tests/UserTest.php" line="12" class="App\Tests\UserTest"/>
+    <testcase name="testEmail" file="/app/tests/UserTest.php" line="18" class="App\Tests\UserTest">
+      <failure type="PHPUnit\Framework\ExpectationFailedException">App\Tests\UserTest::testEmail
+Failed asserting that two strings are identical.
+
+/app/tests/UserTest.php:21
+</failure>
+    </testcase>
+    <testsuite name="App\Tests\UserTest::testAge" tests="2" failures="0" errors="1">
+      <testcase name="testAge with data set #1" file="/app/tests/UserTest.php" line="25" class="App\Tests\UserTest">
+        <error type="InvalidArgumentException">App\Tests\UserTest::testAge with data set #1 (-1)
+InvalidArgumentException: Invalid age
+
+/app/src/User.php:30
+/app/tests/UserTest.php:27
+</error>
+      </testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>
+XML
+exit 1
+`)
+	tool.writeFile(t, "src/User.php", "{}")
+	filePath := tool.writeFile(t, "tests/UserTest.php", "{}")
+
+	providerConfig := tool.providerConfig("vendor/bin/phpunit")
+	providerConfig.ConfigFile = "phpunit.xml.dist"
+	provider := diagnostics.NewPhpUnit(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if !strings.Contains(content, "--configuration=phpunit.xml.dist") || !strings.Contains(content, "tests/UserTest.php") {
+		t.Errorf("Expected the configured test file run, got %q", content)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", result)
+	}
+
+	failure := result[0]
+	if failure.Range.Start.Line != 20 || failure.Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Expected an error on the assertion, got %+v", failure)
+	}
+	if failure.Message != "testEmail: Failed asserting that two strings are identical." {
+		t.Errorf("Unexpected failure message: %q", failure.Message)
+	}
+
+	testError := result[1]
+	if testError.Range.Start.Line != 26 {
+		t.Errorf("Expected the error on the call in the test, got %+v", testError)
+	}
+	if testError.Message != "testAge with data set #1: InvalidArgumentException: Invalid age" {
+		t.Errorf("Unexpected error message: %q", testError.Message)
+	}
+}
+
+func TestPhpUnit_AnalyzeSkipsOtherFiles(t *testing.T) {
+	provider := diagnostics.NewPhpUnit(config.DiagnosticsProvider{
+		Enabled:   true,
+		Container: "php-diagls-missing-container",
+		Path:      "vendor/bin/phpunit",
+		Fallback:  []string{"local"},
+		LocalPath: filepath.Join(t.TempDir(), "missing-phpunit"),
+	})
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filepath.Join(t.TempDir(), "src/User.php"))
+	if err != nil || len(result) != 0 || failed() != nil {
+		t.Errorf("Expected the source file skipped, got %+v, %v, %v", result, err, failed())
+	}
+}
+
+func TestRunsOnSave(t *testing.T) {
+	tests := []struct {
+		providerId string
+		runOn      string
+		expected   bool
+	}{
+		{diagnostics.PhpUnitProviderId, "", true},
+		{diagnostics.PhpUnitProviderId, config.RunOnAuto, false},
+		{diagnostics.InfectionProviderId, "", true},
+		{diagnostics.InfectionProviderId, config.RunOnManual, false},
+		{diagnostics.PhpStanProviderId, "", false},
+		{diagnostics.PhpStanProviderId, config.RunOnSave, true},
+		{diagnostics.PhpStanProviderId, config.RunOnManual, false},
+	}
+
+	for _, tt := range tests {
+		if actual := diagnostics.RunsOnSave(tt.providerId, config.DiagnosticsProvider{RunOn: tt.runOn}); actual != tt.expected {
+			t.Errorf("RunsOnSave(%s, %q) = %v, expected %v", tt.providerId, tt.runOn, actual, tt.expected)
+		}
+	}
+}
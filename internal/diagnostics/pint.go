@@ -0,0 +1,140 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	PintProviderId   string = "pint"
+	PintProviderName string = "pint"
+)
+
+type Pint struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *Pint) Id() string {
+	return PintProviderId
+}
+
+func (dp *Pint) Name() string {
+	return PintProviderName
+}
+
+func (dp *Pint) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(ctx, projectRoot, dp.testCommand(relativeFilePath))
+
+	return dp.parseOutput(ctx, result)
+}
+
+// AnalyzeContent checks the content piped through stdin
+func (dp *Pint) AnalyzeContent(ctx context.Context, filePath string, content string) ([]protocol.Diagnostic, error) {
+	result := dp.executor.Run(ctx, providerRoot(dp.config, filePath), dp.stdinTestCommand(), content)
+
+	return dp.parseOutput(ctx, result)
+}
+
+// testCommand reports the changes Pint would make to the target in the php-cs-fixer JSON format, with their diff
+func (dp *Pint) testCommand(target string) string {
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--config=%s", dp.config.ConfigFile)
+	}
+
+	return fmt.Sprintf("%s --test --format=json %s %s 2>/dev/null", dp.config.Path, configArg, target)
+}
+
+// stdinTestCommand runs testCommand on a copy of stdin, Pint being unable to read it
+func (dp *Pint) stdinTestCommand() string {
+	return stdinCopyCommand(PintProviderId, dp.testCommand)
+}
+
+func (dp *Pint) parseOutput(ctx context.Context, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running pint: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	// Pint reports in the php-cs-fixer JSON format
+	var fullAnalysisResult PhpCsFixerOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	// Pint can't apply a single rule, the changes are reported with all the rules applied to the file
+	for _, file := range fullAnalysisResult.Files {
+		message := PhpCsFixerStyleIssueMessage
+		if len(file.Rules) > 0 {
+			message = fmt.Sprintf("%s (%s)", message, strings.Join(file.Rules, ", "))
+		}
+
+		linesRange := diffRanges(file.Diff)
+		if len(linesRange) == 0 {
+			linesRange = []protocol.Range{{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 100}}}
+		}
+		for _, lineRange := range linesRange {
+			diagnostic := protocol.Diagnostic{
+				Range:    lineRange,
+				Severity: protocol.DiagnosticSeverityWarning,
+				Source:   dp.Name(),
+				Message:  message,
+			}
+			if len(file.Rules) == 1 {
+				diagnostic.Code = file.Rules[0]
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// CanFormat returns true if formatting is enabled for this provider
+func (dp *Pint) CanFormat() bool {
+	return dp.config.Format.Enabled
+}
+
+// Format applies the diff of the changes Pint would make to the content
+func (dp *Pint) Format(ctx context.Context, filePath string, content string) (string, error) {
+	return stdinFormat(ctx, dp.Name(), dp.config, dp.executor, filePath, content, dp.stdinTestCommand(), func(result *container.CommandResult, duration time.Duration) ([]string, error) {
+		var formatResult PhpCsFixerOutputResult
+		if err := unmarshalToolOutput(result, &formatResult); err != nil {
+			log.Printf("%s%s pint returned exit code %d after %v: %s", logging.LogTagLSP, logging.LogTagServer, result.ExitCode, duration, string(result.Stderr))
+			return nil, fmt.Errorf("pint report unusable: %w", err)
+		}
+		log.Printf("%s%s pint completed in %v, %d files to fix", logging.LogTagLSP, logging.LogTagServer, duration, len(formatResult.Files))
+
+		diffs := make([]string, 0, len(formatResult.Files))
+		for _, file := range formatResult.Files {
+			diffs = append(diffs, file.Diff)
+		}
+		return diffs, nil
+	})
+}
+
+func NewPint(providerConfig config.DiagnosticsProvider) *Pint {
+	return &Pint{
+		config:   providerConfig,
+		executor: newExecutor(PintProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,74 @@
+package diagnostics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+// pintScript reports the change of the quotes, recording its arguments and the content of the checked file
+const pintScript = `echo "$@" > arguments
+for last; do :; done
+cp "$last" checked
+cat <<'JSON'
+{"files": [{"name": "src/User.php", "appliedFixers": ["single_quote"], "diff": "--- Original\n+++ New\n@@ -1,3 +1,3 @@\n <?php\n \n-echo \"hello\";\n+echo 'hello';\n"}], "time": {"total": 0.01}, "memory": 14}
+JSON
+exit 1
+`
+
+func TestPint_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "pint", pintScript)
+	filePath := tool.writeFile(t, "src/User.php", "<?php\n\necho \"hello\";\n")
+
+	providerConfig := tool.providerConfig("vendor/bin/pint")
+	providerConfig.ConfigFile = "pint.json"
+	provider := diagnostics.NewPint(providerConfig)
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+
+	content := tool.readFile(t, "arguments")
+	if !strings.Contains(content, "--test --format=json --config=pint.json src/User.php") {
+		t.Errorf("Expected a test run of the file, got %q", content)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", result)
+	}
+	if result[0].Range.Start.Line != 2 || result[0].Severity != protocol.DiagnosticSeverityWarning || result[0].Code != "single_quote" {
+		t.Errorf("Expected a warning on the changed line, got %+v", result[0])
+	}
+	if result[0].Message != "Style issue (single_quote)" {
+		t.Errorf("Unexpected message: %q", result[0].Message)
+	}
+}
+
+func TestPint_Format(t *testing.T) {
+	tool := newFakeTool(t, "pint", pintScript)
+	providerConfig := tool.providerConfig("vendor/bin/pint")
+	providerConfig.Format = config.FormatConfig{Enabled: true}
+	provider := diagnostics.NewPint(providerConfig)
+
+	unsaved := "<?php\n\necho \"hello\";\n"
+	formatted, err := provider.Format(context.Background(), tool.path("src/User.php"), unsaved)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if formatted != "<?php\n\necho 'hello';\n" {
+		t.Errorf("Expected the diff applied, got %q", formatted)
+	}
+
+	if checked := tool.readFile(t, "checked"); checked != unsaved {
+		t.Errorf("Expected the unsaved content checked, got %q", checked)
+	}
+}
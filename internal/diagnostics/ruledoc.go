@@ -0,0 +1,47 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markers around the fixing examples in php-cs-fixer's describe output
+const (
+	describeDiffBegin = "---------- begin diff ----------"
+	describeDiffEnd   = "----------- end diff -----------"
+)
+
+// RuleDocMarkdown renders the explanation of a rule as a Markdown document, the diffs of the fixing
+// examples as diff code blocks
+func RuleDocMarkdown(providerName string, rule string, explanation string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# %s\n\n_%s_\n\n", rule, providerName)
+
+	inDiff := false
+	diffIndent := ""
+	for _, line := range strings.Split(explanation, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inDiff && trimmed == describeDiffBegin:
+			inDiff = true
+			diffIndent = line[:strings.Index(line, describeDiffBegin)]
+			out.WriteString("```diff\n")
+		case inDiff && trimmed == describeDiffEnd:
+			inDiff = false
+			out.WriteString("```\n")
+		case inDiff:
+			if trimmed == "" {
+				// Trailing blank line of the example
+				continue
+			}
+			out.WriteString(strings.TrimPrefix(line, diffIndent) + "\n")
+		default:
+			out.WriteString(line + "\n")
+		}
+	}
+	if inDiff {
+		out.WriteString("```\n")
+	}
+
+	return strings.TrimRight(out.String(), "\n") + "\n"
+}
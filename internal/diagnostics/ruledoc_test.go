@@ -0,0 +1,51 @@
+package diagnostics_test
+
+import (
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func TestRuleDocMarkdown(t *testing.T) {
+	t.Run("php-cs-fixer fixing examples", func(t *testing.T) {
+		explanation := "Description of the `array_syntax` rule.\n\n" +
+			"PHP arrays should be declared using the configured syntax.\n\n" +
+			"Fixing examples:\n\n" +
+			" * Example #1. Fixing with the default configuration.\n\n" +
+			"   ---------- begin diff ----------\n" +
+			"   --- Original\n" +
+			"   +++ New\n" +
+			"   @@ -1,2 +1,2 @@\n" +
+			"    <?php\n" +
+			"   -$a = array(1,2);\n" +
+			"   +$a = [1,2];\n" +
+			"\n" +
+			"   ----------- end diff -----------"
+
+		expected := "# array_syntax\n\n_php-cs-fixer_\n\n" +
+			"Description of the `array_syntax` rule.\n\n" +
+			"PHP arrays should be declared using the configured syntax.\n\n" +
+			"Fixing examples:\n\n" +
+			" * Example #1. Fixing with the default configuration.\n\n" +
+			"```diff\n" +
+			"--- Original\n" +
+			"+++ New\n" +
+			"@@ -1,2 +1,2 @@\n" +
+			" <?php\n" +
+			"-$a = array(1,2);\n" +
+			"+$a = [1,2];\n" +
+			"```\n"
+
+		if markdown := diagnostics.RuleDocMarkdown("php-cs-fixer", "array_syntax", explanation); markdown != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, markdown)
+		}
+	})
+
+	t.Run("plain explanation", func(t *testing.T) {
+		markdown := diagnostics.RuleDocMarkdown("phpstan", "variable.undefined", "PHPStan error identifier variable.undefined\n")
+		expected := "# variable.undefined\n\n_phpstan_\n\nPHPStan error identifier variable.undefined\n"
+		if markdown != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, markdown)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package diagnostics
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// SarifOutputResult is a SARIF 2.1 report, the format of psalm, semgrep or phpstan with an extension
+type SarifOutputResult struct {
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+// SarifRun holds the results of a tool run and the rules they refer to
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []SarifRule `json:"rules"`
+}
+
+// SarifRule is the metadata of a rule, helpUri linking to its documentation
+type SarifRule struct {
+	Id                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	HelpUri              string             `json:"helpUri"`
+	DefaultConfiguration SarifConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SarifResult is a reported issue, ruleIndex pointing to the rules of the driver
+type SarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	RuleIndex *int            `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   SarifMessage    `json:"message"`
+	Locations []SarifLocation `json:"locations"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           SarifRegion           `json:"region"`
+}
+
+type SarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+// SarifRegion is the reported range, lines and columns being 1-based and the end column exclusive
+type SarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifDiagnostics converts the results located in the file, the reported paths being resolved from the project root
+func sarifDiagnostics(report SarifOutputResult, projectRoot string, filePath string, source string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+
+	filePath = filepath.Clean(filePath)
+	for _, run := range report.Runs {
+		for _, result := range run.Results {
+			for _, location := range result.Locations {
+				reportedPath := sarifPath(location.PhysicalLocation.ArtifactLocation.Uri)
+				if resolvedPath, found := utils.ResolveToolPath(projectRoot, reportedPath); !found || resolvedPath != filePath {
+					continue
+				}
+
+				diagnostics = append(diagnostics, sarifDiagnostic(result, sarifResultRule(run.Tool.Driver, result), location.PhysicalLocation.Region, source))
+				break
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+func sarifDiagnostic(result SarifResult, rule *SarifRule, region SarifRegion, source string) protocol.Diagnostic {
+	startLine := uint32(0)
+	if region.StartLine > 0 {
+		startLine = uint32(region.StartLine - 1)
+	}
+	startColumn := uint32(0)
+	if region.StartColumn > 0 {
+		startColumn = uint32(region.StartColumn - 1)
+	}
+	endLine := startLine
+	if region.EndLine > region.StartLine {
+		endLine = uint32(region.EndLine - 1)
+	}
+	endColumn := uint32(100)
+	if region.EndColumn > 0 {
+		endColumn = uint32(region.EndColumn - 1)
+	}
+
+	level := result.Level
+	if level == "" && rule != nil {
+		level = rule.DefaultConfiguration.Level
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startColumn},
+			End:   protocol.Position{Line: endLine, Character: endColumn},
+		},
+		Severity: sarifSeverity(level),
+		Source:   source,
+		Message:  strings.TrimSpace(result.Message.Text),
+	}
+	ruleId := result.RuleId
+	if ruleId == "" && rule != nil {
+		ruleId = rule.Id
+	}
+	if ruleId != "" {
+		diagnostic.Code = ruleId
+	}
+	if rule != nil && rule.HelpUri != "" {
+		diagnostic.CodeDescription = &protocol.CodeDescription{Href: protocol.URI(rule.HelpUri)}
+	}
+	return diagnostic
+}
+
+// sarifResultRule returns the metadata of the rule of the result, by index when given else by id
+func sarifResultRule(driver SarifDriver, result SarifResult) *SarifRule {
+	if result.RuleIndex != nil && *result.RuleIndex >= 0 && *result.RuleIndex < len(driver.Rules) {
+		return &driver.Rules[*result.RuleIndex]
+	}
+	for i := range driver.Rules {
+		if driver.Rules[i].Id == result.RuleId {
+			return &driver.Rules[i]
+		}
+	}
+	return nil
+}
+
+// sarifPath returns the path of an artifact uri, either a file uri or a path relative to the project root
+func sarifPath(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil && parsed.Scheme == "file" {
+		return parsed.Path
+	}
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		return unescaped
+	}
+	return uri
+}
+
+// sarifSeverity maps the SARIF level, warning being the default level of the format
+func sarifSeverity(level string) protocol.DiagnosticSeverity {
+	switch strings.ToLower(level) {
+	case "error":
+		return protocol.DiagnosticSeverityError
+	case "note":
+		return protocol.DiagnosticSeverityInformation
+	case "none":
+		return protocol.DiagnosticSeverityHint
+	default:
+		return protocol.DiagnosticSeverityWarning
+	}
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultConfigFiles are the configuration files the tools load from the project without configFile
+var DefaultConfigFiles = map[string]string{
+	PhpStanProviderId:    "phpstan.neon",
+	PhpCsFixerProviderId: ".php-cs-fixer.dist.php",
+}
+
+// Directories listed in the scaffolded phpstan configuration when they exist, the project otherwise
+var phpStanScaffoldPaths = []string{"src", "tests"}
+
+const phpStanConfigScaffold = `# Generated by php-diagls, adjust the level and paths to the project
+parameters:
+    level: 5
+    paths:
+%s
+`
+
+const phpCsFixerConfigScaffold = `<?php
+
+// Generated by php-diagls, adjust the rules to the project coding standard
+$finder = (new PhpCsFixer\Finder())
+    ->in(__DIR__)
+    ->exclude(['var', 'vendor']);
+
+return (new PhpCsFixer\Config())
+    ->setRules([
+        '@PSR12' => true,
+    ])
+    ->setFinder($finder);
+`
+
+// ConfigScaffold returns a default configuration file of the provider's tool for the project, false when
+// the provider has none
+func ConfigScaffold(providerId string, projectRoot string) (string, bool) {
+	switch providerId {
+	case PhpStanProviderId:
+		var paths []string
+		for _, dir := range phpStanScaffoldPaths {
+			if info, err := os.Stat(filepath.Join(projectRoot, dir)); err == nil && info.IsDir() {
+				paths = append(paths, fmt.Sprintf("        - %s", dir))
+			}
+		}
+		if len(paths) == 0 {
+			paths = []string{"        - ."}
+		}
+		return fmt.Sprintf(phpStanConfigScaffold, strings.Join(paths, "\n")), true
+	case PhpCsFixerProviderId:
+		return phpCsFixerConfigScaffold, true
+	default:
+		return "", false
+	}
+}
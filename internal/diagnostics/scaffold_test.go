@@ -0,0 +1,47 @@
+package diagnostics_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+)
+
+func TestConfigScaffold(t *testing.T) {
+	t.Run("phpstan lists the existing source directories", func(t *testing.T) {
+		projectRoot := t.TempDir()
+		if err := os.Mkdir(filepath.Join(projectRoot, "src"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		scaffold, ok := diagnostics.ConfigScaffold(diagnostics.PhpStanProviderId, projectRoot)
+		if !ok {
+			t.Fatal("Expected a phpstan scaffold")
+		}
+		if !strings.Contains(scaffold, "        - src\n") || strings.Contains(scaffold, "tests") {
+			t.Errorf("Expected only src in the paths, got:\n%s", scaffold)
+		}
+	})
+
+	t.Run("phpstan analyzes the project without source directories", func(t *testing.T) {
+		scaffold, _ := diagnostics.ConfigScaffold(diagnostics.PhpStanProviderId, t.TempDir())
+		if !strings.Contains(scaffold, "        - .\n") {
+			t.Errorf("Expected the project in the paths, got:\n%s", scaffold)
+		}
+	})
+
+	t.Run("php-cs-fixer", func(t *testing.T) {
+		scaffold, ok := diagnostics.ConfigScaffold(diagnostics.PhpCsFixerProviderId, t.TempDir())
+		if !ok || !strings.HasPrefix(scaffold, "<?php") || !strings.Contains(scaffold, "PhpCsFixer\\Config") {
+			t.Errorf("Expected a php-cs-fixer config, got:\n%s", scaffold)
+		}
+	})
+
+	t.Run("no scaffold", func(t *testing.T) {
+		if _, ok := diagnostics.ConfigScaffold(diagnostics.PhpLintProviderId, t.TempDir()); ok {
+			t.Error("Expected no scaffold for phplint")
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	SymfonyYamlProviderId   string = "symfonyyaml"
+	SymfonyYamlProviderName string = "symfony-lint-yaml"
+)
+
+// SymfonyYamlFileResult is the result of a linted file, the line being 1-based, or -1 when unknown
+type SymfonyYamlFileResult struct {
+	File    string `json:"file"`
+	Valid   bool   `json:"valid"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// SymfonyYamlOutputResult is the JSON report of bin/console lint:yaml
+type SymfonyYamlOutputResult []SymfonyYamlFileResult
+
+type SymfonyYaml struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *SymfonyYaml) Id() string {
+	return SymfonyYamlProviderId
+}
+
+func (dp *SymfonyYaml) Name() string {
+	return SymfonyYamlProviderName
+}
+
+// Analyze lints the YAML file with the Symfony console of the project, custom tags (!tagged_iterator) being
+// parsed as the container does
+func (dp *SymfonyYaml) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s lint:yaml %s --format=json --parse-tags --no-interaction 2>/dev/null", dp.config.Path, relativeFilePath),
+	)
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+func (dp *SymfonyYaml) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running lint:yaml: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	var fullAnalysisResult SymfonyYamlOutputResult
+	if err := unmarshalToolOutput(result, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return diagnostics, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	filePath = filepath.Clean(filePath)
+	for _, file := range fullAnalysisResult {
+		if file.Valid {
+			continue
+		}
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, file.File); !found || resolvedPath != filePath {
+			continue
+		}
+
+		diagnostics = append(diagnostics, dp.fileDiagnostic(file))
+	}
+
+	return diagnostics, nil
+}
+
+// fileDiagnostic reports the parse error of the file. Deprecated syntaxes are reported as parse errors
+// too, they are warnings tagged as deprecated.
+func (dp *SymfonyYaml) fileDiagnostic(file SymfonyYamlFileResult) protocol.Diagnostic {
+	line := uint32(0)
+	if file.Line > 0 {
+		line = uint32(file.Line - 1)
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 0}, End: protocol.Position{Line: line, Character: 100}},
+		Severity: protocol.DiagnosticSeverityError,
+		Source:   dp.Name(),
+		Message:  strings.TrimSpace(file.Message),
+	}
+	if strings.Contains(strings.ToLower(file.Message), "deprecated") {
+		diagnostic.Severity = protocol.DiagnosticSeverityWarning
+		diagnostic.Tags = []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated}
+	}
+	return diagnostic
+}
+
+func NewSymfonyYaml(providerConfig config.DiagnosticsProvider) *SymfonyYaml {
+	return &SymfonyYaml{
+		config:   providerConfig,
+		executor: newExecutor(SymfonyYamlProviderName, providerConfig),
+	}
+}
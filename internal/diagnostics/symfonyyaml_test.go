@@ -0,0 +1,72 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestSymfonyYaml_Analyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []protocol.Diagnostic
+	}{
+		{
+			name:     "valid file",
+			output:   `[{"file": "/app/config/services.yaml", "valid": true}]`,
+			expected: []protocol.Diagnostic{},
+		},
+		{
+			name:   "parse error",
+			output: `[{"file": "/app/config/services.yaml", "line": 4, "valid": false, "message": "Unable to parse at line 4 (near \"  foo: bar\")."}]`,
+			expected: []protocol.Diagnostic{{
+				Range:    protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 100}},
+				Severity: protocol.DiagnosticSeverityError,
+				Message:  `Unable to parse at line 4 (near "  foo: bar").`,
+			}},
+		},
+		{
+			name:   "deprecation",
+			output: `[{"file": "/app/config/services.yaml", "line": 2, "valid": false, "message": "Support for the \"!str\" tag is deprecated."}, {"file": "/app/config/routes.yaml", "line": 1, "valid": false, "message": "Unable to parse."}]`,
+			expected: []protocol.Diagnostic{{
+				Range:    protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 1, Character: 100}},
+				Severity: protocol.DiagnosticSeverityWarning,
+				Message:  `Support for the "!str" tag is deprecated.`,
+				Tags:     []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := newFakeTool(t, "console", outputScript(tt.output, 1))
+			filePath := tool.writeFile(t, "config/services.yaml", "{}")
+			tool.writeFile(t, "config/routes.yaml", "{}")
+			provider := diagnostics.NewSymfonyYaml(tool.providerConfig("bin/console"))
+
+			ctx, failed := diagnostics.TrackFailures(context.Background())
+			result, err := provider.Analyze(ctx, filePath)
+			if err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+			if failure := failed(); failure != nil {
+				t.Errorf("Expected no failure, got %v", failure)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %+v", len(tt.expected), result)
+			}
+			for i, expected := range tt.expected {
+				got := result[i]
+				if got.Range != expected.Range || got.Severity != expected.Severity || got.Message != expected.Message || len(got.Tags) != len(expected.Tags) {
+					t.Errorf("Expected %+v, got %+v", expected, got)
+				}
+				if got.Source != diagnostics.SymfonyYamlProviderName {
+					t.Errorf("Unexpected source %s", got.Source)
+				}
+			}
+		})
+	}
+}
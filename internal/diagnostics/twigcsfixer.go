@@ -0,0 +1,76 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	TwigCsFixerProviderId   string = "twigcsfixer"
+	TwigCsFixerProviderName string = "twig-cs-fixer"
+
+	TwigTemplatePattern string = "*.twig"
+)
+
+type TwigCsFixer struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *TwigCsFixer) Id() string {
+	return TwigCsFixerProviderId
+}
+
+func (dp *TwigCsFixer) Name() string {
+	return TwigCsFixerProviderName
+}
+
+func (dp *TwigCsFixer) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	configArg := ""
+	if dp.config.ConfigFile != "" {
+		configArg = fmt.Sprintf("--config=%s", dp.config.ConfigFile)
+	}
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s lint --report=checkstyle --no-cache %s %s 2>/dev/null", dp.config.Path, configArg, relativeFilePath),
+	)
+
+	return dp.parseOutput(ctx, projectRoot, filePath, result)
+}
+
+func (dp *TwigCsFixer) parseOutput(ctx context.Context, projectRoot string, filePath string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	if result.Err != nil {
+		log.Printf("Error running twig-cs-fixer: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return []protocol.Diagnostic{}, nil
+	}
+
+	var fullAnalysisResult CheckstyleOutputResult
+	if err := xml.Unmarshal(result.Stdout, &fullAnalysisResult); err != nil {
+		log.Printf("Unmarshall err: %s", err)
+		markFailed(ctx, outputFailure(result, err))
+		return []protocol.Diagnostic{}, nil
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	return checkstyleDiagnostics(fullAnalysisResult, projectRoot, filePath, dp.Name()), nil
+}
+
+func NewTwigCsFixer(providerConfig config.DiagnosticsProvider) *TwigCsFixer {
+	return &TwigCsFixer{
+		config:   providerConfig,
+		executor: newExecutor(TwigCsFixerProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,53 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestTwigCsFixer_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "twig-cs-fixer", `cat <<'XML'
+<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle>
+  <file name="/app/templates/base.html.twig">
+    <error line="3" column="5" severity="error" message="Expecting 1 whitespace after &quot;{{&quot;; found 0." source="DelimiterSpacing.After"/>
+    <error line="7" severity="notice" message="A line should not end with blank space."/>
+  </file>
+  <file name="/app/templates/other.html.twig">
+    <error line="1" column="1" severity="error" message="Unexpected token." source="Fatal"/>
+  </file>
+</checkstyle>
+XML
+exit 1
+`)
+	filePath := tool.writeFile(t, "templates/base.html.twig", "{}")
+	tool.writeFile(t, "templates/other.html.twig", "{}")
+
+	provider := diagnostics.NewTwigCsFixer(tool.providerConfig("vendor/bin/twig-cs-fixer"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected the 2 violations of base.html.twig, got %+v", result)
+	}
+
+	spacing, blank := result[0], result[1]
+	if spacing.Range.Start.Line != 2 || spacing.Range.Start.Character != 4 || spacing.Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Unexpected spacing violation: %+v", spacing)
+	}
+	if spacing.Code != "DelimiterSpacing.After" || spacing.Message != `Expecting 1 whitespace after "{{"; found 0.` {
+		t.Errorf("Expected the rule as code, got %+v", spacing)
+	}
+	if blank.Range.Start.Line != 6 || blank.Severity != protocol.DiagnosticSeverityInformation || blank.Code != nil {
+		t.Errorf("Unexpected notice: %+v", blank)
+	}
+}
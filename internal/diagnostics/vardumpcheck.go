@@ -0,0 +1,142 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	VarDumpCheckProviderId   string = "vardumpcheck"
+	VarDumpCheckProviderName string = "var-dump-check"
+)
+
+// A leftover debug call, e.g. "Forgotten dump 'var_dump' found in ./src/User.php:36"
+var varDumpCheckFoundRegex = regexp.MustCompile(`^Forgotten dump '(.+)' found in (.+):(\d+)$`)
+
+// VarDumpCheckDump is a leftover debug call, the line being 1-based
+type VarDumpCheckDump struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// VarDumpCheckOutputResult is the report of var-dump-check, which has no JSON format, and the debug calls
+// read from it
+type VarDumpCheckOutputResult struct {
+	Output string             `json:"output"`
+	Dumps  []VarDumpCheckDump `json:"dumps"`
+}
+
+type VarDumpCheck struct {
+	config   config.DiagnosticsProvider
+	executor *container.Executor
+}
+
+func (dp *VarDumpCheck) Id() string {
+	return VarDumpCheckProviderId
+}
+
+func (dp *VarDumpCheck) Name() string {
+	return VarDumpCheckProviderName
+}
+
+// Analyze reports the debug calls left in the file: var_dump, var_export and print_r, and the dump and dd
+// helpers of Symfony and Laravel
+func (dp *VarDumpCheck) Analyze(ctx context.Context, filePath string) ([]protocol.Diagnostic, error) {
+	content, err := readDocument(ctx, filePath)
+	if err != nil {
+		return []protocol.Diagnostic{}, err
+	}
+
+	projectRoot := providerRoot(dp.config, filePath)
+	relativeFilePath, _ := filepath.Rel(projectRoot, filePath)
+
+	result := dp.executor.Run(
+		ctx,
+		projectRoot,
+		fmt.Sprintf("%s --symfony --laravel --no-colors %s 2>/dev/null", dp.config.Path, relativeFilePath),
+	)
+
+	return dp.parseOutput(ctx, projectRoot, filePath, string(content), result)
+}
+
+func (dp *VarDumpCheck) parseOutput(ctx context.Context, projectRoot string, filePath string, content string, result *container.CommandResult) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+
+	if result.Err != nil {
+		log.Printf("Error running var-dump-check: %v", result.Err)
+		markFailed(ctx, result.Failure())
+		return diagnostics, nil
+	}
+
+	fullAnalysisResult := VarDumpCheckOutputResult{Output: string(result.Stdout), Dumps: []VarDumpCheckDump{}}
+	if !strings.Contains(fullAnalysisResult.Output, "Checked ") {
+		// No summary, the check didn't run, e.g. the file is missing in the container
+		markFailed(ctx, outputFailure(result, fmt.Errorf("unexpected output: %s", strings.TrimSpace(fullAnalysisResult.Output))))
+		return diagnostics, nil
+	}
+
+	for _, line := range strings.Split(fullAnalysisResult.Output, "\n") {
+		matches := varDumpCheckFoundRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			continue
+		}
+		dumpLine, _ := strconv.Atoi(matches[3])
+		fullAnalysisResult.Dumps = append(fullAnalysisResult.Dumps, VarDumpCheckDump{Function: matches[1], File: matches[2], Line: dumpLine})
+	}
+	recordRawResult(ctx, fullAnalysisResult)
+
+	lines := strings.Split(content, "\n")
+	filePath = filepath.Clean(filePath)
+	for _, dump := range fullAnalysisResult.Dumps {
+		if resolvedPath, found := utils.ResolveToolPath(projectRoot, dump.File); !found || resolvedPath != filePath {
+			continue
+		}
+
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    dumpRange(lines, dump),
+			Severity: protocol.DiagnosticSeverityWarning,
+			Source:   dp.Name(),
+			Code:     dump.Function,
+			Message:  fmt.Sprintf("Forgotten debug call: %s", dump.Function),
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// dumpRange locates the call on the reported line, the whole line when not found
+func dumpRange(lines []string, dump VarDumpCheckDump) protocol.Range {
+	line := 0
+	if dump.Line > 0 {
+		line = dump.Line - 1
+	}
+
+	dumpRange := protocol.Range{Start: protocol.Position{Line: uint32(line), Character: 0}, End: protocol.Position{Line: uint32(line), Character: 100}}
+	if line < len(lines) {
+		// Static calls are reported by their method, e.g. VarDumper::dump
+		call := regexp.MustCompile(`(^|[^\w$>])(?P<at>` + regexp.QuoteMeta(dump.Function) + `)\s*\(`)
+		if callRange, found := declarationRange(lines[line:line+1], call); found {
+			dumpRange.Start.Character = callRange.Start.Character
+			dumpRange.End.Character = callRange.End.Character
+		}
+	}
+	return dumpRange
+}
+
+func NewVarDumpCheck(providerConfig config.DiagnosticsProvider) *VarDumpCheck {
+	return &VarDumpCheck{
+		config:   providerConfig,
+		executor: newExecutor(VarDumpCheckProviderName, providerConfig),
+	}
+}
@@ -0,0 +1,70 @@
+package diagnostics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+func TestVarDumpCheck_Analyze(t *testing.T) {
+	tool := newFakeTool(t, "var-dump-check", `cat <<'TEXT'
+PHP Var Dump Check 0.5
+Checked 1 files in 0.1 second, dump found in 1 file
+
+------------------------------------------------------------
+Forgotten dump 'dd' found in ./src/Cart.php:5
+    3| function total(array $items): int
+    4| {
+  > 5|     dd($items);
+------------------------------------------------------------
+Forgotten dump 'print_r' found in ./src/Cart.php:6
+  > 6|     $this->dump($items); print_r($items);
+TEXT
+exit 1
+`)
+	filePath := tool.writeFile(t, "src/Cart.php", "<?php\n\nfunction total(array $items): int\n{\n    dd($items);\n    $this->dump($items); print_r($items);\n}\n")
+
+	provider := diagnostics.NewVarDumpCheck(tool.providerConfig("vendor/bin/var-dump-check"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if failure := failed(); failure != nil {
+		t.Errorf("Expected no failure, got %v", failure)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", result)
+	}
+
+	expected := []protocol.Range{
+		{Start: protocol.Position{Line: 4, Character: 4}, End: protocol.Position{Line: 4, Character: 6}},
+		{Start: protocol.Position{Line: 5, Character: 25}, End: protocol.Position{Line: 5, Character: 32}},
+	}
+	for i, dump := range result {
+		if dump.Range != expected[i] || dump.Severity != protocol.DiagnosticSeverityWarning {
+			t.Errorf("Expected a warning at %+v, got %+v", expected[i], dump)
+		}
+	}
+	if result[1].Message != "Forgotten debug call: print_r" || result[1].Code != "print_r" {
+		t.Errorf("Unexpected diagnostic: %+v", result[1])
+	}
+}
+
+func TestVarDumpCheck_AnalyzeUnexpectedOutput(t *testing.T) {
+	tool := newFakeTool(t, "var-dump-check", "echo 'Path Cart.php not found'\nexit 254\n")
+	filePath := tool.writeFile(t, "Cart.php", "{}")
+	provider := diagnostics.NewVarDumpCheck(tool.providerConfig("vendor/bin/var-dump-check"))
+
+	ctx, failed := diagnostics.TrackFailures(context.Background())
+	result, err := provider.Analyze(ctx, filePath)
+	if err != nil || len(result) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v, %v", result, err)
+	}
+	if failed() == nil {
+		t.Error("Expected the run reported as failed")
+	}
+}
@@ -0,0 +1,159 @@
+package formatting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+// Id of the formatting provider running the configured chain
+const ChainProviderId = "chain"
+
+// Time a command step may run when it has no timeout
+const defaultCommandStepTimeout = 30 * time.Second
+
+type chainStep struct {
+	provider FormattingProvider
+	timeout  time.Duration
+}
+
+// Chain runs its formatting providers in order, each one formatting the output of the previous one
+type Chain struct {
+	steps []chainStep
+}
+
+// NewChain creates the formatting chain of the configuration. Provider steps use the provider settings
+// with formatting enabled, command steps run in their container or the project one.
+func NewChain(serverConfig *config.Config) (*Chain, error) {
+	chain := &Chain{}
+	for i, step := range serverConfig.FormatChain {
+		timeout := time.Duration(step.TimeoutSeconds) * time.Second
+
+		var provider FormattingProvider
+		if step.Command != "" {
+			containerName := step.Container
+			if containerName == "" {
+				containerName = serverConfig.Container
+			}
+			name := step.Name
+			if name == "" {
+				name = fmt.Sprintf("step %d", i+1)
+			}
+			provider = &commandFormatter{name: name, command: step.Command, projectRoot: serverConfig.ProjectRoot, executor: container.NewExecutor(containerName)}
+			if timeout == 0 {
+				timeout = defaultCommandStepTimeout
+			}
+		} else {
+			providerConfig, exists := serverConfig.DiagnosticsProviders[step.Provider]
+			if !exists || !providerConfig.Enabled {
+				return nil, fmt.Errorf("%s step %d: provider %s is not enabled", config.ConfigItemFormatChain, i+1, step.Provider)
+			}
+			providerConfig.Format.Enabled = true
+			providerConfig.Format.TimeoutSeconds = step.TimeoutSeconds
+
+			var err error
+			if provider, err = NewFormattingProvider(step.Provider, providerConfig); err != nil {
+				return nil, fmt.Errorf("%s step %d: %w", config.ConfigItemFormatChain, i+1, err)
+			}
+		}
+
+		chain.steps = append(chain.steps, chainStep{provider: provider, timeout: timeout})
+	}
+
+	return chain, nil
+}
+
+func (c *Chain) Id() string {
+	return ChainProviderId
+}
+
+// Name lists the steps, e.g. "rector > php-cs-fixer"
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.steps))
+	for _, step := range c.steps {
+		names = append(names, step.provider.Name())
+	}
+	return strings.Join(names, " > ")
+}
+
+// Format runs the steps in order, stopping at the first failing one
+func (c *Chain) Format(ctx context.Context, filePath string, content string) (string, error) {
+	for _, step := range c.steps {
+		formatted, err := c.formatStep(ctx, step, filePath, content)
+		if err != nil {
+			return content, fmt.Errorf("%s failed: %w", step.provider.Name(), err)
+		}
+		content = formatted
+	}
+	return content, nil
+}
+
+func (c *Chain) formatStep(ctx context.Context, step chainStep, filePath string, content string) (string, error) {
+	if step.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	formatted, err := step.provider.Format(ctx, filePath, content)
+	log.Printf("%s%s Format step %s done in %v", logging.LogTagLSP, logging.LogTagServer, step.provider.Name(), time.Since(start).Round(time.Millisecond))
+	return formatted, err
+}
+
+// FormatRules applies the rules with the first step able to, the other steps don't run
+func (c *Chain) FormatRules(ctx context.Context, filePath string, content string, rules []string) (string, error) {
+	for _, step := range c.steps {
+		if formatter, ok := step.provider.(RuleFormatter); ok {
+			return formatter.FormatRules(ctx, filePath, content, rules)
+		}
+	}
+	return content, fmt.Errorf("no step of %s can apply a single rule", c.Name())
+}
+
+// commandFormatter pipes the content through a command run in a container
+type commandFormatter struct {
+	name    string
+	command string
+	// Root of the project the configuration was loaded for, the closest one of the file when empty
+	projectRoot string
+	executor    *container.Executor
+}
+
+func (f *commandFormatter) Id() string {
+	return f.name
+}
+
+func (f *commandFormatter) Name() string {
+	return f.name
+}
+
+func (f *commandFormatter) Format(ctx context.Context, filePath string, content string) (string, error) {
+	projectRoot := f.projectRoot
+	if projectRoot == "" {
+		projectRoot = utils.FindProjectRoot(filePath)
+	}
+
+	result := f.executor.Run(ctx, projectRoot, f.command, content)
+	if result.Err != nil {
+		return content, result.Err
+	}
+	if result.ExitCode != 0 {
+		return content, fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(string(result.Stderr)))
+	}
+	if result.Truncated {
+		return content, fmt.Errorf("output exceeds the output cap")
+	}
+	// An empty output is a broken command rather than an empty file
+	if len(result.Stdout) == 0 && content != "" {
+		return content, fmt.Errorf("no output")
+	}
+	return string(result.Stdout), nil
+}
@@ -0,0 +1,42 @@
+package formatting_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/formatting"
+)
+
+func TestNewChain(t *testing.T) {
+	serverConfig := &config.Config{
+		Container: "php",
+		DiagnosticsProviders: map[string]config.DiagnosticsProvider{
+			diagnostics.PhpCsFixerProviderId: {Enabled: true, Container: "php", Path: "vendor/bin/php-cs-fixer"},
+		},
+		FormatChain: []config.FormatStep{
+			{Name: "rector", Command: "vendor/bin/rector-stdin", TimeoutSeconds: 60},
+			{Provider: diagnostics.PhpCsFixerProviderId, TimeoutSeconds: 20},
+		},
+	}
+
+	chain, err := formatting.NewChain(serverConfig)
+	if err != nil {
+		t.Fatalf("NewChain failed: %v", err)
+	}
+	if chain.Id() != formatting.ChainProviderId {
+		t.Errorf("Expected id %s, got %s", formatting.ChainProviderId, chain.Id())
+	}
+	if name := chain.Name(); name != "rector > php-cs-fixer" {
+		t.Errorf("Expected the steps in order, got %q", name)
+	}
+	var _ formatting.RuleFormatter = chain
+
+	t.Run("disabled provider", func(t *testing.T) {
+		serverConfig := &config.Config{FormatChain: []config.FormatStep{{Provider: diagnostics.PhpCsFixerProviderId}}}
+		if _, err := formatting.NewChain(serverConfig); err == nil || !strings.Contains(err.Error(), "not enabled") {
+			t.Errorf("Expected a disabled provider error, got %v", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package formatting
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
@@ -23,16 +24,39 @@ func NewFormattingProvider(providerId string, providerConfig config.DiagnosticsP
 			return formatter, nil
 		}
 		return nil, fmt.Errorf("provider %s does not implement FormattingProvider interface", providerId)
+	case diagnostics.PintProviderId:
+		return diagnostics.NewPint(providerConfig), nil
+	case diagnostics.EcsProviderId:
+		return diagnostics.NewEcs(providerConfig), nil
+	case diagnostics.PhpCbfProviderId:
+		return diagnostics.NewPhpCbf(providerConfig), nil
+	case diagnostics.ComposerNormalizeProviderId:
+		return diagnostics.NewComposerNormalize(providerConfig), nil
 	default:
 		return nil, fmt.Errorf("formatting not supported for provider: %s", providerId)
 	}
 }
 
-// LoadFormattingProviders creates formatting providers from diagnostics providers configuration
+// LoadFormattingProviders creates the PHP formatting providers from diagnostics providers configuration,
+// by decreasing format.priority then by id, the documents (composer.json) having their own, see
+// NewDocumentFormattingProvider
 func LoadFormattingProviders(diagnosticsProviders map[string]config.DiagnosticsProvider) []FormattingProvider {
 	var providers []FormattingProvider
 
-	for id, providerConfig := range diagnosticsProviders {
+	ids := make([]string, 0, len(diagnosticsProviders))
+	for id := range diagnosticsProviders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		priorityI, priorityJ := diagnosticsProviders[ids[i]].Format.Priority, diagnosticsProviders[ids[j]].Format.Priority
+		if priorityI != priorityJ {
+			return priorityI > priorityJ
+		}
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		providerConfig := diagnosticsProviders[id]
 		// Skip if provider is not enabled
 		if !providerConfig.Enabled {
 			continue
@@ -43,6 +67,11 @@ func LoadFormattingProviders(diagnosticsProviders map[string]config.DiagnosticsP
 			continue
 		}
 
+		// Skip the formatters of documents other than PHP files
+		if diagnostics.IsDocumentProvider(id) {
+			continue
+		}
+
 		provider, err := NewFormattingProvider(id, providerConfig)
 		if err != nil {
 			// Log error but continue with other providers
@@ -54,3 +83,19 @@ func LoadFormattingProviders(diagnosticsProviders map[string]config.DiagnosticsP
 
 	return providers
 }
+
+// NewDocumentFormattingProvider creates the formatting provider of a document other than a PHP file, e.g.
+// composer-normalize for composer.json, from the first enabled provider handling it with formatting enabled
+func NewDocumentFormattingProvider(diagnosticsProviders map[string]config.DiagnosticsProvider, filePath string) (FormattingProvider, bool) {
+	for id, providerConfig := range diagnosticsProviders {
+		if !providerConfig.Enabled || !providerConfig.Format.Enabled || !diagnostics.HandlesDocument(id, providerConfig, filePath) {
+			continue
+		}
+
+		if provider, err := NewFormattingProvider(id, providerConfig); err == nil {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
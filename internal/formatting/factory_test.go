@@ -300,6 +300,29 @@ func TestLoadFormattingProviders(t *testing.T) {
 			expectedProviderCount: 0,
 			expectedProviderIds:   []string{},
 		},
+		{
+			name: "document formatter left out",
+			diagnosticsProviders: map[string]config.DiagnosticsProvider{
+				diagnostics.PhpCsFixerProviderId: {
+					Enabled:   true,
+					Container: "php-container",
+					Path:      "/usr/local/bin/php-cs-fixer",
+					Format: config.FormatConfig{
+						Enabled: true,
+					},
+				},
+				diagnostics.ComposerNormalizeProviderId: {
+					Enabled:   true,
+					Container: "php-container",
+					Path:      "composer",
+					Format: config.FormatConfig{
+						Enabled: true,
+					},
+				},
+			},
+			expectedProviderCount: 1,
+			expectedProviderIds:   []string{diagnostics.PhpCsFixerProviderId},
+		},
 	}
 
 	for _, tt := range tests {
@@ -331,6 +354,75 @@ func TestLoadFormattingProviders(t *testing.T) {
 	}
 }
 
+func TestNewDocumentFormattingProvider(t *testing.T) {
+	diagnosticsProviders := map[string]config.DiagnosticsProvider{
+		diagnostics.PhpCsFixerProviderId: {
+			Enabled:   true,
+			Container: "php-container",
+			Path:      "/usr/local/bin/php-cs-fixer",
+			Format:    config.FormatConfig{Enabled: true},
+		},
+		diagnostics.ComposerNormalizeProviderId: {
+			Enabled:   true,
+			Container: "php-container",
+			Path:      "composer",
+			Format:    config.FormatConfig{Enabled: true},
+		},
+	}
+
+	provider, ok := formatting.NewDocumentFormattingProvider(diagnosticsProviders, "/app/composer.json")
+	if !ok || provider.Id() != diagnostics.ComposerNormalizeProviderId {
+		t.Errorf("Expected composer-normalize for composer.json, got %v", provider)
+	}
+
+	if provider, ok := formatting.NewDocumentFormattingProvider(diagnosticsProviders, "/app/src/User.php"); ok {
+		t.Errorf("Expected no document formatter for a PHP file, got %s", provider.Id())
+	}
+
+	normalize := diagnosticsProviders[diagnostics.ComposerNormalizeProviderId]
+	normalize.Format.Enabled = false
+	diagnosticsProviders[diagnostics.ComposerNormalizeProviderId] = normalize
+	if provider, ok := formatting.NewDocumentFormattingProvider(diagnosticsProviders, "/app/composer.json"); ok {
+		t.Errorf("Expected no formatter with formatting disabled, got %s", provider.Id())
+	}
+}
+
+func TestLoadFormattingProviders_SortedById(t *testing.T) {
+	diagnosticsProviders := map[string]config.DiagnosticsProvider{}
+	for _, id := range []string{diagnostics.PintProviderId, diagnostics.PhpCsFixerProviderId, diagnostics.EcsProviderId} {
+		diagnosticsProviders[id] = config.DiagnosticsProvider{
+			Enabled:   true,
+			Container: "php-container",
+			Path:      "vendor/bin/" + id,
+			Format:    config.FormatConfig{Enabled: true},
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		providers := formatting.LoadFormattingProviders(diagnosticsProviders)
+		if len(providers) != 3 || providers[0].Id() != diagnostics.EcsProviderId || providers[1].Id() != diagnostics.PhpCsFixerProviderId || providers[2].Id() != diagnostics.PintProviderId {
+			t.Fatalf("Expected the providers sorted by id, got %v", providers)
+		}
+	}
+}
+
+func TestLoadFormattingProviders_SortedByPriority(t *testing.T) {
+	diagnosticsProviders := map[string]config.DiagnosticsProvider{}
+	for id, priority := range map[string]int{diagnostics.PintProviderId: 10, diagnostics.PhpCsFixerProviderId: 0, diagnostics.EcsProviderId: -1} {
+		diagnosticsProviders[id] = config.DiagnosticsProvider{
+			Enabled:   true,
+			Container: "php-container",
+			Path:      "vendor/bin/" + id,
+			Format:    config.FormatConfig{Enabled: true, Priority: priority},
+		}
+	}
+
+	providers := formatting.LoadFormattingProviders(diagnosticsProviders)
+	if len(providers) != 3 || providers[0].Id() != diagnostics.PintProviderId || providers[1].Id() != diagnostics.PhpCsFixerProviderId || providers[2].Id() != diagnostics.EcsProviderId {
+		t.Fatalf("Expected the providers sorted by priority, got %v", providers)
+	}
+}
+
 func TestLoadFormattingProviders_ProvidersAreUsable(t *testing.T) {
 	diagnosticsProviders := map[string]config.DiagnosticsProvider{
 		diagnostics.PhpCsFixerProviderId: {
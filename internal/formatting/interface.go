@@ -13,3 +13,9 @@ type FormattingProvider interface {
 	// Format applies formatting to the given file content and returns the formatted content
 	Format(ctx context.Context, filePath string, content string) (string, error)
 }
+
+// RuleFormatter is implemented by formatting providers able to apply a subset of their rules
+type RuleFormatter interface {
+	// FormatRules applies the given rules only to the file content and returns the formatted content
+	FormatRules(ctx context.Context, filePath string, content string, rules []string) (string, error)
+}
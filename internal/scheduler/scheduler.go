@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority of an analysis job. Higher tiers run first and preempt running jobs of lower tiers.
+type Priority int
+
+const (
+	PriorityBackground Priority = iota
+	PriorityWatcher
+	PriorityChange
+	PrioritySave
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackground:
+		return "background"
+	case PriorityWatcher:
+		return "watcher"
+	case PriorityChange:
+		return "change"
+	case PrioritySave:
+		return "save"
+	default:
+		return "unknown"
+	}
+}
+
+// Job is the unit of work run by the scheduler. It must stop as soon as ctx is cancelled;
+// a preempted job is started again from scratch later.
+type Job func(ctx context.Context)
+
+type task struct {
+	seq      uint64
+	priority Priority
+	parent   context.Context
+	job      Job
+	done     chan struct{}
+
+	cancel    context.CancelFunc
+	preempted bool
+}
+
+// Scheduler runs jobs on a fixed number of slots, always picking the highest priority tier first
+// (FIFO within a tier). When all slots are busy, a new job preempts the lowest priority running
+// job of a strictly lower tier, which is put back in the queue.
+type Scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	seq     uint64
+	queue   []*task
+	running map[*task]struct{}
+}
+
+func New(slots int) *Scheduler {
+	if slots < 1 {
+		slots = 1
+	}
+
+	return &Scheduler{
+		slots:   slots,
+		running: make(map[*task]struct{}),
+	}
+}
+
+// Submit queues the job and returns a channel closed once the job has run to completion or
+// the parent context was cancelled
+func (s *Scheduler) Submit(parent context.Context, priority Priority, job Job) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	t := &task{
+		seq:      s.seq,
+		priority: priority,
+		parent:   parent,
+		job:      job,
+		done:     make(chan struct{}),
+	}
+	s.queue = append(s.queue, t)
+
+	if s.activeCount() >= s.slots {
+		s.preemptLowerThan(priority)
+	}
+	s.dispatch()
+
+	return t.done
+}
+
+// Pending returns the number of queued and running jobs
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue) + len(s.running)
+}
+
+// preemptLowerThan cancels the lowest priority running job below the given tier. Must be called with mu held.
+func (s *Scheduler) preemptLowerThan(priority Priority) {
+	var victim *task
+	for t := range s.running {
+		if t.priority >= priority || t.preempted {
+			continue
+		}
+		if victim == nil || t.priority < victim.priority || (t.priority == victim.priority && t.seq > victim.seq) {
+			victim = t
+		}
+	}
+
+	if victim != nil {
+		victim.preempted = true
+		victim.cancel()
+	}
+}
+
+// dispatch starts queued jobs while slots are available. Must be called with mu held.
+func (s *Scheduler) dispatch() {
+	for s.activeCount() < s.slots && len(s.queue) > 0 {
+		next := s.popNext()
+		if next.parent.Err() != nil {
+			close(next.done)
+			continue
+		}
+		s.start(next)
+	}
+}
+
+// activeCount ignores preempted jobs that are still winding down. Must be called with mu held.
+func (s *Scheduler) activeCount() int {
+	count := 0
+	for t := range s.running {
+		if !t.preempted {
+			count++
+		}
+	}
+	return count
+}
+
+// popNext removes the highest priority, oldest queued task. Must be called with mu held.
+func (s *Scheduler) popNext() *task {
+	best := 0
+	for i, t := range s.queue {
+		if t.priority > s.queue[best].priority || (t.priority == s.queue[best].priority && t.seq < s.queue[best].seq) {
+			best = i
+		}
+	}
+
+	next := s.queue[best]
+	s.queue = append(s.queue[:best], s.queue[best+1:]...)
+	return next
+}
+
+// start runs the task in its own goroutine. Must be called with mu held.
+func (s *Scheduler) start(t *task) {
+	ctx, cancel := context.WithCancel(t.parent)
+	t.cancel = cancel
+	t.preempted = false
+	s.running[t] = struct{}{}
+
+	go func() {
+		t.job(ctx)
+		cancel()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.running, t)
+		if t.preempted && t.parent.Err() == nil {
+			// Run it again once higher priority work is done
+			s.queue = append(s.queue, t)
+		} else {
+			close(t.done)
+		}
+		s.dispatch()
+	}()
+}
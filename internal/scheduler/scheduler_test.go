@@ -0,0 +1,178 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+)
+
+func waitDone(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Job did not complete in time")
+	}
+}
+
+func TestScheduler_PriorityOrder(t *testing.T) {
+	s := scheduler.New(1)
+
+	release := make(chan struct{})
+	blocker := s.Submit(context.Background(), scheduler.PrioritySave, func(ctx context.Context) {
+		<-release
+	})
+
+	var mu sync.Mutex
+	var order []scheduler.Priority
+	record := func(priority scheduler.Priority) scheduler.Job {
+		return func(ctx context.Context) {
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}
+	}
+
+	background := s.Submit(context.Background(), scheduler.PriorityBackground, record(scheduler.PriorityBackground))
+	watcher := s.Submit(context.Background(), scheduler.PriorityWatcher, record(scheduler.PriorityWatcher))
+	change := s.Submit(context.Background(), scheduler.PriorityChange, record(scheduler.PriorityChange))
+	save := s.Submit(context.Background(), scheduler.PrioritySave, record(scheduler.PrioritySave))
+
+	close(release)
+	for _, done := range []<-chan struct{}{blocker, background, watcher, change, save} {
+		waitDone(t, done)
+	}
+
+	expected := []scheduler.Priority{
+		scheduler.PrioritySave,
+		scheduler.PriorityChange,
+		scheduler.PriorityWatcher,
+		scheduler.PriorityBackground,
+	}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %d jobs to run, got %d", len(expected), len(order))
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Position %d: expected %s, got %s", i, expected[i], order[i])
+		}
+	}
+}
+
+func TestScheduler_PreemptsLowerTiers(t *testing.T) {
+	s := scheduler.New(1)
+
+	var mu sync.Mutex
+	backgroundRuns := 0
+	backgroundCancelled := 0
+	started := make(chan struct{}, 2)
+
+	background := s.Submit(context.Background(), scheduler.PriorityBackground, func(ctx context.Context) {
+		mu.Lock()
+		backgroundRuns++
+		firstRun := backgroundRuns == 1
+		mu.Unlock()
+
+		started <- struct{}{}
+		if !firstRun {
+			return
+		}
+
+		<-ctx.Done()
+		mu.Lock()
+		backgroundCancelled++
+		mu.Unlock()
+	})
+
+	<-started
+
+	saveRan := false
+	save := s.Submit(context.Background(), scheduler.PrioritySave, func(ctx context.Context) {
+		saveRan = true
+	})
+
+	waitDone(t, save)
+	waitDone(t, background)
+
+	if !saveRan {
+		t.Error("Expected save job to run")
+	}
+	if backgroundCancelled != 1 {
+		t.Errorf("Expected background job to be preempted once, got %d", backgroundCancelled)
+	}
+	if backgroundRuns != 2 {
+		t.Errorf("Expected preempted background job to run again, got %d runs", backgroundRuns)
+	}
+}
+
+func TestScheduler_SameTierIsNotPreempted(t *testing.T) {
+	s := scheduler.New(1)
+
+	release := make(chan struct{})
+	cancelled := false
+	first := s.Submit(context.Background(), scheduler.PriorityChange, func(ctx context.Context) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			cancelled = true
+		}
+	})
+
+	second := s.Submit(context.Background(), scheduler.PriorityChange, func(ctx context.Context) {})
+
+	if s.Pending() != 2 {
+		t.Errorf("Expected 2 pending jobs, got %d", s.Pending())
+	}
+
+	close(release)
+	waitDone(t, first)
+	waitDone(t, second)
+
+	if cancelled {
+		t.Error("Jobs of the same tier should not preempt each other")
+	}
+}
+
+func TestScheduler_CancelledParent(t *testing.T) {
+	s := scheduler.New(1)
+
+	release := make(chan struct{})
+	blocker := s.Submit(context.Background(), scheduler.PrioritySave, func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+	queued := s.Submit(ctx, scheduler.PriorityBackground, func(ctx context.Context) {
+		ran = true
+	})
+	cancel()
+
+	close(release)
+	waitDone(t, blocker)
+	waitDone(t, queued)
+
+	if ran {
+		t.Error("Job with cancelled parent context should not run")
+	}
+}
+
+func TestPriority_String(t *testing.T) {
+	tests := map[scheduler.Priority]string{
+		scheduler.PriorityBackground: "background",
+		scheduler.PriorityWatcher:    "watcher",
+		scheduler.PriorityChange:     "change",
+		scheduler.PrioritySave:       "save",
+		scheduler.Priority(42):       "unknown",
+	}
+
+	for priority, expected := range tests {
+		if priority.String() != expected {
+			t.Errorf("Expected %s, got %s", expected, priority.String())
+		}
+	}
+}
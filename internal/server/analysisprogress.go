@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// Analyses of an open document reported as work done progress once they run longer than this, fast runs
+// don't flash a spinner
+const analysisProgressDelay = time.Second
+
+// analysisProgress reports the providers still analyzing a document. The progress is only created once
+// the analysis runs longer than analysisProgressDelay.
+type analysisProgress struct {
+	s     *Server
+	title string
+
+	mu       sync.Mutex
+	running  []string
+	total    int
+	timer    *time.Timer
+	token    *protocol.ProgressToken
+	finished bool
+}
+
+// startAnalysisProgress tracks the analysis of the open document by the providers, nil when the client has
+// no work done progress support or the file isn't open (workspace scans have their own progress)
+func (s *Server) startAnalysisProgress(filePath string, providers []diagnostics.DiagnosticsProvider) *analysisProgress {
+	if !s.workDoneProgressSupported {
+		return nil
+	}
+	if _, open := s.getDocumentContent(utils.PathToURI(filePath)); !open {
+		return nil
+	}
+
+	progress := &analysisProgress{
+		s:     s,
+		title: fmt.Sprintf("Analyzing %s", filepath.Base(filePath)),
+		total: len(providers),
+	}
+	for _, provider := range providers {
+		progress.running = append(progress.running, provider.Name())
+	}
+	progress.timer = time.AfterFunc(analysisProgressDelay, progress.begin)
+
+	return progress
+}
+
+func (progress *analysisProgress) begin() {
+	token := protocol.NewProgressToken(newProgressTokenName())
+
+	ctx, cancel := context.WithTimeout(context.Background(), progressCreateTimeout)
+	_, err := progress.s.conn.Call(ctx, protocol.MethodWorkDoneProgressCreate, &protocol.WorkDoneProgressCreateParams{Token: *token}, nil)
+	cancel()
+	if err != nil {
+		log.Printf("%s%s Failed to create progress for %q: %v", logging.LogTagLSP, logging.LogTagServer, progress.title, err)
+		return
+	}
+
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	progress.token = token
+	message, percentage := progress.state()
+	progress.s.notifyProgress(*token, protocol.WorkDoneProgressBegin{
+		Kind:       protocol.WorkDoneProgressKindBegin,
+		Title:      progress.title,
+		Message:    message,
+		Percentage: percentage,
+	})
+	if progress.finished {
+		progress.end()
+	}
+}
+
+// providerDone reports the end of the provider run
+func (progress *analysisProgress) providerDone(providerName string) {
+	if progress == nil {
+		return
+	}
+
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	for i, name := range progress.running {
+		if name == providerName {
+			progress.running = append(progress.running[:i], progress.running[i+1:]...)
+			break
+		}
+	}
+	if progress.token == nil || progress.finished {
+		return
+	}
+
+	message, percentage := progress.state()
+	progress.s.notifyProgress(*progress.token, protocol.WorkDoneProgressReport{
+		Kind:       protocol.WorkDoneProgressKindReport,
+		Message:    message,
+		Percentage: percentage,
+	})
+}
+
+// finish ends the progress, if it was created
+func (progress *analysisProgress) finish() {
+	if progress == nil {
+		return
+	}
+	progress.timer.Stop()
+
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	progress.finished = true
+	if progress.token != nil {
+		progress.end()
+	}
+}
+
+// end sends the end notification. Must be called with mu held.
+func (progress *analysisProgress) end() {
+	progress.s.notifyProgress(*progress.token, protocol.WorkDoneProgressEnd{Kind: protocol.WorkDoneProgressKindEnd})
+}
+
+// state returns the providers still running and the share of the providers done. Must be called with mu held.
+func (progress *analysisProgress) state() (string, uint32) {
+	done := progress.total - len(progress.running)
+	return strings.Join(progress.running, ", "), uint32(done * 100 / progress.total)
+}
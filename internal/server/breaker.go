@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// providerBreaker is the circuit breaker of a provider in a project root, name being the display name of
+// the provider
+type providerBreaker struct {
+	root    string
+	name    string
+	breaker *utils.CircuitBreaker
+}
+
+// breakerFor returns the circuit breaker of the provider, nil when the provider has none configured. Breakers
+// are kept per provider id, providers can share their display name.
+func (s *Server) breakerFor(root string, id string, name string, providerConfig config.DiagnosticsProvider) *utils.CircuitBreaker {
+	if providerConfig.CircuitBreaker.Failures == 0 {
+		return nil
+	}
+
+	s.breakersMu.Lock()
+	defer s.breakersMu.Unlock()
+
+	key := root + "/" + id
+	if b, exists := s.breakers[key]; exists {
+		return b.breaker
+	}
+
+	breaker := utils.NewCircuitBreaker(providerConfig.CircuitBreaker.Failures, providerConfig.CircuitBreaker.Cooldown())
+	s.breakers[key] = &providerBreaker{root: root, name: name, breaker: breaker}
+	return breaker
+}
+
+// recordProviderOutcome feeds the circuit breaker of the provider, telling the user when it gets suspended
+func (s *Server) recordProviderOutcome(ctx context.Context, breaker *utils.CircuitBreaker, name string, providerConfig config.DiagnosticsProvider, failed bool) {
+	if !breaker.Record(time.Now(), failed) {
+		return
+	}
+
+	cooldown := providerConfig.CircuitBreaker.Cooldown()
+	log.Printf("%s%s %s suspended after %d consecutive failures, retrying in %v", logging.LogTagLSP, logging.LogTagServer, name, providerConfig.CircuitBreaker.Failures, cooldown)
+	s.showWindowMessage(ctx, protocol.MessageTypeWarning, fmt.Sprintf("%s suspended after %d consecutive failures, retrying in %v (see logs for details)", name, providerConfig.CircuitBreaker.Failures, cooldown))
+}
+
+// suspendedProviders returns the end of the suspension of the suspended providers, per project root and provider name
+func (s *Server) suspendedProviders(now time.Time) map[string]map[string]time.Time {
+	s.breakersMu.Lock()
+	defer s.breakersMu.Unlock()
+
+	suspended := make(map[string]map[string]time.Time)
+	for _, b := range s.breakers {
+		if until := b.breaker.OpenUntil(); now.Before(until) {
+			if suspended[b.root] == nil {
+				suspended[b.root] = make(map[string]time.Time)
+			}
+			suspended[b.root][b.name] = until
+		}
+	}
+	return suspended
+}
+
+func (s *Server) resetBreakers() {
+	s.breakersMu.Lock()
+	defer s.breakersMu.Unlock()
+
+	s.breakers = make(map[string]*providerBreaker)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// dispatchCancellable dispatches the request with a context cancelled by its $/cancelRequest, until the
+// request is replied. The reply itself is never cancelled: the client still gets a response, the
+// RequestCancelled error when the handler gave up.
+func (s *Server) dispatchCancellable(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	call, ok := req.(*jsonrpc2.Call)
+	if !ok {
+		return s.dispatch(ctx, reply, req)
+	}
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	s.requestsMu.Lock()
+	s.requestCancels[call.ID()] = cancel
+	s.requestsMu.Unlock()
+
+	replyCtx := context.WithoutCancel(ctx)
+	cancellableReply := func(_ context.Context, result interface{}, err error) error {
+		s.requestsMu.Lock()
+		delete(s.requestCancels, call.ID())
+		s.requestsMu.Unlock()
+
+		cancelled := requestCtx.Err() != nil
+		cancel()
+		if cancelled && err != nil {
+			err = protocol.ErrRequestCancelled
+		}
+		return reply(replyCtx, result, err)
+	}
+
+	return s.dispatch(requestCtx, cancellableReply, req)
+}
+
+// handleCancelRequest cancels the context of the request still being handled, stopping the tools it runs
+func (s *Server) handleCancelRequest(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params struct {
+		ID jsonrpc2.ID `json:"id"`
+	}
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling cancel request params: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		return err
+	}
+
+	s.requestsMu.Lock()
+	cancel, handling := s.requestCancels[params.ID]
+	s.requestsMu.Unlock()
+	if !handling {
+		log.Printf("%s%s Cancellation of request %v ignored, already replied", logging.LogTagLSP, logging.LogTagServer, params.ID)
+		return reply(ctx, nil, nil)
+	}
+
+	log.Printf("%s%s Client requested cancellation for request ID: %v", logging.LogTagLSP, logging.LogTagServer, params.ID)
+	cancel()
+	return reply(ctx, nil, nil)
+}
+
+// supersedeAnalysis starts a new analysis generation of the file and cancels the analysis of the previous
+// one, killing its tools instead of letting them run on stale content. The returned context is cancelled
+// by the next generation. Must be called with diagMu held.
+func (s *Server) supersedeAnalysis(parent context.Context, uri protocol.DocumentURI) (uint64, context.Context) {
+	if cancel, exists := s.diagCancels[uri]; exists {
+		cancel()
+	}
+
+	s.diagGen[uri]++
+	ctx, cancel := context.WithCancel(parent)
+	s.diagCancels[uri] = cancel
+	return s.diagGen[uri], ctx
+}
+
+// releaseAnalysis releases the context of the finished analysis generation, unless a newer one took over
+func (s *Server) releaseAnalysis(uri protocol.DocumentURI, gen uint64) {
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+
+	if s.diagGen[uri] != gen {
+		return
+	}
+	if cancel, exists := s.diagCancels[uri]; exists {
+		cancel()
+		delete(s.diagCancels, uri)
+	}
+}
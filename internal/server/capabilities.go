@@ -11,6 +11,16 @@ const (
 	LspCommandPrefix         = config.Name
 	LspCommandSeparator      = "/"
 	LspCommandNameShowConfig = "showConfig"
+
+	LspCommandNameAnalyzeWorkspace = "analyzeWorkspace"
+	LspCommandNameReloadConfig     = "reloadConfig"
+	LspCommandNameResolvedConfig   = "resolvedConfig"
+	LspCommandNameAnalyzeFile      = "analyzeFile"
+	LspCommandNameFixAll           = "fixAll"
+	LspCommandNameScaffoldConfig   = "scaffoldConfig"
+	LspCommandNameOrganizeImports  = "organizeImports"
+	LspCommandNameFormatWorkspace  = "formatWorkspace"
+	LspCommandNameFormatWith       = "formatWith"
 )
 
 func serverCapabilities() protocol.ServerCapabilities {
@@ -19,16 +29,59 @@ func serverCapabilities() protocol.ServerCapabilities {
 			Change:    protocol.TextDocumentSyncKindFull,
 			OpenClose: true,
 			Save:      &protocol.SaveOptions{IncludeText: false},
+			// Formatting edits returned when formatOnSave is enabled
+			WillSaveWaitUntil: true,
 		},
 		ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
 			Commands: []string{
 				getFullLspCommandName(LspCommandNameShowConfig),
+				getFullLspCommandName(LspCommandNameAnalyzeWorkspace),
+				getFullLspCommandName(LspCommandNameReloadConfig),
+				getFullLspCommandName(LspCommandNameResolvedConfig),
+				getFullLspCommandName(LspCommandNameAnalyzeFile),
+				getFullLspCommandName(LspCommandNameFixAll),
+				getFullLspCommandName(LspCommandNameScaffoldConfig),
+				getFullLspCommandName(LspCommandNameOrganizeImports),
+				getFullLspCommandName(LspCommandNameFormatWorkspace),
+				getFullLspCommandName(LspCommandNameFormatWith),
 			},
 		},
-		DocumentFormattingProvider: true,
+		DocumentFormattingProvider:      true,
+		DocumentRangeFormattingProvider: true,
+		CodeLensProvider:                &protocol.CodeLensOptions{},
+		HoverProvider:                   true,
+		CodeActionProvider: &protocol.CodeActionOptions{
+			CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix, codeActionKindSourceFixAll, protocol.SourceOrganizeImports},
+		},
 	}
 }
 
+// initializeResult adds the LSP 3.17 capabilities the protocol package predates
+type initializeResult struct {
+	Capabilities serverCapabilities317 `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo  `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities317 struct {
+	protocol.ServerCapabilities
+	DiagnosticProvider *diagnosticOptions `json:"diagnosticProvider,omitempty"`
+	InlayHintProvider  bool               `json:"inlayHintProvider,omitempty"`
+}
+
+// initializeResult advertises the diagnostic provider to the clients pulling the diagnostics, the others
+// get them published
+func (s *Server) initializeResult() initializeResult {
+	capabilities := serverCapabilities317{
+		ServerCapabilities: serverCapabilities(),
+		InlayHintProvider:  true,
+	}
+	if s.pullDiagnosticsSupported {
+		capabilities.DiagnosticProvider = &diagnosticOptions{Identifier: string(config.Name), InterFileDependencies: true}
+	}
+
+	return initializeResult{Capabilities: capabilities, ServerInfo: serverInfo()}
+}
+
 func serverInfo() *protocol.ServerInfo {
 	return &protocol.ServerInfo{
 		Name:    string(config.Name),
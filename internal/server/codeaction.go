@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// handleCodeAction offers the timeout quick fixes, the php-cs-fixer rule fixes, the fixes of the repeated rules,
+// the disabling of the php-cs-fixer rules, the phpstan inline ignores, the fix-all and the organize imports
+// source actions
+func (s *Server) handleCodeAction(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.CodeActionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return err
+	}
+
+	actions := timeoutCodeActions(params)
+	actions = append(actions, s.ruleDiffCodeActions(params)...)
+	actions = append(actions, s.ruleFixCodeActions(params)...)
+	actions = append(actions, s.disableRuleCodeActions(params)...)
+	actions = append(actions, s.phpStanSuppressCodeActions(params)...)
+	if fixAll := s.fixAllCodeAction(params.TextDocument.URI); fixAll != nil {
+		actions = append(actions, *fixAll)
+	}
+	if organizeImports := s.organizeImportsCodeAction(params.TextDocument.URI); organizeImports != nil {
+		actions = append(actions, *organizeImports)
+	}
+
+	return reply(ctx, actions, nil)
+}
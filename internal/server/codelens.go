@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// Rules listed in the issue count lens of a provider, the most reported first
+const codeLensMaxRules = 3
+
+// handleCodeLens shows at the top of the document the issues published per provider, with their most
+// reported rules, and offers to run the manual providers and to fix the php-cs-fixer issues
+func (s *Server) handleCodeLens(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.CodeLensParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return err
+	}
+
+	uri := params.TextDocument.URI
+	p := s.projectFor(uri.Filename())
+	if p == nil || !s.isSupportedDocument(uri) || utils.IsIgnoredPath(uri.Filename()) {
+		return reply(ctx, []protocol.CodeLens{}, nil)
+	}
+
+	lenses := s.issueCountCodeLenses(uri)
+	if action := s.fixAllCodeAction(uri); action != nil {
+		lenses = append(lenses, protocol.CodeLens{
+			Range: protocol.Range{},
+			Command: &protocol.Command{
+				Title:     fmt.Sprintf("Fix with %s", diagnostics.PhpCsFixerProviderName),
+				Command:   action.Command.Command,
+				Arguments: action.Command.Arguments,
+			},
+		})
+	}
+	if lens := s.manualCodeLens(p, uri); lens != nil {
+		lenses = append(lenses, *lens)
+	}
+
+	return reply(ctx, lenses, nil)
+}
+
+// issueCountCodeLenses counts the published issues per provider, e.g. "phpstan: 3 issues (argument.type 2,
+// return.missing 1)". The lenses only inform, their command is empty.
+func (s *Server) issueCountCodeLenses(uri protocol.DocumentURI) []protocol.CodeLens {
+	published, _ := s.getPublished(uri.Filename())
+
+	issues := make(map[string]int)
+	ruleIssues := make(map[string]map[string]int)
+	for _, diagnostic := range published {
+		issues[diagnostic.Source]++
+		if rule, ok := diagnostic.Code.(string); ok && rule != "" {
+			if ruleIssues[diagnostic.Source] == nil {
+				ruleIssues[diagnostic.Source] = make(map[string]int)
+			}
+			ruleIssues[diagnostic.Source][rule]++
+		}
+	}
+
+	sources := make([]string, 0, len(issues))
+	for source := range issues {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	lenses := []protocol.CodeLens{}
+	for _, source := range sources {
+		title := fmt.Sprintf("%s: %d issue", source, issues[source])
+		if issues[source] > 1 {
+			title += "s"
+		}
+		if rules := ruleCounts(ruleIssues[source]); rules != "" {
+			title += fmt.Sprintf(" (%s)", rules)
+		}
+
+		lenses = append(lenses, protocol.CodeLens{
+			Range:   protocol.Range{},
+			Command: &protocol.Command{Title: title},
+		})
+	}
+	return lenses
+}
+
+// ruleCounts lists the most reported rules with their count, e.g. "ordered_imports 3, braces 2, +4 more"
+func ruleCounts(counts map[string]int) string {
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if counts[rules[i]] != counts[rules[j]] {
+			return counts[rules[i]] > counts[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+
+	listed := make([]string, 0, codeLensMaxRules+1)
+	for i, rule := range rules {
+		if i == codeLensMaxRules {
+			listed = append(listed, fmt.Sprintf("+%d more", len(rules)-codeLensMaxRules))
+			break
+		}
+		listed = append(listed, fmt.Sprintf("%s %d", rule, counts[rule]))
+	}
+	return strings.Join(listed, ", ")
+}
+
+// refreshCodeLenses asks the client to request the code lenses again once the diagnostics of an open
+// document changed, their counts being outdated
+func (s *Server) refreshCodeLenses(uri protocol.DocumentURI) {
+	if !s.codeLensRefreshSupported {
+		return
+	}
+	if _, open := s.getDocumentContent(uri); !open {
+		return
+	}
+
+	go func() {
+		if _, err := s.conn.Call(context.Background(), protocol.MethodCodeLensRefresh, nil, nil); err != nil {
+			log.Printf("%s%s Failed to refresh the code lenses: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		}
+	}()
+}
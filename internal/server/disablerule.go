@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// disableRuleCodeActions offers to set the rules of the php-cs-fixer diagnostics to false in the php-cs-fixer
+// configuration of the project, as an edit of the configuration file the user reviews and saves
+func (s *Server) disableRuleCodeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	p := s.projectFor(params.TextDocument.URI.Filename())
+	if p == nil {
+		return actions
+	}
+	providerConfig, enabled := p.getPhpCsFixerProviderConfig()
+	if !enabled {
+		return actions
+	}
+
+	configPath, ok := phpCsFixerConfigPath(p.root, providerConfig.ConfigFile)
+	if !ok {
+		return actions
+	}
+	configURI := utils.PathToURI(configPath)
+	configContent, err := s.documentContent(configURI)
+	if err != nil {
+		return actions
+	}
+
+	offered := make(map[string]bool)
+	for _, diagnostic := range params.Context.Diagnostics {
+		rule, ok := diagnostic.Code.(string)
+		if !ok || diagnostic.Source != diagnostics.PhpCsFixerProviderName || offered[rule] {
+			continue
+		}
+		offered[rule] = true
+
+		disabledContent, err := diagnostics.DisablePhpCsFixerRule(configContent, rule)
+		if err != nil {
+			continue
+		}
+
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Disable %s in %s", rule, filepath.Base(configPath)),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{configURI: utils.LineEdits(configContent, disabledContent)},
+			},
+		})
+	}
+
+	return actions
+}
+
+// phpCsFixerConfigPath returns the php-cs-fixer configuration file of the project: its configFile, or the
+// first configuration file php-cs-fixer loads by default
+func phpCsFixerConfigPath(projectRoot string, configFile string) (string, bool) {
+	candidates := []string{}
+	if configFile != "" {
+		if hostPath, inside := utils.HostToolPath(projectRoot, configFile); inside {
+			candidates = append(candidates, hostPath)
+		}
+	} else {
+		for _, fileName := range diagnostics.PhpCsFixerConfigFiles {
+			candidates = append(candidates, filepath.Join(projectRoot, fileName))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
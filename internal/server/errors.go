@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cristianradulescu/php-diagls/internal/container"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/protocol"
+)
+
+// showProviderError logs every provider failure but shows each (provider, error class) once per session:
+// a broken container fails every analysis the same way
+func (s *Server) showProviderError(ctx context.Context, provider string, message string, err error) {
+	log.Printf("%s%s %s: %v", logging.LogTagLSP, logging.LogTagServer, message, err)
+
+	key := provider + "/" + container.ErrorClass(err)
+
+	s.reportedErrorsMu.Lock()
+	reported := s.reportedErrors[key]
+	s.reportedErrors[key] = true
+	s.reportedErrorsMu.Unlock()
+
+	if reported {
+		return
+	}
+
+	s.showWindowMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("%s: %v (see logs for details)", message, err))
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"go.lsp.dev/protocol"
+)
+
+// Exposes the project routing to the tests
+
+// ProjectRoot returns the root of the project analyzing the file, empty without project
+func (s *Server) ProjectRoot(filePath string) string {
+	if p := s.projectFor(filePath); p != nil {
+		return p.root
+	}
+	return ""
+}
+
+// ProjectConfig returns the configuration of the project analyzing the file
+func (s *Server) ProjectConfig(filePath string) *config.Config {
+	if p := s.projectFor(filePath); p != nil {
+		return p.serverConfig
+	}
+	return nil
+}
+
+// WarmedUp reports whether the warm-up results of the document are ready
+func (s *Server) WarmedUp(uri protocol.DocumentURI) bool {
+	s.warmMu.Lock()
+	defer s.warmMu.Unlock()
+	_, exists := s.warmResults[uri]
+	return exists
+}
+
+// ManualDiagnostics returns the stored results of the manual providers on the document
+func (s *Server) ManualDiagnostics(uri protocol.DocumentURI) []protocol.Diagnostic {
+	return s.manualDiagnostics(uri, func(string) bool { return false })
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// Id of the registration of the file watchers, replaced when the configuration is reloaded
+const fileWatchersRegistrationId = config.Name + "/watchedFiles"
+
+// registerFileWatchers asks the client to watch the analyzed files, the configuration files and the
+// configuration files of the providers, instead of depending on what each editor watches by default. The
+// previous registration is replaced, the provider configuration files may have changed.
+func (s *Server) registerFileWatchers() {
+	if !s.watchedFilesDynamicRegistration || s.bufferOnly {
+		return
+	}
+
+	watchers := []protocol.FileSystemWatcher{}
+	for _, glob := range s.watchedFileGlobs() {
+		watchers = append(watchers, protocol.FileSystemWatcher{GlobPattern: glob})
+	}
+
+	go func() {
+		s.fileWatchersMu.Lock()
+		defer s.fileWatchersMu.Unlock()
+
+		if s.fileWatchersRegistered {
+			unregistration := protocol.UnregistrationParams{Unregisterations: []protocol.Unregistration{
+				{ID: fileWatchersRegistrationId, Method: protocol.MethodWorkspaceDidChangeWatchedFiles},
+			}}
+			if _, err := s.conn.Call(context.Background(), protocol.MethodClientUnregisterCapability, &unregistration, nil); err != nil {
+				log.Printf("%s%s Failed to unregister the file watchers: %v", logging.LogTagLSP, logging.LogTagServer, err)
+			}
+			s.fileWatchersRegistered = false
+		}
+
+		registration := protocol.RegistrationParams{Registrations: []protocol.Registration{{
+			ID:              fileWatchersRegistrationId,
+			Method:          protocol.MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}}}
+		if _, err := s.conn.Call(context.Background(), protocol.MethodClientRegisterCapability, &registration, nil); err != nil {
+			log.Printf("%s%s Failed to register the file watchers: %v", logging.LogTagLSP, logging.LogTagServer, err)
+			return
+		}
+		s.fileWatchersRegistered = true
+	}()
+}
+
+// watchedFileGlobs returns the glob patterns of the file extensions of the projects, the configuration file
+// names and the configuration files of the providers
+func (s *Server) watchedFileGlobs() []string {
+	globs := make(map[string]bool)
+	if absolute, ok := s.configFiles.Absolute(); ok {
+		globs[filepath.ToSlash(absolute)] = true
+	} else {
+		for _, name := range s.configFiles.Names() {
+			globs["**/"+filepath.ToSlash(name)] = true
+		}
+	}
+
+	for _, p := range s.allProjects() {
+		extensions := p.serverConfig.FileExtensions
+		if len(extensions) == 0 {
+			extensions = config.DefaultFileExtensions
+		}
+		for _, extension := range extensions {
+			globs["**/*"+extension] = true
+		}
+
+		for _, configPath := range providerConfigFiles(p) {
+			if relativePath, err := filepath.Rel(p.root, configPath); err == nil && !strings.HasPrefix(relativePath, "..") {
+				globs["**/"+filepath.ToSlash(relativePath)] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(globs))
+	for glob := range globs {
+		sorted = append(sorted, glob)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// providerConfigFiles returns the configuration files of the enabled providers of the project, the files
+// php-cs-fixer loads by default when it has no configFile
+func providerConfigFiles(p *project) []string {
+	var configFiles []string
+	for id, providerConfig := range p.serverConfig.DiagnosticsProviders {
+		if !providerConfig.Enabled {
+			continue
+		}
+
+		switch {
+		case providerConfig.ConfigFile != "":
+			if hostPath, inside := utils.HostToolPath(p.root, providerConfig.ConfigFile); inside {
+				configFiles = append(configFiles, hostPath)
+			}
+		case id == diagnostics.PhpCsFixerProviderId:
+			for _, fileName := range diagnostics.PhpCsFixerConfigFiles {
+				configFiles = append(configFiles, filepath.Join(p.root, fileName))
+			}
+		}
+	}
+	return configFiles
+}
+
+// reanalyzeProviderConfigChange analyzes again the open documents of the project whose provider configuration
+// file changed, false when the file isn't one
+func (s *Server) reanalyzeProviderConfigChange(filePath string) bool {
+	filePath = filepath.Clean(filePath)
+	for _, p := range s.allProjects() {
+		for _, configPath := range providerConfigFiles(p) {
+			if configPath != filePath {
+				continue
+			}
+
+			log.Printf("%s%s Provider configuration %s changed, analyzing the open documents of %s again", logging.LogTagLSP, logging.LogTagServer, filePath, p.root)
+			for _, uri := range s.openDocuments() {
+				if s.projectFor(uri.Filename()) == p && s.isSupportedDocument(uri) {
+					s.scheduleDiagnostics(uri, scheduler.PriorityWatcher)
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
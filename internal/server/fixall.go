@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/formatting"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// The protocol package has no constant for this kind, added in LSP 3.15
+	codeActionKindSourceFixAll protocol.CodeActionKind = "source.fixAll"
+
+	// Annotations of the fix-all edits, fixes which may change the behavior of the code need a confirmation
+	fixAllAnnotationLayout protocol.ChangeAnnotationIdentifier = "layout"
+	fixAllAnnotationCode   protocol.ChangeAnnotationIdentifier = "code"
+)
+
+// annotatedWorkspaceEdit is a workspace edit made of annotated text edits, which the protocol package
+// can't express
+type annotatedWorkspaceEdit struct {
+	DocumentChanges   []annotatedDocumentEdit                                           `json:"documentChanges"`
+	ChangeAnnotations map[protocol.ChangeAnnotationIdentifier]protocol.ChangeAnnotation `json:"changeAnnotations"`
+}
+
+type annotatedDocumentEdit struct {
+	TextDocument protocol.OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []protocol.AnnotatedTextEdit                     `json:"edits"`
+}
+
+// fixAllCodeAction offers to apply all php-cs-fixer fixes to the document once it reported issues
+func (s *Server) fixAllCodeAction(uri protocol.DocumentURI) *protocol.CodeAction {
+	p := s.projectFor(uri.Filename())
+	if p == nil {
+		return nil
+	}
+	if _, enabled := p.getPhpCsFixerProviderConfig(); !enabled || len(p.loadFormattingProviders()) == 0 {
+		return nil
+	}
+	published, _ := s.getPublished(uri.Filename())
+	if len(phpCsFixerDiagnostics(published)) == 0 {
+		return nil
+	}
+
+	return &protocol.CodeAction{
+		Title: fmt.Sprintf("Fix all %s issues", diagnostics.PhpCsFixerProviderName),
+		Kind:  codeActionKindSourceFixAll,
+		Command: &protocol.Command{
+			Title:     "Fix all",
+			Command:   getFullLspCommandName(LspCommandNameFixAll),
+			Arguments: []interface{}{string(uri)},
+		},
+	}
+}
+
+// groupRuleOccurrences reports the rules of the categories grouped by the provider once per file
+func groupRuleOccurrences(providerConfig config.DiagnosticsProvider, diags []protocol.Diagnostic) []protocol.Diagnostic {
+	return utils.GroupOccurrences(diags, func(diagnostic protocol.Diagnostic) bool {
+		return providerConfig.GroupsCategory(diagnostics.RuleCategory(diagnostic))
+	})
+}
+
+// ruleFixCodeActions offers to fix all the occurrences in the file of the php-cs-fixer rules reported more
+// than once, grouped or not
+func (s *Server) ruleFixCodeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	p := s.projectFor(params.TextDocument.URI.Filename())
+	if p == nil {
+		return actions
+	}
+	formattingProviders := p.loadFormattingProviders()
+	if len(formattingProviders) == 0 {
+		return actions
+	}
+	if _, ok := formattingProviders[0].(formatting.RuleFormatter); !ok {
+		return actions
+	}
+
+	published, _ := s.getPublished(params.TextDocument.URI.Filename())
+	ruleOccurrences := make(map[string]int)
+	for _, diagnostic := range phpCsFixerDiagnostics(published) {
+		if rule, ok := diagnostic.Code.(string); ok {
+			ruleOccurrences[rule] += utils.Occurrences(diagnostic)
+		}
+	}
+
+	for _, diagnostic := range params.Context.Diagnostics {
+		rule, ok := diagnostic.Code.(string)
+		occurrences := max(utils.Occurrences(diagnostic), ruleOccurrences[rule])
+		if !ok || diagnostic.Source != diagnostics.PhpCsFixerProviderName || occurrences < 2 {
+			continue
+		}
+
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Fix all %d %s occurrences", occurrences, rule),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Command: &protocol.Command{
+				Title:     fmt.Sprintf("Fix all %s occurrences", rule),
+				Command:   getFullLspCommandName(LspCommandNameFixAll),
+				Arguments: []interface{}{string(params.TextDocument.URI), rule},
+			},
+		})
+	}
+
+	return actions
+}
+
+func phpCsFixerDiagnostics(diags []protocol.Diagnostic) []protocol.Diagnostic {
+	fixerDiagnostics := []protocol.Diagnostic{}
+	for _, diagnostic := range diags {
+		if diagnostic.Source == diagnostics.PhpCsFixerProviderName {
+			fixerDiagnostics = append(fixerDiagnostics, diagnostic)
+		}
+	}
+	return fixerDiagnostics
+}
+
+// handleFixAllCommand formats the document given as argument and sends the changes to the client as a
+// workspace edit. Clients supporting change annotations preview every changed block, labeled with the
+// rules reported there; blocks touched by rules changing more than the layout need a confirmation. An
+// optional second argument only applies this rule.
+func (s *Server) handleFixAllCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	if len(arguments) == 0 {
+		return reply(ctx, nil, fmt.Errorf("missing document URI argument"))
+	}
+	uriArgument, ok := arguments[0].(string)
+	if !ok || uriArgument == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid document URI argument: %v", arguments[0]))
+	}
+
+	uri := protocol.DocumentURI(uriArgument)
+	filePath := uri.Filename()
+	p := s.projectFor(filePath)
+	if p == nil || len(p.loadFormattingProviders()) == 0 {
+		return reply(ctx, nil, fmt.Errorf("no formatting provider for %s", uri))
+	}
+
+	var rule string
+	if len(arguments) > 1 {
+		if rule, ok = arguments[1].(string); !ok {
+			return reply(ctx, nil, fmt.Errorf("invalid rule argument: %v", arguments[1]))
+		}
+	}
+
+	content, err := s.documentContent(uri)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	provider := p.loadFormattingProviders()[0]
+	var fixedContent string
+	if rule == "" {
+		fixedContent, err = provider.Format(ctx, filePath, content)
+	} else if ruleFormatter, ok := provider.(formatting.RuleFormatter); ok {
+		fixedContent, err = ruleFormatter.FormatRules(ctx, filePath, content, []string{rule})
+	} else {
+		return reply(ctx, nil, fmt.Errorf("%s can't apply a single rule", provider.Name()))
+	}
+	if err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s failed: %w", provider.Name(), err))
+	}
+
+	edits := utils.LineEdits(content, fixedContent)
+	if len(edits) == 0 {
+		return reply(ctx, nil, nil)
+	}
+
+	params := &applyEditParams{Label: fmt.Sprintf("Fix all %s issues", provider.Name())}
+	if rule != "" {
+		params.Label = fmt.Sprintf("Fix all %s occurrences", rule)
+	}
+	if s.changeAnnotationsSupported {
+		published, _ := s.getPublished(filePath)
+		reported := phpCsFixerDiagnostics(published)
+		if rule != "" {
+			// Grouped occurrences aren't published, every change is the rule's
+			reported = make([]protocol.Diagnostic, 0, len(edits))
+			for _, edit := range edits {
+				reported = append(reported, protocol.Diagnostic{Range: edit.Range, Code: rule})
+			}
+		}
+		params.Edit = annotateFixAllEdits(uri, edits, reported)
+	} else {
+		params.Edit = protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits}}
+	}
+
+	s.applyEdit(filePath, params)
+
+	return reply(ctx, nil, nil)
+}
+
+// applyEdit sends the changes of the file to the client with workspace/applyEdit
+func (s *Server) applyEdit(filePath string, params *applyEditParams) {
+	// The client answers through the connection the command handler blocks
+	go func() {
+		var result protocol.ApplyWorkspaceEditResponse
+		if _, err := s.conn.Call(context.Background(), protocol.MethodWorkspaceApplyEdit, params, &result); err != nil {
+			log.Printf("%s%s Failed to apply the changes of %s: %v", logging.LogTagLSP, logging.LogTagServer, filePath, err)
+			return
+		}
+		if !result.Applied {
+			log.Printf("%s%s Changes of %s not applied: %s", logging.LogTagLSP, logging.LogTagServer, filePath, result.FailureReason)
+		}
+	}()
+}
+
+// applyEditParams is protocol.ApplyWorkspaceEditParams holding either kind of workspace edit
+type applyEditParams struct {
+	Label string      `json:"label,omitempty"`
+	Edit  interface{} `json:"edit"`
+}
+
+// annotateFixAllEdits labels every edit with the rules reported on the lines it changes
+func annotateFixAllEdits(uri protocol.DocumentURI, edits []protocol.TextEdit, reported []protocol.Diagnostic) annotatedWorkspaceEdit {
+	layoutRules, codeRules := map[string]bool{}, map[string]bool{}
+	annotated := make([]protocol.AnnotatedTextEdit, 0, len(edits))
+
+	for _, edit := range edits {
+		var rules []string
+		for _, diagnostic := range reported {
+			rule, ok := diagnostic.Code.(string)
+			if ok && overlapsLines(diagnostic.Range, edit.Range) {
+				rules = append(rules, rule)
+			}
+		}
+
+		// Unattributed changes can't be told harmless
+		annotation := fixAllAnnotationLayout
+		if len(rules) == 0 {
+			annotation = fixAllAnnotationCode
+		}
+		for _, rule := range rules {
+			if !diagnostics.IsCosmeticRule(rule) {
+				annotation = fixAllAnnotationCode
+			}
+		}
+		annotationRules := layoutRules
+		if annotation == fixAllAnnotationCode {
+			annotationRules = codeRules
+		}
+		for _, rule := range rules {
+			annotationRules[rule] = true
+		}
+
+		annotated = append(annotated, protocol.AnnotatedTextEdit{TextEdit: edit, AnnotationID: annotation})
+	}
+
+	annotations := map[protocol.ChangeAnnotationIdentifier]protocol.ChangeAnnotation{}
+	for _, edit := range annotated {
+		switch edit.AnnotationID {
+		case fixAllAnnotationLayout:
+			annotations[edit.AnnotationID] = protocol.ChangeAnnotation{Label: "Layout fixes", Description: ruleList(layoutRules)}
+		case fixAllAnnotationCode:
+			annotations[edit.AnnotationID] = protocol.ChangeAnnotation{Label: "Code fixes", Description: ruleList(codeRules), NeedsConfirmation: true}
+		}
+	}
+
+	return annotatedWorkspaceEdit{
+		DocumentChanges: []annotatedDocumentEdit{
+			{TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri}}, Edits: annotated},
+		},
+		ChangeAnnotations: annotations,
+	}
+}
+
+// overlapsLines reports whether the diagnostic is on one of the lines replaced by the edit
+func overlapsLines(diagnostic protocol.Range, edit protocol.Range) bool {
+	if edit.Start.Line == edit.End.Line {
+		// Insertion before the line
+		return diagnostic.Start.Line <= edit.Start.Line && edit.Start.Line <= diagnostic.End.Line
+	}
+	return diagnostic.Start.Line < edit.End.Line && edit.Start.Line <= diagnostic.End.Line
+}
+
+func ruleList(rules map[string]bool) string {
+	names := make([]string, 0, len(rules))
+	for rule := range rules {
+		names = append(names, rule)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// handleWillSaveWaitUntil returns the formatting edits of the document about to be saved when the project
+// enables formatOnSave, so clients apply them before writing the file. Saves after a delay (auto-save) are
+// left alone, formatting would rewrite the buffer while typing.
+func (s *Server) handleWillSaveWaitUntil(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.WillSaveTextDocumentParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return err
+	}
+
+	uri := params.TextDocument.URI
+	filePath := uri.Filename()
+	p := s.projectFor(filePath)
+	if p == nil || !p.serverConfig.FormatOnSave || params.Reason == protocol.TextDocumentSaveReasonAfterDelay {
+		return reply(ctx, []protocol.TextEdit{}, nil)
+	}
+
+	content, open := s.getDocumentContent(uri)
+	provider, ok := p.formattingProviderFor(filePath)
+	if !open || !ok {
+		return reply(ctx, []protocol.TextEdit{}, nil)
+	}
+
+	// The connection keeps serving the other requests while the formatter runs
+	go func() {
+		formattedContent, err := provider.Format(ctx, filePath, content)
+		if err != nil {
+			log.Printf("%s%s Formatting %s on save with %s failed: %v", logging.LogTagLSP, logging.LogTagServer, filePath, provider.Name(), err)
+			_ = reply(ctx, []protocol.TextEdit{}, nil)
+			return
+		}
+
+		_ = reply(ctx, p.formattingEdits(ctx, filePath, content, formattedContent), nil)
+	}()
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cristianradulescu/php-diagls/internal/formatting"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// handleFormatWithCommand formats the document given as first argument with the provider given as second
+// argument, whatever the priority of the formatters, and sends the changes to the client as a workspace edit.
+// The provider must be enabled; its formatting doesn't need to be.
+func (s *Server) handleFormatWithCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	if len(arguments) < 2 {
+		return reply(ctx, nil, fmt.Errorf("expected a document URI and a provider id as arguments"))
+	}
+	uriArgument, ok := arguments[0].(string)
+	if !ok || uriArgument == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid document URI argument: %v", arguments[0]))
+	}
+	providerId, ok := arguments[1].(string)
+	if !ok || providerId == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid provider argument: %v", arguments[1]))
+	}
+
+	uri := protocol.DocumentURI(uriArgument)
+	filePath := uri.Filename()
+	p := s.projectFor(filePath)
+	if p == nil {
+		return reply(ctx, nil, fmt.Errorf("no project for %s", uri))
+	}
+	providerConfig, exists := p.serverConfig.DiagnosticsProviders[providerId]
+	if !exists || !providerConfig.Enabled {
+		return reply(ctx, nil, fmt.Errorf("provider %s is not enabled for %s", providerId, uri))
+	}
+
+	providerConfig.Format.Enabled = true
+	provider, err := formatting.NewFormattingProvider(providerId, providerConfig)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	content, err := s.documentContent(uri)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	formattedContent, err := provider.Format(ctx, filePath, content)
+	if err != nil {
+		return reply(ctx, nil, fmt.Errorf("formatting with %s failed: %w", provider.Name(), err))
+	}
+
+	edits := p.formattingEdits(ctx, filePath, content, formattedContent)
+	if len(edits) == 0 {
+		return reply(ctx, nil, nil)
+	}
+
+	s.applyEdit(filePath, &applyEditParams{
+		Label: "Format with " + provider.Name(),
+		Edit:  protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits}},
+	})
+
+	return reply(ctx, nil, nil)
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// Files formatted concurrently by the workspace formatting, the edits of the open documents of a batch are
+// sent together
+const formatWorkspaceBatchSize = 10
+
+func (s *Server) handleFormatWorkspaceCommand(ctx context.Context, reply jsonrpc2.Replier) error {
+	if len(s.allProjects()) == 0 {
+		return reply(ctx, nil, fmt.Errorf("no project root available"))
+	}
+	if s.bufferOnly {
+		return reply(ctx, nil, fmt.Errorf("the workspace files can't be listed in buffer-only mode"))
+	}
+
+	s.startBackgroundJob("Formatting workspace", s.formatWorkspace)
+
+	return reply(ctx, nil, nil)
+}
+
+// formatWorkspace formats the PHP files of every project with the formatter of their closest project. Open
+// documents are changed through workspace/applyEdit, so their buffers stay the reference; the other files
+// are written directly.
+func (s *Server) formatWorkspace(ctx context.Context, report progressReporter) {
+	projects := s.allProjects()
+
+	var files []string
+	for _, p := range projects {
+		projectFiles, err := utils.FindFiles(ctx, p.root, p.serverConfig.SupportsFile)
+		if err != nil {
+			log.Printf("%s%s Workspace formatting stopped: %v", logging.LogTagLSP, logging.LogTagServer, err)
+			return
+		}
+
+		for _, filePath := range projectFiles {
+			// Files of nested projects are formatted with their own formatter
+			if s.projectFor(filePath) == p {
+				files = append(files, filePath)
+			}
+		}
+	}
+
+	log.Printf("%s%s Workspace formatting found %d files in %d projects", logging.LogTagLSP, logging.LogTagServer, len(files), len(projects))
+
+	formatted := 0
+	for start := 0; start < len(files); start += formatWorkspaceBatchSize {
+		if ctx.Err() != nil {
+			log.Printf("%s%s Workspace formatting cancelled after %d/%d files", logging.LogTagLSP, logging.LogTagServer, start, len(files))
+			return
+		}
+
+		end := min(start+formatWorkspaceBatchSize, len(files))
+		report(fmt.Sprintf("%d/%d %s", end, len(files), s.displayPath(files[start])), uint32(start*100/len(files)))
+
+		formatted += s.formatWorkspaceBatch(ctx, files[start:end])
+	}
+
+	log.Printf("%s%s Workspace formatting changed %d/%d files", logging.LogTagLSP, logging.LogTagServer, formatted, len(files))
+}
+
+// formatWorkspaceBatch formats the files concurrently and returns the number of files changed
+func (s *Server) formatWorkspaceBatch(ctx context.Context, files []string) int {
+	var mu sync.Mutex
+	changed := 0
+	documentEdits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+
+	dones := make([]<-chan struct{}, 0, len(files))
+	for _, filePath := range files {
+		dones = append(dones, s.analysisScheduler.Submit(ctx, scheduler.PriorityBackground, func(jobCtx context.Context) {
+			uri := utils.PathToURI(filePath)
+			edits, written := s.formatWorkspaceFile(jobCtx, uri)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(edits) > 0 {
+				documentEdits[uri] = edits
+			}
+			if len(edits) > 0 || written {
+				changed++
+			}
+		}))
+	}
+	for _, done := range dones {
+		<-done
+	}
+
+	if len(documentEdits) > 0 {
+		s.applyEdit(fmt.Sprintf("%d open documents", len(documentEdits)), &applyEditParams{
+			Label: "Format workspace",
+			Edit:  protocol.WorkspaceEdit{Changes: documentEdits},
+		})
+	}
+
+	return changed
+}
+
+// formatWorkspaceFile returns the formatting edits of an open document, or writes the formatted content of a
+// closed file, reporting whether it was written
+func (s *Server) formatWorkspaceFile(ctx context.Context, uri protocol.DocumentURI) ([]protocol.TextEdit, bool) {
+	filePath := uri.Filename()
+	p := s.projectFor(filePath)
+	if p == nil {
+		return nil, false
+	}
+	provider, ok := p.formattingProviderFor(filePath)
+	if !ok {
+		return nil, false
+	}
+
+	content, open := s.getDocumentContent(uri)
+	if !open {
+		fileContent, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Printf("%s%s Failed to read %s: %v", logging.LogTagLSP, logging.LogTagServer, filePath, err)
+			return nil, false
+		}
+		content = string(fileContent)
+	}
+
+	formattedContent, err := provider.Format(ctx, filePath, content)
+	if err != nil {
+		log.Printf("%s%s Formatting %s with %s failed: %v", logging.LogTagLSP, logging.LogTagServer, filePath, provider.Name(), err)
+		return nil, false
+	}
+	if formattedContent == content {
+		return nil, false
+	}
+
+	if open {
+		return utils.LineEdits(content, formattedContent), false
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		log.Printf("%s%s Failed to write %s: %v", logging.LogTagLSP, logging.LogTagServer, filePath, err)
+		return nil, false
+	}
+	if err := os.WriteFile(filePath, []byte(formattedContent), info.Mode().Perm()); err != nil {
+		log.Printf("%s%s Failed to write %s: %v", logging.LogTagLSP, logging.LogTagServer, filePath, err)
+		return nil, false
+	}
+	return nil, true
+}
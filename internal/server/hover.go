@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// handleHover documents the rules of the diagnostics published on the hovered line: the php-cs-fixer
+// `describe` output or the phpstan identifier documentation. Descriptions are cached by the providers, the
+// first hover of a php-cs-fixer rule waits for the tool.
+func (s *Server) handleHover(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.HoverParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return reply(ctx, nil, err)
+	}
+
+	filePath := params.TextDocument.URI.Filename()
+	p := s.projectFor(filePath)
+	if p == nil {
+		return reply(ctx, nil, nil)
+	}
+
+	explainers := make(map[string]diagnostics.RuleExplainer)
+	for _, provider := range s.loadDiagnosticsProviders(p) {
+		if explainer, ok := provider.(diagnostics.RuleExplainer); ok {
+			explainers[provider.Name()] = explainer
+		}
+	}
+
+	line := params.Position.Line
+	published, _ := s.getPublished(filePath)
+	var hovered []protocol.Diagnostic
+	for _, diagnostic := range published {
+		if _, explained := explainers[diagnostic.Source]; explained && diagnostic.Range.Start.Line <= line && line <= diagnostic.Range.End.Line {
+			hovered = append(hovered, diagnostic)
+		}
+	}
+	if len(hovered) == 0 {
+		return reply(ctx, nil, nil)
+	}
+
+	go func() {
+		var sections []string
+		documented := make(map[string]bool)
+		for _, diagnostic := range hovered {
+			rule, ok := diagnostic.Code.(string)
+			if !ok || documented[diagnostic.Source+"/"+rule] {
+				continue
+			}
+			documented[diagnostic.Source+"/"+rule] = true
+
+			explanation, err := explainers[diagnostic.Source].ExplainRule(ctx, rule)
+			if err != nil {
+				log.Printf("%s%s No documentation of %s for the hover: %v", logging.LogTagLSP, logging.LogTagServer, rule, err)
+				continue
+			}
+			sections = append(sections, diagnostics.RuleDocMarkdown(diagnostic.Source, rule, explanation))
+		}
+
+		if len(sections) == 0 {
+			_ = reply(ctx, nil, nil)
+			return
+		}
+		_ = reply(ctx, &protocol.Hover{
+			Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: strings.Join(sections, "\n---\n\n")},
+		}, nil)
+	}()
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// The protocol package predates the inlay hints of LSP 3.17
+const (
+	methodTextDocumentInlayHint     = "textDocument/inlayHint"
+	methodWorkspaceInlayHintRefresh = "workspace/inlayHint/refresh"
+)
+
+// inlayHintCapabilities are the client capabilities of the inlay hints
+type inlayHintCapabilities struct {
+	Capabilities struct {
+		Workspace struct {
+			InlayHint *struct {
+				RefreshSupport bool `json:"refreshSupport"`
+			} `json:"inlayHint"`
+		} `json:"workspace"`
+	} `json:"capabilities"`
+}
+
+type inlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type inlayHint struct {
+	Position    protocol.Position `json:"position"`
+	Label       string            `json:"label"`
+	Tooltip     string            `json:"tooltip,omitempty"`
+	PaddingLeft bool              `json:"paddingLeft,omitempty"`
+}
+
+// setInlayHintRefreshSupport enables the refresh of the inlay hints when the client supports it
+func (s *Server) setInlayHintRefreshSupport(rawParams json.RawMessage) {
+	var capabilities inlayHintCapabilities
+	if err := json.Unmarshal(rawParams, &capabilities); err != nil {
+		return
+	}
+
+	if inlayHintCapabilities := capabilities.Capabilities.Workspace.InlayHint; inlayHintCapabilities != nil {
+		s.inlayHintRefreshSupported = inlayHintCapabilities.RefreshSupport
+	}
+}
+
+// handleInlayHint shows the cyclomatic complexity of the functions declared in the range at the end of their
+// declaration line, from the last phpmetrics report of the document when its inlayHints are enabled.
+// phpmetrics isn't run again: documents not analyzed since they were opened have no hints.
+func (s *Server) handleInlayHint(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params inlayHintParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return reply(ctx, nil, err)
+	}
+
+	hints := []inlayHint{}
+	uri := params.TextDocument.URI
+	if p := s.projectFor(uri.Filename()); p == nil || !inlayHintsEnabled(p) {
+		return reply(ctx, hints, nil)
+	}
+
+	content, open := s.getDocumentContent(uri)
+	if !open {
+		return reply(ctx, hints, nil)
+	}
+	s.rawResultsMu.Lock()
+	report, ok := s.rawResults[uri][diagnostics.PhpMetricsProviderId].Result.(diagnostics.PhpMetricsOutputResult)
+	s.rawResultsMu.Unlock()
+	if !ok {
+		return reply(ctx, hints, nil)
+	}
+
+	lines := strings.Split(content, "\n")
+	for _, function := range diagnostics.PhpMetricsFunctions(content, report) {
+		line := function.Range.Start.Line
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+
+		// Positions count UTF-16 code units
+		lineEnd := uint32(len(utf16.Encode([]rune(strings.TrimRight(lines[line], "\r")))))
+		hints = append(hints, inlayHint{
+			Position:    protocol.Position{Line: line, Character: lineEnd},
+			Label:       fmt.Sprintf("complexity: %d", function.Ccn),
+			Tooltip:     fmt.Sprintf("Cyclomatic complexity of %s reported by %s", function.Label, diagnostics.PhpMetricsProviderName),
+			PaddingLeft: true,
+		})
+	}
+
+	return reply(ctx, hints, nil)
+}
+
+// inlayHintsEnabled reports whether the project shows the phpmetrics complexity as inlay hints
+func inlayHintsEnabled(p *project) bool {
+	providerConfig, exists := p.serverConfig.DiagnosticsProviders[diagnostics.PhpMetricsProviderId]
+	return exists && providerConfig.Enabled && providerConfig.InlayHints
+}
+
+// refreshInlayHints asks the client to request the inlay hints again once an open document was analyzed,
+// the complexity of its functions may have changed
+func (s *Server) refreshInlayHints(uri protocol.DocumentURI) {
+	if !s.inlayHintRefreshSupported {
+		return
+	}
+	if p := s.projectFor(uri.Filename()); p == nil || !inlayHintsEnabled(p) {
+		return
+	}
+	if _, open := s.getDocumentContent(uri); !open {
+		return
+	}
+
+	go func() {
+		if _, err := s.conn.Call(context.Background(), methodWorkspaceInlayHintRefresh, nil, nil); err != nil {
+			log.Printf("%s%s Failed to refresh the inlay hints: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		}
+	}()
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// selectProviders drops the providers the run doesn't include, e.g. the manual providers from the automatic runs
+func selectProviders(p *project, providers []diagnostics.DiagnosticsProvider, run analysisRun) []diagnostics.DiagnosticsProvider {
+	selected := []diagnostics.DiagnosticsProvider{}
+	for _, provider := range providers {
+		if run.runsProvider(provider.Id(), p.serverConfig.DiagnosticsProviders[provider.Id()]) {
+			selected = append(selected, provider)
+		}
+	}
+	return selected
+}
+
+// isDeferredProvider reports whether the provider doesn't run on every change, its last results being
+// published again by the runs without it
+func isDeferredProvider(providerId string, providerConfig config.DiagnosticsProvider) bool {
+	return providerConfig.IsManual() || diagnostics.RunsOnSave(providerId, providerConfig)
+}
+
+// manualProviders returns the enabled providers of the project which only run when requested
+func (s *Server) manualProviders(p *project) []diagnostics.DiagnosticsProvider {
+	manual := []diagnostics.DiagnosticsProvider{}
+	for _, provider := range s.loadDiagnosticsProviders(p) {
+		if p.serverConfig.DiagnosticsProviders[provider.Id()].IsManual() {
+			manual = append(manual, provider)
+		}
+	}
+	return manual
+}
+
+// setManualDiagnostics keeps the results of the last run of the deferred providers (manual and save ones) on
+// the file, by provider id, they are published again along with the automatic results until their next run.
+// nil results forget all the providers of the file.
+func (s *Server) setManualDiagnostics(uri protocol.DocumentURI, results map[string][]protocol.Diagnostic) {
+	s.manualMu.Lock()
+	defer s.manualMu.Unlock()
+
+	if results == nil {
+		delete(s.manualResults, uri)
+		return
+	}
+
+	if s.manualResults[uri] == nil {
+		s.manualResults[uri] = make(map[string][]protocol.Diagnostic)
+	}
+	for providerId, diags := range results {
+		if len(diags) == 0 {
+			delete(s.manualResults[uri], providerId)
+		} else {
+			s.manualResults[uri][providerId] = diags
+		}
+	}
+	if len(s.manualResults[uri]) == 0 {
+		delete(s.manualResults, uri)
+	}
+}
+
+// manualDiagnostics returns the last results of the deferred providers on the file, except the ones of the
+// providers running again
+func (s *Server) manualDiagnostics(uri protocol.DocumentURI, running func(providerId string) bool) []protocol.Diagnostic {
+	s.manualMu.Lock()
+	defer s.manualMu.Unlock()
+
+	providerIds := make([]string, 0, len(s.manualResults[uri]))
+	for providerId := range s.manualResults[uri] {
+		providerIds = append(providerIds, providerId)
+	}
+	sort.Strings(providerIds)
+
+	var diags []protocol.Diagnostic
+	for _, providerId := range providerIds {
+		if !running(providerId) {
+			diags = append(diags, s.manualResults[uri][providerId]...)
+		}
+	}
+	return diags
+}
+
+func (s *Server) clearManualDiagnostics() {
+	s.manualMu.Lock()
+	defer s.manualMu.Unlock()
+
+	s.manualResults = make(map[protocol.DocumentURI]map[string][]protocol.Diagnostic)
+}
+
+// handleAnalyzeFileCommand analyzes the document given as argument with all its providers, the manual ones included.
+// The optional second argument is a timeout in seconds replacing the configured ones.
+func (s *Server) handleAnalyzeFileCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	if len(arguments) == 0 {
+		return reply(ctx, nil, fmt.Errorf("missing document URI argument"))
+	}
+	uriArgument, ok := arguments[0].(string)
+	if !ok || uriArgument == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid document URI argument: %v", arguments[0]))
+	}
+
+	uri := protocol.DocumentURI(uriArgument)
+	if !s.isSupportedDocument(uri) {
+		return reply(ctx, nil, fmt.Errorf("unsupported document: %s", uri))
+	}
+
+	run := analysisRun{includeManual: true}
+	if len(arguments) > 1 {
+		// JSON numbers decode as float64
+		timeoutSeconds, ok := arguments[1].(float64)
+		if !ok || timeoutSeconds <= 0 {
+			return reply(ctx, nil, fmt.Errorf("invalid timeout argument: %v", arguments[1]))
+		}
+		run.timeout = time.Duration(timeoutSeconds * float64(time.Second))
+	}
+
+	log.Printf("%s%s Analyzing %s with all providers", logging.LogTagLSP, logging.LogTagServer, uri.Filename())
+
+	s.analysisScheduler.Submit(context.Background(), scheduler.PrioritySave, func(ctx context.Context) {
+		diags := s.collectDiagnostics(ctx, uri.Filename(), run)
+		if ctx.Err() != nil {
+			return
+		}
+		s.publishDiagnostics(context.Background(), uri, diags)
+	})
+
+	return reply(ctx, nil, nil)
+}
+
+// manualCodeLens offers to run the manual providers from the top of the document
+func (s *Server) manualCodeLens(p *project, uri protocol.DocumentURI) *protocol.CodeLens {
+	manual := s.manualProviders(p)
+	if len(manual) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(manual))
+	for _, provider := range manual {
+		names = append(names, provider.Name())
+	}
+	sort.Strings(names)
+
+	return &protocol.CodeLens{
+		Range: protocol.Range{},
+		Command: &protocol.Command{
+			Title:     fmt.Sprintf("Run %s", strings.Join(names, ", ")),
+			Command:   getFullLspCommandName(LspCommandNameAnalyzeFile),
+			Arguments: []interface{}{string(uri)},
+		},
+	}
+}
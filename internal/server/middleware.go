@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Requests taking longer are logged with their duration
+const slowRequestThreshold = time.Second
+
+// Middleware wraps the handling of every request, implementing cross-cutting concerns such as logging,
+// metrics or tracing once for all the handlers
+type Middleware func(next jsonrpc2.Handler) jsonrpc2.Handler
+
+// Use wraps the request handling with the middlewares, the first one being the outermost. The default
+// recovery and logging middlewares stay outermost. Must be called before the server handles requests.
+func (s *Server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+	s.handler = chainMiddlewares(s.dispatchCancellable, s.middlewares)
+}
+
+func chainMiddlewares(handler jsonrpc2.Handler, middlewares []Middleware) jsonrpc2.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// RecoveryMiddleware turns a panicking handler into an internal error reply, keeping the server running
+func RecoveryMiddleware(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Printf("%s%s Panic handling %s: %v\n%s", logging.LogTagLSP, logging.LogTagServer, req.Method(), recovered, debug.Stack())
+				err = reply(ctx, nil, jsonrpc2.Errorf(jsonrpc2.InternalError, "%s failed: %v", req.Method(), recovered))
+			}
+		}()
+		return next(ctx, reply, req)
+	}
+}
+
+// LoggingMiddleware logs the received requests and the slow ones once handled
+func LoggingMiddleware(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		log.Printf("%s%s Received request: %s", logging.LogTagLSP, logging.LogTagServer, req.Method())
+
+		start := time.Now()
+		err := next(ctx, reply, req)
+		if elapsed := time.Since(start); elapsed > slowRequestThreshold {
+			log.Printf("%s%s Handled %s in %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), elapsed.Round(time.Millisecond))
+		}
+		return err
+	}
+}
+
+// MetricsMiddleware reports the duration and the outcome of every request to observe. Handlers replying
+// asynchronously, such as formatting, are measured until they hand the request over.
+func MetricsMiddleware(observe func(method string, duration time.Duration, err error)) Middleware {
+	return func(next jsonrpc2.Handler) jsonrpc2.Handler {
+		return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+			start := time.Now()
+			err := next(ctx, reply, req)
+			observe(req.Method(), time.Since(start), err)
+			return err
+		}
+	}
+}
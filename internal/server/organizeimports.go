@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// php-cs-fixer rules removing the unused imports and sorting the others
+var organizeImportsRules = []string{"no_unused_imports", "ordered_imports"}
+
+// organizeImportsCodeAction offers to organize the imports of the PHP documents when php-cs-fixer is enabled
+func (s *Server) organizeImportsCodeAction(uri protocol.DocumentURI) *protocol.CodeAction {
+	p := s.projectFor(uri.Filename())
+	if p == nil || p.analyzesDocument(uri.Filename()) {
+		return nil
+	}
+	if _, enabled := p.getPhpCsFixerProviderConfig(); !enabled {
+		return nil
+	}
+
+	return &protocol.CodeAction{
+		Title: "Organize imports",
+		Kind:  protocol.SourceOrganizeImports,
+		Command: &protocol.Command{
+			Title:     "Organize imports",
+			Command:   getFullLspCommandName(LspCommandNameOrganizeImports),
+			Arguments: []interface{}{string(uri)},
+		},
+	}
+}
+
+// handleOrganizeImportsCommand applies the import rules of php-cs-fixer to the document given as argument
+// and sends the changes to the client as a workspace edit. The php-cs-fixer provider doesn't need
+// formatting enabled, only these rules are applied.
+func (s *Server) handleOrganizeImportsCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	if len(arguments) == 0 {
+		return reply(ctx, nil, fmt.Errorf("missing document URI argument"))
+	}
+	uriArgument, ok := arguments[0].(string)
+	if !ok || uriArgument == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid document URI argument: %v", arguments[0]))
+	}
+
+	uri := protocol.DocumentURI(uriArgument)
+	filePath := uri.Filename()
+	p := s.projectFor(filePath)
+	if p == nil {
+		return reply(ctx, nil, fmt.Errorf("no project for %s", uri))
+	}
+	providerConfig, enabled := p.getPhpCsFixerProviderConfig()
+	if !enabled {
+		return reply(ctx, nil, fmt.Errorf("%s is not enabled for %s", diagnostics.PhpCsFixerProviderName, uri))
+	}
+
+	content, err := s.documentContent(uri)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	providerConfig.Format.Enabled = true
+	organizedContent, err := diagnostics.NewPhpCsFixer(providerConfig).FormatRules(ctx, filePath, content, organizeImportsRules)
+	if err != nil {
+		return reply(ctx, nil, fmt.Errorf("%s failed: %w", diagnostics.PhpCsFixerProviderName, err))
+	}
+
+	edits := utils.LineEdits(content, organizedContent)
+	if len(edits) == 0 {
+		return reply(ctx, nil, nil)
+	}
+
+	s.applyEdit(filePath, &applyEditParams{
+		Label: "Organize imports",
+		Edit:  protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits}},
+	})
+
+	return reply(ctx, nil, nil)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+const progressCreateTimeout = 5 * time.Second
+
+// progressReporter sends intermediate reports for a running background job.
+// It is a no-op when the client does not support work done progress.
+type progressReporter func(message string, percentage uint32)
+
+var progressTokenSeq uint64
+
+func newProgressTokenName() string {
+	return fmt.Sprintf("%s-%d", config.Name, atomic.AddUint64(&progressTokenSeq, 1))
+}
+
+// startBackgroundJob runs a server initiated job in its own goroutine. When the client supports
+// work done progress, the job is reported as a cancellable progress and the client can stop it
+// through window/workDoneProgress/cancel.
+func (s *Server) startBackgroundJob(title string, job func(ctx context.Context, report progressReporter)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		defer cancel()
+
+		if !s.workDoneProgressSupported {
+			job(ctx, func(string, uint32) {})
+			return
+		}
+
+		tokenName := newProgressTokenName()
+		token := protocol.NewProgressToken(tokenName)
+
+		createCtx, createCancel := context.WithTimeout(ctx, progressCreateTimeout)
+		_, err := s.conn.Call(createCtx, protocol.MethodWorkDoneProgressCreate, &protocol.WorkDoneProgressCreateParams{Token: *token}, nil)
+		createCancel()
+		if err != nil {
+			log.Printf("%s%s Failed to create progress for %q: %v", logging.LogTagLSP, logging.LogTagServer, title, err)
+			job(ctx, func(string, uint32) {})
+			return
+		}
+
+		s.progressMu.Lock()
+		s.progressJobs[tokenName] = cancel
+		s.progressMu.Unlock()
+		defer func() {
+			s.progressMu.Lock()
+			delete(s.progressJobs, tokenName)
+			s.progressMu.Unlock()
+		}()
+
+		s.notifyProgress(*token, protocol.WorkDoneProgressBegin{
+			Kind:        protocol.WorkDoneProgressKindBegin,
+			Title:       title,
+			Cancellable: true,
+		})
+
+		job(ctx, func(message string, percentage uint32) {
+			s.notifyProgress(*token, protocol.WorkDoneProgressReport{
+				Kind:        protocol.WorkDoneProgressKindReport,
+				Cancellable: true,
+				Message:     message,
+				Percentage:  percentage,
+			})
+		})
+
+		endMessage := "Done"
+		if ctx.Err() != nil {
+			endMessage = "Cancelled"
+		}
+		s.notifyProgress(*token, protocol.WorkDoneProgressEnd{
+			Kind:    protocol.WorkDoneProgressKindEnd,
+			Message: endMessage,
+		})
+	}()
+}
+
+func (s *Server) notifyProgress(token protocol.ProgressToken, value interface{}) {
+	// Passed by pointer so that ProgressToken's pointer receiver MarshalJSON is used
+	params := &protocol.ProgressParams{Token: token, Value: value}
+	if err := s.conn.Notify(context.Background(), protocol.MethodProgress, params); err != nil {
+		log.Printf("%s%s Failed to send progress: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
+}
+
+func (s *Server) handleWorkDoneProgressCancel(ctx context.Context, _ jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.WorkDoneProgressCancelParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+
+		return err
+	}
+
+	s.progressMu.Lock()
+	cancel, exists := s.progressJobs[params.Token.String()]
+	s.progressMu.Unlock()
+
+	if exists {
+		log.Printf("%s%s Client cancelled background job %s", logging.LogTagLSP, logging.LogTagServer, params.Token.String())
+		cancel()
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/formatting"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// project is a directory holding a configuration file, with its own providers. A workspace holds
+// several projects in a monorepo; files are analyzed by the providers of the closest one.
+type project struct {
+	root string
+	// serverConfig is prepared once by setProjects before the project is published, then only read: the
+	// analyses read it without lock, a reload replaces the project rather than its configuration
+	serverConfig *config.Config
+	prepareOnce  sync.Once
+
+	providersMu          sync.Mutex
+	diagnosticsProviders []diagnostics.DiagnosticsProvider
+	formattingProviders  []formatting.FormattingProvider
+
+	messagesOnce sync.Once
+	messages     config.MessageCatalog
+}
+
+func newProject(root string, serverConfig *config.Config) *project {
+	return &project{root: root, serverConfig: serverConfig}
+}
+
+// contains reports whether the file is inside the project root
+func (p *project) contains(filePath string) bool {
+	return filePath == p.root || strings.HasPrefix(filePath, p.root+string(filepath.Separator))
+}
+
+// loadDiagnosticsProviders initializes the enabled providers once, reporting the providers failing to initialize
+func (p *project) loadDiagnosticsProviders(onError func(err error)) []diagnostics.DiagnosticsProvider {
+	p.providersMu.Lock()
+	defer p.providersMu.Unlock()
+
+	// Return cached providers if already initialized
+	if p.diagnosticsProviders != nil {
+		return p.diagnosticsProviders
+	}
+
+	providers := []diagnostics.DiagnosticsProvider{}
+	for id, providerConfig := range p.serverConfig.DiagnosticsProviders {
+		// Initialize only enabled diagnostics providers
+		if !providerConfig.Enabled {
+			continue
+		}
+
+		provider, err := diagnostics.NewDiagnosticsProvider(id, providerConfig)
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		providers = append(providers, provider)
+	}
+
+	// Cache and return
+	p.diagnosticsProviders = providers
+	return p.diagnosticsProviders
+}
+
+func (p *project) loadFormattingProviders() []formatting.FormattingProvider {
+	p.providersMu.Lock()
+	defer p.providersMu.Unlock()
+
+	// Return cached providers if already initialized
+	if p.formattingProviders != nil {
+		return p.formattingProviders
+	}
+
+	// Initialize and cache, a configured chain replaces the providers
+	if len(p.serverConfig.FormatChain) > 0 {
+		chain, err := formatting.NewChain(p.serverConfig)
+		if err != nil {
+			log.Printf("%s%s Formatting disabled in %s: %v", logging.LogTagLSP, logging.LogTagServer, p.root, err)
+			p.formattingProviders = []formatting.FormattingProvider{}
+			return p.formattingProviders
+		}
+		p.formattingProviders = []formatting.FormattingProvider{chain}
+		return p.formattingProviders
+	}
+	p.formattingProviders = formatting.LoadFormattingProviders(p.serverConfig.DiagnosticsProviders)
+	if len(p.formattingProviders) > 1 {
+		names := make([]string, 0, len(p.formattingProviders))
+		for _, provider := range p.formattingProviders {
+			names = append(names, provider.Name())
+		}
+		log.Printf("%s%s Several formatters enabled in %s (%s), formatting with %s only: set format.priority to pick another one or configure %s to run them in order", logging.LogTagLSP, logging.LogTagServer, p.root, strings.Join(names, ", "), names[0], config.ConfigItemFormatChain)
+	}
+	return p.formattingProviders
+}
+
+// formattingProviderFor returns the provider formatting the file: the formatter of its document provider
+// for the documents other than PHP files (composer.json), else the first PHP formatter
+func (p *project) formattingProviderFor(filePath string) (formatting.FormattingProvider, bool) {
+	if p.analyzesDocument(filePath) {
+		return formatting.NewDocumentFormattingProvider(p.serverConfig.DiagnosticsProviders, filePath)
+	}
+
+	formattingProviders := p.loadFormattingProviders()
+	if len(formattingProviders) == 0 {
+		return nil, false
+	}
+	return formattingProviders[0], true
+}
+
+// formattingEdits returns the edits turning the content into the formatted one. With formatChangedLines, only
+// the edits touching the lines changed since the merge base of the ref are kept, and none when git can't tell.
+func (p *project) formattingEdits(ctx context.Context, filePath string, content string, formattedContent string) []protocol.TextEdit {
+	ref := p.serverConfig.FormatChangedLines
+	if ref == "" {
+		// One edit per group of changed lines keeps the cursor and folds of the untouched ones
+		return utils.LineEdits(content, formattedContent)
+	}
+
+	baseContent, err := utils.GitBaseContent(ctx, filePath, ref)
+	if err != nil {
+		log.Printf("%s%s Changed lines of %s unknown, not formatting: %v", logging.LogTagLSP, logging.LogTagServer, filePath, err)
+		return []protocol.TextEdit{}
+	}
+	return utils.ChangedEdits(content, formattedContent, baseContent)
+}
+
+// messageCatalog loads the message catalog of the locale once, a broken catalog leaves the messages untouched
+func (p *project) messageCatalog(locale string) config.MessageCatalog {
+	p.messagesOnce.Do(func() {
+		catalog, err := p.serverConfig.LoadMessageCatalog(p.root, locale)
+		if err != nil {
+			log.Printf("%s%s Message catalog ignored in %s: %v", logging.LogTagLSP, logging.LogTagServer, p.root, err)
+			return
+		}
+		p.messages = catalog
+	})
+	return p.messages
+}
+
+// analyzesDocument reports whether an enabled provider of the project analyzes the file as a document
+// other than a PHP file, e.g. composer.json
+func (p *project) analyzesDocument(filePath string) bool {
+	for id, providerConfig := range p.serverConfig.DiagnosticsProviders {
+		if providerConfig.Enabled && diagnostics.HandlesDocument(id, providerConfig, filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// scansFile reports whether the workspace scan analyzes the file: the PHP files and the documents of the
+// enabled providers, except the ones published on another document (composer.lock)
+func (p *project) scansFile(filePath string) bool {
+	if p.analyzesDocument(filePath) {
+		return diagnostics.PublishedFile(filePath) == filePath
+	}
+	return p.serverConfig.SupportsFile(filePath)
+}
+
+func (p *project) providerCount() int {
+	p.providersMu.Lock()
+	defer p.providersMu.Unlock()
+	return len(p.diagnosticsProviders)
+}
+
+func (p *project) getPhpCsFixerProviderConfig() (config.DiagnosticsProvider, bool) {
+	for id, cfg := range p.serverConfig.DiagnosticsProviders {
+		if id == diagnostics.PhpCsFixerProviderId && cfg.Enabled {
+			return cfg, true
+		}
+	}
+	return config.DiagnosticsProvider{}, false
+}
+
+// discoverProjectRoots returns the directories holding a configuration file in the workspace folders,
+// the folders themselves included. Dependencies and caches are not searched. An absolute configuration
+// file configures the workspace folders.
+func discoverProjectRoots(ctx context.Context, folders []string, files config.Files) []string {
+	if _, ok := files.Absolute(); ok {
+		return folders
+	}
+
+	seen := make(map[string]bool)
+	var roots []string
+
+	for _, folder := range folders {
+		configFiles, err := utils.FindFiles(ctx, folder, func(path string) bool {
+			return files.IsConfigFile(path)
+		})
+		if err != nil {
+			log.Printf("%s%s Project discovery stopped in %s: %v", logging.LogTagLSP, logging.LogTagServer, folder, err)
+		}
+
+		for _, configFile := range configFiles {
+			root, _ := files.Root(configFile)
+			if !seen[root] {
+				seen[root] = true
+				roots = append(roots, root)
+			}
+		}
+	}
+
+	// Workspace folder order first, then parents before their nested projects
+	sort.SliceStable(roots, func(i, j int) bool {
+		return folderIndex(folders, roots[i]) < folderIndex(folders, roots[j])
+	})
+	return roots
+}
+
+// folderIndex returns the index of the workspace folder containing the path
+func folderIndex(folders []string, path string) int {
+	for i, folder := range folders {
+		if path == folder || strings.HasPrefix(path, folder+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(folders)
+}
+
+// loadProjects loads the configuration of every project root. Roots failing to load are reported and
+// skipped, keeping the previous project for that root when there is one.
+func loadProjects(roots []string, files config.Files, previous []*project, onError func(root string, err error)) []*project {
+	previousByRoot := make(map[string]*project, len(previous))
+	for _, p := range previous {
+		previousByRoot[p.root] = p
+	}
+
+	var projects []*project
+	for _, root := range roots {
+		serverConfig, err := (&config.Config{}).LoadConfig(root, files)
+		if err != nil {
+			onError(root, err)
+			if p, exists := previousByRoot[root]; exists {
+				projects = append(projects, p)
+			}
+			continue
+		}
+		projects = append(projects, newProject(root, serverConfig))
+	}
+
+	return projects
+}
+
+// projectFor returns the closest project containing the file. Files outside every project belong to
+// the first one, like they did before monorepo support.
+func (s *Server) projectFor(filePath string) *project {
+	s.projectsMu.RLock()
+	defer s.projectsMu.RUnlock()
+
+	var closest *project
+	for _, p := range s.projects {
+		if p.contains(filePath) && (closest == nil || len(p.root) > len(closest.root)) {
+			closest = p
+		}
+	}
+	if closest == nil && len(s.projects) > 0 {
+		closest = s.projects[0]
+	}
+
+	return closest
+}
+
+// primaryProject is the first project of the first workspace folder, it provides the server wide settings
+func (s *Server) primaryProject() *project {
+	s.projectsMu.RLock()
+	defer s.projectsMu.RUnlock()
+
+	if len(s.projects) == 0 {
+		return nil
+	}
+	return s.projects[0]
+}
+
+func (s *Server) allProjects() []*project {
+	s.projectsMu.RLock()
+	defer s.projectsMu.RUnlock()
+
+	return append([]*project(nil), s.projects...)
+}
+
+// setProjects replaces the projects and applies the server wide settings of the primary one. Projects only
+// naming a container get their providers detected, through the configured Docker daemon. The projects kept
+// by a reload are already prepared and analyzed, their configuration is left untouched.
+func (s *Server) setProjects(projects []*project) {
+	if len(projects) > 0 {
+		applyContainerConfig(projects[0].serverConfig)
+	}
+	for _, p := range projects {
+		p.prepareOnce.Do(func() {
+			diagnostics.AutoConfigure(context.Background(), p.serverConfig, p.root)
+			diagnostics.SplitPhpDocErrors(p.serverConfig)
+			s.setBackendNotifier(p.serverConfig)
+		})
+	}
+
+	s.projectsMu.Lock()
+	s.projects = projects
+	s.projectsMu.Unlock()
+
+	if len(projects) > 1 {
+		roots := make([]string, 0, len(projects))
+		for _, p := range projects {
+			roots = append(roots, p.root)
+		}
+		log.Printf("%s%s Monorepo mode, project roots: %s", logging.LogTagLSP, logging.LogTagServer, strings.Join(roots, ", "))
+	}
+}
+
+// setBackendNotifier tells the user whenever a provider of the configuration switches to another execution
+// backend
+func (s *Server) setBackendNotifier(serverConfig *config.Config) {
+	for id, providerConfig := range serverConfig.DiagnosticsProviders {
+		providerConfig.BackendNotifier = s.notifyBackendChange
+		serverConfig.DiagnosticsProviders[id] = providerConfig
+	}
+}
+
+func (s *Server) notifyBackendChange(message string) {
+	s.showWindowMessage(context.Background(), protocol.MessageTypeInfo, message)
+}
+
+// loadProviders preloads the diagnostics and formatting providers of every project
+func (s *Server) loadProviders() {
+	for _, p := range s.allProjects() {
+		_ = s.loadDiagnosticsProviders(p)
+		_ = p.loadFormattingProviders()
+	}
+}
+
+func (s *Server) loadDiagnosticsProviders(p *project) []diagnostics.DiagnosticsProvider {
+	return p.loadDiagnosticsProviders(func(err error) {
+		message := fmt.Sprintf("%v", err)
+		if len(s.allProjects()) > 1 {
+			message = fmt.Sprintf("%s: %v", p.root, err)
+		}
+		s.showWindowMessage(context.Background(), protocol.MessageTypeError, message)
+	})
+}
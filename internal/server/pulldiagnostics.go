@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// The protocol package predates the pull diagnostics of LSP 3.17
+const (
+	methodTextDocumentDiagnostic     = "textDocument/diagnostic"
+	methodWorkspaceDiagnosticRefresh = "workspace/diagnostic/refresh"
+
+	documentDiagnosticReportFull      = "full"
+	documentDiagnosticReportUnchanged = "unchanged"
+)
+
+// pullDiagnosticsCapabilities are the client capabilities of the pull diagnostics
+type pullDiagnosticsCapabilities struct {
+	Capabilities struct {
+		TextDocument struct {
+			Diagnostic *struct{} `json:"diagnostic"`
+		} `json:"textDocument"`
+		Workspace struct {
+			Diagnostics *struct {
+				RefreshSupport bool `json:"refreshSupport"`
+			} `json:"diagnostics"`
+		} `json:"workspace"`
+	} `json:"capabilities"`
+}
+
+type diagnosticOptions struct {
+	Identifier string `json:"identifier,omitempty"`
+	// The phpstan results of a file depend on the other files
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+type documentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	Identifier       string                          `json:"identifier,omitempty"`
+	PreviousResultID string                          `json:"previousResultId,omitempty"`
+}
+
+type fullDocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId"`
+	Items    []protocol.Diagnostic `json:"items"`
+}
+
+type unchangedDocumentDiagnosticReport struct {
+	Kind     string `json:"kind"`
+	ResultID string `json:"resultId"`
+}
+
+// pulledDiagnostics is the last result of an open document, stale once the document changed
+type pulledDiagnostics struct {
+	resultID    string
+	diagnostics []protocol.Diagnostic
+	stale       bool
+}
+
+// setPullDiagnosticsSupport enables the pull diagnostics when the client supports them
+func (s *Server) setPullDiagnosticsSupport(rawParams json.RawMessage) {
+	var capabilities pullDiagnosticsCapabilities
+	if err := json.Unmarshal(rawParams, &capabilities); err != nil {
+		return
+	}
+
+	s.pullDiagnosticsSupported = capabilities.Capabilities.TextDocument.Diagnostic != nil
+	if diagnosticsCapabilities := capabilities.Capabilities.Workspace.Diagnostics; diagnosticsCapabilities != nil {
+		s.diagnosticRefreshSupported = diagnosticsCapabilities.RefreshSupport
+	}
+}
+
+// pullsDiagnostics reports whether the client pulls the diagnostics of the document: the open ones, the
+// diagnostics of the closed files are still published
+func (s *Server) pullsDiagnostics(uri protocol.DocumentURI) bool {
+	if !s.pullDiagnosticsSupported {
+		return false
+	}
+	_, open := s.getDocumentContent(uri)
+	return open
+}
+
+// markPulledStale makes the next pull of the document analyze it, instead of analyzing it on every change
+func (s *Server) markPulledStale(uri protocol.DocumentURI) {
+	s.pullMu.Lock()
+	defer s.pullMu.Unlock()
+
+	if result, exists := s.pullResults[uri]; exists {
+		result.stale = true
+	}
+}
+
+func (s *Server) deletePulledDiagnostics(uri protocol.DocumentURI) {
+	s.pullMu.Lock()
+	defer s.pullMu.Unlock()
+
+	delete(s.pullResults, uri)
+}
+
+// storePulledDiagnostics keeps the diagnostics for the next pull under a new result id
+func (s *Server) storePulledDiagnostics(uri protocol.DocumentURI, diags []protocol.Diagnostic) string {
+	s.pullMu.Lock()
+	defer s.pullMu.Unlock()
+
+	s.pullResultSeq++
+	resultID := strconv.FormatUint(s.pullResultSeq, 10)
+	s.pullResults[uri] = &pulledDiagnostics{resultID: resultID, diagnostics: utils.EnsureDiagnosticsArray(diags)}
+	return resultID
+}
+
+// updatePulledDiagnostics keeps the diagnostics of an analysis the client didn't pull (save, watchers) and
+// asks the client to pull them again
+func (s *Server) updatePulledDiagnostics(uri protocol.DocumentURI, diags []protocol.Diagnostic) {
+	s.storePulledDiagnostics(uri, diags)
+	if !s.diagnosticRefreshSupported {
+		return
+	}
+
+	go func() {
+		if _, err := s.conn.Call(context.Background(), methodWorkspaceDiagnosticRefresh, nil, nil); err != nil {
+			log.Printf("%s%s Failed to refresh the pulled diagnostics: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		}
+	}()
+}
+
+// handleDocumentDiagnostic replies with the diagnostics of the document, analyzing it when it changed since
+// the last result. A result the client already holds is reported unchanged.
+func (s *Server) handleDocumentDiagnostic(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params documentDiagnosticParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return reply(ctx, nil, err)
+	}
+
+	uri := params.TextDocument.URI
+	if !s.isSupportedDocument(uri) || utils.PathToURI(diagnostics.PublishedFile(uri.Filename())) != uri {
+		return reply(ctx, fullDocumentDiagnosticReport{Kind: documentDiagnosticReportFull, Items: []protocol.Diagnostic{}}, nil)
+	}
+
+	s.pullMu.Lock()
+	result, exists := s.pullResults[uri]
+	if exists && !result.stale {
+		resultID, items := result.resultID, result.diagnostics
+		s.pullMu.Unlock()
+
+		if params.PreviousResultID == resultID {
+			return reply(ctx, unchangedDocumentDiagnosticReport{Kind: documentDiagnosticReportUnchanged, ResultID: resultID}, nil)
+		}
+		return reply(ctx, fullDocumentDiagnosticReport{Kind: documentDiagnosticReportFull, ResultID: resultID, Items: items}, nil)
+	}
+	s.pullMu.Unlock()
+
+	// Pending and running analyses of the document are replaced by this one
+	s.diagMu.Lock()
+	if timer, exists := s.diagTimers[uri]; exists {
+		timer.Stop()
+		delete(s.diagTimers, uri)
+	}
+	gen, analysisCtx := s.supersedeAnalysis(ctx, uri)
+	s.diagMu.Unlock()
+
+	go func() {
+		defer s.releaseAnalysis(uri, gen)
+
+		var diags []protocol.Diagnostic
+		<-s.analysisScheduler.Submit(analysisCtx, scheduler.PriorityChange, func(jobCtx context.Context) {
+			diags = s.collectDiagnostics(jobCtx, uri.Filename(), analysisRun{})
+		})
+		if analysisCtx.Err() != nil {
+			_ = reply(ctx, nil, fmt.Errorf("diagnostics of %s cancelled: %w", uri, analysisCtx.Err()))
+			return
+		}
+
+		s.setPublished(uri.Filename(), diags)
+		s.refreshCodeLenses(uri)
+		s.refreshInlayHints(uri)
+		resultID := s.storePulledDiagnostics(uri, diags)
+		s.statusDiagnosticsPublished(uri, len(diags))
+		s.notifyStatus(context.Background())
+
+		_ = reply(ctx, fullDocumentDiagnosticReport{Kind: documentDiagnosticReportFull, ResultID: resultID, Items: utils.EnsureDiagnosticsArray(diags)}, nil)
+	}()
+
+	return nil
+}
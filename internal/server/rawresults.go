@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// LspRequestRawResults returns the parsed tool output of the last analysis of a document, per provider
+const LspRequestRawResults = config.Name + "/rawResults"
+
+// RawResultsParams selects the document
+type RawResultsParams struct {
+	URI protocol.DocumentURI `json:"uri"`
+}
+
+// RawResults holds the last parsed output of every provider which analyzed the document, by provider id
+type RawResults struct {
+	URI       protocol.DocumentURI         `json:"uri"`
+	Providers map[string]ProviderRawResult `json:"providers"`
+}
+
+// ProviderRawResult is the parsed output of a tool, in the structure of the provider (e.g. the phpstan
+// JSON report, or the php-cs-fixer report with the diff of every rule)
+type ProviderRawResult struct {
+	Provider   string      `json:"provider"`
+	AnalyzedAt time.Time   `json:"analyzedAt"`
+	Result     interface{} `json:"result"`
+}
+
+// setRawResult keeps the parsed tool output of the open documents, the closed ones aren't shown anywhere
+func (s *Server) setRawResult(uri protocol.DocumentURI, providerId string, providerName string, result interface{}) {
+	if _, open := s.getDocumentContent(uri); !open || result == nil {
+		return
+	}
+
+	s.rawResultsMu.Lock()
+	defer s.rawResultsMu.Unlock()
+
+	if s.rawResults[uri] == nil {
+		s.rawResults[uri] = make(map[string]ProviderRawResult)
+	}
+	s.rawResults[uri][providerId] = ProviderRawResult{Provider: providerName, AnalyzedAt: time.Now(), Result: result}
+}
+
+func (s *Server) deleteRawResults(uri protocol.DocumentURI) {
+	s.rawResultsMu.Lock()
+	defer s.rawResultsMu.Unlock()
+
+	delete(s.rawResults, uri)
+}
+
+// handleRawResultsRequest replies with the stored results, tools aren't run again: documents not
+// analyzed since they were opened have no results
+func (s *Server) handleRawResultsRequest(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params RawResultsParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return reply(ctx, nil, err)
+	}
+	if params.URI == "" {
+		return reply(ctx, nil, fmt.Errorf("missing document URI"))
+	}
+
+	s.rawResultsMu.Lock()
+	results := RawResults{URI: params.URI, Providers: make(map[string]ProviderRawResult, len(s.rawResults[params.URI]))}
+	for id, result := range s.rawResults[params.URI] {
+		results.Providers[id] = result
+	}
+	s.rawResultsMu.Unlock()
+
+	return reply(ctx, results, nil)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// reloadConfig discovers the project roots again, reloads their configuration files, rebuilds the
+// providers and re-analyzes all open documents so the editor state converges to the new configuration.
+// The previous configuration of a project is kept when the new one cannot be loaded.
+func (s *Server) reloadConfig(ctx context.Context) {
+	log.Printf("%s%s Reloading configuration from %s", logging.LogTagLSP, logging.LogTagServer, strings.Join(s.workspaceFolders, ", "))
+
+	roots := discoverProjectRoots(ctx, s.workspaceFolders, s.configFiles)
+	projects := loadProjects(roots, s.configFiles, s.allProjects(), func(root string, err error) {
+		log.Printf("%s%s Failed to reload config of %s: %v", logging.LogTagLSP, logging.LogTagServer, root, err)
+		s.showWindowMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("Failed to reload configuration, keeping the previous one: %v", err))
+	})
+	if len(projects) == 0 {
+		s.showWindowMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("No %s found anymore, keeping the previous configuration", strings.Join(s.configFiles.Names(), " or ")))
+		return
+	}
+
+	s.setProjects(projects)
+
+	s.status.mu.Lock()
+	s.status.providerErrors = make(map[string]map[string]string)
+	s.status.mu.Unlock()
+
+	// Providers may no longer be manual, or enabled at all
+	s.clearManualDiagnostics()
+	s.resetBreakers()
+	s.stopWatchers()
+
+	s.warmMu.Lock()
+	s.warmResults = make(map[protocol.DocumentURI]warmResult)
+	s.warmMu.Unlock()
+
+	s.checkToolConfigs()
+	s.registerFileWatchers()
+	s.rebuildProviders()
+}
+
+// rebuildProviders reloads the providers from the current configuration, starts their watch commands
+// and schedules diagnostics for every open document
+func (s *Server) rebuildProviders() {
+	go func() {
+		s.loadProviders()
+		s.startWatchers()
+
+		for _, uri := range s.openDocuments() {
+			if s.isSupportedDocument(uri) {
+				s.scheduleDiagnostics(uri, scheduler.PriorityWatcher)
+			}
+		}
+	}()
+}
+
+func (s *Server) openDocuments() []protocol.DocumentURI {
+	s.docMu.RLock()
+	defer s.docMu.RUnlock()
+
+	uris := make([]protocol.DocumentURI, 0, len(s.documents))
+	for uri := range s.documents {
+		uris = append(uris, uri)
+	}
+	return uris
+}
+
+func (s *Server) handleReloadConfigCommand(ctx context.Context, reply jsonrpc2.Replier) error {
+	s.reloadConfig(ctx)
+
+	return reply(ctx, nil, nil)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// ruleDiffCodeActions offers a "Fix <rule>" quick fix for every php-cs-fixer diagnostic, applying the change
+// of the rule diff kept by the last analysis on the lines of the diagnostic. php-cs-fixer isn't run again, so
+// documents changed since the analysis get no quick fix.
+func (s *Server) ruleDiffCodeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	uri := params.TextDocument.URI
+
+	content, open := s.getDocumentContent(uri)
+	if !open {
+		return actions
+	}
+	rawResult, ok := s.phpCsFixerRawResult(uri)
+	if !ok {
+		return actions
+	}
+
+	// The edits of a rule, computed once per request
+	ruleEdits := make(map[string][]protocol.TextEdit)
+	for _, diagnostic := range params.Context.Diagnostics {
+		rule, ok := diagnostic.Code.(string)
+		if !ok || diagnostic.Source != diagnostics.PhpCsFixerProviderName {
+			continue
+		}
+
+		edits, computed := ruleEdits[rule]
+		if !computed {
+			edits = ruleDiffEdits(content, rawResult.RuleDiffs[rule])
+			ruleEdits[rule] = edits
+		}
+
+		for _, edit := range edits {
+			if !editTouchesLine(edit, diagnostic.Range.Start.Line) {
+				continue
+			}
+
+			actions = append(actions, protocol.CodeAction{
+				Title:       fmt.Sprintf("Fix %s", rule),
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{diagnostic},
+				IsPreferred: true,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: {edit}},
+				},
+			})
+			break
+		}
+	}
+
+	return actions
+}
+
+// phpCsFixerRawResult returns the php-cs-fixer report kept by the last analysis of the document
+func (s *Server) phpCsFixerRawResult(uri protocol.DocumentURI) (diagnostics.PhpCsFixerRawResult, bool) {
+	s.rawResultsMu.Lock()
+	defer s.rawResultsMu.Unlock()
+
+	rawResult, ok := s.rawResults[uri][diagnostics.PhpCsFixerProviderId].Result.(diagnostics.PhpCsFixerRawResult)
+	return rawResult, ok
+}
+
+// ruleDiffEdits returns the edits of the rule diff, one per group of changed lines, or none when the diff
+// doesn't match the content anymore
+func ruleDiffEdits(content string, diff string) []protocol.TextEdit {
+	if diff == "" || !utils.UnifiedDiffApplies(content, diff) {
+		return nil
+	}
+
+	fixedContent, err := utils.ApplyUnifiedDiff(content, diff)
+	if err != nil {
+		return nil
+	}
+	return utils.LineEdits(content, fixedContent)
+}
+
+// editTouchesLine reports whether the edit changes the line, insertions touching the line they are
+// inserted before
+func editTouchesLine(edit protocol.TextEdit, line uint32) bool {
+	last := edit.Range.End.Line
+	if last > edit.Range.Start.Line {
+		last--
+	}
+	return edit.Range.Start.Line <= line && line <= last
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// LspRequestRuleDoc returns the full documentation of a rule reported in diagnostics
+const LspRequestRuleDoc = config.Name + "/ruleDoc"
+
+// RuleDocParams selects the rule to document. The provider id and document URI are optional, the
+// document selecting the project in a monorepo.
+type RuleDocParams struct {
+	Rule     string               `json:"rule"`
+	Provider string               `json:"provider,omitempty"`
+	URI      protocol.DocumentURI `json:"uri,omitempty"`
+}
+
+// RuleDoc is the Markdown documentation of a rule, meant to be opened as a virtual document
+type RuleDoc struct {
+	Rule     string `json:"rule"`
+	Provider string `json:"provider"`
+	Markdown string `json:"markdown"`
+}
+
+// handleRuleDocRequest asks the providers of the project to explain the rule, the first one knowing it
+// wins. Tools may be slow to describe a rule, the reply is sent once the explanation is ready.
+func (s *Server) handleRuleDocRequest(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params RuleDocParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling %s params: %v", logging.LogTagLSP, logging.LogTagServer, req.Method(), err)
+		return reply(ctx, nil, err)
+	}
+	if params.Rule == "" {
+		return reply(ctx, nil, fmt.Errorf("missing rule"))
+	}
+
+	p := s.primaryProject()
+	if params.URI != "" {
+		p = s.projectFor(params.URI.Filename())
+	}
+	if p == nil {
+		return reply(ctx, nil, fmt.Errorf("no configuration loaded"))
+	}
+
+	providers := append([]diagnostics.DiagnosticsProvider(nil), s.loadDiagnosticsProviders(p)...)
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Id() < providers[j].Id() })
+
+	go func() {
+		var reasons []string
+		for _, provider := range providers {
+			if params.Provider != "" && provider.Id() != params.Provider {
+				continue
+			}
+			explainer, ok := provider.(diagnostics.RuleExplainer)
+			if !ok {
+				continue
+			}
+
+			explanation, err := explainer.ExplainRule(ctx, params.Rule)
+			if err != nil {
+				reasons = append(reasons, err.Error())
+				continue
+			}
+
+			_ = reply(ctx, RuleDoc{
+				Rule:     params.Rule,
+				Provider: provider.Id(),
+				Markdown: diagnostics.RuleDocMarkdown(provider.Name(), params.Rule, explanation),
+			}, nil)
+			return
+		}
+
+		if len(reasons) == 0 {
+			_ = reply(ctx, nil, fmt.Errorf("no enabled provider can explain rules"))
+			return
+		}
+		_ = reply(ctx, nil, fmt.Errorf("unknown rule %s: %s", params.Rule, strings.Join(reasons, "; ")))
+	}()
+
+	return nil
+}
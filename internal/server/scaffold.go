@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// checkToolConfigs offers to scaffold the configuration files of the enabled providers which don't exist,
+// once per session and provider. The files can't be checked in buffer-only mode.
+func (s *Server) checkToolConfigs() {
+	if s.bufferOnly {
+		return
+	}
+	for _, p := range s.allProjects() {
+		for id, providerConfig := range p.serverConfig.DiagnosticsProviders {
+			if !providerConfig.Enabled || providerConfig.ConfigFile == "" {
+				continue
+			}
+			if _, ok := diagnostics.ConfigScaffold(id, p.root); !ok {
+				continue
+			}
+
+			hostPath, inside := utils.HostToolPath(p.root, providerConfig.ConfigFile)
+			if !inside {
+				// Only the container can tell whether the file exists
+				log.Printf("%s%s Configuration file %s of %s is outside %s, not checked", logging.LogTagLSP, logging.LogTagServer, providerConfig.ConfigFile, id, p.root)
+				continue
+			}
+			if _, err := os.Stat(hostPath); err == nil {
+				continue
+			}
+
+			log.Printf("%s%s Configuration file %s of %s not found in %s", logging.LogTagLSP, logging.LogTagServer, providerConfig.ConfigFile, id, p.root)
+
+			key := p.root + "/" + id + "/missing-config"
+			s.reportedErrorsMu.Lock()
+			reported := s.reportedErrors[key]
+			s.reportedErrors[key] = true
+			s.reportedErrorsMu.Unlock()
+
+			if !reported {
+				go s.offerConfigScaffold(p, id, providerConfig.ConfigFile, hostPath)
+			}
+		}
+	}
+}
+
+// offerConfigScaffold asks the user whether to create the missing configuration file
+func (s *Server) offerConfigScaffold(p *project, providerId string, configFile string, hostPath string) {
+	action := protocol.MessageActionItem{Title: fmt.Sprintf("Create %s", filepath.Base(hostPath))}
+
+	var chosen *protocol.MessageActionItem
+	_, err := s.conn.Call(context.Background(), protocol.MethodWindowShowMessageRequest, &protocol.ShowMessageRequestParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: fmt.Sprintf("%s configuration file %s not found", providerId, configFile),
+		Actions: []protocol.MessageActionItem{action},
+	}, &chosen)
+	if err != nil {
+		log.Printf("%s%s Failed to offer the %s configuration scaffold: %v", logging.LogTagLSP, logging.LogTagServer, providerId, err)
+		return
+	}
+	if chosen == nil || chosen.Title != action.Title {
+		return
+	}
+
+	if err := s.scaffoldConfig(p, providerId, hostPath); err != nil {
+		s.showWindowMessage(context.Background(), protocol.MessageTypeError, err.Error())
+	}
+}
+
+// scaffoldConfig writes the default configuration of the provider's tool, never replacing an existing
+// file, and analyzes the open documents of the project again
+func (s *Server) scaffoldConfig(p *project, providerId string, hostPath string) error {
+	scaffold, ok := diagnostics.ConfigScaffold(providerId, p.root)
+	if !ok {
+		return fmt.Errorf("no configuration scaffold for %s", providerId)
+	}
+
+	file, err := os.OpenFile(hostPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", hostPath, err)
+	}
+	if _, err := file.WriteString(scaffold); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", hostPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hostPath, err)
+	}
+
+	log.Printf("%s%s Created %s for %s", logging.LogTagLSP, logging.LogTagServer, hostPath, providerId)
+	s.showWindowMessage(context.Background(), protocol.MessageTypeInfo, fmt.Sprintf("Created %s, adjust it to the project", hostPath))
+
+	for _, uri := range s.openDocuments() {
+		if s.isSupportedDocument(uri) && s.projectFor(uri.Filename()) == p {
+			s.scheduleDiagnostics(uri, scheduler.PriorityWatcher)
+		}
+	}
+	return nil
+}
+
+// handleScaffoldConfigCommand writes the default configuration file of the provider given as argument, at
+// its configFile or where the tool looks for it. The optional second argument is a document URI selecting
+// the project in a monorepo.
+func (s *Server) handleScaffoldConfigCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	if len(arguments) == 0 {
+		return reply(ctx, nil, fmt.Errorf("missing provider argument"))
+	}
+	providerId, ok := arguments[0].(string)
+	if !ok || providerId == "" {
+		return reply(ctx, nil, fmt.Errorf("invalid provider argument: %v", arguments[0]))
+	}
+	if s.bufferOnly {
+		return reply(ctx, nil, fmt.Errorf("configuration files can't be created in buffer-only mode"))
+	}
+
+	p := s.primaryProject()
+	if len(arguments) > 1 {
+		if uri, ok := arguments[1].(string); ok && uri != "" {
+			p = s.projectFor(protocol.DocumentURI(uri).Filename())
+		}
+	}
+	if p == nil {
+		return reply(ctx, nil, fmt.Errorf("no configuration loaded"))
+	}
+
+	hostPath := filepath.Join(p.root, diagnostics.DefaultConfigFiles[providerId])
+	if configFile := p.serverConfig.DiagnosticsProviders[providerId].ConfigFile; configFile != "" {
+		var inside bool
+		if hostPath, inside = utils.HostToolPath(p.root, configFile); !inside {
+			return reply(ctx, nil, fmt.Errorf("configuration file %s of %s is outside the project", configFile, providerId))
+		}
+	}
+
+	if err := s.scaffoldConfig(p, providerId, hostPath); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	return reply(ctx, hostPath, nil)
+}
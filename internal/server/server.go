@@ -10,10 +10,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cristianradulescu/php-diagls/internal/cache"
 	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/container"
 	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
-	"github.com/cristianradulescu/php-diagls/internal/formatting"
 	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
 	"github.com/cristianradulescu/php-diagls/internal/utils"
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
@@ -22,49 +24,174 @@ import (
 const (
 	diagnosticsDebounceInterval = 300 * time.Millisecond
 	formattingDebounceInterval  = 100 * time.Millisecond
+
+	// Number of analyses running at the same time across all priority tiers
+	maxConcurrentAnalyses = 4
+
+	// Window messages shown per minute, the extra ones are logged and reported as a count
+	maxWindowMessagesPerMinute = 5
+
+	// Number of lines at the top of a file searched for generated content markers
+	generatedMarkerScanLines = 10
 )
 
 // Server represents the Language Server Protocol (LSP) server
 type Server struct {
-	conn         jsonrpc2.Conn
-	serverConfig *config.Config
+	conn jsonrpc2.Conn
+
+	// Workspace folders sent on initialize, searched for project roots
+	workspaceFolders []string
+
+	// Configuration files searched in the project roots, from --config or the configFiles initialization option
+	configFiles config.Files
+	// --config was given, it wins over the initialization option
+	configFlag bool
 
-	diagnosticsProviders []diagnostics.DiagnosticsProvider
-	formattingProviders  []formatting.FormattingProvider
+	// One project per configuration file found in the workspace folders
+	projectsMu sync.RWMutex
+	projects   []*project
 
 	// In-memory document cache for synchronized content
-	docMu     sync.RWMutex
-	documents map[protocol.DocumentURI]string
+	docMu             sync.RWMutex
+	documents         map[protocol.DocumentURI]string
+	documentLanguages map[protocol.DocumentURI]string
+	// Documents changed since they were opened or last saved
+	dirtyDocuments map[protocol.DocumentURI]bool
 
 	// Debounce for diagnostics (per-file) with last-wins strategy
 	diagMu     sync.Mutex
 	diagTimers map[protocol.DocumentURI]*time.Timer
 	diagGen    map[protocol.DocumentURI]uint64
+	// Cancels the analysis of the latest generation, see supersedeAnalysis
+	diagCancels map[protocol.DocumentURI]context.CancelFunc
+	// Pending runs of the save providers with a debounce, restarted by every save of the file
+	saveTimers map[protocol.DocumentURI]*time.Timer
+
+	// Results of the last run of the manual providers, per file
+	manualMu      sync.Mutex
+	manualResults map[protocol.DocumentURI]map[string][]protocol.Diagnostic
+
+	// Parsed tool output of the last analysis of the open documents, per provider id
+	rawResultsMu sync.Mutex
+	rawResults   map[protocol.DocumentURI]map[string]ProviderRawResult
 
 	// Debounce for formatting (per-file) with last-wins strategy
 	fmtMu     sync.Mutex
 	fmtTimers map[protocol.DocumentURI]*time.Timer
 	fmtGen    map[protocol.DocumentURI]uint64
+
+	// Runs analyses by priority tier so background work never delays the edited file
+	analysisScheduler *scheduler.Scheduler
+
+	// Provider errors already shown to the user, by provider and error class
+	reportedErrorsMu sync.Mutex
+	reportedErrors   map[string]bool
+
+	// Circuit breakers of the providers failing repeatedly, by project root and provider name
+	breakersMu sync.Mutex
+	breakers   map[string]*providerBreaker
+
+	// Caps the window messages so a pathological state can't flood the editor
+	messageLimiter *utils.MessageLimiter
+
+	// Diagnostics last published per file, by file path
+	publishedMu sync.Mutex
+	published   map[string][]protocol.Diagnostic
+
+	// Published diagnostics and files last opened, kept across restarts when the workspace has a cache directory
+	lastKnown   *cache.LastKnown
+	recentFiles *cache.RecentFiles
+
+	// Results of the analyses run ahead of the first didOpen of the recent files
+	warmMu      sync.Mutex
+	warmResults map[protocol.DocumentURI]warmResult
+
+	// Providers running their tool in watch mode, by project root and provider name
+	watchesMu sync.Mutex
+	watches   map[string]*providerWatch
+
+	// Aggregated analysis status reported to editor status bars
+	status *statusTracker
+
+	// Client previews annotated workspace edits
+	changeAnnotationsSupported bool
+
+	// Client requests the code lenses again on workspace/codeLens/refresh
+	codeLensRefreshSupported bool
+	// Client requests the inlay hints again on workspace/inlayHint/refresh
+	inlayHintRefreshSupported bool
+
+	// Client watches the files registered by the server, see registerFileWatchers
+	watchedFilesDynamicRegistration bool
+	fileWatchersMu                  sync.Mutex
+	fileWatchersRegistered          bool
+
+	// The server doesn't share the filesystem of the editor, documents are only known from their buffers
+	bufferOnly bool
+
+	// Locale of the client user interface, selecting the message catalog
+	locale string
+
+	// Request handling wrapped by the middlewares, see Use
+	middlewares []Middleware
+	handler     jsonrpc2.Handler
+
+	// Cancels the requests being handled on $/cancelRequest, by request id
+	requestsMu     sync.Mutex
+	requestCancels map[jsonrpc2.ID]context.CancelFunc
+
+	// Diagnostics of the open documents pulled by the client (textDocument/diagnostic), by URI
+	pullDiagnosticsSupported   bool
+	diagnosticRefreshSupported bool
+	pullMu                     sync.Mutex
+	pullResults                map[protocol.DocumentURI]*pulledDiagnostics
+	pullResultSeq              uint64
+
+	// Server initiated background jobs reported as work done progress
+	workDoneProgressSupported bool
+	progressMu                sync.Mutex
+	progressJobs              map[string]context.CancelFunc
 }
 
 // New creates a new LSP server instance
 func New(conn jsonrpc2.Conn) *Server {
 	s := &Server{
-		conn:         conn,
-		serverConfig: &config.Config{},
-		documents:    make(map[protocol.DocumentURI]string),
-		diagTimers:   make(map[protocol.DocumentURI]*time.Timer),
-		diagGen:      make(map[protocol.DocumentURI]uint64),
-		fmtTimers:    make(map[protocol.DocumentURI]*time.Timer),
-		fmtGen:       make(map[protocol.DocumentURI]uint64),
+		conn:              conn,
+		documents:         make(map[protocol.DocumentURI]string),
+		documentLanguages: make(map[protocol.DocumentURI]string),
+		dirtyDocuments:    make(map[protocol.DocumentURI]bool),
+		diagTimers:        make(map[protocol.DocumentURI]*time.Timer),
+		diagGen:           make(map[protocol.DocumentURI]uint64),
+		diagCancels:       make(map[protocol.DocumentURI]context.CancelFunc),
+		saveTimers:        make(map[protocol.DocumentURI]*time.Timer),
+		manualResults:     make(map[protocol.DocumentURI]map[string][]protocol.Diagnostic),
+		rawResults:        make(map[protocol.DocumentURI]map[string]ProviderRawResult),
+		warmResults:       make(map[protocol.DocumentURI]warmResult),
+		published:         make(map[string][]protocol.Diagnostic),
+		fmtTimers:         make(map[protocol.DocumentURI]*time.Timer),
+		fmtGen:            make(map[protocol.DocumentURI]uint64),
+		analysisScheduler: scheduler.New(maxConcurrentAnalyses),
+		reportedErrors:    make(map[string]bool),
+		breakers:          make(map[string]*providerBreaker),
+		watches:           make(map[string]*providerWatch),
+		messageLimiter:    utils.NewMessageLimiter(maxWindowMessagesPerMinute, time.Minute),
+		status:            newStatusTracker(),
+		progressJobs:      make(map[string]context.CancelFunc),
+		requestCancels:    make(map[jsonrpc2.ID]context.CancelFunc),
+		pullResults:       make(map[protocol.DocumentURI]*pulledDiagnostics),
 	}
 
+	s.Use(RecoveryMiddleware, LoggingMiddleware)
+
 	return s
 }
 
+// Handle handles a request through the middlewares
 func (s *Server) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
-	log.Printf("%s%s Received request: %s", logging.LogTagLSP, logging.LogTagServer, req.Method())
+	return s.handler(ctx, reply, req)
+}
 
+func (s *Server) dispatch(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
 	switch req.Method() {
 	case protocol.MethodInitialize:
 		return s.handleInitialize(ctx, reply, req)
@@ -82,6 +209,20 @@ func (s *Server) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc
 		return s.handleDidSave(ctx, reply, req)
 	case protocol.MethodTextDocumentFormatting:
 		return s.handleDocumentFormatting(ctx, reply, req)
+	case protocol.MethodTextDocumentRangeFormatting:
+		return s.handleDocumentRangeFormatting(ctx, reply, req)
+	case protocol.MethodTextDocumentWillSaveWaitUntil:
+		return s.handleWillSaveWaitUntil(ctx, reply, req)
+	case protocol.MethodTextDocumentCodeLens:
+		return s.handleCodeLens(ctx, reply, req)
+	case protocol.MethodTextDocumentCodeAction:
+		return s.handleCodeAction(ctx, reply, req)
+	case protocol.MethodTextDocumentHover:
+		return s.handleHover(ctx, reply, req)
+	case methodTextDocumentDiagnostic:
+		return s.handleDocumentDiagnostic(ctx, reply, req)
+	case methodTextDocumentInlayHint:
+		return s.handleInlayHint(ctx, reply, req)
 	case protocol.MethodWorkspaceDidChangeWatchedFiles:
 		return s.handleDidChangeWatchedFiles(ctx, reply, req)
 	case protocol.MethodShutdown:
@@ -90,6 +231,14 @@ func (s *Server) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc
 		return s.handleExit(ctx, reply, req)
 	case protocol.MethodCancelRequest:
 		return s.handleCancelRequest(ctx, reply, req)
+	case protocol.MethodWorkDoneProgressCancel:
+		return s.handleWorkDoneProgressCancel(ctx, reply, req)
+	case LspRequestStatus:
+		return s.handleStatusRequest(ctx, reply, req)
+	case LspRequestRuleDoc:
+		return s.handleRuleDocRequest(ctx, reply, req)
+	case LspRequestRawResults:
+		return s.handleRawResultsRequest(ctx, reply, req)
 	default:
 		log.Printf("%s%s Unhandled method: %s", logging.LogTagLSP, logging.LogTagServer, req.Method())
 		return reply(ctx, nil, nil)
@@ -108,42 +257,148 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 
 	log.Printf("%s%s Client info: name=%s, version=%s", logging.LogTagLSP, logging.LogTagServer, params.ClientInfo.Name, params.ClientInfo.Version)
 
-	// Load configuration. Show warning if not found and exit
-	if !s.serverConfig.IsInitialized() {
-		// Determine project root from workspace folder URI or RootURI
-		projectRoot := ""
-		if len(params.WorkspaceFolders) > 0 && params.WorkspaceFolders[0].URI != "" {
-			projectRoot = utils.URIToPath(protocol.DocumentURI(params.WorkspaceFolders[0].URI))
-		} else if params.RootURI != "" {
-			projectRoot = utils.URIToPath(protocol.DocumentURI(params.RootURI))
-		} else {
-			if cwd, cwdErr := os.Getwd(); cwdErr == nil {
-				projectRoot = cwd
-			}
+	s.locale = params.Locale
+
+	if params.Capabilities.Window != nil {
+		s.workDoneProgressSupported = params.Capabilities.Window.WorkDoneProgress
+	}
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.WorkspaceEdit != nil {
+		s.changeAnnotationsSupported = workspace.WorkspaceEdit.DocumentChanges && workspace.WorkspaceEdit.ChangeAnnotationSupport != nil
+	}
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.CodeLens != nil {
+		s.codeLensRefreshSupported = workspace.CodeLens.RefreshSupport
+	}
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		s.watchedFilesDynamicRegistration = workspace.DidChangeWatchedFiles.DynamicRegistration
+	}
+	s.setPullDiagnosticsSupport(req.Params())
+	s.setInlayHintRefreshSupport(req.Params())
+
+	var options initializationOptions
+	if err := decodeInitializationOptions(params.InitializationOptions, &options); err != nil {
+		log.Printf("%s%s Invalid initializationOptions: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	} else {
+		s.setConfigFilesOption(ctx, options.ConfigFiles)
+		s.bufferOnly = options.BufferOnly
+		if s.bufferOnly {
+			log.Printf("%s%s Buffer-only mode, documents are not read from disk", logging.LogTagLSP, logging.LogTagServer)
 		}
-		serverConfig, err := s.serverConfig.LoadConfig(projectRoot)
-		if err != nil {
-			log.Printf("%s%s No config: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
+
+	// Load configuration. Show warning if not found and exit
+	if len(s.allProjects()) == 0 {
+		s.workspaceFolders = workspaceFolders(params)
+
+		roots := discoverProjectRoots(ctx, s.workspaceFolders, s.configFiles)
+		projects := loadProjects(roots, s.configFiles, nil, func(root string, err error) {
+			log.Printf("%s%s Invalid config in %s: %v", logging.LogTagLSP, logging.LogTagServer, root, err)
+		})
+		if len(projects) == 0 {
+			log.Printf("%s%s No config found in %s", logging.LogTagLSP, logging.LogTagServer, strings.Join(s.workspaceFolders, ", "))
 			os.Exit(0)
 		}
-		s.serverConfig = serverConfig
+		s.setProjects(projects)
+		s.loadWorkspaceState()
 
 		// Preload diagnostics and formatting providers once
-		_ = s.loadDiagnosticsProviders()
-		_ = s.loadFormattingProviders()
+		s.loadProviders()
+	}
+
+	return reply(ctx, s.initializeResult(), nil)
+}
+
+// WithConfigFiles searches the configuration files given with --config in the project roots, in place of
+// .php-diagls.json and of the configFiles initialization option
+func (s *Server) WithConfigFiles(files config.Files) *Server {
+	s.configFiles = files
+	s.configFlag = true
+	return s
+}
+
+// setConfigFilesOption searches the configuration files of the initialization option in the project roots,
+// unless --config was given
+func (s *Server) setConfigFilesOption(ctx context.Context, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	if s.configFlag {
+		log.Printf("%s%s Ignoring initializationOptions.configFiles %v, --config %v is used", logging.LogTagLSP, logging.LogTagServer, paths, s.configFiles.Names())
+		s.showWindowMessage(ctx, protocol.MessageTypeWarning, fmt.Sprintf("The configFiles initialization option is ignored, %s is set with --config", strings.Join(s.configFiles.Names(), ", ")))
+		return
+	}
+
+	files, err := config.ParseFiles(paths)
+	if err != nil {
+		log.Printf("%s%s Invalid initializationOptions: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		s.showWindowMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("Invalid configFiles initialization option: %v", err))
+		return
+	}
+	s.configFiles = files
+}
+
+// initializationOptions are the server settings a client can pass in the initialize request
+type initializationOptions struct {
+	// Configuration file paths relative to the project root, or one absolute path, searched instead of
+	// .php-diagls.json unless --config is given
+	ConfigFiles []string `json:"configFiles"`
+	// Never read the documents from disk, for clients not sharing their filesystem with the server
+	BufferOnly bool `json:"bufferOnly"`
+}
+
+// decodeInitializationOptions decodes the options, left untyped by the protocol package
+func decodeInitializationOptions(raw interface{}, options *initializationOptions) error {
+	if raw == nil {
+		return nil
 	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, options)
+}
 
-	resp := protocol.InitializeResult{
-		Capabilities: serverCapabilities(),
-		ServerInfo:   serverInfo(),
+// workspaceFolders returns the paths of all workspace folders, or the root URI (the working directory
+// as a last resort) for clients without workspace folders support
+func workspaceFolders(params protocol.InitializeParams) []string {
+	var folders []string
+	for _, folder := range params.WorkspaceFolders {
+		if folder.URI != "" {
+			folders = append(folders, utils.URIToPath(protocol.DocumentURI(folder.URI)))
+		}
+	}
+	if len(folders) > 0 {
+		return folders
 	}
 
-	return reply(ctx, resp, nil)
+	if params.RootURI != "" {
+		return []string{utils.URIToPath(protocol.DocumentURI(params.RootURI))}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		return []string{cwd}
+	}
+	return nil
+}
+
+// applyContainerConfig points the container commands to the configured Docker daemon and output cap
+func applyContainerConfig(serverConfig *config.Config) {
+	container.SetDockerSettings(container.DockerSettings{
+		Host:         serverConfig.Docker.Host,
+		TLSVerify:    serverConfig.Docker.TlsVerify,
+		CertPath:     serverConfig.Docker.CertPath,
+		Socket:       serverConfig.Docker.Socket,
+		PathMappings: serverConfig.Docker.PathMappings,
+	})
+	container.SetMaxOutputBytes(serverConfig.MaxOutputBytes)
 }
 
 func (s *Server) handleInitialized(ctx context.Context, reply jsonrpc2.Replier, _ jsonrpc2.Request) error {
 	log.Printf("%s%s Client initialized successfully", logging.LogTagLSP, logging.LogTagServer)
 
+	s.warmUpRecentFiles()
+	go s.startWatchers()
+	s.checkToolConfigs()
+	s.registerFileWatchers()
+
 	return reply(ctx, nil, nil)
 }
 
@@ -160,17 +415,73 @@ func (s *Server) handleExecuteCommand(ctx context.Context, reply jsonrpc2.Replie
 	case getFullLspCommandName(LspCommandNameShowConfig):
 		return s.handleShowConfigCommand(ctx, reply)
 
+	case getFullLspCommandName(LspCommandNameAnalyzeWorkspace):
+		return s.handleAnalyzeWorkspaceCommand(ctx, reply)
+
+	case getFullLspCommandName(LspCommandNameReloadConfig):
+		return s.handleReloadConfigCommand(ctx, reply)
+
+	case getFullLspCommandName(LspCommandNameResolvedConfig):
+		return s.handleResolvedConfigCommand(ctx, reply, params.Arguments)
+
+	case getFullLspCommandName(LspCommandNameAnalyzeFile):
+		return s.handleAnalyzeFileCommand(ctx, reply, params.Arguments)
+
+	case getFullLspCommandName(LspCommandNameFixAll):
+		return s.handleFixAllCommand(ctx, reply, params.Arguments)
+
+	case getFullLspCommandName(LspCommandNameScaffoldConfig):
+		return s.handleScaffoldConfigCommand(ctx, reply, params.Arguments)
+
+	case getFullLspCommandName(LspCommandNameOrganizeImports):
+		return s.handleOrganizeImportsCommand(ctx, reply, params.Arguments)
+
+	case getFullLspCommandName(LspCommandNameFormatWorkspace):
+		return s.handleFormatWorkspaceCommand(ctx, reply)
+
+	case getFullLspCommandName(LspCommandNameFormatWith):
+		return s.handleFormatWithCommand(ctx, reply, params.Arguments)
+
 	default:
 		return reply(ctx, nil, fmt.Errorf("unknown command: %s", params.Command))
 	}
 }
 
 func (s *Server) handleShowConfigCommand(ctx context.Context, reply jsonrpc2.Replier) error {
-	s.showWindowMessage(ctx, protocol.MessageTypeInfo, fmt.Sprintf("Current configuration: %s", s.serverConfig.RawData))
+	projects := s.allProjects()
+	if len(projects) == 1 {
+		s.showWindowMessage(ctx, protocol.MessageTypeInfo, fmt.Sprintf("Current configuration: %s", projects[0].serverConfig.RawData))
+		return reply(ctx, nil, nil)
+	}
+
+	for _, p := range projects {
+		s.showWindowMessage(ctx, protocol.MessageTypeInfo, fmt.Sprintf("Current configuration of %s: %s", p.root, p.serverConfig.RawData))
+	}
 
 	return reply(ctx, nil, nil)
 }
 
+// handleResolvedConfigCommand replies with the effective configuration, each value annotated with its source.
+// The optional argument is a document URI selecting the project in a monorepo.
+func (s *Server) handleResolvedConfigCommand(ctx context.Context, reply jsonrpc2.Replier, arguments []interface{}) error {
+	p := s.primaryProject()
+	if len(arguments) > 0 {
+		if uri, ok := arguments[0].(string); ok && uri != "" {
+			p = s.projectFor(protocol.DocumentURI(uri).Filename())
+		}
+	}
+	if p == nil {
+		return reply(ctx, nil, fmt.Errorf("no configuration loaded"))
+	}
+
+	resolved, err := p.serverConfig.Resolve()
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	return reply(ctx, resolved, nil)
+}
+
 func (s *Server) handleDidOpen(ctx context.Context, _ jsonrpc2.Replier, req jsonrpc2.Request) error {
 	var params protocol.DidOpenTextDocumentParams
 	if err := json.Unmarshal(req.Params(), &params); err != nil {
@@ -180,7 +491,23 @@ func (s *Server) handleDidOpen(ctx context.Context, _ jsonrpc2.Replier, req json
 	}
 
 	s.setDocumentContent(params.TextDocument.URI, params.TextDocument.Text)
-	s.scheduleDiagnostics(params.TextDocument.URI)
+	s.setDocumentLanguage(params.TextDocument.URI, string(params.TextDocument.LanguageID))
+	if !s.isSupportedDocument(params.TextDocument.URI) {
+		return nil
+	}
+
+	s.recentFiles.Touch(params.TextDocument.URI.Filename())
+	if s.publishWarmDiagnostics(ctx, params.TextDocument.URI, params.TextDocument.Text) {
+		return nil
+	}
+
+	s.publishStaleDiagnostics(ctx, params.TextDocument.URI)
+	if s.pullsDiagnostics(params.TextDocument.URI) {
+		// Analyzed once the client pulls the diagnostics
+		s.markPulledStale(params.TextDocument.URI)
+		return nil
+	}
+	s.scheduleDiagnostics(params.TextDocument.URI, scheduler.PriorityChange)
 
 	return nil
 }
@@ -196,9 +523,18 @@ func (s *Server) handleDidChange(ctx context.Context, _ jsonrpc2.Replier, req js
 	if len(params.ContentChanges) > 0 {
 		lastChange := params.ContentChanges[len(params.ContentChanges)-1]
 		s.setDocumentContent(params.TextDocument.URI, lastChange.Text)
+		s.setDocumentDirty(params.TextDocument.URI, true)
+	}
+
+	if !s.isSupportedDocument(params.TextDocument.URI) {
+		return nil
 	}
 
-	s.scheduleDiagnostics(params.TextDocument.URI)
+	if s.pullsDiagnostics(params.TextDocument.URI) {
+		s.markPulledStale(params.TextDocument.URI)
+		return nil
+	}
+	s.scheduleDiagnostics(params.TextDocument.URI, scheduler.PriorityChange)
 
 	return nil
 }
@@ -214,6 +550,12 @@ func (s *Server) handleDidSave(ctx context.Context, _ jsonrpc2.Replier, req json
 	if params.Text != "" {
 		s.setDocumentContent(params.TextDocument.URI, params.Text)
 	}
+	s.setDocumentDirty(params.TextDocument.URI, false)
+	s.refreshBuffers(params.TextDocument.URI.Filename())
+
+	if !s.isSupportedDocument(params.TextDocument.URI) {
+		return nil
+	}
 
 	s.scheduleDiagnosticsPriority(params.TextDocument.URI)
 
@@ -229,12 +571,29 @@ func (s *Server) handleDidChangeWatchedFiles(ctx context.Context, _ jsonrpc2.Rep
 	}
 
 	for _, change := range params.Changes {
-		if strings.HasSuffix(string(change.URI), ".php") {
+		if s.configFiles.IsConfigFile(change.URI.Filename()) {
+			s.reloadConfig(ctx)
+			continue
+		}
+		s.refreshBuffers(change.URI.Filename())
+		if s.reanalyzeProviderConfigChange(change.URI.Filename()) {
+			continue
+		}
+
+		if s.isSupportedDocument(change.URI) {
 			switch change.Type {
 			case protocol.FileChangeTypeChanged, protocol.FileChangeTypeCreated:
-				s.scheduleDiagnostics(change.URI)
+				s.scheduleDiagnostics(change.URI, scheduler.PriorityWatcher)
 			case protocol.FileChangeTypeDeleted:
-				s.publishDiagnostics(ctx, change.URI, []protocol.Diagnostic{})
+				// The results are stored and published on the document holding them, composer.json for
+				// composer.lock, under the URI the analyses use whatever the encoding of the client
+				publishedURI := utils.PathToURI(diagnostics.PublishedFile(change.URI.Filename()))
+				s.setManualDiagnostics(publishedURI, nil)
+				if publishedURI.Filename() != change.URI.Filename() {
+					s.scheduleDiagnostics(publishedURI, scheduler.PriorityWatcher)
+					continue
+				}
+				s.publishDiagnostics(ctx, publishedURI, []protocol.Diagnostic{})
 			}
 		}
 	}
@@ -250,8 +609,15 @@ func (s *Server) handleDidClose(ctx context.Context, _ jsonrpc2.Replier, req jso
 		return err
 	}
 
+	supported := s.isSupportedDocument(params.TextDocument.URI)
 	s.deleteDocumentContent(params.TextDocument.URI)
-	s.scheduleDiagnostics(params.TextDocument.URI)
+	s.deleteRawResults(params.TextDocument.URI)
+	s.deletePulledDiagnostics(params.TextDocument.URI)
+	if !supported {
+		return nil
+	}
+
+	s.scheduleDiagnostics(params.TextDocument.URI, scheduler.PriorityWatcher)
 
 	return nil
 }
@@ -259,6 +625,9 @@ func (s *Server) handleDidClose(ctx context.Context, _ jsonrpc2.Replier, req jso
 func (s *Server) handleShutdown(ctx context.Context, reply jsonrpc2.Replier, _ jsonrpc2.Request) error {
 	log.Printf("%s%s Performing cleanup before shutdown", logging.LogTagLSP, logging.LogTagServer)
 
+	s.saveWorkspaceState()
+	s.stopWatchers()
+
 	return reply(ctx, nil, nil)
 }
 
@@ -268,29 +637,56 @@ func (s *Server) handleExit(_ context.Context, _ jsonrpc2.Replier, _ jsonrpc2.Re
 	return s.conn.Close()
 }
 
-func (s *Server) handleCancelRequest(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
-	var params struct {
-		ID interface{} `json:"id"`
+func (s *Server) showWindowMessage(ctx context.Context, messageType protocol.MessageType, message string) {
+	allowed, suppressed := s.messageLimiter.Allow(time.Now())
+	if !allowed {
+		log.Printf("%s%s Window message suppressed: %s", logging.LogTagLSP, logging.LogTagServer, message)
+		// Report the suppressed messages once the limit allows it, unless another message does it first
+		if suppressed == 1 {
+			time.AfterFunc(s.messageLimiter.NextSlot(time.Now()), s.reportSuppressedMessages)
+		}
+		return
 	}
-	if err := json.Unmarshal(req.Params(), &params); err != nil {
-		log.Printf("%s%s Error unmarshaling cancel request params: %v", logging.LogTagLSP, logging.LogTagServer, err)
-		return err
+
+	if suppressed > 0 {
+		message = fmt.Sprintf("%s (%d more messages suppressed, see logs for details)", message, suppressed)
 	}
+	s.notifyWindowMessage(ctx, messageType, message)
+}
 
-	log.Printf("%s%s Client requested cancellation for request ID: %v", logging.LogTagLSP, logging.LogTagServer, params.ID)
-	// Note: The actual cancellation is handled by the jsonrpc2 library's context cancellation mechanism
-	// This handler acknowledges the cancel request - the running operation should detect ctx.Done()
-	return reply(ctx, nil, nil)
+// reportSuppressedMessages tells the user how many messages were held back since the last one shown
+func (s *Server) reportSuppressedMessages() {
+	suppressed := s.messageLimiter.TakeSuppressed(time.Now())
+	if suppressed == 0 {
+		return
+	}
+
+	s.notifyWindowMessage(context.Background(), protocol.MessageTypeWarning, fmt.Sprintf("%d messages suppressed, see logs for details", suppressed))
 }
 
-func (s *Server) showWindowMessage(ctx context.Context, messageType protocol.MessageType, message string) {
+func (s *Server) notifyWindowMessage(ctx context.Context, messageType protocol.MessageType, message string) {
 	params := &protocol.ShowMessageParams{Type: messageType, Message: message}
 	if err := s.conn.Notify(ctx, protocol.MethodWindowShowMessage, params); err != nil {
 		log.Printf("%s%s Failed to send window message: %v", logging.LogTagLSP, logging.LogTagServer, err)
 	}
 }
 
+// publishDiagnostics publishes the analysis results of the file and records them for the next session
 func (s *Server) publishDiagnostics(ctx context.Context, uri protocol.DocumentURI, diagnostics []protocol.Diagnostic) {
+	s.setPublished(uri.Filename(), diagnostics)
+	s.sendDiagnostics(ctx, uri, diagnostics)
+	s.refreshCodeLenses(uri)
+	s.refreshInlayHints(uri)
+}
+
+func (s *Server) sendDiagnostics(ctx context.Context, uri protocol.DocumentURI, diagnostics []protocol.Diagnostic) {
+	if s.pullsDiagnostics(uri) {
+		s.updatePulledDiagnostics(uri, diagnostics)
+		s.statusDiagnosticsPublished(uri, len(diagnostics))
+		s.notifyStatus(ctx)
+		return
+	}
+
 	params := protocol.PublishDiagnosticsParams{
 		URI:         uri,
 		Diagnostics: utils.EnsureDiagnosticsArray(diagnostics),
@@ -299,6 +695,9 @@ func (s *Server) publishDiagnostics(ctx context.Context, uri protocol.DocumentUR
 	if err := s.conn.Notify(ctx, protocol.MethodTextDocumentPublishDiagnostics, params); err != nil {
 		log.Printf("%s%s Failed to publish diagnostics: %v", logging.LogTagLSP, logging.LogTagServer, err)
 	}
+
+	s.statusDiagnosticsPublished(uri, len(params.Diagnostics))
+	s.notifyStatus(ctx)
 }
 
 func (s *Server) setDocumentContent(uri protocol.DocumentURI, content string) {
@@ -318,42 +717,100 @@ func (s *Server) deleteDocumentContent(uri protocol.DocumentURI) {
 	s.docMu.Lock()
 	defer s.docMu.Unlock()
 	delete(s.documents, uri)
+	delete(s.documentLanguages, uri)
+	delete(s.dirtyDocuments, uri)
 }
 
-func (s *Server) scheduleDiagnostics(uri protocol.DocumentURI) {
+func (s *Server) setDocumentDirty(uri protocol.DocumentURI, dirty bool) {
+	s.docMu.Lock()
+	defer s.docMu.Unlock()
+	if dirty {
+		s.dirtyDocuments[uri] = true
+		return
+	}
+	delete(s.dirtyDocuments, uri)
+}
+
+// documentContent returns the synchronized buffer of the document, or the file on disk unless the server
+// is buffer-only
+func (s *Server) documentContent(uri protocol.DocumentURI) (string, error) {
+	if content, exists := s.getDocumentContent(uri); exists {
+		return content, nil
+	}
+	if s.bufferOnly {
+		return "", fmt.Errorf("%s is not open, documents are not read from disk in buffer-only mode", uri.Filename())
+	}
+
+	content, err := os.ReadFile(uri.Filename())
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(content), nil
+}
+
+// dirtyDocumentContent returns the unsaved buffer of the document, if any
+func (s *Server) dirtyDocumentContent(uri protocol.DocumentURI) (string, bool) {
+	s.docMu.RLock()
+	defer s.docMu.RUnlock()
+	if !s.dirtyDocuments[uri] {
+		return "", false
+	}
+	content, exists := s.documents[uri]
+	return content, exists
+}
+
+func (s *Server) setDocumentLanguage(uri protocol.DocumentURI, languageId string) {
+	s.docMu.Lock()
+	defer s.docMu.Unlock()
+	s.documentLanguages[uri] = languageId
+}
+
+// isSupportedDocument checks the language id sent on didOpen first, then the file extensions configured
+// in the project of the document. The documents of the enabled document providers are supported too.
+func (s *Server) isSupportedDocument(uri protocol.DocumentURI) bool {
+	p := s.projectFor(uri.Filename())
+	if p == nil {
+		return false
+	}
+	if p.analyzesDocument(uri.Filename()) {
+		return true
+	}
+
+	s.docMu.RLock()
+	languageId, known := s.documentLanguages[uri]
+	s.docMu.RUnlock()
+
+	if known && p.serverConfig.SupportsLanguageId(languageId) {
+		return true
+	}
+
+	return p.serverConfig.SupportsFile(uri.Filename())
+}
+
+func (s *Server) scheduleDiagnostics(uri protocol.DocumentURI, priority scheduler.Priority) {
+	uri = utils.PathToURI(diagnostics.PublishedFile(uri.Filename()))
 	s.diagMu.Lock()
 
 	if timer, exists := s.diagTimers[uri]; exists {
 		timer.Stop()
 	}
 
-	if s.diagGen == nil {
-		s.diagGen = make(map[protocol.DocumentURI]uint64)
-	}
-	s.diagGen[uri]++
-	gen := s.diagGen[uri]
+	gen, analysisCtx := s.supersedeAnalysis(context.Background(), uri)
 
 	s.diagTimers[uri] = time.AfterFunc(diagnosticsDebounceInterval, func() {
 		s.diagMu.Lock()
 		delete(s.diagTimers, uri)
 		s.diagMu.Unlock()
 
-		filePath := uri.Filename()
-		diags := s.collectDiagnostics(context.Background(), filePath)
-
-		s.diagMu.Lock()
-		currentGen := s.diagGen[uri]
-		s.diagMu.Unlock()
-		if gen != currentGen {
-			return
-		}
-
-		s.publishDiagnostics(context.Background(), uri, diags)
+		s.analysisScheduler.Submit(analysisCtx, priority, func(ctx context.Context) {
+			s.runDiagnostics(ctx, uri, gen, analysisRun{})
+		})
 	})
 	s.diagMu.Unlock()
 }
 
 func (s *Server) scheduleDiagnosticsPriority(uri protocol.DocumentURI) {
+	uri = utils.PathToURI(diagnostics.PublishedFile(uri.Filename()))
 	s.diagMu.Lock()
 
 	if timer, exists := s.diagTimers[uri]; exists {
@@ -361,31 +818,85 @@ func (s *Server) scheduleDiagnosticsPriority(uri protocol.DocumentURI) {
 		delete(s.diagTimers, uri)
 	}
 
-	if s.diagGen == nil {
-		s.diagGen = make(map[protocol.DocumentURI]uint64)
-	}
-	s.diagGen[uri]++
-	gen := s.diagGen[uri]
+	gen, analysisCtx := s.supersedeAnalysis(context.Background(), uri)
 	s.diagMu.Unlock()
 
-	go func(u protocol.DocumentURI, g uint64) {
-		filePath := u.Filename()
-		diags := s.collectDiagnostics(context.Background(), filePath)
+	s.analysisScheduler.Submit(analysisCtx, scheduler.PrioritySave, func(ctx context.Context) {
+		s.runDiagnostics(ctx, uri, gen, analysisRun{saved: true})
+	})
 
+	s.scheduleSettledSave(uri)
+}
+
+// scheduleSettledSave runs the save providers with a debounce (Infection) once the file wasn't saved again
+// for the longest debounce of them, every save restarting the wait
+func (s *Server) scheduleSettledSave(uri protocol.DocumentURI) {
+	var debounce time.Duration
+	if p := s.projectFor(uri.Filename()); p != nil {
+		for _, provider := range filterFileProviders(s.loadDiagnosticsProviders(p), p.serverConfig.DiagnosticsProviders, uri.Filename()) {
+			providerConfig := p.serverConfig.DiagnosticsProviders[provider.Id()]
+			if providerConfig.IsManual() || !diagnostics.RunsOnSave(provider.Id(), providerConfig) {
+				continue
+			}
+			if providerDebounce := diagnostics.SaveDebounce(provider.Id(), providerConfig); providerDebounce > debounce {
+				debounce = providerDebounce
+			}
+		}
+	}
+
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+
+	if timer, exists := s.saveTimers[uri]; exists {
+		timer.Stop()
+		delete(s.saveTimers, uri)
+	}
+	if debounce == 0 {
+		return
+	}
+
+	s.saveTimers[uri] = time.AfterFunc(debounce, func() {
 		s.diagMu.Lock()
-		currentGen := s.diagGen[u]
+		delete(s.saveTimers, uri)
+		gen, analysisCtx := s.supersedeAnalysis(context.Background(), uri)
 		s.diagMu.Unlock()
-		if g != currentGen {
-			return
-		}
 
-		s.publishDiagnostics(context.Background(), u, diags)
-	}(uri, gen)
+		s.analysisScheduler.Submit(analysisCtx, scheduler.PrioritySave, func(ctx context.Context) {
+			s.runDiagnostics(ctx, uri, gen, analysisRun{saved: true, settled: true})
+		})
+	})
 }
 
-func (s *Server) scheduleFormatting(ctx context.Context, reply jsonrpc2.Replier, params protocol.DocumentFormattingParams) {
-	uri := params.TextDocument.URI
+// runDiagnostics analyzes the document and publishes the result unless a newer analysis was
+// scheduled meanwhile, cancelling this one, or the run was preempted by the scheduler
+func (s *Server) runDiagnostics(ctx context.Context, uri protocol.DocumentURI, gen uint64, run analysisRun) {
+	defer s.releaseAnalysis(uri, gen)
 
+	s.diagMu.Lock()
+	currentGen := s.diagGen[uri]
+	s.diagMu.Unlock()
+	if gen != currentGen {
+		return
+	}
+
+	diags := s.collectDiagnostics(ctx, uri.Filename(), run)
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.diagMu.Lock()
+	currentGen = s.diagGen[uri]
+	s.diagMu.Unlock()
+	if gen != currentGen {
+		return
+	}
+
+	s.publishDiagnostics(context.Background(), uri, diags)
+}
+
+// scheduleFormatting formats the document once the requests settle. With a range, the whole document is
+// formatted but only the changes touching the lines of the range are returned.
+func (s *Server) scheduleFormatting(ctx context.Context, reply jsonrpc2.Replier, uri protocol.DocumentURI, lineRange *protocol.Range) {
 	s.fmtMu.Lock()
 
 	if timer, exists := s.fmtTimers[uri]; exists {
@@ -411,141 +922,302 @@ func (s *Server) scheduleFormatting(ctx context.Context, reply jsonrpc2.Replier,
 
 		filePath := uri.Filename()
 
-		content, exists := s.getDocumentContent(uri)
-		if !exists {
-			fileContent, err := os.ReadFile(filePath)
-			if err != nil {
-				_ = reply(ctx, nil, fmt.Errorf("failed to read file: %w", err))
-				return
-			}
-			content = string(fileContent)
+		content, err := s.documentContent(uri)
+		if err != nil {
+			_ = reply(ctx, nil, err)
+			return
 		}
 
-		formattingProviders := s.loadFormattingProviders()
-		if len(formattingProviders) == 0 {
+		p := s.projectFor(filePath)
+		if p == nil {
 			_ = reply(ctx, []protocol.TextEdit{}, nil)
 			return
 		}
 
-		provider := formattingProviders[0]
-		formattedContent, err := provider.Format(ctx, filePath, content)
-		if err != nil {
+		provider, ok := p.formattingProviderFor(filePath)
+		if !ok {
 			_ = reply(ctx, []protocol.TextEdit{}, nil)
 			return
 		}
 
-		if formattedContent == content {
+		formattedContent, err := provider.Format(ctx, filePath, content)
+		if err != nil {
+			log.Printf("%s%s Formatting with %s failed: %v", logging.LogTagLSP, logging.LogTagServer, provider.Name(), err)
 			_ = reply(ctx, []protocol.TextEdit{}, nil)
 			return
 		}
 
-		lines := strings.Split(content, "\n")
-		endLine := uint32(len(lines) - 1)
-		endCharacter := uint32(0)
-		if len(lines) > 0 {
-			endCharacter = uint32(len(lines[len(lines)-1]))
-		}
-
-		textEdits := []protocol.TextEdit{
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 0},
-					End:   protocol.Position{Line: endLine, Character: endCharacter},
-				},
-				NewText: formattedContent,
-			},
+		if lineRange != nil {
+			_ = reply(ctx, utils.RangeEdits(content, formattedContent, *lineRange), nil)
+			return
 		}
 
-		_ = reply(ctx, textEdits, nil)
+		_ = reply(ctx, p.formattingEdits(ctx, filePath, content, formattedContent), nil)
 	})
 	s.fmtMu.Unlock()
 }
 
-func (s *Server) loadDiagnosticsProviders() []diagnostics.DiagnosticsProvider {
-	// Return cached providers if already initialized
-	if s.diagnosticsProviders != nil {
-		return s.diagnosticsProviders
+// analysisRun tunes one analysis of a file
+type analysisRun struct {
+	// Run the manual and save providers too, automatic runs report their last results instead
+	includeManual bool
+	// The file was saved, the save providers run too
+	saved bool
+	// The save debounce elapsed: the save providers with a debounce run instead of the other ones
+	settled bool
+	// Replaces the timeout of every provider when set
+	timeout time.Duration
+	// Results of the providers which analyzed a batch of files including this one, by provider id and
+	// file path. These providers aren't run again for the file.
+	batchResults map[string]map[string][]protocol.Diagnostic
+}
+
+// providerTimeout returns the time the provider may analyze a file, no limit when 0
+func (run analysisRun) providerTimeout(providerConfig config.DiagnosticsProvider) time.Duration {
+	if run.timeout > 0 {
+		return run.timeout
 	}
+	return time.Duration(providerConfig.TimeoutSeconds) * time.Second
+}
 
-	providers := []diagnostics.DiagnosticsProvider{}
-	for id, providerConfig := range s.serverConfig.DiagnosticsProviders {
-		// Initialize only enabled diagnostics providers
-		if !providerConfig.Enabled {
-			continue
+// runsProvider reports whether the run analyzes the file with the provider
+func (run analysisRun) runsProvider(providerId string, providerConfig config.DiagnosticsProvider) bool {
+	switch {
+	case providerConfig.IsManual():
+		return run.includeManual
+	case diagnostics.RunsOnSave(providerId, providerConfig):
+		if run.includeManual {
+			return true
 		}
+		return run.saved && (diagnostics.SaveDebounce(providerId, providerConfig) > 0) == run.settled
+	default:
+		return true
+	}
+}
 
-		provider, err := diagnostics.NewDiagnosticsProvider(id, providerConfig)
-		if err != nil {
-			s.showWindowMessage(context.Background(), protocol.MessageTypeError, fmt.Sprintf("%v", err))
-			continue
-		}
+// collectDiagnostics analyzes the file with the providers of its project
+func (s *Server) collectDiagnostics(ctx context.Context, filePath string, run analysisRun) []protocol.Diagnostic {
+	var collected []protocol.Diagnostic
 
-		providers = append(providers, provider)
+	if utils.IsIgnoredPath(filePath) {
+		return collected
 	}
 
-	// Cache and return
-	s.diagnosticsProviders = providers
-	return s.diagnosticsProviders
-}
+	p := s.projectFor(filePath)
+	if p == nil {
+		return collected
+	}
+	catalog := p.messageCatalog(s.locale)
 
-func (s *Server) collectDiagnostics(ctx context.Context, filePath string) []protocol.Diagnostic {
-	var diagnostics []protocol.Diagnostic
+	uri := utils.PathToURI(filePath)
+	collected = s.manualDiagnostics(uri, func(providerId string) bool {
+		return run.runsProvider(providerId, p.serverConfig.DiagnosticsProviders[providerId])
+	})
 
-	ignoredDirs := []string{"/vendor/", "/var/cache/"}
-	for _, dir := range ignoredDirs {
-		if strings.Contains(filePath, dir) {
-			return diagnostics
-		}
+	providers := filterFileProviders(selectProviders(p, s.loadDiagnosticsProviders(p), run), p.serverConfig.DiagnosticsProviders, filePath)
+	if len(providers) == 0 {
+		return collected
 	}
 
-	providers := s.loadDiagnosticsProviders()
-	if len(providers) == 0 {
-		return diagnostics
+	if s.isGeneratedFile(p, filePath) {
+		log.Printf("%s%s Generated file detected, running lightweight providers only: %s", logging.LogTagLSP, logging.LogTagServer, filePath)
+		providers = filterLightweightProviders(providers)
+		if len(providers) == 0 {
+			return collected
+		}
 	}
 
+	ctx = diagnostics.WithSharedRuns(s.documentReaderContext(ctx))
+
+	// Status notifications must go out even when the analysis context gets cancelled
+	s.statusAnalysisStarted(context.Background())
+	defer s.statusAnalysisFinished(context.Background())
+	progress := s.startAnalysisProgress(filePath, providers)
+	defer progress.finish()
+
+	projectRoot, serverConfig := p.root, p.serverConfig
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	manualResults := make(map[string][]protocol.Diagnostic)
 
 	wg.Add(len(providers))
 	for _, provider := range providers {
 		p := provider
 		go func() {
 			defer wg.Done()
+			defer progress.providerDone(p.Name())
 
-			providerDiagnostics, err := p.Analyze(filePath)
-			if err != nil {
-				s.showWindowMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("Diagnostics provider %s failed: %v", p.Name(), err))
+			// The watch command reports the file, the provider isn't run for it
+			if watched, active := s.watchedDiagnostics(projectRoot, p.Name(), filePath); active {
+				mu.Lock()
+				collected = append(collected, utils.TranslateMessages(watched, catalog)...)
+				mu.Unlock()
 				return
 			}
 
+			providerConfig := serverConfig.DiagnosticsProviders[p.Id()]
+			if batch, analyzed := run.batchResults[p.Id()]; analyzed {
+				batchDiagnostics := utils.TranslateMessages(batch[filePath], catalog)
+				mu.Lock()
+				collected = append(collected, batchDiagnostics...)
+				if isDeferredProvider(p.Id(), providerConfig) {
+					manualResults[p.Id()] = append(manualResults[p.Id()], batchDiagnostics...)
+				}
+				mu.Unlock()
+				return
+			}
+
+			breaker := s.breakerFor(projectRoot, p.Id(), p.Name(), providerConfig)
+			if !breaker.Allow(time.Now()) {
+				log.Printf("%s%s %s is suspended, skipped for %s", logging.LogTagLSP, logging.LogTagServer, p.Name(), filePath)
+				return
+			}
+
+			providerCtx := ctx
+			timeout := run.providerTimeout(providerConfig)
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				providerCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			providerCtx, toolFailure := diagnostics.TrackFailures(providerCtx)
+			providerCtx, rawResult := diagnostics.TrackRawResult(providerCtx)
+
+			s.syncBuffer(providerCtx, p, filePath)
+
+			providerDiagnostics, err := p.Analyze(providerCtx, filePath)
+			if ctx.Err() != nil {
+				return
+			}
+
+			timedOut := providerCtx.Err() == context.DeadlineExceeded
+			toolErr := toolFailure()
+			s.recordProviderOutcome(ctx, breaker, p.Name(), providerConfig, err != nil || timedOut || toolErr != nil)
+
+			if timedOut {
+				// Providers swallow most tool errors, the results of an interrupted run can't be trusted
+				log.Printf("%s%s %s timed out after %v on %s", logging.LogTagLSP, logging.LogTagServer, p.Name(), timeout, filePath)
+				s.statusProviderResult(projectRoot, p.Name(), fmt.Errorf("timed out after %v", timeout))
+				providerDiagnostics = []protocol.Diagnostic{timeoutDiagnostic(p, timeout)}
+			} else {
+				if err != nil {
+					s.statusProviderResult(projectRoot, p.Name(), err)
+					s.showProviderError(ctx, p.Name(), fmt.Sprintf("Diagnostics provider %s failed", p.Name()), err)
+					return
+				}
+				s.statusProviderResult(projectRoot, p.Name(), toolErr)
+				s.setRawResult(uri, p.Id(), p.Name(), rawResult())
+				// The tool didn't run at all, the setup needs fixing rather than the code
+				if container.Failure(toolErr).IsBackendFailure() {
+					s.showProviderError(ctx, p.Name(), fmt.Sprintf("Diagnostics provider %s can't run its tool", p.Name()), toolErr)
+				}
+
+				providerDiagnostics = utils.TranslateMessages(providerDiagnostics, catalog)
+				if providerConfig.FirstOccurrenceOnly {
+					providerDiagnostics = utils.FirstOccurrences(providerDiagnostics)
+				} else if len(providerConfig.GroupRules) > 0 {
+					providerDiagnostics = groupRuleOccurrences(providerConfig, providerDiagnostics)
+				}
+				providerDiagnostics = utils.SummarizeDiagnostics(providerDiagnostics, providerConfig.SummarizeThreshold)
+			}
+
 			mu.Lock()
-			diagnostics = append(diagnostics, providerDiagnostics...)
+			collected = append(collected, providerDiagnostics...)
+			if isDeferredProvider(p.Id(), providerConfig) {
+				manualResults[p.Id()] = append(manualResults[p.Id()], providerDiagnostics...)
+			}
 			mu.Unlock()
 		}()
 	}
 	wg.Wait()
 
-	return diagnostics
+	if len(manualResults) > 0 && ctx.Err() == nil {
+		s.setManualDiagnostics(uri, manualResults)
+	}
+
+	return utils.MergeDuplicateDiagnostics(collected)
+}
+
+// documentReaderContext makes the providers computing ranges against the document read its buffer in
+// buffer-only mode, never the file on disk
+func (s *Server) documentReaderContext(ctx context.Context) context.Context {
+	if !s.bufferOnly {
+		return ctx
+	}
+	return diagnostics.WithDocumentReader(ctx, func(documentPath string) (string, error) {
+		return s.documentContent(utils.PathToURI(documentPath))
+	})
 }
 
-func (s *Server) loadFormattingProviders() []formatting.FormattingProvider {
-	// Return cached providers if already initialized
-	if s.formattingProviders != nil {
-		return s.formattingProviders
+// syncBuffer makes the provider analyze the unsaved buffer of the file, or the file on disk once saved or closed
+func (s *Server) syncBuffer(ctx context.Context, provider diagnostics.DiagnosticsProvider, filePath string) {
+	syncer, ok := provider.(diagnostics.BufferSyncer)
+	if !ok {
+		return
 	}
 
-	// Initialize and cache
-	s.formattingProviders = formatting.LoadFormattingProviders(s.serverConfig.DiagnosticsProviders)
-	return s.formattingProviders
+	var err error
+	if content, dirty := s.dirtyDocumentContent(utils.PathToURI(filePath)); dirty {
+		err = syncer.SyncBuffer(ctx, filePath, content)
+	} else {
+		err = syncer.RestoreBuffer(ctx, filePath)
+	}
+
+	if err != nil {
+		log.Printf("%s%s Buffer sync failed, analyzing the file on disk: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
 }
 
-func (s *Server) getPhpCsFixerProviderConfig() (config.DiagnosticsProvider, bool) {
-	for id, cfg := range s.serverConfig.DiagnosticsProviders {
-		if id == diagnostics.PhpCsFixerProviderId && cfg.Enabled {
-			return cfg, true
+// refreshBuffers makes the providers analyzing unsaved buffers copy the file again, it changed on disk
+func (s *Server) refreshBuffers(filePath string) {
+	p := s.projectFor(filePath)
+	if p == nil {
+		return
+	}
+
+	for _, provider := range s.loadDiagnosticsProviders(p) {
+		if syncer, ok := provider.(diagnostics.BufferSyncer); ok {
+			syncer.RefreshFile(filePath)
+		}
+	}
+}
+
+// filterFileProviders keeps the providers analyzing the file, the document providers (composer.json) and
+// the PHP ones analyzing different files
+func filterFileProviders(providers []diagnostics.DiagnosticsProvider, providerConfigs map[string]config.DiagnosticsProvider, filePath string) []diagnostics.DiagnosticsProvider {
+	fileProviders := []diagnostics.DiagnosticsProvider{}
+	for _, provider := range providers {
+		if diagnostics.AnalyzesFile(provider.Id(), providerConfigs, filePath) {
+			fileProviders = append(fileProviders, provider)
 		}
 	}
-	return config.DiagnosticsProvider{}, false
+	return fileProviders
+}
+
+func filterLightweightProviders(providers []diagnostics.DiagnosticsProvider) []diagnostics.DiagnosticsProvider {
+	lightweightProviders := []diagnostics.DiagnosticsProvider{}
+	for _, provider := range providers {
+		if diagnostics.IsLightweightProvider(provider.Id()) {
+			lightweightProviders = append(lightweightProviders, provider)
+		}
+	}
+	return lightweightProviders
+}
+
+// isGeneratedFile checks the top of the synchronized buffer (or the file on disk) for the generated
+// content markers of the project
+func (s *Server) isGeneratedFile(p *project, filePath string) bool {
+	if len(p.serverConfig.GeneratedMarkers) == 0 {
+		return false
+	}
+
+	content, err := s.documentContent(utils.PathToURI(filePath))
+	if err != nil {
+		return false
+	}
+
+	return utils.HasGeneratedMarker(content, p.serverConfig.GeneratedMarkers, generatedMarkerScanLines)
 }
 
 func (s *Server) handleDocumentFormatting(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -555,6 +1227,17 @@ func (s *Server) handleDocumentFormatting(ctx context.Context, reply jsonrpc2.Re
 		return err
 	}
 
-	s.scheduleFormatting(ctx, reply, params)
+	s.scheduleFormatting(ctx, reply, params.TextDocument.URI, nil)
+	return nil
+}
+
+func (s *Server) handleDocumentRangeFormatting(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.DocumentRangeFormattingParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		log.Printf("%s%s Error unmarshaling document range formatting params: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		return err
+	}
+
+	s.scheduleFormatting(ctx, reply, params.TextDocument.URI, &params.Range)
 	return nil
 }
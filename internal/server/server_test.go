@@ -1,9 +1,20 @@
 package server_test
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/server"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 )
 
@@ -13,9 +24,15 @@ func TestServerCapabilities(t *testing.T) {
 		// We can't call serverCapabilities directly as it's not exported
 		// This test documents the expected capabilities structure
 		t.Log("Expected server capabilities:")
-		t.Log("- TextDocumentSync: Full sync with open/close/save")
+		t.Log("- TextDocumentSync: Full sync with open/close/save, willSaveWaitUntil for formatOnSave")
 		t.Log("- ExecuteCommandProvider: Supports php-diagls/showConfig command")
 		t.Log("- DocumentFormattingProvider: true")
+		t.Log("- DocumentRangeFormattingProvider: true, the edits touching the range only")
+		t.Log("- CodeLensProvider: issue counts per provider, Fix with php-cs-fixer and Run lens for the manual providers")
+		t.Log("- CodeActionProvider: quickfix re-running timed out analyses")
+		t.Log("- HoverProvider: true, the documentation of the rules reported on the hovered line")
+		t.Log("- DiagnosticProvider: for clients supporting textDocument/diagnostic, interFileDependencies set")
+		t.Log("- InlayHintProvider: true, the phpmetrics complexity of the functions when its inlayHints are enabled")
 	})
 }
 
@@ -151,10 +168,40 @@ func TestServerHandle_MethodRouting(t *testing.T) {
 			handlerName: "handleDocumentFormatting",
 			description: "Schedules document formatting with debounce",
 		},
+		{
+			method:      protocol.MethodTextDocumentWillSaveWaitUntil,
+			handlerName: "handleWillSaveWaitUntil",
+			description: "Returns the formatting edits of the document when formatOnSave is enabled",
+		},
+		{
+			method:      protocol.MethodTextDocumentRangeFormatting,
+			handlerName: "handleDocumentRangeFormatting",
+			description: "Schedules document formatting with debounce, keeping the edits of the range",
+		},
+		{
+			method:      protocol.MethodTextDocumentCodeLens,
+			handlerName: "handleCodeLens",
+			description: "Shows the issue counts per provider and the fix and run lenses",
+		},
+		{
+			method:      protocol.MethodTextDocumentHover,
+			handlerName: "handleHover",
+			description: "Returns the Markdown documentation of the rules of the diagnostics on the hovered line",
+		},
+		{
+			method:      "textDocument/inlayHint",
+			handlerName: "handleInlayHint",
+			description: "Returns the cyclomatic complexity of the functions in the range from the last phpmetrics report",
+		},
+		{
+			method:      "textDocument/diagnostic",
+			handlerName: "handleDocumentDiagnostic",
+			description: "Returns the diagnostics of an open document, analyzing it when it changed since the last result",
+		},
 		{
 			method:      protocol.MethodWorkspaceDidChangeWatchedFiles,
 			handlerName: "handleDidChangeWatchedFiles",
-			description: "Handles file system changes for .php files",
+			description: "Handles file system changes of the analyzed, configuration and provider configuration files",
 		},
 		{
 			method:      protocol.MethodShutdown,
@@ -169,7 +216,22 @@ func TestServerHandle_MethodRouting(t *testing.T) {
 		{
 			method:      protocol.MethodCancelRequest,
 			handlerName: "handleCancelRequest",
-			description: "Acknowledges request cancellation",
+			description: "Cancels the context of the request being handled",
+		},
+		{
+			method:      "php-diagls/status",
+			handlerName: "handleStatusRequest",
+			description: "Returns the current status snapshot",
+		},
+		{
+			method:      "php-diagls/ruleDoc",
+			handlerName: "handleRuleDocRequest",
+			description: "Returns the Markdown documentation of a rule for a virtual document",
+		},
+		{
+			method:      "php-diagls/rawResults",
+			handlerName: "handleRawResultsRequest",
+			description: "Returns the parsed tool output of the last analysis of an open document, per provider",
 		},
 	}
 
@@ -224,6 +286,14 @@ func TestServerDiagnosticsScheduling(t *testing.T) {
 		t.Log("Still uses generation counter for race prevention")
 	})
 
+	t.Run("priority tiers", func(t *testing.T) {
+		t.Log("Debounced runs are submitted to the analysis scheduler")
+		t.Log("Tiers: save > change (open/edit) > watcher (file events, close, reload) > background (workspace scan)")
+		t.Log("At most maxConcurrentAnalyses (4) analyses run at the same time")
+		t.Log("A higher tier job preempts (cancels and re-queues) a running lower tier job")
+		t.Log("Results of preempted runs are never published")
+	})
+
 	t.Run("generation counter behavior", func(t *testing.T) {
 		t.Log("Per-file generation counter (diagGen map)")
 		t.Log("Incremented on each schedule call")
@@ -297,6 +367,16 @@ func TestServerCollectDiagnostics(t *testing.T) {
 		t.Log("Uses sync.Mutex to protect diagnostics slice")
 	})
 
+	t.Run("generated files", func(t *testing.T) {
+		t.Log("Scans the first 10 lines of the buffer for generatedMarkers")
+		t.Log("Only lightweight providers (phplint) run on generated files")
+	})
+
+	t.Run("duplicate merging", func(t *testing.T) {
+		t.Log("Collapses diagnostics with same source+code+message on one line")
+		t.Log("Merged diagnostic spans the union range of the duplicates")
+	})
+
 	t.Run("error handling", func(t *testing.T) {
 		t.Log("Shows error window message if provider fails")
 		t.Log("Continues with other providers on error")
@@ -373,11 +453,20 @@ func TestServerFileWatcherBehavior(t *testing.T) {
 		t.Log("FileChangeTypeChanged: Schedules diagnostics")
 		t.Log("FileChangeTypeCreated: Schedules diagnostics")
 		t.Log("FileChangeTypeDeleted: Publishes empty diagnostics (clears)")
+		t.Log("FileChangeTypeDeleted of a file published on another document (composer.lock): Re-analyzes that document")
+	})
+
+	t.Run("config file changes", func(t *testing.T) {
+		t.Log(".php-diagls.json changes trigger reloadConfig")
+		t.Log("Providers are rebuilt from the new configuration")
+		t.Log("All open documents are re-analyzed")
+		t.Log("Previous configuration is kept if the new one fails to load")
 	})
 
 	t.Run("file filtering", func(t *testing.T) {
-		t.Log("Only processes files ending with .php")
-		t.Log("Ignores non-PHP files")
+		t.Log("Only processes files matching the configured fileExtensions (default .php)")
+		t.Log("Open documents are also matched by their didOpen languageId (default php)")
+		t.Log("Ignores other files")
 	})
 }
 
@@ -471,6 +560,1519 @@ func TestServerTestability(t *testing.T) {
 	})
 }
 
+// TestServerStatusReporting documents the status notifications
+func TestServerStatusReporting(t *testing.T) {
+	t.Run("states", func(t *testing.T) {
+		t.Log("idle: no analysis running and no provider errors")
+		t.Log("analyzing: at least one collectDiagnostics run in flight")
+		t.Log("provider-error: last run of at least one provider failed")
+		t.Log("provider-suspended: a provider is suspended by its circuit breaker, takes precedence over provider-error")
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		t.Log("Sends $/php-diagls/status when analysis starts and finishes")
+		t.Log("Sends $/php-diagls/status after diagnostics are published")
+		t.Log("Payload: state, analyzing, providers, providerErrors, diagnostics, roots")
+		t.Log("roots: the same counters per project root")
+	})
+
+	t.Run("on-demand snapshot", func(t *testing.T) {
+		t.Log("Request: php-diagls/status")
+		t.Log("Returns the same payload as the notification")
+	})
+}
+
+// TestServerBackgroundJobs documents server initiated work done progress
+func TestServerBackgroundJobs(t *testing.T) {
+	t.Run("progress support", func(t *testing.T) {
+		t.Log("Enabled when client advertises window.workDoneProgress")
+		t.Log("Token created via window/workDoneProgress/create")
+		t.Log("Sends begin/report/end through $/progress")
+		t.Log("Without support the job runs silently")
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		t.Log("Progress is reported as cancellable")
+		t.Log("window/workDoneProgress/cancel cancels the job context")
+		t.Log("The warm-up of the recent files on initialized is a background job too")
+	})
+
+	t.Run("analyzeWorkspace command", func(t *testing.T) {
+		t.Log("Command: php-diagls/analyzeWorkspace")
+		t.Log("Walks the project root for .php files, skipping ignored directories")
+		t.Log("Publishes diagnostics per file and reports N/M progress")
+		t.Log("Batch providers (php-parallel-lint) analyze all files of a project first, a failed batch runs per file")
+	})
+}
+
+// TestServerManualProviders documents the runOn: manual mode
+func TestServerManualProviders(t *testing.T) {
+	t.Run("automatic runs", func(t *testing.T) {
+		t.Log("Open, change, save, watcher and reload runs skip the manual providers")
+		t.Log("The results of the last manual run are published along with the automatic ones")
+		t.Log("Config reload and file deletion drop the stored manual results")
+	})
+
+	t.Run("manual runs", func(t *testing.T) {
+		t.Log("Command: php-diagls/analyzeFile <uri> runs all providers of the document")
+		t.Log("analyzeWorkspace runs all providers, the manual ones included")
+		t.Log("textDocument/codeLens returns a Run lens at line 0 calling analyzeFile")
+	})
+}
+
+func TestServerManualProviders_DeletedFile(t *testing.T) {
+	fakePhpstan := newFakeTool(t, "phpstan", `cat <<'JSON'
+{"totals": {"errors": 0, "file_errors": 1}, "files": {"src/Foo.php": {"errors": 1, "messages": [
+  {"message": "Undefined variable: $foo", "line": 2, "ignorable": true}
+]}}, "errors": []}
+JSON
+exit 1
+`)
+	// The client encodes the space of the deleted file URI, the analyses don't
+	projectRoot := filepath.Join(t.TempDir(), "my project")
+	writeConfig(t, projectRoot, map[string]interface{}{
+		"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, map[string]interface{}{"runOn": "manual"}),
+	})
+	ts := startTestServer(t, projectRoot, nil, nil)
+
+	filePath := filepath.Join(projectRoot, "src/Foo.php")
+	documentURI := ts.open(t, filePath, "<?php\necho $foo;\n")
+	ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+		Command:   "php-diagls/analyzeFile",
+		Arguments: []interface{}{string(documentURI)},
+	})
+	ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool {
+		return len(diags) == 1
+	})
+	if diags := ts.ManualDiagnostics(documentURI); len(diags) != 1 {
+		t.Fatalf("Expected the manual run stored, got %v", diags)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		t.Fatal(err)
+	}
+	ts.notify(t, protocol.MethodWorkspaceDidChangeWatchedFiles, protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{URI: protocol.DocumentURI(strings.ReplaceAll(string(documentURI), " ", "%20")), Type: protocol.FileChangeTypeDeleted}},
+	})
+
+	if diags := ts.ManualDiagnostics(documentURI); len(diags) != 0 {
+		t.Errorf("Expected the manual results of the deleted file dropped, got %v", diags)
+	}
+	ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool {
+		return len(diags) == 0
+	})
+}
+
+// TestServerSaveProviders documents the runOn: save mode, the default of phpunit and infection
+func TestServerSaveProviders(t *testing.T) {
+	t.Run("save runs", func(t *testing.T) {
+		t.Log("textDocument/didSave runs the save providers along with the automatic ones")
+		t.Log("Open, change, watcher and reload runs skip them and publish their last results, by provider")
+		t.Log("analyzeFile and analyzeWorkspace run them too")
+	})
+
+	t.Run("debounced save runs", func(t *testing.T) {
+		t.Log("Save providers with a debounce (debounceSeconds, 30s for infection) skip the save run")
+		t.Log("They run once the file wasn't saved again for the longest debounce, each save restarting the wait")
+	})
+}
+
+// TestServerAnalysisTimeouts documents the timeoutSeconds handling
+func TestServerAnalysisTimeouts(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		t.Log("Each provider run gets its own timeoutSeconds deadline, no limit when 0")
+		t.Log("A timed out provider publishes one Warning at line 0 with code timeout instead of its results")
+		t.Log("Status reports the provider as failing, no popup is shown")
+	})
+
+	t.Run("re-run", func(t *testing.T) {
+		t.Log("textDocument/codeAction returns a quickfix for the timeout diagnostics")
+		t.Log("It runs php-diagls/analyzeFile <uri> <seconds> with twice the timeout which was hit")
+	})
+}
+
+// TestServerRuleDiffFixes tests the php-cs-fixer quick fixes applying the rule diffs of the last analysis
+func TestServerRuleDiffFixes(t *testing.T) {
+	t.Run("fix", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+
+		fixes := map[string]protocol.CodeAction{}
+		for _, action := range ts.codeActions(t, documentURI, published) {
+			if action.Kind == protocol.QuickFix && strings.HasPrefix(action.Title, "Fix ") {
+				fixes[action.Title] = action
+			}
+		}
+		if len(fixes) != 2 {
+			t.Fatalf("Expected one quick fix per rule, got %v", fixes)
+		}
+
+		tests := []struct {
+			rule    string
+			line    uint32
+			newText string
+		}{
+			{phpCsFixerSemicolonRule, 1, "echo \"a\";\n"},
+			{"array_syntax", 3, "$b = [1];\n"},
+		}
+		for _, tt := range tests {
+			fix, exists := fixes["Fix "+tt.rule]
+			if !exists {
+				t.Errorf("Expected a quick fix for %s, got %v", tt.rule, fixes)
+				continue
+			}
+			if !fix.IsPreferred || len(fix.Diagnostics) != 1 || fix.Diagnostics[0].Code != tt.rule {
+				t.Errorf("Expected a preferred fix of the %s diagnostic, got %+v", tt.rule, fix)
+			}
+			if fix.Edit == nil || len(fix.Edit.Changes[documentURI]) != 1 {
+				t.Fatalf("Expected one edit of the document for %s, got %+v", tt.rule, fix.Edit)
+			}
+			assertLineEdit(t, fix.Edit.Changes[documentURI][0], tt.line, tt.newText)
+		}
+	})
+
+	t.Run("changed document", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+
+		// The diffs don't apply to the new content
+		ts.notify(t, protocol.MethodTextDocumentDidChange, protocol.DidChangeTextDocumentParams{
+			TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: documentURI}, Version: 2},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "<?php\necho \"b\" ;\n$c = 2;\n$b = array(2);\n"}},
+		})
+
+		for _, action := range ts.codeActions(t, documentURI, published) {
+			if action.Edit != nil {
+				t.Errorf("Expected no rule diff fix for a changed document, got %q", action.Title)
+			}
+		}
+	})
+}
+
+// TestServerDisableRule tests the quick fixes disabling the php-cs-fixer rules in the project configuration
+func TestServerDisableRule(t *testing.T) {
+	disableFixes := func(actions []protocol.CodeAction) map[string]protocol.CodeAction {
+		fixes := map[string]protocol.CodeAction{}
+		for _, action := range actions {
+			if strings.HasPrefix(action.Title, "Disable ") {
+				fixes[action.Title] = action
+			}
+		}
+		return fixes
+	}
+
+	t.Run("rules array", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+		configPath := filepath.Join(filepath.Dir(filepath.Dir(documentURI.Filename())), ".php-cs-fixer.dist.php")
+		writeFile(t, configPath, "<?php\n\nreturn (new PhpCsFixer\\Config())\n    ->setRules([\n        '@PSR12' => true,\n        'array_syntax' => ['syntax' => 'short'],\n    ]);\n")
+
+		// Offered once per rule
+		fixes := disableFixes(ts.codeActions(t, documentURI, append(published, published...)))
+		if len(fixes) != 2 {
+			t.Fatalf("Expected one fix per rule, got %v", fixes)
+		}
+
+		tests := []struct {
+			rule string
+			edit protocol.TextEdit
+		}{
+			{
+				rule: "array_syntax",
+				edit: protocol.TextEdit{
+					Range:   protocol.Range{Start: protocol.Position{Line: 5}, End: protocol.Position{Line: 6}},
+					NewText: "        'array_syntax' => false,\n",
+				},
+			},
+			{
+				// Rules not configured are added first
+				rule: phpCsFixerSemicolonRule,
+				edit: protocol.TextEdit{
+					Range:   protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 4}},
+					NewText: "        '" + phpCsFixerSemicolonRule + "' => false,\n",
+				},
+			},
+		}
+		configURI := utils.PathToURI(configPath)
+		for _, tt := range tests {
+			fix, exists := fixes["Disable "+tt.rule+" in .php-cs-fixer.dist.php"]
+			if !exists {
+				t.Errorf("Expected a fix disabling %s, got %v", tt.rule, fixes)
+				continue
+			}
+			if fix.Edit == nil || len(fix.Edit.Changes[configURI]) != 1 {
+				t.Fatalf("Expected one edit of the configuration, got %+v", fix.Edit)
+			}
+			if edit := fix.Edit.Changes[configURI][0]; edit != tt.edit {
+				t.Errorf("Expected %+v, got %+v", tt.edit, edit)
+			}
+		}
+	})
+
+	t.Run("no rules array", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+		configPath := filepath.Join(filepath.Dir(filepath.Dir(documentURI.Filename())), ".php-cs-fixer.php")
+		writeFile(t, configPath, "<?php\n\nreturn (new PhpCsFixer\\Config())->setRules($rules);\n")
+
+		if fixes := disableFixes(ts.codeActions(t, documentURI, published)); len(fixes) != 0 {
+			t.Errorf("Expected no fix without a literal rules array, got %v", fixes)
+		}
+	})
+
+	t.Run("no configuration", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+
+		if fixes := disableFixes(ts.codeActions(t, documentURI, published)); len(fixes) != 0 {
+			t.Errorf("Expected no fix without configuration file, got %v", fixes)
+		}
+	})
+}
+
+// TestServerPhpStanSuppress tests the quick fixes ignoring the phpstan errors inline
+func TestServerPhpStanSuppress(t *testing.T) {
+	fakePhpstan := newFakeTool(t, "phpstan", `cat <<'JSON'
+{"totals": {"errors": 0, "file_errors": 2}, "files": {"src/Foo.php": {"errors": 2, "messages": [
+  {"message": "Undefined variable: $foo", "line": 4, "ignorable": true, "identifier": "variable.undefined"},
+  {"message": "Undefined variable: $bar", "line": 5, "ignorable": true}
+]}}, "errors": []}
+JSON
+exit 1
+`)
+	projectRoot := t.TempDir()
+	writeConfig(t, projectRoot, map[string]interface{}{
+		"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, nil),
+	})
+	ts := newTestServer(t, projectRoot, nil, nil)
+
+	content := "<?php\nfunction total(): int\n{\n    return $foo;\n    echo \"é\" . $bar;\n}\n"
+	documentURI := ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), content)
+	published := ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool {
+		return len(diags) == 2
+	})
+
+	fixes := map[string]protocol.CodeAction{}
+	for _, action := range ts.codeActions(t, documentURI, published) {
+		fixes[action.Title] = action
+	}
+
+	tests := []struct {
+		title string
+		edit  protocol.TextEdit
+	}{
+		{
+			// Above the line, with its indentation
+			title: "Ignore variable.undefined on this line",
+			edit: protocol.TextEdit{
+				Range:   protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3}},
+				NewText: "    /** @phpstan-ignore variable.undefined */\n",
+			},
+		},
+		{
+			// At the end of the line, counting UTF-16 code units
+			title: "Ignore phpstan errors on this line",
+			edit: protocol.TextEdit{
+				Range:   protocol.Range{Start: protocol.Position{Line: 4, Character: 20}, End: protocol.Position{Line: 4, Character: 20}},
+				NewText: " // @phpstan-ignore-line",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			fix, exists := fixes[tt.title]
+			if !exists {
+				t.Fatalf("Expected the quick fix, got %v", fixes)
+			}
+			if fix.Kind != protocol.QuickFix || len(fix.Diagnostics) != 1 {
+				t.Errorf("Expected a quick fix of one diagnostic, got %+v", fix)
+			}
+			if fix.Edit == nil || len(fix.Edit.Changes[documentURI]) != 1 {
+				t.Fatalf("Expected one edit of the document, got %+v", fix.Edit)
+			}
+			if edit := fix.Edit.Changes[documentURI][0]; edit != tt.edit {
+				t.Errorf("Expected %+v, got %+v", tt.edit, edit)
+			}
+		})
+	}
+}
+
+// TestServerPullDiagnostics tests the diagnostics pulled by the client with result ids
+func TestServerPullDiagnostics(t *testing.T) {
+	newPullTestServer := func(t *testing.T, capabilities map[string]interface{}) (*testServer, string, func() int) {
+		runs := filepath.Join(t.TempDir(), "runs")
+		fakePhpstan := newFakeTool(t, "phpstan", `echo run >> `+runs+`
+cat <<'JSON'
+{"totals": {"errors": 0, "file_errors": 1}, "files": {"src/Foo.php": {"errors": 1, "messages": [
+  {"message": "Undefined variable: $foo", "line": 2, "ignorable": true, "identifier": "variable.undefined"}
+]}}, "errors": []}
+JSON
+exit 1
+`)
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, nil),
+		})
+
+		countRuns := func() int {
+			content, _ := os.ReadFile(runs)
+			return strings.Count(string(content), "run")
+		}
+		return newTestServer(t, projectRoot, capabilities, nil), projectRoot, countRuns
+	}
+
+	pull := func(t *testing.T, ts *testServer, documentURI protocol.DocumentURI, previousResultId string) map[string]interface{} {
+		result := ts.request(t, "textDocument/diagnostic", map[string]interface{}{
+			"textDocument":     protocol.TextDocumentIdentifier{URI: documentURI},
+			"previousResultId": previousResultId,
+		})
+		var report map[string]interface{}
+		if err := json.Unmarshal(result, &report); err != nil {
+			t.Fatalf("Invalid report %s: %v", result, err)
+		}
+		return report
+	}
+
+	pullCapabilities := map[string]interface{}{
+		"textDocument": map[string]interface{}{"diagnostic": map[string]interface{}{}},
+		"workspace":    map[string]interface{}{"diagnostics": map[string]interface{}{"refreshSupport": true}},
+	}
+
+	t.Run("capabilities", func(t *testing.T) {
+		diagnosticProvider := func(ts *testServer) map[string]interface{} {
+			var result struct {
+				Capabilities struct {
+					DiagnosticProvider map[string]interface{} `json:"diagnosticProvider"`
+				} `json:"capabilities"`
+			}
+			if err := json.Unmarshal(ts.initializeResult, &result); err != nil {
+				t.Fatalf("Invalid initialize result %s: %v", ts.initializeResult, err)
+			}
+			return result.Capabilities.DiagnosticProvider
+		}
+
+		ts, _, _ := newPullTestServer(t, pullCapabilities)
+		if provider := diagnosticProvider(ts); provider == nil || provider["interFileDependencies"] != true {
+			t.Errorf("Expected the diagnosticProvider capability with inter-file dependencies, got %v", provider)
+		}
+
+		ts, _, _ = newPullTestServer(t, nil)
+		if provider := diagnosticProvider(ts); provider != nil {
+			t.Errorf("Expected no diagnosticProvider for a client not pulling diagnostics, got %v", provider)
+		}
+	})
+
+	t.Run("pull", func(t *testing.T) {
+		ts, projectRoot, countRuns := newPullTestServer(t, pullCapabilities)
+		documentURI := ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), "<?php\necho $foo;\n")
+
+		// Analyzed on the first pull only
+		time.Sleep(500 * time.Millisecond)
+		if runs := countRuns(); runs != 0 {
+			t.Fatalf("Expected no analysis before the pull, got %d", runs)
+		}
+		report := pull(t, ts, documentURI, "")
+		items, _ := report["items"].([]interface{})
+		if report["kind"] != "full" || report["resultId"] == "" || len(items) != 1 {
+			t.Fatalf("Expected a full report with 1 item, got %v", report)
+		}
+		resultId := report["resultId"].(string)
+
+		// The result held by the client is reported unchanged, without analysis
+		if report := pull(t, ts, documentURI, resultId); report["kind"] != "unchanged" || report["resultId"] != resultId {
+			t.Errorf("Expected an unchanged report %s, got %v", resultId, report)
+		}
+		if runs := countRuns(); runs != 1 {
+			t.Errorf("Expected 1 analysis, got %d", runs)
+		}
+
+		// A change makes the next pull analyze the document again
+		ts.notify(t, protocol.MethodTextDocumentDidChange, protocol.DidChangeTextDocumentParams{
+			TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: documentURI}, Version: 2},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "<?php\necho $foo . 1;\n"}},
+		})
+		report = pull(t, ts, documentURI, resultId)
+		if report["kind"] != "full" || report["resultId"] == resultId {
+			t.Errorf("Expected a full report with a new result id, got %v", report)
+		}
+		if runs := countRuns(); runs != 2 {
+			t.Errorf("Expected 2 analyses, got %d", runs)
+		}
+
+		// Open documents are never published
+		for _, params := range ts.client.sent(protocol.MethodTextDocumentPublishDiagnostics) {
+			if strings.Contains(string(params), "Undefined variable") {
+				t.Errorf("Expected no published diagnostics, got %s", params)
+			}
+		}
+	})
+
+	t.Run("other analyses", func(t *testing.T) {
+		ts, projectRoot, countRuns := newPullTestServer(t, pullCapabilities)
+		documentURI := ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), "<?php\necho $foo;\n")
+		resultId := pull(t, ts, documentURI, "")["resultId"]
+
+		// The save analysis replaces the result and asks the client to pull again
+		ts.notify(t, protocol.MethodTextDocumentDidSave, protocol.DidSaveTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: documentURI}})
+		ts.client.waitFor(t, "workspace/diagnostic/refresh", func(json.RawMessage) bool { return true })
+		if runs := countRuns(); runs != 2 {
+			t.Errorf("Expected the save analysis, got %d analyses", runs)
+		}
+		if report := pull(t, ts, documentURI, resultId.(string)); report["kind"] != "full" || report["resultId"] == resultId {
+			t.Errorf("Expected the save result reported, got %v", report)
+		}
+	})
+
+	t.Run("push client", func(t *testing.T) {
+		ts, projectRoot, _ := newPullTestServer(t, nil)
+		documentURI := ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), "<?php\necho $foo;\n")
+
+		ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool { return len(diags) == 1 })
+	})
+}
+
+// TestServerFileWatchers documents the file watchers registered by the server
+func TestServerFileWatchers(t *testing.T) {
+	t.Log("Once initialized, clients supporting dynamic registration are asked to watch the files with client/registerCapability")
+	t.Log("Globs: **/*<extension> of every project, **/<configuration file name> and the provider configuration files")
+	t.Log("Reloading the configuration unregisters the watchers and registers them again")
+	t.Log("A changed provider configuration file schedules the analysis of the open documents of its project")
+}
+
+// TestServerCancellation documents the cancellation of the requests and of the superseded analyses
+func TestServerCancellation(t *testing.T) {
+	t.Log("Every request is handled with a context cancelled by its $/cancelRequest until it is replied")
+	t.Log("A cancelled request failing is replied with the RequestCancelled error, the reply itself is never cancelled")
+	t.Log("Scheduling a new analysis of a file cancels the running one, killing the docker exec of its tools")
+	t.Log("The context of an analysis is released once it finishes, unless a newer analysis took over")
+}
+
+// TestServerAnalysisProgress documents the progress of the document analyses
+func TestServerAnalysisProgress(t *testing.T) {
+	t.Log("Analyses of open documents create a work done progress once they run for analysisProgressDelay")
+	t.Log("The begin and report messages list the providers still running, the percentage is the share of providers done")
+	t.Log("The progress ends with the analysis; faster analyses and clients without progress support get none")
+}
+
+// TestServerFixAll tests the fix-all action and the workspace edits of the fixAll command
+func TestServerFixAll(t *testing.T) {
+	t.Run("action", func(t *testing.T) {
+		ts, documentURI, published := newPhpCsFixerTestServer(t, nil)
+
+		var fixAll *protocol.CodeAction
+		for _, action := range ts.codeActions(t, documentURI, published) {
+			if action.Kind == "source.fixAll" {
+				fixAll = &action
+			}
+		}
+		if fixAll == nil {
+			t.Fatal("Expected a source.fixAll action")
+		}
+		if fixAll.Command == nil || fixAll.Command.Command != "php-diagls/fixAll" || len(fixAll.Command.Arguments) != 1 || fixAll.Command.Arguments[0] != string(documentURI) {
+			t.Errorf("Expected the fixAll command of the document, got %+v", fixAll.Command)
+		}
+	})
+
+	t.Run("plain edits", func(t *testing.T) {
+		ts, documentURI, _ := newPhpCsFixerTestServer(t, nil)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/fixAll",
+			Arguments: []interface{}{string(documentURI)},
+		})
+
+		edit := waitForApplyEdit(t, ts.client)
+		edits := edit.Edit.Changes[documentURI]
+		if len(edits) != 2 {
+			t.Fatalf("Expected one edit per changed block, got %+v", edit.Edit)
+		}
+		assertLineEdit(t, edits[0], 1, "echo \"a\";\n")
+		assertLineEdit(t, edits[1], 3, "$b = [1];\n")
+		if len(edit.Edit.DocumentChanges) != 0 {
+			t.Errorf("Expected no annotated edits without client support, got %+v", edit.Edit.DocumentChanges)
+		}
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		ts, documentURI, _ := newPhpCsFixerTestServer(t, changeAnnotationsCapabilities)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/fixAll",
+			Arguments: []interface{}{string(documentURI)},
+		})
+
+		edit := waitForApplyEdit(t, ts.client)
+		if len(edit.Edit.DocumentChanges) != 1 || len(edit.Edit.DocumentChanges[0].Edits) != 2 {
+			t.Fatalf("Expected the annotated edits of the document, got %+v", edit.Edit)
+		}
+		edits := edit.Edit.DocumentChanges[0].Edits
+		assertLineEdit(t, edits[0].TextEdit, 1, "echo \"a\";\n")
+		assertLineEdit(t, edits[1].TextEdit, 3, "$b = [1];\n")
+
+		// The whitespace fix is harmless, the array syntax one needs a confirmation
+		if edits[0].AnnotationID != "layout" || edits[1].AnnotationID != "code" {
+			t.Errorf("Expected layout then code annotations, got %s and %s", edits[0].AnnotationID, edits[1].AnnotationID)
+		}
+		layout, code := edit.Edit.ChangeAnnotations["layout"], edit.Edit.ChangeAnnotations["code"]
+		if layout.NeedsConfirmation || layout.Description != phpCsFixerSemicolonRule {
+			t.Errorf("Expected the layout fixes of %s without confirmation, got %+v", phpCsFixerSemicolonRule, layout)
+		}
+		if !code.NeedsConfirmation || code.Description != "array_syntax" {
+			t.Errorf("Expected the code fixes of array_syntax with confirmation, got %+v", code)
+		}
+	})
+
+	t.Run("rule", func(t *testing.T) {
+		ts, documentURI, _ := newPhpCsFixerTestServer(t, changeAnnotationsCapabilities)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/fixAll",
+			Arguments: []interface{}{string(documentURI), phpCsFixerSemicolonRule},
+		})
+
+		edit := waitForApplyEdit(t, ts.client)
+		if len(edit.Edit.DocumentChanges) != 1 || len(edit.Edit.DocumentChanges[0].Edits) != 1 {
+			t.Fatalf("Expected the edit of the rule only, got %+v", edit.Edit)
+		}
+		assertLineEdit(t, edit.Edit.DocumentChanges[0].Edits[0].TextEdit, 1, "echo \"a\";\n")
+		if label := edit.Edit.ChangeAnnotations["layout"].Description; label != phpCsFixerSemicolonRule {
+			t.Errorf("Expected the change annotated with %s, got %q", phpCsFixerSemicolonRule, label)
+		}
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		ts, _, _ := newPhpCsFixerTestServer(t, nil)
+
+		_, err := ts.requestErr(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/fixAll",
+			Arguments: []interface{}{"file:///nowhere/Missing.php"},
+		})
+		if err == nil {
+			t.Error("Expected the command to fail for a missing document")
+		}
+	})
+}
+
+// The php-cs-fixer analysis of the fake tool: a cosmetic rule on line 2, a structural one on line 4
+const (
+	phpCsFixerSemicolonRule = "no_singleline_whitespace_before_semicolons"
+	phpCsFixerContent       = "<?php\necho \"a\" ;\n$c = 1;\n$b = array(1);\n"
+	phpCsFixerSemicolonDiff = "--- a\n+++ b\n@@ -1,4 +1,4 @@\n <?php\n-echo \"a\" ;\n+echo \"a\";\n $c = 1;\n $b = array(1);\n"
+	phpCsFixerArrayDiff     = "--- a\n+++ b\n@@ -1,4 +1,4 @@\n <?php\n echo \"a\" ;\n $c = 1;\n-$b = array(1);\n+$b = [1];\n"
+	phpCsFixerDiff          = "--- a\n+++ b\n@@ -1,4 +1,4 @@\n <?php\n-echo \"a\" ;\n+echo \"a\";\n $c = 1;\n-$b = array(1);\n+$b = [1];\n"
+)
+
+// Client capabilities previewing annotated workspace edits
+var changeAnnotationsCapabilities = map[string]interface{}{
+	"workspace": map[string]interface{}{
+		"workspaceEdit": map[string]interface{}{"documentChanges": true, "changeAnnotationSupport": map[string]interface{}{}},
+	},
+}
+
+// newPhpCsFixerTestServer opens src/Foo.php in a project analyzed and formatted by a fake php-cs-fixer,
+// returning its published diagnostics
+func newPhpCsFixerTestServer(t *testing.T, capabilities map[string]interface{}) (*testServer, protocol.DocumentURI, []protocol.Diagnostic) {
+	t.Helper()
+
+	report := func(diff string, rules ...string) string {
+		output, _ := json.Marshal(map[string]interface{}{
+			"files": []map[string]interface{}{{"name": "src/Foo.php", "diff": diff, "appliedFixers": rules}},
+		})
+		return string(output)
+	}
+	fakeFixer := newFakeTool(t, "php-cs-fixer", `case "$*" in
+  describe*) echo "Description of the rule."; exit 0;;
+  *"--format json --rules `+phpCsFixerSemicolonRule+`"*) cat <<'JSON'
+`+report(phpCsFixerSemicolonDiff, phpCsFixerSemicolonRule)+`
+JSON
+    exit 8;;
+  *"--format json --rules array_syntax"*) cat <<'JSON'
+`+report(phpCsFixerArrayDiff, "array_syntax")+`
+JSON
+    exit 8;;
+  *"--format json"*) cat <<'JSON'
+`+report(phpCsFixerDiff, phpCsFixerSemicolonRule, "array_syntax")+`
+JSON
+    exit 8;;
+  *"--rules `+phpCsFixerSemicolonRule+`"*) cat >/dev/null; cat <<'DIFF'
+`+phpCsFixerSemicolonDiff+`DIFF
+    exit 8;;
+  *) cat >/dev/null; cat <<'DIFF'
+`+phpCsFixerDiff+`DIFF
+    exit 8;;
+esac
+`)
+
+	projectRoot := t.TempDir()
+	writeConfig(t, projectRoot, map[string]interface{}{
+		"phpcsfixer": fakeProvider("vendor/bin/php-cs-fixer", fakeFixer, map[string]interface{}{"format": map[string]interface{}{"enabled": true}}),
+	})
+	ts := newTestServer(t, projectRoot, capabilities, nil)
+
+	documentURI := ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), phpCsFixerContent)
+	published := ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool {
+		return len(diags) == 2
+	})
+	return ts, documentURI, published
+}
+
+// applyEdit holds the plain or annotated workspace edit sent with workspace/applyEdit
+type applyEdit struct {
+	Label string `json:"label"`
+	Edit  struct {
+		Changes         map[protocol.DocumentURI][]protocol.TextEdit `json:"changes"`
+		DocumentChanges []struct {
+			TextDocument protocol.OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+			Edits        []protocol.AnnotatedTextEdit                     `json:"edits"`
+		} `json:"documentChanges"`
+		ChangeAnnotations map[string]protocol.ChangeAnnotation `json:"changeAnnotations"`
+	} `json:"edit"`
+}
+
+// waitForApplyEdit waits for the first workspace/applyEdit sent to the client
+func waitForApplyEdit(t *testing.T, client *fakeClient) applyEdit {
+	t.Helper()
+
+	var edit applyEdit
+	params := client.waitFor(t, protocol.MethodWorkspaceApplyEdit, func(json.RawMessage) bool { return true })
+	if err := json.Unmarshal(params, &edit); err != nil {
+		t.Fatalf("Invalid workspace/applyEdit %s: %v", params, err)
+	}
+	return edit
+}
+
+// assertLineEdit checks the edit replaces the line with the text
+func assertLineEdit(t *testing.T, edit protocol.TextEdit, line uint32, newText string) {
+	t.Helper()
+
+	if edit.Range.Start.Line != line || edit.Range.End.Line != line+1 || edit.NewText != newText {
+		t.Errorf("Expected line %d replaced with %q, got %+v", line, newText, edit)
+	}
+}
+
+// TestServerOrganizeImports tests the organize imports action and command
+func TestServerOrganizeImports(t *testing.T) {
+	const (
+		content = "<?php\nuse A\\One;\nuse C\\Unused;\n\nnew One();\n"
+		diff    = "--- a\n+++ b\n@@ -1,5 +1,4 @@\n <?php\n use A\\One;\n-use C\\Unused;\n \n new One();\n"
+	)
+	// Only the import rules change the document, any other run fails
+	fakeFixer := newFakeTool(t, "php-cs-fixer", `case "$*" in
+  *"--format json"*) echo '{"files": []}'; exit 0;;
+  *"--rules no_unused_imports,ordered_imports"*) input=$(cat)
+    case "$input" in
+      *Unused*) cat <<'DIFF'
+`+diff+`DIFF
+        exit 8;;
+    esac
+    exit 0;;
+  *) exit 1;;
+esac
+`)
+
+	newOrganizeImportsTestServer := func(t *testing.T, enabled bool) (*testServer, protocol.DocumentURI) {
+		projectRoot := t.TempDir()
+		providerConfig := fakeProvider("vendor/bin/php-cs-fixer", fakeFixer, map[string]interface{}{"enabled": enabled})
+		writeConfig(t, projectRoot, map[string]interface{}{"phpcsfixer": providerConfig})
+		ts := newTestServer(t, projectRoot, nil, nil)
+		return ts, ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), content)
+	}
+	organizeImports := func(actions []protocol.CodeAction) *protocol.CodeAction {
+		for _, action := range actions {
+			if action.Kind == protocol.SourceOrganizeImports {
+				return &action
+			}
+		}
+		return nil
+	}
+
+	t.Run("action", func(t *testing.T) {
+		ts, documentURI := newOrganizeImportsTestServer(t, true)
+
+		action := organizeImports(ts.codeActions(t, documentURI, nil))
+		if action == nil {
+			t.Fatal("Expected a source.organizeImports action")
+		}
+		if action.Command == nil || action.Command.Command != "php-diagls/organizeImports" || len(action.Command.Arguments) != 1 || action.Command.Arguments[0] != string(documentURI) {
+			t.Errorf("Expected the organizeImports command of the document, got %+v", action.Command)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ts, documentURI := newOrganizeImportsTestServer(t, false)
+
+		if action := organizeImports(ts.codeActions(t, documentURI, nil)); action != nil {
+			t.Errorf("Expected no action without php-cs-fixer, got %+v", action)
+		}
+	})
+
+	t.Run("command", func(t *testing.T) {
+		ts, documentURI := newOrganizeImportsTestServer(t, true)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/organizeImports",
+			Arguments: []interface{}{string(documentURI)},
+		})
+
+		edit := waitForApplyEdit(t, ts.client)
+		if edit.Label != "Organize imports" {
+			t.Errorf("Expected the Organize imports label, got %q", edit.Label)
+		}
+		edits := edit.Edit.Changes[documentURI]
+		if len(edits) != 1 {
+			t.Fatalf("Expected the removal of the unused import, got %+v", edit.Edit)
+		}
+		assertLineEdit(t, edits[0], 2, "")
+	})
+
+	t.Run("organized", func(t *testing.T) {
+		ts, documentURI := newOrganizeImportsTestServer(t, true)
+		ts.notify(t, protocol.MethodTextDocumentDidChange, protocol.DidChangeTextDocumentParams{
+			TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: documentURI}, Version: 2},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "<?php\nuse A\\One;\n\nnew One();\n"}},
+		})
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/organizeImports",
+			Arguments: []interface{}{string(documentURI)},
+		})
+
+		// The edits are sent asynchronously, the one of a document still to organize comes alone
+		otherURI := ts.open(t, filepath.Join(filepath.Dir(documentURI.Filename()), "Bar.php"), content)
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/organizeImports",
+			Arguments: []interface{}{string(otherURI)},
+		})
+		edit := waitForApplyEdit(t, ts.client)
+		if _, exists := edit.Edit.Changes[otherURI]; !exists || len(edit.Edit.Changes) != 1 {
+			t.Errorf("Expected no edit when the imports are organized, got %+v", edit.Edit)
+		}
+		if edits := ts.client.sent(protocol.MethodWorkspaceApplyEdit); len(edits) != 1 {
+			t.Errorf("Expected a single edit, got %s", edits)
+		}
+	})
+}
+
+// TestServerFormatWorkspace tests the workspace formatting command
+func TestServerFormatWorkspace(t *testing.T) {
+	const (
+		content   = "<?php\necho \"a\" ;\n"
+		formatted = "<?php\necho \"a\";\n"
+		diff      = "--- a\n+++ b\n@@ -1,2 +1,2 @@\n <?php\n-echo \"a\" ;\n+echo \"a\";\n"
+	)
+	fakeFixer := newFakeTool(t, "php-cs-fixer", `case "$*" in
+  *"--format json"*) echo '{"files": []}'; exit 0;;
+  *) cat >/dev/null; cat <<'DIFF'
+`+diff+`DIFF
+    exit 8;;
+esac
+`)
+
+	newFormatWorkspaceTestServer := func(t *testing.T, options map[string]interface{}) (*testServer, string) {
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpcsfixer": fakeProvider("vendor/bin/php-cs-fixer", fakeFixer, map[string]interface{}{"format": map[string]interface{}{"enabled": true}}),
+		})
+		return newTestServer(t, projectRoot, nil, options), projectRoot
+	}
+
+	t.Run("open and closed files", func(t *testing.T) {
+		ts, projectRoot := newFormatWorkspaceTestServer(t, nil)
+		openPath, closedPath := filepath.Join(projectRoot, "src/Open.php"), filepath.Join(projectRoot, "src/Closed.php")
+		writeFile(t, closedPath, content)
+		openURI := ts.open(t, openPath, content)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command: "php-diagls/formatWorkspace",
+		})
+
+		// The open document is changed through its buffer
+		edit := waitForApplyEdit(t, ts.client)
+		if edit.Label != "Format workspace" || len(edit.Edit.Changes) != 1 || len(edit.Edit.Changes[openURI]) != 1 {
+			t.Fatalf("Expected the edit of the open document only, got %+v", edit)
+		}
+		assertLineEdit(t, edit.Edit.Changes[openURI][0], 1, "echo \"a\";\n")
+		if written, _ := os.ReadFile(openPath); string(written) != content {
+			t.Errorf("Expected the open document left on disk, got %q", written)
+		}
+
+		// The closed file is written in the same batch
+		if written, _ := os.ReadFile(closedPath); string(written) != formatted {
+			t.Errorf("Expected the closed file formatted on disk, got %q", written)
+		}
+	})
+
+	t.Run("buffer-only", func(t *testing.T) {
+		ts, _ := newFormatWorkspaceTestServer(t, map[string]interface{}{"bufferOnly": true})
+
+		_, err := ts.requestErr(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command: "php-diagls/formatWorkspace",
+		})
+		if err == nil {
+			t.Error("Expected the command to fail in buffer-only mode")
+		}
+	})
+}
+
+// TestServerFormatWith tests the formatter selection and the command formatting with a given provider
+func TestServerFormatWith(t *testing.T) {
+	// php-cs-fixer fixes the semicolon, pint the array syntax
+	fakeFixer := newFakeTool(t, "php-cs-fixer", `case "$*" in
+  *"--format json"*) echo '{"files": []}'; exit 0;;
+  *) cat >/dev/null; cat <<'DIFF'
+`+phpCsFixerSemicolonDiff+`DIFF
+    exit 8;;
+esac
+`)
+	pintReport, _ := json.Marshal(map[string]interface{}{
+		"files": []map[string]interface{}{{"name": "src/Foo.php", "diff": phpCsFixerArrayDiff, "appliedFixers": []string{"array_syntax"}}},
+	})
+	fakePint := newFakeTool(t, "pint", "cat <<'JSON'\n"+string(pintReport)+"\nJSON\nexit 8\n")
+
+	newFormatWithTestServer := func(t *testing.T, pintFormat map[string]interface{}) (*testServer, protocol.DocumentURI) {
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpcsfixer": fakeProvider("vendor/bin/php-cs-fixer", fakeFixer, map[string]interface{}{"format": map[string]interface{}{"enabled": true}}),
+			"pint":       fakeProvider("vendor/bin/pint", fakePint, map[string]interface{}{"format": pintFormat}),
+		})
+		ts := newTestServer(t, projectRoot, nil, nil)
+		return ts, ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), phpCsFixerContent)
+	}
+	formatting := func(t *testing.T, ts *testServer, documentURI protocol.DocumentURI) []protocol.TextEdit {
+		t.Helper()
+
+		result := ts.request(t, protocol.MethodTextDocumentFormatting, protocol.DocumentFormattingParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+		})
+		var edits []protocol.TextEdit
+		if err := json.Unmarshal(result, &edits); err != nil {
+			t.Fatalf("Invalid formatting edits %s: %v", result, err)
+		}
+		return edits
+	}
+
+	t.Run("priority", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			pintFormat map[string]interface{}
+			line       uint32
+			newText    string
+		}{
+			{"same priority, by id", map[string]interface{}{"enabled": true}, 1, "echo \"a\";\n"},
+			{"higher priority", map[string]interface{}{"enabled": true, "priority": 10}, 3, "$b = [1];\n"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ts, documentURI := newFormatWithTestServer(t, tt.pintFormat)
+
+				edits := formatting(t, ts, documentURI)
+				if len(edits) != 1 {
+					t.Fatalf("Expected the edit of a single formatter, got %+v", edits)
+				}
+				assertLineEdit(t, edits[0], tt.line, tt.newText)
+			})
+		}
+	})
+
+	t.Run("command", func(t *testing.T) {
+		// Formatting with pint isn't enabled
+		ts, documentURI := newFormatWithTestServer(t, nil)
+
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/formatWith",
+			Arguments: []interface{}{string(documentURI), "pint"},
+		})
+
+		edit := waitForApplyEdit(t, ts.client)
+		if edit.Label != "Format with pint" || len(edit.Edit.Changes[documentURI]) != 1 {
+			t.Fatalf("Expected the pint edit of the document, got %+v", edit)
+		}
+		assertLineEdit(t, edit.Edit.Changes[documentURI][0], 3, "$b = [1];\n")
+	})
+
+	t.Run("provider not enabled", func(t *testing.T) {
+		ts, documentURI := newFormatWithTestServer(t, nil)
+
+		_, err := ts.requestErr(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/formatWith",
+			Arguments: []interface{}{string(documentURI), "ecs"},
+		})
+		if err == nil {
+			t.Error("Expected the command to fail for a provider not enabled")
+		}
+	})
+}
+
+// TestServerScaffoldConfig tests the missing tool configuration handling
+func TestServerScaffoldConfig(t *testing.T) {
+	t.Run("detection", func(t *testing.T) {
+		projectRoot := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(projectRoot, "config"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpstan": fakeProvider("vendor/bin/phpstan", "/bin/false", map[string]interface{}{"configFile": "/app/config/phpstan.neon"}),
+			// Outside the project mount, only the container can tell whether it exists
+			"phpcsfixer": fakeProvider("vendor/bin/php-cs-fixer", "/bin/false", map[string]interface{}{"configFile": "/etc/php-cs-fixer.php"}),
+		})
+		ts := newTestServer(t, projectRoot, nil, nil)
+
+		params := ts.client.waitFor(t, protocol.MethodWindowShowMessageRequest, func(json.RawMessage) bool { return true })
+		var request protocol.ShowMessageRequestParams
+		if err := json.Unmarshal(params, &request); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(request.Message, "/app/config/phpstan.neon") || len(request.Actions) != 1 || request.Actions[0].Title != "Create phpstan.neon" {
+			t.Errorf("Expected the phpstan configuration offered, got %+v", request)
+		}
+		if requests := ts.client.sent(protocol.MethodWindowShowMessageRequest); len(requests) != 1 {
+			t.Errorf("Expected a single offer, got %s", requests)
+		}
+	})
+
+	t.Run("scaffold", func(t *testing.T) {
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpstan": fakeProvider("vendor/bin/phpstan", "/bin/false", nil),
+		})
+		ts := newTestServer(t, projectRoot, nil, nil)
+
+		result := ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/scaffoldConfig",
+			Arguments: []interface{}{"phpstan"},
+		})
+		configPath := filepath.Join(projectRoot, "phpstan.neon")
+		var hostPath string
+		if err := json.Unmarshal(result, &hostPath); err != nil || hostPath != configPath {
+			t.Errorf("Expected %s to be written, got %s", configPath, result)
+		}
+		if content, err := os.ReadFile(configPath); err != nil || !strings.Contains(string(content), "parameters:") {
+			t.Errorf("Expected the default phpstan configuration, got %q (%v)", content, err)
+		}
+
+		// Existing files are never replaced
+		_, err := ts.requestErr(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/scaffoldConfig",
+			Arguments: []interface{}{"phpstan"},
+		})
+		if err == nil {
+			t.Error("Expected the command to fail for an existing file")
+		}
+	})
+
+	t.Run("outside the project", func(t *testing.T) {
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpcsfixer": fakeProvider("vendor/bin/php-cs-fixer", "/bin/false", map[string]interface{}{"configFile": "/etc/php-cs-fixer.php"}),
+		})
+		ts := newTestServer(t, projectRoot, nil, nil)
+
+		_, err := ts.requestErr(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{
+			Command:   "php-diagls/scaffoldConfig",
+			Arguments: []interface{}{"phpcsfixer"},
+		})
+		if err == nil {
+			t.Error("Expected the command to fail for a configuration file outside the project")
+		}
+		if _, err := os.Stat(filepath.Join(projectRoot, "php-cs-fixer.php")); err == nil {
+			t.Error("Expected no configuration written in the project")
+		}
+	})
+}
+
+// TestServerProviderErrors documents the provider error popups
+func TestServerProviderErrors(t *testing.T) {
+	t.Run("deduplication", func(t *testing.T) {
+		t.Log("Every provider failure is logged")
+		t.Log("Errors are classified with container.ErrorClass (unavailable, tool-missing, permission, ...)")
+		t.Log("window/showMessage is sent once per session per (provider, error class)")
+		t.Log("Messages end with '(see logs for details)'")
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		t.Log("At most 5 window/showMessage notifications per sliding minute, all message types")
+		t.Log("Suppressed messages are logged and counted")
+		t.Log("The next message shown carries the count, or a warning reports it once a slot frees up")
+	})
+}
+
+// TestServerLastKnownDiagnostics tests the diagnostics kept across restarts
+func TestServerLastKnownDiagnostics(t *testing.T) {
+	// The fake phpstan reports the message of its message file on line 2, none when it's empty
+	newLastKnownTestServer := func(t *testing.T) (ts *testServer, filePath string, setMessage func(string), runs func() int) {
+		toolDir := t.TempDir()
+		messageFile, runsFile := filepath.Join(toolDir, "message"), filepath.Join(toolDir, "runs")
+		setMessage = func(message string) { writeFile(t, messageFile, message) }
+		runs = func() int {
+			content, _ := os.ReadFile(runsFile)
+			return strings.Count(string(content), "\n")
+		}
+		fakePhpstan := newFakeTool(t, "phpstan", `echo run >> `+runsFile+`
+message=$(cat `+messageFile+`)
+if [ -z "$message" ]; then
+  echo '{"totals": {"errors": 0, "file_errors": 0}, "files": {}, "errors": []}'
+  exit 0
+fi
+cat <<JSON
+{"totals": {"errors": 0, "file_errors": 1}, "files": {"src/Foo.php": {"errors": 1, "messages": [
+  {"message": "$message", "line": 2, "ignorable": true}
+]}}, "errors": []}
+JSON
+exit 1
+`)
+		setMessage("Old error")
+
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, nil),
+		})
+		return newTestServer(t, projectRoot, nil, nil), filepath.Join(projectRoot, "src/Foo.php"), setMessage, runs
+	}
+	hasMessage := func(message string) func([]protocol.Diagnostic) bool {
+		return func(diags []protocol.Diagnostic) bool {
+			return len(diags) == 1 && diags[0].Message == message
+		}
+	}
+	// didOpen sends the document without writing it, the content on disk being the one of the warm-up
+	didOpen := func(t *testing.T, ts *testServer, filePath string, content string) protocol.DocumentURI {
+		documentURI := utils.PathToURI(filePath)
+		ts.notify(t, protocol.MethodTextDocumentDidOpen, protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: documentURI, LanguageID: "php", Version: 1, Text: content},
+		})
+		return documentURI
+	}
+	firstPublished := func(t *testing.T, client *fakeClient, documentURI protocol.DocumentURI) []protocol.Diagnostic {
+		t.Helper()
+
+		for _, params := range client.sent(protocol.MethodTextDocumentPublishDiagnostics) {
+			var published protocol.PublishDiagnosticsParams
+			if err := json.Unmarshal(params, &published); err == nil && published.URI == documentURI {
+				return published.Diagnostics
+			}
+		}
+		return nil
+	}
+
+	t.Run("republish", func(t *testing.T) {
+		ts, filePath, setMessage, _ := newLastKnownTestServer(t)
+		documentURI := ts.open(t, filePath, "<?php\n$a = 1;\n")
+		ts.client.waitForDiagnostics(t, documentURI, hasMessage("Old error"))
+
+		setMessage("New error")
+		ts = ts.restart(t)
+
+		// Shown before the analysis of the new content, then replaced by its results
+		didOpen(t, ts, filePath, "<?php\n$a = 2;\n")
+		if stale := firstPublished(t, ts.client, documentURI); len(stale) != 1 || stale[0].Message != "[stale] Old error" {
+			t.Errorf("Expected the last known diagnostics marked stale, got %+v", stale)
+		}
+		ts.client.waitForDiagnostics(t, documentURI, hasMessage("New error"))
+	})
+
+	t.Run("files without diagnostics", func(t *testing.T) {
+		ts, filePath, setMessage, _ := newLastKnownTestServer(t)
+		documentURI := ts.open(t, filePath, "<?php\n$a = 1;\n")
+		ts.client.waitForDiagnostics(t, documentURI, hasMessage("Old error"))
+
+		setMessage("")
+		ts.notify(t, protocol.MethodTextDocumentDidSave, protocol.DidSaveTextDocumentParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+		})
+		ts.client.waitForDiagnostics(t, documentURI, func(diags []protocol.Diagnostic) bool { return len(diags) == 0 })
+		ts = ts.restart(t)
+
+		didOpen(t, ts, filePath, "<?php\n$a = 2;\n")
+		if stale := firstPublished(t, ts.client, documentURI); len(stale) != 0 {
+			t.Errorf("Expected no last known diagnostics, got %+v", stale)
+		}
+	})
+
+	t.Run("warm-up", func(t *testing.T) {
+		ts, filePath, setMessage, runs := newLastKnownTestServer(t)
+		content := "<?php\n$a = 1;\n"
+		documentURI := ts.open(t, filePath, content)
+		ts.client.waitForDiagnostics(t, documentURI, hasMessage("Old error"))
+
+		// The recently opened file is analyzed on initialized
+		setMessage("Warm error")
+		ts = ts.restart(t)
+		deadline := time.Now().Add(10 * time.Second)
+		for !ts.WarmedUp(documentURI) {
+			if time.Now().After(deadline) {
+				t.Fatal("The recent file wasn't warmed up")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		warmUpRuns := runs()
+
+		// The unchanged document gets the warm-up results without analysis
+		didOpen(t, ts, filePath, content)
+		if published := firstPublished(t, ts.client, documentURI); len(published) != 1 || published[0].Message != "Warm error" {
+			t.Errorf("Expected the warm-up results, got %+v", published)
+		}
+		if runs() != warmUpRuns {
+			t.Errorf("Expected no analysis of the warmed up document, got %d runs after %d", runs(), warmUpRuns)
+		}
+	})
+
+	t.Run("warm-up progress", func(t *testing.T) {
+		ts, filePath, _, _ := newLastKnownTestServer(t)
+		documentURI := ts.open(t, filePath, "<?php\n$a = 1;\n")
+		ts.client.waitForDiagnostics(t, documentURI, hasMessage("Old error"))
+
+		// Reported like the other background jobs, the client can cancel it
+		ts.capabilities = map[string]interface{}{"window": map[string]interface{}{"workDoneProgress": true}}
+		ts = ts.restart(t)
+		ts.client.waitFor(t, protocol.MethodProgress, func(params json.RawMessage) bool {
+			return strings.Contains(string(params), `"title":"Warming up recent files"`) && strings.Contains(string(params), `"cancellable":true`)
+		})
+		ts.client.waitFor(t, protocol.MethodProgress, func(params json.RawMessage) bool {
+			return strings.Contains(string(params), `"kind":"end"`)
+		})
+	})
+}
+
+// TestServerCircuitBreaker tests the circuitBreaker provider option
+func TestServerCircuitBreaker(t *testing.T) {
+	// The fake phpstan crashes until its fixed file exists
+	newBreakerTestServer := func(t *testing.T, cooldownSeconds int) (ts *testServer, documentURI protocol.DocumentURI, fix func(), runs func() int) {
+		toolDir := t.TempDir()
+		fixedFile, runsFile := filepath.Join(toolDir, "fixed"), filepath.Join(toolDir, "runs")
+		fix = func() { writeFile(t, fixedFile, "") }
+		runs = func() int {
+			content, _ := os.ReadFile(runsFile)
+			return strings.Count(string(content), "\n")
+		}
+		fakePhpstan := newFakeTool(t, "phpstan", `echo run >> `+runsFile+`
+if [ -f `+fixedFile+` ]; then
+  echo '{"totals": {"errors": 0, "file_errors": 0}, "files": {}, "errors": []}'
+  exit 0
+fi
+echo "Segmentation fault" >&2
+exit 139
+`)
+
+		projectRoot := t.TempDir()
+		writeConfig(t, projectRoot, map[string]interface{}{
+			"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, map[string]interface{}{
+				"circuitBreaker": map[string]interface{}{"failures": 2, "cooldownSeconds": cooldownSeconds},
+			}),
+		})
+		ts = newTestServer(t, projectRoot, nil, nil)
+		documentURI = ts.open(t, filepath.Join(projectRoot, "src/Foo.php"), "<?php\n")
+		return ts, documentURI, fix, runs
+	}
+	// analyze saves the document and waits for its analysis
+	analyze := func(t *testing.T, ts *testServer, documentURI protocol.DocumentURI) {
+		t.Helper()
+
+		published := len(ts.client.sent(protocol.MethodTextDocumentPublishDiagnostics))
+		ts.notify(t, protocol.MethodTextDocumentDidSave, protocol.DidSaveTextDocumentParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+		})
+		deadline := time.Now().Add(10 * time.Second)
+		for len(ts.client.sent(protocol.MethodTextDocumentPublishDiagnostics)) == published {
+			if time.Now().After(deadline) {
+				t.Fatal("The saved document wasn't analyzed")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	suspendedMessage := func(params json.RawMessage) bool {
+		var message protocol.ShowMessageParams
+		return json.Unmarshal(params, &message) == nil && strings.Contains(message.Message, "phpstan suspended after 2 consecutive failures")
+	}
+
+	t.Run("suspension", func(t *testing.T) {
+		ts, documentURI, _, runs := newBreakerTestServer(t, 60)
+		ts.client.waitForDiagnostics(t, documentURI, func([]protocol.Diagnostic) bool { return true })
+
+		analyze(t, ts, documentURI)
+		ts.client.waitFor(t, protocol.MethodWindowShowMessage, suspendedMessage)
+
+		// The suspended provider isn't run
+		failedRuns := runs()
+		analyze(t, ts, documentURI)
+		if runs() != failedRuns {
+			t.Errorf("Expected the suspended provider skipped, got %d runs after %d", runs(), failedRuns)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		ts, documentURI, fix, runs := newBreakerTestServer(t, 1)
+		ts.client.waitForDiagnostics(t, documentURI, func([]protocol.Diagnostic) bool { return true })
+		analyze(t, ts, documentURI)
+		ts.client.waitFor(t, protocol.MethodWindowShowMessage, suspendedMessage)
+
+		// The successful retry after the cooldown resumes the provider
+		fix()
+		time.Sleep(1100 * time.Millisecond)
+		failedRuns := runs()
+		analyze(t, ts, documentURI)
+		analyze(t, ts, documentURI)
+		if runs() != failedRuns+2 {
+			t.Errorf("Expected the provider resumed, got %d runs after %d", runs(), failedRuns)
+		}
+	})
+}
+
+// TestServerWatch tests the watch provider option
+func TestServerWatch(t *testing.T) {
+	report := func(files map[string]string) string {
+		reported := map[string]interface{}{}
+		for file, message := range files {
+			reported[file] = map[string]interface{}{"errors": 1, "messages": []map[string]interface{}{{"message": message, "line": 2, "ignorable": true}}}
+		}
+		output, _ := json.Marshal(map[string]interface{}{"totals": map[string]int{"errors": 0, "file_errors": len(files)}, "files": reported, "errors": []string{}})
+		return string(output)
+	}
+
+	// The watch command reports both files, then the first only; the per file runs are counted
+	runsFile := filepath.Join(t.TempDir(), "runs")
+	fakePhpstan := newFakeTool(t, "phpstan", `case "$*" in
+  *--watch*)
+    echo '`+report(map[string]string{"src/Foo.php": "Watched error", "src/Bar.php": "Closed error"})+`'
+    sleep 0.2
+    echo '`+report(map[string]string{"src/Foo.php": "Watched error"})+`'
+    exec sleep 30;;
+esac
+echo run >> `+runsFile+`
+echo '{"totals": {"errors": 0, "file_errors": 0}, "files": {}, "errors": []}'
+`)
+
+	projectRoot := t.TempDir()
+	writeConfig(t, projectRoot, map[string]interface{}{
+		"phpstan": fakeProvider("vendor/bin/phpstan", fakePhpstan, map[string]interface{}{
+			"watch": map[string]interface{}{"enabled": true, "command": "vendor/bin/phpstan analyze --watch"},
+		}),
+	})
+	fooPath, barPath := filepath.Join(projectRoot, "src/Foo.php"), filepath.Join(projectRoot, "src/Bar.php")
+	writeFile(t, fooPath, "<?php\n$foo = 1;\n")
+	writeFile(t, barPath, "<?php\n$bar = 1;\n")
+	ts := newTestServer(t, projectRoot, nil, nil)
+
+	hasMessage := func(message string) func([]protocol.Diagnostic) bool {
+		return func(diags []protocol.Diagnostic) bool {
+			return len(diags) == 1 && diags[0].Message == message
+		}
+	}
+
+	t.Run("reports", func(t *testing.T) {
+		// The closed file gets the watch results, cleaned once missing from a report
+		barURI := utils.PathToURI(barPath)
+		ts.client.waitForDiagnostics(t, barURI, hasMessage("Closed error"))
+		ts.client.waitForDiagnostics(t, barURI, func(diags []protocol.Diagnostic) bool { return len(diags) == 0 })
+	})
+
+	t.Run("analysis", func(t *testing.T) {
+		// The opened file gets the last watch results, phpstan isn't run for it
+		fooURI := ts.open(t, fooPath, "<?php\n$foo = 1;\n")
+		ts.client.waitForDiagnostics(t, fooURI, hasMessage("Watched error"))
+		if runs, _ := os.ReadFile(runsFile); len(runs) != 0 {
+			t.Errorf("Expected no per file analysis while the watch reports, got %q", runs)
+		}
+	})
+}
+
+// TestServerGroupRules documents the groupRules provider option
+func TestServerGroupRules(t *testing.T) {
+	t.Log("Rules of the grouped categories are reported once per file, with the occurrences in the message and data")
+	t.Log("A grouped php-cs-fixer rule gets a quick fix running fixAll with the rule as second argument")
+	t.Log("fixAll with a rule runs php-cs-fixer with --rules, every change being annotated with this rule")
+}
+
+// TestServerMessages documents the message catalogs
+func TestServerMessages(t *testing.T) {
+	t.Log("The catalog of the initialize locale replaces the messages of its rule ids, watched results included")
+	t.Log("Catalogs are loaded once per project; a missing or broken catalog is logged and ignored")
+}
+
+// TestServerDocumentProviders documents the providers of documents other than PHP files
+func TestServerDocumentProviders(t *testing.T) {
+	t.Log("Providers declare the file name patterns of their documents, e.g. composer.json for composeraudit, composerunused and composervalidate")
+	t.Log("These documents are supported when one of their providers is enabled")
+	t.Log("Changes of composer.lock analyze the composer.json of its directory, where the advisories are published")
+	t.Log("PHP providers don't run on these documents, document providers don't run on PHP files")
+	t.Log("twigcsfixer claims the .twig templates, whatever the fileExtensions setting")
+	t.Log("The workspace scan includes the documents of the enabled providers, batch providers only get their own files")
+}
+
+// TestServerBufferOnly documents the bufferOnly initialization option
+func TestServerBufferOnly(t *testing.T) {
+	t.Log("Formatting, fix-all and the generated file check only use the synchronized buffers")
+	t.Log("A document which isn't open fails to format instead of being read from disk")
+	t.Log("Workspace scan, warm-up, tool config checks, scaffolding and watch mode are disabled")
+}
+
+// TestServerMonorepo tests the routing of the files to the closest project and the reload of the projects
+func TestServerMonorepo(t *testing.T) {
+	newMonorepo := func(t *testing.T) (string, *testServer) {
+		workspace := t.TempDir()
+		phplint := map[string]interface{}{"phplint": fakeProvider("php", "/bin/true", nil)}
+		for _, root := range []string{"", "packages/api", "packages/api/modules/billing", "packages/web"} {
+			writeConfig(t, filepath.Join(workspace, root), phplint)
+		}
+		// Dependencies are not searched for projects
+		writeConfig(t, filepath.Join(workspace, "vendor/acme/lib"), phplint)
+
+		return workspace, newTestServer(t, workspace, nil, nil)
+	}
+
+	t.Run("routing", func(t *testing.T) {
+		workspace, ts := newMonorepo(t)
+
+		tests := []struct {
+			name     string
+			filePath string
+			root     string
+		}{
+			{"workspace file", "src/Kernel.php", ""},
+			{"project file", "packages/api/src/Controller.php", "packages/api"},
+			{"nested project file", "packages/api/modules/billing/src/Invoice.php", "packages/api/modules/billing"},
+			{"sibling sharing the prefix of a project", "packages/apidoc/Doc.php", ""},
+			{"other project file", "packages/web/index.php", "packages/web"},
+			{"project root itself", "packages/api", "packages/api"},
+			{"dependency of a project", "vendor/acme/lib/src/Lib.php", ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				expected := filepath.Join(workspace, tt.root)
+				if root := ts.ProjectRoot(filepath.Join(workspace, tt.filePath)); root != expected {
+					t.Errorf("Expected %s in %s, got %s", tt.filePath, expected, root)
+				}
+			})
+		}
+
+		t.Run("file outside every project", func(t *testing.T) {
+			if root := ts.ProjectRoot(filepath.Join(t.TempDir(), "Script.php")); root != workspace {
+				t.Errorf("Expected the first project %s, got %s", workspace, root)
+			}
+		})
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		workspace, ts := newMonorepo(t)
+		api := filepath.Join(workspace, "packages/api/src/Controller.php")
+		web := filepath.Join(workspace, "packages/web/index.php")
+		billing := filepath.Join(workspace, "packages/api/modules/billing/src/Invoice.php")
+		reload := func() {
+			ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{Command: "php-diagls/reloadConfig"})
+		}
+
+		// Only the changed project gets the new configuration
+		writeConfig(t, filepath.Join(workspace, "packages/api"), map[string]interface{}{
+			"phplint": fakeProvider("php", "/bin/true", nil),
+			"phpstan": fakeProvider("vendor/bin/phpstan", "/bin/true", nil),
+		})
+		reload()
+		if _, exists := ts.ProjectConfig(api).DiagnosticsProviders["phpstan"]; !exists {
+			t.Error("Expected phpstan in the reloaded project")
+		}
+		for _, filePath := range []string{web, billing} {
+			if _, exists := ts.ProjectConfig(filePath).DiagnosticsProviders["phpstan"]; exists {
+				t.Errorf("Expected no phpstan in the project of %s", filePath)
+			}
+		}
+
+		// A broken configuration keeps the previous one of its project
+		writeFile(t, filepath.Join(workspace, "packages/web", config.ConfigFileName), "{")
+		reload()
+		if root := ts.ProjectRoot(web); root != filepath.Join(workspace, "packages/web") {
+			t.Errorf("Expected the web project kept, got %s", root)
+		}
+		if _, exists := ts.ProjectConfig(web).DiagnosticsProviders["phplint"]; !exists {
+			t.Error("Expected the previous configuration of the web project kept")
+		}
+		ts.client.waitFor(t, protocol.MethodWindowShowMessage, func(params json.RawMessage) bool {
+			return strings.Contains(string(params), "keeping the previous one")
+		})
+
+		// Deleted and created configuration files remove and add projects
+		if err := os.Remove(filepath.Join(workspace, "packages/api/modules/billing", config.ConfigFileName)); err != nil {
+			t.Fatal(err)
+		}
+		writeConfig(t, filepath.Join(workspace, "packages/admin"), map[string]interface{}{"phplint": fakeProvider("php", "/bin/true", nil)})
+		reload()
+		if root := ts.ProjectRoot(billing); root != filepath.Join(workspace, "packages/api") {
+			t.Errorf("Expected the files of the deleted project in its parent, got %s", root)
+		}
+		admin := filepath.Join(workspace, "packages/admin/src/User.php")
+		if root := ts.ProjectRoot(admin); root != filepath.Join(workspace, "packages/admin") {
+			t.Errorf("Expected the created project, got %s", root)
+		}
+	})
+
+	t.Run("config file names", func(t *testing.T) {
+		t.Log("initializationOptions.configFiles replaces the config file paths searched in the roots, unless --config is given")
+		t.Log("A root is the directory holding one of the paths, tools/php-diagls.json making tools/ part of the path")
+		t.Log("An absolute --config configures the workspace folders")
+	})
+
+	t.Run("auto-configure", func(t *testing.T) {
+		t.Log("A config naming only a container gets its providers detected once the Docker settings are applied")
+		t.Log("A kept previous config is not detected again")
+		t.Log("Without container either, the container of the PHP service of the compose file is used")
+		t.Log("The compose detection is reported in the detectedContainer field of the status roots")
+	})
+}
+
+// TestServerReloadKeptProject reloads a broken configuration while the kept project is analyzed, run with
+// -race to check the analyses don't read a configuration being modified
+func TestServerReloadKeptProject(t *testing.T) {
+	workspace := t.TempDir()
+	writeConfig(t, workspace, map[string]interface{}{"phplint": fakeProvider("php", "/bin/true", nil)})
+	ts := startTestServer(t, workspace, nil, nil)
+	documentURI := ts.open(t, filepath.Join(workspace, "src/Kernel.php"), "<?php\n")
+
+	writeFile(t, filepath.Join(workspace, config.ConfigFileName), "{")
+	for i := 0; i < 10; i++ {
+		ts.notify(t, protocol.MethodTextDocumentDidSave, protocol.DidSaveTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: documentURI}})
+		ts.request(t, protocol.MethodWorkspaceExecuteCommand, protocol.ExecuteCommandParams{Command: "php-diagls/reloadConfig"})
+	}
+
+	if _, exists := ts.ProjectConfig(documentURI.Filename()).DiagnosticsProviders["phplint"]; !exists {
+		t.Error("Expected the previous configuration kept")
+	}
+}
+
+func TestServerConfigFiles(t *testing.T) {
+	newWorkspace := func(t *testing.T) string {
+		workspace := t.TempDir()
+		writeConfig(t, filepath.Join(workspace, "tools"), map[string]interface{}{"phplint": fakeProvider("php", "/bin/true", nil)})
+		writeConfig(t, filepath.Join(workspace, "qa"), map[string]interface{}{"phpstan": fakeProvider("vendor/bin/phpstan", "/bin/true", nil)})
+		return workspace
+	}
+	options := map[string]interface{}{"configFiles": []string{"tools/" + config.ConfigFileName}}
+
+	t.Run("initialization option", func(t *testing.T) {
+		workspace := newWorkspace(t)
+		ts := startTestServer(t, workspace, nil, options)
+
+		filePath := filepath.Join(workspace, "src/Kernel.php")
+		if root := ts.ProjectRoot(filePath); root != workspace {
+			t.Errorf("Expected the project in %s, got %s", workspace, root)
+		}
+		if _, exists := ts.ProjectConfig(filePath).DiagnosticsProviders["phplint"]; !exists {
+			t.Error("Expected the tools/ configuration")
+		}
+	})
+
+	t.Run("--config wins", func(t *testing.T) {
+		workspace := newWorkspace(t)
+		files, err := config.ParseFiles([]string{"qa/" + config.ConfigFileName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &fakeClient{}
+		ts := &testServer{Server: server.New(client).WithConfigFiles(files), client: client, folder: workspace, options: options}
+		ts.initialize(t)
+
+		filePath := filepath.Join(workspace, "src/Kernel.php")
+		if _, exists := ts.ProjectConfig(filePath).DiagnosticsProviders["phpstan"]; !exists {
+			t.Error("Expected the qa/ configuration given with --config")
+		}
+		ts.client.waitFor(t, protocol.MethodWindowShowMessage, func(params json.RawMessage) bool {
+			return strings.Contains(string(params), "--config")
+		})
+	})
+}
+
 // TestServerGetPhpCsFixerProviderConfig documents provider config lookup
 func TestServerGetPhpCsFixerProviderConfig(t *testing.T) {
 	t.Run("behavior", func(t *testing.T) {
@@ -486,3 +2088,330 @@ func TestServerGetPhpCsFixerProviderConfig(t *testing.T) {
 		t.Log("May be for future features or leftover from refactoring")
 	})
 }
+
+func TestServerMiddlewares(t *testing.T) {
+	t.Run("custom middleware", func(t *testing.T) {
+		s := server.New(nil)
+		var observed []string
+		s.Use(server.MetricsMiddleware(func(method string, _ time.Duration, _ error) {
+			observed = append(observed, method)
+		}))
+
+		req, _ := jsonrpc2.NewNotification("custom/unknown", nil)
+		if err := s.Handle(context.Background(), func(context.Context, interface{}, error) error { return nil }, req); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(observed) != 1 || observed[0] != "custom/unknown" {
+			t.Errorf("Expected the request to be observed, got %v", observed)
+		}
+	})
+
+	t.Run("recovery", func(t *testing.T) {
+		handler := server.RecoveryMiddleware(func(context.Context, jsonrpc2.Replier, jsonrpc2.Request) error {
+			panic("boom")
+		})
+
+		var replyErr error
+		req, _ := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), "textDocument/formatting", nil)
+		handler(context.Background(), func(_ context.Context, _ interface{}, err error) error {
+			replyErr = err
+			return nil
+		}, req)
+		if replyErr == nil || !strings.Contains(replyErr.Error(), "boom") {
+			t.Errorf("Expected an internal error reply, got %v", replyErr)
+		}
+	})
+}
+
+// fakeClient is the connection of a test server, recording the notifications and requests sent to the client
+type fakeClient struct {
+	mu       sync.Mutex
+	messages []clientMessage
+}
+
+type clientMessage struct {
+	method string
+	params json.RawMessage
+}
+
+func (c *fakeClient) Call(_ context.Context, method string, params, _ interface{}) (jsonrpc2.ID, error) {
+	c.record(method, params)
+	return jsonrpc2.NewNumberID(0), nil
+}
+
+func (c *fakeClient) Notify(_ context.Context, method string, params interface{}) error {
+	c.record(method, params)
+	return nil
+}
+
+func (c *fakeClient) Go(context.Context, jsonrpc2.Handler) {}
+
+func (c *fakeClient) Close() error { return nil }
+
+func (c *fakeClient) Done() <-chan struct{} { return make(chan struct{}) }
+
+func (c *fakeClient) Err() error { return nil }
+
+func (c *fakeClient) record(method string, params interface{}) {
+	data, _ := json.Marshal(params)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, clientMessage{method: method, params: data})
+}
+
+// sent returns the params of the messages of the method sent so far
+func (c *fakeClient) sent(method string) []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var params []json.RawMessage
+	for _, message := range c.messages {
+		if message.method == method {
+			params = append(params, message.params)
+		}
+	}
+	return params
+}
+
+// waitFor waits for a message of the method accepted by the filter, returning its params
+func (c *fakeClient) waitFor(t *testing.T, method string, accept func(params json.RawMessage) bool) json.RawMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, params := range c.sent(method) {
+			if accept(params) {
+				return params
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("No %s accepted, sent: %s", method, c.sent(method))
+	return nil
+}
+
+// waitForDiagnostics waits for the analysis results of the document, stale ones excluded
+func (c *fakeClient) waitForDiagnostics(t *testing.T, uri protocol.DocumentURI, accept func(diags []protocol.Diagnostic) bool) []protocol.Diagnostic {
+	t.Helper()
+
+	var published protocol.PublishDiagnosticsParams
+	c.waitFor(t, protocol.MethodTextDocumentPublishDiagnostics, func(params json.RawMessage) bool {
+		var candidate protocol.PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &candidate); err != nil || candidate.URI != uri {
+			return false
+		}
+		for _, diagnostic := range candidate.Diagnostics {
+			if strings.HasPrefix(diagnostic.Message, "[stale] ") {
+				return false
+			}
+		}
+		if !accept(candidate.Diagnostics) {
+			return false
+		}
+		published = candidate
+		return true
+	})
+	return published.Diagnostics
+}
+
+// testServer is a server initialized on a workspace folder, talking to a fake client
+type testServer struct {
+	*server.Server
+	client *fakeClient
+	nextId int32
+	// Result of the initialize request
+	initializeResult json.RawMessage
+
+	folder       string
+	capabilities map[string]interface{}
+	options      map[string]interface{}
+}
+
+// newTestServer initializes a server on the workspace folder with the client capabilities and the
+// initialization options, both nil for none
+func newTestServer(t *testing.T, folder string, capabilities map[string]interface{}, options map[string]interface{}) *testServer {
+	t.Helper()
+
+	// The workspace state goes to the user cache directory
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if capabilities == nil {
+		capabilities = map[string]interface{}{}
+	}
+
+	return startTestServer(t, folder, capabilities, options)
+}
+
+func startTestServer(t *testing.T, folder string, capabilities map[string]interface{}, options map[string]interface{}) *testServer {
+	t.Helper()
+
+	client := &fakeClient{}
+	ts := &testServer{Server: server.New(client), client: client, folder: folder, capabilities: capabilities, options: options}
+	ts.initialize(t)
+
+	return ts
+}
+
+// initialize sends the initialize request and the initialized notification, shutting the server down at
+// the end of the test
+func (ts *testServer) initialize(t *testing.T) {
+	t.Helper()
+
+	ts.initializeResult = ts.request(t, protocol.MethodInitialize, map[string]interface{}{
+		"clientInfo":            map[string]interface{}{"name": "php-diagls-test"},
+		"rootUri":               string(utils.PathToURI(ts.folder)),
+		"capabilities":          ts.capabilities,
+		"initializationOptions": ts.options,
+	})
+	ts.notify(t, protocol.MethodInitialized, map[string]interface{}{})
+	t.Cleanup(func() {
+		ts.request(t, protocol.MethodShutdown, nil)
+	})
+}
+
+// restart shuts the server down and initializes a new one on the same workspace, which keeps its state
+func (ts *testServer) restart(t *testing.T) *testServer {
+	t.Helper()
+
+	ts.request(t, protocol.MethodShutdown, nil)
+	return startTestServer(t, ts.folder, ts.capabilities, ts.options)
+}
+
+// request sends a request to the server and waits for its result
+func (ts *testServer) request(t *testing.T, method string, params interface{}) json.RawMessage {
+	t.Helper()
+
+	result, err := ts.requestErr(t, method, params)
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	return result
+}
+
+// requestErr sends a request to the server and waits for its result or error
+func (ts *testServer) requestErr(t *testing.T, method string, params interface{}) (json.RawMessage, error) {
+	t.Helper()
+
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(atomic.AddInt32(&ts.nextId, 1)), method, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		result json.RawMessage
+		err    error
+	}
+	replied := make(chan response, 1)
+	replier := func(_ context.Context, result interface{}, err error) error {
+		data, _ := json.Marshal(result)
+		select {
+		case replied <- response{result: data, err: err}:
+		default:
+			t.Errorf("%s replied twice", method)
+		}
+		return nil
+	}
+	if err := ts.Handle(context.Background(), replier, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-replied:
+		return r.result, r.err
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s not replied", method)
+		return nil, nil
+	}
+}
+
+// notify sends a notification to the server
+func (ts *testServer) notify(t *testing.T, method string, params interface{}) {
+	t.Helper()
+
+	req, err := jsonrpc2.NewNotification(method, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Handle(context.Background(), func(context.Context, interface{}, error) error { return nil }, req); err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+}
+
+// open opens the document with the content, also written to disk
+func (ts *testServer) open(t *testing.T, filePath string, content string) protocol.DocumentURI {
+	t.Helper()
+
+	writeFile(t, filePath, content)
+	documentURI := utils.PathToURI(filePath)
+	ts.notify(t, protocol.MethodTextDocumentDidOpen, protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: documentURI, LanguageID: "php", Version: 1, Text: content},
+	})
+	return documentURI
+}
+
+// codeActions returns the code actions of the document for the diagnostics
+func (ts *testServer) codeActions(t *testing.T, documentURI protocol.DocumentURI, diags []protocol.Diagnostic) []protocol.CodeAction {
+	t.Helper()
+
+	result := ts.request(t, protocol.MethodTextDocumentCodeAction, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+		Context:      protocol.CodeActionContext{Diagnostics: diags},
+	})
+
+	var actions []protocol.CodeAction
+	if err := json.Unmarshal(result, &actions); err != nil {
+		t.Fatalf("Invalid code actions %s: %v", result, err)
+	}
+	return actions
+}
+
+// writeFile writes the file, creating its directory
+func writeFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeConfig writes the configuration file of the project with the providers
+func writeConfig(t *testing.T, projectRoot string, providers map[string]interface{}) {
+	t.Helper()
+
+	content, err := json.Marshal(map[string]interface{}{"diagnosticsProviders": providers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(projectRoot, config.ConfigFileName), string(content))
+}
+
+// newFakeTool writes an executable shell script standing for the tool
+func newFakeTool(t *testing.T, name string, script string) string {
+	t.Helper()
+
+	fakeTool := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fakeTool, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return fakeTool
+}
+
+// fakeProvider is the configuration of a provider running the fake tool through the local fallback, the
+// container never existing. The settings are added to the configuration.
+func fakeProvider(path string, fakeTool string, settings map[string]interface{}) map[string]interface{} {
+	providerConfig := map[string]interface{}{
+		"enabled":   true,
+		"container": "php-diagls-missing-container",
+		"path":      path,
+		"fallback":  []string{"local"},
+		"localPath": fakeTool,
+	}
+	for key, value := range settings {
+		providerConfig[key] = value
+	}
+	return providerConfig
+}
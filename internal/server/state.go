@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"github.com/cristianradulescu/php-diagls/internal/cache"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/protocol"
+)
+
+// Prefix of the last known diagnostics republished while the file is analyzed again
+const staleDiagnosticPrefix = "[stale] "
+
+// loadWorkspaceState reads the diagnostics published and the files opened before the last shutdown of the workspace
+func (s *Server) loadWorkspaceState() {
+	if len(s.workspaceFolders) == 0 {
+		return
+	}
+
+	dir, err := cache.WorkspaceDir(s.workspaceFolders[0])
+	if err != nil {
+		log.Printf("%s%s Workspace state disabled: %v", logging.LogTagLSP, logging.LogTagServer, err)
+		return
+	}
+
+	s.lastKnown = cache.LoadLastKnown(dir)
+	s.recentFiles = cache.LoadRecentFiles(dir)
+}
+
+func (s *Server) saveWorkspaceState() {
+	if err := s.lastKnown.Save(); err != nil {
+		log.Printf("%s%s Failed to save last known diagnostics: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
+	if err := s.recentFiles.Save(); err != nil {
+		log.Printf("%s%s Failed to save recent files: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
+}
+
+// setPublished records the diagnostics published for the file, persisted with the workspace state
+func (s *Server) setPublished(filePath string, diagnostics []protocol.Diagnostic) {
+	s.publishedMu.Lock()
+	if len(diagnostics) == 0 {
+		delete(s.published, filePath)
+	} else {
+		s.published[filePath] = diagnostics
+	}
+	s.publishedMu.Unlock()
+
+	s.lastKnown.Set(filePath, diagnostics)
+}
+
+// getPublished returns the diagnostics last published for the file, falling back to the ones of the
+// previous session. Both are cleared together, a file without diagnostics has none in either.
+func (s *Server) getPublished(filePath string) ([]protocol.Diagnostic, bool) {
+	s.publishedMu.Lock()
+	diagnostics, exists := s.published[filePath]
+	s.publishedMu.Unlock()
+	if exists {
+		return diagnostics, true
+	}
+
+	return s.lastKnown.Get(filePath)
+}
+
+// publishStaleDiagnostics shows the last known diagnostics of the file until its analysis completes
+func (s *Server) publishStaleDiagnostics(ctx context.Context, uri protocol.DocumentURI) {
+	lastKnown, exists := s.lastKnown.Get(uri.Filename())
+	if !exists {
+		return
+	}
+
+	stale := make([]protocol.Diagnostic, 0, len(lastKnown))
+	for _, diagnostic := range lastKnown {
+		diagnostic.Message = staleDiagnosticPrefix + diagnostic.Message
+		stale = append(stale, diagnostic)
+	}
+
+	s.sendDiagnostics(ctx, uri, stale)
+}
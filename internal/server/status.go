@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/config"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// LspNotificationStatus is pushed to the client whenever the server status changes
+	LspNotificationStatus = "$/" + config.Name + "/status"
+	// LspRequestStatus lets the client fetch the current status snapshot on demand
+	LspRequestStatus = config.Name + "/status"
+
+	StatusStateIdle          = "idle"
+	StatusStateAnalyzing     = "analyzing"
+	StatusStateProviderError = "provider-error"
+	// A provider is suspended by its circuit breaker
+	StatusStateProviderSuspended = "provider-suspended"
+)
+
+// StatusSnapshot is the payload of the status notification and request
+type StatusSnapshot struct {
+	State          string            `json:"state"`
+	Analyzing      int               `json:"analyzing"`
+	Providers      int               `json:"providers"`
+	ProviderErrors map[string]string `json:"providerErrors"`
+	// Time (RFC 3339) each suspended provider is retried
+	SuspendedProviders map[string]string `json:"suspendedProviders"`
+	Diagnostics        int               `json:"diagnostics"`
+	// Status of every project root, more than one in a monorepo
+	Roots []RootStatus `json:"roots"`
+}
+
+// RootStatus is the status of the providers of one project root
+type RootStatus struct {
+	Root               string            `json:"root"`
+	Providers          int               `json:"providers"`
+	ProviderErrors     map[string]string `json:"providerErrors"`
+	SuspendedProviders map[string]string `json:"suspendedProviders"`
+	Diagnostics        int               `json:"diagnostics"`
+	// Container found in the compose file, when the configuration names none
+	DetectedContainer *config.ComposeDetection `json:"detectedContainer,omitempty"`
+}
+
+type statusTracker struct {
+	mu        sync.Mutex
+	analyzing int
+	// Last error per project root and provider name
+	providerErrors map[string]map[string]string
+	diagnostics    map[protocol.DocumentURI]int
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{
+		providerErrors: make(map[string]map[string]string),
+		diagnostics:    make(map[protocol.DocumentURI]int),
+	}
+}
+
+// snapshot aggregates the status of the roots, rootOf routes a document to its project root
+func (st *statusTracker) snapshot(roots []RootStatus, rootOf func(uri protocol.DocumentURI) string) StatusSnapshot {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	snapshot := StatusSnapshot{
+		State:              StatusStateIdle,
+		Analyzing:          st.analyzing,
+		ProviderErrors:     make(map[string]string),
+		SuspendedProviders: make(map[string]string),
+		Roots:              roots,
+	}
+
+	rootIndex := make(map[string]int, len(roots))
+	for i := range snapshot.Roots {
+		root := &snapshot.Roots[i]
+		rootIndex[root.Root] = i
+		root.ProviderErrors = make(map[string]string, len(st.providerErrors[root.Root]))
+		for name, err := range st.providerErrors[root.Root] {
+			root.ProviderErrors[name] = err
+			snapshot.ProviderErrors[name] = err
+		}
+		for name, until := range root.SuspendedProviders {
+			snapshot.SuspendedProviders[name] = until
+		}
+		snapshot.Providers += root.Providers
+	}
+
+	for uri, count := range st.diagnostics {
+		snapshot.Diagnostics += count
+		if i, exists := rootIndex[rootOf(uri)]; exists {
+			snapshot.Roots[i].Diagnostics += count
+		}
+	}
+
+	if st.analyzing > 0 {
+		snapshot.State = StatusStateAnalyzing
+	} else if len(snapshot.SuspendedProviders) > 0 {
+		snapshot.State = StatusStateProviderSuspended
+	} else if len(snapshot.ProviderErrors) > 0 {
+		snapshot.State = StatusStateProviderError
+	}
+
+	return snapshot
+}
+
+func (s *Server) statusSnapshot() StatusSnapshot {
+	projects := s.allProjects()
+	suspended := s.suspendedProviders(time.Now())
+	roots := make([]RootStatus, 0, len(projects))
+	for _, p := range projects {
+		root := RootStatus{
+			Root:               p.root,
+			Providers:          p.providerCount(),
+			SuspendedProviders: make(map[string]string),
+			DetectedContainer:  p.serverConfig.ComposeDetection,
+		}
+		for name, until := range suspended[p.root] {
+			root.SuspendedProviders[name] = until.Format(time.RFC3339)
+		}
+		roots = append(roots, root)
+	}
+
+	return s.status.snapshot(roots, func(uri protocol.DocumentURI) string {
+		if p := s.projectFor(uri.Filename()); p != nil {
+			return p.root
+		}
+		return ""
+	})
+}
+
+func (s *Server) notifyStatus(ctx context.Context) {
+	if err := s.conn.Notify(ctx, LspNotificationStatus, s.statusSnapshot()); err != nil {
+		log.Printf("%s%s Failed to send status notification: %v", logging.LogTagLSP, logging.LogTagServer, err)
+	}
+}
+
+func (s *Server) statusAnalysisStarted(ctx context.Context) {
+	s.status.mu.Lock()
+	s.status.analyzing++
+	s.status.mu.Unlock()
+
+	s.notifyStatus(ctx)
+}
+
+func (s *Server) statusAnalysisFinished(ctx context.Context) {
+	s.status.mu.Lock()
+	if s.status.analyzing > 0 {
+		s.status.analyzing--
+	}
+	s.status.mu.Unlock()
+
+	s.notifyStatus(ctx)
+}
+
+func (s *Server) statusProviderResult(root string, providerName string, err error) {
+	s.status.mu.Lock()
+	defer s.status.mu.Unlock()
+
+	if err != nil {
+		if s.status.providerErrors[root] == nil {
+			s.status.providerErrors[root] = make(map[string]string)
+		}
+		s.status.providerErrors[root][providerName] = err.Error()
+		return
+	}
+	delete(s.status.providerErrors[root], providerName)
+}
+
+func (s *Server) statusDiagnosticsPublished(uri protocol.DocumentURI, count int) {
+	s.status.mu.Lock()
+	defer s.status.mu.Unlock()
+
+	if count == 0 {
+		delete(s.status.diagnostics, uri)
+		return
+	}
+	s.status.diagnostics[uri] = count
+}
+
+func (s *Server) handleStatusRequest(ctx context.Context, reply jsonrpc2.Replier, _ jsonrpc2.Request) error {
+	return reply(ctx, s.statusSnapshot(), nil)
+}
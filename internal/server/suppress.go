@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+// phpStanSuppressCodeActions offers to ignore the phpstan errors inline: errors with an identifier get a
+// `/** @phpstan-ignore <identifier> */` comment above their line, the others a `// @phpstan-ignore-line`
+// comment at the end of it
+func (s *Server) phpStanSuppressCodeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	uri := params.TextDocument.URI
+
+	content, err := s.documentContent(uri)
+	if err != nil {
+		return actions
+	}
+	lines := strings.Split(content, "\n")
+
+	for _, diagnostic := range params.Context.Diagnostics {
+		line := diagnostic.Range.Start.Line
+		if diagnostic.Source != diagnostics.PhpStanProviderName || int(line) >= len(lines) {
+			continue
+		}
+
+		title, edit := phpStanSuppressEdit(diagnostic, lines[line])
+		actions = append(actions, protocol.CodeAction{
+			Title:       title,
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: {edit}},
+			},
+		})
+	}
+
+	return actions
+}
+
+// phpStanSuppressEdit returns the title and the edit of the comment ignoring the error reported on the line
+func phpStanSuppressEdit(diagnostic protocol.Diagnostic, lineContent string) (string, protocol.TextEdit) {
+	line := diagnostic.Range.Start.Line
+
+	if identifier, ok := diagnostic.Code.(string); ok && identifier != "" {
+		indentation := lineContent[:len(lineContent)-len(strings.TrimLeft(lineContent, " \t"))]
+		return fmt.Sprintf("Ignore %s on this line", identifier), protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line}},
+			NewText: fmt.Sprintf("%s/** @phpstan-ignore %s */\n", indentation, identifier),
+		}
+	}
+
+	// Positions count UTF-16 code units
+	end := protocol.Position{Line: line, Character: uint32(len(utf16.Encode([]rune(strings.TrimRight(lineContent, "\r")))))}
+	return "Ignore phpstan errors on this line", protocol.TextEdit{
+		Range:   protocol.Range{Start: end, End: end},
+		NewText: " // @phpstan-ignore-line",
+	}
+}
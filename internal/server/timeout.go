@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// Code of the diagnostic reported when a provider runs out of time
+	timeoutDiagnosticCode = "timeout"
+
+	// The quick fix re-runs the analysis with this many times the timeout which was hit
+	timeoutRetryFactor = 2
+)
+
+// timeoutDiagnostic tells the user the provider ran out of time. The timeout is kept in the data of the
+// diagnostic for the re-run quick fix.
+func timeoutDiagnostic(provider diagnostics.DiagnosticsProvider, timeout time.Duration) protocol.Diagnostic {
+	return protocol.Diagnostic{
+		Range:    protocol.Range{},
+		Severity: protocol.DiagnosticSeverityWarning,
+		Source:   provider.Name(),
+		Code:     timeoutDiagnosticCode,
+		Message:  fmt.Sprintf("%s timed out after %v — results may be incomplete", provider.Name(), timeout),
+		Data:     map[string]interface{}{"timeoutSeconds": timeout.Seconds()},
+	}
+}
+
+// timeoutCodeActions offers to re-run the analysis with a longer timeout for the timeout diagnostics
+func timeoutCodeActions(params protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	for _, diagnostic := range params.Context.Diagnostics {
+		if code, ok := diagnostic.Code.(string); !ok || code != timeoutDiagnosticCode {
+			continue
+		}
+
+		data, ok := diagnostic.Data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		timeoutSeconds, ok := data["timeoutSeconds"].(float64)
+		if !ok || timeoutSeconds <= 0 {
+			continue
+		}
+
+		retrySeconds := timeoutSeconds * timeoutRetryFactor
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Re-run %s with a %v timeout", diagnostic.Source, time.Duration(retrySeconds*float64(time.Second))),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Command: &protocol.Command{
+				Title:     fmt.Sprintf("Re-run %s", diagnostic.Source),
+				Command:   getFullLspCommandName(LspCommandNameAnalyzeFile),
+				Arguments: []interface{}{string(params.TextDocument.URI), retrySeconds},
+			},
+		})
+	}
+
+	return actions
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// Number of recently used files analyzed ahead of their first didOpen
+const warmUpFiles = 10
+
+// warmResult holds the diagnostics of the file content analyzed during the warm-up
+type warmResult struct {
+	content     string
+	diagnostics []protocol.Diagnostic
+}
+
+// warmUpRecentFiles analyzes the files last opened in the workspace at background priority, which
+// also fills the tool caches, so their first didOpen gets the results right away. The warm-up is a
+// background job, reported and cancellable like the workspace scan.
+func (s *Server) warmUpRecentFiles() {
+	files := s.recentFiles.List(warmUpFiles)
+	if len(files) == 0 || s.bufferOnly {
+		return
+	}
+
+	s.startBackgroundJob("Warming up recent files", func(ctx context.Context, report progressReporter) {
+		s.warmUp(ctx, files, report)
+	})
+}
+
+func (s *Server) warmUp(ctx context.Context, files []string, report progressReporter) {
+	log.Printf("%s%s Warming up %d recently used files", logging.LogTagLSP, logging.LogTagServer, len(files))
+
+	for i, filePath := range files {
+		if ctx.Err() != nil {
+			log.Printf("%s%s Warm-up cancelled after %d/%d files", logging.LogTagLSP, logging.LogTagServer, i, len(files))
+			return
+		}
+
+		uri := utils.PathToURI(filePath)
+		if !s.isSupportedDocument(uri) {
+			continue
+		}
+
+		report(fmt.Sprintf("%d/%d %s", i+1, len(files), s.displayPath(filePath)), uint32(i*100/len(files)))
+
+		done := s.analysisScheduler.Submit(ctx, scheduler.PriorityBackground, func(jobCtx context.Context) {
+			// Opened meanwhile, the didOpen analysis takes over
+			if _, open := s.getDocumentContent(uri); open {
+				return
+			}
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return
+			}
+
+			diags := s.collectDiagnostics(jobCtx, filePath, analysisRun{})
+			if jobCtx.Err() != nil {
+				return
+			}
+
+			s.warmMu.Lock()
+			s.warmResults[uri] = warmResult{content: string(content), diagnostics: diags}
+			s.warmMu.Unlock()
+		})
+		<-done
+	}
+}
+
+// publishWarmDiagnostics publishes the warm-up results of the opened document when its content did not
+// change since, reporting whether the analysis can be skipped
+func (s *Server) publishWarmDiagnostics(ctx context.Context, uri protocol.DocumentURI, content string) bool {
+	s.warmMu.Lock()
+	result, exists := s.warmResults[uri]
+	delete(s.warmResults, uri)
+	s.warmMu.Unlock()
+
+	if !exists || result.content != content {
+		return false
+	}
+
+	log.Printf("%s%s Publishing warm-up results of %s", logging.LogTagLSP, logging.LogTagServer, uri.Filename())
+	s.publishDiagnostics(ctx, uri, result.diagnostics)
+	return true
+}
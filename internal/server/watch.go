@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+// Wait before restarting a watch command which exited or failed to start
+const watchRestartDelay = 30 * time.Second
+
+// providerWatch is a provider of a project root running its tool in watch mode. The provider is
+// analyzed on demand again while its watch command is not reporting.
+type providerWatch struct {
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	active  bool
+	results map[string][]protocol.Diagnostic
+}
+
+// report replaces the results with those of the last run, returning the files of both runs
+func (w *providerWatch) report(results map[string][]protocol.Diagnostic) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files := make([]string, 0, len(results)+len(w.results))
+	for filePath := range results {
+		files = append(files, filePath)
+	}
+	for filePath := range w.results {
+		if _, exists := results[filePath]; !exists {
+			files = append(files, filePath)
+		}
+	}
+
+	w.active = true
+	w.results = results
+	return files
+}
+
+func (w *providerWatch) stopped() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.active = false
+	w.results = nil
+}
+
+// startWatchers starts the watch command of every automatic provider configured to run in watch mode
+func (s *Server) startWatchers() {
+	for _, p := range s.allProjects() {
+		for _, provider := range s.loadDiagnosticsProviders(p) {
+			providerConfig := p.serverConfig.DiagnosticsProviders[provider.Id()]
+			watcher, ok := provider.(diagnostics.Watcher)
+			if !providerConfig.Watch.Enabled || providerConfig.IsManual() {
+				continue
+			}
+			if !ok {
+				log.Printf("%s%s %s has no watch mode, analyzing files on demand", logging.LogTagLSP, logging.LogTagServer, provider.Name())
+				continue
+			}
+			// Reported paths are matched to the files on disk
+			if s.bufferOnly {
+				log.Printf("%s%s %s watch mode is not available in buffer-only mode, analyzing files on demand", logging.LogTagLSP, logging.LogTagServer, provider.Name())
+				continue
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			w := &providerWatch{cancel: cancel}
+
+			s.watchesMu.Lock()
+			s.watches[p.root+"/"+provider.Name()] = w
+			s.watchesMu.Unlock()
+
+			go s.runWatcher(ctx, p.root, provider.Name(), watcher, w)
+		}
+	}
+}
+
+// runWatcher keeps the watch command running until the context is cancelled
+func (s *Server) runWatcher(ctx context.Context, root string, name string, watcher diagnostics.Watcher, w *providerWatch) {
+	for {
+		log.Printf("%s%s Starting %s watch in %s", logging.LogTagLSP, logging.LogTagServer, name, root)
+
+		err := watcher.Watch(ctx, root, func(results map[string][]protocol.Diagnostic) {
+			s.publishWatchReport(root, w, results)
+		})
+		w.stopped()
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("%s%s %s watch stopped in %s, restarting in %v: %v", logging.LogTagLSP, logging.LogTagServer, name, root, watchRestartDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRestartDelay):
+		}
+	}
+}
+
+// publishWatchReport re-analyzes the open files of the report, merging the watch results with the
+// other providers, and publishes the watch results of the other files as is
+func (s *Server) publishWatchReport(root string, w *providerWatch, results map[string][]protocol.Diagnostic) {
+	for _, filePath := range w.report(results) {
+		if p := s.projectFor(filePath); p == nil || p.root != root || utils.IsIgnoredPath(filePath) {
+			continue
+		}
+
+		uri := utils.PathToURI(filePath)
+		if _, open := s.getDocumentContent(uri); open {
+			s.scheduleDiagnostics(uri, scheduler.PriorityWatcher)
+			continue
+		}
+
+		diags := results[filePath]
+		if diags == nil {
+			diags = []protocol.Diagnostic{}
+		}
+		s.publishDiagnostics(context.Background(), uri, diags)
+	}
+}
+
+// watchedDiagnostics returns the last watch results of the provider for the file, reporting
+// whether the provider is watched at all
+func (s *Server) watchedDiagnostics(root string, name string, filePath string) ([]protocol.Diagnostic, bool) {
+	s.watchesMu.Lock()
+	w, exists := s.watches[root+"/"+name]
+	s.watchesMu.Unlock()
+	if !exists {
+		return nil, false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.active {
+		return nil, false
+	}
+	return append([]protocol.Diagnostic(nil), w.results[filePath]...), true
+}
+
+func (s *Server) stopWatchers() {
+	s.watchesMu.Lock()
+	defer s.watchesMu.Unlock()
+
+	for _, w := range s.watches {
+		w.cancel()
+	}
+	s.watches = make(map[string]*providerWatch)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cristianradulescu/php-diagls/internal/diagnostics"
+	"github.com/cristianradulescu/php-diagls/internal/logging"
+	"github.com/cristianradulescu/php-diagls/internal/scheduler"
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func (s *Server) handleAnalyzeWorkspaceCommand(ctx context.Context, reply jsonrpc2.Replier) error {
+	if len(s.allProjects()) == 0 {
+		return reply(ctx, nil, fmt.Errorf("no project root available"))
+	}
+	if s.bufferOnly {
+		return reply(ctx, nil, fmt.Errorf("the workspace files can't be listed in buffer-only mode"))
+	}
+
+	s.startBackgroundJob("Analyzing workspace", s.analyzeWorkspace)
+
+	return reply(ctx, nil, nil)
+}
+
+// analyzeWorkspace analyzes the files of every project, each file with the providers of its closest project
+func (s *Server) analyzeWorkspace(ctx context.Context, report progressReporter) {
+	projects := s.allProjects()
+
+	var files []string
+	filesByProject := make(map[*project][]string)
+	for _, p := range projects {
+		projectFiles, err := utils.FindFiles(ctx, p.root, p.scansFile)
+		if err != nil {
+			log.Printf("%s%s Workspace scan stopped: %v", logging.LogTagLSP, logging.LogTagServer, err)
+			return
+		}
+
+		for _, filePath := range projectFiles {
+			// Files of nested projects are collected with their own project
+			if s.projectFor(filePath) == p {
+				files = append(files, filePath)
+				filesByProject[p] = append(filesByProject[p], filePath)
+			}
+		}
+	}
+
+	log.Printf("%s%s Workspace scan found %d files in %d projects", logging.LogTagLSP, logging.LogTagServer, len(files), len(projects))
+
+	batches := make(map[*project]map[string]map[string][]protocol.Diagnostic)
+	for p, projectFiles := range filesByProject {
+		batches[p] = s.analyzeBatches(ctx, p, projectFiles, report)
+	}
+
+	for i, filePath := range files {
+		if ctx.Err() != nil {
+			log.Printf("%s%s Workspace scan cancelled after %d/%d files", logging.LogTagLSP, logging.LogTagServer, i, len(files))
+			return
+		}
+
+		report(fmt.Sprintf("%d/%d %s", i+1, len(files), s.displayPath(filePath)), uint32(i*100/len(files)))
+
+		run := analysisRun{includeManual: true, batchResults: batches[s.projectFor(filePath)]}
+		done := s.analysisScheduler.Submit(ctx, scheduler.PriorityBackground, func(jobCtx context.Context) {
+			diags := s.collectDiagnostics(jobCtx, filePath, run)
+			if jobCtx.Err() != nil {
+				return
+			}
+			s.publishDiagnostics(context.Background(), utils.PathToURI(filePath), diags)
+		})
+		<-done
+	}
+}
+
+// analyzeBatches runs the providers able to analyze all the files of the project at once, by provider id.
+// Their results replace the runs per file of the scan; files of a failed batch are analyzed one by one.
+func (s *Server) analyzeBatches(ctx context.Context, p *project, files []string, report progressReporter) map[string]map[string][]protocol.Diagnostic {
+	results := make(map[string]map[string][]protocol.Diagnostic)
+	for _, provider := range selectProviders(p, s.loadDiagnosticsProviders(p), analysisRun{includeManual: true}) {
+		analyzer, ok := provider.(diagnostics.BatchAnalyzer)
+		if !ok || ctx.Err() != nil {
+			continue
+		}
+
+		providerFiles := []string{}
+		for _, filePath := range files {
+			if diagnostics.AnalyzesFile(provider.Id(), p.serverConfig.DiagnosticsProviders, filePath) {
+				providerFiles = append(providerFiles, filePath)
+			}
+		}
+		if len(providerFiles) == 0 {
+			continue
+		}
+
+		report(fmt.Sprintf("%s: %d files", provider.Name(), len(providerFiles)), 0)
+		batchCtx, toolFailure := diagnostics.TrackFailures(ctx)
+		providerResults, err := analyzer.AnalyzeFiles(batchCtx, p.root, providerFiles)
+		if err == nil {
+			err = toolFailure()
+		}
+		if err != nil {
+			log.Printf("%s%s %s batch failed, analyzing the files one by one: %v", logging.LogTagLSP, logging.LogTagServer, provider.Name(), err)
+			continue
+		}
+		results[provider.Id()] = providerResults
+	}
+	return results
+}
+
+// displayPath shortens the path relative to the workspace folder holding it
+func (s *Server) displayPath(filePath string) string {
+	for _, folder := range s.workspaceFolders {
+		if relativePath, err := filepath.Rel(folder, filePath); err == nil && !strings.HasPrefix(relativePath, "..") {
+			return relativePath
+		}
+	}
+	return filePath
+}
@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+// Enables the regression thresholds of the benchmarks, see `make bench-check`
+const benchThresholdsEnv = "PHP_DIAGLS_BENCH_THRESHOLDS"
+
+// largeDiffFixture returns a PHP file of the given number of lines, the php-cs-fixer style diff changing
+// every step-th line and the fixed content
+func largeDiffFixture(lines int, step int) (string, string, string) {
+	original := make([]string, lines)
+	fixed := make([]string, lines)
+	for i := range original {
+		original[i] = fmt.Sprintf("    $value%d = array(%d, \"item\");", i, i)
+		fixed[i] = original[i]
+	}
+
+	var diff strings.Builder
+	diff.WriteString("--- Original\n+++ New\n")
+	for i := step / 2; i < lines-1; i += step {
+		fixed[i] = fmt.Sprintf("    $value%d = [%d, 'item'];", i, i)
+		fmt.Fprintf(&diff, "@@ -%d,3 +%d,3 @@\n %s\n-%s\n+%s\n %s\n", i, i, original[i-1], original[i], fixed[i], original[i+1])
+	}
+
+	return strings.Join(original, "\n") + "\n", diff.String(), strings.Join(fixed, "\n") + "\n"
+}
+
+func BenchmarkApplyUnifiedDiff(b *testing.B) {
+	original, diff, _ := largeDiffFixture(5000, 10)
+	b.SetBytes(int64(len(original)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := utils.ApplyUnifiedDiff(original, diff); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLineEdits(b *testing.B) {
+	original, _, fixed := largeDiffFixture(2000, 20)
+	b.SetBytes(int64(len(original)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		utils.LineEdits(original, fixed)
+	}
+}
+
+func TestApplyUnifiedDiff_LargeFixture(t *testing.T) {
+	original, diff, fixed := largeDiffFixture(5000, 10)
+	result, err := utils.ApplyUnifiedDiff(original, diff)
+	if err != nil {
+		t.Fatalf("ApplyUnifiedDiff failed: %v", err)
+	}
+	if result != fixed {
+		t.Error("Expected the fixture diff to produce the fixed content")
+	}
+}
+
+// TestBenchmarkThresholds guards the hot paths against performance regressions. Allocations are stable
+// across machines, durations get a wide margin.
+func TestBenchmarkThresholds(t *testing.T) {
+	if os.Getenv(benchThresholdsEnv) == "" {
+		t.Skipf("Set %s=1 to check the benchmark thresholds", benchThresholdsEnv)
+	}
+
+	thresholds := []struct {
+		name         string
+		benchmark    func(b *testing.B)
+		maxNsPerOp   int64
+		maxAllocsPer int64
+	}{
+		{"ApplyUnifiedDiff", BenchmarkApplyUnifiedDiff, 15_000_000, 1_500},
+		{"LineEdits", BenchmarkLineEdits, 40_000_000, 500},
+	}
+
+	for _, threshold := range thresholds {
+		result := testing.Benchmark(threshold.benchmark)
+		t.Logf("%s: %d ns/op, %d allocs/op", threshold.name, result.NsPerOp(), result.AllocsPerOp())
+		if result.NsPerOp() > threshold.maxNsPerOp {
+			t.Errorf("%s: %d ns/op exceeds %d", threshold.name, result.NsPerOp(), threshold.maxNsPerOp)
+		}
+		if result.AllocsPerOp() > threshold.maxAllocsPer {
+			t.Errorf("%s: %d allocs/op exceeds %d", threshold.name, result.AllocsPerOp(), threshold.maxAllocsPer)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker stops calling an operation failing repeatedly. After threshold consecutive failures it
+// opens for the cooldown, then lets a single call through (half-open), the others waiting for another
+// cooldown: a success closes it, a failure opens it for another cooldown. A probe whose outcome is never
+// recorded frees the half-open slot after the cooldown. A nil breaker never opens.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may go through now
+func (b *CircuitBreaker) Allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) {
+		return false
+	}
+
+	// Half-open, the probe holds the breaker open until its outcome is recorded
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// Record reports the outcome of a call, it returns true when the failure opens the closed breaker
+func (b *CircuitBreaker) Record(now time.Time, failed bool) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return false
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return b.failures == b.threshold
+}
+
+// OpenUntil returns the end of the current cooldown, zero when the breaker never opened since the last success
+func (b *CircuitBreaker) OpenUntil() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.openUntil
+}
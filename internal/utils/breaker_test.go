@@ -0,0 +1,80 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		breaker := utils.NewCircuitBreaker(3, time.Minute)
+
+		breaker.Record(start, true)
+		breaker.Record(start, true)
+		breaker.Record(start, false)
+		breaker.Record(start, true)
+		if opened := breaker.Record(start, true); opened {
+			t.Fatal("Expected a success to reset the failure count")
+		}
+		if opened := breaker.Record(start, true); !opened {
+			t.Fatal("Expected the third consecutive failure to open the breaker")
+		}
+
+		if breaker.Allow(start.Add(30 * time.Second)) {
+			t.Error("Expected calls to be refused during the cooldown")
+		}
+		if until := breaker.OpenUntil(); !until.Equal(start.Add(time.Minute)) {
+			t.Errorf("Expected the breaker to be open until %v, got %v", start.Add(time.Minute), until)
+		}
+	})
+
+	t.Run("half-open retry", func(t *testing.T) {
+		breaker := utils.NewCircuitBreaker(1, time.Minute)
+		breaker.Record(start, true)
+
+		retry := start.Add(time.Minute)
+		if !breaker.Allow(retry) {
+			t.Fatal("Expected a retry once the cooldown elapsed")
+		}
+		if breaker.Allow(retry) {
+			t.Fatal("Expected a single call through while the retry runs")
+		}
+		if opened := breaker.Record(retry, true); opened {
+			t.Error("Expected a failed retry not to report the breaker as newly opened")
+		}
+		if breaker.Allow(retry.Add(30 * time.Second)) {
+			t.Error("Expected a failed retry to start another cooldown")
+		}
+
+		breaker.Record(retry.Add(time.Minute), false)
+		if !breaker.Allow(retry.Add(time.Minute)) || !breaker.OpenUntil().IsZero() {
+			t.Error("Expected a successful retry to close the breaker")
+		}
+	})
+
+	t.Run("abandoned retry", func(t *testing.T) {
+		breaker := utils.NewCircuitBreaker(1, time.Minute)
+		breaker.Record(start, true)
+
+		// The outcome of the retry is never recorded
+		retry := start.Add(time.Minute)
+		breaker.Allow(retry)
+		if breaker.Allow(retry.Add(30 * time.Second)) {
+			t.Error("Expected the calls to wait for the retry")
+		}
+		if !breaker.Allow(retry.Add(time.Minute)) {
+			t.Error("Expected another retry once the cooldown elapsed")
+		}
+	})
+
+	t.Run("nil breaker", func(t *testing.T) {
+		var breaker *utils.CircuitBreaker
+		if breaker.Record(start, true) || !breaker.Allow(start) {
+			t.Error("Expected a nil breaker never to open")
+		}
+	})
+}
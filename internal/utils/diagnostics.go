@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// Data key of the number of occurrences a grouped diagnostic stands for
+	OccurrencesDataKey = "occurrences"
+	// Placeholder of the tool message in the messages of a catalog
+	MessagePlaceholder = "{message}"
+)
+
+// MergeDuplicateDiagnostics collapses diagnostics sharing the same source, code and message on the
+// same line into a single diagnostic spanning the union of their ranges
+func MergeDuplicateDiagnostics(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	if len(diagnostics) < 2 {
+		return diagnostics
+	}
+
+	type diagnosticKey struct {
+		line    uint32
+		source  string
+		code    string
+		message string
+	}
+
+	merged := make([]protocol.Diagnostic, 0, len(diagnostics))
+	indexByKey := make(map[diagnosticKey]int)
+
+	for _, diagnostic := range diagnostics {
+		key := diagnosticKey{
+			line:    diagnostic.Range.Start.Line,
+			source:  diagnostic.Source,
+			code:    fmt.Sprint(diagnostic.Code),
+			message: diagnostic.Message,
+		}
+
+		index, exists := indexByKey[key]
+		if !exists {
+			indexByKey[key] = len(merged)
+			merged = append(merged, diagnostic)
+			continue
+		}
+
+		existing := &merged[index]
+		if positionBefore(diagnostic.Range.Start, existing.Range.Start) {
+			existing.Range.Start = diagnostic.Range.Start
+		}
+		if positionBefore(existing.Range.End, diagnostic.Range.End) {
+			existing.Range.End = diagnostic.Range.End
+		}
+		// Lower values are more severe
+		if diagnostic.Severity != 0 && (existing.Severity == 0 || diagnostic.Severity < existing.Severity) {
+			existing.Severity = diagnostic.Severity
+		}
+	}
+
+	return merged
+}
+
+// TranslateMessages replaces the message of the diagnostics whose rule id is in the catalog
+func TranslateMessages(diagnostics []protocol.Diagnostic, catalog map[string]string) []protocol.Diagnostic {
+	if len(catalog) == 0 {
+		return diagnostics
+	}
+
+	translated := make([]protocol.Diagnostic, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		if rule, ok := diagnostic.Code.(string); ok {
+			if message, exists := catalog[rule]; exists {
+				diagnostic.Message = strings.ReplaceAll(message, MessagePlaceholder, diagnostic.Message)
+			}
+		}
+		translated[i] = diagnostic
+	}
+
+	return translated
+}
+
+func positionBefore(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// SummarizeDiagnostics replaces the diagnostics of a single provider with one summary diagnostic per
+// rule, placed at the first occurrence, when their number exceeds the threshold. A threshold lower
+// than 1 disables summarizing.
+func SummarizeDiagnostics(diagnostics []protocol.Diagnostic, threshold int) []protocol.Diagnostic {
+	if threshold < 1 || len(diagnostics) <= threshold {
+		return diagnostics
+	}
+
+	summaries := make([]protocol.Diagnostic, 0)
+	counts := make(map[string]int)
+	indexByRule := make(map[string]int)
+
+	for _, diagnostic := range diagnostics {
+		rule := diagnosticRule(diagnostic)
+		counts[rule]++
+		index, exists := indexByRule[rule]
+		if !exists {
+			indexByRule[rule] = len(summaries)
+			summaries = append(summaries, diagnostic)
+			continue
+		}
+		// The summary doesn't depend on the report order
+		if positionBefore(diagnostic.Range.Start, summaries[index].Range.Start) {
+			summaries[index] = diagnostic
+		}
+	}
+
+	for rule, index := range indexByRule {
+		summaries[index].Message = fmt.Sprintf("%s: %d occurrences — run Fix All", rule, counts[rule])
+	}
+
+	return summaries
+}
+
+// FirstOccurrences keeps the first occurrence of each rule in the file and adds the number of
+// occurrences to its message when the rule is violated more than once
+func FirstOccurrences(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	return GroupOccurrences(diagnostics, func(protocol.Diagnostic) bool { return true })
+}
+
+// GroupOccurrences keeps the first occurrence of each rule selected by grouped, adding the number of
+// occurrences to its message and to its data (occurrences) when the rule is violated more than once.
+// The other diagnostics are kept as is.
+func GroupOccurrences(diagnostics []protocol.Diagnostic, grouped func(protocol.Diagnostic) bool) []protocol.Diagnostic {
+	if len(diagnostics) < 2 {
+		return diagnostics
+	}
+
+	firsts := make([]protocol.Diagnostic, 0)
+	counts := make(map[string]int)
+	indexByRule := make(map[string]int)
+
+	for _, diagnostic := range diagnostics {
+		if !grouped(diagnostic) {
+			firsts = append(firsts, diagnostic)
+			continue
+		}
+
+		rule := diagnosticRule(diagnostic)
+		counts[rule]++
+		index, exists := indexByRule[rule]
+		if !exists {
+			indexByRule[rule] = len(firsts)
+			firsts = append(firsts, diagnostic)
+			continue
+		}
+		// Providers don't always report in file order
+		if positionBefore(diagnostic.Range.Start, firsts[index].Range.Start) {
+			firsts[index] = diagnostic
+		}
+	}
+
+	for rule, index := range indexByRule {
+		if counts[rule] > 1 {
+			firsts[index].Message = fmt.Sprintf("%s (%d occurrences in this file)", firsts[index].Message, counts[rule])
+			firsts[index].Data = withOccurrences(firsts[index].Data, counts[rule])
+		}
+	}
+
+	return firsts
+}
+
+// withOccurrences adds the number of occurrences to the data of a diagnostic, next to the keys set by its
+// provider. The map is copied, the diagnostics of a shared run holding the same one; data other than a map
+// is kept as is.
+func withOccurrences(data interface{}, occurrences int) interface{} {
+	if data == nil {
+		return map[string]interface{}{OccurrencesDataKey: occurrences}
+	}
+	existing, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+
+	merged := make(map[string]interface{}, len(existing)+1)
+	for key, value := range existing {
+		merged[key] = value
+	}
+	merged[OccurrencesDataKey] = occurrences
+	return merged
+}
+
+// Occurrences returns the number of occurrences a grouped diagnostic stands for, as sent back by the
+// client, 1 for other diagnostics
+func Occurrences(diagnostic protocol.Diagnostic) int {
+	if data, ok := diagnostic.Data.(map[string]interface{}); ok {
+		switch occurrences := data[OccurrencesDataKey].(type) {
+		case int:
+			return occurrences
+		case float64:
+			return int(occurrences)
+		}
+	}
+	return 1
+}
+
+// diagnosticRule returns the rule identifier of a diagnostic, falling back to its message
+func diagnosticRule(diagnostic protocol.Diagnostic) string {
+	if diagnostic.Code != nil {
+		if code := fmt.Sprint(diagnostic.Code); code != "" {
+			return code
+		}
+	}
+	return diagnostic.Message
+}
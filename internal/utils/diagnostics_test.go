@@ -0,0 +1,277 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
+)
+
+func diagnosticAt(line, startChar, endChar uint32, source string, code string, message string) protocol.Diagnostic {
+	return protocol.Diagnostic{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: startChar},
+			End:   protocol.Position{Line: line, Character: endChar},
+		},
+		Severity: protocol.DiagnosticSeverityWarning,
+		Source:   source,
+		Code:     code,
+		Message:  message,
+	}
+}
+
+func TestMergeDuplicateDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []protocol.Diagnostic
+		expected []protocol.Diagnostic
+	}{
+		{
+			name:     "nil input",
+			input:    nil,
+			expected: nil,
+		},
+		{
+			name: "identical diagnostics on same line are merged into union range",
+			input: []protocol.Diagnostic{
+				diagnosticAt(3, 4, 10, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(3, 0, 6, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(3, 8, 20, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			},
+			expected: []protocol.Diagnostic{
+				diagnosticAt(3, 0, 20, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			},
+		},
+		{
+			name: "different lines are kept",
+			input: []protocol.Diagnostic{
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(2, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			},
+			expected: []protocol.Diagnostic{
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(2, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			},
+		},
+		{
+			name: "different source, code or message are kept",
+			input: []protocol.Diagnostic{
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "phpstan", "array_syntax", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "single_quote", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Other message"),
+			},
+			expected: []protocol.Diagnostic{
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "phpstan", "array_syntax", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "single_quote", "Use short array syntax"),
+				diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Other message"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := utils.MergeDuplicateDiagnostics(tt.input)
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %d", len(tt.expected), len(result))
+			}
+
+			for i := range result {
+				if result[i].Range != tt.expected[i].Range {
+					t.Errorf("Diagnostic %d: expected range %v, got %v", i, tt.expected[i].Range, result[i].Range)
+				}
+				if result[i].Source != tt.expected[i].Source || result[i].Code != tt.expected[i].Code || result[i].Message != tt.expected[i].Message {
+					t.Errorf("Diagnostic %d: expected %v, got %v", i, tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeDuplicateDiagnostics_KeepsMostSevere(t *testing.T) {
+	warning := diagnosticAt(5, 0, 5, "phpstan", "", "Undefined variable")
+	errorDiagnostic := diagnosticAt(5, 0, 5, "phpstan", "", "Undefined variable")
+	errorDiagnostic.Severity = protocol.DiagnosticSeverityError
+
+	result := utils.MergeDuplicateDiagnostics([]protocol.Diagnostic{warning, errorDiagnostic})
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(result))
+	}
+	if result[0].Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Expected merged severity Error, got %v", result[0].Severity)
+	}
+}
+
+func TestSummarizeDiagnostics(t *testing.T) {
+	diagnostics := []protocol.Diagnostic{
+		diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		diagnosticAt(4, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		diagnosticAt(2, 0, 5, "php-cs-fixer", "single_quote", "Use single quotes"),
+		diagnosticAt(7, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+	}
+
+	t.Run("disabled threshold keeps diagnostics", func(t *testing.T) {
+		result := utils.SummarizeDiagnostics(diagnostics, 0)
+		if len(result) != len(diagnostics) {
+			t.Errorf("Expected %d diagnostics, got %d", len(diagnostics), len(result))
+		}
+	})
+
+	t.Run("below threshold keeps diagnostics", func(t *testing.T) {
+		result := utils.SummarizeDiagnostics(diagnostics, 4)
+		if len(result) != len(diagnostics) {
+			t.Errorf("Expected %d diagnostics, got %d", len(diagnostics), len(result))
+		}
+	})
+
+	t.Run("above threshold summarizes per rule", func(t *testing.T) {
+		result := utils.SummarizeDiagnostics(diagnostics, 3)
+		if len(result) != 2 {
+			t.Fatalf("Expected 2 summary diagnostics, got %d", len(result))
+		}
+
+		if result[0].Code != "array_syntax" || result[0].Range.Start.Line != 1 {
+			t.Errorf("Expected array_syntax summary at first occurrence, got %v", result[0])
+		}
+		if result[0].Message != "array_syntax: 3 occurrences — run Fix All" {
+			t.Errorf("Unexpected summary message: %s", result[0].Message)
+		}
+		if result[1].Message != "single_quote: 1 occurrences — run Fix All" {
+			t.Errorf("Unexpected summary message: %s", result[1].Message)
+		}
+	})
+
+	t.Run("summary at the first occurrence in the file", func(t *testing.T) {
+		// Reported out of order, e.g. merged from parallel runs
+		input := []protocol.Diagnostic{
+			diagnosticAt(7, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			diagnosticAt(4, 2, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+			diagnosticAt(4, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		}
+
+		result := utils.SummarizeDiagnostics(input, 1)
+		if len(result) != 1 || result[0].Range.Start.Line != 4 || result[0].Range.Start.Character != 0 {
+			t.Errorf("Expected the summary at line 4 character 0, got %v", result)
+		}
+	})
+
+	t.Run("diagnostics without code are grouped by message", func(t *testing.T) {
+		input := []protocol.Diagnostic{
+			diagnosticAt(1, 0, 5, "phpstan", "", "Undefined variable"),
+			diagnosticAt(2, 0, 5, "phpstan", "", "Undefined variable"),
+		}
+		input[0].Code = nil
+		input[1].Code = nil
+
+		result := utils.SummarizeDiagnostics(input, 1)
+		if len(result) != 1 {
+			t.Fatalf("Expected 1 summary diagnostic, got %d", len(result))
+		}
+		if result[0].Message != "Undefined variable: 2 occurrences — run Fix All" {
+			t.Errorf("Unexpected summary message: %s", result[0].Message)
+		}
+	})
+}
+
+func TestFirstOccurrences(t *testing.T) {
+	diagnostics := []protocol.Diagnostic{
+		diagnosticAt(4, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		diagnosticAt(1, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		diagnosticAt(2, 0, 5, "php-cs-fixer", "single_quote", "Use single quotes"),
+		diagnosticAt(7, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+	}
+
+	result := utils.FirstOccurrences(diagnostics)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d", len(result))
+	}
+
+	if result[0].Code != "array_syntax" || result[0].Range.Start.Line != 1 {
+		t.Errorf("Expected the earliest array_syntax occurrence, got %v", result[0])
+	}
+	if result[0].Message != "Use short array syntax (3 occurrences in this file)" {
+		t.Errorf("Unexpected message: %s", result[0].Message)
+	}
+	if result[1].Message != "Use single quotes" {
+		t.Errorf("Expected a single occurrence to keep its message, got %s", result[1].Message)
+	}
+}
+
+func TestGroupOccurrences(t *testing.T) {
+	diags := []protocol.Diagnostic{
+		{Range: protocol.Range{Start: protocol.Position{Line: 2}}, Code: "single_quote", Message: "Use single quotes"},
+		{Range: protocol.Range{Start: protocol.Position{Line: 3}}, Code: "no_unused_imports", Message: "Remove unused import"},
+		{Range: protocol.Range{Start: protocol.Position{Line: 5}}, Code: "single_quote", Message: "Use single quotes"},
+		{Range: protocol.Range{Start: protocol.Position{Line: 7}}, Code: "no_unused_imports", Message: "Remove unused import"},
+	}
+
+	result := utils.GroupOccurrences(diags, func(diagnostic protocol.Diagnostic) bool {
+		return diagnostic.Code == "single_quote"
+	})
+
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d", len(result))
+	}
+	if result[0].Message != "Use single quotes (2 occurrences in this file)" || utils.Occurrences(result[0]) != 2 {
+		t.Errorf("Expected a group of 2 occurrences, got %q (%d)", result[0].Message, utils.Occurrences(result[0]))
+	}
+	for _, diagnostic := range result[1:] {
+		if diagnostic.Code != "no_unused_imports" || utils.Occurrences(diagnostic) != 1 {
+			t.Errorf("Expected ungrouped diagnostics kept as is, got %v", diagnostic)
+		}
+	}
+
+	// Data as sent back by the client
+	if occurrences := utils.Occurrences(protocol.Diagnostic{Data: map[string]interface{}{"occurrences": float64(4)}}); occurrences != 4 {
+		t.Errorf("Expected 4 occurrences, got %d", occurrences)
+	}
+}
+
+func TestGroupOccurrences_ProviderData(t *testing.T) {
+	// The provider data of the first occurrence, shared with the other one
+	data := map[string]interface{}{"fixable": true}
+	diags := []protocol.Diagnostic{
+		{Range: protocol.Range{Start: protocol.Position{Line: 5}}, Code: "single_quote", Message: "Use single quotes", Data: data},
+		{Range: protocol.Range{Start: protocol.Position{Line: 2}}, Code: "single_quote", Message: "Use single quotes", Data: data},
+	}
+
+	result := utils.FirstOccurrences(diags)
+
+	if len(result) != 1 || utils.Occurrences(result[0]) != 2 {
+		t.Fatalf("Expected a group of 2 occurrences, got %v", result)
+	}
+	if merged := result[0].Data.(map[string]interface{}); merged["fixable"] != true {
+		t.Errorf("Expected the provider data kept, got %v", merged)
+	}
+	if _, exists := data["occurrences"]; exists {
+		t.Errorf("Expected the provider data left untouched, got %v", data)
+	}
+}
+
+func TestTranslateMessages(t *testing.T) {
+	diagnostics := []protocol.Diagnostic{
+		diagnosticAt(1, 0, 5, "phpstan", "argument.type", "Parameter #1 $id expects int, string given."),
+		diagnosticAt(2, 0, 5, "php-cs-fixer", "array_syntax", "Use short array syntax"),
+		diagnosticAt(3, 0, 5, "phplint", "", "Syntax error"),
+	}
+	catalog := map[string]string{
+		"argument.type": "Type mismatch, see the typing guide: {message}",
+		"array_syntax":  "Utilisez la syntaxe courte des tableaux",
+	}
+
+	translated := utils.TranslateMessages(diagnostics, catalog)
+
+	expected := []string{
+		"Type mismatch, see the typing guide: Parameter #1 $id expects int, string given.",
+		"Utilisez la syntaxe courte des tableaux",
+		"Syntax error",
+	}
+	for i, message := range expected {
+		if translated[i].Message != message {
+			t.Errorf("Expected message %q, got %q", message, translated[i].Message)
+		}
+	}
+}
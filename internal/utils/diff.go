@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// Context lines around every hunk, as in `diff -u` and `git diff`
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns a git style unified diff turning original into modified, with a/ and b/ prefixed
+// headers so it can be applied with `git apply`. It returns "" when the contents are equal.
+func UnifiedDiff(path string, original string, modified string) string {
+	if original == modified {
+		return ""
+	}
+
+	ops := diffLines(splitLines(original), splitLines(modified))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+
+	for start := 0; start < len(ops); {
+		// Find the next change and the extent of its hunk, merging changes separated by few context lines
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		hunkStart := max(first-diffContextLines, start)
+		hunkEnd := first
+		for hunkEnd < len(ops) {
+			if ops[hunkEnd].kind != ' ' {
+				hunkEnd++
+				continue
+			}
+			next := hunkEnd
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-hunkEnd > 2*diffContextLines {
+				hunkEnd = min(hunkEnd+diffContextLines, len(ops))
+				break
+			}
+			hunkEnd = next
+		}
+
+		writeHunk(&out, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+
+	return out.String()
+}
+
+// LineEdits returns the edits turning original into modified, one per group of changed lines. Every
+// edit replaces whole lines of the original, so it can be reviewed on its own.
+func LineEdits(original string, modified string) []protocol.TextEdit {
+	edits := []protocol.TextEdit{}
+	if original == modified {
+		return edits
+	}
+
+	ops := diffLines(splitLines(original), splitLines(modified))
+	line := uint32(0)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			line++
+			i++
+			continue
+		}
+
+		start := line
+		var newText strings.Builder
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				line++
+			} else {
+				newText.WriteString(ops[i].line)
+			}
+		}
+
+		edits = append(edits, protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: start}, End: protocol.Position{Line: line}},
+			NewText: newText.String(),
+		})
+	}
+
+	return edits
+}
+
+// RangeEdits returns the LineEdits touching the lines of the range, leaving the other changes out. A range
+// ending at the start of a line doesn't include that line.
+func RangeEdits(original string, modified string, lineRange protocol.Range) []protocol.TextEdit {
+	first, last := lineRange.Start.Line, lineRange.End.Line
+	if last > first && lineRange.End.Character == 0 {
+		last--
+	}
+
+	edits := []protocol.TextEdit{}
+	for _, edit := range LineEdits(original, modified) {
+		editFirst, editLast := editLines(edit)
+		if editFirst <= last && editLast >= first {
+			edits = append(edits, edit)
+		}
+	}
+
+	return edits
+}
+
+// ChangedEdits returns the LineEdits touching the lines of original changed since base, an earlier version
+// of original, leaving the changes of the untouched lines out
+func ChangedEdits(original string, modified string, base string) []protocol.TextEdit {
+	// The edits turning original back into base are located on its changed lines
+	changes := LineEdits(original, base)
+
+	edits := []protocol.TextEdit{}
+	for _, edit := range LineEdits(original, modified) {
+		editFirst, editLast := editLines(edit)
+		for _, change := range changes {
+			changeFirst, changeLast := editLines(change)
+			if editFirst <= changeLast && editLast >= changeFirst {
+				edits = append(edits, edit)
+				break
+			}
+		}
+	}
+
+	return edits
+}
+
+// editLines returns the first and last lines of original touched by the edit, insertions touching the line
+// they are inserted before
+func editLines(edit protocol.TextEdit) (uint32, uint32) {
+	last := edit.Range.End.Line
+	if last > edit.Range.Start.Line {
+		last--
+	}
+	return edit.Range.Start.Line, last
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp, start int, end int) {
+	// Line numbers (1-based) of the first hunk line in both versions
+	originalLine, modifiedLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			originalLine++
+		}
+		if op.kind != '-' {
+			modifiedLine++
+		}
+	}
+
+	originalCount, modifiedCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			originalCount++
+		}
+		if op.kind != '-' {
+			modifiedCount++
+		}
+	}
+
+	// An empty range starts at the line preceding it
+	if originalCount == 0 {
+		originalLine--
+	}
+	if modifiedCount == 0 {
+		modifiedLine--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", originalLine, originalCount, modifiedLine, modifiedCount)
+	for _, op := range ops[start:end] {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits the content keeping the line endings, so a missing final newline is a difference
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script between a and b (Myers' algorithm)
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	maxEdits := n + m
+	offset := maxEdits + 1
+	v := make([]int, 2*maxEdits+2)
+	var trace [][]int
+
+	for d := 0; d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrack walks the saved frontiers back from the end to build the edit script
+func backtrack(a []string, b []string, trace [][]int, offset int, d int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
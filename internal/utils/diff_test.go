@@ -1,9 +1,11 @@
 package utils_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/cristianradulescu/php-diagls/internal/utils"
+	"go.lsp.dev/protocol"
 )
 
 func TestApplyUnifiedDiff(t *testing.T) {
@@ -321,6 +323,20 @@ new line 2
 	}
 }
 
+func TestUnifiedDiffApplies(t *testing.T) {
+	diff := "--- Original\n+++ New\n@@ -1,3 +1,3 @@\n <?php\n-$a=1;\n+$a = 1;\n $b = 2;\n"
+
+	if !utils.UnifiedDiffApplies("<?php\n$a=1;\n$b = 2;\n", diff) {
+		t.Error("Expected the diff to apply to the content it was computed on")
+	}
+	if utils.UnifiedDiffApplies("<?php\n$a = 1;\n$b = 2;\n", diff) {
+		t.Error("Expected the diff not to apply to changed content")
+	}
+	if utils.UnifiedDiffApplies("<?php\n", diff) {
+		t.Error("Expected the diff not to apply to shorter content")
+	}
+}
+
 // TestApplyUnifiedDiff_EdgeCases tests specific edge cases that need special attention
 func TestApplyUnifiedDiff_EdgeCases(t *testing.T) {
 	tests := []struct {
@@ -469,3 +485,169 @@ $a = new ClassA();`,
 		})
 	}
 }
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		expected string
+	}{
+		{
+			name:     "equal contents",
+			original: "a\nb\n",
+			modified: "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "single change",
+			original: "<?php\n$a = array(1);\necho $a;\n",
+			modified: "<?php\n$a = [1];\necho $a;\n",
+			expected: "diff --git a/src/Foo.php b/src/Foo.php\n--- a/src/Foo.php\n+++ b/src/Foo.php\n" +
+				"@@ -1,3 +1,3 @@\n <?php\n-$a = array(1);\n+$a = [1];\n echo $a;\n",
+		},
+		{
+			name:     "distant changes get separate hunks",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			modified: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: "diff --git a/src/Foo.php b/src/Foo.php\n--- a/src/Foo.php\n+++ b/src/Foo.php\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name:     "missing final newline",
+			original: "<?php\necho 1;",
+			modified: "<?php\necho 1;\n",
+			expected: "diff --git a/src/Foo.php b/src/Foo.php\n--- a/src/Foo.php\n+++ b/src/Foo.php\n" +
+				"@@ -1,2 +1,2 @@\n <?php\n-echo 1;\n\\ No newline at end of file\n+echo 1;\n",
+		},
+		{
+			name:     "insertion into empty file",
+			original: "",
+			modified: "<?php\n",
+			expected: "diff --git a/src/Foo.php b/src/Foo.php\n--- a/src/Foo.php\n+++ b/src/Foo.php\n" +
+				"@@ -0,0 +1,1 @@\n+<?php\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := utils.UnifiedDiff("src/Foo.php", tt.original, tt.modified)
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLineEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		expected []protocol.TextEdit
+	}{
+		{
+			name:     "equal contents",
+			original: "a\nb\n",
+			modified: "a\nb\n",
+			expected: []protocol.TextEdit{},
+		},
+		{
+			name:     "replaced line",
+			original: "<?php\n$a = array(1);\necho $a;\n",
+			modified: "<?php\n$a = [1];\necho $a;\n",
+			expected: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 2}}, NewText: "$a = [1];\n"},
+			},
+		},
+		{
+			name:     "separate edits for separate changes",
+			original: "1\n2\n3\n4\n",
+			modified: "one\n2\n3\n4\nfive\n",
+			expected: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 1}}, NewText: "one\n"},
+				{Range: protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 4}}, NewText: "five\n"},
+			},
+		},
+		{
+			name:     "deleted lines",
+			original: "<?php\n\n\necho 1;\n",
+			modified: "<?php\necho 1;\n",
+			expected: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 3}}, NewText: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := utils.LineEdits(tt.original, tt.modified)
+			if !reflect.DeepEqual(edits, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, edits)
+			}
+		})
+	}
+}
+
+func TestRangeEdits(t *testing.T) {
+	original := "<?php\n$a=1;\n$b=2;\n$c=3;\n"
+	modified := "<?php\n$a = 1;\n$b=2;\n$c = 3;\n"
+
+	tests := []struct {
+		name      string
+		lineRange protocol.Range
+		expected  []protocol.TextEdit
+	}{
+		{
+			name:      "change in the range",
+			lineRange: protocol.Range{Start: protocol.Position{Line: 1, Character: 2}, End: protocol.Position{Line: 1, Character: 4}},
+			expected: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 2}}, NewText: "$a = 1;\n"},
+			},
+		},
+		{
+			name:      "range ending at the start of a changed line",
+			lineRange: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 3}},
+			expected:  []protocol.TextEdit{},
+		},
+		{
+			name:      "whole document",
+			lineRange: protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 4}},
+			expected: []protocol.TextEdit{
+				{Range: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 2}}, NewText: "$a = 1;\n"},
+				{Range: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 4}}, NewText: "$c = 3;\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := utils.RangeEdits(original, modified, tt.lineRange)
+			if !reflect.DeepEqual(edits, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, edits)
+			}
+		})
+	}
+}
+
+func TestChangedEdits(t *testing.T) {
+	base := "<?php\n$a=1;\n$b=2;\n"
+	original := "<?php\n$a=1;\n$b=2;\n$c=3;\n"
+	modified := "<?php\n$a = 1;\n$b=2;\n$c = 3;\n"
+
+	expected := []protocol.TextEdit{
+		{Range: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 4}}, NewText: "$c = 3;\n"},
+	}
+	if edits := utils.ChangedEdits(original, modified, base); !reflect.DeepEqual(edits, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, edits)
+	}
+
+	if edits := utils.ChangedEdits(original, modified, original); len(edits) != 0 {
+		t.Errorf("Expected no edits without changes, got %+v", edits)
+	}
+
+	if edits := utils.ChangedEdits(original, modified, ""); len(edits) != 2 {
+		t.Errorf("Expected all the edits of a new file, got %+v", edits)
+	}
+}
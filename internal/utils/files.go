@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directories never analyzed, neither on demand nor during workspace scans
+var ignoredDirs = []string{"/vendor/", "/var/cache/", "/.git/", "/node_modules/"}
+
+func IsIgnoredPath(filePath string) bool {
+	for _, dir := range ignoredDirs {
+		if strings.Contains(filePath, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindFiles walks root and returns all supported files that are not ignored
+func FindFiles(ctx context.Context, root string, supportsFile func(string) bool) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if IsIgnoredPath(path + "/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if supportsFile(path) && !IsIgnoredPath(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// ResolveToolPath maps a path reported by a tool to the project file it designates. Tools running in a
+// container report paths under their own mount point, matched by the longest trailing part of the path
+// existing in the project.
+func ResolveToolPath(projectRoot string, reportedPath string) (string, bool) {
+	isFile := func(candidate string) bool {
+		info, err := os.Stat(candidate)
+		return err == nil && info.Mode().IsRegular()
+	}
+
+	if !filepath.IsAbs(reportedPath) {
+		candidate := filepath.Join(projectRoot, reportedPath)
+		if !isFile(candidate) {
+			return "", false
+		}
+		return candidate, true
+	}
+	if strings.HasPrefix(reportedPath, projectRoot+string(filepath.Separator)) && isFile(reportedPath) {
+		return reportedPath, true
+	}
+
+	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(reportedPath), "/"), "/")
+	for i := range parts {
+		candidate := filepath.Join(projectRoot, filepath.Join(parts[i:]...))
+		if isFile(candidate) {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// HostToolPath returns where a path given to a tool is in the project, whether the file exists or not, and
+// false when the path doesn't map into the project. Absolute paths are matched by their longest trailing part
+// whose directory exists in the project, at least one directory of which must match: a container path such
+// as /etc/phpstan.neon is outside the project mount.
+func HostToolPath(projectRoot string, toolPath string) (string, bool) {
+	if resolved, found := ResolveToolPath(projectRoot, toolPath); found {
+		return resolved, true
+	}
+	if !filepath.IsAbs(toolPath) {
+		return filepath.Join(projectRoot, toolPath), true
+	}
+	if strings.HasPrefix(toolPath, projectRoot+string(filepath.Separator)) {
+		return toolPath, true
+	}
+
+	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(toolPath), "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		dir := filepath.Join(projectRoot, filepath.Join(parts[i:len(parts)-1]...))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return filepath.Join(dir, parts[len(parts)-1]), true
+		}
+	}
+
+	return "", false
+}
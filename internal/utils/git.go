@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitShow returns the content of the object (e.g. ":path" for the staged version, "HEAD:path" for the
+// committed one); exists is false when the object does not exist
+func GitShow(ctx context.Context, root string, object string) (content string, exists bool, err error) {
+	if _, err := Git(ctx, root, "cat-file", "-e", object); err != nil {
+		return "", false, nil
+	}
+
+	content, err = Git(ctx, root, "show", object)
+	if err != nil {
+		return "", false, err
+	}
+	return content, true, nil
+}
+
+// GitBaseContent returns the content of the file at the merge base of ref and HEAD, the committed version
+// for ref "HEAD". A file added since is returned empty.
+func GitBaseContent(ctx context.Context, filePath string, ref string) (string, error) {
+	dir := filepath.Dir(filePath)
+	base, err := Git(ctx, dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	// ./ paths are relative to the directory of the command instead of the top level
+	content, _, err := GitShow(ctx, dir, strings.TrimSpace(base)+":./"+filepath.Base(filePath))
+	return content, err
+}
+
+// Git runs git in dir and returns its output, the error holding the stderr of git
+func Git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,55 @@
+package utils_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+func TestGitBaseContent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+
+	filePath := filepath.Join(root, "src", "Foo.php")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte("<?php\n$a=1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("init", "-q")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "initial")
+	if err := os.WriteFile(filePath, []byte("<?php\n$a=1;\n$b=2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := utils.GitBaseContent(context.Background(), filePath, "HEAD")
+	if err != nil || content != "<?php\n$a=1;\n" {
+		t.Errorf("Expected the committed content, got %q (%v)", content, err)
+	}
+
+	content, err = utils.GitBaseContent(context.Background(), filepath.Join(root, "src", "Bar.php"), "HEAD")
+	if err != nil || content != "" {
+		t.Errorf("Expected no content for a new file, got %q (%v)", content, err)
+	}
+
+	if _, err := utils.GitBaseContent(context.Background(), filePath, "missing-ref"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+}
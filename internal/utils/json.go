@@ -0,0 +1,64 @@
+package utils
+
+import "bytes"
+
+// RepairTruncatedJSON turns the intact prefix of a truncated JSON document into a valid document by
+// dropping the incomplete trailing value and closing the open objects and arrays. Complete documents
+// are returned as is; nil is returned when no usable prefix exists.
+func RepairTruncatedJSON(data []byte) []byte {
+	var stack []byte
+	var safeStack []byte
+	safe := -1
+	inString, escaped := false, false
+
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			// An empty container is a fine placeholder for a key's value, but would add a bogus array element
+			inArray := len(stack) > 0 && stack[len(stack)-1] == '['
+			stack = append(stack, c)
+			if !inArray {
+				safe, safeStack = i+1, append(safeStack[:0], stack...)
+			}
+		case '}', ']':
+			if len(stack) == 0 {
+				return nil
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return data[:i+1]
+			}
+			// Everything up to here is a complete value of the enclosing object or array
+			safe, safeStack = i+1, append(safeStack[:0], stack...)
+		}
+	}
+
+	if safe < 0 {
+		return nil
+	}
+
+	repaired := bytes.TrimRight(append([]byte(nil), data[:safe]...), " \t\r\n,")
+	for i := len(safeStack) - 1; i >= 0; i-- {
+		if safeStack[i] == '{' {
+			repaired = append(repaired, '}')
+		} else {
+			repaired = append(repaired, ']')
+		}
+	}
+
+	return repaired
+}
@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+func TestRepairTruncatedJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "complete document",
+			input:    `{"files":{"a.php":{"messages":[]}}}`,
+			expected: `{"files":{"a.php":{"messages":[]}}}`,
+		},
+		{
+			name:     "truncated inside a string",
+			input:    `{"files":{"a.php":{"messages":[{"message":"first"},{"message":"sec`,
+			expected: `{"files":{"a.php":{"messages":[{"message":"first"}]}}}`,
+		},
+		{
+			name:     "truncated after a comma",
+			input:    `{"files":{"a.php":{"messages":[]},`,
+			expected: `{"files":{"a.php":{"messages":[]}}}`,
+		},
+		{
+			name:     "braces inside strings are ignored",
+			input:    `{"files":{"a.php":{"messages":[{"message":"unexpected } in \"{\""}],"errors`,
+			expected: `{"files":{"a.php":{"messages":[{"message":"unexpected } in \"{\""}]}}}`,
+		},
+		{
+			name:     "truncated right after the first brace",
+			input:    `{"files`,
+			expected: `{}`,
+		},
+		{
+			name:     "no JSON",
+			input:    `PHP Fatal error`,
+			expected: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(utils.RepairTruncatedJSON([]byte(tt.input)))
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+			if result != "" && !json.Valid([]byte(result)) {
+				t.Errorf("Expected valid JSON, got %s", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// MessageLimiter lets through at most limit messages per sliding window and counts the ones held back
+type MessageLimiter struct {
+	mu         sync.Mutex
+	limit      int
+	window     time.Duration
+	sent       []time.Time
+	suppressed int
+}
+
+func NewMessageLimiter(limit int, window time.Duration) *MessageLimiter {
+	return &MessageLimiter{limit: limit, window: window}
+}
+
+// Allow reports whether a message may be sent now. An allowed message takes over the count of the
+// messages suppressed since the last one sent; a suppressed one returns the running count.
+func (l *MessageLimiter) Allow(now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.takeSlot(now) {
+		l.suppressed++
+		return false, l.suppressed
+	}
+
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return true, suppressed
+}
+
+// TakeSuppressed returns the number of suppressed messages when a slot is free to report them, 0 otherwise
+func (l *MessageLimiter) TakeSuppressed(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.suppressed == 0 || !l.takeSlot(now) {
+		return 0
+	}
+
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return suppressed
+}
+
+// NextSlot returns the time left until a message may be sent again
+func (l *MessageLimiter) NextSlot(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.expire(now)
+	if len(l.sent) < l.limit {
+		return 0
+	}
+	return l.sent[0].Add(l.window).Sub(now)
+}
+
+func (l *MessageLimiter) takeSlot(now time.Time) bool {
+	l.expire(now)
+	if len(l.sent) >= l.limit {
+		return false
+	}
+
+	l.sent = append(l.sent, now)
+	return true
+}
+
+func (l *MessageLimiter) expire(now time.Time) {
+	i := 0
+	for i < len(l.sent) && !now.Before(l.sent[i].Add(l.window)) {
+		i++
+	}
+	l.sent = l.sent[i:]
+}
@@ -0,0 +1,71 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cristianradulescu/php-diagls/internal/utils"
+)
+
+func TestMessageLimiter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("suppresses messages over the limit", func(t *testing.T) {
+		limiter := utils.NewMessageLimiter(2, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			if allowed, _ := limiter.Allow(start); !allowed {
+				t.Fatalf("Expected message %d to be allowed", i+1)
+			}
+		}
+
+		allowed, suppressed := limiter.Allow(start.Add(time.Second))
+		if allowed || suppressed != 1 {
+			t.Errorf("Expected the third message to be suppressed, got allowed=%v suppressed=%d", allowed, suppressed)
+		}
+		if _, suppressed := limiter.Allow(start.Add(2 * time.Second)); suppressed != 2 {
+			t.Errorf("Expected 2 suppressed messages, got %d", suppressed)
+		}
+
+		if wait := limiter.NextSlot(start.Add(10 * time.Second)); wait != 50*time.Second {
+			t.Errorf("Expected the next slot in 50s, got %v", wait)
+		}
+	})
+
+	t.Run("next allowed message carries the suppressed count", func(t *testing.T) {
+		limiter := utils.NewMessageLimiter(1, time.Minute)
+
+		limiter.Allow(start)
+		limiter.Allow(start.Add(time.Second))
+		limiter.Allow(start.Add(2 * time.Second))
+
+		allowed, suppressed := limiter.Allow(start.Add(time.Minute))
+		if !allowed || suppressed != 2 {
+			t.Errorf("Expected an allowed message with 2 suppressed, got allowed=%v suppressed=%d", allowed, suppressed)
+		}
+		if _, suppressed := limiter.Allow(start.Add(2 * time.Minute)); suppressed != 0 {
+			t.Errorf("Expected the count to be reset, got %d", suppressed)
+		}
+	})
+
+	t.Run("take suppressed", func(t *testing.T) {
+		limiter := utils.NewMessageLimiter(1, time.Minute)
+
+		if suppressed := limiter.TakeSuppressed(start); suppressed != 0 {
+			t.Errorf("Expected nothing to report, got %d", suppressed)
+		}
+
+		limiter.Allow(start)
+		limiter.Allow(start.Add(time.Second))
+
+		if suppressed := limiter.TakeSuppressed(start.Add(30 * time.Second)); suppressed != 0 {
+			t.Errorf("Expected no free slot yet, got %d", suppressed)
+		}
+		if suppressed := limiter.TakeSuppressed(start.Add(time.Minute)); suppressed != 1 {
+			t.Errorf("Expected 1 suppressed message, got %d", suppressed)
+		}
+		if allowed, _ := limiter.Allow(start.Add(time.Minute)); allowed {
+			t.Error("Expected the report to take the free slot")
+		}
+	})
+}
@@ -16,13 +16,17 @@ func URIToPath(uri protocol.DocumentURI) string {
 	return strings.TrimPrefix(string(uri), "file://")
 }
 
-// Find the project root directory by looking for the config file
+func PathToURI(filePath string) protocol.DocumentURI {
+	return protocol.DocumentURI("file://" + filePath)
+}
+
+// Find the project root directory by looking for the default config file, for configurations without
+// project root (see config.DiagnosticsProvider.ProjectRoot)
 func FindProjectRoot(filePath string) string {
 	dir := filepath.Dir(filePath)
 
 	for {
-		configPath := filepath.Join(dir, config.ConfigFileName)
-		if _, err := os.Stat(configPath); err == nil {
+		if _, exists := config.DefaultFiles.Find(dir); exists {
 			return dir
 		}
 
@@ -45,6 +49,27 @@ func EnsureDiagnosticsArray(diagnostics []protocol.Diagnostic) []protocol.Diagno
 	return diagnostics
 }
 
+// HasGeneratedMarker reports whether one of the markers appears in the first maxLines lines of the content
+func HasGeneratedMarker(content string, markers []string, maxLines int) bool {
+	if len(markers) == 0 {
+		return false
+	}
+
+	lines := strings.SplitN(content, "\n", maxLines+1)
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+
+	for _, line := range lines {
+		for _, marker := range markers {
+			if marker != "" && strings.Contains(line, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func SnakeCaseToHumanReadable(stringToConvert string) string {
 	stringToConvert = strings.Trim(stringToConvert, "_")
 	if stringToConvert == "" {
@@ -69,6 +94,44 @@ func CopyFile(src, dst string) error {
 	return os.WriteFile(dst, data, 0644)
 }
 
+// UnifiedDiffApplies reports whether the context and removed lines of the diff are the lines of the original
+// content, i.e. the content didn't change since the diff was computed
+func UnifiedDiffApplies(originalContent, diff string) bool {
+	lines := strings.Split(originalContent, "\n")
+	originalLineNum := 0
+
+	re := regexp.MustCompile(`@@\s+-(\d+),(\d+)?\s+\+(\d+),(\d+)?\s+@@`)
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@") {
+			matches := re.FindStringSubmatch(line)
+			if len(matches) < 2 {
+				return false
+			}
+			startLine, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return false
+			}
+			originalLineNum = startLine - 1
+			continue
+		}
+
+		if len(line) == 0 || (line[0] != ' ' && line[0] != '-') {
+			continue
+		}
+		if originalLineNum < 0 || originalLineNum >= len(lines) || lines[originalLineNum] != line[1:] {
+			return false
+		}
+		originalLineNum++
+	}
+
+	return true
+}
+
 // ApplyUnifiedDiff applies a unified diff to the original content to produce the modified content
 func ApplyUnifiedDiff(originalContent, diff string) (string, error) {
 	lines := strings.Split(originalContent, "\n")
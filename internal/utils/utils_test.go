@@ -53,6 +53,25 @@ func TestURIToPath(t *testing.T) {
 	}
 }
 
+func TestPathToURI(t *testing.T) {
+	paths := []string{
+		"/home/user/project/file.php",
+		"/app/src/Controller/HomeController.php",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			uri := utils.PathToURI(path)
+			if string(uri) != "file://"+path {
+				t.Errorf("PathToURI(%s) = %s; expected file://%s", path, uri, path)
+			}
+			if utils.URIToPath(uri) != path {
+				t.Errorf("URIToPath(PathToURI(%s)) = %s; expected round trip", path, utils.URIToPath(uri))
+			}
+		})
+	}
+}
+
 func TestFindProjectRoot(t *testing.T) {
 	// Create temporary directory structure
 	tempDir := t.TempDir()
@@ -409,3 +428,128 @@ func TestCopyFile(t *testing.T) {
 		})
 	}
 }
+
+func TestHasGeneratedMarker(t *testing.T) {
+	markers := []string{"@generated", "Autogenerated by"}
+
+	tests := []struct {
+		name     string
+		content  string
+		markers  []string
+		maxLines int
+		expected bool
+	}{
+		{
+			name:     "marker in docblock",
+			content:  "<?php\n\n/**\n * @generated\n */\nclass Foo {}",
+			markers:  markers,
+			maxLines: 10,
+			expected: true,
+		},
+		{
+			name:     "second marker",
+			content:  "<?php\n// Autogenerated by Doctrine\n",
+			markers:  markers,
+			maxLines: 10,
+			expected: true,
+		},
+		{
+			name:     "marker after scanned lines",
+			content:  "<?php\n\n\n\n// @generated",
+			markers:  markers,
+			maxLines: 3,
+			expected: false,
+		},
+		{
+			name:     "no marker",
+			content:  "<?php\n\nclass Foo {}",
+			markers:  markers,
+			maxLines: 10,
+			expected: false,
+		},
+		{
+			name:     "no markers configured",
+			content:  "<?php\n// @generated",
+			markers:  nil,
+			maxLines: 10,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := utils.HasGeneratedMarker(tt.content, tt.markers, tt.maxLines)
+			if result != tt.expected {
+				t.Errorf("HasGeneratedMarker() = %v; expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveToolPath(t *testing.T) {
+	projectRoot := t.TempDir()
+	fooPath := filepath.Join(projectRoot, "src", "Foo.php")
+	if err := os.MkdirAll(filepath.Dir(fooPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fooPath, []byte("<?php\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		reported string
+		expected string
+		found    bool
+	}{
+		{"relative path", "src/Foo.php", fooPath, true},
+		{"host path", fooPath, fooPath, true},
+		{"container path", "/app/src/Foo.php", fooPath, true},
+		{"nested container path", "/var/www/html/src/Foo.php", fooPath, true},
+		{"missing file", "/app/src/Bar.php", "", false},
+		{"missing relative file", "src/Bar.php", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, found := utils.ResolveToolPath(projectRoot, tt.reported)
+			if resolved != tt.expected || found != tt.found {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.found, resolved, found)
+			}
+		})
+	}
+}
+
+func TestHostToolPath(t *testing.T) {
+	projectRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectRoot, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(projectRoot, "config", "phpstan.neon")
+	if err := os.WriteFile(existing, []byte("parameters:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		toolPath string
+		expected string
+		inside   bool
+	}{
+		{"existing file", "/app/config/phpstan.neon", existing, true},
+		{"relative path", "phpstan.neon", filepath.Join(projectRoot, "phpstan.neon"), true},
+		{"host path", filepath.Join(projectRoot, "phpstan.neon"), filepath.Join(projectRoot, "phpstan.neon"), true},
+		{"container path in an existing directory", "/app/config/phpstan.dist.neon", filepath.Join(projectRoot, "config", "phpstan.dist.neon"), true},
+		{"container path outside the project", "/etc/phpstan.neon", "", false},
+		{"container path without matching directory", "/app/.php-cs-fixer.dist.php", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostPath, inside := utils.HostToolPath(projectRoot, tt.toolPath)
+			if hostPath != tt.expected || inside != tt.inside {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.inside, hostPath, inside)
+			}
+		})
+	}
+}
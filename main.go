@@ -7,6 +7,8 @@ import (
 	"log"
 	"os"
 
+	"github.com/cristianradulescu/php-diagls/internal/cli"
+	"github.com/cristianradulescu/php-diagls/internal/config"
 	"github.com/cristianradulescu/php-diagls/internal/logging"
 	"github.com/cristianradulescu/php-diagls/internal/server"
 	"go.lsp.dev/jsonrpc2"
@@ -14,10 +16,21 @@ import (
 
 func main() {
 	var stdin bool
+	var configFile string
 
 	flag.BoolVar(&stdin, "stdin", false, "Use stdin/stdout for communication")
+	flag.StringVar(&configFile, "config", "", "Configuration file, relative to the project root or absolute (default "+config.ConfigFileName+")")
 	flag.Parse()
 
+	if flag.NArg() > 0 && cli.IsCommand(flag.Arg(0)) {
+		os.Exit(cli.Run(flag.Args(), configFile, os.Stdout, os.Stderr))
+	}
+
+	configFiles, err := config.ParseFiles([]string{configFile})
+	if err != nil {
+		log.Fatalf("%s%s %v", logging.LogTagLSP, logging.LogTagMain, err)
+	}
+
 	if stdin {
 		log.SetOutput(os.Stderr)
 
@@ -39,6 +52,9 @@ func main() {
 	log.Printf("%s%s LSP server connection established", logging.LogTagLSP, logging.LogTagMain)
 
 	lspServer := server.New(conn)
+	if configFile != "" {
+		lspServer.WithConfigFiles(configFiles)
+	}
 	log.Printf("%s%s Starting to handle requests...", logging.LogTagLSP, logging.LogTagMain)
 	conn.Go(ctx, lspServer.Handle)
 